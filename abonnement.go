@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des abonnements, de leur index par critère (critere~valeur~statut~id)
+// et de leur index par abonné (abonne~id)
+const (
+	PrefixeAbonnement          = "ABONNEMENT"
+	PrefixeIndexAbonnement     = "INDEX_ABONNEMENT"
+	PrefixeAbonnementParAbonne = "ABONNEMENT_PAR_ABONNE"
+)
+
+// Nombre maximal d'abonnements en vigueur par client abonné
+const AbonnementsMaxParAbonne = 20
+
+// Critères d'indexation d'un abonnement, du plus au moins sélectif ; le statut de transfert ne fait
+// que restreindre l'abonnement indexé sous l'un d'eux
+const (
+	critereTitre   = "titre"
+	critereProprio = "proprio"
+	critereCommune = "commune"
+	critereStatut  = "statut" // Critère des abonnements antérieurs, seulement lu par le job ABONNEMENTS
+)
+
+// S'abonner aux modifications du titre, ou des titres de la commune ou du propriétaire indiqués, au statut
+// de transfert éventuel. Une organisation tierce (banque, employeur) ne peut suivre que les titres d'un
+// propriétaire qui lui a consenti leur consultation.
+func (s *SmartContract) CreerAbonnement(ctx contractapi.TransactionContextInterface, idTitre string, commune string, proprio string, statut string) (string, error) {
+	if idTitre == "" && commune == "" && proprio == "" {
+		return "", nouvelleErreur(CodeRequeteInvalide, "un abonnement doit porter sur un titre, une commune ou un propriétaire")
+	}
+	switch statut {
+	case "", TransfertEnCosignature, TransfertEnAttente, TransfertFinalise, TransfertAnnule:
+	default:
+		return "", nouvelleErreur(CodeRequeteInvalide, "statut de transfert inconnu: %s", statut)
+	}
+
+	abonne, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	abonnement := &Abonnement{
+		Id:      ctx.GetStub().GetTxID(),
+		Abonne:  abonne,
+		IdTitre: idTitre,
+		Commune: commune,
+		Proprio: proprio,
+		Statut:  statut,
+		CreeLe:  maintenant.Format(FormatDate),
+	}
+	if err := autoriserAbonnement(s, ctx, abonnement); err != nil {
+		return "", err
+	}
+	abonnements, err := titresIndexes(ctx, PrefixeAbonnementParAbonne, []string{abonne}, AbonnementsMaxParAbonne)
+	if err != nil {
+		return "", err
+	}
+	if len(abonnements) >= AbonnementsMaxParAbonne {
+		return "", nouvelleErreur(CodeQuotaDepasse, "le client a atteint le nombre maximal de %d abonnements", AbonnementsMaxParAbonne)
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnement, []string{abonnement.Id})
+	if err != nil {
+		return "", err
+	}
+	if err := ecrireEtat(ctx, cle, abonnement); err != nil {
+		return "", err
+	}
+	if err := indexerAbonnement(ctx, abonnement); err != nil {
+		return "", err
+	}
+
+	return abonnement.Id, nil
+}
+
+// Vérifier que l'appelant peut suivre les titres visés par l'abonnement : les organisations qui
+// consultent les titres sans consentement suivent tout titre, une organisation tierce le titre ou le
+// propriétaire pour lesquels elle a reçu le consentement en vigueur
+func autoriserAbonnement(s *SmartContract, ctx contractapi.TransactionContextInterface, abonnement *Abonnement) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if slices.Contains(organisationsSansConsentement, mspID) {
+		return nil
+	}
+
+	titre := &TitreFoncier{Proprio: abonnement.Proprio}
+	if abonnement.IdTitre != "" {
+		titre, err = s.LireTitreFoncier(ctx, abonnement.IdTitre)
+		if err != nil {
+			return err
+		}
+		if abonnement.Proprio != "" && normaliserNom(abonnement.Proprio) != normaliserNom(titre.Proprio) {
+			return nouvelleErreur(CodeRequeteInvalide, "le titre foncier %s n'appartient pas à %s", titre.Id, abonnement.Proprio)
+		}
+	}
+	if titre.Proprio == "" {
+		return nouvelleErreur(CodeAccesRefuse, "l'organisation %s ne peut suivre que les titres d'un propriétaire qui y a consenti", mspID)
+	}
+	return verifierConsentement(ctx, titre, PorteeTitre)
+}
+
+// Résilier un abonnement (abonné uniquement)
+func (s *SmartContract) ResilierAbonnement(ctx contractapi.TransactionContextInterface, idAbonnement string) error {
+	abonnement, err := s.LireAbonnement(ctx, idAbonnement)
+	if err != nil {
+		return err
+	}
+	abonne, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if abonne != abonnement.Abonne {
+		return nouvelleErreur(CodeAccesRefuse, "l'abonnement %s appartient à un autre client", idAbonnement)
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnement, []string{idAbonnement})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(cle); err != nil {
+		return err
+	}
+	return desindexerAbonnement(ctx, abonnement)
+}
+
+// Lire un abonnement
+func (s *SmartContract) LireAbonnement(ctx contractapi.TransactionContextInterface, idAbonnement string) (*Abonnement, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnement, []string{idAbonnement})
+	if err != nil {
+		return nil, err
+	}
+
+	var abonnement Abonnement
+	existe, err := lireEtat(ctx, cle, &abonnement)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("abonnement %s non trouvé", idAbonnement)
+	}
+
+	return &abonnement, nil
+}
+
+// Un abonnement n'est indexé que sous son critère le plus sélectif, suivi de son statut éventuel
+func cleIndexAbonnement(ctx contractapi.TransactionContextInterface, abonnement *Abonnement) (string, error) {
+	critere, valeur := critereCommune, abonnement.Commune
+	if abonnement.IdTitre != "" {
+		critere, valeur = critereTitre, abonnement.IdTitre
+	} else if abonnement.Proprio != "" {
+		critere, valeur = critereProprio, normaliserNom(abonnement.Proprio)
+	}
+	return ctx.GetStub().CreateCompositeKey(PrefixeIndexAbonnement, []string{critere, valeur, abonnement.Statut, abonnement.Id})
+}
+
+func indexerAbonnement(ctx contractapi.TransactionContextInterface, abonnement *Abonnement) error {
+	cleIndex, err := cleIndexAbonnement(ctx, abonnement)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(cleIndex, valeurIndex); err != nil {
+		return err
+	}
+	cleAbonne, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnementParAbonne, []string{abonnement.Abonne, abonnement.Id})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(cleAbonne, valeurIndex)
+}
+
+func desindexerAbonnement(ctx contractapi.TransactionContextInterface, abonnement *Abonnement) error {
+	cleIndex, err := cleIndexAbonnement(ctx, abonnement)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(cleIndex); err != nil {
+		return err
+	}
+	cleAbonne, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnementParAbonne, []string{abonnement.Abonne, abonnement.Id})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(cleAbonne)
+}
+
+// Signaler la modification d'un titre (et le statut du transfert en cause) aux abonnements correspondants ;
+// seuls sont lus les abonnements indexés sous le titre, son propriétaire ou sa commune, sans statut ou
+// au statut du transfert
+func notifierAbonnes(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, statut string) error {
+	criteres := []struct{ nom, valeur string }{
+		{critereTitre, titre.Id},
+		{critereProprio, normaliserNom(titre.Proprio)},
+		{critereCommune, titre.Commune},
+	}
+	statuts := []string{""}
+	if statut != "" {
+		statuts = append(statuts, statut)
+	}
+
+	var declenchements []Declenchement
+	for _, critere := range criteres {
+		if critere.valeur == "" {
+			continue
+		}
+		for _, filtre := range statuts {
+			ids, err := titresIndexes(ctx, PrefixeIndexAbonnement, []string{critere.nom, critere.valeur, filtre}, 0)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAbonnement, []string{id})
+				if err != nil {
+					return err
+				}
+				var abonnement Abonnement
+				existe, err := lireEtat(ctx, cle, &abonnement)
+				if err != nil {
+					return err
+				}
+				if existe && correspond(&abonnement, titre, statut) {
+					declenchements = append(declenchements, Declenchement{IdAbonnement: id, IdTitre: titre.Id, Statut: statut})
+				}
+			}
+		}
+	}
+
+	return signalerDeclenchements(ctx, declenchements)
+}
+
+func correspond(abonnement *Abonnement, titre *TitreFoncier, statut string) bool {
+	if abonnement.IdTitre != "" && abonnement.IdTitre != titre.Id {
+		return false
+	}
+	if abonnement.Commune != "" && abonnement.Commune != titre.Commune {
+		return false
+	}
+	if abonnement.Proprio != "" && normaliserNom(abonnement.Proprio) != normaliserNom(titre.Proprio) {
+		return false
+	}
+	return abonnement.Statut == "" || abonnement.Statut == statut
+}
+
+// Page du job ABONNEMENTS : les abonnements créés avant l'index par statut et par abonné y sont inscrits,
+// leur ancienne entrée (critere~valeur~id) retirée ; ceux qui ne portaient que sur un statut, qu'aucun
+// critère sélectif n'indexe plus, sont résiliés. Le signet est le dernier abonnement parcouru.
+func pageAbonnements(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	cles, err := clesParPrefixeApres(ctx, PrefixeAbonnement, job.Signet, taillePage)
+	if err != nil {
+		return false, err
+	}
+	for _, cle := range cles {
+		job.Signet = cle
+		job.Compteurs["abonnementsParcourus"]++
+		var abonnement Abonnement
+		if _, err := lireEtat(ctx, cle, &abonnement); err != nil {
+			return false, err
+		}
+
+		critere, valeur := critereStatut, abonnement.Statut
+		if abonnement.Proprio != "" {
+			critere, valeur = critereProprio, normaliserNom(abonnement.Proprio)
+		} else if abonnement.Commune != "" {
+			critere, valeur = critereCommune, abonnement.Commune
+		}
+		ancienne, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexAbonnement, []string{critere, valeur, abonnement.Id})
+		if err != nil {
+			return false, err
+		}
+		if err := ctx.GetStub().DelState(ancienne); err != nil {
+			return false, err
+		}
+
+		if abonnement.IdTitre == "" && abonnement.Commune == "" && abonnement.Proprio == "" {
+			if err := ctx.GetStub().DelState(cle); err != nil {
+				return false, err
+			}
+			job.Compteurs["abonnementsResilies"]++
+			continue
+		}
+		if err := indexerAbonnement(ctx, &abonnement); err != nil {
+			return false, err
+		}
+		job.Compteurs["abonnementsIndexes"]++
+	}
+	return len(cles) < taillePage, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// MSP de l'État (administration centrale), seul habilité à gérer le domaine public
+const MSPEtat = "EtatMSP"
+
+// MSP de la conservation foncière, qui enrôle les agents des bureaux fonciers
+const MSPConservation = "ConservationMSP"
+
+// MSP du parquet, chargé des enquêtes pour corruption
+const MSPParquet = "ParquetMSP"
+
+// MSP de la cellule de renseignement financier, destinataire des déclarations anti-blanchiment
+const MSPConformite = "CentifMSP"
+
+// MSP du Trésor public, qui encaisse les droits et frais facturés par le registre
+const MSPTresor = "TresorMSP"
+
+// MSP des greffes des juridictions, qui enregistrent les décisions de justice
+const MSPJuridictions = "JuridictionsMSP"
+
+// MSP de la passerelle citoyenne, qui enrôle une identité par citoyen
+const MSPPasserelle = "PasserelleMSP"
+
+// MSP Identity Mixer (Idemix) de la passerelle citoyenne : ses lettres de créance anonymes ne révèlent
+// aux membres du réseau ni l'identité du citoyen ni le lien entre deux de ses consultations
+const MSPPasserelleIdemix = "PasserelleIdemixMSP"
+
+// MSP des ordres professionnels, qui tiennent les licences de leurs membres
+const (
+	MSPChambreNotaires  = "ChambreNotairesMSP"
+	MSPOrdreGeometres   = "OrdreGeometresMSP"
+	MSPOrdreEvaluateurs = "OrdreEvaluateursMSP"
+)
+
+// MSP des communes, qui enrôle leurs agents
+const MSPCommunes = "CommunesMSP"
+
+// Rôles portés par l'attribut "role" des certificats clients ; notaires, géomètres et évaluateurs
+// doivent en outre détenir une licence en cours de validité
+const (
+	RoleAssureur     = "assureur"
+	RoleAuditeur     = "auditeur"
+	RoleConservateur = "conservateur" // Conservateur de la propriété foncière
+	RoleEvaluateur   = "evaluateur"
+	RoleGeometre     = "geometre"
+	RoleInspecteur   = "inspecteur" // Agent de terrain du bureau foncier
+	RoleMairie       = "mairie"     // Agent communal, commune portée par l'attribut "commune"
+	RoleNotaire      = "notaire"
+	RoleSuperviseur  = "superviseur" // Chef de bureau, qui affecte les dossiers aux agents
+	RoleUrbanisme    = "urbanisme"   // Agent de la direction de l'urbanisme
+)
+
+// Rôles des agents d'un bureau foncier, seuls à pouvoir se prévaloir de l'attribut "bureau"
+var rolesBureau = []string{RoleConservateur, RoleInspecteur, RoleSuperviseur}
+
+// Organisation dont l'autorité délivre chaque rôle : l'attribut "role" d'un certificat émis par une
+// autre organisation n'est pas reconnu, chaque membre du réseau pouvant inscrire ce qu'il veut dans
+// les certificats de ses propres identités. Les assureurs sont les compagnies agréées par la
+// configuration du canal, chacune étant une organisation du réseau.
+var emetteursRoles = map[string]string{
+	RoleAuditeur:     MSPEtat,
+	RoleConservateur: MSPConservation,
+	RoleEvaluateur:   MSPOrdreEvaluateurs,
+	RoleGeometre:     MSPOrdreGeometres,
+	RoleInspecteur:   MSPConservation,
+	RoleMairie:       MSPCommunes,
+	RoleNotaire:      MSPChambreNotaires,
+	RoleSuperviseur:  MSPConservation,
+	RoleUrbanisme:    MSPEtat,
+}
+
+// Indiquer si un rôle est reconnu aux identités d'une organisation
+func roleReconnu(config *Configuration, role string, mspID string) bool {
+	if role == RoleAssureur {
+		return slices.Contains(config.CompagniesAssurance, mspID)
+	}
+	emetteur, connu := emetteursRoles[role]
+	return connu && emetteur == mspID
+}
+
+// Vérifier que l'appelant appartient à l'organisation attendue
+func verifierMSP(ctx contractapi.TransactionContextInterface, mspAutorise string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID != mspAutorise {
+		return nouvelleErreur(CodeAccesRefuse, "l'organisation %s n'est pas autorisée pour cette opération", mspID)
+	}
+	return nil
+}
+
+// Bureau foncier de l'appelant, porté par l'attribut "bureau" de son certificat. L'attribut n'est
+// reconnu qu'aux agents de bureau enrôlés par la conservation foncière : toute autre identité, même
+// munie de l'attribut, n'est rattachée à aucun bureau (vide).
+func bureauAppelant(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID != MSPConservation {
+		return "", nil
+	}
+	role, _, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if !slices.Contains(rolesBureau, role) {
+		return "", nil
+	}
+	bureau, _, err := ctx.GetClientIdentity().GetAttributeValue("bureau")
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	return bureau, nil
+}
+
+// Propriétaire représenté par l'appelant, porté par l'attribut "proprio" que délivre la passerelle
+// citoyenne à l'enrôlement. L'attribut d'une identité d'une autre organisation, qui ne l'a pas vérifié,
+// est ignoré (vide, comme s'il était absent).
+func proprioAppelant(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID != MSPPasserelle {
+		return "", nil
+	}
+	proprio, _, err := ctx.GetClientIdentity().GetAttributeValue("proprio")
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	return proprio, nil
+}
+
+// Vérifier que l'appelant peut modifier un titre : l'administration centrale agit sur tous les titres,
+// les agents d'un bureau foncier sur les seuls titres de leur bureau
+func verifierBureau(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID == MSPEtat {
+		return nil
+	}
+
+	bureau, err := bureauAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if bureau == "" || bureau != titre.BureauFoncier {
+		return nouvelleErreur(CodeAccesRefuse, "le titre foncier %s ne relève pas du bureau %q", titre.Id, bureau)
+	}
+	return nil
+}
+
+// Vérifier que l'appelant porte l'attribut de rôle attendu dans un certificat de l'organisation qui délivre
+// ce rôle, et la licence de sa profession
+func verifierRole(ctx contractapi.TransactionContextInterface, role string) error {
+	valeur, trouve, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if !trouve || valeur != role {
+		return nouvelleErreur(CodeAccesRefuse, "le rôle %s est requis pour cette opération", role)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if !roleReconnu(config, role, mspID) {
+		return nouvelleErreur(CodeAccesRefuse, "le rôle %s n'est pas reconnu aux identités de %s", role, mspID)
+	}
+	return verifierLicence(ctx, role)
+}
+
+// Vérifier que l'appelant est un agent de la commune indiquée
+func verifierAgentCommunal(ctx contractapi.TransactionContextInterface, commune string) error {
+	if err := verifierRole(ctx, RoleMairie); err != nil {
+		return err
+	}
+	communeAgent, _, err := ctx.GetClientIdentity().GetAttributeValue("commune")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if communeAgent == "" || communeAgent != commune {
+		return nouvelleErreur(CodeAccesRefuse, "l'agent de la commune %q n'est pas habilité pour la commune %s", communeAgent, commune)
+	}
+	return nil
+}
+
+func validerRattachementBureaux(parametres map[string]string) error {
+	if parametres["commune"] == "" || parametres["bureau"] == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "la commune et le bureau foncier de rattachement sont requis")
+	}
+	return nil
+}
+
+// Page d'un job de rattachement : les titres de la commune enregistrés sans bureau foncier, dont
+// l'administration centrale restait seule à pouvoir modifier, sont confiés au bureau indiqué. Le
+// signet est le dernier titre parcouru.
+func pageRattachementBureaux(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	debut := ""
+	if job.Signet != "" {
+		debut = job.Signet + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return false, err
+	}
+	defer resultsIterator.Close()
+
+	parcourus := 0
+	for parcourus < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, err
+		}
+		parcourus++
+		job.Signet = queryResponse.Key
+
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return false, err
+		}
+		if titre.Id == "" {
+			continue
+		}
+		job.Compteurs["titresParcourus"]++
+		if titre.BureauFoncier != "" || titre.Commune != job.Parametres["commune"] {
+			continue
+		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return false, err
+		}
+		titre.BureauFoncier = job.Parametres["bureau"]
+		if err := sauvegarderTitre(ctx, &titre); err != nil {
+			return false, err
+		}
+		job.Compteurs["titresRattaches"]++
+	}
+	return parcourus < taillePage, nil
+}
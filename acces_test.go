@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Le rôle assureur n'est reconnu qu'aux compagnies agréées par la configuration
+func TestAssureurAgree(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	assureur := &identiteTest{msp: "AssuranceSaharMSP", attributs: map[string]string{"role": RoleAssureur}}
+	assurer := func() error {
+		return r.appeler(assureur, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.EnregistrerAssurance(ctx, "TF002", "POL-1", 1000000, "2024-01-01", "2025-01-01")
+		})
+	}
+	verifierCode(t, "compagnie non agréée", assurer(), CodeAccesRefuse)
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.DefinirConfiguration(ctx, `{"compagniesAssurance":["AssuranceSaharMSP"]}`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifierCode(t, "compagnie agréée", assurer(), "")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des affectations de dossiers (nature, idDossier)
+const PrefixeAffectation = "AFFECTATION"
+
+// Procédures dont les dossiers en cours peuvent être affectés à un agent : leur conclusion revient à
+// un agent du bureau du titre
+var naturesAffectables = []string{DossierTransfert, DossierSignalement}
+
+// Affecter un dossier en cours à un agent (réservé au superviseur du bureau du titre). L'agent seul
+// peut ensuite le conclure, ou un superviseur du bureau lorsque la procédure lui est ouverte ; chaque
+// réaffectation est conservée à l'historique.
+func (s *SmartContract) AssignerDossier(ctx contractapi.TransactionContextInterface, nature string, id string, agentId string) (*AffectationDossier, error) {
+	if err := verifierRole(ctx, RoleSuperviseur); err != nil {
+		return nil, err
+	}
+	if agentId == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'agent affecté est obligatoire")
+	}
+	if !slices.Contains(naturesAffectables, nature) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "les dossiers %s ne sont pas affectables (attendu: %s)", nature, strings.Join(naturesAffectables, ", "))
+	}
+
+	// Le statut d'un signalement est lu dans son suivi, seul à le désigner sans son titre
+	var transfert *Transfert
+	var idTitre string
+	switch nature {
+	case DossierTransfert:
+		var err error
+		if transfert, err = s.LireTransfert(ctx, id); err != nil {
+			return nil, err
+		}
+		if transfert.Statut != TransfertEnAttente && transfert.Statut != TransfertEnCosignature {
+			return nil, fmt.Errorf("le dossier %s n'est plus en attente (%s)", id, transfert.Statut)
+		}
+		idTitre = transfert.IdTitre
+	default:
+		suivi, err := s.LireSuiviStatut(ctx, nature, id)
+		if err != nil {
+			return nil, err
+		}
+		if statutDefinitif(nature, suivi.Statut) {
+			return nil, fmt.Errorf("le dossier %s n'est plus en cours (%s)", id, suivi.Statut)
+		}
+		idTitre = suivi.IdTitre
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	bureau, err := bureauAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bureau == "" || bureau != titre.BureauFoncier {
+		return nil, nouvelleErreur(CodeAccesRefuse, "le dossier %s ne relève pas du bureau %q", id, bureau)
+	}
+
+	affectation, err := lireAffectation(ctx, nature, id)
+	if err != nil {
+		return nil, err
+	}
+	if affectation == nil {
+		affectation = &AffectationDossier{Nature: nature, IdDossier: id, Historique: []EtapeAffectation{}}
+	} else if affectation.Agent == agentId {
+		return nil, fmt.Errorf("le dossier %s est déjà affecté à %s", id, agentId)
+	}
+	superviseur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Un transfert quitte la file de son ancien titulaire (ou du bureau) pour celle de l'agent
+	if transfert != nil {
+		genre, ancien := AffectationBureau, titre.BureauFoncier
+		if affectation.Agent != "" {
+			genre, ancien = AffectationAgent, affectation.Agent
+		}
+		anciennes, err := entreesFileTransfert(ctx, transfert, genre, ancien)
+		if err != nil {
+			return nil, err
+		}
+		if err := mettreAJourFileAttente(ctx, anciennes, false); err != nil {
+			return nil, err
+		}
+	}
+
+	affectation.Agent = agentId
+	affectation.Historique = append(affectation.Historique, EtapeAffectation{
+		Agent:       agentId,
+		AffecteePar: superviseur,
+		AffecteeLe:  maintenant.Format(time.RFC3339),
+		TxId:        ctx.GetStub().GetTxID(),
+	})
+	if err := sauvegarderAffectation(ctx, affectation); err != nil {
+		return nil, err
+	}
+	if transfert != nil {
+		nouvelles, err := entreesFileTransfert(ctx, transfert, AffectationAgent, agentId)
+		if err != nil {
+			return nil, err
+		}
+		if err := mettreAJourFileAttente(ctx, nouvelles, transfert.Statut == TransfertEnAttente); err != nil {
+			return nil, err
+		}
+	}
+	return affectation, nil
+}
+
+// Lire l'affectation d'un dossier
+func (s *SmartContract) LireAffectationDossier(ctx contractapi.TransactionContextInterface, nature string, id string) (*AffectationDossier, error) {
+	affectation, err := lireAffectation(ctx, nature, id)
+	if err != nil {
+		return nil, err
+	}
+	if affectation == nil {
+		return nil, fmt.Errorf("le dossier %s %s n'est affecté à aucun agent", nature, id)
+	}
+	return affectation, nil
+}
+
+// Responsable d'un transfert dans les files des conservateurs, avec son genre d'affectation : l'agent
+// affecté, à défaut le bureau
+func responsableTransfert(ctx contractapi.TransactionContextInterface, transfert *Transfert, bureau string) (string, string, error) {
+	affectation, err := lireAffectation(ctx, DossierTransfert, transfert.Id)
+	if err != nil {
+		return "", "", err
+	}
+	if affectation == nil {
+		return AffectationBureau, bureau, nil
+	}
+	return AffectationAgent, affectation.Agent, nil
+}
+
+// Vérifier que l'appelant est l'agent affecté au dossier, ou un superviseur du bureau du titre ; un
+// dossier non affecté reste ouvert à tout agent du bureau
+func verifierAffectation(ctx contractapi.TransactionContextInterface, nature string, id string, titre *TitreFoncier) error {
+	affectation, err := lireAffectation(ctx, nature, id)
+	if err != nil || affectation == nil {
+		return err
+	}
+	role, _, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if role == RoleSuperviseur {
+		bureau, err := bureauAppelant(ctx)
+		if err != nil {
+			return err
+		}
+		if bureau != "" && bureau == titre.BureauFoncier {
+			return nil
+		}
+	}
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if agent != affectation.Agent {
+		return nouvelleErreur(CodeAccesRefuse, "le dossier %s est affecté à %s", id, affectation.Agent)
+	}
+	return nil
+}
+
+// Affectation d'un dossier (nil si aucune). Les transferts affectés avant les autres procédures
+// le sont sous leur seul identifiant.
+func lireAffectation(ctx contractapi.TransactionContextInterface, nature string, id string) (*AffectationDossier, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAffectation, []string{nature, id})
+	if err != nil {
+		return nil, err
+	}
+
+	var affectation AffectationDossier
+	existe, err := lireEtat(ctx, cle, &affectation)
+	if err != nil {
+		return nil, err
+	}
+	if !existe && nature == DossierTransfert {
+		if cle, err = ctx.GetStub().CreateCompositeKey(PrefixeAffectation, []string{id}); err != nil {
+			return nil, err
+		}
+		if existe, err = lireEtat(ctx, cle, &affectation); err != nil {
+			return nil, err
+		}
+		affectation.Nature = nature
+	}
+	if !existe {
+		return nil, nil
+	}
+	return &affectation, nil
+}
+
+func sauvegarderAffectation(ctx contractapi.TransactionContextInterface, affectation *AffectationDossier) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAffectation, []string{affectation.Nature, affectation.IdDossier})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, affectation); err != nil {
+		return err
+	}
+	if affectation.Nature != DossierTransfert {
+		return nil
+	}
+	ancienne, err := ctx.GetStub().CreateCompositeKey(PrefixeAffectation, []string{affectation.IdDossier})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(ancienne)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites de la superficie qu'occupe chaque titre dans ses zonages (commune~zone~idTitre)
+const PrefixeAllocationTitre = "ALLOCATION_TITRE"
+
+// Préfixe des anciens compteurs de zonage (commune~zone), que toute écriture de titre du zonage
+// réécrivait ; le job ALLOCATIONS_ZONES les retire une fois les titres inscrits un à un
+const PrefixeAllocationZone = "ALLOCATION_ZONE"
+
+// Superficie qu'un titre occupe dans chacun de ses zonages, tant qu'il n'est ni morcelé ni muté
+func contributionsZones(titre *TitreFoncier) map[[2]string]int {
+	contributions := make(map[[2]string]int)
+	if titre == nil || len(titre.MorceleEn) > 0 || titre.MuteVers != "" {
+		return contributions
+	}
+	for _, zone := range titre.Zones {
+		contributions[[2]string{titre.Commune, zone}] = titre.Superficie
+	}
+	return contributions
+}
+
+// Reporter l'écriture d'un titre sur ses allocations de zonage (titre nil : suppression). Une
+// attribution qui porterait un zonage plafonné au-delà de son plafond est rejetée ; un plafond
+// abaissé sous l'existant ne bloque que les attributions suivantes. Seul le contrôle d'un plafond
+// lit les allocations des autres titres du zonage.
+func reporterAllocations(ctx contractapi.TransactionContextInterface, ancien *TitreFoncier, titre *TitreFoncier) error {
+	avant := contributionsZones(ancien)
+	apres := contributionsZones(titre)
+	zones := make([][2]string, 0, len(avant)+len(apres))
+	for zone := range avant {
+		zones = append(zones, zone)
+	}
+	for zone := range apres {
+		if _, ok := avant[zone]; !ok {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i][0] < zones[j][0] || zones[i][0] == zones[j][0] && zones[i][1] < zones[j][1]
+	})
+
+	idTitre := ""
+	if titre != nil {
+		idTitre = titre.Id
+	} else if ancien != nil {
+		idTitre = ancien.Id
+	}
+	var config *Configuration
+	for _, zone := range zones {
+		superficie, attribue := apres[zone]
+		precedente, attribuee := avant[zone]
+		if attribue == attribuee && superficie == precedente {
+			continue
+		}
+
+		if superficie > precedente {
+			if config == nil {
+				var err error
+				if config, err = lireConfiguration(ctx); err != nil {
+					return err
+				}
+			}
+			if plafond := config.PlafondSuperficie(zone[0], zone[1]); plafond > 0 {
+				autres, err := superficieZone(ctx, zone[0], zone[1], idTitre)
+				if err != nil {
+					return err
+				}
+				if autres+superficie > plafond {
+					return nouvelleErreur(CodePlafondZoneAtteint, "la zone %q de %s ne peut recevoir %d m² de plus : %d m² attribués pour un plafond de %d m²",
+						zone[1], zone[0], superficie-precedente, autres+precedente, plafond)
+				}
+			}
+		}
+
+		var allocation *AllocationTitre
+		if attribue {
+			allocation = &AllocationTitre{Commune: zone[0], Zone: zone[1], IdTitre: idTitre, Superficie: superficie}
+		}
+		if err := sauvegarderAllocationTitre(ctx, zone[0], zone[1], idTitre, allocation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Superficie attribuée aux titres d'un zonage, hors le titre exclu. Une transaction qui écrit
+// plusieurs titres (morcellement, fusion) tient compte de ses propres allocations, que l'état ne
+// lui rendrait pas avant validation.
+func superficieZone(ctx contractapi.TransactionContextInterface, commune string, zone string, exclu string) (int, error) {
+	var ecrites map[string]*AllocationTitre
+	if contexte, suivi := ctx.(*ContexteTransaction); suivi {
+		ecrites = contexte.allocations
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeAllocationTitre, []string{commune, zone})
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	superficie := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		if _, ecrite := ecrites[queryResponse.Key]; ecrite {
+			continue
+		}
+		var allocation AllocationTitre
+		if err := decoderEtat(queryResponse.Value, &allocation); err != nil {
+			return 0, fmt.Errorf("allocation de zone illisible %q: %v", queryResponse.Key, err)
+		}
+		if allocation.IdTitre == exclu {
+			continue
+		}
+		superficie += allocation.Superficie
+	}
+	for _, allocation := range ecrites {
+		if allocation != nil && allocation.Commune == commune && allocation.Zone == zone && allocation.IdTitre != exclu {
+			superficie += allocation.Superficie
+		}
+	}
+	return superficie, nil
+}
+
+// Écrire l'allocation d'un titre dans un zonage, ou la retirer (allocation nil)
+func sauvegarderAllocationTitre(ctx contractapi.TransactionContextInterface, commune string, zone string, idTitre string, allocation *AllocationTitre) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAllocationTitre, []string{commune, zone, idTitre})
+	if err != nil {
+		return err
+	}
+	if contexte, suivi := ctx.(*ContexteTransaction); suivi {
+		if contexte.allocations == nil {
+			contexte.allocations = make(map[string]*AllocationTitre)
+		}
+		contexte.allocations[cle] = allocation
+	}
+	if allocation == nil {
+		return ctx.GetStub().DelState(cle)
+	}
+	return ecrireEtat(ctx, cle, allocation)
+}
+
+// Page du job ALLOCATIONS_ZONES : les titres écrits avant le suivi des zonages titre par titre y sont
+// inscrits, sans contrôle des plafonds, qu'ils occupent déjà. La dernière page retire les anciens
+// compteurs de zonage. Le signet est le dernier titre parcouru.
+func pageAllocationsZones(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	debut := ""
+	if job.Signet != "" {
+		debut = job.Signet + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return false, err
+	}
+	defer resultsIterator.Close()
+
+	parcourus := 0
+	for parcourus < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, err
+		}
+		parcourus++
+		job.Signet = queryResponse.Key
+
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return false, err
+		}
+		if titre.Id == "" || titre.Id != queryResponse.Key {
+			continue
+		}
+		job.Compteurs["titresParcourus"]++
+		for zone, superficie := range contributionsZones(&titre) {
+			cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAllocationTitre, []string{zone[0], zone[1], titre.Id})
+			if err != nil {
+				return false, err
+			}
+			var allocation AllocationTitre
+			existe, err := lireEtat(ctx, cle, &allocation)
+			if err != nil {
+				return false, err
+			}
+			if existe && allocation.Superficie == superficie {
+				continue
+			}
+			allocation = AllocationTitre{Commune: zone[0], Zone: zone[1], IdTitre: titre.Id, Superficie: superficie}
+			if err := ecrireEtat(ctx, cle, allocation); err != nil {
+				return false, err
+			}
+			job.Compteurs["allocationsInscrites"]++
+		}
+	}
+	if parcourus == taillePage {
+		return false, nil
+	}
+
+	compteurs, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeAllocationZone, []string{})
+	if err != nil {
+		return false, err
+	}
+	defer compteurs.Close()
+	for compteurs.HasNext() {
+		queryResponse, err := compteurs.Next()
+		if err != nil {
+			return false, err
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return false, err
+		}
+		job.Compteurs["compteursRetires"]++
+	}
+	return true, nil
+}
+
+// Superficie attribuée et restant attribuable dans chaque zonage d'une commune, pour les services
+// de planification : zonages suivis ou plafonnés par la configuration. Les titres écrits avant le
+// suivi titre par titre n'y figurent qu'après le job ALLOCATIONS_ZONES.
+func (s *SmartContract) GetQuotasCommune(ctx contractapi.TransactionContextInterface, commune string) (*QuotasCommune, error) {
+	if commune == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la commune est requise")
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make(map[string]*QuotaZone)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeAllocationTitre, []string{commune})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var allocation AllocationTitre
+		if err := decoderEtat(queryResponse.Value, &allocation); err != nil {
+			return nil, fmt.Errorf("allocation de zone illisible %q: %v", queryResponse.Key, err)
+		}
+		quota := zones[allocation.Zone]
+		if quota == nil {
+			quota = &QuotaZone{Zone: allocation.Zone}
+			zones[allocation.Zone] = quota
+		}
+		quota.Attribuee += allocation.Superficie
+		quota.Titres++
+	}
+	for _, plafond := range config.PlafondsZones {
+		if plafond.Commune == commune && zones[plafond.Zone] == nil {
+			zones[plafond.Zone] = &QuotaZone{Zone: plafond.Zone}
+		}
+	}
+
+	quotas := &QuotasCommune{Commune: commune, Zones: []QuotaZone{}}
+	for _, quota := range zones {
+		quota.Plafond = config.PlafondSuperficie(commune, quota.Zone)
+		if quota.Plafond > quota.Attribuee {
+			quota.Disponible = quota.Plafond - quota.Attribuee
+		}
+		quotas.Zones = append(quotas.Zones, *quota)
+	}
+	sort.Slice(quotas.Zones, func(i, j int) bool { return quotas.Zones[i].Zone < quotas.Zones[j].Zone })
+	return quotas, nil
+}
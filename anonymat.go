@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Consultations ouvertes aux lettres de créance anonymes de la passerelle citoyenne : vérifications
+// d'un titre, d'un document ou d'une attestation avant un achat, qui ne désignent pas l'appelant.
+// Les autres transactions engagent leur auteur et exigent une identité X.509.
+var consultationsAnonymes = []string{
+	"GetEmprisesTitre",
+	"GetInfoChaine",
+	"GetLitigesTitre",
+	"GetPermisConstruire",
+	"GetRangHypotheques",
+	"LireTitreFoncier",
+	"Ping",
+	"RechercherParAncienneReference",
+	"RechercherParHashDocument",
+	"VerifierAttestation",
+}
+
+// Indiquer si l'appelant présente une lettre de créance anonyme (Idemix) de la passerelle citoyenne
+func identiteAnonyme(ctx contractapi.TransactionContextInterface) (bool, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	return mspID == MSPPasserelleIdemix, nil
+}
+
+// Réserver les identités anonymes aux consultations qui leur sont ouvertes
+func verifierIdentiteAnonyme(ctx contractapi.TransactionContextInterface) error {
+	anonyme, err := identiteAnonyme(ctx)
+	if err != nil || !anonyme {
+		return err
+	}
+	operation := operationCourante(ctx)
+	for _, nom := range consultationsAnonymes {
+		if nom == operation {
+			return nil
+		}
+	}
+	return nouvelleErreur(CodeAccesRefuse, "la transaction %s n'est pas ouverte aux identités anonymes de la passerelle citoyenne", operation)
+}
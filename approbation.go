@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des demandes d'approbation (workflow~objet)
+const PrefixeApprobation = "APPROBATION"
+
+// Soumettre un morcellement ou un rezonage à l'approbation collégiale prévue par la configuration,
+// par l'agent habilité à l'acte ; les détails (lots ou zonages, au format attendu par l'acte) devront
+// être repris, au même contenu, lors de son exécution. L'approbation d'un transfert est demandée à sa
+// proposition, ou par un conservateur pour un transfert en attente proposé avant que la configuration
+// ne la prévoie.
+func (s *SmartContract) DemanderApprobation(ctx contractapi.TransactionContextInterface, workflow string, objet string, details string) error {
+	switch workflow {
+	case WorkflowMorcellement:
+		if err := verifierRole(ctx, RoleConservateur); err != nil {
+			return err
+		}
+	case WorkflowZonage:
+		if err := verifierMSP(ctx, MSPEtat); err != nil {
+			return err
+		}
+	case WorkflowTransfert:
+		if err := verifierRole(ctx, RoleConservateur); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("procédure d'approbation inconnue: %s", workflow)
+	}
+	if workflow == WorkflowTransfert {
+		transfert, err := s.LireTransfert(ctx, objet)
+		if err != nil {
+			return err
+		}
+		if transfert.Statut != TransfertEnAttente {
+			return fmt.Errorf("le transfert %s n'est pas en attente", objet)
+		}
+		details = ""
+	} else if _, err := s.LireTitreFoncier(ctx, objet); err != nil {
+		return err
+	}
+
+	approbation, err := lireApprobation(ctx, workflow, objet)
+	if err != nil {
+		return err
+	}
+	if approbation != nil && (approbation.Statut == ApprobationEnCours || approbation.Statut == ApprobationAccordee) {
+		return fmt.Errorf("une demande d'approbation %s est déjà %s pour %s", workflow, approbation.Statut, objet)
+	}
+	ouverte, err := ouvrirApprobation(ctx, workflow, objet, details)
+	if err != nil {
+		return err
+	}
+	if !ouverte {
+		return fmt.Errorf("la procédure %s n'est soumise à aucune approbation sur ce canal", workflow)
+	}
+	return nil
+}
+
+// Approuver une demande, au titre du rôle de l'appelant ; elle est accordée une fois le quorum atteint
+func (s *SmartContract) Approuver(ctx contractapi.TransactionContextInterface, workflow string, objet string, motif string) error {
+	return decider(ctx, workflow, objet, DecisionApprouve, motif)
+}
+
+// Rejeter une demande, au titre du rôle de l'appelant ; le rejet la clôt, et avec elle le transfert
+// dont elle conditionnait la finalisation
+func (s *SmartContract) Rejeter(ctx contractapi.TransactionContextInterface, workflow string, objet string, motif string) error {
+	if motif == "" {
+		return fmt.Errorf("le motif du rejet est obligatoire")
+	}
+	if err := decider(ctx, workflow, objet, DecisionRejete, motif); err != nil {
+		return err
+	}
+	if workflow != WorkflowTransfert {
+		return nil
+	}
+
+	transfert, err := s.LireTransfert(ctx, objet)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente && transfert.Statut != TransfertEnCosignature {
+		return nil
+	}
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	modifie, err := abandonnerTransfert(ctx, titre, transfert)
+	if err != nil || !modifie {
+		return err
+	}
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Lire une demande d'approbation
+func (s *SmartContract) LireApprobation(ctx contractapi.TransactionContextInterface, workflow string, objet string) (*Approbation, error) {
+	approbation, err := lireApprobation(ctx, workflow, objet)
+	if err != nil {
+		return nil, err
+	}
+	if approbation == nil {
+		return nil, fmt.Errorf("aucune demande d'approbation %s pour %s", workflow, objet)
+	}
+	return approbation, nil
+}
+
+// Enregistrer la décision de l'appelant sur une demande en cours
+func decider(ctx contractapi.TransactionContextInterface, workflow string, objet string, decision string, motif string) error {
+	approbation, err := lireApprobation(ctx, workflow, objet)
+	if err != nil {
+		return err
+	}
+	if approbation == nil {
+		return fmt.Errorf("aucune demande d'approbation %s pour %s", workflow, objet)
+	}
+	if approbation.Statut != ApprobationEnCours {
+		return fmt.Errorf("la demande d'approbation %s de %s n'est plus en cours (%s)", workflow, objet, approbation.Statut)
+	}
+
+	role, _, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	habilite := false
+	for _, autorise := range approbation.Roles {
+		habilite = habilite || autorise == role
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if !habilite || !roleReconnu(config, role, mspID) {
+		return nouvelleErreur(CodeAccesRefuse, "le rôle %q ne peut se prononcer sur la procédure %s", role, workflow)
+	}
+	if err := verifierLicence(ctx, role); err != nil {
+		return err
+	}
+	approbateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if approbateur == approbation.DemandeePar {
+		return nouvelleErreur(CodeAccesRefuse, "l'auteur de la demande ne peut se prononcer sur celle-ci")
+	}
+	for _, precedente := range approbation.Decisions {
+		if precedente.Approbateur == approbateur {
+			return fmt.Errorf("%s s'est déjà prononcé sur la demande d'approbation %s de %s", approbateur, workflow, objet)
+		}
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	approbation.Decisions = append(approbation.Decisions, DecisionApprobation{
+		Approbateur: approbateur,
+		Role:        role,
+		Decision:    decision,
+		Motif:       motif,
+		Horodatage:  maintenant.Format(time.RFC3339),
+	})
+	approuvees := 0
+	for _, d := range approbation.Decisions {
+		if d.Decision == DecisionApprouve {
+			approuvees++
+		}
+	}
+	if decision == DecisionRejete {
+		approbation.Statut = ApprobationRejetee
+	} else if approuvees >= approbation.Quorum {
+		approbation.Statut = ApprobationAccordee
+	}
+	if err := sauvegarderApprobation(ctx, approbation); err != nil {
+		return err
+	}
+
+	// Le journal des agents retient le délai de traitement de la demande
+	var delai int64
+	if demandee, err := time.Parse(time.RFC3339, approbation.DemandeeLe); err == nil {
+		delai = int64(maintenant.Sub(demandee) / time.Second)
+	}
+	traitement := TraitementApprobation
+	if decision == DecisionRejete {
+		traitement = TraitementRejet
+	}
+	return enregistrerTraitement(ctx, traitement, workflow+"/"+objet, delai)
+}
+
+// Une demande en cours attend un agent si son rôle y est habilité et qu'il ne l'a ni ouverte ni
+// déjà tranchée
+func attendAgent(approbation Approbation, agent string, role string) bool {
+	if approbation.Statut != ApprobationEnCours || approbation.DemandeePar == agent {
+		return false
+	}
+	for _, decision := range approbation.Decisions {
+		if decision.Approbateur == agent {
+			return false
+		}
+	}
+	for _, autorise := range approbation.Roles {
+		if autorise == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Ouvrir la demande d'approbation d'un acte si la configuration en prévoit une pour sa procédure
+func ouvrirApprobation(ctx contractapi.TransactionContextInterface, workflow string, objet string, details string) (bool, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return false, err
+	}
+	quorum := config.QuorumApprobation(workflow)
+	if quorum == nil {
+		return false, nil
+	}
+	demandeur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	approbation := &Approbation{
+		Workflow:    workflow,
+		Objet:       objet,
+		Details:     details,
+		Roles:       quorum.Roles,
+		Quorum:      quorum.Quorum,
+		Statut:      ApprobationEnCours,
+		Decisions:   []DecisionApprobation{},
+		DemandeePar: demandeur,
+		DemandeeLe:  maintenant.Format(time.RFC3339),
+	}
+	if err := sauvegarderApprobation(ctx, approbation); err != nil {
+		return false, err
+	}
+	return true, enregistrerTraitement(ctx, TraitementDemande, workflow+"/"+objet, 0)
+}
+
+// Vérifier qu'un acte a été approuvé, tel que soumis, lorsque sa procédure l'exige ; la demande
+// retournée (nil si aucune n'est requise) doit être consommée avec l'acte
+func verifierApprobation(ctx contractapi.TransactionContextInterface, workflow string, objet string, details string) (*Approbation, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.QuorumApprobation(workflow) == nil {
+		return nil, nil
+	}
+	approbation, err := lireApprobation(ctx, workflow, objet)
+	if err != nil {
+		return nil, err
+	}
+	if approbation == nil || approbation.Statut != ApprobationAccordee {
+		return nil, nouvelleErreur(CodeApprobationRequise, "l'acte %s de %s requiert une approbation accordée", workflow, objet)
+	}
+	if !detailsConcordent(approbation.Details, details) {
+		return nil, nouvelleErreur(CodeApprobationRequise, "l'acte %s de %s diffère de celui qui a été approuvé", workflow, objet)
+	}
+	return approbation, nil
+}
+
+// Indiquer si les détails d'un acte sont ceux qui ont été approuvés : deux documents JSON concordent
+// quelles que soient leur mise en forme et l'ordre de leurs clés, les autres détails à l'identique
+func detailsConcordent(approuves string, soumis string) bool {
+	if approuves == soumis {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(approuves), &a) != nil || json.Unmarshal([]byte(soumis), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Marquer la demande approuvée comme utilisée par l'acte
+func consommerApprobation(ctx contractapi.TransactionContextInterface, approbation *Approbation) error {
+	if approbation == nil {
+		return nil
+	}
+	approbation.Statut = ApprobationUtilisee
+	return sauvegarderApprobation(ctx, approbation)
+}
+
+func lireApprobation(ctx contractapi.TransactionContextInterface, workflow string, objet string) (*Approbation, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeApprobation, []string{workflow, objet})
+	if err != nil {
+		return nil, err
+	}
+	var approbation Approbation
+	existe, err := lireEtat(ctx, cle, &approbation)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &approbation, nil
+}
+
+func sauvegarderApprobation(ctx contractapi.TransactionContextInterface, approbation *Approbation) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeApprobation, []string{approbation.Workflow, approbation.Objet})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, approbation); err != nil {
+		return err
+	}
+	if err := suivreStatut(ctx, DossierApprobation, approbation.Workflow+"/"+approbation.Objet, "", approbation.Statut); err != nil {
+		return err
+	}
+
+	entrees, err := entreesFileApprobation(ctx, approbation)
+	if err != nil {
+		return err
+	}
+	return mettreAJourFileAttente(ctx, entrees, approbation.Statut == ApprobationEnCours)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des demandes d'archivage
+const PrefixeArchivage = "ARCHIVAGE"
+
+// Proposer l'archivage d'un titre ; l'identifiant retourné est à transmettre au second conservateur
+func (s *SmartContract) ProposerArchivage(ctx contractapi.TransactionContextInterface, idTitre string, motif string) (string, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return "", err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return "", err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return "", err
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return "", err
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	demande := &DemandeArchivage{
+		Id:         ctx.GetStub().GetTxID(),
+		IdTitre:    idTitre,
+		Motif:      motif,
+		ProposePar: conservateur,
+		ProposeLe:  maintenant.Format(time.RFC3339),
+		Statut:     ArchivageEnAttente,
+	}
+	if err := sauvegarderDemandeArchivage(ctx, demande); err != nil {
+		return "", err
+	}
+
+	return demande.Id, nil
+}
+
+// Confirmer un archivage proposé par un autre conservateur : le titre quitte le registre courant
+// et son dernier état est conservé, avec ses documents, dans la demande
+func (s *SmartContract) ConfirmerArchivage(ctx contractapi.TransactionContextInterface, idDemande string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	demande, err := s.LireDemandeArchivage(ctx, idDemande)
+	if err != nil {
+		return err
+	}
+	if demande.Statut != ArchivageEnAttente {
+		return fmt.Errorf("la demande d'archivage %s n'est pas en attente", idDemande)
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if conservateur == demande.ProposePar {
+		return nouvelleErreur(CodeAccesRefuse, "l'archivage doit être confirmé par un conservateur distinct de celui qui l'a proposé")
+	}
+
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	proposeLe, err := time.Parse(time.RFC3339, demande.ProposeLe)
+	if err != nil {
+		return fmt.Errorf("demande d'archivage %s corrompue: %v", idDemande, err)
+	}
+	if maintenant.After(proposeLe.Add(time.Duration(config.DelaiConfirmationArchivage) * time.Hour)) {
+		return fmt.Errorf("la demande d'archivage %s a expiré, une nouvelle proposition est nécessaire", idDemande)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, demande.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return err
+	}
+
+	demande.Statut = ArchivageConfirme
+	demande.ConfirmePar = conservateur
+	demande.ConfirmeLe = maintenant.Format(time.RFC3339)
+	demande.Titre = titre
+	if err := sauvegarderDemandeArchivage(ctx, demande); err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().DelState(titre.Id); err != nil {
+		return err
+	}
+	// Les documents du titre ne subsistent que dans la demande confirmée
+	if err := supprimerDocumentsSepares(ctx, titre); err != nil {
+		return err
+	}
+	if err := reporterAllocations(ctx, titre, nil); err != nil {
+		return err
+	}
+	if err := journaliserTitre(ctx, titre, nil); err != nil {
+		return err
+	}
+	return mettreAJourIndex(ctx, titre, nil)
+}
+
+// Lire une demande d'archivage
+func (s *SmartContract) LireDemandeArchivage(ctx contractapi.TransactionContextInterface, idDemande string) (*DemandeArchivage, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeArchivage, []string{idDemande})
+	if err != nil {
+		return nil, err
+	}
+
+	var demande DemandeArchivage
+	existe, err := lireEtat(ctx, cle, &demande)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("demande d'archivage %s non trouvée", idDemande)
+	}
+
+	return &demande, nil
+}
+
+func sauvegarderDemandeArchivage(ctx contractapi.TransactionContextInterface, demande *DemandeArchivage) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeArchivage, []string{demande.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, demande)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestArchivageRetireLesDocuments(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	r.signerDocument(r.autorite("TGI-DK"))
+	second := &identiteTest{msp: MSPConservation, attributs: map[string]string{"role": RoleConservateur, "bureau": "DK"}, nom: "second conservateur"}
+	if err := r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	err := r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.AjouterDocument(ctx, "TF100", r.pdf, "TGI-DK")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if documents := r.lire("TF100").Documents; len(documents) != 1 {
+		t.Fatalf("documents du titre: %+v", documents)
+	}
+
+	var demande string
+	err = r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) (err error) {
+		demande, err = r.s.ProposerArchivage(ctx, "TF100", "doublon")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.appeler(second, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.ConfirmerArchivage(ctx, demande)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive *DemandeArchivage
+	err = r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		archive, err = r.s.LireDemandeArchivage(ctx, demande)
+		return err
+	})
+	if err != nil || archive.Titre == nil || len(archive.Titre.Documents) != 1 {
+		t.Errorf("documents conservés dans la demande confirmée: %+v (%v)", archive, err)
+	}
+
+	// Un titre immatriculé sous le même identifiant n'hérite ni des documents ni de leur séquence
+	if err := r.ajouter(conservateurDK, "TF100", "Moussa Fall"); err != nil {
+		t.Fatal(err)
+	}
+	if documents := r.lire("TF100").Documents; len(documents) != 0 {
+		t.Errorf("documents hérités du titre archivé: %+v", documents)
+	}
+	err = r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.AjouterDocument(ctx, "TF100", r.pdf, "TGI-DK")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if documents := r.lire("TF100").Documents; len(documents) != 1 || documents[0].Seq != 1 {
+		t.Errorf("documents du nouveau titre: %+v", documents)
+	}
+}
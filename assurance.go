@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des assurances de titre
+const PrefixeAssurance = "ASSURANCE"
+
+// Enregistrer une assurance de titre (compagnies d'assurance uniquement)
+func (s *SmartContract) EnregistrerAssurance(ctx contractapi.TransactionContextInterface, idTitre string, numeroPolice string, couverture int, dateDebut string, dateFin string) error {
+	if err := verifierRole(ctx, RoleAssureur); err != nil {
+		return err
+	}
+	if _, err := s.LireTitreFoncier(ctx, idTitre); err != nil {
+		return err
+	}
+
+	assureur, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	assurance := model.NouvelleAssurance(idTitre, assureur, numeroPolice, couverture, dateDebut, dateFin)
+	if err := assurance.Valider(); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAssurance, []string{idTitre, numeroPolice})
+	if err != nil {
+		return err
+	}
+	existant, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return fmt.Errorf("la police %s existe déjà pour le titre %s", numeroPolice, idTitre)
+	}
+
+	return sauvegarderAssurance(ctx, cle, assurance)
+}
+
+// Renouveler une assurance de titre en repoussant sa date de fin
+func (s *SmartContract) RenouvelerAssurance(ctx contractapi.TransactionContextInterface, idTitre string, numeroPolice string, nouvelleDateFin string) error {
+	cle, assurance, err := lireAssurancePourAssureur(ctx, idTitre, numeroPolice)
+	if err != nil {
+		return err
+	}
+	if assurance.Statut != AssuranceActive {
+		return fmt.Errorf("la police %s est résiliée", numeroPolice)
+	}
+	if nouvelleDateFin <= assurance.DateFin {
+		return fmt.Errorf("la nouvelle date de fin doit être postérieure au %s", assurance.DateFin)
+	}
+
+	assurance.DateFin = nouvelleDateFin
+	if err := assurance.Valider(); err != nil {
+		return err
+	}
+
+	return sauvegarderAssurance(ctx, cle, assurance)
+}
+
+// Résilier une assurance de titre
+func (s *SmartContract) ResilierAssurance(ctx contractapi.TransactionContextInterface, idTitre string, numeroPolice string) error {
+	cle, assurance, err := lireAssurancePourAssureur(ctx, idTitre, numeroPolice)
+	if err != nil {
+		return err
+	}
+	if assurance.Statut == AssuranceResiliee {
+		return fmt.Errorf("la police %s est déjà résiliée", numeroPolice)
+	}
+
+	assurance.Statut = AssuranceResiliee
+
+	return sauvegarderAssurance(ctx, cle, assurance)
+}
+
+// Lister les assurances d'un titre foncier
+func (s *SmartContract) GetAssurancesTitre(ctx contractapi.TransactionContextInterface, idTitre string) ([]*AssuranceTitre, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeAssurance, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var assurances []*AssuranceTitre
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var assurance AssuranceTitre
+		err = decoderEtat(queryResponse.Value, &assurance)
+		if err != nil {
+			return nil, err
+		}
+		assurances = append(assurances, &assurance)
+	}
+
+	return assurances, nil
+}
+
+// Lire une police et vérifier que l'appelant est la compagnie qui l'a émise
+func lireAssurancePourAssureur(ctx contractapi.TransactionContextInterface, idTitre string, numeroPolice string) (string, *AssuranceTitre, error) {
+	if err := verifierRole(ctx, RoleAssureur); err != nil {
+		return "", nil, err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAssurance, []string{idTitre, numeroPolice})
+	if err != nil {
+		return "", nil, err
+	}
+	var assurance AssuranceTitre
+	existe, err := lireEtat(ctx, cle, &assurance)
+	if err != nil {
+		return "", nil, err
+	}
+	if !existe {
+		return "", nil, fmt.Errorf("police %s non trouvée pour le titre %s", numeroPolice, idTitre)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID != assurance.Assureur {
+		return "", nil, nouvelleErreur(CodeAccesRefuse, "la police %s a été émise par %s", numeroPolice, assurance.Assureur)
+	}
+
+	return cle, &assurance, nil
+}
+
+func sauvegarderAssurance(ctx contractapi.TransactionContextInterface, cle string, assurance *AssuranceTitre) error {
+	return ecrireEtat(ctx, cle, assurance)
+}
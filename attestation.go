@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des attestations de propriété
+const PrefixeAttestation = "ATTESTATION"
+
+// Durée de validité maximale d'une attestation, en jours
+const ValiditeAttestationMax = 90
+
+// Délivrer une attestation de propriété à un tiers, à la demande du propriétaire ou d'un conservateur du
+// bureau du titre. L'attestation ne fait foi qu'une fois signée par la conservation foncière
+// (SignerAttestation). Un titre saisi n'est pas attesté.
+func (s *SmartContract) EmettreAttestation(ctx contractapi.TransactionContextInterface, id string, destinataire string, validiteJours int) (*Attestation, error) {
+	if destinataire == "" {
+		return nil, fmt.Errorf("le destinataire de l'attestation est obligatoire")
+	}
+	if validiteJours <= 0 || validiteJours > ValiditeAttestationMax {
+		return nil, fmt.Errorf("la validité doit être comprise entre 1 et %d jours", ValiditeAttestationMax)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio == "" || proprio != titre.Proprio {
+		if err := verifierRole(ctx, RoleConservateur); err != nil {
+			return nil, err
+		}
+		if err := verifierBureau(ctx, titre); err != nil {
+			return nil, err
+		}
+	}
+	if titre.MuteVers != "" {
+		return nil, nouvelleErreur(CodeTitreMute, "le titre foncier %s est désormais administré sur le canal %s", titre.Id, titre.MuteVers)
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return nil, err
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attestation := &Attestation{
+		Id:           ctx.GetStub().GetTxID(),
+		IdTitre:      titre.Id,
+		NumTF:        titre.NumTF,
+		Proprio:      titre.Proprio,
+		Destinataire: destinataire,
+		EmiseLe:      maintenant.Format(time.RFC3339),
+		ExpireLe:     maintenant.AddDate(0, 0, validiteJours).Format(time.RFC3339),
+	}
+	if attestation.Empreinte, err = empreinteAttestation(attestation); err != nil {
+		return nil, err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAttestation, []string{attestation.Id})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, attestation); err != nil {
+		return nil, err
+	}
+	if err := suivreStatut(ctx, DossierAttestation, attestation.Id, titre.Id, AttestationEmise); err != nil {
+		return nil, err
+	}
+
+	contenu, err := json.Marshal(attestation)
+	if err != nil {
+		return nil, err
+	}
+	if err := emettreEvenement(ctx, EvenementAttestationEmise, contenu); err != nil {
+		return nil, err
+	}
+
+	return attestation, nil
+}
+
+// Signer une attestation au nom de la conservation foncière du bureau du titre (réservé à ses
+// conservateurs) : la signature de l'empreinte, produite hors du registre avec la clé de l'autorité, est
+// vérifiée sur son certificat
+func (s *SmartContract) SignerAttestation(ctx contractapi.TransactionContextInterface, attId string, autorite string, signature string) (*Attestation, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAttestation, []string{attId})
+	if err != nil {
+		return nil, err
+	}
+	var attestation Attestation
+	existe, err := lireEtat(ctx, cle, &attestation)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("attestation %s non trouvée", attId)
+	}
+	if attestation.Signature != "" {
+		return nil, fmt.Errorf("l'attestation %s a déjà été signée par %s", attId, attestation.Signataire)
+	}
+	titre, err := s.LireTitreFoncier(ctx, attestation.IdTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return nil, err
+	}
+	signataire, err := s.conservationSignataire(ctx, autorite)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierSignatureAutorite(signataire, []byte(attestation.Empreinte), signature); err != nil {
+		return nil, nouvelleErreur(CodeCertificatInvalide, "attestation %s: %v", attId, err)
+	}
+
+	attestation.Signataire = autorite
+	attestation.Signature = signature
+	if err := ecrireEtat(ctx, cle, attestation); err != nil {
+		return nil, err
+	}
+	return &attestation, nil
+}
+
+// Vérifier une attestation : intègre, signée par la conservation foncière, non expirée, le propriétaire attesté détient toujours le titre
+// et celui-ci n'a pas été saisi depuis
+func (s *SmartContract) VerifierAttestation(ctx contractapi.TransactionContextInterface, attId string) (*VerificationAttestation, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAttestation, []string{attId})
+	if err != nil {
+		return nil, err
+	}
+	var attestation Attestation
+	existe, err := lireEtat(ctx, cle, &attestation)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return &VerificationAttestation{Motif: "attestation inconnue"}, nil
+	}
+	verification := &VerificationAttestation{Attestation: &attestation}
+
+	empreinte, err := empreinteAttestation(&attestation)
+	if err != nil {
+		return nil, err
+	}
+	if empreinte != attestation.Empreinte {
+		verification.Motif = "empreinte invalide"
+		return verification, nil
+	}
+	if attestation.Signature == "" {
+		verification.Motif = "attestation non signée par la conservation foncière"
+		return verification, nil
+	}
+	signataire, err := s.LireAutorite(ctx, attestation.Signataire)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierSignatureAutorite(signataire, []byte(attestation.Empreinte), attestation.Signature); err != nil {
+		verification.Motif = "signature invalide"
+		return verification, nil
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expireLe, err := time.Parse(time.RFC3339, attestation.ExpireLe)
+	if err != nil {
+		return nil, fmt.Errorf("attestation %s corrompue: %v", attId, err)
+	}
+	if maintenant.After(expireLe) {
+		verification.Motif = "attestation expirée"
+		return verification, nil
+	}
+
+	var titre TitreFoncier
+	existe, err = lireEtat(ctx, attestation.IdTitre, &titre)
+	if err != nil {
+		return nil, err
+	}
+	if !existe || titre.Proprio != attestation.Proprio || titre.MuteVers != "" {
+		verification.Motif = "le propriétaire attesté ne détient plus le titre sur ce canal"
+		return verification, nil
+	}
+	if len(titre.Saisies) > 0 {
+		verification.Motif = "le titre fait l'objet d'une saisie conservatoire"
+		return verification, nil
+	}
+
+	verification.Valide = true
+	return verification, nil
+}
+
+func empreinteAttestation(attestation *Attestation) (string, error) {
+	copie := *attestation
+	copie.Empreinte = ""
+	copie.Signataire = ""
+	copie.Signature = ""
+
+	contenu, err := json.Marshal(copie)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(contenu)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Une attestation ne vaut qu'une fois signée par la conservation foncière du bureau du titre
+func TestAttestationSignee(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	conservation := nouvelleAutoriteTest(t, "CF-DK")
+	r.enregistrerAutorite(conservation, AutoriteConservation)
+	if err := r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	emettre := func(appelant *identiteTest) (attestation *Attestation, err error) {
+		err = r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) (err error) {
+			attestation, err = r.s.EmettreAttestation(ctx, "TF100", "Consulat de France", 30)
+			return err
+		})
+		return attestation, err
+	}
+	signer := func(appelant *identiteTest, attestation *Attestation, signature string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := r.s.SignerAttestation(ctx, attestation.Id, "CF-DK", signature)
+			return err
+		})
+	}
+	verifier := func(attestation *Attestation) (verification *VerificationAttestation) {
+		t.Helper()
+		err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+			verification, err = r.s.VerifierAttestation(ctx, attestation.Id)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return verification
+	}
+
+	_, err := emettre(conservateurTH)
+	verifierCode(t, "attestation par un autre bureau", err, CodeAccesRefuse)
+	attestation, err := emettre(conservateurDK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := verifier(attestation); v.Valide {
+		t.Error("attestation non signée reconnue valide")
+	}
+
+	signature := conservation.signer(t, []byte(attestation.Empreinte))
+	verifierCode(t, "signature par un autre bureau", signer(conservateurTH, attestation, signature), CodeAccesRefuse)
+	faussaire := nouvelleAutoriteTest(t, "CF-DK")
+	verifierCode(t, "signature d'une autre clé", signer(conservateurDK, attestation, faussaire.signer(t, []byte(attestation.Empreinte))), CodeCertificatInvalide)
+	verifierCode(t, "signature de la conservation", signer(conservateurDK, attestation, signature), "")
+	if v := verifier(attestation); !v.Valide {
+		t.Errorf("attestation signée refusée: %s", v.Motif)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des autorités émettrices
+const PrefixeAutorite = "AUTORITE"
+
+// Enregistrer une autorité émettrice et son certificat de signature (réservé à l'État)
+func (s *SmartContract) EnregistrerAutorite(ctx contractapi.TransactionContextInterface, id string, nom string, typeAutorite string, certificatPEM string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	certificat, err := analyserCertificat(certificatPEM)
+	if err != nil {
+		return err
+	}
+	empreinte := sha256.Sum256(certificat.Raw)
+
+	autorite := AutoriteEmettrice{
+		Id:         id,
+		Nom:        nom,
+		Type:       typeAutorite,
+		Certificat: certificatPEM,
+		Empreinte:  hex.EncodeToString(empreinte[:]),
+		Active:     true,
+	}
+	if err := autorite.Valider(); err != nil {
+		return err
+	}
+
+	return sauvegarderAutorite(ctx, &autorite)
+}
+
+// Révoquer une autorité émettrice (réservé à l'État)
+func (s *SmartContract) RevoquerAutorite(ctx contractapi.TransactionContextInterface, id string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	autorite, err := s.LireAutorite(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	autorite.Active = false
+
+	return sauvegarderAutorite(ctx, autorite)
+}
+
+// Lire une autorité émettrice
+func (s *SmartContract) LireAutorite(ctx contractapi.TransactionContextInterface, id string) (*AutoriteEmettrice, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAutorite, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	var autorite AutoriteEmettrice
+	existe, err := lireEtat(ctx, cle, &autorite)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("autorité %s non trouvée", id)
+	}
+
+	return &autorite, nil
+}
+
+// Lister les autorités émettrices reconnues
+func (s *SmartContract) GetAutorites(ctx contractapi.TransactionContextInterface) ([]*AutoriteEmettrice, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeAutorite, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var autorites []*AutoriteEmettrice
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var autorite AutoriteEmettrice
+		err = decoderEtat(queryResponse.Value, &autorite)
+		if err != nil {
+			return nil, err
+		}
+		autorites = append(autorites, &autorite)
+	}
+
+	return autorites, nil
+}
+
+// Vérifier que l'émetteur d'un document est une autorité active au certificat en cours de validité ;
+// retourne l'autorité, dont le certificat vérifie ensuite la signature du document
+func (s *SmartContract) verifierEmetteur(ctx contractapi.TransactionContextInterface, issuer string) (*AutoriteEmettrice, error) {
+	autorite, err := s.LireAutorite(ctx, issuer)
+	if err != nil {
+		return nil, nouvelleErreur(CodeEmetteurNonReconnu, "l'émetteur %s n'est pas une autorité reconnue", issuer)
+	}
+	if !autorite.Active {
+		return nil, nouvelleErreur(CodeEmetteurNonReconnu, "l'autorité %s a été révoquée", issuer)
+	}
+
+	certificat, err := analyserCertificat(autorite.Certificat)
+	if err != nil {
+		return nil, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if maintenant.Before(certificat.NotBefore) || maintenant.After(certificat.NotAfter) {
+		return nil, nouvelleErreur(CodeEmetteurNonReconnu, "le certificat de l'autorité %s n'est pas en cours de validité", issuer)
+	}
+
+	return autorite, nil
+}
+
+// Vérifier qu'une signature (base64) d'un contenu a été produite par la clé du certificat d'une autorité :
+// ECDSA ou RSA PKCS #1 v1.5 sur son SHA-256, ou Ed25519
+func verifierSignatureAutorite(autorite *AutoriteEmettrice, contenu []byte, signature string) error {
+	octets, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(octets) == 0 {
+		return fmt.Errorf("signature absente ou non encodée en base64")
+	}
+	certificat, err := analyserCertificat(autorite.Certificat)
+	if err != nil {
+		return err
+	}
+	var algorithme x509.SignatureAlgorithm
+	switch certificat.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithme = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithme = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithme = x509.PureEd25519
+	default:
+		return fmt.Errorf("clé du certificat de l'autorité %s non prise en charge", autorite.Id)
+	}
+	if err := certificat.CheckSignature(algorithme, contenu, octets); err != nil {
+		return fmt.Errorf("la signature n'a pas été produite par l'autorité %s", autorite.Id)
+	}
+	return nil
+}
+
+// Décoder un certificat X.509 au format PEM
+func analyserCertificat(certificatPEM string) (*x509.Certificate, error) {
+	bloc, _ := pem.Decode([]byte(certificatPEM))
+	if bloc == nil || bloc.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("certificat PEM invalide")
+	}
+	certificat, err := x509.ParseCertificate(bloc.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("certificat invalide: %v", err)
+	}
+	return certificat, nil
+}
+
+func sauvegarderAutorite(ctx contractapi.TransactionContextInterface, autorite *AutoriteEmettrice) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAutorite, []string{autorite.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, autorite)
+}
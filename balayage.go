@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe de la clé composite mémorisant la progression du balayage de chaque type d'échéance
+const PrefixeBalayage = "BALAYAGE"
+
+// Nombre maximal d'enregistrements parcourus par passage
+const limiteBalayage = 500
+
+// Parcourir au plus limite enregistrements d'un type d'échéance (PROMESSE, USUFRUIT, ARCHIVAGE) et
+// émettre un rappel pour chaque échéance atteinte dans le délai configuré (réservé à l'État).
+// Destiné à un ordonnanceur hors chaîne : chaque passage reprend où le précédent s'est arrêté.
+func (s *SmartContract) BalayerEcheances(ctx contractapi.TransactionContextInterface, typeEcheance string, limite int) (*ResultatBalayage, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return nil, err
+	}
+	if limite <= 0 || limite > limiteBalayage {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", limiteBalayage)
+	}
+	var prefixe string
+	switch typeEcheance {
+	case BalayagePromesses:
+		prefixe = PrefixePromesse
+	case BalayageArchivages:
+		prefixe = PrefixeArchivage
+	case BalayageUsufruits:
+		// Les titres fonciers sont enregistrés sous des clés simples
+	default:
+		return nil, nouvelleErreur(CodeRequeteInvalide, "type d'échéance inconnu: %s", typeEcheance)
+	}
+
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+	horizon := maintenant.AddDate(0, 0, config.DelaiRappelEcheances).Format(FormatDate)
+
+	cleProgression, err := ctx.GetStub().CreateCompositeKey(PrefixeBalayage, []string{typeEcheance})
+	if err != nil {
+		return nil, err
+	}
+	var derniere string
+	if _, err := lireEtat(ctx, cleProgression, &derniere); err != nil {
+		return nil, err
+	}
+
+	// Les titres se parcourent directement à partir de la progression ; les clés composites, qu'une
+	// requête d'intervalle ne peut atteindre, sont parcourues dans l'ordre et celles déjà vues écartées
+	var resultsIterator shim.StateQueryIteratorInterface
+	if prefixe == "" {
+		debut := ""
+		if derniere != "" {
+			debut = derniere + "\x00"
+		}
+		resultsIterator, err = ctx.GetStub().GetStateByRange(debut, "")
+	} else {
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey(prefixe, []string{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	resultat := &ResultatBalayage{Type: typeEcheance, Rappels: []RappelEcheance{}}
+	for resultat.Examines < limite && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if queryResponse.Key <= derniere {
+			continue
+		}
+		resultat.Examines++
+		derniere = queryResponse.Key
+
+		rappel, err := rappelEcheance(typeEcheance, queryResponse.Value, config, aujourdhui)
+		if err != nil {
+			return nil, err
+		}
+		if rappel != nil && rappel.Echeance <= horizon {
+			echeance, err := time.Parse(FormatDate, rappel.Echeance)
+			if err != nil {
+				return nil, err
+			}
+			rappel.Jours = int(echeance.Sub(maintenant.Truncate(24*time.Hour)).Hours() / 24)
+			resultat.Rappels = append(resultat.Rappels, *rappel)
+		}
+	}
+	if !resultsIterator.HasNext() {
+		resultat.Termine = true
+		derniere = ""
+	}
+	if err := ecrireEtat(ctx, cleProgression, derniere); err != nil {
+		return nil, err
+	}
+
+	if len(resultat.Rappels) == 0 {
+		return resultat, nil
+	}
+	evenement, err := json.Marshal(resultat)
+	if err != nil {
+		return nil, err
+	}
+	if err := emettreEvenement(ctx, EvenementRappelsEcheances, evenement); err != nil {
+		return nil, err
+	}
+	return resultat, nil
+}
+
+// Échéance encore à venir d'un enregistrement parcouru (nil si aucune)
+func rappelEcheance(typeEcheance string, valeur []byte, config *Configuration, aujourdhui string) (*RappelEcheance, error) {
+	switch typeEcheance {
+	case BalayagePromesses:
+		var promesse Promesse
+		if err := decoderEtat(valeur, &promesse); err != nil {
+			return nil, err
+		}
+		if promesse.Statut != PromesseActive || promesse.DateLimite < aujourdhui {
+			return nil, nil
+		}
+		return &RappelEcheance{Type: typeEcheance, Objet: promesse.Id, IdTitre: promesse.IdTitre, Echeance: promesse.DateLimite}, nil
+
+	case BalayageUsufruits:
+		var titre TitreFoncier
+		if err := decoderEtat(valeur, &titre); err != nil {
+			return nil, err
+		}
+		if titre.Usufruit == nil || titre.Usufruit.Echeance == "" || titre.Usufruit.Echeance < aujourdhui {
+			return nil, nil
+		}
+		return &RappelEcheance{Type: typeEcheance, Objet: titre.Id, IdTitre: titre.Id, Echeance: titre.Usufruit.Echeance}, nil
+
+	default:
+		var demande DemandeArchivage
+		if err := decoderEtat(valeur, &demande); err != nil {
+			return nil, err
+		}
+		if demande.Statut != ArchivageEnAttente {
+			return nil, nil
+		}
+		proposeLe, err := time.Parse(time.RFC3339, demande.ProposeLe)
+		if err != nil {
+			return nil, fmt.Errorf("horodatage de la demande d'archivage %s invalide: %v", demande.Id, err)
+		}
+		echeance := proposeLe.Add(time.Duration(config.DelaiConfirmationArchivage) * time.Hour).Format(FormatDate)
+		if echeance < aujourdhui {
+			return nil, nil
+		}
+		return &RappelEcheance{Type: typeEcheance, Objet: demande.Id, IdTitre: demande.IdTitre, Echeance: echeance}, nil
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Collection privée des bénéficiaires effectifs, partagée avec la cellule de renseignement financier
+const CollectionBeneficiaires = "beneficiairesEffectifs"
+
+// Préfixe des clés composites des déclarations de bénéficiaires (idTitre~txID)
+const PrefixeBeneficiaires = "BENEFICIAIRES"
+
+// Clé transitoire portant la liste JSON des bénéficiaires effectifs
+const CleTransitoireBeneficiaires = "beneficiaires"
+
+// Déclarer les bénéficiaires effectifs d'un titre, transmis sous la clé transitoire "beneficiaires" ;
+// réservé au titulaire du titre et aux notaires
+func (s *SmartContract) DeclarerBeneficiaireEffectif(ctx contractapi.TransactionContextInterface, idTitre string) error {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	// Un titre sans propriétaire inscrit n'est déclaré que par un notaire
+	if proprio == "" || proprio != titre.Proprio {
+		if err := verifierRole(ctx, RoleNotaire); err != nil {
+			return err
+		}
+	}
+
+	transitoire, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des données transitoires: %v", err)
+	}
+	saisie, ok := transitoire[CleTransitoireBeneficiaires]
+	if !ok {
+		return fmt.Errorf("bénéficiaires attendus sous la clé transitoire %q", CleTransitoireBeneficiaires)
+	}
+
+	declarant, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	declaration := &DeclarationBeneficiaires{
+		Id:         ctx.GetStub().GetTxID(),
+		IdTitre:    idTitre,
+		Titulaire:  titre.Proprio,
+		DeclarePar: declarant,
+		DeclareLe:  maintenant.Format(time.RFC3339),
+	}
+	if err := decoderJSON(saisie, &declaration.Beneficiaires); err != nil {
+		return fmt.Errorf("bénéficiaires invalides: %v", err)
+	}
+	if err := declaration.Valider(); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeBeneficiaires, []string{idTitre, declaration.Id})
+	if err != nil {
+		return err
+	}
+	return ecrirePrive(ctx, CollectionBeneficiaires, cle, declaration)
+}
+
+// Dernière déclaration des bénéficiaires effectifs d'un titre (réservé à la cellule de renseignement financier)
+func (s *SmartContract) LireBeneficiairesEffectifs(ctx contractapi.TransactionContextInterface, idTitre string) (*DeclarationBeneficiaires, error) {
+	if err := verifierMSP(ctx, MSPConformite); err != nil {
+		return nil, err
+	}
+
+	derniere, err := derniereDeclarationBeneficiaires(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if derniere == nil {
+		return nil, fmt.Errorf("aucun bénéficiaire effectif déclaré pour le titre foncier %s", idTitre)
+	}
+
+	return derniere, nil
+}
+
+// Historique des déclarations de bénéficiaires effectifs d'un titre (réservé à la cellule de renseignement financier)
+func (s *SmartContract) GetDeclarationsBeneficiaires(ctx contractapi.TransactionContextInterface, idTitre string) ([]*DeclarationBeneficiaires, error) {
+	if err := verifierMSP(ctx, MSPConformite); err != nil {
+		return nil, err
+	}
+	return declarationsBeneficiaires(ctx, idTitre)
+}
+
+// Dernière déclaration des bénéficiaires effectifs d'un titre (nil si aucune)
+func derniereDeclarationBeneficiaires(ctx contractapi.TransactionContextInterface, idTitre string) (*DeclarationBeneficiaires, error) {
+	declarations, err := declarationsBeneficiaires(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+
+	var derniere *DeclarationBeneficiaires
+	for _, declaration := range declarations {
+		if derniere == nil || declaration.DeclareLe > derniere.DeclareLe {
+			derniere = declaration
+		}
+	}
+	return derniere, nil
+}
+
+// Déclarations des bénéficiaires effectifs d'un titre, lues dans la collection privée
+func declarationsBeneficiaires(ctx contractapi.TransactionContextInterface, idTitre string) ([]*DeclarationBeneficiaires, error) {
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(CollectionBeneficiaires, PrefixeBeneficiaires, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var declarations []*DeclarationBeneficiaires
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var declaration DeclarationBeneficiaires
+		err = decoderJSON(queryResponse.Value, &declaration)
+		if err != nil {
+			return nil, err
+		}
+		declarations = append(declarations, &declaration)
+	}
+
+	return declarations, nil
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des rectifications de bornage (id)
+const PrefixeRectificationBornage = "BORNAGE"
+
+// Proposer, au vu du procès-verbal de bornage, la rectification de la limite commune de deux parcelles
+// (réservé aux géomètres) : la demande (DemandeBornage, JSON) donne le contour et la superficie
+// rectifiés de chacune. Les contours doivent rester contigus, sans surface commune ni chevauchement
+// d'une autre parcelle, chaque superficie concorder avec l'aire de son contour et leur total rester le
+// même ; la proposition vaut signature du géomètre. Les contours rectifiés prennent effet à la date du
+// bornage indiquée par la demande, et non à celle de la dernière signature.
+func (s *SmartContract) ProposerRectificationBornage(ctx contractapi.TransactionContextInterface, demande string) (*RectificationBornage, error) {
+	if err := verifierRole(ctx, RoleGeometre); err != nil {
+		return nil, err
+	}
+	var requete DemandeBornage
+	if err := json.Unmarshal([]byte(demande), &requete); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "demande de rectification de bornage invalide: %v", err)
+	}
+	if err := requete.Valider(); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rectification := &RectificationBornage{
+		Id:         ctx.GetStub().GetTxID(),
+		Statut:     BornagePropose,
+		TxId:       ctx.GetStub().GetTxID(),
+		Signatures: []SignatureBornage{},
+	}
+	for _, parcelle := range requete.Parcelles {
+		titre, err := s.LireTitreFoncier(ctx, parcelle.IdTitre)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifierBornable(titre); err != nil {
+			return nil, err
+		}
+		rectifiee := ParcelleRectifiee{
+			IdTitre:            titre.Id,
+			Proprio:            titre.Proprio,
+			Superficie:         parcelle.Superficie,
+			AncienneGeometrie:  titre.Geometrie,
+			AncienneSuperficie: titre.Superficie,
+		}
+		if rectifiee.Geometrie, rectifiee.SystemeCoordonnees, err = model.NormaliserGeometrie(string(parcelle.Geometrie), config.EmpriseTerritoire); err != nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "contour rectifié du titre foncier %s invalide: %v", titre.Id, err)
+		}
+		contours, err := model.ContoursGeoJSON(rectifiee.Geometrie)
+		if err != nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+		}
+		if aire := model.Superficie(contours); !model.SuperficieConcorde(rectifiee.Superficie, aire) {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "la superficie rectifiée du titre foncier %s (%d m²) ne concorde pas avec l'aire de son contour (%.0f m²)", titre.Id, rectifiee.Superficie, aire)
+		}
+		rectification.Parcelles = append(rectification.Parcelles, rectifiee)
+	}
+	// La limite commune se déplace d'une parcelle à l'autre : la surface des deux reste la même
+	avant := rectification.Parcelles[0].AncienneSuperficie + rectification.Parcelles[1].AncienneSuperficie
+	apres := rectification.Parcelles[0].Superficie + rectification.Parcelles[1].Superficie
+	if !model.SuperficieConcorde(apres, float64(avant)) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la rectification porte la superficie totale des deux parcelles de %d à %d m², le bornage doit la conserver", avant, apres)
+	}
+	if err := verifierContoursRectifies(ctx, rectification); err != nil {
+		return nil, err
+	}
+	if rectification.ProcesVerbal, err = s.nouveauDocument(ctx, requete.ProcesVerbal.Chemin, requete.ProcesVerbal.Issuer); err != nil {
+		return nil, err
+	}
+
+	geometre, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if requete.DateEffet > maintenant.Format(FormatDate) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la date du bornage (%s) ne peut être postérieure à la proposition", requete.DateEffet)
+	}
+	rectification.Geometre = geometre
+	rectification.DateEffet = requete.DateEffet
+	rectification.ProposeeLe = maintenant.Format(time.RFC3339)
+	rectification.Signatures = append(rectification.Signatures, SignatureBornage{
+		Qualite:    SignataireGeometre,
+		Signataire: geometre,
+		Identite:   geometre,
+		Horodatage: rectification.ProposeeLe,
+		TxId:       rectification.TxId,
+	})
+
+	if err := sauvegarderRectificationBornage(ctx, rectification); err != nil {
+		return nil, err
+	}
+	return rectification, nil
+}
+
+// Signer une rectification de bornage au nom du propriétaire représenté par l'appelant, pour chacune
+// des parcelles qu'il détient ; la signature du second propriétaire applique la rectification aux deux
+// titres dans la même transaction, procès-verbal rattaché
+func (s *SmartContract) SignerRectificationBornage(ctx contractapi.TransactionContextInterface, id string) (*RectificationBornage, error) {
+	rectification, err := s.LireRectificationBornage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rectification.Statut != BornagePropose {
+		return nil, fmt.Errorf("la rectification de bornage %s est déjà %s", id, rectification.Statut)
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signees := 0
+	for _, parcelle := range rectification.Parcelles {
+		if proprio == "" || parcelle.Proprio != proprio {
+			continue
+		}
+		if rectification.SigneePar(parcelle.IdTitre) {
+			return nil, fmt.Errorf("la rectification de bornage %s est déjà signée pour le titre foncier %s", id, parcelle.IdTitre)
+		}
+		rectification.Signatures = append(rectification.Signatures, SignatureBornage{
+			Qualite:    SignataireProprietaire,
+			IdTitre:    parcelle.IdTitre,
+			Signataire: proprio,
+			Identite:   identite,
+			Horodatage: maintenant.Format(time.RFC3339),
+			TxId:       ctx.GetStub().GetTxID(),
+		})
+		signees++
+	}
+	if signees == 0 {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'appelant n'est propriétaire d'aucune des parcelles de la rectification de bornage %s", id)
+	}
+
+	complete := true
+	for _, parcelle := range rectification.Parcelles {
+		complete = complete && rectification.SigneePar(parcelle.IdTitre)
+	}
+	if complete {
+		if err := appliquerRectificationBornage(s, ctx, rectification); err != nil {
+			return nil, err
+		}
+		rectification.Statut = BornageApplique
+		rectification.AppliqueeLe = maintenant.Format(time.RFC3339)
+		rectification.TxApplication = ctx.GetStub().GetTxID()
+	}
+
+	if err := sauvegarderRectificationBornage(ctx, rectification); err != nil {
+		return nil, err
+	}
+	return rectification, nil
+}
+
+// Refuser une rectification de bornage en attente de signatures, par le géomètre qui l'a proposée ou
+// par l'un des propriétaires ; le refus la clôt
+func (s *SmartContract) RefuserRectificationBornage(ctx contractapi.TransactionContextInterface, id string, motif string) (*RectificationBornage, error) {
+	if motif == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif du refus est obligatoire")
+	}
+	rectification, err := s.LireRectificationBornage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rectification.Statut != BornagePropose {
+		return nil, fmt.Errorf("la rectification de bornage %s est déjà %s", id, rectification.Statut)
+	}
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	partie := identite == rectification.Geometre
+	for _, parcelle := range rectification.Parcelles {
+		partie = partie || (proprio != "" && parcelle.Proprio == proprio)
+	}
+	if !partie {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'appelant n'est pas partie à la rectification de bornage %s", id)
+	}
+
+	rectification.Statut = BornageRefuse
+	rectification.Motif = motif
+	if err := sauvegarderRectificationBornage(ctx, rectification); err != nil {
+		return nil, err
+	}
+	return rectification, nil
+}
+
+// Attester le contour d'une parcelle tel qu'il figure au titre (réservé aux géomètres), au vu du plan
+// ou du procès-verbal de bornage rattaché au titre ; l'attestation tombe au dépôt d'un autre contour
+func (s *SmartContract) AttesterContour(ctx contractapi.TransactionContextInterface, idTitre string, chemin string, issuer string) (*TitreFoncier, error) {
+	if err := verifierRole(ctx, RoleGeometre); err != nil {
+		return nil, err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierEnVigueur(titre); err != nil {
+		return nil, err
+	}
+	if titre.Geometrie == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le titre foncier %s ne porte aucun contour à attester", idTitre)
+	}
+	document, err := s.nouveauDocument(ctx, chemin, issuer)
+	if err != nil {
+		return nil, err
+	}
+	geometre, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	titre.Documents = append(titre.Documents, document)
+	titre.GeometreContour = geometre
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return nil, err
+	}
+	return titre, nil
+}
+
+// Lire une rectification de bornage
+func (s *SmartContract) LireRectificationBornage(ctx contractapi.TransactionContextInterface, id string) (*RectificationBornage, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRectificationBornage, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	var rectification RectificationBornage
+	existe, err := lireEtat(ctx, cle, &rectification)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("rectification de bornage %s non trouvée", id)
+	}
+	return &rectification, nil
+}
+
+// Vérifier qu'une parcelle peut être rebornée : son titre est en vigueur, ni saisi ni démembré (le
+// bornage engage la propriété, à laquelle l'usufruitier n'est pas partie)
+func verifierBornable(titre *TitreFoncier) error {
+	if err := verifierEnVigueur(titre); err != nil {
+		return err
+	}
+	if titre.Usufruit != nil {
+		return nouvelleErreur(CodeTitreDemembre, "la propriété du titre foncier %s est démembrée, l'usufruitier %s n'est pas partie au bornage", titre.Id, titre.Usufruit.Usufruitier)
+	}
+	return verifierNonSaisi(titre)
+}
+
+// Vérifier qu'un titre est encore en vigueur sur le canal
+func verifierEnVigueur(titre *TitreFoncier) error {
+	if titre.MuteVers != "" {
+		return nouvelleErreur(CodeTitreMute, "le titre foncier %s est désormais administré sur le canal %s", titre.Id, titre.MuteVers)
+	}
+	if len(titre.MorceleEn) > 0 {
+		return nouvelleErreur(CodeTitreMorcele, "le titre foncier %s est clos, morcelé", titre.Id)
+	}
+	if titre.Realisation != "" {
+		return nouvelleErreur(CodeTitreEnRealisation, "le titre foncier %s fait l'objet de la réalisation de l'hypothèque %s", titre.Id, titre.Realisation)
+	}
+	return nil
+}
+
+// Vérifier que les contours rectifiés des deux parcelles restent contigus et n'empiètent sur aucune
+// autre parcelle en vigueur
+func verifierContoursRectifies(ctx contractapi.TransactionContextInterface, rectification *RectificationBornage) error {
+	a, b := rectification.Parcelles[0], rectification.Parcelles[1]
+	contoursA, err := model.ContoursGeoJSON(a.Geometrie)
+	if err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	contoursB, err := model.ContoursGeoJSON(b.Geometrie)
+	if err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	if model.Chevauchent(contoursA, contoursB) {
+		return nouvelleErreur(CodeParcelleChevauchee, "les contours rectifiés des titres fonciers %s et %s se chevauchent", a.IdTitre, b.IdTitre)
+	}
+	if !model.Contigues(contoursA, contoursB) {
+		return nouvelleErreur(CodeRequeteInvalide, "les contours rectifiés des titres fonciers %s et %s ne partagent aucune limite", a.IdTitre, b.IdTitre)
+	}
+	for _, parcelle := range rectification.Parcelles {
+		if err := verifierChevauchements(ctx, parcelle.Geometrie, a.IdTitre, b.IdTitre); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Porter les contours et superficies rectifiés sur les deux titres, et y rattacher le procès-verbal ;
+// chaque titre doit être resté tel qu'à la proposition (contour et propriétaire), et les parcelles
+// voisines immatriculées depuis ne doivent pas être atteintes
+func appliquerRectificationBornage(s *SmartContract, ctx contractapi.TransactionContextInterface, rectification *RectificationBornage) error {
+	if err := verifierContoursRectifies(ctx, rectification); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	procesVerbal := rectification.ProcesVerbal
+	procesVerbal.AjouteLe = maintenant.Format(FormatDate)
+	// Rectification proposée avant que la date du bornage soit demandée : elle prend effet à l'application
+	dateEffet := maintenant.Format(time.RFC3339)
+	if rectification.DateEffet != "" {
+		date, err := time.Parse(FormatDate, rectification.DateEffet)
+		if err != nil {
+			return err
+		}
+		dateEffet = date.Format(time.RFC3339)
+	}
+
+	for _, parcelle := range rectification.Parcelles {
+		titre, err := s.LireTitreFoncier(ctx, parcelle.IdTitre)
+		if err != nil {
+			return err
+		}
+		if err := verifierBornable(titre); err != nil {
+			return err
+		}
+		if titre.Geometrie != parcelle.AncienneGeometrie || titre.Proprio != parcelle.Proprio {
+			return fmt.Errorf("le titre foncier %s a été modifié depuis la proposition de la rectification de bornage %s", titre.Id, rectification.Id)
+		}
+
+		titre.Geometrie = parcelle.Geometrie
+		titre.SystemeCoordonnees = parcelle.SystemeCoordonnees
+		titre.GeometrieDepuis = dateEffet
+		titre.Superficie = parcelle.Superficie
+		titre.GeometreContour = rectification.Geometre
+		titre.Documents = append(titre.Documents, procesVerbal)
+		if err := titre.Valider(); err != nil {
+			return err
+		}
+		if err := sauvegarderTitre(ctx, titre); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sauvegarderRectificationBornage(ctx contractapi.TransactionContextInterface, rectification *RectificationBornage) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRectificationBornage, []string{rectification.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, rectification)
+}
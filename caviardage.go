@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+
+	"titrefoncier/pkg/model"
+)
+
+// Rôle désignant, dans les règles de caviardage, les identités enrôlées par la passerelle citoyenne
+const RoleCitoyen = "citoyen"
+
+// Caviarder la réponse d'une consultation selon les règles du canal applicables à l'appelant. Faute
+// de pouvoir appliquer une règle, la consultation échoue plutôt que de divulguer le champ.
+func caviarderReponse(stub shim.ChaincodeStubInterface, config *Configuration, operation string, reponse pb.Response) pb.Response {
+	if len(config.Caviardage) == 0 || reponse.Status >= shim.ERRORTHRESHOLD || !consultation(operation) || !json.Valid(reponse.Payload) {
+		return reponse
+	}
+	role, err := roleCaviardage(stub, config)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	regles := config.ReglesCaviardage(role, operation)
+	if len(regles) == 0 {
+		return reponse
+	}
+
+	contenu, err := model.CaviarderReponse(reponse.Payload, regles)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("caviardage de la réponse impossible: %v", err))
+	}
+	reponse.Payload = contenu
+	return reponse
+}
+
+// Rôle de l'appelant au regard du caviardage : RoleCitoyen pour une identité anonyme de la passerelle
+// citoyenne, dont l'attribut "role" n'est que le rôle MSP de la lettre de créance ; sinon son attribut
+// "role" s'il est délivré par l'organisation de ce rôle, ou RoleCitoyen pour une identité de la
+// passerelle. Un rôle non reconnu ne soustrait l'appelant à aucune règle.
+func roleCaviardage(stub shim.ChaincodeStubInterface, config *Configuration) (string, error) {
+	identite, err := cid.New(stub)
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	mspID, err := identite.GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID == MSPPasserelleIdemix {
+		return RoleCitoyen, nil
+	}
+	role, trouve, err := identite.GetAttributeValue("role")
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if trouve && roleReconnu(config, role, mspID) {
+		return role, nil
+	}
+	if mspID == MSPPasserelle {
+		return RoleCitoyen, nil
+	}
+	return "", nil
+}
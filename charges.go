@@ -0,0 +1,64 @@
+package main
+
+import "sort"
+
+// Inscrire une charge sur un titre
+func ajouterCharge(titre *TitreFoncier, charge Charge) {
+	titre.Charges = append(titre.Charges, charge)
+}
+
+// Radier les charges d'un type donné issues d'un acte ; indique si une charge a été radiée
+func retirerCharge(titre *TitreFoncier, typeCharge string, reference string) bool {
+	charges := titre.Charges[:0]
+	for _, charge := range titre.Charges {
+		if charge.Type == typeCharge && charge.Reference == reference {
+			continue
+		}
+		charges = append(charges, charge)
+	}
+	radiee := len(charges) != len(titre.Charges)
+	titre.Charges = charges
+	return radiee
+}
+
+// Promesse de vente en vigueur au profit d'un bénéficiaire (nil si aucune)
+func promesseAuProfitDe(titre *TitreFoncier, beneficiaire string, date string) *Charge {
+	for i := range titre.Charges {
+		charge := &titre.Charges[i]
+		if charge.Type == ChargePromesseVente && charge.Beneficiaire == beneficiaire && !charge.EstEchue(date) {
+			return charge
+		}
+	}
+	return nil
+}
+
+// Vérifier qu'aucune promesse de vente en vigueur ne réserve le titre à un autre acte que celui indiqué
+func verifierLibreDePromesse(titre *TitreFoncier, reference string, date string) error {
+	for _, charge := range titre.Charges {
+		if charge.Type == ChargePromesseVente && charge.Reference != reference && !charge.EstEchue(date) {
+			return nouvelleErreur(CodeTitreSousPromesse, "le titre foncier %s est réservé à %s par l'acte %s", titre.Id, charge.Beneficiaire, charge.Reference)
+		}
+	}
+	return nil
+}
+
+// Hypothèques inscrites sur un titre, dans l'ordre de leur rang
+func hypothequesOrdonnees(titre *TitreFoncier) []*Charge {
+	var hypotheques []*Charge
+	for i := range titre.Charges {
+		if titre.Charges[i].Type == ChargeHypotheque {
+			hypotheques = append(hypotheques, &titre.Charges[i])
+		}
+	}
+	sort.SliceStable(hypotheques, func(i, j int) bool {
+		return hypotheques[i].Rang < hypotheques[j].Rang
+	})
+	return hypotheques
+}
+
+// Attribuer des rangs consécutifs aux hypothèques, dans l'ordre donné
+func renumeroterHypotheques(hypotheques []*Charge) {
+	for i, hypotheque := range hypotheques {
+		hypotheque.Rang = i + 1
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Tuiles de l'index spatial d'un titre : seules les parcelles en vigueur, au contour lisible, y figurent
+func tuilesTitre(titre *TitreFoncier) []string {
+	if titre.Geometrie == "" || len(titre.MorceleEn) > 0 || titre.MuteVers != "" {
+		return nil
+	}
+	contours, err := model.ContoursGeoJSON(titre.Geometrie)
+	if err != nil {
+		return nil
+	}
+	return model.TuilesGeohash(contours)
+}
+
+// Parcelles en vigueur dont le contour chevauche une géométrie proposée (GeoJSON Polygon ou
+// MultiPolygon, en WGS84 ou en UTM 28N), par exemple avant une immatriculation ou un détachement ;
+// les parcelles qui ne font que partager une limite avec elle ne sont pas retenues
+func (s *SmartContract) DetecterChevauchements(ctx contractapi.TransactionContextInterface, geometrie string) ([]string, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if geometrie, _, err = model.NormaliserGeometrie(geometrie, config.EmpriseTerritoire); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	contours, err := model.ContoursGeoJSON(geometrie)
+	if err != nil {
+		return nil, err
+	}
+	return titresChevauchant(ctx, contours)
+}
+
+// Rejeter une géométrie qui chevauche une parcelle en vigueur, hors les titres exclus (titre mère
+// d'un détachement)
+func verifierChevauchements(ctx contractapi.TransactionContextInterface, geometrie string, exclus ...string) error {
+	contours, err := model.ContoursGeoJSON(geometrie)
+	if err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	ids, err := titresChevauchant(ctx, contours)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		exclu := false
+		for _, e := range exclus {
+			exclu = exclu || e == id
+		}
+		if !exclu {
+			return nouvelleErreur(CodeParcelleChevauchee, "la parcelle chevauche le titre foncier %s", id)
+		}
+	}
+	return nil
+}
+
+// Titres dont le contour chevauche une géométrie : les candidats sont ceux de l'index spatial, puis
+// leurs contours sont comparés
+func titresChevauchant(ctx contractapi.TransactionContextInterface, contours [][]model.Point) ([]string, error) {
+	candidats, err := candidatsGeohash(ctx, PrefixeIndexGeohash, contours)
+	if err != nil {
+		return nil, err
+	}
+
+	titres := []string{}
+	for _, id := range candidats {
+		var titre TitreFoncier
+		existe, err := lireEtat(ctx, id, &titre)
+		if err != nil {
+			return nil, err
+		}
+		if !existe || titre.Geometrie == "" || len(titre.MorceleEn) > 0 || titre.MuteVers != "" {
+			continue
+		}
+		parcelle, err := model.ContoursGeoJSON(titre.Geometrie)
+		if err != nil {
+			continue
+		}
+		if model.Chevauchent(parcelle, contours) {
+			titres = append(titres, id)
+		}
+	}
+	return titres, nil
+}
+
+// Identifiants, triés, des entrées d'un index spatial dont une tuile contient une tuile de la géométrie
+// ou y est contenue
+func candidatsGeohash(ctx contractapi.TransactionContextInterface, prefixe string, contours [][]model.Point) ([]string, error) {
+	candidats := map[string]bool{}
+	for _, tuile := range model.TuilesGeohash(contours) {
+		caracteres := strings.Split(tuile, "")
+		// Tuiles de même précision ou plus fines
+		ids, err := titresIndexes(ctx, prefixe, caracteres, 0)
+		if err != nil {
+			return nil, err
+		}
+		// Tuiles plus grossières, désignées exactement par leur attribut de fin
+		for n := 1; n < len(caracteres); n++ {
+			englobants, err := titresIndexes(ctx, prefixe, append(caracteres[:n:n], ""), 0)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, englobants...)
+		}
+		for _, id := range ids {
+			candidats[id] = true
+		}
+	}
+
+	ids := make([]string, 0, len(candidats))
+	for id := range candidats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
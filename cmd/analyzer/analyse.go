@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Action recommandée sur une clé chaude
+const RecommandationSeparer = "SEPARER_DOCUMENTS" // Documents nombreux encore enregistrés dans l'état du titre
+
+// Clé dont l'historique ou l'état dépasse les seuils
+type CleChaude struct {
+	model.MesureHistorique
+	Recommandation string `json:"recommandation,omitempty"`
+	Separee        bool   `json:"separee,omitempty"` // SeparerDocuments a été soumis
+}
+
+// Rapport d'analyse de l'historique
+type Rapport struct {
+	GenereLe        string      `json:"genereLe"`
+	TitresAnalyses  int         `json:"titresAnalyses"`
+	VersionsTotales int         `json:"versionsTotales"`
+	TailleCumulee   int         `json:"tailleCumulee"` // Octets de toutes les versions de tous les titres
+	ClesChaudes     []CleChaude `json:"clesChaudes"`   // Par taille cumulée décroissante
+}
+
+// Analyse de l'historique des titres du registre
+type analyseur struct {
+	registre       *client.Registre
+	page           int
+	seuilVersions  int
+	seuilTaille    int
+	seuilDocuments int
+	top            int
+	separer        bool // Soumettre SeparerDocuments là où la séparation est recommandée
+}
+
+func (a *analyseur) executer() (*Rapport, error) {
+	rapport := &Rapport{GenereLe: time.Now().UTC().Format(time.RFC3339), ClesChaudes: []CleChaude{}}
+
+	signet := ""
+	for {
+		contenu, err := a.registre.Evaluer("MesurerHistoriques", strconv.Itoa(a.page), signet)
+		if err != nil {
+			return nil, err
+		}
+		var page model.PageMesuresHistorique
+		if err := json.Unmarshal(contenu, &page); err != nil {
+			return nil, fmt.Errorf("réponse MesurerHistoriques invalide: %v", err)
+		}
+
+		for _, mesure := range page.Mesures {
+			rapport.TitresAnalyses++
+			rapport.VersionsTotales += mesure.Versions
+			rapport.TailleCumulee += mesure.TailleCumulee
+			if cle, chaude := a.evaluer(mesure); chaude {
+				rapport.ClesChaudes = append(rapport.ClesChaudes, cle)
+			}
+		}
+		if page.Signet == "" {
+			break
+		}
+		signet = page.Signet
+	}
+
+	sort.SliceStable(rapport.ClesChaudes, func(i, j int) bool {
+		return rapport.ClesChaudes[i].TailleCumulee > rapport.ClesChaudes[j].TailleCumulee
+	})
+	if a.top > 0 && len(rapport.ClesChaudes) > a.top {
+		rapport.ClesChaudes = rapport.ClesChaudes[:a.top]
+	}
+
+	if a.separer {
+		for i := range rapport.ClesChaudes {
+			cle := &rapport.ClesChaudes[i]
+			if cle.Recommandation != RecommandationSeparer {
+				continue
+			}
+			if _, err := a.registre.Soumettre(context.Background(), "SeparerDocuments", cle.Cle); err != nil {
+				slog.Warn("séparation des documents impossible", slog.String("cle", cle.Cle), slog.Any("erreur", err))
+				continue
+			}
+			cle.Separee = true
+		}
+	}
+
+	return rapport, nil
+}
+
+// Une clé est chaude au-delà du seuil de versions ou de taille de son état
+func (a *analyseur) evaluer(mesure *model.MesureHistorique) (CleChaude, bool) {
+	cle := CleChaude{MesureHistorique: *mesure}
+	if mesure.Versions < a.seuilVersions && mesure.TailleCourante < a.seuilTaille {
+		return cle, false
+	}
+	if !mesure.DocumentsSepares && mesure.Documents >= a.seuilDocuments {
+		cle.Recommandation = RecommandationSeparer
+	}
+	return cle, true
+}
@@ -0,0 +1,71 @@
+// Commande analyzer : mesure l'historique des titres du registre, signale les clés dont les
+// versions s'accumulent ou dont l'état grossit, et peut en séparer les documents.
+//
+//	analyzer -canal dakar -seuil-versions 200 -separer -pair peer0.dakar:7051 \
+//	  -tls-ca /etc/hyperledger/peer-ca.pem -msp EtatMSP -msp-config /etc/titrefoncier/analyse/msp
+//
+// Les transactions passent par le service Gateway du pair, sous l'identité -msp ; pair et identité
+// valent par défaut les variables CORE_PEER_* de la CLI peer.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+
+	"titrefoncier/pkg/client"
+)
+
+func main() {
+	options := client.DeclarerOptions(flag.CommandLine)
+	options.DeclarerIdentite(flag.CommandLine)
+	page := flag.Int("page", 100, "titres mesurés par appel")
+	seuilVersions := flag.Int("seuil-versions", 100, "nombre de versions à partir duquel une clé est signalée")
+	seuilTaille := flag.Int("seuil-taille", 64<<10, "taille de l'état (octets) à partir de laquelle une clé est signalée")
+	seuilDocuments := flag.Int("seuil-documents", 20, "nombre de documents à partir duquel leur séparation est recommandée")
+	top := flag.Int("top", 50, "nombre maximal de clés rapportées (0 : toutes)")
+	separer := flag.Bool("separer", false, "soumettre SeparerDocuments pour chaque clé où la séparation est recommandée")
+	sortie := flag.String("rapport", "", "fichier du rapport JSON (sortie standard par défaut)")
+	flag.Parse()
+
+	passerelle, registre, err := options.Registre()
+	if err != nil {
+		slog.Error("connexion au registre impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	defer passerelle.Fermer()
+
+	a := &analyseur{
+		registre:       registre,
+		page:           *page,
+		seuilVersions:  *seuilVersions,
+		seuilTaille:    *seuilTaille,
+		seuilDocuments: *seuilDocuments,
+		top:            *top,
+		separer:        *separer,
+	}
+
+	rapport, err := a.executer()
+	if err != nil {
+		slog.Error("analyse interrompue", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	destination := os.Stdout
+	if *sortie != "" {
+		if destination, err = os.Create(*sortie); err != nil {
+			slog.Error("création du rapport impossible", slog.Any("erreur", err))
+			os.Exit(1)
+		}
+		defer destination.Close()
+	}
+	encodeur := json.NewEncoder(destination)
+	encodeur.SetIndent("", "  ")
+	if err := encodeur.Encode(rapport); err != nil {
+		slog.Error("écriture du rapport impossible", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	slog.Info("analyse terminée", slog.Int("titres", rapport.TitresAnalyses), slog.Int("clesChaudes", len(rapport.ClesChaudes)))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Portées des clés d'API, de la plus restreinte à la plus large : chacune comprend les précédentes
+const (
+	PorteeVerification = "verification" // Vérifier un document ou une attestation
+	PorteeLecture      = "lecture"      // Consulter titres, dossiers et transferts
+	PorteeRegistraire  = "registraire"  // Immatriculer et céder, comme un conservateur
+)
+
+var rangsPortee = map[string]int{
+	PorteeVerification: 1,
+	PorteeLecture:      2,
+	PorteeRegistraire:  3,
+}
+
+// Compte de service d'un intégrateur : sa clé d'API n'est connue de la passerelle que par son empreinte
+type compteService struct {
+	Id        string `json:"id"`        // Intégrateur (ex: "banque-atlantique")
+	Empreinte string `json:"empreinte"` // SHA-256 hexadécimal de la clé d'API
+	Portee    string `json:"portee"`    // verification, lecture ou registraire
+	Identite  string `json:"identite"`  // Identité Fabric déclarée sous laquelle ses appels sont soumis
+
+	// Périmètre des événements poussés à ses webhooks, hors portée registraire : titres désignés, de
+	// ses communes ou de son propriétaire, et que son identité Fabric est autorisée à consulter
+	Titres   []string `json:"titres,omitempty"`
+	Communes []string `json:"communes,omitempty"`
+	Proprio  string   `json:"proprio,omitempty"`
+}
+
+// Indiquer si un titre relève du périmètre d'événements du compte
+func (c *compteService) suit(titre *model.TitreFoncier) bool {
+	return slices.Contains(c.Titres, titre.Id) || slices.Contains(c.Communes, titre.Commune) || (c.Proprio != "" && c.Proprio == titre.Proprio)
+}
+
+// Fichier des comptes de service de la passerelle
+type configurationComptes struct {
+	Identites map[string]client.Identite `json:"identites"`
+	Comptes   []compteService            `json:"comptes"`
+}
+
+func chargerComptes(fichier string) (*configurationComptes, error) {
+	contenu, err := os.ReadFile(fichier)
+	if err != nil {
+		return nil, err
+	}
+	var config configurationComptes
+	if err := json.Unmarshal(contenu, &config); err != nil {
+		return nil, fmt.Errorf("fichier des comptes %s invalide: %v", fichier, err)
+	}
+	if err := config.valider(); err != nil {
+		return nil, fmt.Errorf("fichier des comptes %s: %v", fichier, err)
+	}
+	return &config, nil
+}
+
+// Une identité Fabric ne sert qu'à des comptes d'une même portée : un intégrateur en lecture
+// n'obtient jamais les droits de l'identité d'un registraire
+func (c *configurationComptes) valider() error {
+	ids := make(map[string]bool)
+	empreintes := make(map[string]bool)
+	portees := make(map[string]string)
+	for _, compte := range c.Comptes {
+		if compte.Id == "" || ids[compte.Id] {
+			return fmt.Errorf("les comptes doivent être identifiés et distincts (%q)", compte.Id)
+		}
+		ids[compte.Id] = true
+		if empreinte, err := hex.DecodeString(compte.Empreinte); err != nil || len(empreinte) != sha256.Size {
+			return fmt.Errorf("empreinte SHA-256 invalide pour le compte %s", compte.Id)
+		}
+		if empreintes[compte.Empreinte] {
+			return fmt.Errorf("la clé du compte %s est déjà attribuée", compte.Id)
+		}
+		empreintes[compte.Empreinte] = true
+		if rangsPortee[compte.Portee] == 0 {
+			return fmt.Errorf("portée inconnue pour le compte %s: %q", compte.Id, compte.Portee)
+		}
+		if _, declaree := c.Identites[compte.Identite]; !declaree {
+			return fmt.Errorf("identité non déclarée pour le compte %s: %q", compte.Id, compte.Identite)
+		}
+		if portee, utilisee := portees[compte.Identite]; utilisee && portee != compte.Portee {
+			return fmt.Errorf("l'identité %s sert déjà des comptes de portée %s", compte.Identite, portee)
+		}
+		portees[compte.Identite] = compte.Portee
+	}
+	return nil
+}
+
+// Compte titulaire d'une clé d'API présentée (nil si inconnue)
+func (c *configurationComptes) compte(cle string) *compteService {
+	empreinte := sha256.Sum256([]byte(cle))
+	for i := range c.Comptes {
+		if c.Comptes[i].Empreinte == hex.EncodeToString(empreinte[:]) {
+			return &c.Comptes[i]
+		}
+	}
+	return nil
+}
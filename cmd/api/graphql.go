@@ -0,0 +1,838 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"titrefoncier/pkg/client"
+)
+
+// Sous-ensemble de GraphQL pris en charge par la passerelle : requêtes (query) avec alias,
+// arguments, variables et fragments nommés ou en ligne. Les mutations, souscriptions, directives
+// et l'introspection ne le sont pas ; le schéma est publié en SDL sur GET /graphql/schema.
+
+// Limites d'une requête : profondeur des sélections et consultations du registre
+const (
+	ProfondeurGraphQLMax    = 8
+	ConsultationsGraphQLMax = 200
+)
+
+// Type objet du schéma et ses champs, dans l'ordre de leur déclaration
+type typeGraphQL struct {
+	nom    string
+	champs []*champGraphQL
+}
+
+func (t *typeGraphQL) champ(nom string) *champGraphQL {
+	for _, c := range t.champs {
+		if c.nom == nom {
+			return c
+		}
+	}
+	return nil
+}
+
+// Champ d'un type : la portée de clé d'API qui l'autorise et sa résolution depuis l'objet parent
+type champGraphQL struct {
+	nom       string
+	arguments []argumentGraphQL
+	sdl       string       // Type du champ en SDL (ex: "[Document!]!")
+	objet     *typeGraphQL // Type des valeurs si le champ n'est pas scalaire
+	portee    string
+	resoudre  func(x *execution, parent interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+type argumentGraphQL struct {
+	nom string
+	sdl string // "String!", "Int", ...
+}
+
+// Schéma en SDL, types dans l'ordre où la racine les atteint
+func schemaSDL(racine *typeGraphQL) string {
+	var sdl strings.Builder
+	vus := map[string]bool{}
+	var ecrire func(t *typeGraphQL)
+	ecrire = func(t *typeGraphQL) {
+		if vus[t.nom] {
+			return
+		}
+		vus[t.nom] = true
+		fmt.Fprintf(&sdl, "type %s {\n", t.nom)
+		for _, c := range t.champs {
+			arguments := make([]string, len(c.arguments))
+			for i, a := range c.arguments {
+				arguments[i] = a.nom + ": " + a.sdl
+			}
+			signature := c.nom
+			if len(arguments) > 0 {
+				signature += "(" + strings.Join(arguments, ", ") + ")"
+			}
+			fmt.Fprintf(&sdl, "  %s: %s @portee(requise: %q)\n", signature, c.sdl, c.portee)
+		}
+		sdl.WriteString("}\n\n")
+		for _, c := range t.champs {
+			if c.objet != nil {
+				ecrire(c.objet)
+			}
+		}
+	}
+	sdl.WriteString("directive @portee(requise: String!) on FIELD_DEFINITION\n\n")
+	ecrire(racine)
+	return strings.TrimSuffix(sdl.String(), "\n")
+}
+
+// Requête GraphQL, telle que la transmettent les clients HTTP
+type requeteGraphQL struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type reponseGraphQL struct {
+	Data   *objetGraphQL   `json:"data,omitempty"`
+	Errors []erreurGraphQL `json:"errors,omitempty"`
+}
+
+type erreurGraphQL struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Objet de réponse, dont les clés suivent l'ordre de la sélection
+type objetGraphQL struct {
+	cles    []string
+	valeurs map[string]interface{}
+}
+
+func (o *objetGraphQL) definir(cle string, valeur interface{}) {
+	if _, existe := o.valeurs[cle]; !existe {
+		o.cles = append(o.cles, cle)
+	}
+	o.valeurs[cle] = valeur
+}
+
+func (o *objetGraphQL) MarshalJSON() ([]byte, error) {
+	var tampon bytes.Buffer
+	tampon.WriteByte('{')
+	for i, cle := range o.cles {
+		if i > 0 {
+			tampon.WriteByte(',')
+		}
+		nom, _ := json.Marshal(cle)
+		valeur, err := json.Marshal(o.valeurs[cle])
+		if err != nil {
+			return nil, err
+		}
+		tampon.Write(nom)
+		tampon.WriteByte(':')
+		tampon.Write(valeur)
+	}
+	tampon.WriteByte('}')
+	return tampon.Bytes(), nil
+}
+
+// Exécution d'une requête pour un compte ; les erreurs de champ sont cumulées, le champ valant null
+type execution struct {
+	compte        *compteService
+	variables     map[string]interface{}
+	fragments     map[string]*fragmentGraphQL
+	erreurs       []erreurGraphQL
+	consultations int
+	registre      *client.Registre // Registre joint sous l'identité du compte
+}
+
+// Compter une consultation du registre, au plus ConsultationsGraphQLMax par requête
+func (x *execution) consulter() error {
+	x.consultations++
+	if x.consultations > ConsultationsGraphQLMax {
+		return fmt.Errorf("la requête dépasse %d consultations du registre", ConsultationsGraphQLMax)
+	}
+	return nil
+}
+
+// Exécuter une requête sur le type racine
+func executerGraphQL(racine *typeGraphQL, requete requeteGraphQL, compte *compteService, registre *client.Registre) *reponseGraphQL {
+	document, err := analyserGraphQL(requete.Query)
+	if err != nil {
+		return &reponseGraphQL{Errors: []erreurGraphQL{{Message: err.Error()}}}
+	}
+	operation, err := document.operationNommee(requete.OperationName)
+	if err != nil {
+		return &reponseGraphQL{Errors: []erreurGraphQL{{Message: err.Error()}}}
+	}
+
+	variables := map[string]interface{}{}
+	for nom, definition := range operation.variables {
+		valeur, fournie := requete.Variables[nom]
+		switch {
+		case fournie:
+			variables[nom] = valeur
+		case definition.defaut != nil:
+			variables[nom] = definition.defaut
+		case strings.HasSuffix(definition.sdl, "!"):
+			return &reponseGraphQL{Errors: []erreurGraphQL{{Message: "variable requise: $" + nom}}}
+		}
+	}
+
+	x := &execution{compte: compte, variables: variables, fragments: document.fragments, registre: registre}
+	if err := x.valider(racine, operation.selections, 1, map[string]bool{}); err != nil {
+		return &reponseGraphQL{Errors: []erreurGraphQL{{Message: err.Error()}}}
+	}
+	data := x.executerObjet(racine, nil, operation.selections, nil)
+	return &reponseGraphQL{Data: data, Errors: x.erreurs}
+}
+
+// Champ demandé sous une clé de réponse ; les sélections de même clé sont fusionnées
+type champDemande struct {
+	cle        string
+	nom        string
+	arguments  map[string]interface{}
+	selections []*selectionGraphQL
+}
+
+// Champs demandés d'un type, fragments développés
+func (x *execution) collecter(t *typeGraphQL, selections []*selectionGraphQL, demandes []*champDemande) []*champDemande {
+	for _, s := range selections {
+		switch {
+		case s.fragment != "":
+			if f := x.fragments[s.fragment]; f != nil && f.condition == t.nom {
+				demandes = x.collecter(t, f.selections, demandes)
+			}
+		case s.nom == "":
+			if s.condition == "" || s.condition == t.nom {
+				demandes = x.collecter(t, s.selections, demandes)
+			}
+		default:
+			cle := s.alias
+			if cle == "" {
+				cle = s.nom
+			}
+			fusionne := false
+			for _, d := range demandes {
+				if d.cle == cle {
+					d.selections = append(d.selections[:len(d.selections):len(d.selections)], s.selections...)
+					fusionne = true
+				}
+			}
+			if !fusionne {
+				demandes = append(demandes, &champDemande{cle: cle, nom: s.nom, arguments: s.arguments, selections: s.selections})
+			}
+		}
+	}
+	return demandes
+}
+
+// Vérifier la requête sur le schéma avant toute consultation : champs et arguments connus,
+// sélections présentes sur les seuls objets, profondeur et fragments bornés
+func (x *execution) valider(t *typeGraphQL, selections []*selectionGraphQL, profondeur int, fragmentsEnCours map[string]bool) error {
+	if profondeur > ProfondeurGraphQLMax {
+		return fmt.Errorf("la requête dépasse %d niveaux de sélection", ProfondeurGraphQLMax)
+	}
+	for _, s := range selections {
+		switch {
+		case s.fragment != "":
+			f := x.fragments[s.fragment]
+			if f == nil {
+				return fmt.Errorf("fragment inconnu: %s", s.fragment)
+			}
+			if fragmentsEnCours[s.fragment] {
+				return fmt.Errorf("le fragment %s se contient lui-même", s.fragment)
+			}
+			fragmentsEnCours[s.fragment] = true
+			if err := x.valider(t, f.selections, profondeur, fragmentsEnCours); err != nil {
+				return err
+			}
+			delete(fragmentsEnCours, s.fragment)
+		case s.nom == "":
+			if err := x.valider(t, s.selections, profondeur, fragmentsEnCours); err != nil {
+				return err
+			}
+		case s.nom == "__typename":
+		default:
+			c := t.champ(s.nom)
+			if c == nil {
+				return fmt.Errorf("champ inconnu sur %s: %s", t.nom, s.nom)
+			}
+			for nom := range s.arguments {
+				if !c.accepte(nom) {
+					return fmt.Errorf("argument inconnu sur %s.%s: %s", t.nom, s.nom, nom)
+				}
+			}
+			for _, a := range c.arguments {
+				if _, fourni := s.arguments[a.nom]; !fourni && strings.HasSuffix(a.sdl, "!") {
+					return fmt.Errorf("argument requis sur %s.%s: %s", t.nom, s.nom, a.nom)
+				}
+			}
+			if c.objet == nil && len(s.selections) > 0 {
+				return fmt.Errorf("le champ %s.%s est scalaire et n'admet pas de sélection", t.nom, s.nom)
+			}
+			if c.objet != nil {
+				if len(s.selections) == 0 {
+					return fmt.Errorf("le champ %s.%s exige une sélection", t.nom, s.nom)
+				}
+				if err := x.valider(c.objet, s.selections, profondeur+1, fragmentsEnCours); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *champGraphQL) accepte(argument string) bool {
+	for _, a := range c.arguments {
+		if a.nom == argument {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *execution) executerObjet(t *typeGraphQL, parent interface{}, selections []*selectionGraphQL, chemin []interface{}) *objetGraphQL {
+	objet := &objetGraphQL{valeurs: map[string]interface{}{}}
+	for _, d := range x.collecter(t, selections, nil) {
+		if d.nom == "__typename" {
+			objet.definir(d.cle, t.nom)
+			continue
+		}
+		cheminChamp := append(chemin[:len(chemin):len(chemin)], d.cle)
+		c := t.champ(d.nom)
+		if rangsPortee[x.compte.Portee] < rangsPortee[c.portee] {
+			x.erreurs = append(x.erreurs, erreurGraphQL{Message: fmt.Sprintf("la portée %s ne donne pas accès à %s.%s (%s requise)", x.compte.Portee, t.nom, c.nom, c.portee), Path: cheminChamp})
+			objet.definir(d.cle, nil)
+			continue
+		}
+		valeur, err := c.resoudre(x, parent, x.argumentsEvalues(d.arguments))
+		if err != nil {
+			message := err.Error()
+			var erreur *client.Erreur
+			if errors.As(err, &erreur) {
+				_, message = echecPublic(erreur)
+			}
+			x.erreurs = append(x.erreurs, erreurGraphQL{Message: message, Path: cheminChamp})
+			objet.definir(d.cle, nil)
+			continue
+		}
+		objet.definir(d.cle, x.completer(c, valeur, d.selections, cheminChamp))
+	}
+	return objet
+}
+
+// Valeur de réponse d'un champ : les objets, seuls ou en liste, sont exécutés sur leur sélection
+func (x *execution) completer(c *champGraphQL, valeur interface{}, selections []*selectionGraphQL, chemin []interface{}) interface{} {
+	if c.objet == nil || valeur == nil {
+		return valeur
+	}
+	if liste, ok := valeur.([]interface{}); ok {
+		elements := make([]interface{}, len(liste))
+		for i, element := range liste {
+			elements[i] = x.executerObjet(c.objet, element, selections, append(chemin[:len(chemin):len(chemin)], i))
+		}
+		return elements
+	}
+	return x.executerObjet(c.objet, valeur, selections, chemin)
+}
+
+// Arguments d'un champ, variables substituées
+func (x *execution) argumentsEvalues(arguments map[string]interface{}) map[string]interface{} {
+	valeurs := make(map[string]interface{}, len(arguments))
+	for nom, valeur := range arguments {
+		if v, ok := valeur.(variableGraphQL); ok {
+			valeurs[nom] = x.variables[string(v)]
+			continue
+		}
+		valeurs[nom] = valeur
+	}
+	return valeurs
+}
+
+// Argument chaîne d'un champ
+func argumentChaine(args map[string]interface{}, nom string) (string, error) {
+	valeur, ok := args[nom].(string)
+	if !ok {
+		return "", fmt.Errorf("l'argument %s doit être une chaîne", nom)
+	}
+	return valeur, nil
+}
+
+// Argument entier d'un champ, défaut s'il est absent ; les variables JSON arrivent en float64
+func argumentEntier(args map[string]interface{}, nom string, defaut int) (int, error) {
+	switch valeur := args[nom].(type) {
+	case nil:
+		return defaut, nil
+	case int:
+		return valeur, nil
+	case float64:
+		if valeur == float64(int(valeur)) {
+			return int(valeur), nil
+		}
+	}
+	return 0, fmt.Errorf("l'argument %s doit être un entier", nom)
+}
+
+// Document analysé : opérations et fragments nommés
+type documentGraphQL struct {
+	operations []*operationGraphQL
+	fragments  map[string]*fragmentGraphQL
+}
+
+type operationGraphQL struct {
+	nom        string
+	variables  map[string]definitionVariable
+	selections []*selectionGraphQL
+}
+
+type definitionVariable struct {
+	sdl    string
+	defaut interface{}
+}
+
+type fragmentGraphQL struct {
+	condition  string
+	selections []*selectionGraphQL
+}
+
+// Champ (nom renseigné), développement d'un fragment nommé (fragment) ou fragment en ligne
+type selectionGraphQL struct {
+	alias      string
+	nom        string
+	arguments  map[string]interface{}
+	selections []*selectionGraphQL
+	fragment   string
+	condition  string
+}
+
+// Référence à une variable, dans la valeur d'un argument
+type variableGraphQL string
+
+func (d *documentGraphQL) operationNommee(nom string) (*operationGraphQL, error) {
+	if nom == "" {
+		if len(d.operations) != 1 {
+			return nil, fmt.Errorf("operationName requis : le document contient %d opérations", len(d.operations))
+		}
+		return d.operations[0], nil
+	}
+	for _, o := range d.operations {
+		if o.nom == nom {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("opération inconnue: %s", nom)
+}
+
+// Lexème : nom, chaîne, nombre ou ponctuation
+type jetonGraphQL struct {
+	genre    byte // 'n' nom, 's' chaîne, 'i' entier, 'f' flottant, 'p' ponctuation, 0 fin
+	valeur   string
+	position int
+}
+
+type analyseurGraphQL struct {
+	source string
+	i      int
+	jeton  jetonGraphQL
+}
+
+func analyserGraphQL(source string) (*documentGraphQL, error) {
+	a := &analyseurGraphQL{source: source}
+	if err := a.avancer(); err != nil {
+		return nil, err
+	}
+	document := &documentGraphQL{fragments: map[string]*fragmentGraphQL{}}
+	for a.jeton.genre != 0 {
+		switch {
+		case a.est('p', "{"):
+			selections, err := a.selections()
+			if err != nil {
+				return nil, err
+			}
+			document.operations = append(document.operations, &operationGraphQL{selections: selections})
+		case a.est('n', "query"):
+			operation, err := a.operation()
+			if err != nil {
+				return nil, err
+			}
+			document.operations = append(document.operations, operation)
+		case a.est('n', "fragment"):
+			if err := a.avancer(); err != nil {
+				return nil, err
+			}
+			nom, err := a.nom()
+			if err != nil {
+				return nil, err
+			}
+			if err := a.attendre('n', "on"); err != nil {
+				return nil, err
+			}
+			condition, err := a.nom()
+			if err != nil {
+				return nil, err
+			}
+			selections, err := a.selections()
+			if err != nil {
+				return nil, err
+			}
+			if _, existe := document.fragments[nom]; existe {
+				return nil, fmt.Errorf("fragment défini deux fois: %s", nom)
+			}
+			document.fragments[nom] = &fragmentGraphQL{condition: condition, selections: selections}
+		case a.jeton.genre == 'n' && (a.jeton.valeur == "mutation" || a.jeton.valeur == "subscription"):
+			return nil, fmt.Errorf("opération %s non prise en charge : la passerelle GraphQL est en lecture seule", a.jeton.valeur)
+		default:
+			return nil, a.erreur("définition attendue")
+		}
+	}
+	if len(document.operations) == 0 {
+		return nil, fmt.Errorf("le document ne contient aucune opération")
+	}
+	return document, nil
+}
+
+func (a *analyseurGraphQL) operation() (*operationGraphQL, error) {
+	if err := a.avancer(); err != nil {
+		return nil, err
+	}
+	operation := &operationGraphQL{variables: map[string]definitionVariable{}}
+	if a.jeton.genre == 'n' {
+		operation.nom = a.jeton.valeur
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+	}
+	if a.est('p', "(") {
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+		for !a.est('p', ")") {
+			if err := a.attendre('p', "$"); err != nil {
+				return nil, err
+			}
+			nom, err := a.nom()
+			if err != nil {
+				return nil, err
+			}
+			if err := a.attendre('p', ":"); err != nil {
+				return nil, err
+			}
+			sdl, err := a.typeVariable()
+			if err != nil {
+				return nil, err
+			}
+			definition := definitionVariable{sdl: sdl}
+			if a.est('p', "=") {
+				if err := a.avancer(); err != nil {
+					return nil, err
+				}
+				if definition.defaut, err = a.valeur(true); err != nil {
+					return nil, err
+				}
+			}
+			operation.variables[nom] = definition
+		}
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+	}
+	selections, err := a.selections()
+	if err != nil {
+		return nil, err
+	}
+	operation.selections = selections
+	return operation, nil
+}
+
+func (a *analyseurGraphQL) typeVariable() (string, error) {
+	var sdl string
+	if a.est('p', "[") {
+		if err := a.avancer(); err != nil {
+			return "", err
+		}
+		element, err := a.typeVariable()
+		if err != nil {
+			return "", err
+		}
+		if err := a.attendre('p', "]"); err != nil {
+			return "", err
+		}
+		sdl = "[" + element + "]"
+	} else {
+		nom, err := a.nom()
+		if err != nil {
+			return "", err
+		}
+		sdl = nom
+	}
+	if a.est('p', "!") {
+		sdl += "!"
+		if err := a.avancer(); err != nil {
+			return "", err
+		}
+	}
+	return sdl, nil
+}
+
+func (a *analyseurGraphQL) selections() ([]*selectionGraphQL, error) {
+	if err := a.attendre('p', "{"); err != nil {
+		return nil, err
+	}
+	var selections []*selectionGraphQL
+	for !a.est('p', "}") {
+		if a.jeton.genre == 0 {
+			return nil, a.erreur("} attendu")
+		}
+		if a.est('p', "@") {
+			return nil, a.erreur("directives non prises en charge")
+		}
+		if a.est('p', "...") {
+			if err := a.avancer(); err != nil {
+				return nil, err
+			}
+			s := &selectionGraphQL{}
+			switch {
+			case a.est('n', "on"):
+				if err := a.avancer(); err != nil {
+					return nil, err
+				}
+				condition, err := a.nom()
+				if err != nil {
+					return nil, err
+				}
+				s.condition = condition
+				fallthrough
+			case a.est('p', "{"):
+				sous, err := a.selections()
+				if err != nil {
+					return nil, err
+				}
+				s.selections = sous
+			default:
+				nom, err := a.nom()
+				if err != nil {
+					return nil, err
+				}
+				s.fragment = nom
+			}
+			selections = append(selections, s)
+			continue
+		}
+
+		nom, err := a.nom()
+		if err != nil {
+			return nil, err
+		}
+		s := &selectionGraphQL{nom: nom}
+		if a.est('p', ":") {
+			if err := a.avancer(); err != nil {
+				return nil, err
+			}
+			if s.nom, err = a.nom(); err != nil {
+				return nil, err
+			}
+			s.alias = nom
+		}
+		if a.est('p', "(") {
+			if err := a.avancer(); err != nil {
+				return nil, err
+			}
+			s.arguments = map[string]interface{}{}
+			for !a.est('p', ")") {
+				argument, err := a.nom()
+				if err != nil {
+					return nil, err
+				}
+				if err := a.attendre('p', ":"); err != nil {
+					return nil, err
+				}
+				if s.arguments[argument], err = a.valeur(false); err != nil {
+					return nil, err
+				}
+			}
+			if err := a.avancer(); err != nil {
+				return nil, err
+			}
+		}
+		if a.est('p', "{") {
+			if s.selections, err = a.selections(); err != nil {
+				return nil, err
+			}
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, a.erreur("sélection vide")
+	}
+	return selections, a.avancer()
+}
+
+// Valeur d'un argument ; une valeur par défaut de variable ne peut pas contenir de variable
+func (a *analyseurGraphQL) valeur(constante bool) (interface{}, error) {
+	j := a.jeton
+	switch {
+	case j.genre == 'p' && j.valeur == "$" && !constante:
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+		nom, err := a.nom()
+		return variableGraphQL(nom), err
+	case j.genre == 's':
+		return j.valeur, a.avancer()
+	case j.genre == 'i':
+		n, err := strconv.Atoi(j.valeur)
+		if err != nil {
+			return nil, a.erreur("entier invalide")
+		}
+		return n, a.avancer()
+	case j.genre == 'f':
+		f, err := strconv.ParseFloat(j.valeur, 64)
+		if err != nil {
+			return nil, a.erreur("nombre invalide")
+		}
+		return f, a.avancer()
+	case j.genre == 'n':
+		var v interface{} = j.valeur // Énumération
+		switch j.valeur {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		}
+		return v, a.avancer()
+	case a.est('p', "["):
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+		liste := []interface{}{}
+		for !a.est('p', "]") {
+			element, err := a.valeur(constante)
+			if err != nil {
+				return nil, err
+			}
+			liste = append(liste, element)
+		}
+		return liste, a.avancer()
+	case a.est('p', "{"):
+		if err := a.avancer(); err != nil {
+			return nil, err
+		}
+		objet := map[string]interface{}{}
+		for !a.est('p', "}") {
+			nom, err := a.nom()
+			if err != nil {
+				return nil, err
+			}
+			if err := a.attendre('p', ":"); err != nil {
+				return nil, err
+			}
+			if objet[nom], err = a.valeur(constante); err != nil {
+				return nil, err
+			}
+		}
+		return objet, a.avancer()
+	}
+	return nil, a.erreur("valeur attendue")
+}
+
+func (a *analyseurGraphQL) est(genre byte, valeur string) bool {
+	return a.jeton.genre == genre && a.jeton.valeur == valeur
+}
+
+func (a *analyseurGraphQL) attendre(genre byte, valeur string) error {
+	if !a.est(genre, valeur) {
+		return a.erreur(valeur + " attendu")
+	}
+	return a.avancer()
+}
+
+func (a *analyseurGraphQL) nom() (string, error) {
+	if a.jeton.genre != 'n' {
+		return "", a.erreur("nom attendu")
+	}
+	nom := a.jeton.valeur
+	return nom, a.avancer()
+}
+
+func (a *analyseurGraphQL) erreur(attendu string) error {
+	trouve := a.jeton.valeur
+	if a.jeton.genre == 0 {
+		trouve = "fin du document"
+	}
+	return fmt.Errorf("erreur de syntaxe à la position %d : %s, trouvé %q", a.jeton.position, attendu, trouve)
+}
+
+// Lire le lexème suivant ; virgules, blancs et commentaires sont ignorés
+func (a *analyseurGraphQL) avancer() error {
+	s := a.source
+	for a.i < len(s) {
+		c := s[a.i]
+		if c == '#' {
+			for a.i < len(s) && s[a.i] != '\n' {
+				a.i++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		a.i++
+	}
+	debut := a.i
+	if a.i >= len(s) {
+		a.jeton = jetonGraphQL{position: debut}
+		return nil
+	}
+
+	c := s[a.i]
+	switch {
+	case strings.HasPrefix(s[a.i:], "..."):
+		a.i += 3
+		a.jeton = jetonGraphQL{genre: 'p', valeur: "...", position: debut}
+	case strings.ContainsRune("!$():=@[]{}|", rune(c)):
+		a.i++
+		a.jeton = jetonGraphQL{genre: 'p', valeur: string(c), position: debut}
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		for a.i < len(s) && (s[a.i] == '_' || s[a.i] >= 'A' && s[a.i] <= 'Z' || s[a.i] >= 'a' && s[a.i] <= 'z' || s[a.i] >= '0' && s[a.i] <= '9') {
+			a.i++
+		}
+		a.jeton = jetonGraphQL{genre: 'n', valeur: s[debut:a.i], position: debut}
+	case c == '-' || c >= '0' && c <= '9':
+		a.i++
+		genre := byte('i')
+		for a.i < len(s) && (s[a.i] >= '0' && s[a.i] <= '9' || strings.ContainsRune(".eE+-", rune(s[a.i]))) {
+			if !(s[a.i] >= '0' && s[a.i] <= '9') {
+				genre = 'f'
+			}
+			a.i++
+		}
+		a.jeton = jetonGraphQL{genre: genre, valeur: s[debut:a.i], position: debut}
+	case c == '"':
+		if strings.HasPrefix(s[a.i:], `"""`) {
+			return fmt.Errorf("erreur de syntaxe à la position %d : chaînes en bloc non prises en charge", debut)
+		}
+		a.i++
+		for a.i < len(s) && s[a.i] != '"' && s[a.i] != '\n' {
+			if s[a.i] == '\\' {
+				a.i++
+			}
+			a.i++
+		}
+		if a.i >= len(s) || s[a.i] != '"' {
+			return fmt.Errorf("erreur de syntaxe à la position %d : chaîne non terminée", debut)
+		}
+		a.i++
+		// Les échappements GraphQL sont ceux de JSON
+		var valeur string
+		if err := json.Unmarshal([]byte(s[debut:a.i]), &valeur); err != nil {
+			return fmt.Errorf("erreur de syntaxe à la position %d : chaîne invalide", debut)
+		}
+		a.jeton = jetonGraphQL{genre: 's', valeur: valeur, position: debut}
+	default:
+		return fmt.Errorf("erreur de syntaxe à la position %d : caractère inattendu %q", debut, c)
+	}
+	return nil
+}
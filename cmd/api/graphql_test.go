@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Contrat de consultation servi depuis la mémoire, qui compte les évaluations
+type contratFactice struct {
+	titres      map[string]*model.TitreFoncier
+	evaluations int
+}
+
+func (c *contratFactice) EvaluateTransaction(fonction string, arguments ...string) ([]byte, error) {
+	c.evaluations++
+	switch fonction {
+	case "LireTitreFoncier":
+		titre, ok := c.titres[arguments[0]]
+		if !ok {
+			return nil, fmt.Errorf("le titre foncier %s n'existe pas", arguments[0])
+		}
+		return json.Marshal(titre)
+	case "GetRangHypotheques":
+		var hypotheques []model.Charge
+		for _, charge := range c.titres[arguments[0]].Charges {
+			if charge.Type == model.ChargeHypotheque {
+				hypotheques = append(hypotheques, charge)
+			}
+		}
+		return json.Marshal(hypotheques)
+	case "GetLitigesTitre":
+		return []byte("[]"), nil
+	case "RechercherTitres":
+		var selecteur map[string]string
+		if err := json.Unmarshal([]byte(arguments[0]), &selecteur); err != nil {
+			return nil, err
+		}
+		resultat := model.ResultatRecherche{Titres: []*model.TitreFoncier{}}
+		for _, id := range []string{"TF1", "TF2"} {
+			if titre := c.titres[id]; titre.Proprio == selecteur["proprio"] {
+				resultat.Titres = append(resultat.Titres, titre)
+			}
+		}
+		resultat.Nombre = len(resultat.Titres)
+		return json.Marshal(resultat)
+	}
+	return nil, fmt.Errorf("fonction inattendue: %s", fonction)
+}
+
+func (c *contratFactice) SubmitTransaction(fonction string, arguments ...string) ([]byte, error) {
+	return nil, fmt.Errorf("soumission inattendue: %s", fonction)
+}
+
+func nouveauContratFactice() *contratFactice {
+	return &contratFactice{titres: map[string]*model.TitreFoncier{
+		"TF1": {Id: "TF1", NumTF: "1001/DK", Commune: "Dakar-Plateau", Proprio: "Awa Ndiaye", Superficie: 450,
+			Document: "/mnt/shared_dir/TF1.pdf", DocHash: "h1",
+			Charges: []model.Charge{{Type: model.ChargeHypotheque, Beneficiaire: "BHS", Reference: "H1", Montant: 85000000, Rang: 1}}},
+		"TF2": {Id: "TF2", NumTF: "1002/DK", Commune: "Medina", Proprio: "Awa Ndiaye", Superficie: 300,
+			Document: "/mnt/shared_dir/TF2.pdf", DocHash: "h2"},
+	}}
+}
+
+// Exécuter une requête sous une portée et rendre sa réponse en JSON
+func executer(t *testing.T, contrat *contratFactice, portee string, requete requeteGraphQL) (string, *reponseGraphQL) {
+	t.Helper()
+	reponse := executerGraphQL(typeRequete, requete, &compteService{Id: "test", Portee: portee}, client.NewRegistre(contrat))
+	contenu, err := json.Marshal(reponse)
+	if err != nil {
+		t.Fatalf("encodage de la réponse: %v", err)
+	}
+	return string(contenu), reponse
+}
+
+func TestAnalyserGraphQL(t *testing.T) {
+	document, err := analyserGraphQL(`
+		# Dossier d'un titre
+		query Dossier($id: String!, $limite: Int = 5) {
+			dossier: titre(id: $id) { id, ...Parcelle ... on Titre { commune } }
+			proprietaire(nom: "Awa \"la\" Ndiayeé") { titres(limite: $limite) { id } }
+		}
+		fragment Parcelle on Titre { numTF superficie }
+		{ titre(id: "TF1") { id } }`)
+	if err != nil {
+		t.Fatalf("analyse: %v", err)
+	}
+	if len(document.operations) != 2 || len(document.fragments) != 1 {
+		t.Fatalf("%d opérations et %d fragments, 2 et 1 attendus", len(document.operations), len(document.fragments))
+	}
+
+	operation, err := document.operationNommee("Dossier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(operation.variables, map[string]definitionVariable{"id": {sdl: "String!"}, "limite": {sdl: "Int", defaut: 5}}) {
+		t.Errorf("variables: %+v", operation.variables)
+	}
+	dossier := operation.selections[0]
+	if dossier.alias != "dossier" || dossier.nom != "titre" || dossier.arguments["id"] != variableGraphQL("id") {
+		t.Errorf("champ aliasé: %+v", dossier)
+	}
+	if len(dossier.selections) != 3 || dossier.selections[1].fragment != "Parcelle" || dossier.selections[2].condition != "Titre" {
+		t.Errorf("fragments de la sélection: %+v", dossier.selections)
+	}
+	if nom := operation.selections[1].arguments["nom"]; nom != `Awa "la" Ndiayeé` {
+		t.Errorf("chaîne échappée: %q", nom)
+	}
+	if _, err := document.operationNommee(""); err == nil {
+		t.Error("operationName exigé pour un document de deux opérations")
+	}
+	if _, err := document.operationNommee("Autre"); err == nil {
+		t.Error("opération inconnue acceptée")
+	}
+
+	for _, source := range []string{
+		``,
+		`fragment F on Titre { id }`,
+		`{ titre(id: "TF1") { id }`,
+		`{ }`,
+		`{ titre(id: "TF1) { id } }`,
+		`{ titre(id: """TF1""") { id } }`,
+		`{ titre(id: "TF1") @include(if: true) { id } }`,
+		`mutation { AjouterTitreFoncier }`,
+		`subscription { titre }`,
+		`query ($id: String = $autre) { titre(id: $id) { id } }`,
+		`fragment F on Titre { id } fragment F on Titre { numTF } { ...F }`,
+		`{ titre(id: "TF1") { id } } %`,
+	} {
+		if _, err := analyserGraphQL(source); err == nil {
+			t.Errorf("document invalide accepté: %s", source)
+		}
+	}
+}
+
+func TestValiderGraphQL(t *testing.T) {
+	profond := `{ titre(id: "TF1") { id } }`
+	for i := 0; i < ProfondeurGraphQLMax; i++ {
+		profond = strings.Replace(profond, `{ id }`, `{ proprietaire { titres { id } } }`, 1)
+	}
+
+	for _, cas := range []struct {
+		requete requeteGraphQL
+		erreur  string
+	}{
+		{requeteGraphQL{Query: `{ titre(id: "TF1") { inconnu } }`}, "champ inconnu sur Titre: inconnu"},
+		{requeteGraphQL{Query: `{ titre(numero: "TF1") { id } }`}, "argument inconnu sur Query.titre: numero"},
+		{requeteGraphQL{Query: `{ titre { id } }`}, "argument requis sur Query.titre: id"},
+		{requeteGraphQL{Query: `{ titre(id: "TF1") }`}, "le champ Query.titre exige une sélection"},
+		{requeteGraphQL{Query: `{ titre(id: "TF1") { id { valeur } } }`}, "le champ Titre.id est scalaire et n'admet pas de sélection"},
+		{requeteGraphQL{Query: `{ titre(id: "TF1") { ...F } }`}, "fragment inconnu: F"},
+		{requeteGraphQL{Query: `fragment F on Titre { proprietaire { titres { ...F } } } { titre(id: "TF1") { ...F } }`}, "le fragment F se contient lui-même"},
+		{requeteGraphQL{Query: profond}, fmt.Sprintf("la requête dépasse %d niveaux de sélection", ProfondeurGraphQLMax)},
+		{requeteGraphQL{Query: `query ($id: String!) { titre(id: $id) { id } }`}, "variable requise: $id"},
+	} {
+		contrat := nouveauContratFactice()
+		_, reponse := executer(t, contrat, PorteeRegistraire, cas.requete)
+		if reponse.Data != nil || len(reponse.Errors) != 1 || reponse.Errors[0].Message != cas.erreur {
+			t.Errorf("%s: réponse %+v, erreur %q attendue", cas.requete.Query, reponse, cas.erreur)
+		}
+		if contrat.evaluations != 0 {
+			t.Errorf("%s: %d consultations du registre avant le rejet de la requête", cas.requete.Query, contrat.evaluations)
+		}
+	}
+}
+
+func TestExecuterGraphQL(t *testing.T) {
+	requete := requeteGraphQL{
+		Query: `query Dossier($id: String!) {
+			dossier: titre(id: $id) { __typename id ...Parcelle proprietaire { nom titres(limite: 2) { id } } }
+		}
+		fragment Parcelle on Titre { numTF superficie hypotheques { rang montant } }`,
+		Variables: map[string]interface{}{"id": "TF1"},
+	}
+	contenu, _ := executer(t, nouveauContratFactice(), PorteeRegistraire, requete)
+	attendu := `{"data":{"dossier":{"__typename":"Titre","id":"TF1","numTF":"1001/DK","superficie":450,` +
+		`"hypotheques":[{"rang":1,"montant":85000000}],"proprietaire":{"nom":"Awa Ndiaye","titres":[{"id":"TF1"},{"id":"TF2"}]}}}}`
+	if contenu != attendu {
+		t.Errorf("réponse\n%s\nattendue\n%s", contenu, attendu)
+	}
+
+	// Un titre introuvable vaut null à son chemin, sans faire échouer les autres champs
+	contenu, _ = executer(t, nouveauContratFactice(), PorteeVerification, requeteGraphQL{Query: `{ a: titre(id: "TF9") { id } b: titre(id: "TF2") { id } }`})
+	attendu = `{"data":{"a":null,"b":{"id":"TF2"}},"errors":[{"message":"opération refusée par le registre","path":["a"]}]}`
+	if contenu != attendu {
+		t.Errorf("réponse\n%s\nattendue\n%s", contenu, attendu)
+	}
+}
+
+func TestPorteeGraphQL(t *testing.T) {
+	requete := requeteGraphQL{Query: `{ titre(id: "TF1") { id superficie documents { hash chemin } hypotheques { montant } } }`}
+
+	contenu, _ := executer(t, nouveauContratFactice(), PorteeVerification, requete)
+	attendu := `{"data":{"titre":{"id":"TF1","superficie":null,"documents":[{"hash":"h1","chemin":null}],"hypotheques":null}},"errors":[` +
+		`{"message":"la portée verification ne donne pas accès à Titre.superficie (lecture requise)","path":["titre","superficie"]},` +
+		`{"message":"la portée verification ne donne pas accès à Document.chemin (registraire requise)","path":["titre","documents",0,"chemin"]},` +
+		`{"message":"la portée verification ne donne pas accès à Titre.hypotheques (lecture requise)","path":["titre","hypotheques"]}]}`
+	if contenu != attendu {
+		t.Errorf("portée verification\n%s\nattendue\n%s", contenu, attendu)
+	}
+
+	contenu, _ = executer(t, nouveauContratFactice(), PorteeLecture, requete)
+	attendu = `{"data":{"titre":{"id":"TF1","superficie":450,"documents":[{"hash":"h1","chemin":null}],"hypotheques":[{"montant":null}]}},"errors":[` +
+		`{"message":"la portée lecture ne donne pas accès à Document.chemin (registraire requise)","path":["titre","documents",0,"chemin"]},` +
+		`{"message":"la portée lecture ne donne pas accès à Hypotheque.montant (registraire requise)","path":["titre","hypotheques",0,"montant"]}]}`
+	if contenu != attendu {
+		t.Errorf("portée lecture\n%s\nattendue\n%s", contenu, attendu)
+	}
+
+	// Un champ refusé n'est pas résolu : la portée verification ne consulte pas le propriétaire
+	contrat := nouveauContratFactice()
+	_, reponse := executer(t, contrat, PorteeVerification, requeteGraphQL{Query: `{ proprietaire(nom: "Awa Ndiaye") { titres { id } } }`})
+	if len(reponse.Errors) != 1 || contrat.evaluations != 0 {
+		t.Errorf("%d erreurs et %d consultations, 1 et 0 attendues", len(reponse.Errors), contrat.evaluations)
+	}
+}
+
+func TestConsultationsGraphQL(t *testing.T) {
+	var requete strings.Builder
+	requete.WriteString("{")
+	for i := 0; i <= ConsultationsGraphQLMax; i++ {
+		fmt.Fprintf(&requete, ` t%d: titre(id: "TF1") { id }`, i)
+	}
+	requete.WriteString(" }")
+
+	contrat := nouveauContratFactice()
+	_, reponse := executer(t, contrat, PorteeVerification, requeteGraphQL{Query: requete.String()})
+	dernier := fmt.Sprintf("t%d", ConsultationsGraphQLMax)
+	if contrat.evaluations != ConsultationsGraphQLMax {
+		t.Errorf("%d consultations du registre, %d attendues", contrat.evaluations, ConsultationsGraphQLMax)
+	}
+	if len(reponse.Errors) != 1 || !reflect.DeepEqual(reponse.Errors[0].Path, []interface{}{dernier}) || reponse.Data.valeurs[dernier] != nil {
+		t.Errorf("erreurs %+v : seul %s doit dépasser la limite", reponse.Errors, dernier)
+	}
+}
@@ -0,0 +1,79 @@
+// Commande api : passerelle REST du registre pour les intégrateurs tiers. Chaque compte de service
+// reçoit une clé d'API de portée verification, lecture ou registraire, contrôlée route par route,
+// et ses appels sont soumis sous l'identité Fabric qui lui est attribuée dans le fichier des comptes.
+// POST /graphql répond aux requêtes imbriquées sur le dossier d'un titre, champ par champ selon la
+// portée du compte (schéma : GET /graphql/schema). Les terminaux de terrain suivent les écritures
+// de titres d'une commune (GET /communes/{commune}/modifications?depuis=) et soumettent leurs
+// opérations saisies hors ligne (POST /synchronisation), refusées en conflit si le titre a été
+// écrit depuis leur dernière synchronisation.
+//
+// Les appels sont transmis au service Gateway du pair -pair.
+//
+//	api -ecoute :8080 -canal dakar -comptes /etc/titrefoncier/comptes.json \
+//	  -pair peer0.dakar:7051 -tls-ca /etc/hyperledger/peer-ca.pem
+//
+// Avec -webhooks, les intégrateurs enregistrent des URL (POST /webhooks) auxquelles la passerelle
+// pousse les événements du chaincode, reçus du même pair sous l'identité -identite-evenements et
+// signés par HMAC-SHA256 :
+//
+//	api -comptes comptes.json -webhooks /var/lib/titrefoncier/webhooks.json -identite-evenements lecture
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"titrefoncier/pkg/client"
+)
+
+func main() {
+	ecoute := flag.String("ecoute", ":8080", "adresse d'écoute HTTP")
+	options := client.DeclarerOptions(flag.CommandLine)
+	fichierComptes := flag.String("comptes", "comptes.json", "fichier des identités Fabric et des comptes de service")
+	fichierWebhooks := flag.String("webhooks", "", "fichier des webhooks des intégrateurs (vide : webhooks désactivés)")
+	identiteEvenements := flag.String("identite-evenements", "", "identité Fabric déclarée sous laquelle les événements sont écoutés")
+	flag.Parse()
+
+	comptes, err := chargerComptes(*fichierComptes)
+	if err != nil {
+		slog.Error("chargement des comptes impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+
+	pair, err := options.Connecter()
+	if err != nil {
+		slog.Error("connexion au pair impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	defer pair.Fermer()
+
+	p := &passerelle{comptes: comptes, registres: make(map[string]*client.Registre)}
+	for nom, identite := range comptes.Identites {
+		if p.registres[nom], err = pair.Registre(identite); err != nil {
+			slog.Error("identité Fabric inutilisable", slog.String("identite", nom), slog.Any("erreur", err))
+			os.Exit(2)
+		}
+	}
+
+	if *fichierWebhooks != "" {
+		identite, declaree := comptes.Identites[*identiteEvenements]
+		if !declaree {
+			slog.Error("identité d'écoute des événements non déclarée", slog.String("identite", *identiteEvenements))
+			os.Exit(2)
+		}
+		if p.webhooks, err = chargerWebhooks(*fichierWebhooks); err != nil {
+			slog.Error("chargement des webhooks impossible", slog.Any("erreur", err))
+			os.Exit(2)
+		}
+		go p.distribuer(context.Background(), pair.Ecouteur(identite))
+	}
+
+	slog.Info("passerelle démarrée", slog.String("ecoute", *ecoute), slog.Int("comptes", len(comptes.Comptes)), slog.Int("identites", len(comptes.Identites)))
+	if err := http.ListenAndServe(*ecoute, p.routeur()); err != nil {
+		slog.Error("arrêt de la passerelle", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"titrefoncier/pkg/model"
+)
+
+// Schéma GraphQL du dossier d'un titre : titre → propriétaire → documents → hypothèques → litiges.
+// Chaque champ exige une portée ; un champ hors de la portée du compte vaut null et donne une erreur
+// à son chemin, sans faire échouer le reste de la requête. Seuls les champs de portée verification,
+// la plus restreinte, peuvent donc être déclarés non nuls.
+var (
+	typeRequete      = &typeGraphQL{nom: "Query"}
+	typeTitre        = &typeGraphQL{nom: "Titre"}
+	typeProprietaire = &typeGraphQL{nom: "Proprietaire"}
+	typeDocument     = &typeGraphQL{nom: "Document"}
+	typeHypotheque   = &typeGraphQL{nom: "Hypotheque"}
+	typeLitige       = &typeGraphQL{nom: "Litige"}
+)
+
+// Titres d'un propriétaire par défaut, et au plus, dans une requête
+const (
+	LimiteTitresGraphQL    = 20
+	LimiteTitresGraphQLMax = 100
+)
+
+func init() {
+	typeRequete.champs = []*champGraphQL{
+		{nom: "titre", arguments: []argumentGraphQL{{"id", "String!"}}, sdl: "Titre", objet: typeTitre, portee: PorteeVerification,
+			resoudre: func(x *execution, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				id, err := argumentChaine(args, "id")
+				if err != nil {
+					return nil, err
+				}
+				if err := x.consulter(); err != nil {
+					return nil, err
+				}
+				return x.registre.LireTitreFoncier(id)
+			}},
+		{nom: "proprietaire", arguments: []argumentGraphQL{{"nom", "String!"}}, sdl: "Proprietaire", objet: typeProprietaire, portee: PorteeLecture,
+			resoudre: func(_ *execution, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return argumentChaine(args, "nom")
+			}},
+	}
+
+	typeTitre.champs = []*champGraphQL{
+		scalaire("id", "String!", PorteeVerification, func(t *model.TitreFoncier) interface{} { return t.Id }),
+		scalaire("numTF", "String!", PorteeVerification, func(t *model.TitreFoncier) interface{} { return t.NumTF }),
+		scalaire("commune", "String!", PorteeVerification, func(t *model.TitreFoncier) interface{} { return t.Commune }),
+		scalaire("inalienable", "Boolean!", PorteeVerification, func(t *model.TitreFoncier) interface{} { return t.Inalienable }),
+		scalaire("superficie", "Int", PorteeLecture, func(t *model.TitreFoncier) interface{} { return t.Superficie }),
+		scalaire("bureau", "String", PorteeLecture, func(t *model.TitreFoncier) interface{} { return optionnel(t.BureauFoncier) }),
+		scalaire("zones", "[String!]", PorteeLecture, func(t *model.TitreFoncier) interface{} { return liste(t.Zones) }),
+		scalaire("creeLe", "String", PorteeLecture, func(t *model.TitreFoncier) interface{} { return optionnel(t.CreeLe) }),
+		{nom: "proprietaire", sdl: "Proprietaire", objet: typeProprietaire, portee: PorteeLecture,
+			resoudre: func(_ *execution, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				return parent.(*model.TitreFoncier).Proprio, nil
+			}},
+		{nom: "documents", sdl: "[Document!]!", objet: typeDocument, portee: PorteeVerification,
+			resoudre: func(_ *execution, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				t := parent.(*model.TitreFoncier)
+				documents := []interface{}{model.DocumentTitre{Chemin: t.Document, Hash: t.DocHash, AjouteLe: t.CreeLe}}
+				for _, d := range t.Documents {
+					documents = append(documents, d)
+				}
+				return documents, nil
+			}},
+		{nom: "hypotheques", sdl: "[Hypotheque!]", objet: typeHypotheque, portee: PorteeLecture,
+			resoudre: func(x *execution, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				var hypotheques []model.Charge
+				if err := x.evaluer(&hypotheques, "GetRangHypotheques", parent.(*model.TitreFoncier).Id); err != nil {
+					return nil, err
+				}
+				return liste(hypotheques), nil
+			}},
+		{nom: "litiges", sdl: "[Litige!]", objet: typeLitige, portee: PorteeLecture,
+			resoudre: func(x *execution, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+				var litiges []*model.Litige
+				if err := x.evaluer(&litiges, "GetLitigesTitre", parent.(*model.TitreFoncier).Id); err != nil {
+					return nil, err
+				}
+				return liste(litiges), nil
+			}},
+	}
+
+	typeProprietaire.champs = []*champGraphQL{
+		scalaire("nom", "String", PorteeLecture, func(nom string) interface{} { return nom }),
+		{nom: "titres", arguments: []argumentGraphQL{{"limite", fmt.Sprintf("Int = %d", LimiteTitresGraphQL)}}, sdl: "[Titre!]", objet: typeTitre, portee: PorteeLecture,
+			resoudre: func(x *execution, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				limite, err := argumentEntier(args, "limite", LimiteTitresGraphQL)
+				if err != nil {
+					return nil, err
+				}
+				if limite <= 0 || limite > LimiteTitresGraphQLMax {
+					return nil, fmt.Errorf("la limite doit être comprise entre 1 et %d", LimiteTitresGraphQLMax)
+				}
+				if err := x.consulter(); err != nil {
+					return nil, err
+				}
+				resultat, err := x.registre.TitresParProprietaire(parent.(string), limite, "")
+				if err != nil {
+					return nil, err
+				}
+				return liste(resultat.Titres), nil
+			}},
+	}
+
+	// Le chemin NFS des documents ne sert qu'aux conservateurs
+	typeDocument.champs = []*champGraphQL{
+		scalaire("hash", "String!", PorteeVerification, func(d model.DocumentTitre) interface{} { return d.Hash }),
+		scalaire("issuer", "String", PorteeVerification, func(d model.DocumentTitre) interface{} { return optionnel(d.Issuer) }),
+		scalaire("ajouteLe", "String", PorteeVerification, func(d model.DocumentTitre) interface{} { return optionnel(d.AjouteLe) }),
+		scalaire("statut", "String", PorteeVerification, func(d model.DocumentTitre) interface{} { return optionnel(d.Statut) }),
+		scalaire("type", "String", PorteeLecture, func(d model.DocumentTitre) interface{} { return optionnel(d.Type) }),
+		scalaire("taille", "Int", PorteeLecture, func(d model.DocumentTitre) interface{} { return d.Taille }),
+		scalaire("chemin", "String", PorteeRegistraire, func(d model.DocumentTitre) interface{} { return d.Chemin }),
+	}
+
+	// Le montant des créances garanties est réservé aux conservateurs
+	typeHypotheque.champs = []*champGraphQL{
+		scalaire("rang", "Int", PorteeLecture, func(c model.Charge) interface{} { return c.Rang }),
+		scalaire("beneficiaire", "String", PorteeLecture, func(c model.Charge) interface{} { return c.Beneficiaire }),
+		scalaire("reference", "String", PorteeLecture, func(c model.Charge) interface{} { return c.Reference }),
+		scalaire("dateLimite", "String", PorteeLecture, func(c model.Charge) interface{} { return optionnel(c.DateLimite) }),
+		scalaire("montant", "Int", PorteeRegistraire, func(c model.Charge) interface{} { return c.Montant }),
+	}
+
+	// L'objet et la décision d'un litige restent réservés aux conservateurs
+	typeLitige.champs = []*champGraphQL{
+		scalaire("id", "String", PorteeLecture, func(l *model.Litige) interface{} { return l.Id }),
+		scalaire("statut", "String", PorteeLecture, func(l *model.Litige) interface{} { return l.Statut }),
+		scalaire("ouvertLe", "String", PorteeLecture, func(l *model.Litige) interface{} { return l.OuvertLe }),
+		scalaire("closLe", "String", PorteeLecture, func(l *model.Litige) interface{} { return optionnel(l.ClosLe) }),
+		scalaire("origine", "String", PorteeRegistraire, func(l *model.Litige) interface{} { return l.Origine }),
+		scalaire("objet", "String", PorteeRegistraire, func(l *model.Litige) interface{} { return l.Objet }),
+		scalaire("decision", "String", PorteeRegistraire, func(l *model.Litige) interface{} { return optionnel(l.Decision) }),
+	}
+}
+
+// Champ scalaire lu sur l'objet parent
+func scalaire[T any](nom string, sdl string, portee string, valeur func(T) interface{}) *champGraphQL {
+	return &champGraphQL{nom: nom, sdl: sdl, portee: portee, resoudre: func(_ *execution, parent interface{}, _ map[string]interface{}) (interface{}, error) {
+		return valeur(parent.(T)), nil
+	}}
+}
+
+// Évaluer une transaction de consultation et décoder sa réponse JSON
+func (x *execution) evaluer(v interface{}, fonction string, arguments ...string) error {
+	if err := x.consulter(); err != nil {
+		return err
+	}
+	reponse, err := x.registre.Evaluer(fonction, arguments...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(reponse, v); err != nil {
+		return fmt.Errorf("réponse %s invalide: %v", fonction, err)
+	}
+	return nil
+}
+
+// Chaîne facultative : null plutôt que vide
+func optionnel(valeur string) interface{} {
+	if valeur == "" {
+		return nil
+	}
+	return valeur
+}
+
+// Éléments d'une liste de réponse, jamais null
+func liste[T any](elements []T) []interface{} {
+	valeurs := make([]interface{}, len(elements))
+	for i, e := range elements {
+		valeurs[i] = e
+	}
+	return valeurs
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/extrait"
+	"titrefoncier/pkg/model"
+)
+
+// Passerelle REST : chaque route exige une portée, et les appels d'un compte sont soumis sous
+// l'identité Fabric qui lui est attribuée
+type passerelle struct {
+	comptes   *configurationComptes
+	registres map[string]*client.Registre // Par identité Fabric
+	webhooks  *registreWebhooks           // nil si les webhooks ne sont pas activés
+}
+
+// Taille maximale du corps d'une requête, en octets
+const TailleCorpsMax = 1 << 20
+
+type route struct {
+	motif   string // Méthode et chemin (http.ServeMux)
+	portee  string
+	traiter func(r *client.Registre, req *http.Request) (interface{}, error)
+}
+
+var routes = []route{
+	{"GET /documents/{hash}/verification", PorteeVerification, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		return brut(r.Evaluer("RechercherParHashDocument", req.PathValue("hash")))
+	}},
+	{"GET /attestations/{id}/verification", PorteeVerification, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		return brut(r.Evaluer("VerifierAttestation", req.PathValue("id")))
+	}},
+	{"GET /titres/{id}", PorteeLecture, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		return r.LireTitreFoncier(req.PathValue("id"))
+	}},
+	{"GET /titres/{id}/dossier", PorteeLecture, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		return brut(r.Evaluer("LireDossierTitre", req.PathValue("id")))
+	}},
+	{"GET /proprietaires/{proprio}/titres", PorteeLecture, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		limite, err := strconv.Atoi(req.URL.Query().Get("limite"))
+		if err != nil {
+			limite = 50
+		}
+		return r.TitresParProprietaire(req.PathValue("proprio"), limite, req.URL.Query().Get("signet"))
+	}},
+	{"GET /transferts/{id}", PorteeLecture, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		return r.LireTransfert(req.PathValue("id"))
+	}},
+	{"GET /communes/{commune}/titres/extrait", PorteeLecture, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		requete := req.URL.Query()
+		format := requete.Get("format")
+		if format == "" {
+			format = extrait.FormatCSV
+		}
+		var noms []string
+		if requete.Get("colonnes") != "" {
+			noms = strings.Split(requete.Get("colonnes"), ",")
+		}
+		colonnes, err := extrait.Colonnes(noms)
+		if err == nil {
+			err = extrait.ValiderFormat(format)
+		}
+		filtre := extrait.Filtre{Commune: req.PathValue("commune"), Du: requete.Get("du"), Au: requete.Get("au")}
+		if err == nil {
+			err = filtre.Valider()
+		}
+		if err != nil {
+			return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: err.Error(), Classe: model.ClasseValidation}
+		}
+		return &flux{
+			typeContenu: extrait.TypeContenu(format),
+			nomFichier:  "titres-" + filtre.Commune + "." + format,
+			ecrire: func(w io.Writer) error {
+				_, err := extrait.Exporter(r, filtre, colonnes, func() (extrait.Ecrivain, error) {
+					return extrait.NouvelEcrivain(format, w, colonnes)
+				})
+				return err
+			},
+		}, nil
+	}},
+	{"GET /communes/{commune}/modifications", PorteeLecture, modificationsCommune},
+	{"POST /graphql", PorteeVerification, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		var requete requeteGraphQL
+		if err := decoderCorps(req, &requete); err != nil {
+			return nil, err
+		}
+		return executerGraphQL(typeRequete, requete, compteRequete(req), r), nil
+	}},
+	{"GET /graphql/schema", PorteeVerification, func(_ *client.Registre, _ *http.Request) (interface{}, error) {
+		return &flux{typeContenu: "text/plain; charset=utf-8", ecrire: func(w io.Writer) error {
+			_, err := io.WriteString(w, schemaSDL(typeRequete))
+			return err
+		}}, nil
+	}},
+	{"POST /dossiers", PorteeRegistraire, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		var dossier model.DossierImmatriculation
+		if err := decoderCorps(req, &dossier); err != nil {
+			return nil, err
+		}
+		return r.CreerDossierComplet(req.Context(), &dossier)
+	}},
+	{"POST /synchronisation", PorteeRegistraire, synchroniser},
+	{"POST /transferts", PorteeRegistraire, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		var proposition struct {
+			Id       string `json:"id"`
+			IdTitre  string `json:"idTitre"`
+			Acheteur string `json:"acheteur"`
+			Prix     int    `json:"prix"`
+		}
+		if err := decoderCorps(req, &proposition); err != nil {
+			return nil, err
+		}
+		if err := r.ProposerTransfert(req.Context(), proposition.Id, proposition.IdTitre, proposition.Acheteur, proposition.Prix); err != nil {
+			return nil, err
+		}
+		return r.LireTransfert(proposition.Id)
+	}},
+	{"POST /transferts/{id}/finalisation", PorteeRegistraire, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		if err := r.FinaliserTransfert(req.Context(), req.PathValue("id")); err != nil {
+			return nil, err
+		}
+		return r.LireTransfert(req.PathValue("id"))
+	}},
+	{"POST /transferts/{id}/annulation", PorteeRegistraire, func(r *client.Registre, req *http.Request) (interface{}, error) {
+		if err := r.AnnulerTransfert(req.Context(), req.PathValue("id")); err != nil {
+			return nil, err
+		}
+		return r.LireTransfert(req.PathValue("id"))
+	}},
+}
+
+// Réponse écrite au fil de sa production plutôt qu'encodée en JSON (extraits)
+type flux struct {
+	typeContenu string
+	nomFichier  string // Fichier proposé au téléchargement (vide : affiché)
+	ecrire      func(w io.Writer) error
+}
+
+// Sortie d'un flux : les en-têtes ne sont envoyés qu'à la première écriture, de sorte qu'un échec
+// antérieur reste rapporté comme une erreur JSON, et chaque écriture est transmise aussitôt
+type sortieFlux struct {
+	w       http.ResponseWriter
+	f       *flux
+	entames bool
+}
+
+func (s *sortieFlux) Write(octets []byte) (int, error) {
+	if !s.entames {
+		s.entames = true
+		s.w.Header().Set("Content-Type", s.f.typeContenu)
+		if s.f.nomFichier != "" {
+			s.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": s.f.nomFichier}))
+		}
+	}
+	n, err := s.w.Write(octets)
+	if vidage, ok := s.w.(http.Flusher); ok {
+		vidage.Flush()
+	}
+	return n, err
+}
+
+// Réponse JSON déjà décodée de son enveloppe par le client
+func brut(reponse []byte, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(reponse), nil
+}
+
+func (p *passerelle) routeur() *http.ServeMux {
+	mux := http.NewServeMux()
+	routesPasserelle := routes
+	if p.webhooks != nil {
+		routesPasserelle = slices.Concat(routes, p.routesWebhooks())
+	}
+	for _, r := range routesPasserelle {
+		mux.Handle(r.motif, p.autoriser(r))
+	}
+	return mux
+}
+
+// Authentifier la clé d'API présentée (Authorization: Bearer ou X-Api-Key) et contrôler la portée de la route
+func (p *passerelle) autoriser(r route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cle := req.Header.Get("X-Api-Key")
+		if jeton, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			cle = jeton
+		}
+		compte := p.comptes.compte(cle)
+		if cle == "" || compte == nil {
+			repondreErreur(w, http.StatusUnauthorized, model.CodeAccesRefuse, "clé d'API absente ou inconnue")
+			return
+		}
+		journal := slog.With(slog.String("compte", compte.Id), slog.String("route", r.motif))
+		if rangsPortee[compte.Portee] < rangsPortee[r.portee] {
+			journal.Warn("portée insuffisante", slog.String("portee", compte.Portee))
+			repondreErreur(w, http.StatusForbidden, model.CodeAccesRefuse, "la portée "+compte.Portee+" ne donne pas accès à cette route ("+r.portee+" requise)")
+			return
+		}
+
+		req.Body = http.MaxBytesReader(w, req.Body, TailleCorpsMax)
+		req = req.WithContext(context.WithValue(req.Context(), cleCompte{}, compte))
+		resultat, err := r.traiter(p.registres[compte.Identite], req)
+		if err != nil {
+			journal.Info("appel rejeté", slog.Any("erreur", err))
+			repondreEchec(w, err)
+			return
+		}
+		if f, ok := resultat.(*flux); ok {
+			sortie := &sortieFlux{w: w, f: f}
+			if err := f.ecrire(sortie); err != nil {
+				journal.Info("flux interrompu", slog.Bool("entame", sortie.entames), slog.Any("erreur", err))
+				if !sortie.entames {
+					repondreEchec(w, err)
+				}
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resultat)
+	})
+}
+
+type cleCompte struct{}
+
+// Compte authentifié d'une requête
+func compteRequete(req *http.Request) *compteService {
+	return req.Context().Value(cleCompte{}).(*compteService)
+}
+
+// Décoder le corps JSON d'une requête, dans la limite de TailleCorpsMax
+func decoderCorps(req *http.Request, valeur interface{}) error {
+	err := json.NewDecoder(req.Body).Decode(valeur)
+	var tropGrand *http.MaxBytesError
+	if err == nil || errors.As(err, &tropGrand) {
+		return err
+	}
+	return &client.Erreur{Code: model.CodeRequeteInvalide, Message: "corps de requête JSON invalide", Classe: model.ClasseValidation}
+}
+
+// Messages des échecs sans code d'erreur métier, par classe : le texte brut du chaincode ou de la
+// passerelle Fabric, journalisé, n'est pas renvoyé aux intégrateurs
+var messagesEchec = map[string]string{
+	model.ClasseValidation: "requête non conforme au contrat",
+	model.ClasseConflit:    "conflit avec une transaction concurrente, la requête peut être soumise de nouveau",
+	model.ClasseMetier:     "opération refusée par le registre",
+}
+
+// Statut HTTP d'un échec selon sa classe : requête invalide, conflit passager ou refus métier. Seuls
+// les messages des erreurs métier codées sont transmis tels quels.
+func repondreEchec(w http.ResponseWriter, err error) {
+	var tropGrand *http.MaxBytesError
+	if errors.As(err, &tropGrand) {
+		repondreErreur(w, http.StatusRequestEntityTooLarge, model.CodeRequeteInvalide, fmt.Sprintf("le corps de la requête dépasse %d octets", tropGrand.Limit))
+		return
+	}
+	var erreur *client.Erreur
+	if !errors.As(err, &erreur) {
+		repondreErreur(w, http.StatusBadGateway, model.CodeErreurInterne, "registre momentanément indisponible")
+		return
+	}
+	statut := http.StatusUnprocessableEntity
+	switch {
+	case erreur.Code == model.CodeAccesRefuse:
+		statut = http.StatusForbidden
+	case erreur.Classe == model.ClasseValidation:
+		statut = http.StatusBadRequest
+	case erreur.Classe == model.ClasseConflit:
+		statut = http.StatusConflict
+	}
+	code, message := echecPublic(erreur)
+	repondreErreur(w, statut, code, message)
+}
+
+// Code et message d'un échec du registre transmis aux intégrateurs
+func echecPublic(erreur *client.Erreur) (string, string) {
+	if erreur.Code == "" || erreur.Code == model.CodeErreurInterne {
+		return model.CodeErreurInterne, messagesEchec[erreur.Classe]
+	}
+	return erreur.Code, erreur.Message
+}
+
+func repondreErreur(w http.ResponseWriter, statut int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statut)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
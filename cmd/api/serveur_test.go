@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Le corps d'une requête est borné à TailleCorpsMax octets
+func TestCorpsLimite(t *testing.T) {
+	empreinte := sha256.Sum256([]byte("cle-test"))
+	p := &passerelle{
+		comptes:   &configurationComptes{Comptes: []compteService{{Id: "test", Empreinte: hex.EncodeToString(empreinte[:]), Portee: PorteeLecture, Identite: "lecteur"}}},
+		registres: map[string]*client.Registre{"lecteur": client.NewRegistre(nouveauContratFactice())},
+	}
+	gestionnaire := p.autoriser(route{"POST /essai", PorteeLecture, func(_ *client.Registre, req *http.Request) (interface{}, error) {
+		var demande map[string]string
+		if err := decoderCorps(req, &demande); err != nil {
+			return nil, err
+		}
+		return demande, nil
+	}})
+	poster := func(corps string) int {
+		requete := httptest.NewRequest(http.MethodPost, "/essai", strings.NewReader(corps))
+		requete.Header.Set("X-Api-Key", "cle-test")
+		reponse := httptest.NewRecorder()
+		gestionnaire.ServeHTTP(reponse, requete)
+		return reponse.Code
+	}
+
+	if statut := poster(`{"idTitre":"TF1"}`); statut != http.StatusOK {
+		t.Errorf("corps admis: statut %d", statut)
+	}
+	if statut := poster(`{"idTitre":"` + strings.Repeat("x", TailleCorpsMax) + `"}`); statut != http.StatusRequestEntityTooLarge {
+		t.Errorf("corps démesuré: statut %d, %d attendu", statut, http.StatusRequestEntityTooLarge)
+	}
+	if statut := poster(`{"idTitre":`); statut != http.StatusBadRequest {
+		t.Errorf("corps invalide: statut %d, %d attendu", statut, http.StatusBadRequest)
+	}
+}
+
+// Seuls les messages des erreurs métier codées parviennent aux intégrateurs
+func TestEchecSansDetailsInternes(t *testing.T) {
+	_, sansCode := client.NewRegistre(nouveauContratFactice()).Evaluer("GetStatistiques")
+	cas := []struct {
+		nom     string
+		err     error
+		statut  int
+		code    string
+		message string
+	}{
+		{"passerelle injoignable", errors.New("rpc error: code = Unavailable desc = dial tcp 10.0.0.5:7051"), http.StatusBadGateway, model.CodeErreurInterne, "registre momentanément indisponible"},
+		{"échec sans code", sansCode, http.StatusUnprocessableEntity, model.CodeErreurInterne, messagesEchec[model.ClasseMetier]},
+		{"erreur métier", &client.Erreur{Code: model.CodeTitreSaisi, Message: "le titre TF1 est saisi", Classe: model.ClasseMetier}, http.StatusUnprocessableEntity, model.CodeTitreSaisi, "le titre TF1 est saisi"},
+	}
+	for _, c := range cas {
+		reponse := httptest.NewRecorder()
+		repondreEchec(reponse, c.err)
+		var corps map[string]string
+		if err := json.Unmarshal(reponse.Body.Bytes(), &corps); err != nil {
+			t.Fatal(err)
+		}
+		if reponse.Code != c.statut || corps["code"] != c.code || corps["message"] != c.message {
+			t.Errorf("%s: %d %v, attendu %d %s %q", c.nom, reponse.Code, corps, c.statut, c.code, c.message)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Opérations au plus par synchronisation
+const OperationsSynchronisationMax = 100
+
+// Statuts d'une opération synchronisée
+const (
+	StatutSoumise = "SOUMISE"
+	StatutConflit = "CONFLIT"
+	StatutRejetee = "REJETEE"
+)
+
+// Opération mise en file par un terminal : version est la transaction de la dernière écriture du
+// titre que le terminal connaissait (txId de son journal, vide si le titre n'y figurait pas)
+type operationHorsLigne struct {
+	Id        string   `json:"id"`
+	Fonction  string   `json:"fonction"`
+	IdTitre   string   `json:"idTitre"`
+	Arguments []string `json:"arguments"`
+	Version   string   `json:"version"`
+}
+
+// Issue d'une opération : la réponse du chaincode, ou en cas de conflit la dernière écriture du
+// titre, que le terminal relit avant de proposer de nouveau l'opération
+type resultatOperation struct {
+	Id      string                     `json:"id"`
+	Statut  string                     `json:"statut"`
+	Reponse json.RawMessage            `json:"reponse,omitempty"`
+	Version *model.ModificationCommune `json:"version,omitempty"`
+	Code    string                     `json:"code,omitempty"`
+	Message string                     `json:"message,omitempty"`
+}
+
+// Page du journal des écritures de titres d'une commune, après le signet depuis
+func modificationsCommune(r *client.Registre, req *http.Request) (interface{}, error) {
+	limite := 100
+	if valeur := req.URL.Query().Get("limite"); valeur != "" {
+		var err error
+		if limite, err = strconv.Atoi(valeur); err != nil {
+			return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: "limite invalide: " + valeur, Classe: model.ClasseValidation}
+		}
+	}
+	return r.ModificationsCommune(req.PathValue("commune"), req.URL.Query().Get("depuis"), limite)
+}
+
+// Soumettre dans l'ordre les opérations mises en file hors ligne. Une opération n'est appliquée que si
+// le titre n'a pas été écrit depuis la version connue du terminal ; les opérations suivantes d'une
+// même file sur ce titre tiennent compte des écritures que la file vient de produire.
+func synchroniser(r *client.Registre, req *http.Request) (interface{}, error) {
+	var demande struct {
+		Operations []operationHorsLigne `json:"operations"`
+	}
+	if err := decoderCorps(req, &demande); err != nil {
+		return nil, err
+	}
+	if len(demande.Operations) > OperationsSynchronisationMax {
+		return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: fmt.Sprintf("au plus %d opérations par synchronisation", OperationsSynchronisationMax), Classe: model.ClasseValidation}
+	}
+	for _, operation := range demande.Operations {
+		if operation.Fonction == "" || operation.IdTitre == "" {
+			return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: fmt.Sprintf("opération %s: la fonction et l'identifiant du titre sont requis", operation.Id), Classe: model.ClasseValidation}
+		}
+	}
+
+	// Version connue du terminal d'un titre déjà écrit par la file, et version produite. Le registre
+	// contrôle lui-même la version attendue dans la transaction qui applique l'opération.
+	connues, produites := make(map[string]string), make(map[string]string)
+	resultats := make([]resultatOperation, 0, len(demande.Operations))
+	for _, operation := range demande.Operations {
+		resultat := resultatOperation{Id: operation.Id}
+		attendue := operation.Version
+		if connue, ecrit := connues[operation.IdTitre]; ecrit && connue == operation.Version {
+			attendue = produites[operation.IdTitre]
+		}
+		appliquee, err := r.OperationHorsLigne(req.Context(), operation.IdTitre, attendue, operation.Fonction, operation.Arguments)
+		var erreur *client.Erreur
+		if errors.As(err, &erreur) && erreur.Code == model.CodeVersionPerimee {
+			version, err := r.VersionTitre(operation.IdTitre)
+			if err != nil {
+				resultats = append(resultats, rejet(operation.Id, err))
+				continue
+			}
+			resultat.Statut, resultat.Version = StatutConflit, version
+			resultats = append(resultats, resultat)
+			continue
+		}
+		if err != nil {
+			resultats = append(resultats, rejet(operation.Id, err))
+			continue
+		}
+		resultat.Statut = StatutSoumise
+		if appliquee.Reponse != "" {
+			resultat.Reponse, _ = json.Marshal(appliquee.Reponse)
+		}
+		connues[operation.IdTitre], produites[operation.IdTitre] = operation.Version, appliquee.Version
+		resultats = append(resultats, resultat)
+	}
+	return map[string]interface{}{"resultats": resultats}, nil
+}
+
+// Opération refusée par le registre, avec son code d'erreur métier
+func rejet(id string, err error) resultatOperation {
+	resultat := resultatOperation{Id: id, Statut: StatutRejetee, Code: model.CodeErreurInterne, Message: messagesEchec[model.ClasseMetier]}
+	var erreur *client.Erreur
+	if errors.As(err, &erreur) {
+		resultat.Code, resultat.Message = echecPublic(erreur)
+	}
+	return resultat
+}
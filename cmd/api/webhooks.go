@@ -0,0 +1,539 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Tentatives de livraison d'un événement à un webhook, espacées de délais doublés à chaque échec
+const (
+	tentativesLivraison = 8
+	delaiLivraison      = 2 * time.Second
+	delaiLivraisonMax   = 10 * time.Minute
+)
+
+// Livraisons menées de front, toutes destinations confondues, et intervalle de relève des livraisons dues
+const (
+	livraisonsSimultanees = 8
+	intervalleLivraisons  = time.Second
+)
+
+// Webhook d'un intégrateur : les événements retenus par ses filtres lui sont poussés, signés par
+// HMAC-SHA256 avec un secret remis une seule fois, à l'enregistrement
+type webhook struct {
+	Id         string   `json:"id"`
+	Compte     string   `json:"compte"`
+	URL        string   `json:"url"`
+	Evenements []string `json:"evenements,omitempty"` // Noms d'événements retenus (vide : tous)
+	Secret     string   `json:"secret,omitempty"`
+	CreeLe     string   `json:"creeLe"`
+}
+
+// Un filtre retient l'événement de même nom, ou ses variantes suffixées (AlerteAbonnement:<ids>)
+func (w *webhook) retient(evenement string) bool {
+	if len(w.Evenements) == 0 {
+		return true
+	}
+	for _, filtre := range w.Evenements {
+		if evenement == filtre || strings.HasPrefix(evenement, filtre+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Livraison d'un événement à un webhook, conservée jusqu'à son succès ou l'épuisement des tentatives
+type livraison struct {
+	Webhook   string          `json:"webhook"`
+	Evenement string          `json:"evenement"`
+	TxId      string          `json:"txId"`
+	Corps     json.RawMessage `json:"corps"`
+	Tentative int             `json:"tentative"` // Tentatives déjà faites
+	Prochaine time.Time       `json:"prochaine"` // Date de la prochaine tentative
+}
+
+func (l *livraison) id() string {
+	return l.Webhook + ":" + l.TxId + ":" + l.Evenement
+}
+
+// Webhooks enregistrés, livraisons en attente et position de reprise de l'écoute, conservés dans un
+// fichier JSON
+type registreWebhooks struct {
+	mu         sync.Mutex
+	fichier    string
+	enCours    map[string]bool // Livraisons confiées à un livreur, par identifiant
+	Webhooks   []webhook       `json:"webhooks"`
+	Livraisons []livraison     `json:"livraisons"`
+	Bloc       uint64          `json:"bloc"` // Bloc du dernier événement distribué
+	TxId       string          `json:"txId"` // Dernière transaction distribuée de ce bloc
+}
+
+func chargerWebhooks(fichier string) (*registreWebhooks, error) {
+	registre := &registreWebhooks{fichier: fichier, enCours: make(map[string]bool)}
+	contenu, err := os.ReadFile(fichier)
+	if os.IsNotExist(err) {
+		return registre, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contenu, registre); err != nil {
+		return nil, fmt.Errorf("fichier des webhooks %s invalide: %v", fichier, err)
+	}
+	return registre, nil
+}
+
+// Écrire le fichier par remplacement, pour ne jamais laisser un fichier tronqué ; mu est détenu
+func (r *registreWebhooks) sauvegarder() error {
+	contenu, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporaire := r.fichier + ".tmp"
+	if err := os.WriteFile(temporaire, contenu, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporaire, r.fichier)
+}
+
+func (r *registreWebhooks) enregistrer(w webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Webhooks = append(r.Webhooks, w)
+	return r.sauvegarder()
+}
+
+// Webhooks d'un compte, sans leur secret
+func (r *registreWebhooks) duCompte(compte string) []webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhooks := []webhook{}
+	for _, w := range r.Webhooks {
+		if w.Compte == compte {
+			w.Secret = ""
+			webhooks = append(webhooks, w)
+		}
+	}
+	return webhooks
+}
+
+func (r *registreWebhooks) supprimer(compte string, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.Webhooks, func(w webhook) bool { return w.Id == id && w.Compte == compte })
+	if i < 0 {
+		return false, nil
+	}
+	r.Webhooks = slices.Delete(r.Webhooks, i, i+1)
+	return true, r.sauvegarder()
+}
+
+// Webhooks retenant un événement
+func (r *registreWebhooks) destinataires(evenement string) []webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []webhook
+	for _, w := range r.Webhooks {
+		if w.retient(evenement) {
+			webhooks = append(webhooks, w)
+		}
+	}
+	return webhooks
+}
+
+func (r *registreWebhooks) position() (uint64, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Bloc, r.TxId
+}
+
+// Inscrire les livraisons d'un événement et avancer la position de reprise après lui, dans une même
+// écriture du fichier : un événement n'est jamais tenu pour distribué sans que ses livraisons le soient
+func (r *registreWebhooks) planifier(livraisons []livraison, bloc uint64, txId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	avant, blocAvant, txAvant := r.Livraisons, r.Bloc, r.TxId
+	r.Livraisons = append(slices.Clip(r.Livraisons), livraisons...)
+	r.Bloc, r.TxId = bloc, txId
+	if err := r.sauvegarder(); err != nil {
+		r.Livraisons, r.Bloc, r.TxId = avant, blocAvant, txAvant
+		return err
+	}
+	return nil
+}
+
+// Livraisons dues, au plus n, avec leur webhook ; elles sont réservées jusqu'à leur conclusion
+func (r *registreWebhooks) dues(maintenant time.Time, n int) ([]livraison, []webhook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var livraisons []livraison
+	var webhooks []webhook
+	for _, l := range r.Livraisons {
+		if len(livraisons) == n {
+			break
+		}
+		if r.enCours[l.id()] || l.Prochaine.After(maintenant) {
+			continue
+		}
+		i := slices.IndexFunc(r.Webhooks, func(w webhook) bool { return w.Id == l.Webhook })
+		if i < 0 {
+			continue
+		}
+		r.enCours[l.id()] = true
+		livraisons = append(livraisons, l)
+		webhooks = append(webhooks, r.Webhooks[i])
+	}
+	return livraisons, webhooks
+}
+
+// Conclure une tentative : la livraison est retirée une fois réussie, abandonnée ou son webhook
+// supprimé, sinon reprogrammée après un délai doublé à chaque échec
+func (r *registreWebhooks) conclure(l livraison, echec error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.enCours, l.id())
+	i := slices.IndexFunc(r.Livraisons, func(autre livraison) bool { return autre.id() == l.id() })
+	if i < 0 {
+		return nil
+	}
+	supprime := !slices.ContainsFunc(r.Webhooks, func(w webhook) bool { return w.Id == l.Webhook })
+	if echec == nil || supprime || l.Tentative+1 >= tentativesLivraison {
+		r.Livraisons = slices.Delete(r.Livraisons, i, i+1)
+	} else {
+		r.Livraisons[i].Tentative = l.Tentative + 1
+		r.Livraisons[i].Prochaine = time.Now().Add(min(delaiLivraison<<l.Tentative, delaiLivraisonMax))
+	}
+	return r.sauvegarder()
+}
+
+// Routes de gestion des webhooks du compte appelant ; la portée lecture est requise pour recevoir
+// les événements du registre
+func (p *passerelle) routesWebhooks() []route {
+	return []route{
+		{"POST /webhooks", PorteeLecture, func(_ *client.Registre, req *http.Request) (interface{}, error) {
+			var demande struct {
+				URL        string   `json:"url"`
+				Evenements []string `json:"evenements"`
+			}
+			if err := decoderCorps(req, &demande); err != nil {
+				return nil, err
+			}
+			if err := verifierCible(req.Context(), demande.URL); err != nil {
+				return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: err.Error(), Classe: model.ClasseValidation}
+			}
+			if slices.Contains(demande.Evenements, "") {
+				return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: "filtre d'événement vide", Classe: model.ClasseValidation}
+			}
+			compte := compteRequete(req)
+			if compte.Portee != PorteeRegistraire && len(compte.Titres) == 0 && len(compte.Communes) == 0 && compte.Proprio == "" {
+				return nil, &client.Erreur{Code: model.CodeAccesRefuse, Message: "le compte " + compte.Id + " n'a aucun périmètre d'événements (titres, communes ou propriétaire)", Classe: model.ClasseMetier}
+			}
+			id, secret := make([]byte, 8), make([]byte, 32)
+			if _, err := rand.Read(id); err != nil {
+				return nil, err
+			}
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			w := webhook{
+				Id:         hex.EncodeToString(id),
+				Compte:     compte.Id,
+				URL:        demande.URL,
+				Evenements: demande.Evenements,
+				Secret:     hex.EncodeToString(secret),
+				CreeLe:     time.Now().UTC().Format(time.RFC3339),
+			}
+			if err := p.webhooks.enregistrer(w); err != nil {
+				return nil, err
+			}
+			return w, nil
+		}},
+		{"GET /webhooks", PorteeLecture, func(_ *client.Registre, req *http.Request) (interface{}, error) {
+			return p.webhooks.duCompte(compteRequete(req).Id), nil
+		}},
+		{"DELETE /webhooks/{id}", PorteeLecture, func(_ *client.Registre, req *http.Request) (interface{}, error) {
+			supprime, err := p.webhooks.supprimer(compteRequete(req).Id, req.PathValue("id"))
+			if err != nil {
+				return nil, err
+			}
+			if !supprime {
+				return nil, &client.Erreur{Code: model.CodeRequeteInvalide, Message: "webhook inconnu: " + req.PathValue("id"), Classe: model.ClasseValidation}
+			}
+			return map[string]string{"id": req.PathValue("id")}, nil
+		}},
+	}
+}
+
+// Une URL de webhook est en https et désigne un hôte public : la passerelle, placée dans le réseau
+// du registre, ne doit pas servir à atteindre ses pairs ou ses autres services internes
+func verifierCible(ctx context.Context, adresse string) error {
+	cible, err := url.Parse(adresse)
+	if err != nil || cible.Scheme != "https" || cible.Hostname() == "" {
+		return fmt.Errorf("URL de webhook invalide (https attendu): %s", adresse)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", cible.Hostname())
+	if err != nil {
+		return fmt.Errorf("hôte du webhook introuvable: %v", err)
+	}
+	for _, ip := range ips {
+		if !adressePublique(ip) {
+			return fmt.Errorf("l'hôte du webhook %s désigne une adresse non publique (%s)", cible.Hostname(), ip.Unmap())
+		}
+	}
+	return nil
+}
+
+// Plage partagée des opérateurs (RFC 6598), absente des plages privées de netip
+var plagePartagee = netip.MustParsePrefix("100.64.0.0/10")
+
+func adressePublique(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !plagePartagee.Contains(ip)
+}
+
+// Corps poussé aux webhooks ; le contenu est celui de l'événement émis par le chaincode
+type notification struct {
+	Evenement string          `json:"evenement"`
+	TxId      string          `json:"txId"`
+	Bloc      uint64          `json:"bloc"`
+	Contenu   json.RawMessage `json:"contenu"`
+}
+
+// Distribuer les événements du chaincode aux webhooks, en reprenant après le dernier événement
+// distribué ; l'écoute est rétablie après chaque rupture du flux. Les livraisons sont inscrites au
+// fichier avant que la position n'avance, et reprises au redémarrage si la passerelle s'arrête avant
+// leur aboutissement.
+func (p *passerelle) distribuer(ctx context.Context, ecouteur *client.EcouteurEvenements) {
+	go p.livrerEnAttente(ctx)
+
+	delai := delaiLivraison
+	for ctx.Err() == nil {
+		bloc, apresTx := p.webhooks.position()
+		err := ecouteur.Ecouter(ctx, bloc, apresTx, func(evenement client.EvenementChaincode) error {
+			delai = delaiLivraison
+			// Les alertes supplantées par l'événement émis, jointes à son contenu, sont poussées à part
+			var livraisons []livraison
+			for _, emis := range model.SeparerEvenements(evenement.Nom, evenement.Contenu) {
+				contenu := emis.Contenu
+				if !json.Valid(contenu) {
+					contenu, _ = json.Marshal(string(emis.Contenu))
+				}
+				corps, err := json.Marshal(notification{Evenement: emis.Nom, TxId: evenement.TxId, Bloc: evenement.Bloc, Contenu: contenu})
+				if err != nil {
+					return err
+				}
+				for _, w := range p.destinataires(emis.Nom, contenu) {
+					livraisons = append(livraisons, livraison{Webhook: w.Id, Evenement: emis.Nom, TxId: evenement.TxId, Corps: corps})
+				}
+			}
+			return p.webhooks.planifier(livraisons, evenement.Bloc, evenement.TxId)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("écoute des événements interrompue", slog.Any("erreur", err), slog.Duration("reprise", delai))
+		select {
+		case <-ctx.Done():
+		case <-time.After(delai):
+		}
+		delai = min(2*delai, delaiLivraisonMax)
+	}
+}
+
+// Webhooks auxquels pousser un événement : ceux des comptes registraires, et ceux des autres comptes
+// lorsque chaque titre désigné par l'événement relève de leur périmètre et que leur identité Fabric
+// peut le consulter. Un événement qui ne désigne aucun titre n'est poussé qu'aux registraires.
+func (p *passerelle) destinataires(evenement string, contenu []byte) []webhook {
+	titres := titresConcernes(contenu)
+	admis := make(map[string]bool) // Par compte
+	var webhooks []webhook
+	for _, w := range p.webhooks.destinataires(evenement) {
+		autorise, evalue := admis[w.Compte]
+		if !evalue {
+			autorise = p.suitEvenement(w.Compte, titres)
+			admis[w.Compte] = autorise
+		}
+		if autorise {
+			webhooks = append(webhooks, w)
+		}
+	}
+	return webhooks
+}
+
+func (p *passerelle) suitEvenement(id string, titres []string) bool {
+	i := slices.IndexFunc(p.comptes.Comptes, func(c compteService) bool { return c.Id == id })
+	if i < 0 {
+		return false
+	}
+	compte := &p.comptes.Comptes[i]
+	if compte.Portee == PorteeRegistraire {
+		return true
+	}
+	if len(titres) == 0 {
+		return false
+	}
+	for _, idTitre := range titres {
+		titre, err := p.registres[compte.Identite].LireTitreFoncier(idTitre)
+		if err != nil || !compte.suit(titre) {
+			return false
+		}
+	}
+	return true
+}
+
+// Titres désignés par le contenu d'un événement : champs idTitre, à toute profondeur, et clés de
+// titre des modifications d'un résumé de transaction
+func titresConcernes(contenu []byte) []string {
+	var valeur interface{}
+	if err := json.Unmarshal(contenu, &valeur); err != nil {
+		return nil
+	}
+	var titres []string
+	var parcourir func(v interface{})
+	parcourir = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if id, ok := v["idTitre"].(string); ok && id != "" {
+				titres = append(titres, id)
+			}
+			if attributs, ok := v["attributs"].([]interface{}); ok && v["type"] == model.TypeModificationTitre && len(attributs) > 0 {
+				if id, ok := attributs[0].(string); ok {
+					titres = append(titres, id)
+				}
+			}
+			for _, champ := range v {
+				parcourir(champ)
+			}
+		case []interface{}:
+			for _, element := range v {
+				parcourir(element)
+			}
+		}
+	}
+	parcourir(valeur)
+	slices.Sort(titres)
+	return slices.Compact(titres)
+}
+
+// Relever les livraisons dues et les confier aux livreurs, au plus livraisonsSimultanees à la fois
+func (p *passerelle) livrerEnAttente(ctx context.Context) {
+	places := make(chan struct{}, livraisonsSimultanees)
+	for ctx.Err() == nil {
+		livraisons, webhooks := p.webhooks.dues(time.Now(), livraisonsSimultanees-len(places))
+		for i := range livraisons {
+			places <- struct{}{}
+			go func(l livraison, w webhook) {
+				defer func() { <-places }()
+				err := livrer(ctx, w, l)
+				if ctx.Err() != nil {
+					return
+				}
+				if err := p.webhooks.conclure(l, err); err != nil {
+					slog.Error("enregistrement de la livraison impossible", slog.String("webhook", w.Id), slog.String("txId", l.TxId), slog.Any("erreur", err))
+				}
+			}(livraisons[i], webhooks[i])
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(intervalleLivraisons):
+		}
+	}
+}
+
+// Tenter de pousser un événement à un webhook. L'intégrateur vérifie X-Titrefoncier-Signature
+// (sha256=<HMAC hexadécimal de "<horodatage>.<corps>">), rejette les horodatages de plus de cinq minutes
+// et écarte les doublons par l'identifiant de livraison, constant d'une tentative à l'autre.
+func livrer(ctx context.Context, w webhook, l livraison) error {
+	horodatage := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(horodatage + "."))
+	mac.Write(l.Corps)
+	journal := slog.With(slog.String("webhook", w.Id), slog.String("compte", w.Compte), slog.String("evenement", l.Evenement), slog.String("txId", l.TxId))
+
+	err := envoyer(ctx, w.URL, l.Corps, map[string]string{
+		"X-Titrefoncier-Evenement":  l.Evenement,
+		"X-Titrefoncier-Livraison":  l.id(),
+		"X-Titrefoncier-Horodatage": horodatage,
+		"X-Titrefoncier-Signature":  "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+	})
+	switch {
+	case err == nil:
+		journal.Debug("événement livré", slog.Int("tentative", l.Tentative+1))
+	case l.Tentative+1 >= tentativesLivraison:
+		journal.Error("livraison abandonnée", slog.Int("tentatives", l.Tentative+1), slog.Any("erreur", err))
+	default:
+		journal.Warn("livraison échouée", slog.Int("tentative", l.Tentative+1), slog.Any("erreur", err))
+	}
+	return err
+}
+
+// Client des webhooks : les redirections restent en https, et chaque connexion est refusée vers une
+// adresse non publique, l'adresse résolue au moment de l'appel pouvant différer de celle vérifiée à
+// l'enregistrement
+var clientWebhooks = &http.Client{
+	Timeout: 15 * time.Second,
+	CheckRedirect: func(requete *http.Request, precedentes []*http.Request) error {
+		if requete.URL.Scheme != "https" || len(precedentes) >= 5 {
+			return errors.New("redirection refusée vers " + requete.URL.Redacted())
+		}
+		return nil
+	},
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(_ string, adresse string, _ syscall.RawConn) error {
+				hote, _, err := net.SplitHostPort(adresse)
+				if err != nil {
+					return err
+				}
+				if ip, err := netip.ParseAddr(hote); err != nil || !adressePublique(ip) {
+					return errors.New("connexion refusée vers l'adresse non publique " + hote)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+func envoyer(ctx context.Context, adresse string, corps []byte, entetes map[string]string) error {
+	requete, err := http.NewRequestWithContext(ctx, http.MethodPost, adresse, bytes.NewReader(corps))
+	if err != nil {
+		return err
+	}
+	requete.Header.Set("Content-Type", "application/json")
+	for nom, valeur := range entetes {
+		requete.Header.Set(nom, valeur)
+	}
+	reponse, err := clientWebhooks.Do(requete)
+	if err != nil {
+		return err
+	}
+	defer reponse.Body.Close()
+	if reponse.StatusCode < 200 || reponse.StatusCode > 299 {
+		return fmt.Errorf("réponse %s", reponse.Status)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// Chaincode déployé sur le canal
+const (
+	nomChaincode     = "titrefoncier"
+	versionChaincode = "1.0"
+	adresseService   = "titrefoncier.devnet:9999"
+)
+
+// Le pair de l'État endosse seul les transactions du réseau de développement
+const politiqueEndossement = "OR('EtatMSP.peer')"
+
+// Compiler le chaincode, installer son paquet de service, démarrer son conteneur puis l'approuver et
+// le valider sur le canal
+func (r *reseau) deployer() error {
+	slog.Info("compilation du chaincode", slog.String("source", r.source))
+	compilation := exec.Command("go", "build", "-o", filepath.Join(r.repertoire, "chaincode", nomChaincode), ".")
+	compilation.Dir = r.source
+	compilation.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux", "GOARCH="+runtime.GOARCH)
+	if sortie, err := compilation.CombinedOutput(); err != nil {
+		return fmt.Errorf("compilation du chaincode: %v: %s", err, bytes.TrimSpace(sortie))
+	}
+
+	paquet, err := r.empaqueter()
+	if err != nil {
+		return err
+	}
+	collections, err := r.ecrireCollections()
+	if err != nil {
+		return err
+	}
+
+	etat := organisations[0]
+	admin := identitePeer(etat.MSP, mspAdmin(etat))
+	slog.Info("installation du chaincode", slog.String("paquet", r.packageId))
+	if err := r.outil(admin, "peer", "lifecycle", "chaincode", "install", racineConteneur+"/chaincode/"+filepath.Base(paquet)); err != nil {
+		return err
+	}
+	if err := r.compose("up", "-d", "titrefoncier.devnet"); err != nil {
+		return err
+	}
+
+	definition := []string{"--channelID", r.canal, "--name", nomChaincode, "--version", versionChaincode, "--sequence", "1",
+		"--signature-policy", politiqueEndossement, "--collections-config", racineConteneur + "/chaincode/" + filepath.Base(collections)}
+	approbation := append([]string{"peer", "lifecycle", "chaincode", "approveformyorg", "--package-id", r.packageId}, definition...)
+	if err := r.outil(admin, append(approbation, optionsOrderer()...)...); err != nil {
+		return err
+	}
+	validation := append([]string{"peer", "lifecycle", "chaincode", "commit"}, definition...)
+	if err := r.outil(admin, append(validation, optionsInvoke()...)...); err != nil {
+		return err
+	}
+	slog.Info("chaincode déployé", slog.String("canal", r.canal), slog.String("chaincode", nomChaincode))
+	return nil
+}
+
+// Paquet chaincode-as-a-service : l'adresse du conteneur du chaincode et les index CouchDB du module.
+// L'identifiant du paquet est son libellé suivi de l'empreinte SHA-256 du fichier, comme le calcule le pair.
+func (r *reseau) empaqueter() (string, error) {
+	connexion, err := json.Marshal(map[string]interface{}{"address": adresseService, "dial_timeout": "10s", "tls_required": false})
+	if err != nil {
+		return "", err
+	}
+	fichiers := map[string][]byte{"connection.json": connexion}
+	index := filepath.Join(r.source, "META-INF")
+	err = filepath.WalkDir(index, func(chemin string, entree fs.DirEntry, err error) error {
+		if err != nil || entree.IsDir() {
+			return err
+		}
+		contenu, err := os.ReadFile(chemin)
+		if err != nil {
+			return err
+		}
+		relatif, err := filepath.Rel(r.source, chemin)
+		if err != nil {
+			return err
+		}
+		fichiers[filepath.ToSlash(relatif)] = contenu
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("index CouchDB: %v", err)
+	}
+	code, err := archiveTarGz(fichiers)
+	if err != nil {
+		return "", err
+	}
+
+	libelle := nomChaincode + "_" + versionChaincode
+	metadonnees, err := json.Marshal(map[string]string{"type": "ccaas", "label": libelle})
+	if err != nil {
+		return "", err
+	}
+	paquet, err := archiveTarGz(map[string][]byte{"metadata.json": metadonnees, "code.tar.gz": code})
+	if err != nil {
+		return "", err
+	}
+	empreinte := sha256.Sum256(paquet)
+	r.packageId = libelle + ":" + hex.EncodeToString(empreinte[:])
+
+	chemin := filepath.Join(r.repertoire, "chaincode", libelle+".tar.gz")
+	return chemin, os.WriteFile(chemin, paquet, 0o644)
+}
+
+// Collections privées du module, ramenées à un seul pair : la diffusion exigée par la configuration
+// de production ferait échouer toute écriture privée faute d'autre pair
+func (r *reseau) ecrireCollections() (string, error) {
+	contenu, err := os.ReadFile(filepath.Join(r.source, "collections_config.json"))
+	if err != nil {
+		return "", err
+	}
+	var collections []map[string]interface{}
+	if err := json.Unmarshal(contenu, &collections); err != nil {
+		return "", fmt.Errorf("collections_config.json invalide: %v", err)
+	}
+	for _, c := range collections {
+		c["requiredPeerCount"], c["maxPeerCount"] = 0, 0
+	}
+	if contenu, err = json.MarshalIndent(collections, "", "  "); err != nil {
+		return "", err
+	}
+	chemin := filepath.Join(r.repertoire, "chaincode", "collections_config.json")
+	return chemin, os.WriteFile(chemin, contenu, 0o644)
+}
+
+// Archive tar compressée, aux entrées triées pour que l'empreinte du paquet soit reproductible
+func archiveTarGz(fichiers map[string][]byte) ([]byte, error) {
+	noms := make([]string, 0, len(fichiers))
+	for nom := range fichiers {
+		noms = append(noms, nom)
+	}
+	slices.Sort(noms)
+
+	var tampon bytes.Buffer
+	compression := gzip.NewWriter(&tampon)
+	archive := tar.NewWriter(compression)
+	for _, nom := range noms {
+		if err := archive.WriteHeader(&tar.Header{Name: nom, Mode: 0o644, Size: int64(len(fichiers[nom])), Typeflag: tar.TypeReg}); err != nil {
+			return nil, err
+		}
+		if _, err := archive.Write(fichiers[nom]); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := compression.Close(); err != nil {
+		return nil, err
+	}
+	return tampon.Bytes(), nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"titrefoncier/pkg/client"
+)
+
+// Identités sous lesquelles les données d'exemple sont soumises, dans le répertoire de l'hôte
+func (r *reseau) identitesDonnees() map[string]client.Identite {
+	etat, notaires, geometres, passerelle := organisations[0], organisations[1], organisations[2], organisations[3]
+	identites := map[string]client.Identite{
+		"etat":       {MSP: etat.MSP, Configuration: r.hote(mspAdmin(etat))},
+		"notaires":   {MSP: notaires.MSP, Configuration: r.hote(mspAdmin(notaires))},
+		"geometres":  {MSP: geometres.MSP, Configuration: r.hote(mspAdmin(geometres))},
+		"passerelle": {MSP: passerelle.MSP, Configuration: r.hote(mspUtilisateur(passerelle, "User1"))},
+	}
+	for _, role := range identitesRoles {
+		identites[role.nom] = client.Identite{MSP: organisationNommee(role.organisation).MSP, Configuration: r.hote(mspRole(role.nom))}
+	}
+	return identites
+}
+
+// Communes administrées par le canal de développement
+var communesDonnees = []string{"Dakar-Plateau", "Medina", "Rufisque-Est", "Thies-Nord"}
+
+// Titres immatriculés par le conservateur ; chacun reçoit un document PDF propre
+var titresDonnees = []struct {
+	id, proprio, numTF string
+	superficie         int
+	commune            string
+}{
+	{"TF-DK-0001", "Awa Ndiaye", "1001/DK", 450, "Dakar-Plateau"},
+	{"TF-DK-0002", "Mamadou Diallo", "1002/DK", 820, "Dakar-Plateau"},
+	{"TF-DK-0003", "SCI Les Almadies", "1003/DK", 2400, "Dakar-Plateau"},
+	{"TF-MD-0001", "Fatou Sarr", "2001/MD", 300, "Medina"},
+	{"TF-MD-0002", "Cheikh Ba", "2002/MD", 275, "Medina"},
+	{"TF-RF-0001", "Ousmane Faye", "3001/RF", 1200, "Rufisque-Est"},
+	{"TF-RF-0002", "Aminata Gueye", "3002/RF", 950, "Rufisque-Est"},
+	{"TF-RF-0003", "Commune de Rufisque-Est", "3003/RF", 5000, "Rufisque-Est"},
+	{"TF-TH-0001", "Ibrahima Sy", "4001/TH", 1600, "Thies-Nord"},
+	{"TF-TH-0002", "Coumba Diop", "4002/TH", 700, "Thies-Nord"},
+}
+
+// Appel soumis sous une identité des données d'exemple
+type appelDonnees struct {
+	identite  string
+	fonction  string
+	arguments []string
+}
+
+// Opérations courantes sur les titres immatriculés : licences des professionnels, hypothèque,
+// transfert en attente, constat de terrain et signalement d'occupation
+func appelsDonnees() []appelDonnees {
+	configuration, _ := json.Marshal(map[string]interface{}{"communes": communesDonnees})
+	appels := []appelDonnees{
+		{"etat", "DefinirConfiguration", []string{string(configuration)}},
+		{"notaires", "EnregistrerLicence", []string{licenceNotaire, "notaire", "Me Aminata Sow", "2030-12-31"}},
+		{"geometres", "EnregistrerLicence", []string{licenceGeometre, "geometre", "Ibrahima Fall", "2030-12-31"}},
+	}
+	for _, t := range titresDonnees {
+		appels = append(appels, appelDonnees{"conservateur", "AjouterTitreFoncierCommune",
+			[]string{t.id, t.proprio, t.numTF, strconv.Itoa(t.superficie), cheminDocument(t.id), t.commune}})
+	}
+	return append(appels,
+		appelDonnees{"notaire", "InscrireHypotheque", []string{"TF-DK-0003", "Banque de l'Habitat du Sénégal", "85000000"}},
+		appelDonnees{"conservateur", "ProposerTransfert", []string{"TR-DK-0001", "TF-DK-0001", "Moussa Diop", "45000000"}},
+		appelDonnees{"inspecteur", "EnregistrerConstatTerrain", []string{"TF-RF-0002",
+			`{"type":"Polygon","coordinates":[[[-17.2731,14.7162],[-17.2722,14.7162],[-17.2722,14.7170],[-17.2731,14.7170],[-17.2731,14.7162]]]}`,
+			"[]", "Clôture conforme au plan de bornage"}},
+		appelDonnees{"passerelle", "SignalerOccupationIllegale", []string{"TF-RF-0003", "Construction sans autorisation sur la parcelle communale",
+			empreinte("photo-occupation-TF-RF-0003")}},
+	)
+}
+
+// Documents des titres, dans le partage monté par le conteneur du chaincode
+func cheminDocument(id string) string {
+	return "/mnt/shared_dir/" + id + ".pdf"
+}
+
+func empreinte(contenu string) string {
+	somme := sha256.Sum256([]byte(contenu))
+	return hex.EncodeToString(somme[:])
+}
+
+// Charger les données d'exemple ; un réseau déjà chargé est laissé en l'état
+func (r *reseau) charger() error {
+	pair, err := r.connecter()
+	if err != nil {
+		return err
+	}
+	defer pair.Fermer()
+	registres := make(map[string]*client.Registre)
+	for nom, identite := range r.identitesDonnees() {
+		if registres[nom], err = pair.Registre(identite); err != nil {
+			return fmt.Errorf("identité %s: %v", nom, err)
+		}
+	}
+	if _, err := registres["etat"].LireTitreFoncier(titresDonnees[0].id); err == nil {
+		slog.Info("données d'exemple déjà chargées", slog.String("titre", titresDonnees[0].id))
+		return nil
+	}
+
+	// Un PDF minimal par titre : le chaincode en contrôle le type et en calcule l'empreinte
+	for _, t := range titresDonnees {
+		document := fmt.Sprintf("%%PDF-1.4\n%% Titre foncier %s (%s), %s\n%%%%EOF\n", t.id, t.numTF, t.commune)
+		if err := os.WriteFile(filepath.Join(r.repertoire, "documents", t.id+".pdf"), []byte(document), 0o644); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	for _, appel := range appelsDonnees() {
+		if _, err := registres[appel.identite].Soumettre(ctx, appel.fonction, appel.arguments...); err != nil {
+			return fmt.Errorf("%s (%s): %w", appel.fonction, appel.identite, err)
+		}
+		slog.Info("donnée chargée", slog.String("fonction", appel.fonction), slog.String("identite", appel.identite))
+	}
+	return nil
+}
+
+// Fichier des comptes de la commande api, aux identités du réseau : une clé d'API par portée,
+// affichée une seule fois, la passerelle n'en conservant que l'empreinte
+func (r *reseau) ecrireComptes(w io.Writer) error {
+	passerelle := organisations[3]
+	type compte struct {
+		Id        string `json:"id"`
+		Empreinte string `json:"empreinte"`
+		Portee    string `json:"portee"`
+		Identite  string `json:"identite"`
+	}
+	comptes := struct {
+		Identites map[string]client.Identite `json:"identites"`
+		Comptes   []compte                   `json:"comptes"`
+	}{Identites: map[string]client.Identite{
+		"conservateur": {MSP: organisationNommee("Conservation").MSP, Configuration: r.hote(mspRole("conservateur"))},
+		"lecture":      {MSP: passerelle.MSP, Configuration: r.hote(mspUtilisateur(passerelle, "User1"))},
+		"verification": {MSP: passerelle.MSP, Configuration: r.hote(mspUtilisateur(passerelle, "User2"))},
+	}}
+
+	fmt.Fprintf(w, "Clés d'API de la passerelle (api -comptes %s) :\n", filepath.Join(r.repertoire, "comptes.json"))
+	for _, portee := range []struct{ nom, identite string }{{"verification", "verification"}, {"lecture", "lecture"}, {"registraire", "conservateur"}} {
+		octets := make([]byte, 24)
+		if _, err := rand.Read(octets); err != nil {
+			return err
+		}
+		cle := hex.EncodeToString(octets)
+		comptes.Comptes = append(comptes.Comptes, compte{Id: "devnet-" + portee.nom, Empreinte: empreinte(cle), Portee: portee.nom, Identite: portee.identite})
+		fmt.Fprintf(w, "  %-12s %s\n", portee.nom, cle)
+	}
+	contenu, err := json.MarshalIndent(comptes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.repertoire, "comptes.json"), contenu, 0o600); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nService Gateway du pair, depuis l'hôte :\n")
+	fmt.Fprintf(w, "  export CORE_PEER_ADDRESS=%s CORE_PEER_TLS_ENABLED=true CORE_PEER_TLS_ROOTCERT_FILE=%s\n", adressePairHote, r.hote(pairTLSCA))
+	fmt.Fprintf(w, "  api -canal %s -comptes %s\n", r.canal, filepath.Join(r.repertoire, "comptes.json"))
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Identité de rôle enrôlée auprès de l'autorité de l'organisation qui délivre ce rôle (acces.go), avec
+// les attributs lus par le chaincode
+type identiteRole struct {
+	nom          string
+	organisation string   // Nom de l'organisation émettrice
+	attributs    []string // nom=valeur, inscrits au certificat (:ecert)
+}
+
+var identitesRoles = []identiteRole{
+	{nom: "conservateur", organisation: "Conservation", attributs: []string{"role=conservateur", "bureau=Dakar-Plateau"}},
+	{nom: "inspecteur", organisation: "Conservation", attributs: []string{"role=inspecteur", "bureau=Dakar-Plateau"}},
+	{nom: "notaire", organisation: "ChambreNotaires", attributs: []string{"role=notaire", "licence=" + licenceNotaire}},
+	{nom: "geometre", organisation: "OrdreGeometres", attributs: []string{"role=geometre", "licence=" + licenceGeometre}},
+	{nom: "mairie", organisation: "Communes", attributs: []string{"role=mairie", "commune=Rufisque-Est"}},
+	{nom: "auditeur", organisation: "Etat", attributs: []string{"role=auditeur"}},
+}
+
+// Licences délivrées par les ordres aux identités de rôle (voir donnees.go)
+const (
+	licenceNotaire  = "N-DK-2024-001"
+	licenceGeometre = "G-DK-2024-017"
+)
+
+// Chaque autorité est servie, sans TLS, par le conteneur ca.<domaine> de son organisation, où elle
+// est appelée
+const adresseAutorite = "localhost:7054"
+
+// Les certificats émis portent l'unité organisationnelle de leur type (client, admin, peer)
+const configurationNodeOUs = `NodeOUs:
+  Enable: true
+  ClientOUIdentifier:
+    Certificate: cacerts/localhost-7054.pem
+    OrganizationalUnitIdentifier: client
+  PeerOUIdentifier:
+    Certificate: cacerts/localhost-7054.pem
+    OrganizationalUnitIdentifier: peer
+  AdminOUIdentifier:
+    Certificate: cacerts/localhost-7054.pem
+    OrganizationalUnitIdentifier: admin
+  OrdererOUIdentifier:
+    Certificate: cacerts/localhost-7054.pem
+    OrganizationalUnitIdentifier: orderer
+`
+
+// MSP d'une identité de rôle
+func mspRole(nom string) string {
+	return racineConteneur + "/identites/" + nom + "/msp"
+}
+
+// Enrôler les identités de rôle ; chaque autorité reprend la clé de l'autorité cryptogen de son
+// organisation, de sorte que ses certificats sont reconnus par le MSP de l'organisation sur le canal
+func (r *reseau) enroler() error {
+	for _, identite := range identitesRoles {
+		o := organisationNommee(identite.organisation)
+		administrateur := racineConteneur + "/ca-client/" + o.Domaine
+		if err := reessayer(func() error {
+			return r.autorite(o, "enroll", "-u", "http://admin:adminpw@"+adresseAutorite, "-H", administrateur)
+		}); err != nil {
+			return err
+		}
+
+		attributs := make([]string, len(identite.attributs))
+		for i, a := range identite.attributs {
+			attributs[i] = a + ":ecert"
+		}
+		// Le MSP est créé d'avance : sa configuration, écrite ici, ne bute pas sur les droits des
+		// fichiers que le conteneur écrit en root
+		msp := filepath.Join(r.repertoire, "identites", identite.nom, "msp")
+		if err := os.MkdirAll(msp, 0o755); err != nil {
+			return err
+		}
+		secret := identite.nom + "pw"
+		if err := r.autorite(o, "register", "-H", administrateur, "--id.name", identite.nom, "--id.secret", secret,
+			"--id.type", "client", "--id.attrs", strings.Join(attributs, ",")); err != nil {
+			return err
+		}
+		if err := r.autorite(o, "enroll", "-u", "http://"+identite.nom+":"+secret+"@"+adresseAutorite, "-M", mspRole(identite.nom)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(msp, "config.yaml"), []byte(configurationNodeOUs), 0o644); err != nil {
+			return fmt.Errorf("identité %s: %v", identite.nom, err)
+		}
+		slog.Info("identité enrôlée", slog.String("identite", identite.nom), slog.String("msp", o.MSP), slog.Any("attributs", identite.attributs))
+	}
+	return nil
+}
+
+// Exécuter le client de l'autorité d'une organisation dans son conteneur
+func (r *reseau) autorite(o organisation, arguments ...string) error {
+	_, _, err := r.executerCompose(argumentsExec("ca."+o.Domaine, nil, append([]string{"fabric-ca-client"}, arguments...))...)
+	return err
+}
@@ -0,0 +1,76 @@
+// Commande devnet : réseau Fabric de développement pour les intégrateurs. Elle génère dans un
+// répertoire de travail les organisations (l'État, seule à tenir un pair, et les organisations
+// clientes des notaires, géomètres, de la passerelle et de la CENTIF), démarre orderer, pair, CouchDB
+// et autorité de certification sous Docker, crée le canal et y déploie ce chaincode en service
+// (chaincode-as-a-service).
+//
+//	devnet -repertoire ./devnet up      # réseau, canal, chaincode, identités de rôle et données
+//	devnet -repertoire ./devnet seed    # données d'exemple seules, sur un réseau déjà démarré
+//	devnet -repertoire ./devnet down    # arrêt du réseau et suppression du répertoire
+//
+// Les identités de rôle (conservateur, inspecteur, notaire, géomètre, mairie, auditeur) sont
+// enrôlées auprès de l'autorité de l'État avec les attributs que le chaincode contrôle. up écrit
+// aussi un fichier de comptes pour la commande api et affiche les clés d'API de ses comptes.
+// Seuls Docker (compose) et la chaîne Go sont requis : les outils Fabric tournent en conteneurs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	repertoire := flag.String("repertoire", "devnet", "répertoire de travail du réseau")
+	canal := flag.String("canal", "devnet", "canal créé et servi par le réseau")
+	source := flag.String("source", ".", "racine du module du chaincode")
+	versionFabric := flag.String("fabric", "2.5", "version des images Hyperledger Fabric")
+	versionCA := flag.String("fabric-ca", "1.5", "version de l'image Fabric CA")
+	sansDonnees := flag.Bool("sans-donnees", false, "ne pas charger les données d'exemple au démarrage")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: devnet [options] up|seed|down\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	absolu, err := filepath.Abs(*repertoire)
+	if err != nil {
+		slog.Error("répertoire de travail invalide", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	r := &reseau{
+		repertoire:    absolu,
+		canal:         *canal,
+		source:        *source,
+		versionFabric: *versionFabric,
+		versionCA:     *versionCA,
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		err = r.demarrer()
+		if err == nil && !*sansDonnees {
+			err = r.charger()
+		}
+		if err == nil {
+			err = r.ecrireComptes(os.Stdout)
+		}
+	case "seed":
+		err = r.charger()
+	case "down":
+		err = r.arreter()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("devnet "+flag.Arg(0)+" interrompu", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import "text/template"
+
+// Organisations générées par cryptogen ; deux utilisateurs par organisation, dont la passerelle
+// REST tire ses identités de lecture et de vérification
+var modeleCrypto = template.Must(template.New("crypto-config").Parse(`OrdererOrgs:
+  - Name: Orderer
+    Domain: devnet
+    EnableNodeOUs: true
+    Specs:
+      - Hostname: orderer
+        SANS: [localhost, 127.0.0.1]
+PeerOrgs:
+{{- range .Organisations}}
+  - Name: {{.Nom}}
+    Domain: {{.Domaine}}
+    EnableNodeOUs: true
+    Template:
+      Count: {{if .Pair}}1{{else}}0{{end}}
+      SANS: [localhost, 127.0.0.1]
+    Users:
+      Count: 2
+{{- end}}
+`))
+
+// Canal du registre : les organisations sans pair n'endossent rien, d'où les politiques ANY
+// d'endossement et de cycle de vie, satisfaites par le seul pair de l'État
+var modeleConfigtx = template.Must(template.New("configtx").Parse(`Organizations:
+  - &OrdererOrg
+    Name: OrdererOrg
+    ID: OrdererMSP
+    MSPDir: organisations/ordererOrganizations/devnet/msp
+    OrdererEndpoints: [orderer.devnet:7050]
+    Policies:
+      Readers: {Type: Signature, Rule: "OR('OrdererMSP.member')"}
+      Writers: {Type: Signature, Rule: "OR('OrdererMSP.member')"}
+      Admins: {Type: Signature, Rule: "OR('OrdererMSP.admin')"}
+{{- range .Organisations}}
+  - &{{.Nom}}
+    Name: {{.Nom}}
+    ID: {{.MSP}}
+    MSPDir: organisations/peerOrganizations/{{.Domaine}}/msp
+    Policies:
+      Readers: {Type: Signature, Rule: "OR('{{.MSP}}.admin', '{{.MSP}}.peer', '{{.MSP}}.client')"}
+      Writers: {Type: Signature, Rule: "OR('{{.MSP}}.admin', '{{.MSP}}.client')"}
+      Admins: {Type: Signature, Rule: "OR('{{.MSP}}.admin')"}
+      Endorsement: {Type: Signature, Rule: "OR('{{.MSP}}.peer')"}
+{{- end}}
+
+Capabilities:
+  Channel: &CapacitesCanal {V2_0: true}
+  Orderer: &CapacitesOrderer {V2_0: true}
+  Application: &CapacitesApplication {V2_0: true}
+
+Application: &Application
+  Organizations:
+  Policies:
+    Readers: {Type: ImplicitMeta, Rule: "ANY Readers"}
+    Writers: {Type: ImplicitMeta, Rule: "ANY Writers"}
+    Admins: {Type: ImplicitMeta, Rule: "MAJORITY Admins"}
+    LifecycleEndorsement: {Type: ImplicitMeta, Rule: "ANY Endorsement"}
+    Endorsement: {Type: ImplicitMeta, Rule: "ANY Endorsement"}
+  Capabilities: *CapacitesApplication
+
+Orderer: &Orderer
+  OrdererType: etcdraft
+  EtcdRaft:
+    Consenters:
+      - Host: orderer.devnet
+        Port: 7050
+        ClientTLSCert: organisations/ordererOrganizations/devnet/orderers/orderer.devnet/tls/server.crt
+        ServerTLSCert: organisations/ordererOrganizations/devnet/orderers/orderer.devnet/tls/server.crt
+  BatchTimeout: 1s
+  BatchSize:
+    MaxMessageCount: 10
+    AbsoluteMaxBytes: 99 MB
+    PreferredMaxBytes: 512 KB
+  Organizations:
+  Policies:
+    Readers: {Type: ImplicitMeta, Rule: "ANY Readers"}
+    Writers: {Type: ImplicitMeta, Rule: "ANY Writers"}
+    Admins: {Type: ImplicitMeta, Rule: "MAJORITY Admins"}
+    BlockValidation: {Type: ImplicitMeta, Rule: "ANY Writers"}
+  Capabilities: *CapacitesOrderer
+
+Channel: &Canal
+  Policies:
+    Readers: {Type: ImplicitMeta, Rule: "ANY Readers"}
+    Writers: {Type: ImplicitMeta, Rule: "ANY Writers"}
+    Admins: {Type: ImplicitMeta, Rule: "MAJORITY Admins"}
+  Capabilities: *CapacitesCanal
+
+Profiles:
+  Registre:
+    <<: *Canal
+    Orderer:
+      <<: *Orderer
+      Organizations: [*OrdererOrg]
+    Application:
+      <<: *Application
+      Organizations:
+{{- range .Organisations}}
+        - *{{.Nom}}
+{{- end}}
+`))
+
+// Conteneurs du réseau. Le chaincode tourne en service (CHAINCODE_ID fixé une fois son paquet
+// installé) et lit les documents des titres dans documents/, monté comme le partage NFS des pairs.
+var modeleCompose = template.Must(template.New("compose").Parse(`name: devnet
+
+networks:
+  devnet:
+    name: devnet
+
+services:
+  orderer.devnet:
+    image: hyperledger/fabric-orderer:{{.VersionFabric}}
+    environment:
+      - ORDERER_GENERAL_LISTENADDRESS=0.0.0.0
+      - ORDERER_GENERAL_LISTENPORT=7050
+      - ORDERER_GENERAL_LOCALMSPID=OrdererMSP
+      - ORDERER_GENERAL_LOCALMSPDIR=/var/hyperledger/orderer/msp
+      - ORDERER_GENERAL_TLS_ENABLED=true
+      - ORDERER_GENERAL_TLS_PRIVATEKEY=/var/hyperledger/orderer/tls/server.key
+      - ORDERER_GENERAL_TLS_CERTIFICATE=/var/hyperledger/orderer/tls/server.crt
+      - ORDERER_GENERAL_TLS_ROOTCAS=[/var/hyperledger/orderer/tls/ca.crt]
+      - ORDERER_GENERAL_CLUSTER_CLIENTCERTIFICATE=/var/hyperledger/orderer/tls/server.crt
+      - ORDERER_GENERAL_CLUSTER_CLIENTPRIVATEKEY=/var/hyperledger/orderer/tls/server.key
+      - ORDERER_GENERAL_CLUSTER_ROOTCAS=[/var/hyperledger/orderer/tls/ca.crt]
+      - ORDERER_GENERAL_BOOTSTRAPMETHOD=none
+      - ORDERER_CHANNELPARTICIPATION_ENABLED=true
+      - ORDERER_ADMIN_LISTENADDRESS=0.0.0.0:7053
+      - ORDERER_ADMIN_TLS_ENABLED=true
+      - ORDERER_ADMIN_TLS_PRIVATEKEY=/var/hyperledger/orderer/tls/server.key
+      - ORDERER_ADMIN_TLS_CERTIFICATE=/var/hyperledger/orderer/tls/server.crt
+      - ORDERER_ADMIN_TLS_ROOTCAS=[/var/hyperledger/orderer/tls/ca.crt]
+      - ORDERER_ADMIN_TLS_CLIENTAUTHREQUIRED=true
+      - ORDERER_ADMIN_TLS_CLIENTROOTCAS=[/var/hyperledger/orderer/tls/ca.crt]
+    volumes:
+      - ./organisations/ordererOrganizations/devnet/orderers/orderer.devnet/msp:/var/hyperledger/orderer/msp
+      - ./organisations/ordererOrganizations/devnet/orderers/orderer.devnet/tls:/var/hyperledger/orderer/tls
+    ports:
+      - 7050:7050
+    networks: [devnet]
+
+  couchdb.etat.devnet:
+    image: couchdb:3.3.3
+    environment:
+      - COUCHDB_USER=admin
+      - COUCHDB_PASSWORD=adminpw
+    ports:
+      - 5984:5984
+    networks: [devnet]
+
+  peer0.etat.devnet:
+    image: hyperledger/fabric-peer:{{.VersionFabric}}
+    environment:
+      - CORE_PEER_ID=peer0.etat.devnet
+      - CORE_PEER_ADDRESS=peer0.etat.devnet:7051
+      - CORE_PEER_LISTENADDRESS=0.0.0.0:7051
+      - CORE_PEER_CHAINCODEADDRESS=peer0.etat.devnet:7052
+      - CORE_PEER_CHAINCODELISTENADDRESS=0.0.0.0:7052
+      - CORE_PEER_GOSSIP_EXTERNALENDPOINT=peer0.etat.devnet:7051
+      - CORE_PEER_GOSSIP_BOOTSTRAP=peer0.etat.devnet:7051
+      - CORE_PEER_LOCALMSPID=EtatMSP
+      - CORE_PEER_MSPCONFIGPATH=/etc/hyperledger/fabric/msp
+      - CORE_PEER_TLS_ENABLED=true
+      - CORE_PEER_TLS_CERT_FILE=/etc/hyperledger/fabric/tls/server.crt
+      - CORE_PEER_TLS_KEY_FILE=/etc/hyperledger/fabric/tls/server.key
+      - CORE_PEER_TLS_ROOTCERT_FILE=/etc/hyperledger/fabric/tls/ca.crt
+      - CORE_LEDGER_STATE_STATEDATABASE=CouchDB
+      - CORE_LEDGER_STATE_COUCHDBCONFIG_COUCHDBADDRESS=couchdb.etat.devnet:5984
+      - CORE_LEDGER_STATE_COUCHDBCONFIG_USERNAME=admin
+      - CORE_LEDGER_STATE_COUCHDBCONFIG_PASSWORD=adminpw
+    volumes:
+      - ./organisations/peerOrganizations/etat.devnet/peers/peer0.etat.devnet/msp:/etc/hyperledger/fabric/msp
+      - ./organisations/peerOrganizations/etat.devnet/peers/peer0.etat.devnet/tls:/etc/hyperledger/fabric/tls
+    ports:
+      - 7051:7051
+    depends_on: [couchdb.etat.devnet]
+    networks: [devnet]
+{{range .Organisations}}{{if .Autorite}}
+  ca.{{.Domaine}}:
+    image: hyperledger/fabric-ca:{{$.VersionCA}}
+    command: >-
+      fabric-ca-server start -b admin:adminpw --port 7054
+      --ca.certfile /devnet/organisations/peerOrganizations/{{.Domaine}}/ca/ca.{{.Domaine}}-cert.pem
+      --ca.keyfile /devnet/organisations/peerOrganizations/{{.Domaine}}/ca/priv_sk
+    environment:
+      - FABRIC_CA_HOME=/devnet/ca/{{.Domaine}}
+    volumes:
+      - .:/devnet
+    networks: [devnet]
+{{end}}{{end}}
+  titrefoncier.devnet:
+    image: alpine:3.20
+    command: [/chaincode/titrefoncier]
+    environment:
+      - CHAINCODE_SERVER_ADDRESS=0.0.0.0:9999
+      - CHAINCODE_ID=${CHAINCODE_ID:-}
+    volumes:
+      - ./chaincode:/chaincode:ro
+      - ./documents:/mnt/shared_dir:ro
+    networks: [devnet]
+
+  outils:
+    image: hyperledger/fabric-tools:{{.VersionFabric}}
+    command: [tail, -f, /dev/null]
+    working_dir: /devnet
+    environment:
+      - CORE_PEER_ADDRESS=peer0.etat.devnet:7051
+      - CORE_PEER_TLS_ENABLED=true
+      - CORE_PEER_TLS_ROOTCERT_FILE=/devnet/organisations/peerOrganizations/etat.devnet/peers/peer0.etat.devnet/tls/ca.crt
+    volumes:
+      - .:/devnet
+    networks: [devnet]
+`))
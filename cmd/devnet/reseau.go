@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"titrefoncier/pkg/client"
+)
+
+// Organisation du réseau ; les identifiants MSP sont ceux que le chaincode contrôle (acces.go)
+type organisation struct {
+	Nom      string
+	MSP      string
+	Domaine  string
+	Pair     bool // Seule l'État tient un pair : les autres organisations ne font que soumettre
+	Autorite bool // Autorité fabric-ca enrôlant les identités de rôle que l'organisation délivre
+}
+
+var organisations = []organisation{
+	{Nom: "Etat", MSP: "EtatMSP", Domaine: "etat.devnet", Pair: true, Autorite: true},
+	{Nom: "ChambreNotaires", MSP: "ChambreNotairesMSP", Domaine: "notaires.devnet", Autorite: true},
+	{Nom: "OrdreGeometres", MSP: "OrdreGeometresMSP", Domaine: "geometres.devnet", Autorite: true},
+	{Nom: "Passerelle", MSP: "PasserelleMSP", Domaine: "passerelle.devnet"},
+	{Nom: "Centif", MSP: "CentifMSP", Domaine: "centif.devnet"},
+	{Nom: "Conservation", MSP: "ConservationMSP", Domaine: "conservation.devnet", Autorite: true},
+	{Nom: "Communes", MSP: "CommunesMSP", Domaine: "communes.devnet", Autorite: true},
+}
+
+// Organisation de ce nom
+func organisationNommee(nom string) organisation {
+	for _, o := range organisations {
+		if o.Nom == nom {
+			return o
+		}
+	}
+	panic("organisation inconnue: " + nom)
+}
+
+// Chemins dans les conteneurs, où le répertoire de travail est monté en /devnet
+const (
+	racineConteneur = "/devnet"
+	ordererTLSCA    = racineConteneur + "/organisations/ordererOrganizations/devnet/orderers/orderer.devnet/tls/ca.crt"
+	adminOrderer    = racineConteneur + "/organisations/ordererOrganizations/devnet/users/Admin@devnet/tls"
+	pairTLSCA       = racineConteneur + "/organisations/peerOrganizations/etat.devnet/peers/peer0.etat.devnet/tls/ca.crt"
+	adressePair     = "peer0.etat.devnet:7051"
+	adresseOrderer  = "orderer.devnet:7050"
+)
+
+// Port du pair publié sur l'hôte, dont le certificat TLS couvre localhost
+const adressePairHote = "localhost:7051"
+
+// Réseau de développement décrit par son répertoire de travail
+type reseau struct {
+	repertoire    string
+	canal         string
+	source        string
+	versionFabric string
+	versionCA     string
+	packageId     string // Identifiant du paquet de chaincode installé, pour son conteneur
+}
+
+// MSP de l'administrateur d'une organisation, générée par cryptogen
+func mspAdmin(o organisation) string {
+	return fmt.Sprintf("%s/organisations/peerOrganizations/%s/users/Admin@%s/msp", racineConteneur, o.Domaine, o.Domaine)
+}
+
+// MSP d'un utilisateur (User1, User2) d'une organisation
+func mspUtilisateur(o organisation, utilisateur string) string {
+	return fmt.Sprintf("%s/organisations/peerOrganizations/%s/users/%s@%s/msp", racineConteneur, o.Domaine, utilisateur, o.Domaine)
+}
+
+// Démarrer le réseau : organisations, conteneurs, canal, chaincode et identités de rôle
+func (r *reseau) demarrer() error {
+	if _, err := os.Stat(r.repertoire); err == nil {
+		return fmt.Errorf("le répertoire %s existe déjà (devnet down pour repartir de zéro)", r.repertoire)
+	}
+	for _, sous := range []string{"canal", "chaincode", "documents"} {
+		if err := os.MkdirAll(filepath.Join(r.repertoire, sous), 0o755); err != nil {
+			return err
+		}
+	}
+	for fichier, modele := range map[string]*template.Template{"crypto-config.yaml": modeleCrypto, "configtx.yaml": modeleConfigtx, "compose.yaml": modeleCompose} {
+		if err := r.ecrireModele(fichier, modele); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("génération des organisations")
+	if err := r.compose("up", "-d", "outils"); err != nil {
+		return err
+	}
+	if err := r.outil(nil, "cryptogen", "generate", "--config", racineConteneur+"/crypto-config.yaml", "--output", racineConteneur+"/organisations"); err != nil {
+		return err
+	}
+	bloc := fmt.Sprintf("%s/canal/%s.block", racineConteneur, r.canal)
+	if err := r.outil(nil, "configtxgen", "-configPath", racineConteneur, "-profile", "Registre", "-channelID", r.canal, "-outputBlock", bloc); err != nil {
+		return err
+	}
+
+	slog.Info("démarrage des conteneurs")
+	services := []string{"up", "-d", "orderer.devnet", "couchdb.etat.devnet", "peer0.etat.devnet"}
+	for _, o := range organisations {
+		if o.Autorite {
+			services = append(services, "ca."+o.Domaine)
+		}
+	}
+	if err := r.compose(services...); err != nil {
+		return err
+	}
+
+	slog.Info("création du canal", slog.String("canal", r.canal))
+	if err := reessayer(func() error {
+		return r.outil(nil, "osnadmin", "channel", "join", "--channelID", r.canal, "--config-block", bloc, "-o", "orderer.devnet:7053",
+			"--ca-file", ordererTLSCA, "--client-cert", adminOrderer+"/client.crt", "--client-key", adminOrderer+"/client.key")
+	}); err != nil {
+		return err
+	}
+	etat := organisations[0]
+	if err := reessayer(func() error {
+		return r.outil(identitePeer(etat.MSP, mspAdmin(etat)), "peer", "channel", "join", "-b", bloc)
+	}); err != nil {
+		return err
+	}
+
+	if err := r.deployer(); err != nil {
+		return err
+	}
+	if err := r.enroler(); err != nil {
+		return err
+	}
+	return r.rendre()
+}
+
+// Chemin sur l'hôte d'un fichier du répertoire de travail monté dans les conteneurs
+func (r *reseau) hote(chemin string) string {
+	return filepath.Join(r.repertoire, chemin[len(racineConteneur):])
+}
+
+// Se connecter depuis l'hôte au service Gateway du pair de l'État, pour le chaincode du canal
+func (r *reseau) connecter() (*client.Passerelle, error) {
+	return client.Connecter(client.Pair{Adresse: adressePairHote, CA: r.hote(pairTLSCA)}, r.canal, nomChaincode)
+}
+
+// Rendre à l'utilisateur les fichiers que les conteneurs écrivent en root, clés comprises, pour que
+// les applications de l'hôte puissent s'en servir
+func (r *reseau) rendre() error {
+	return r.outil(nil, "chown", "-R", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()), racineConteneur)
+}
+
+// Arrêter le réseau et supprimer son répertoire ; les fichiers écrits par les conteneurs, qui
+// appartiennent à root, sont supprimés depuis le conteneur d'outils
+func (r *reseau) arreter() error {
+	if _, err := os.Stat(filepath.Join(r.repertoire, "compose.yaml")); err != nil {
+		return fmt.Errorf("aucun réseau dans %s", r.repertoire)
+	}
+	if err := r.compose("up", "-d", "outils"); err == nil {
+		r.outil(nil, "sh", "-c", "rm -rf "+racineConteneur+"/*")
+	}
+	if err := r.compose("down", "--volumes", "--remove-orphans"); err != nil {
+		return err
+	}
+	return os.RemoveAll(r.repertoire)
+}
+
+func (r *reseau) ecrireModele(fichier string, modele *template.Template) error {
+	var contenu bytes.Buffer
+	if err := modele.Execute(&contenu, r); err != nil {
+		return fmt.Errorf("%s: %v", fichier, err)
+	}
+	return os.WriteFile(filepath.Join(r.repertoire, fichier), contenu.Bytes(), 0o644)
+}
+
+// Organisations du réseau, pour les modèles
+func (r *reseau) Organisations() []organisation {
+	return organisations
+}
+
+func (r *reseau) VersionFabric() string { return r.versionFabric }
+func (r *reseau) VersionCA() string     { return r.versionCA }
+
+// Exécuter docker compose sur le projet du réseau
+func (r *reseau) compose(arguments ...string) error {
+	_, _, err := r.executerCompose(arguments...)
+	return err
+}
+
+// Sortie et journal d'une commande docker compose ; l'erreur reprend le journal
+func (r *reseau) executerCompose(arguments ...string) ([]byte, []byte, error) {
+	args := append([]string{"compose", "-p", "devnet", "-f", filepath.Join(r.repertoire, "compose.yaml")}, arguments...)
+	var sortie, journal bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Env = append(os.Environ(), "CHAINCODE_ID="+r.packageId)
+	cmd.Stdout = &sortie
+	cmd.Stderr = &journal
+	if err := cmd.Run(); err != nil {
+		return nil, journal.Bytes(), fmt.Errorf("docker %s: %v: %s", strings.Join(arguments, " "), err, bytes.TrimSpace(journal.Bytes()))
+	}
+	return bytes.TrimSpace(sortie.Bytes()), journal.Bytes(), nil
+}
+
+// Exécuter un outil Fabric dans le conteneur d'outils, avec des variables d'environnement
+func (r *reseau) outil(env []string, arguments ...string) error {
+	_, _, err := r.executerCompose(argumentsExec("outils", env, arguments)...)
+	return err
+}
+
+func argumentsExec(service string, env []string, arguments []string) []string {
+	args := []string{"exec", "-T"}
+	for _, variable := range env {
+		args = append(args, "-e", variable)
+	}
+	return append(append(args, service), arguments...)
+}
+
+// Variables de la CLI peer pour une identité
+func identitePeer(msp string, configuration string) []string {
+	return []string{"CORE_PEER_LOCALMSPID=" + msp, "CORE_PEER_MSPCONFIGPATH=" + configuration}
+}
+
+// Options de la CLI peer désignant l'orderer
+func optionsOrderer() []string {
+	return []string{"-o", adresseOrderer, "--tls", "--cafile", ordererTLSCA}
+}
+
+// Options de la CLI peer désignant l'orderer et le pair endosseur
+func optionsInvoke() []string {
+	return append(optionsOrderer(), "--peerAddresses", adressePair, "--tlsRootCertFiles", pairTLSCA)
+}
+
+// Reprendre une étape tant que les conteneurs qu'elle sollicite démarrent
+func reessayer(etape func() error) error {
+	var err error
+	for tentative := 0; tentative < 15; tentative++ {
+		if err = etape(); err == nil {
+			return nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+// Commande export : écrit l'extrait CSV ou XLSX des titres d'une commune, colonnes choisies et
+// période d'immatriculation facultative, pour les bureaux fonciers qui alimentent des tableurs.
+// Les titres sont écrits page par page, à mesure de leur lecture sur le registre.
+//
+//	export -canal dakar -commune Dakar-Plateau -du 2024-01-01 -au 2024-12-31 \
+//	  -colonnes id,numTF,proprio,superficie,zones -format xlsx -sortie plateau-2024.xlsx
+//
+// Les titres sont lus par le service Gateway du pair -pair, sous l'identité -msp ; pair et identité
+// valent par défaut les variables CORE_PEER_* de la CLI peer.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/extrait"
+)
+
+func main() {
+	options := client.DeclarerOptions(flag.CommandLine)
+	options.DeclarerIdentite(flag.CommandLine)
+	commune := flag.String("commune", "", "commune dont les titres sont extraits")
+	du := flag.String("du", "", "titres immatriculés à partir de cette date (AAAA-MM-JJ)")
+	au := flag.String("au", "", "titres immatriculés jusqu'à cette date incluse (AAAA-MM-JJ)")
+	noms := flag.String("colonnes", strings.Join(extrait.ColonnesParDefaut, ","), "colonnes de l'extrait ("+strings.Join(extrait.NomsColonnes(), ", ")+")")
+	format := flag.String("format", extrait.FormatCSV, "format de l'extrait (csv ou xlsx)")
+	fichier := flag.String("sortie", "", "fichier de l'extrait (sortie standard par défaut)")
+	flag.Parse()
+
+	colonnes, err := extrait.Colonnes(strings.Split(*noms, ","))
+	if err != nil {
+		slog.Error("colonnes invalides", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	if err := extrait.ValiderFormat(*format); err != nil {
+		slog.Error("format invalide", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	filtre := extrait.Filtre{Commune: *commune, Du: *du, Au: *au}
+	if err := filtre.Valider(); err != nil {
+		slog.Error("filtre invalide", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+
+	passerelle, registre, err := options.Registre()
+	if err != nil {
+		slog.Error("connexion au registre impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	defer passerelle.Fermer()
+
+	var sortie io.Writer = os.Stdout
+	if *fichier != "" {
+		f, err := os.Create(*fichier)
+		if err != nil {
+			slog.Error("création de l'extrait impossible", slog.Any("erreur", err))
+			os.Exit(1)
+		}
+		defer f.Close()
+		sortie = f
+	}
+	tampon := bufio.NewWriter(sortie)
+
+	nombre, err := extrait.Exporter(registre, filtre, colonnes, func() (extrait.Ecrivain, error) {
+		return extrait.NouvelEcrivain(*format, tampon, colonnes)
+	})
+	if err == nil {
+		err = tampon.Flush()
+	}
+	if err != nil {
+		slog.Error("extrait interrompu", slog.Int("titres", nombre), slog.Any("erreur", err))
+		os.Exit(1)
+	}
+	slog.Info("extrait écrit", slog.String("commune", *commune), slog.Int("titres", nombre))
+}
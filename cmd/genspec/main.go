@@ -0,0 +1,64 @@
+// Commande genspec : produit la spécification OpenAPI des transactions du contrat, à destination des
+// équipes passerelle et mobile, à partir des métadonnées publiées par le chaincode et de ses sources
+// (noms et descriptions des paramètres).
+//
+//	peer chaincode query -C dakar -n titrefoncier -c '{"Args":["org.hyperledger.fabric:GetMetadata"]}' \
+//	  | genspec -sources . -enveloppe -sortie openapi.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+)
+
+func main() {
+	entree := flag.String("metadonnees", "", "fichier des métadonnées du contrat (entrée standard par défaut)")
+	sources := flag.String("sources", ".", "répertoire des sources du chaincode")
+	enveloppe := flag.Bool("enveloppe", false, "décrire les réponses enveloppées {code, message, data, txId, timestamp}")
+	sortie := flag.String("sortie", "", "fichier de la spécification (sortie standard par défaut)")
+	flag.Parse()
+
+	lecteur := io.Reader(os.Stdin)
+	if *entree != "" {
+		fichier, err := os.Open(*entree)
+		if err != nil {
+			slog.Error("lecture des métadonnées impossible", slog.Any("erreur", err))
+			os.Exit(1)
+		}
+		defer fichier.Close()
+		lecteur = fichier
+	}
+	var meta metadata.ContractChaincodeMetadata
+	if err := json.NewDecoder(lecteur).Decode(&meta); err != nil {
+		slog.Error("métadonnées du contrat invalides", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	signatures, err := lireSignatures(*sources)
+	if err != nil {
+		slog.Error("analyse des sources impossible", slog.String("sources", *sources), slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	spec := genererSpecification(&meta, signatures, *enveloppe)
+	contenu, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		slog.Error("sérialisation de la spécification impossible", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+	contenu = append(contenu, '\n')
+
+	if *sortie == "" {
+		os.Stdout.Write(contenu)
+		return
+	}
+	if err := os.WriteFile(*sortie, contenu, 0o644); err != nil {
+		slog.Error("écriture de la spécification impossible", slog.String("fichier", *sortie), slog.Any("erreur", err))
+		os.Exit(1)
+	}
+}
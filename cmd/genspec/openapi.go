@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+)
+
+// Version de la spécification OpenAPI produite
+const versionOpenAPI = "3.0.3"
+
+// Contrat système de contractapi (métadonnées), exclu de la spécification
+const contratSysteme = "org.hyperledger.fabric"
+
+// Marque des transactions de consultation dans les métadonnées contractapi
+const etiquetteEvaluation = "evaluate"
+
+type objet = map[string]interface{}
+
+// Construire la spécification OpenAPI : une opération POST par transaction, dont le corps reprend les
+// paramètres nommés et la réponse le résultat, avec les schémas des types du modèle en composants
+func genererSpecification(meta *metadata.ContractChaincodeMetadata, signatures map[string]signature, enveloppe bool) objet {
+	info := objet{"title": "titrefoncier", "version": "latest"}
+	chemins := objet{}
+
+	noms := make([]string, 0, len(meta.Contracts))
+	for nom := range meta.Contracts {
+		if nom != contratSysteme {
+			noms = append(noms, nom)
+		}
+	}
+	sort.Strings(noms)
+	for _, nom := range noms {
+		contrat := meta.Contracts[nom]
+		if contrat.Info != nil && contrat.Default {
+			info = objet{"title": contrat.Info.Title, "description": contrat.Info.Description, "version": contrat.Info.Version}
+		}
+		for _, tx := range contrat.Transactions {
+			chemin := "/" + tx.Name
+			if !contrat.Default {
+				chemin = "/" + nom + chemin
+			}
+			chemins[chemin] = objet{"post": operation(nom, tx, signatures[tx.Name], enveloppe)}
+		}
+	}
+
+	schemas := objet{}
+	for nom, composant := range meta.Components.Schemas {
+		schema := objet{
+			"type":                 "object",
+			"properties":           composant.Properties,
+			"additionalProperties": composant.AdditionalProperties,
+		}
+		if len(composant.Required) > 0 {
+			schema["required"] = composant.Required
+		}
+		schemas[nom] = schema
+	}
+
+	return objet{
+		"openapi": versionOpenAPI,
+		"info":    info,
+		"tags": []objet{
+			{"name": "submit", "description": "Transactions à soumettre pour endossement et validation"},
+			{"name": "evaluate", "description": "Consultations, évaluées sur un seul pair sans écriture"},
+		},
+		"paths":      chemins,
+		"components": objet{"schemas": schemas},
+	}
+}
+
+func operation(contrat string, tx metadata.TransactionMetadata, sig signature, enveloppe bool) objet {
+	mode := "submit"
+	for _, etiquette := range tx.Tag {
+		if etiquette == etiquetteEvaluation {
+			mode = etiquetteEvaluation
+		}
+	}
+
+	op := objet{
+		"operationId":          tx.Name,
+		"tags":                 []string{mode},
+		"x-fabric-contract":    contrat,
+		"x-fabric-transaction": mode,
+		"x-fabric-arguments":   "les paramètres sont transmis dans l'ordre du corps, sérialisés en chaînes",
+		"responses":            reponses(tx, enveloppe),
+	}
+	if sig.description != "" {
+		description := strings.Join(strings.Fields(sig.description), " ")
+		op["summary"] = resume(description)
+		op["description"] = description
+	}
+
+	if len(tx.Parameters) == 0 {
+		return op
+	}
+	proprietes := objet{}
+	ordre := make([]string, len(tx.Parameters))
+	for i, parametre := range tx.Parameters {
+		nom := parametre.Name
+		if i < len(sig.parametres) {
+			nom = sig.parametres[i]
+		}
+		ordre[i] = nom
+		proprietes[nom] = parametre.Schema
+	}
+	op["requestBody"] = objet{
+		"required": true,
+		"content": objet{"application/json": objet{"schema": objet{
+			"type":                 "object",
+			"properties":           proprietes,
+			"required":             ordre,
+			"additionalProperties": false,
+			"x-fabric-order":       ordre, // Ordre des arguments de la transaction
+		}}},
+	}
+	return op
+}
+
+func reponses(tx metadata.TransactionMetadata, enveloppe bool) objet {
+	echec := objet{"description": "Échec de la transaction ; les erreurs métier sont de la forme \"CODE: message\""}
+
+	var resultat interface{}
+	if tx.Returns.Schema != nil {
+		resultat = tx.Returns.Schema
+	}
+	if enveloppe {
+		data := objet{"nullable": true}
+		if resultat != nil {
+			data = objet{"allOf": []interface{}{resultat}, "nullable": true}
+		}
+		resultat = objet{
+			"type": "object",
+			"properties": objet{
+				"code":      objet{"type": "string", "description": "OK, ou code d'erreur métier"},
+				"message":   objet{"type": "string"},
+				"data":      data,
+				"txId":      objet{"type": "string"},
+				"timestamp": objet{"type": "string", "format": "date-time"},
+			},
+			"required": []string{"code", "message", "data", "txId", "timestamp"},
+		}
+	}
+
+	succes := objet{"description": "Transaction réussie"}
+	if resultat != nil {
+		succes["content"] = objet{"application/json": objet{"schema": resultat}}
+	}
+	return objet{"200": succes, "default": echec}
+}
+
+// Première proposition d'une description, avant sa justification ou ses précisions
+func resume(description string) string {
+	for _, separateur := range []string{". ", " ; ", " : "} {
+		if debut, _, ok := strings.Cut(description, separateur); ok {
+			description = debut
+		}
+	}
+	return strings.TrimSuffix(description, ".")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+)
+
+// Récepteur des transactions du contrat dans les sources du chaincode
+const recepteurContrat = "SmartContract"
+
+// Signature d'une transaction relevée dans les sources
+type signature struct {
+	description string   // Commentaire de la méthode
+	parametres  []string // Noms des paramètres, contexte de transaction exclu
+}
+
+// Relever dans les sources du chaincode les méthodes exportées du contrat
+func lireSignatures(repertoire string) (map[string]signature, error) {
+	fset := token.NewFileSet()
+	filtre := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	paquets, err := parser.ParseDir(fset, repertoire, filtre, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := make(map[string]signature)
+	for _, paquet := range paquets {
+		for _, fichier := range paquet.Files {
+			for _, decl := range fichier.Decls {
+				fonction, ok := decl.(*ast.FuncDecl)
+				if !ok || !fonction.Name.IsExported() || !methodeContrat(fonction) {
+					continue
+				}
+				var sig signature
+				if fonction.Doc != nil {
+					sig.description = strings.TrimSpace(fonction.Doc.Text())
+				}
+				for i, champ := range fonction.Type.Params.List {
+					for _, nom := range champ.Names {
+						if i > 0 {
+							sig.parametres = append(sig.parametres, nom.Name)
+						}
+					}
+				}
+				signatures[fonction.Name.Name] = sig
+			}
+		}
+	}
+	return signatures, nil
+}
+
+func methodeContrat(fonction *ast.FuncDecl) bool {
+	if fonction.Recv == nil || len(fonction.Recv.List) != 1 {
+		return false
+	}
+	etoile, ok := fonction.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := etoile.X.(*ast.Ident)
+	return ok && ident.Name == recepteurContrat
+}
@@ -0,0 +1,63 @@
+// Commande geoserver : couche cadastrale du géoportail national, dérivée du registre. Les contours
+// des parcelles en vigueur sont reportés dans PostGIS au fil des événements du chaincode, reçus du
+// service Gateway du pair -pair ; chaque transaction désigne par son résumé les titres écrits, dont
+// l'état courant est relu. Une base vide est d'abord chargée, page par page, de tous les titres du
+// registre, puis l'écoute part de l'origine du canal ; -recharger reprend ce chargement au démarrage.
+// La position de lecture est conservée dans la base : après une interruption, l'écoute reprend à la
+// dernière transaction reportée.
+//
+//	geoserver -ecoute :8090 -canal dakar -pair peer0.dakar:7051 -tls-ca /etc/hyperledger/peer-ca.pem \
+//	  -msp EtatMSP -msp-config /etc/titrefoncier/geoportail/msp -base "host=postgis dbname=cadastre"
+//
+// La couche est servie en tuiles vectorielles (GET /tuiles/{z}/{x}/{y}.pbf, couche "parcelles") et en
+// GeoJSON (GET /couches/parcelles?bbox=ouest,sud,est,nord&commune=), sans le nom des propriétaires.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"titrefoncier/pkg/client"
+)
+
+func main() {
+	ecoute := flag.String("ecoute", ":8090", "adresse d'écoute HTTP")
+	options := client.DeclarerOptions(flag.CommandLine)
+	options.DeclarerIdentite(flag.CommandLine)
+	connexion := flag.String("base", "", "chaîne de connexion PostGIS (vide : variables PG*)")
+	recharger := flag.Bool("recharger", false, "recharger la couche depuis l'état courant du registre au démarrage")
+	origine := flag.String("origine", "", "origine autorisée des requêtes du géoportail (CORS, vide : aucune)")
+	flag.Parse()
+
+	passerelle, registre, err := options.Registre()
+	if err != nil {
+		slog.Error("connexion au registre impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	defer passerelle.Fermer()
+	base, err := ouvrirPostGIS(context.Background(), *connexion)
+	if err != nil {
+		slog.Error("initialisation de la base PostGIS impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+
+	m := &materialisation{
+		registre:  registre,
+		base:      base,
+		canal:     options.Canal,
+		recharger: *recharger,
+	}
+	ecouteur := passerelle.Ecouteur(options.Identite)
+	ecouteur.DepuisOrigine = true
+	go m.executer(context.Background(), ecouteur)
+
+	s := &serveur{base: base, origine: *origine}
+	slog.Info("service cartographique démarré", slog.String("ecoute", *ecoute), slog.String("canal", options.Canal))
+	if err := http.ListenAndServe(*ecoute, s.routeur()); err != nil {
+		slog.Error("arrêt du service cartographique", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+}
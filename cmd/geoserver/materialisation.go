@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Reprises de l'écoute après une interruption, espacées de délais doublés à chaque échec
+const (
+	delaiReprise    = 2 * time.Second
+	delaiRepriseMax = 10 * time.Minute
+)
+
+// Message de LireResumeTransaction pour une transaction qui n'a rien écrit
+const motifSansResume = "aucun résumé conservé"
+
+// Titres lus par page lors du chargement de la couche (LimiteRechercheMax du chaincode)
+const taillePageChargement = 100
+
+// Report dans PostGIS des parcelles écrites par les transactions du registre : chaque événement du
+// chaincode désigne, par le résumé de sa transaction, les titres écrits, dont l'état courant est relu.
+// Les titres écrits avant que les transactions ne portent leur résumé, ou par une transaction qui n'en
+// a pas laissé, ne sont reportés que par le chargement de la couche, page par page, depuis l'état courant
+// du registre : il précède la première écoute, et chaque démarrage demandé avec -recharger.
+type materialisation struct {
+	registre  *client.Registre
+	base      *basePostGIS
+	canal     string
+	recharger bool // Recharger la couche avant de reprendre l'écoute
+}
+
+// Écouter les événements à partir de la dernière position reportée, jusqu'à l'annulation du contexte
+func (m *materialisation) executer(ctx context.Context, ecouteur *client.EcouteurEvenements) {
+	delai := delaiReprise
+	for ctx.Err() == nil {
+		bloc, apresTx, chargee, err := m.base.position(ctx, m.canal)
+		if err == nil && (!chargee || m.recharger) {
+			if err = m.charger(ctx); err == nil {
+				m.recharger = false
+			}
+		}
+		if err == nil {
+			err = ecouteur.Ecouter(ctx, bloc, apresTx, func(evenement client.EvenementChaincode) error {
+				delai = delaiReprise
+				return m.reporter(ctx, evenement)
+			})
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("report des parcelles interrompu", slog.Any("erreur", err), slog.Duration("reprise", delai))
+		select {
+		case <-ctx.Done():
+		case <-time.After(delai):
+		}
+		delai = min(2*delai, delaiRepriseMax)
+	}
+}
+
+// Charger la couche depuis l'état courant du registre, page par page, puis fixer la position de
+// lecture à l'origine du canal si aucune n'était enregistrée : l'écoute rejoue ensuite les
+// transactions depuis le premier bloc, chaque titre désigné étant relu dans son état courant
+func (m *materialisation) charger(ctx context.Context) error {
+	signet, reportes := "", 0
+	for {
+		page, err := m.registre.TitresPagines(taillePageChargement, signet)
+		if err != nil {
+			return err
+		}
+		ecritures := make([]ecritureParcelle, 0, len(page.Titres))
+		for _, titre := range page.Titres {
+			ecriture := ecritureParcelle{id: titre.Id}
+			if enVigueur(titre) {
+				ecriture.titre = titre
+				reportes++
+			}
+			ecritures = append(ecritures, ecriture)
+		}
+		if err := m.base.charger(ctx, m.canal, ecritures); err != nil {
+			return err
+		}
+		if signet = page.Signet; signet == "" {
+			break
+		}
+	}
+	slog.Info("couche chargée", slog.String("canal", m.canal), slog.Int("parcelles", reportes))
+	return m.base.initialiserPosition(ctx, m.canal)
+}
+
+// Reporter les titres écrits par la transaction d'un événement : une parcelle en vigueur, munie d'un
+// contour, est insérée ou mise à jour ; un titre archivé, muté, morcelé ou sans contour est retiré
+func (m *materialisation) reporter(ctx context.Context, evenement client.EvenementChaincode) error {
+	modifications, err := m.modifications(evenement)
+	if err != nil {
+		return err
+	}
+
+	var ecritures []ecritureParcelle
+	vus := make(map[string]bool)
+	for _, modification := range modifications {
+		if modification.Type != model.TypeModificationTitre || len(modification.Attributs) != 1 || vus[modification.Attributs[0]] {
+			continue
+		}
+		id := modification.Attributs[0]
+		vus[id] = true
+		titre, err := m.titreEnVigueur(id)
+		if err != nil {
+			return err
+		}
+		ecritures = append(ecritures, ecritureParcelle{id: id, titre: titre})
+	}
+
+	if err := m.base.reporter(ctx, m.canal, evenement.Bloc, evenement.TxId, ecritures); err != nil {
+		return err
+	}
+	if len(ecritures) > 0 {
+		slog.Info("parcelles reportées", slog.String("txId", evenement.TxId), slog.Uint64("bloc", evenement.Bloc), slog.Int("titres", len(ecritures)))
+	}
+	return nil
+}
+
+// Enregistrements écrits par la transaction d'un événement : le résumé est l'événement lui-même, ou il
+// est joint au contenu de l'événement métier qui occupait la transaction. Les transactions antérieures à
+// cette jonction en conservaient le résumé au registre.
+func (m *materialisation) modifications(evenement client.EvenementChaincode) ([]model.ModificationCle, error) {
+	var resume model.ResumeTransaction
+	if evenement.Nom == model.EvenementResumeTransaction {
+		if err := json.Unmarshal(evenement.Contenu, &resume); err != nil {
+			return nil, fmt.Errorf("résumé de la transaction %s invalide: %v", evenement.TxId, err)
+		}
+		return resume.Modifications, nil
+	}
+
+	var champs map[string]json.RawMessage
+	if err := json.Unmarshal(evenement.Contenu, &champs); err == nil && champs[model.ChampResumeTransaction] != nil {
+		if err := json.Unmarshal(champs[model.ChampResumeTransaction], &resume); err != nil {
+			return nil, fmt.Errorf("résumé de la transaction %s invalide: %v", evenement.TxId, err)
+		}
+		return resume.Modifications, nil
+	}
+
+	contenu, err := m.registre.Evaluer("LireResumeTransaction", evenement.TxId)
+	if err != nil {
+		if strings.Contains(err.Error(), motifSansResume) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(contenu, &resume); err != nil {
+		return nil, fmt.Errorf("réponse LireResumeTransaction invalide: %v", err)
+	}
+	return resume.Modifications, nil
+}
+
+// État courant d'un titre à publier, nil s'il ne figure plus dans la couche
+func (m *materialisation) titreEnVigueur(id string) (*model.TitreFoncier, error) {
+	version, err := m.registre.VersionTitre(id)
+	if err != nil {
+		return nil, err
+	}
+	if version.TxId == "" || version.Suppression {
+		return nil, nil
+	}
+	titre, err := m.registre.LireTitreFoncier(id)
+	if err != nil || !enVigueur(titre) {
+		return nil, err
+	}
+	return titre, nil
+}
+
+// Indiquer si un titre figure dans la couche : muni d'un contour, ni muté ni morcelé
+func enVigueur(titre *model.TitreFoncier) bool {
+	return titre.Geometrie != "" && titre.MuteVers == "" && len(titre.MorceleEn) == 0
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"titrefoncier/pkg/model"
+)
+
+// Schéma de la couche cadastrale (PostGIS 3) : une ligne par parcelle en vigueur, contour en WGS84,
+// et la position de lecture des événements de chaque canal. Le nom du propriétaire n'y figure pas,
+// la couche étant publiée sur le géoportail.
+const schemaPostGIS = `
+CREATE EXTENSION IF NOT EXISTS postgis;
+CREATE TABLE IF NOT EXISTS parcelles (
+	id                  text PRIMARY KEY,
+	canal               text NOT NULL,
+	num_tf              text NOT NULL,
+	commune             text NOT NULL,
+	bureau              text,
+	superficie          integer NOT NULL,
+	systeme_coordonnees text,
+	geometrie_depuis    timestamptz,
+	tx_id               text NOT NULL, -- Vide pour une parcelle reprise par un chargement de la couche
+	geom                geometry(MultiPolygon, 4326) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS parcelles_geom_idx ON parcelles USING GIST (geom);
+CREATE INDEX IF NOT EXISTS parcelles_commune_idx ON parcelles (commune);
+CREATE TABLE IF NOT EXISTS position_evenements (
+	canal text PRIMARY KEY,
+	bloc  bigint NOT NULL,
+	tx_id text NOT NULL
+);
+`
+
+// Étendue d'une tuile vectorielle, en unités de tuile, et marge autour de la tuile
+const (
+	etendueTuile = 4096
+	margeTuile   = 64
+)
+
+// Report d'une parcelle en vigueur, inséré ou mis à jour
+const requeteParcelle = `INSERT INTO parcelles (id, canal, num_tf, commune, bureau, superficie, systeme_coordonnees, geometrie_depuis, tx_id, geom)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON($10), 4326)))
+ON CONFLICT (id) DO UPDATE SET canal = EXCLUDED.canal, num_tf = EXCLUDED.num_tf, commune = EXCLUDED.commune,
+	bureau = EXCLUDED.bureau, superficie = EXCLUDED.superficie, systeme_coordonnees = EXCLUDED.systeme_coordonnees,
+	geometrie_depuis = EXCLUDED.geometrie_depuis, tx_id = EXCLUDED.tx_id, geom = EXCLUDED.geom`
+
+// Base PostGIS, jointe par un pool de connexions configuré par une chaîne de connexion libpq ou les
+// variables PG* habituelles ; les valeurs sont toujours transmises en paramètres des requêtes
+type basePostGIS struct {
+	pool *pgxpool.Pool
+}
+
+// État d'un titre à reporter dans la couche
+type ecritureParcelle struct {
+	id    string
+	titre *model.TitreFoncier // nil : parcelle retirée de la couche
+}
+
+// Ouvrir la base et y créer au besoin le schéma de la couche
+func ouvrirPostGIS(ctx context.Context, connexion string) (*basePostGIS, error) {
+	pool, err := pgxpool.New(ctx, connexion)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, schemaPostGIS); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &basePostGIS{pool: pool}, nil
+}
+
+// Position de lecture des événements d'un canal : bloc et dernière transaction reportée ; faux avant
+// le chargement initial de la couche
+func (b *basePostGIS) position(ctx context.Context, canal string) (uint64, string, bool, error) {
+	var bloc int64
+	var txId string
+	err := b.pool.QueryRow(ctx, "SELECT bloc, tx_id FROM position_evenements WHERE canal = $1", canal).Scan(&bloc, &txId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	return uint64(bloc), txId, true, nil
+}
+
+// Reporter les parcelles d'une transaction et avancer la position de lecture, dans une même
+// transaction SQL : un événement rejoué après une interruption ne l'est qu'une fois
+func (b *basePostGIS) reporter(ctx context.Context, canal string, bloc uint64, txId string, ecritures []ecritureParcelle) error {
+	return pgx.BeginFunc(ctx, b.pool, func(tx pgx.Tx) error {
+		if err := ecrireParcelles(ctx, tx, canal, txId, ecritures); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO position_evenements (canal, bloc, tx_id) VALUES ($1, $2, $3)
+ON CONFLICT (canal) DO UPDATE SET bloc = EXCLUDED.bloc, tx_id = EXCLUDED.tx_id`, canal, int64(bloc), txId)
+		return err
+	})
+}
+
+// Reporter une page du chargement de la couche, sans toucher à la position de lecture
+func (b *basePostGIS) charger(ctx context.Context, canal string, ecritures []ecritureParcelle) error {
+	return pgx.BeginFunc(ctx, b.pool, func(tx pgx.Tx) error {
+		return ecrireParcelles(ctx, tx, canal, "", ecritures)
+	})
+}
+
+// Fixer, une fois la couche chargée, la position d'où l'écoute reprend les événements si aucune
+// n'est encore enregistrée pour le canal
+func (b *basePostGIS) initialiserPosition(ctx context.Context, canal string) error {
+	_, err := b.pool.Exec(ctx, "INSERT INTO position_evenements (canal, bloc, tx_id) VALUES ($1, 0, '') ON CONFLICT (canal) DO NOTHING", canal)
+	return err
+}
+
+func ecrireParcelles(ctx context.Context, tx pgx.Tx, canal string, txId string, ecritures []ecritureParcelle) error {
+	for _, ecriture := range ecritures {
+		if ecriture.titre == nil {
+			if _, err := tx.Exec(ctx, "DELETE FROM parcelles WHERE id = $1", ecriture.id); err != nil {
+				return err
+			}
+			continue
+		}
+		titre := ecriture.titre
+		var depuis *string
+		if titre.GeometrieDepuis != "" {
+			depuis = &titre.GeometrieDepuis
+		}
+		if _, err := tx.Exec(ctx, requeteParcelle, titre.Id, canal, titre.NumTF, titre.Commune, titre.BureauFoncier, titre.Superficie,
+			titre.SystemeCoordonnees, depuis, txId, titre.Geometrie); err != nil {
+			return fmt.Errorf("report de la parcelle %s: %v", titre.Id, err)
+		}
+	}
+	return nil
+}
+
+// Tuile vectorielle (Mapbox Vector Tile) de la couche "parcelles" aux coordonnées z/x/y du schéma
+// XYZ, en Web Mercator ; vide si aucune parcelle ne l'atteint
+func (b *basePostGIS) tuile(ctx context.Context, z int, x int, y int) ([]byte, error) {
+	var tuile []byte
+	err := b.pool.QueryRow(ctx, `SELECT ST_AsMVT(t, 'parcelles', $4, 'geom') FROM (
+	SELECT id, num_tf, commune, superficie, ST_AsMVTGeom(ST_Transform(geom, 3857), ST_TileEnvelope($1, $2, $3), $4, $5, true) AS geom
+	FROM parcelles
+	WHERE geom && ST_Transform(ST_TileEnvelope($1, $2, $3, margin => $5::float8 / $4), 4326)
+) t`, z, x, y, etendueTuile, margeTuile).Scan(&tuile)
+	return tuile, err
+}
+
+// Filtre d'une couche GeoJSON
+type filtreCouche struct {
+	emprise []float64 // [ouest, sud, est, nord] en WGS84 (vide : tout le canal)
+	commune string
+	limite  int
+}
+
+// Couche GeoJSON (FeatureCollection, WGS84) des parcelles retenues par un filtre, par identifiant
+func (b *basePostGIS) couche(ctx context.Context, filtre filtreCouche) ([]byte, error) {
+	conditions := []string{"true"}
+	arguments := []any{filtre.limite}
+	if len(filtre.emprise) == 4 {
+		arguments = append(arguments, filtre.emprise[0], filtre.emprise[1], filtre.emprise[2], filtre.emprise[3])
+		n := len(arguments)
+		conditions = append(conditions, fmt.Sprintf("geom && ST_MakeEnvelope($%d, $%d, $%d, $%d, 4326)", n-3, n-2, n-1, n))
+	}
+	if filtre.commune != "" {
+		arguments = append(arguments, filtre.commune)
+		conditions = append(conditions, fmt.Sprintf("commune = $%d", len(arguments)))
+	}
+	var couche string
+	err := b.pool.QueryRow(ctx, fmt.Sprintf(`SELECT json_build_object('type', 'FeatureCollection', 'features', coalesce(json_agg(ST_AsGeoJSON(t.*, 'geom', 7)::json), '[]'::json))::text FROM (
+	SELECT id, num_tf AS "numTF", commune, superficie, geometrie_depuis AS "geometrieDepuis", geom
+	FROM parcelles WHERE %s ORDER BY id LIMIT $1
+) t`, strings.Join(conditions, " AND ")), arguments...).Scan(&couche)
+	return []byte(couche), err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"titrefoncier/pkg/model"
+)
+
+// Nombre de parcelles d'une couche GeoJSON, par défaut et au plus
+const (
+	limiteCouche    = 1000
+	limiteCoucheMax = 10000
+)
+
+// Niveau de zoom maximal des tuiles servies
+const zoomMax = 22
+
+// Service cartographique : tuiles vectorielles et couche GeoJSON des parcelles reportées, en lecture
+// publique pour le géoportail
+type serveur struct {
+	base    *basePostGIS
+	origine string // Valeur de Access-Control-Allow-Origin (vide : pas d'en-tête CORS)
+}
+
+func (s *serveur) routeur() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tuiles/{z}/{x}/{y}", s.servirTuile)
+	mux.HandleFunc("GET /couches/parcelles", s.servirCouche)
+	return mux
+}
+
+// GET /tuiles/{z}/{x}/{y}[.pbf|.mvt] : tuile vectorielle de la couche "parcelles", 204 si elle est vide
+func (s *serveur) servirTuile(w http.ResponseWriter, req *http.Request) {
+	y := strings.TrimSuffix(strings.TrimSuffix(req.PathValue("y"), ".pbf"), ".mvt")
+	coordonnees, err := entiers(req.PathValue("z"), req.PathValue("x"), y)
+	if err == nil && (coordonnees[0] > zoomMax || coordonnees[1] >= 1<<coordonnees[0] || coordonnees[2] >= 1<<coordonnees[0]) {
+		err = fmt.Errorf("tuile %d/%d/%d hors de la grille", coordonnees[0], coordonnees[1], coordonnees[2])
+	}
+	if err != nil {
+		repondreErreur(w, http.StatusBadRequest, model.CodeRequeteInvalide, err.Error())
+		return
+	}
+
+	tuile, err := s.base.tuile(req.Context(), coordonnees[0], coordonnees[1], coordonnees[2])
+	if err != nil {
+		slog.Warn("tuile indisponible", slog.String("tuile", req.URL.Path), slog.Any("erreur", err))
+		repondreErreur(w, http.StatusServiceUnavailable, model.CodeErreurInterne, "tuile indisponible")
+		return
+	}
+	s.entetes(w)
+	if len(tuile) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Write(tuile)
+}
+
+// GET /couches/parcelles?bbox=ouest,sud,est,nord&commune=&limite= : parcelles en GeoJSON (WGS84)
+func (s *serveur) servirCouche(w http.ResponseWriter, req *http.Request) {
+	requete := req.URL.Query()
+	filtre := filtreCouche{commune: requete.Get("commune"), limite: limiteCouche}
+	var err error
+	if bbox := requete.Get("bbox"); bbox != "" {
+		if filtre.emprise, err = reels(strings.Split(bbox, ",")); err == nil && len(filtre.emprise) != 4 {
+			err = fmt.Errorf("bbox attendue: ouest,sud,est,nord")
+		}
+	}
+	if limite := requete.Get("limite"); err == nil && limite != "" {
+		if filtre.limite, err = strconv.Atoi(limite); err == nil && (filtre.limite <= 0 || filtre.limite > limiteCoucheMax) {
+			err = fmt.Errorf("la limite doit être comprise entre 1 et %d", limiteCoucheMax)
+		}
+	}
+	if err != nil {
+		repondreErreur(w, http.StatusBadRequest, model.CodeRequeteInvalide, err.Error())
+		return
+	}
+
+	couche, err := s.base.couche(req.Context(), filtre)
+	if err != nil {
+		slog.Warn("couche indisponible", slog.Any("erreur", err))
+		repondreErreur(w, http.StatusServiceUnavailable, model.CodeErreurInterne, "couche indisponible")
+		return
+	}
+	s.entetes(w)
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Write(couche)
+}
+
+// En-têtes communs des réponses cartographiques : la couche évolue avec le registre, à quelques
+// secondes près
+func (s *serveur) entetes(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	if s.origine != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.origine)
+	}
+}
+
+func entiers(valeurs ...string) ([]int, error) {
+	resultat := make([]int, len(valeurs))
+	for i, valeur := range valeurs {
+		entier, err := strconv.Atoi(valeur)
+		if err != nil || entier < 0 {
+			return nil, fmt.Errorf("coordonnée de tuile invalide: %q", valeur)
+		}
+		resultat[i] = entier
+	}
+	return resultat, nil
+}
+
+func reels(valeurs []string) ([]float64, error) {
+	resultat := make([]float64, len(valeurs))
+	for i, valeur := range valeurs {
+		reel, err := strconv.ParseFloat(strings.TrimSpace(valeur), 64)
+		if err != nil {
+			return nil, fmt.Errorf("coordonnée invalide: %q", valeur)
+		}
+		resultat[i] = reel
+	}
+	return resultat, nil
+}
+
+func repondreErreur(w http.ResponseWriter, statut int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statut)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
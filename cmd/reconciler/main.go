@@ -0,0 +1,73 @@
+// Commande reconciler : rapproche le magasin de documents (NFS, IPFS monté) des hashes
+// enregistrés sur le registre et produit un rapport des écarts.
+//
+//	reconciler -racine /mnt/shared_dir -canal dakar -signaler -pair peer0.dakar:7051 \
+//	  -tls-ca /etc/hyperledger/peer-ca.pem -msp EtatMSP -msp-config /etc/titrefoncier/rapprochement/msp
+//
+// Les transactions passent par le service Gateway du pair, sous l'identité -msp ; pair et identité
+// valent par défaut les variables CORE_PEER_* de la CLI peer.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+
+	"titrefoncier/pkg/client"
+)
+
+func main() {
+	racine := flag.String("racine", "/mnt/shared_dir", "racine du magasin de documents")
+	options := client.DeclarerOptions(flag.CommandLine)
+	options.DeclarerIdentite(flag.CommandLine)
+	prefixe := flag.String("prefixe", "", "substitution de préfixe des chemins enregistrés, sous la forme ancien=nouveau")
+	signaler := flag.Bool("signaler", false, "soumettre SignalerDocumentCorrompu pour chaque document corrompu")
+	sortie := flag.String("rapport", "", "fichier du rapport JSON (sortie standard par défaut)")
+	flag.Parse()
+
+	passerelle, registre, err := options.Registre()
+	if err != nil {
+		slog.Error("connexion au registre impossible", slog.Any("erreur", err))
+		os.Exit(2)
+	}
+	defer passerelle.Fermer()
+
+	r := &reconciliateur{
+		registre: registre,
+		racine:   *racine,
+		signaler: *signaler,
+	}
+	if *prefixe != "" {
+		ancien, nouveau, ok := strings.Cut(*prefixe, "=")
+		if !ok {
+			slog.Error("substitution de préfixe invalide, ancien=nouveau attendu", slog.String("prefixe", *prefixe))
+			os.Exit(2)
+		}
+		r.ancien, r.nouveau = ancien, nouveau
+	}
+
+	rapport, err := r.executer()
+	if err != nil {
+		slog.Error("rapprochement interrompu", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	destination := os.Stdout
+	if *sortie != "" {
+		if destination, err = os.Create(*sortie); err != nil {
+			slog.Error("création du rapport impossible", slog.Any("erreur", err))
+			os.Exit(1)
+		}
+		defer destination.Close()
+	}
+	encodeur := json.NewEncoder(destination)
+	encodeur.SetIndent("", "  ")
+	if err := encodeur.Encode(rapport); err != nil {
+		slog.Error("écriture du rapport impossible", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	slog.Info("rapprochement terminé", slog.Int("fichiers", rapport.FichiersAnalyses), slog.Int("documents", rapport.DocumentsVerifies), slog.Int("ecarts", len(rapport.Ecarts)))
+}
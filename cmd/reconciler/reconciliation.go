@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Natures des écarts entre le magasin de documents et le registre
+const (
+	EcartCorrompu     = "CORROMPU"      // Le fichier ne correspond plus au hash enregistré
+	EcartManquant     = "MANQUANT"      // Le fichier référencé par le registre est introuvable
+	EcartNonReference = "NON_REFERENCE" // Le fichier n'est rattaché à aucun titre
+)
+
+// Écart constaté sur un document
+type Ecart struct {
+	Type         string `json:"type"`
+	IdTitre      string `json:"idTitre,omitempty"`
+	Chemin       string `json:"chemin"`
+	HashRegistre string `json:"hashRegistre,omitempty"`
+	HashConstate string `json:"hashConstate,omitempty"`
+	Signale      bool   `json:"signale,omitempty"` // Un signalement a été soumis au registre
+}
+
+// Rapport de rapprochement
+type Rapport struct {
+	GenereLe          string  `json:"genereLe"`
+	Racine            string  `json:"racine"`
+	FichiersAnalyses  int     `json:"fichiersAnalyses"`
+	DocumentsVerifies int     `json:"documentsVerifies"`
+	Ecarts            []Ecart `json:"ecarts"`
+}
+
+// Rapprochement du magasin de documents avec le registre
+type reconciliateur struct {
+	registre *client.Registre
+	racine   string // Racine du magasin (NFS, ou IPFS monté en système de fichiers)
+	ancien   string // Préfixe des chemins enregistrés sur le registre...
+	nouveau  string // ...et son équivalent sur la machine du rapprochement
+	signaler bool   // Soumettre SignalerDocumentCorrompu pour chaque document corrompu
+}
+
+func (r *reconciliateur) executer() (*Rapport, error) {
+	rapport := &Rapport{GenereLe: time.Now().UTC().Format(time.RFC3339), Racine: r.racine, Ecarts: []Ecart{}}
+
+	hashes, err := r.parcourir()
+	if err != nil {
+		return nil, err
+	}
+	rapport.FichiersAnalyses = len(hashes)
+
+	contenu, err := r.registre.Evaluer("GetAllTitresFonciers")
+	if err != nil {
+		return nil, err
+	}
+	var titres []*model.TitreFoncier
+	if err := json.Unmarshal(contenu, &titres); err != nil {
+		return nil, fmt.Errorf("réponse GetAllTitresFonciers invalide: %v", err)
+	}
+
+	// Documents référencés par le registre
+	references := make(map[string]bool)
+	for _, titre := range titres {
+		documents := []model.DocumentTitre{{Chemin: titre.Document, Hash: titre.DocHash}}
+		documents = append(documents, titre.Documents...)
+		for _, document := range documents {
+			if document.Chemin == "" {
+				continue
+			}
+			rapport.DocumentsVerifies++
+			chemin := r.cheminLocal(document.Chemin)
+			references[chemin] = true
+
+			hash, present := hashes[chemin]
+			switch {
+			case !present:
+				rapport.Ecarts = append(rapport.Ecarts, Ecart{Type: EcartManquant, IdTitre: titre.Id, Chemin: document.Chemin, HashRegistre: document.Hash})
+			case hash != document.Hash:
+				ecart := Ecart{Type: EcartCorrompu, IdTitre: titre.Id, Chemin: document.Chemin, HashRegistre: document.Hash, HashConstate: hash}
+				if r.signaler {
+					if _, err := r.registre.Soumettre(context.Background(), "SignalerDocumentCorrompu", titre.Id, document.Chemin, hash); err != nil {
+						slog.Error("signalement impossible", slog.String("idTitre", titre.Id), slog.String("chemin", document.Chemin), slog.Any("erreur", err))
+					} else {
+						ecart.Signale = true
+					}
+				}
+				rapport.Ecarts = append(rapport.Ecarts, ecart)
+			}
+		}
+	}
+
+	// Fichiers du magasin sans titre : le hash peut être enregistré sous un autre chemin
+	for _, chemin := range clesTriees(hashes) {
+		if references[chemin] {
+			continue
+		}
+		contenu, err := r.registre.Evaluer("RechercherParHashDocument", hashes[chemin])
+		if err != nil {
+			return nil, err
+		}
+		var verification model.VerificationDocument
+		if err := json.Unmarshal(contenu, &verification); err != nil {
+			return nil, fmt.Errorf("réponse RechercherParHashDocument invalide: %v", err)
+		}
+		if !verification.Enregistre {
+			rapport.Ecarts = append(rapport.Ecarts, Ecart{Type: EcartNonReference, Chemin: chemin, HashConstate: hashes[chemin]})
+		}
+	}
+
+	return rapport, nil
+}
+
+// Hash SHA-1 de chaque fichier du magasin, par chemin local
+func (r *reconciliateur) parcourir() (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(r.racine, func(chemin string, entree fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entree.Type().IsRegular() {
+			return nil
+		}
+		hash, err := hashFichier(chemin)
+		if err != nil {
+			return err
+		}
+		hashes[chemin] = hash
+		return nil
+	})
+	return hashes, err
+}
+
+// Chemin local d'un document enregistré, après substitution éventuelle du préfixe de montage
+func (r *reconciliateur) cheminLocal(chemin string) string {
+	if r.ancien != "" && strings.HasPrefix(chemin, r.ancien) {
+		chemin = r.nouveau + strings.TrimPrefix(chemin, r.ancien)
+	}
+	return filepath.Clean(chemin)
+}
+
+// Même calcul que controlerDocument côté chaincode
+func hashFichier(chemin string) (string, error) {
+	fichier, err := os.Open(chemin)
+	if err != nil {
+		return "", err
+	}
+	defer fichier.Close()
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, fichier); err != nil {
+		return "", fmt.Errorf("erreur de lecture de %s: %v", chemin, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func clesTriees(m map[string]string) []string {
+	cles := make([]string, 0, len(m))
+	for cle := range m {
+		cles = append(cles, cle)
+	}
+	sort.Strings(cles)
+	return cles
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Étape de l'audit de cohérence portant sur les titres eux-mêmes, avant celles des index
+const EtapeAuditTitres = "TITRES"
+
+// Séparateur entre l'étape et le signet du registre dans le signet de l'audit
+const separateurSignetAudit = "|"
+
+// Étapes de l'audit de cohérence, dans l'ordre de parcours
+var etapesAudit = []string{EtapeAuditTitres, PrefixeIndexHash, PrefixeIndexNumTF, PrefixeIndexProprio, PrefixeIndexNom, PrefixeIndexHypotheque, PrefixeIndexGeohash, PrefixeIndexQualite, PrefixeIndexFolio}
+
+// Contrôler page par page les invariants du registre (propriétaires des titres, tantièmes des
+// copropriétés, parts des bénéficiaires effectifs, entrées d'index), par exemple après une migration ou
+// un incident (réservé aux auditeurs). La collection privée des bénéficiaires effectifs n'étant lisible
+// que de ses membres, leurs parts ne sont contrôlées qu'aux audits menés par l'État ou la CENTIF.
+func (s *SmartContract) AuditerCoherence(ctx contractapi.TransactionContextInterface, taillePage int, signet string) (*RapportCoherence, error) {
+	if err := verifierRole(ctx, RoleAuditeur); err != nil {
+		return nil, err
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+
+	etape, derniere := 0, ""
+	if signet != "" {
+		nom, suite, _ := strings.Cut(signet, separateurSignetAudit)
+		etape = -1
+		for i, e := range etapesAudit {
+			if e == nom {
+				etape = i
+			}
+		}
+		if etape < 0 {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "signet d'audit invalide: %s", signet)
+		}
+		derniere = suite
+	}
+
+	// Le signet porte la dernière clé contrôlée et non un signet de pagination : l'audit est aussi
+	// déroulé par un job, dont la transaction écrit et ne peut donc recourir aux requêtes paginées
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	if etapesAudit[etape] == EtapeAuditTitres {
+		debut := ""
+		if derniere != "" {
+			debut = derniere + "\x00"
+		}
+		resultsIterator, err = ctx.GetStub().GetStateByRange(debut, "")
+	} else {
+		resultsIterator, err = ctx.GetStub().GetStateByPartialCompositeKey(etapesAudit[etape], []string{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	rapport := &RapportCoherence{Etape: etapesAudit[etape], Anomalies: []AnomalieCoherence{}}
+	titres := make(map[string]*TitreFoncier)
+	for rapport.Controles < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if queryResponse.Key <= derniere {
+			continue
+		}
+		rapport.Controles++
+		derniere = queryResponse.Key
+
+		var anomalies []AnomalieCoherence
+		if rapport.Etape == EtapeAuditTitres {
+			var titre TitreFoncier
+			if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+				return nil, err
+			}
+			if err := chargerDocuments(ctx, &titre); err != nil {
+				return nil, err
+			}
+			anomalies, err = controlerTitre(ctx, &titre)
+		} else {
+			anomalies, err = controlerEntreeIndex(ctx, queryResponse.Key, titres)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rapport.Anomalies = append(rapport.Anomalies, anomalies...)
+	}
+	rapport.Nombre = len(rapport.Anomalies)
+
+	// Une page incomplète termine l'étape : la suivante reprend au début de son index
+	if rapport.Controles == taillePage {
+		rapport.Signet = rapport.Etape + separateurSignetAudit + derniere
+	} else if etape+1 < len(etapesAudit) {
+		rapport.Signet = etapesAudit[etape+1] + separateurSignetAudit
+	}
+
+	return rapport, nil
+}
+
+// Anomalies d'un titre : propriétaire absent ou absorbé, tantièmes incohérents, parts des bénéficiaires
+// effectifs incomplètes, entrées d'index manquantes
+func controlerTitre(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) ([]AnomalieCoherence, error) {
+	var anomalies []AnomalieCoherence
+	if titre.Proprio == "" {
+		anomalies = append(anomalies, AnomalieCoherence{
+			Type:    AnomalieProprietaireManquant,
+			IdTitre: titre.Id,
+			Detail:  "le titre ne désigne aucun propriétaire",
+		})
+	} else {
+		fusions, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeFusion, []string{titre.Proprio})
+		if err != nil {
+			return nil, err
+		}
+		fusionne := fusions.HasNext()
+		fusions.Close()
+		if fusionne {
+			anomalies = append(anomalies, AnomalieCoherence{
+				Type:    AnomalieProprietaireFusionne,
+				IdTitre: titre.Id,
+				Detail:  fmt.Sprintf("le propriétaire %s a été absorbé par une fusion", titre.Proprio),
+			})
+		}
+	}
+
+	if titre.Copropriete {
+		anomalie, err := controlerTantiemes(ctx, titre)
+		if err != nil {
+			return nil, err
+		}
+		if anomalie != nil {
+			anomalies = append(anomalies, *anomalie)
+		}
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID == MSPEtat || mspID == MSPConformite {
+		anomalie, err := controlerBeneficiaires(ctx, titre)
+		if err != nil {
+			return nil, err
+		}
+		if anomalie != nil {
+			anomalies = append(anomalies, *anomalie)
+		}
+	}
+
+	entrees, err := entreesIndex(ctx, titre)
+	if err != nil {
+		return nil, err
+	}
+	for _, cle := range clesTriees(entrees) {
+		valeur, err := ctx.GetStub().GetState(cle)
+		if err != nil {
+			return nil, err
+		}
+		if valeur != nil {
+			continue
+		}
+		prefixe, attributs, err := ctx.GetStub().SplitCompositeKey(cle)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, AnomalieCoherence{
+			Type:      AnomalieIndexManquant,
+			IdTitre:   titre.Id,
+			Index:     prefixe,
+			Attributs: attributs,
+			Detail:    "entrée d'index absente, à rétablir par ReconstruireIndex",
+		})
+	}
+
+	return anomalies, nil
+}
+
+// Anomalie d'une entrée d'index : titre absent du registre ou ne correspondant plus à l'entrée.
+// Les titres lus sont conservés dans titres (nil si absent) pour le reste de la page.
+func controlerEntreeIndex(ctx contractapi.TransactionContextInterface, cle string, titres map[string]*TitreFoncier) ([]AnomalieCoherence, error) {
+	prefixe, attributs, err := ctx.GetStub().SplitCompositeKey(cle)
+	if err != nil {
+		return nil, err
+	}
+	id := attributs[len(attributs)-1]
+
+	titre, lu := titres[id]
+	if !lu {
+		var t TitreFoncier
+		existe, err := lireTitre(ctx, id, &t)
+		if err != nil {
+			return nil, err
+		}
+		if existe {
+			titre = &t
+		}
+		titres[id] = titre
+	}
+
+	anomalie := AnomalieCoherence{IdTitre: id, Index: prefixe, Attributs: attributs}
+	if titre == nil {
+		anomalie.Type = AnomalieIndexOrphelin
+		anomalie.Detail = "l'entrée désigne un titre absent du registre (archivé ou supprimé)"
+		return []AnomalieCoherence{anomalie}, nil
+	}
+
+	entrees, err := entreesIndex(ctx, titre)
+	if err != nil {
+		return nil, err
+	}
+	if entrees[cle] {
+		return nil, nil
+	}
+	anomalie.Type = AnomalieIndexPerime
+	anomalie.Detail = "l'entrée ne correspond plus au titre"
+	return []AnomalieCoherence{anomalie}, nil
+}
+
+// Anomalie des quotes-parts d'une copropriété : les tantièmes des lots doivent faire le total déclaré
+func controlerTantiemes(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) (*AnomalieCoherence, error) {
+	var copropriete Copropriete
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeCopropriete, []string{titre.Id})
+	if err != nil {
+		return nil, err
+	}
+	existe, err := lireEtat(ctx, cle, &copropriete)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return &AnomalieCoherence{
+			Type:    AnomalieTantiemes,
+			IdTitre: titre.Id,
+			Detail:  "le titre est placé sous le régime de la copropriété sans état descriptif enregistré",
+		}, nil
+	}
+
+	somme := 0
+	for _, numero := range copropriete.Lots {
+		var lot LotCopropriete
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLotCopropriete, []string{titre.Id, numero})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := lireEtat(ctx, cle, &lot); err != nil {
+			return nil, err
+		}
+		somme += lot.Tantiemes
+	}
+	if somme == copropriete.TantiemesTotal {
+		return nil, nil
+	}
+	return &AnomalieCoherence{
+		Type:    AnomalieTantiemes,
+		IdTitre: titre.Id,
+		Detail:  fmt.Sprintf("les tantièmes des lots (%d) diffèrent du total de la copropriété (%d)", somme, copropriete.TantiemesTotal),
+	}, nil
+}
+
+// Anomalie des parts des bénéficiaires effectifs d'un titre : celles de la dernière déclaration doivent
+// totaliser 100 %, les déclarations antérieures à leur validation ayant pu dépasser ce total
+func controlerBeneficiaires(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) (*AnomalieCoherence, error) {
+	declaration, err := derniereDeclarationBeneficiaires(ctx, titre.Id)
+	if err != nil || declaration == nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, beneficiaire := range declaration.Beneficiaires {
+		total += beneficiaire.Pourcentage
+	}
+	if total == 100 {
+		return nil, nil
+	}
+	return &AnomalieCoherence{
+		Type:    AnomalieBeneficiaires,
+		IdTitre: titre.Id,
+		Detail:  fmt.Sprintf("les parts des bénéficiaires effectifs déclarés le %s totalisent %d %%", declaration.DeclareLe, total),
+	}, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Écritures comptables d'une période (AAAA, AAAA-MM ou AAAA-MM-JJ), réservées au Trésor pour le
+// rapprochement des recettes du registre avec ses relevés : émission, paiement et remboursements de
+// chaque facture, datés par leur transaction. Les factures sont lues jour après jour dans l'index par
+// date, au plus taillePage par appel ; les écritures d'une page sont classées par date. Les factures
+// antérieures à l'index n'y figurent qu'après le job INDEX_ACTIVITE.
+func (s *SmartContract) ExtraireEcrituresComptables(ctx contractapi.TransactionContextInterface, periode string, taillePage int, signet string) (*PageEcrituresComptables, error) {
+	if err := verifierMSP(ctx, MSPTresor); err != nil {
+		return nil, err
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	debut, fin, err := bornesPeriode(periode)
+	if err != nil {
+		return nil, err
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Le signet porte le jour en cours de lecture et le signet de pagination de ce jour
+	jour, signetJour := debut, ""
+	if signet != "" {
+		date, suite, _ := strings.Cut(signet, separateurSignetAudit)
+		jour, err = time.Parse(FormatDate, date)
+		if err != nil || jour.Before(debut) || !jour.Before(fin) {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "signet d'écritures invalide: %s", signet)
+		}
+		signetJour = suite
+	}
+
+	page := &PageEcrituresComptables{Periode: periode, Ecritures: []EcritureComptable{}}
+	restant := taillePage
+	for ; jour.Before(fin); jour, signetJour = jour.AddDate(0, 0, 1), "" {
+		resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeFactureParDate, []string{jour.Format(FormatDate)}, int32(restant), signetJour)
+		if err != nil {
+			return nil, err
+		}
+		intervalle := periodeRapport{debut: jour, fin: jour.AddDate(0, 0, 1)}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			restant--
+			_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			facture, err := s.LireFacture(ctx, attributs[1])
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			ecritures, err := ecrituresFacture(s, ctx, facture, &config.ComptesComptables, intervalle)
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			page.Ecritures = append(page.Ecritures, ecritures...)
+		}
+		resultsIterator.Close()
+		if restant == 0 {
+			page.Signet = jour.Format(FormatDate) + separateurSignetAudit + metadonnees.GetBookmark()
+			break
+		}
+	}
+	sort.SliceStable(page.Ecritures, func(i, j int) bool {
+		return page.Ecritures[i].Horodatage < page.Ecritures[j].Horodatage
+	})
+	return page, nil
+}
+
+// Inscrire une facture dans l'index par date au jour d'une de ses écritures (horodatage RFC 3339)
+func indexerFactureLe(ctx contractapi.TransactionContextInterface, idFacture string, horodatage string) error {
+	instant, err := time.Parse(time.RFC3339, horodatage)
+	if err != nil {
+		return fmt.Errorf("horodatage d'écriture invalide %q: %v", horodatage, err)
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFactureParDate, []string{instant.UTC().Format(FormatDate), idFacture})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(cle, valeurIndex)
+}
+
+// Écritures d'une facture passées sur la période
+func ecrituresFacture(s *SmartContract, ctx contractapi.TransactionContextInterface, facture *Facture, comptes *ComptesComptables, periode periodeRapport) ([]EcritureComptable, error) {
+	var ecritures []EcritureComptable
+	ecriture := func(journal string, horodatage string, piece string, libelle string, debit string, credit string, montant int) {
+		ecritures = append(ecritures, EcritureComptable{
+			Journal:      journal,
+			Horodatage:   horodatage,
+			Piece:        piece,
+			IdFacture:    facture.Id,
+			Procedure:    facture.Procedure,
+			Libelle:      libelle,
+			CompteDebit:  debit,
+			CompteCredit: credit,
+			Montant:      montant,
+		})
+	}
+	dansPeriode := func(horodatage string) bool {
+		instant, err := time.Parse(time.RFC3339, horodatage)
+		return err == nil && periode.contient(instant)
+	}
+
+	if dansPeriode(facture.EmiseLe) {
+		for _, ligne := range facture.Lignes {
+			ecriture(JournalFacturation, facture.EmiseLe, facture.Id, ligne.Motif, comptes.Redevables, comptes.Produit(facture.Procedure), ligne.Montant)
+		}
+	}
+	if facture.Statut == FacturePayee && dansPeriode(facture.PayeeLe) {
+		ecriture(JournalEncaissement, facture.PayeeLe, facture.RefTresor, fmt.Sprintf("Règlement de la facture %s", facture.Id), comptes.Tresorerie, comptes.Redevables, facture.Montant)
+	}
+	for _, id := range facture.Remboursements {
+		remboursement, err := s.LireRemboursement(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if remboursement.Statut == RemboursementApprouve && dansPeriode(remboursement.DecideLe) {
+			ecriture(JournalRemboursement, remboursement.DecideLe, remboursement.RefTresor, remboursement.Motif, comptes.Remboursement(facture.Procedure), comptes.Tresorerie, remboursement.Montant)
+		}
+	}
+	return ecritures, nil
+}
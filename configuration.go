@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Version du contrat déployé
+const VersionContrat = "1.1.0"
+
+// Préfixe des clés composites de configuration, une par canal régional
+const PrefixeConfiguration = "CONFIG"
+
+// Préfixe des clés composites des compteurs de numérotation
+const PrefixeSequence = "SEQUENCE"
+
+// Informations sur le canal et le contrat en service
+type InfoChaine struct {
+	Canal                  string `json:"canal"`
+	VersionContrat         string `json:"versionContrat"`
+	EmpreinteConfiguration string `json:"empreinteConfiguration"` // SHA-256 de la configuration du canal
+}
+
+// Enregistrer la configuration du canal courant (réservé à l'État)
+func (s *SmartContract) DefinirConfiguration(ctx contractapi.TransactionContextInterface, configJSON string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	config := model.ConfigurationParDefaut()
+	if err := json.Unmarshal([]byte(configJSON), config); err != nil {
+		return fmt.Errorf("configuration invalide: %v", err)
+	}
+	if err := config.Valider(); err != nil {
+		return err
+	}
+
+	cle, err := cleConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+
+	// La configuration reste en JSON : c'est elle qui fixe le format des autres enregistrements
+	valeur, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(cle, valeur)
+}
+
+// Lire la configuration du canal courant
+func (s *SmartContract) LireConfiguration(ctx contractapi.TransactionContextInterface) (*Configuration, error) {
+	return lireConfiguration(ctx)
+}
+
+func lireConfiguration(ctx contractapi.TransactionContextInterface) (*Configuration, error) {
+	configJSON, err := lireConfigurationBrute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := model.ConfigurationParDefaut()
+	if configJSON == nil {
+		return config, nil
+	}
+	if err := json.Unmarshal(configJSON, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Informations sur le canal, la version du contrat et la configuration en vigueur
+func (s *SmartContract) GetInfoChaine(ctx contractapi.TransactionContextInterface) (*InfoChaine, error) {
+	configJSON, err := lireConfigurationBrute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if configJSON == nil {
+		if configJSON, err = json.Marshal(model.ConfigurationParDefaut()); err != nil {
+			return nil, err
+		}
+	}
+	empreinte := sha256.Sum256(configJSON)
+
+	return &InfoChaine{
+		Canal:                  ctx.GetStub().GetChannelID(),
+		VersionContrat:         VersionContrat,
+		EmpreinteConfiguration: hex.EncodeToString(empreinte[:]),
+	}, nil
+}
+
+// Vérifier qu'une commune relève du canal courant
+func verifierCommune(config *Configuration, commune string) error {
+	if !config.CommuneAutorisee(commune) {
+		return nouvelleErreur(CodeCommuneHorsCanal, "la commune %s n'est pas administrée sur ce canal", commune)
+	}
+	return nil
+}
+
+// Attribuer le prochain numéro d'une séquence propre au canal (ex: "DK-000042")
+func (s *SmartContract) prochainNumero(ctx contractapi.TransactionContextInterface, sequence string) (string, error) {
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequence, []string{ctx.GetStub().GetChannelID(), sequence})
+	if err != nil {
+		return "", err
+	}
+	valeur, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de la séquence: %v", err)
+	}
+
+	compteur := 0
+	if valeur != nil {
+		if compteur, err = strconv.Atoi(string(valeur)); err != nil {
+			return "", fmt.Errorf("séquence %s corrompue: %v", sequence, err)
+		}
+	}
+	compteur++
+
+	if err := ctx.GetStub().PutState(cle, []byte(strconv.Itoa(compteur))); err != nil {
+		return "", err
+	}
+
+	numero := fmt.Sprintf("%06d", compteur)
+	if config.PrefixeNumerotation != "" {
+		numero = config.PrefixeNumerotation + "-" + numero
+	}
+	return numero, nil
+}
+
+// Clé de la configuration du canal courant
+func cleConfiguration(ctx contractapi.TransactionContextInterface) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(PrefixeConfiguration, []string{ctx.GetStub().GetChannelID()})
+}
+
+// Configuration lue au cours d'une transaction : GetState ne restitue que l'état validé, que la
+// transaction n'écrit pas pour elle-même, de sorte qu'une seule lecture par transaction suffit
+type memoConfiguration struct {
+	lue     bool
+	contenu []byte
+}
+
+// Configurations mémorisées des transactions en cours, par stub (voir chaincodeEnveloppe)
+var configurationsTransaction sync.Map
+
+func lireConfigurationBrute(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	var memo *memoConfiguration
+	if valeur, ok := configurationsTransaction.Load(ctx.GetStub()); ok {
+		memo = valeur.(*memoConfiguration)
+		if memo.lue {
+			return memo.contenu, nil
+		}
+	}
+
+	cle, err := cleConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configJSON, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de la configuration: %v", err)
+	}
+	if memo != nil {
+		memo.lue, memo.contenu = true, configJSON
+	}
+	return configJSON, nil
+}
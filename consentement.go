@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des consentements au partage de données (proprio~destinataire)
+const PrefixeConsentement = "CONSENTEMENT"
+
+// Durée maximale d'un consentement, en jours
+const DureeConsentementMax = 365
+
+// Organisations consultant les titres sans consentement du propriétaire : autorités publiques agissant
+// en vertu de la loi, conservation et professions qui instruisent les dossiers, et passerelle citoyenne
+// (portail public, consultations anonymes et comptes de l'API REST, qui filtre ses propres accès)
+var organisationsSansConsentement = []string{
+	MSPEtat, MSPJuridictions, MSPParquet, MSPConformite, MSPTresor,
+	MSPConservation, MSPChambreNotaires, MSPOrdreGeometres, MSPOrdreEvaluateurs,
+	MSPPasserelle, MSPPasserelleIdemix,
+}
+
+// Consultations d'un titre, désigné par leur premier argument, soumises au consentement de son
+// propriétaire et à une consultation déclarée lorsqu'une organisation tierce les appelle directement
+var consultationsTitre = []string{
+	"ComparerVersions",
+	"GetAssurancesTitre",
+	"GetCertificatsConformite",
+	"GetEmprisesTitre",
+	"GetHistoriqueEvaluations",
+	"GetLitigesTitre",
+	"GetPermisConstruire",
+	"GetRangHypotheques",
+	"GetSaisiesConservatoires",
+	"LireGeometrieALaDate",
+	"LireTitreALaDate",
+	"LireTitreFoncier",
+	"LireVersionTitre",
+}
+
+// Autoriser, pour le propriétaire appelant, une organisation tierce (banque, employeur) à consulter
+// ses titres pendant duree jours ; le consentement remplace celui déjà accordé au même destinataire
+func (s *SmartContract) AccorderConsentement(ctx contractapi.TransactionContextInterface, destinataire string, portee string, duree int) (*ConsentementPartage, error) {
+	if destinataire == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le destinataire du consentement est obligatoire")
+	}
+	if portee != PorteeTitre && portee != PorteeDossier {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "portée de consentement inconnue: %s (attendu: %s ou %s)", portee, PorteeTitre, PorteeDossier)
+	}
+	if duree <= 0 || duree > DureeConsentementMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la durée doit être comprise entre 1 et %d jours", DureeConsentementMax)
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio == "" {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'identité de l'appelant n'est rattachée à aucun propriétaire")
+	}
+	ids, err := titresIndexes(ctx, PrefixeIndexProprio, []string{proprio}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nouvelleErreur(CodeAccesRefuse, "aucun titre n'est enregistré au nom de %s", proprio)
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consentement := &ConsentementPartage{
+		Id:           ctx.GetStub().GetTxID(),
+		Proprio:      proprio,
+		Destinataire: destinataire,
+		Portee:       portee,
+		AccordeLe:    maintenant.Format(time.RFC3339),
+		ExpireLe:     maintenant.AddDate(0, 0, duree).Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentement, []string{proprio, destinataire})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, consentement); err != nil {
+		return nil, err
+	}
+	return consentement, nil
+}
+
+// Révoquer, avec effet immédiat, le consentement accordé par le propriétaire appelant à un destinataire
+func (s *SmartContract) RevoquerConsentement(ctx contractapi.TransactionContextInterface, destinataire string) (*ConsentementPartage, error) {
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio == "" {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'identité de l'appelant n'est rattachée à aucun propriétaire")
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentement, []string{proprio, destinataire})
+	if err != nil {
+		return nil, err
+	}
+	var consentement ConsentementPartage
+	existe, err := lireEtat(ctx, cle, &consentement)
+	if err != nil {
+		return nil, err
+	}
+	if !existe || consentement.RevoqueLe != "" {
+		return nil, fmt.Errorf("aucun consentement de %s en faveur de %s n'est en vigueur", proprio, destinataire)
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consentement.RevoqueLe = maintenant.Format(time.RFC3339)
+	consentement.TxRevocation = ctx.GetStub().GetTxID()
+	if err := ecrireEtat(ctx, cle, &consentement); err != nil {
+		return nil, err
+	}
+	return &consentement, nil
+}
+
+// Consentements accordés par le propriétaire appelant, révoqués et expirés compris
+func (s *SmartContract) GetConsentements(ctx contractapi.TransactionContextInterface) ([]*ConsentementPartage, error) {
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio == "" {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'identité de l'appelant n'est rattachée à aucun propriétaire")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeConsentement, []string{proprio})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	consentements := []*ConsentementPartage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var consentement ConsentementPartage
+		if err := decoderEtat(queryResponse.Value, &consentement); err != nil {
+			return nil, err
+		}
+		consentements = append(consentements, &consentement)
+	}
+	return consentements, nil
+}
+
+// Vérifier, avant une lecture déléguée, que l'appelant est le propriétaire du titre, une autorité
+// publique, ou une organisation à laquelle le propriétaire a consenti la consultation de cette portée
+func verifierConsentement(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, portee string) error {
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if proprio != "" && proprio == titre.Proprio {
+		return nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if slices.Contains(organisationsSansConsentement, mspID) {
+		return nil
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentement, []string{titre.Proprio, mspID})
+	if err != nil {
+		return err
+	}
+	var consentement ConsentementPartage
+	existe, err := lireEtat(ctx, cle, &consentement)
+	if err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if !existe || !consentement.Autorise(portee, maintenant) {
+		return nouvelleErreur(CodeConsentementRequis, "la consultation du titre foncier %s par %s requiert le consentement en vigueur de son propriétaire (portée %s)", titre.Id, mspID, portee)
+	}
+	return nil
+}
+
+// Vérifier, avant une consultation de titre appelée par une organisation tierce, le consentement du
+// propriétaire et la déclaration préalable de sa finalité (voir DeclarerConsultation)
+func verifierConsultationTierce(ctx *ContexteTransaction) error {
+	operation := operationCourante(ctx)
+	if !slices.Contains(consultationsTitre, operation) {
+		return nil
+	}
+	_, arguments := ctx.GetStub().GetFunctionAndParameters()
+	if len(arguments) == 0 {
+		return nil
+	}
+	// Un titre absent est signalé par la consultation elle-même
+	var titre TitreFoncier
+	existe, err := lireEtat(ctx, arguments[0], &titre)
+	if err != nil || !existe || titre.Id == "" {
+		return err
+	}
+
+	tierce, err := organisationTierce(ctx, &titre)
+	if err != nil || !tierce {
+		return err
+	}
+	if err := controlerConsentement(ctx, &titre, operation); err != nil {
+		return err
+	}
+	return verifierConsultationDeclaree(ctx, titre.Id)
+}
+
+// Indiquer si l'appelant n'est ni le propriétaire du titre, ni une organisation qui le consulte sans
+// son consentement
+func organisationTierce(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) (bool, error) {
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return false, err
+	}
+	if proprio != "" && proprio == titre.Proprio {
+		return false, nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	return !slices.Contains(organisationsSansConsentement, mspID), nil
+}
+
+// Vérifier le consentement du propriétaire à une consultation ; sans BlocageConsentements, la
+// consultation non consentie est seulement journalisée
+func controlerConsentement(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, operation string) error {
+	err := verifierConsentement(ctx, titre, PorteeTitre)
+	var erreur *ErreurMetier
+	if !errors.As(err, &erreur) || erreur.Code != CodeConsentementRequis {
+		return err
+	}
+	config, errConfig := lireConfiguration(ctx)
+	if errConfig != nil {
+		return errConfig
+	}
+	if config.BlocageConsentements {
+		return err
+	}
+	journalTx(ctx).Warn("consultation sans consentement du propriétaire", slog.String("operation", operation), slog.String("idTitre", titre.Id))
+	return nil
+}
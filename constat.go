@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des constats de terrain (idTitre~idConstat)
+const PrefixeConstatTerrain = "CONSTAT_TERRAIN"
+
+// Enregistrer le constat d'un inspecteur sur la parcelle d'un titre (réservé aux inspecteurs) : les
+// coordonnées relevées (GeoJSON) et les photos (JSON, liste de hashes) pourront être confrontées à la
+// géométrie déclarée du titre lors de l'instruction d'un litige
+func (s *SmartContract) EnregistrerConstatTerrain(ctx contractapi.TransactionContextInterface, idTitre string, coordonnees string, photosHashes string, observations string) (string, error) {
+	if err := verifierRole(ctx, RoleInspecteur); err != nil {
+		return "", err
+	}
+	if !json.Valid([]byte(coordonnees)) {
+		return "", nouvelleErreur(CodeRequeteInvalide, "coordonnées invalides, GeoJSON attendu")
+	}
+	var photos []string
+	if err := json.Unmarshal([]byte(photosHashes), &photos); err != nil {
+		return "", nouvelleErreur(CodeRequeteInvalide, "liste des hashes de photos invalide: %v", err)
+	}
+	for i, photo := range photos {
+		photos[i] = strings.ToLower(strings.TrimSpace(photo))
+		if !hashValide(photos[i]) {
+			return "", nouvelleErreur(CodeRequeteInvalide, "hash de photo invalide (SHA-1 ou SHA-256 attendu): %s", photo)
+		}
+	}
+	if photos == nil {
+		photos = []string{}
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return "", err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return "", err
+	}
+	inspecteur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	constat := &ConstatTerrain{
+		Id:           ctx.GetStub().GetTxID(),
+		IdTitre:      idTitre,
+		Coordonnees:  coordonnees,
+		Photos:       photos,
+		Observations: observations,
+		Inspecteur:   inspecteur,
+		Horodatage:   maintenant.Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConstatTerrain, []string{idTitre, constat.Id})
+	if err != nil {
+		return "", err
+	}
+	if err := ecrireEtat(ctx, cle, constat); err != nil {
+		return "", err
+	}
+
+	return constat.Id, nil
+}
+
+// Lister les constats de terrain d'un titre foncier
+func (s *SmartContract) GetConstatsTerrain(ctx contractapi.TransactionContextInterface, idTitre string) ([]*ConstatTerrain, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeConstatTerrain, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var constats []*ConstatTerrain
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var constat ConstatTerrain
+		err = decoderEtat(queryResponse.Value, &constat)
+		if err != nil {
+			return nil, err
+		}
+		constats = append(constats, &constat)
+	}
+
+	return constats, nil
+}
+
+// Hash hexadécimal d'un des algorithmes acceptés par le registre (SHA-1, SHA-256)
+func hashValide(hash string) bool {
+	octets, err := hex.DecodeString(hash)
+	return err == nil && (len(octets) == 20 || len(octets) == 32)
+}
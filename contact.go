@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Collection privée des données personnelles des propriétaires (voir collections_config.json)
+const CollectionDonneesPersonnelles = "donneesPersonnelles"
+
+// Préfixe des clés composites des coordonnées (idProprio)
+const PrefixeContact = "CONTACT"
+
+// Clé transitoire portant les coordonnées, qui ne doivent pas figurer dans les arguments de la transaction
+const CleTransitoireContact = "contact"
+
+// Mettre à jour ses propres coordonnées, transmises en JSON sous la clé transitoire "contact" ;
+// les champs omis restent inchangés
+func (s *SmartContract) MettreAJourContact(ctx contractapi.TransactionContextInterface) error {
+	idProprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if idProprio == "" {
+		return nouvelleErreur(CodeAccesRefuse, "l'identité de l'appelant n'est rattachée à aucun propriétaire")
+	}
+	ids, err := titresIndexes(ctx, PrefixeIndexProprio, []string{idProprio}, 1)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nouvelleErreur(CodeAccesRefuse, "aucun titre n'est enregistré au nom de %s", idProprio)
+	}
+
+	transitoire, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des données transitoires: %v", err)
+	}
+	saisie, ok := transitoire[CleTransitoireContact]
+	if !ok {
+		return fmt.Errorf("coordonnées attendues sous la clé transitoire %q", CleTransitoireContact)
+	}
+	var nouveau ContactProprietaire
+	if err := decoderJSON(saisie, &nouveau); err != nil {
+		return fmt.Errorf("coordonnées invalides: %v", err)
+	}
+	if err := nouveau.Valider(); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeContact, []string{idProprio})
+	if err != nil {
+		return err
+	}
+	contact := ContactProprietaire{IdProprio: idProprio}
+	if _, err := lirePrive(ctx, CollectionDonneesPersonnelles, cle, &contact); err != nil {
+		return err
+	}
+	if nouveau.Telephone != "" {
+		contact.Telephone = nouveau.Telephone
+	}
+	if nouveau.Email != "" {
+		contact.Email = nouveau.Email
+	}
+	if nouveau.Adresse != "" {
+		contact.Adresse = nouveau.Adresse
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	contact.MisAJourLe = maintenant.Format(time.RFC3339)
+
+	return ecrirePrive(ctx, CollectionDonneesPersonnelles, cle, &contact)
+}
+
+// Lire les coordonnées d'un propriétaire : un propriétaire ne lit que les siennes, les agents de l'État toutes
+func (s *SmartContract) LireContact(ctx contractapi.TransactionContextInterface, idProprio string) (*ContactProprietaire, error) {
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio != "" && proprio != idProprio {
+		return nil, nouvelleErreur(CodeAccesRefuse, "les coordonnées de %s ne sont pas accessibles à %s", idProprio, proprio)
+	}
+	if proprio == "" {
+		if err := verifierMSP(ctx, MSPEtat); err != nil {
+			return nil, err
+		}
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeContact, []string{idProprio})
+	if err != nil {
+		return nil, err
+	}
+	var contact ContactProprietaire
+	existe, err := lirePrive(ctx, CollectionDonneesPersonnelles, cle, &contact)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("aucune coordonnée enregistrée pour %s", idProprio)
+	}
+
+	return &contact, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Identité cliente d'un appelant : MSP, attributs et nom commun (le rôle par défaut) de son certificat
+type identiteTest struct {
+	msp       string
+	attributs map[string]string
+	nom       string
+}
+
+func (i *identiteTest) GetID() (string, error) {
+	if i.nom != "" {
+		return "x509::CN=" + i.nom + "::" + i.msp, nil
+	}
+	return "x509::CN=" + i.attributs["role"] + "::" + i.msp, nil
+}
+func (i *identiteTest) GetMSPID() (string, error) { return i.msp, nil }
+func (i *identiteTest) GetAttributeValue(nom string) (string, bool, error) {
+	valeur, trouve := i.attributs[nom]
+	return valeur, trouve, nil
+}
+func (i *identiteTest) AssertAttributeValue(nom string, valeur string) error {
+	if i.attributs[nom] != valeur {
+		return fmt.Errorf("attribut %s différent de %s", nom, valeur)
+	}
+	return nil
+}
+func (i *identiteTest) GetX509Certificate() (*x509.Certificate, error) { return nil, nil }
+
+// Appelants types
+var (
+	etat           = &identiteTest{msp: MSPEtat}
+	conservateurDK = &identiteTest{msp: MSPConservation, attributs: map[string]string{"role": RoleConservateur, "bureau": "DK"}}
+	conservateurTH = &identiteTest{msp: MSPConservation, attributs: map[string]string{"role": RoleConservateur, "bureau": "TH"}}
+	inspecteurDK   = &identiteTest{msp: MSPConservation, attributs: map[string]string{"role": RoleInspecteur, "bureau": "DK"}}
+	notaire        = &identiteTest{msp: MSPChambreNotaires, attributs: map[string]string{"role": RoleNotaire, "bureau": "DK"}}
+	sansRole       = &identiteTest{msp: MSPConservation, attributs: map[string]string{"bureau": "DK"}}
+)
+
+// Registre d'un canal sur un stub simulé : chaque appel est une transaction validée à son retour,
+// sauf en cas d'erreur où ses écritures sont abandonnées comme le ferait l'endossement
+type registreTest struct {
+	t    *testing.T
+	stub *shimtest.MockStub
+	s    *SmartContract
+	txs  int
+	pdf  string
+}
+
+func nouveauRegistreTest(t *testing.T) *registreTest {
+	r := &registreTest{t: t, s: &SmartContract{}}
+	r.stub = shimtest.NewMockStub("titrefoncier", consultationsCanal{r.s})
+	r.stub.ChannelID = "dakar"
+	r.pdf = filepath.Join(t.TempDir(), "titre.pdf")
+	if err := os.WriteFile(r.pdf, []byte("%PDF-1.4\n% titre foncier\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error { return r.s.InitLedger(ctx) }); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Relier les registres de deux canaux pour leurs appels inter-canaux
+func (r *registreTest) relier(autre *registreTest) {
+	r.stub.Invokables["titrefoncier/"+autre.stub.ChannelID] = autre.stub
+	autre.stub.Invokables["titrefoncier/"+r.stub.ChannelID] = r.stub
+}
+
+// Chaincode d'un registre tel que l'appellent les autres canaux : ses consultations booléennes, sous
+// l'identité de l'État
+type consultationsCanal struct{ s *SmartContract }
+
+func (c consultationsCanal) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c consultationsCanal) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(etat)
+	var resultat bool
+	var err error
+	switch args := stub.GetStringArgs(); args[0] {
+	case "VerifierCertificatMutation":
+		resultat, err = c.s.VerifierCertificatMutation(ctx, args[1], args[2])
+	case "CertificatImporte":
+		resultat, err = c.s.CertificatImporte(ctx, args[1], args[2])
+	case "ImportRenonce":
+		resultat, err = c.s.ImportRenonce(ctx, args[1], args[2])
+	default:
+		return shim.Error("consultation inconnue: " + args[0])
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(strconv.FormatBool(resultat)))
+}
+
+func (r *registreTest) appeler(appelant *identiteTest, transaction func(contractapi.TransactionContextInterface) error) error {
+	r.txs++
+	txId := fmt.Sprintf("tx%d", r.txs)
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(r.stub)
+	ctx.SetClientIdentity(appelant)
+
+	sauvegarde := make(map[string][]byte, len(r.stub.State))
+	for cle, valeur := range r.stub.State {
+		sauvegarde[cle] = valeur
+	}
+	cles := list.New()
+	cles.PushBackList(r.stub.Keys)
+	r.stub.MockTransactionStart(txId)
+	err := transaction(ctx)
+	r.stub.MockTransactionEnd(txId)
+	if err != nil {
+		r.stub.State, r.stub.Keys = sauvegarde, cles
+	}
+	return err
+}
+
+func (r *registreTest) ajouter(appelant *identiteTest, id string, proprio string) error {
+	return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.AjouterTitreFoncier(ctx, id, proprio, "", 1000, r.pdf)
+	})
+}
+
+func (r *registreTest) lire(id string) *TitreFoncier {
+	r.t.Helper()
+	var titre *TitreFoncier
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		titre, err = r.s.LireTitreFoncier(ctx, id)
+		return err
+	})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return titre
+}
+
+// Autorité émettrice au certificat auto-signé, et sa clé de signature
+type autoriteTest struct {
+	id         string
+	cle        *ecdsa.PrivateKey
+	certificat string
+}
+
+func nouvelleAutoriteTest(t *testing.T, id string) *autoriteTest {
+	t.Helper()
+	cle, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modele := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modele, modele, &cle.PublicKey, cle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &autoriteTest{id: id, cle: cle, certificat: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// Signature ECDSA d'un contenu, encodée en base64
+func (a *autoriteTest) signer(t *testing.T, contenu []byte) string {
+	t.Helper()
+	hash := sha256.Sum256(contenu)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.cle, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+func (r *registreTest) enregistrerAutorite(a *autoriteTest, typeAutorite string) {
+	r.t.Helper()
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.EnregistrerAutorite(ctx, a.id, a.id, typeAutorite, a.certificat)
+	})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Enregistrer un tribunal au certificat auto-signé
+func (r *registreTest) autorite(id string) *autoriteTest {
+	r.t.Helper()
+	a := nouvelleAutoriteTest(r.t, id)
+	r.enregistrerAutorite(a, AutoriteTribunal)
+	return a
+}
+
+// Déposer à côté du document de test sa signature détachée par une autorité
+func (r *registreTest) signerDocument(a *autoriteTest) {
+	r.t.Helper()
+	contenu, err := os.ReadFile(r.pdf)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(r.pdf+SuffixeSignatureDocument, []byte(a.signer(r.t, contenu)+"\n"), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Vérifier le code métier d'une erreur (vide : succès attendu)
+func verifierCode(t *testing.T, operation string, err error, code string) {
+	t.Helper()
+	var metier *ErreurMetier
+	switch {
+	case code == "" && err != nil:
+		t.Errorf("%s: %v", operation, err)
+	case code != "" && err == nil:
+		t.Errorf("%s: acceptée, %s attendu", operation, code)
+	case code != "" && (!errors.As(err, &metier) || metier.Code != code):
+		t.Errorf("%s: %v, %s attendu", operation, err, code)
+	}
+}
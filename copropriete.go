@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des copropriétés (idTitre) et de leurs lots (idTitre~numero)
+const (
+	PrefixeCopropriete    = "COPROPRIETE"
+	PrefixeLotCopropriete = "LOT_COPROPRIETE"
+)
+
+// Placer un titre sous le régime de la copropriété selon son état descriptif de division (réservé aux
+// conservateurs) ; les lots reviennent d'abord au propriétaire du titre, puis sont cédés séparément
+func (s *SmartContract) CreerCopropriete(ctx contractapi.TransactionContextInterface, idTitre string, syndic string, descriptifJSON string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	if syndic == "" {
+		return fmt.Errorf("le syndic de la copropriété est obligatoire")
+	}
+
+	var descriptif DescriptifCopropriete
+	if err := json.Unmarshal([]byte(descriptifJSON), &descriptif); err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "état descriptif de division invalide: %v", err)
+	}
+	if err := descriptif.Valider(); err != nil {
+		return err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAlienable(titre); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	copropriete := &Copropriete{
+		IdTitre:         idTitre,
+		Syndic:          syndic,
+		TantiemesTotal:  descriptif.TantiemesTotal,
+		Lots:            []string{},
+		PartiesCommunes: descriptif.PartiesCommunes,
+		CreeLe:          maintenant.Format(FormatDate),
+	}
+	if copropriete.PartiesCommunes == nil {
+		copropriete.PartiesCommunes = []PartieCommune{}
+	}
+	for i := range descriptif.Lots {
+		lot := &descriptif.Lots[i]
+		lot.IdTitre = idTitre
+		lot.Proprio = titre.Proprio
+		lot.Acte = ""
+		lot.CessionConsentie = ""
+		lot.Saisies = nil
+		if err := sauvegarderLotCopropriete(ctx, lot); err != nil {
+			return err
+		}
+		copropriete.Lots = append(copropriete.Lots, lot.Numero)
+	}
+	if err := sauvegarderCopropriete(ctx, copropriete); err != nil {
+		return err
+	}
+
+	titre.Copropriete = true
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Enregistrer le syndic désigné par délibération de l'assemblée générale des copropriétaires (réservé aux
+// conservateurs du bureau du titre support)
+func (s *SmartContract) DesignerSyndic(ctx contractapi.TransactionContextInterface, idTitre string, syndic string, deliberation string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	if syndic == "" || deliberation == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "le syndic et la délibération de l'assemblée générale qui le désigne sont obligatoires")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	copropriete, err := s.LireCopropriete(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	copropriete.Syndic = syndic
+	copropriete.Deliberation = deliberation
+
+	return sauvegarderCopropriete(ctx, copropriete)
+}
+
+// Consentir, pour le propriétaire d'un lot représenté par la passerelle citoyenne, la cession de son lot
+// à l'acheteur indiqué ; le consentement remplace le précédent et vaut jusqu'à l'enregistrement de la cession
+func (s *SmartContract) ConsentirCessionLot(ctx contractapi.TransactionContextInterface, idTitre string, numero string, acheteur string) error {
+	if acheteur == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "l'acheteur du lot est obligatoire")
+	}
+	lot, err := s.LireLotCopropriete(ctx, idTitre, numero)
+	if err != nil {
+		return err
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if proprio == "" || proprio != lot.Proprio {
+		return nouvelleErreur(CodeAccesRefuse, "seul le propriétaire du lot %s de la copropriété %s peut en consentir la cession", numero, idTitre)
+	}
+	if err := verifierLotNonSaisi(lot); err != nil {
+		return err
+	}
+
+	lot.CessionConsentie = acheteur
+	return sauvegarderLotCopropriete(ctx, lot)
+}
+
+// Enregistrer la cession d'un lot de copropriété, indépendamment des autres lots, sur l'acte notarié qui
+// la constate (réservé aux conservateurs du bureau du titre support) ; son propriétaire doit l'avoir
+// consentie à cet acheteur (ConsentirCessionLot)
+func (s *SmartContract) CederLotCopropriete(ctx contractapi.TransactionContextInterface, idTitre string, numero string, acheteur string, acte string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	if acheteur == "" || acte == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "l'acheteur et l'acte de cession du lot sont obligatoires")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	// Le régime de la copropriété n'interdit que la cession du titre entier
+	support := *titre
+	support.Copropriete = false
+	if err := verifierAlienable(&support); err != nil {
+		return err
+	}
+
+	lot, err := s.LireLotCopropriete(ctx, idTitre, numero)
+	if err != nil {
+		return err
+	}
+	if err := verifierLotNonSaisi(lot); err != nil {
+		return err
+	}
+	if lot.CessionConsentie != acheteur {
+		return nouvelleErreur(CodeConsentementRequis, "le propriétaire du lot %s de la copropriété %s n'a pas consenti sa cession à %s", numero, idTitre, acheteur)
+	}
+	lot.Proprio = acheteur
+	lot.Acte = acte
+	lot.CessionConsentie = ""
+
+	return sauvegarderLotCopropriete(ctx, lot)
+}
+
+// Vérifier qu'aucune saisie conservatoire n'est en vigueur sur un lot de copropriété
+func verifierLotNonSaisi(lot *LotCopropriete) error {
+	if len(lot.Saisies) > 0 {
+		return nouvelleErreur(CodeTitreSaisi, "le lot %s de la copropriété %s fait l'objet d'une saisie conservatoire (procédure %s)", lot.Numero, lot.IdTitre, lot.Saisies[0])
+	}
+	return nil
+}
+
+// Lire la copropriété constituée sur un titre
+func (s *SmartContract) LireCopropriete(ctx contractapi.TransactionContextInterface, idTitre string) (*Copropriete, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeCopropriete, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+
+	var copropriete Copropriete
+	existe, err := lireEtat(ctx, cle, &copropriete)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("le titre foncier %s n'est pas placé sous le régime de la copropriété", idTitre)
+	}
+
+	return &copropriete, nil
+}
+
+// Lire un lot de copropriété
+func (s *SmartContract) LireLotCopropriete(ctx contractapi.TransactionContextInterface, idTitre string, numero string) (*LotCopropriete, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLotCopropriete, []string{idTitre, numero})
+	if err != nil {
+		return nil, err
+	}
+
+	var lot LotCopropriete
+	existe, err := lireEtat(ctx, cle, &lot)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("lot %s de la copropriété %s non trouvé", numero, idTitre)
+	}
+
+	return &lot, nil
+}
+
+// Lister les lots d'une copropriété
+func (s *SmartContract) GetLotsCopropriete(ctx contractapi.TransactionContextInterface, idTitre string) ([]*LotCopropriete, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeLotCopropriete, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var lots []*LotCopropriete
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var lot LotCopropriete
+		err = decoderEtat(queryResponse.Value, &lot)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, &lot)
+	}
+
+	return lots, nil
+}
+
+func sauvegarderCopropriete(ctx contractapi.TransactionContextInterface, copropriete *Copropriete) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeCopropriete, []string{copropriete.IdTitre})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, copropriete)
+}
+
+func sauvegarderLotCopropriete(ctx contractapi.TransactionContextInterface, lot *LotCopropriete) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLotCopropriete, []string{lot.IdTitre, lot.Numero})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, lot)
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des décisions de justice (juridiction~numero)
+const PrefixeDecisionJudiciaire = "DECISION_JUDICIAIRE"
+
+// Enregistrer une décision de justice (réservé aux greffes des juridictions) ; elle pourra être citée,
+// sous la référence "juridiction:numero", comme fondement des seules opérations que sa portée ordonne
+// sur les titres (JSON, liste d'identifiants) qu'elle vise. La juridiction doit être un tribunal actif
+// du registre des autorités ; une mainlevée désigne la décision de gel qu'elle lève.
+func (s *SmartContract) EnregistrerDecisionJudiciaire(ctx contractapi.TransactionContextInterface, juridiction string, numero string, dispositif string, hashJugement string, rendueLe string, portee string, titres string, beneficiaire string, decisionLevee string) error {
+	if err := verifierMSP(ctx, MSPJuridictions); err != nil {
+		return err
+	}
+	if juridiction == "" || strings.Contains(juridiction, ":") || numero == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "la juridiction (sans ':') et le numéro de la décision sont obligatoires")
+	}
+	if dispositif == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "le dispositif de la décision est obligatoire")
+	}
+	if !hashValide(hashJugement) {
+		return nouvelleErreur(CodeRequeteInvalide, "hash du jugement invalide (SHA-1 ou SHA-256 hexadécimal attendu): %s", hashJugement)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := time.Parse(FormatDate, rendueLe); err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "date de la décision invalide (AAAA-MM-JJ attendu): %s", rendueLe)
+	}
+	if rendueLe > maintenant.Format(FormatDate) {
+		return nouvelleErreur(CodeRequeteInvalide, "la décision ne peut être datée du %s, postérieur à son enregistrement", rendueLe)
+	}
+	if err := verifierJuridiction(s, ctx, juridiction); err != nil {
+		return err
+	}
+
+	decision := &DecisionJudiciaire{
+		Juridiction:   juridiction,
+		Numero:        numero,
+		Dispositif:    dispositif,
+		Portee:        portee,
+		Beneficiaire:  beneficiaire,
+		DecisionLevee: decisionLevee,
+		HashJugement:  hashJugement,
+		RendueLe:      rendueLe,
+		EnregistreeLe: maintenant.Format(time.RFC3339),
+	}
+	if err := json.Unmarshal([]byte(titres), &decision.Titres); err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "liste des titres visés invalide: %v", err)
+	}
+	if err := decision.Valider(); err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	if decision.Portee == ExecutionMainlevee {
+		gel, err := decisionCitee(ctx, decision.DecisionLevee)
+		if err != nil {
+			return err
+		}
+		for _, id := range decision.Titres {
+			if !gel.Vise(ExecutionGel, id) {
+				return nouvelleErreur(CodeRequeteInvalide, "la décision %s n'a pas ordonné le gel du titre foncier %s", gel.Reference(), id)
+			}
+		}
+	}
+
+	existante, err := lireDecision(ctx, juridiction, numero)
+	if err != nil {
+		return err
+	}
+	if existante != nil {
+		return fmt.Errorf("la décision %s est déjà enregistrée", existante.Reference())
+	}
+	if decision.EnregistreePar, err = ctx.GetClientIdentity().GetID(); err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	return sauvegarderDecision(ctx, decision)
+}
+
+// Vérifier que la juridiction est un tribunal actif du registre des autorités
+func verifierJuridiction(s *SmartContract, ctx contractapi.TransactionContextInterface, juridiction string) error {
+	autorite, err := s.LireAutorite(ctx, juridiction)
+	if err != nil || autorite.Type != AutoriteTribunal {
+		return nouvelleErreur(CodeEmetteurNonReconnu, "la juridiction %s n'est pas un tribunal reconnu du registre des autorités", juridiction)
+	}
+	if !autorite.Active {
+		return nouvelleErreur(CodeEmetteurNonReconnu, "le tribunal %s a été révoqué du registre des autorités", juridiction)
+	}
+	return nil
+}
+
+// Lire une décision de justice et les opérations fondées sur elle
+func (s *SmartContract) LireDecisionJudiciaire(ctx contractapi.TransactionContextInterface, juridiction string, numero string) (*DecisionJudiciaire, error) {
+	decision, err := lireDecision(ctx, juridiction, numero)
+	if err != nil {
+		return nil, err
+	}
+	if decision == nil {
+		return nil, fmt.Errorf("décision %s:%s non trouvée", juridiction, numero)
+	}
+	return decision, nil
+}
+
+// Geler un titre en exécution d'une décision de justice (réservé au conservateur) : le gel bloque les
+// actes de disposition comme une saisie conservatoire, jusqu'à sa mainlevée sur une autre décision
+func (s *SmartContract) GelerTitreSurDecision(ctx contractapi.TransactionContextInterface, idTitre string, decision string) error {
+	titre, err := s.titreAExecuter(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	fondement, err := decisionOrdonnant(ctx, decision, ExecutionGel, idTitre)
+	if err != nil {
+		return err
+	}
+	existante, err := lireSaisie(ctx, idTitre, decision)
+	if err != nil {
+		return err
+	}
+	if existante != nil {
+		return fmt.Errorf("la décision %s a déjà été exécutée sur le titre foncier %s", decision, idTitre)
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	saisie := &SaisieConservatoire{
+		IdTitre:      idTitre,
+		RefProcedure: decision,
+		PoseePar:     conservateur,
+		PoseeLe:      maintenant.Format(time.RFC3339),
+		Statut:       SaisieEnVigueur,
+		Decision:     decision,
+	}
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+	titre.Saisies = append(titre.Saisies, decision)
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return err
+	}
+	return executerDecision(ctx, fondement, ExecutionGel, idTitre, "")
+}
+
+// Lever le gel ordonné par une décision en exécution d'une décision de mainlevée, distincte et
+// désignant ce gel (réservé au conservateur)
+func (s *SmartContract) LeverGelSurDecision(ctx contractapi.TransactionContextInterface, idTitre string, decision string, mainlevee string) error {
+	titre, err := s.titreAExecuter(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	fondement, err := decisionOrdonnant(ctx, mainlevee, ExecutionMainlevee, idTitre)
+	if err != nil {
+		return err
+	}
+	if fondement.DecisionLevee != decision {
+		return nouvelleErreur(CodeRequeteInvalide, "la décision %s lève le gel %s, et non %s", mainlevee, fondement.DecisionLevee, decision)
+	}
+	saisie, err := s.LireSaisieConservatoire(ctx, idTitre, decision)
+	if err != nil {
+		return err
+	}
+	if saisie.Decision == "" {
+		return fmt.Errorf("la saisie %s du titre foncier %s relève du parquet", decision, idTitre)
+	}
+	if saisie.Statut != SaisieEnVigueur {
+		return fmt.Errorf("le gel %s du titre foncier %s a déjà été levé", decision, idTitre)
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	saisie.Statut = SaisieLevee
+	saisie.Mainlevee = mainlevee
+	saisie.LeveePar = conservateur
+	saisie.LeveeLe = maintenant.Format(time.RFC3339)
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+
+	saisies := titre.Saisies[:0]
+	for _, ref := range titre.Saisies {
+		if ref != decision {
+			saisies = append(saisies, ref)
+		}
+	}
+	titre.Saisies = saisies
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return err
+	}
+	return executerDecision(ctx, fondement, ExecutionMainlevee, idTitre, decision)
+}
+
+// Transférer la propriété d'un titre au bénéficiaire désigné par une décision de justice (réservé au
+// conservateur) ; la décision tient lieu de consentement du propriétaire et des créanciers, dont les
+// charges restent inscrites. Le titre ne doit être ni saisi ni en cours de réalisation ; un usufruit
+// en vigueur subsiste, le bénéficiaire n'acquérant que la nue-propriété. Les transferts en attente du
+// titre, proposés par l'ancien propriétaire, sont annulés.
+func (s *SmartContract) ExecuterTransfertJudiciaire(ctx contractapi.TransactionContextInterface, idTitre string, decision string, beneficiaire string) error {
+	if beneficiaire == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "le bénéficiaire du transfert est obligatoire")
+	}
+	titre, err := s.titreAExecuter(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	fondement, err := decisionOrdonnant(ctx, decision, ExecutionTransfert, idTitre)
+	if err != nil {
+		return err
+	}
+	if fondement.Beneficiaire != beneficiaire {
+		return nouvelleErreur(CodeRequeteInvalide, "la décision %s désigne %s comme bénéficiaire, et non %s", decision, fondement.Beneficiaire, beneficiaire)
+	}
+	for _, execution := range fondement.Executions {
+		if execution.Usage == ExecutionTransfert && execution.IdTitre == idTitre {
+			return fmt.Errorf("la décision %s a déjà été exécutée sur le titre foncier %s", decision, idTitre)
+		}
+	}
+	if err := verifierAlienableDemembre(titre); err != nil {
+		return err
+	}
+
+	transferts, err := s.transfertsEnCoursTitre(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	for _, transfert := range transferts {
+		if _, err := abandonnerTransfert(ctx, titre, transfert); err != nil {
+			return err
+		}
+	}
+
+	titre.Proprio = beneficiaire
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return err
+	}
+	return executerDecision(ctx, fondement, ExecutionTransfert, idTitre, beneficiaire)
+}
+
+// Clore un litige tranché par une décision de justice (réservé au conservateur)
+func (s *SmartContract) CloreLitige(ctx contractapi.TransactionContextInterface, idTitre string, idLitige string, decision string) error {
+	if _, err := s.titreAExecuter(ctx, idTitre); err != nil {
+		return err
+	}
+	litige, err := s.LireLitige(ctx, idTitre, idLitige)
+	if err != nil {
+		return err
+	}
+	if litige.Statut != LitigeOuvert {
+		return fmt.Errorf("le litige %s est déjà clos", idLitige)
+	}
+	fondement, err := decisionOrdonnant(ctx, decision, ExecutionCloture, idTitre)
+	if err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	litige.Statut = LitigeClos
+	litige.Decision = decision
+	litige.ClosLe = maintenant.Format(time.RFC3339)
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLitige, []string{idTitre, idLitige})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, litige); err != nil {
+		return err
+	}
+	if err := suivreStatut(ctx, DossierLitige, idLitige, idTitre, litige.Statut); err != nil {
+		return err
+	}
+	if err := inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteLitigeClos, Objet: idLitige}); err != nil {
+		return err
+	}
+	return executerDecision(ctx, fondement, ExecutionCloture, idTitre, idLitige)
+}
+
+// Titre sur lequel le conservateur appelant peut exécuter une décision
+func (s *SmartContract) titreAExecuter(ctx contractapi.TransactionContextInterface, idTitre string) (*TitreFoncier, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return nil, err
+	}
+	if titre.MuteVers != "" {
+		return nil, nouvelleErreur(CodeTitreMute, "le titre foncier %s est désormais administré sur le canal %s", titre.Id, titre.MuteVers)
+	}
+	return titre, nil
+}
+
+// Décision enregistrée citée sous la référence "juridiction:numero"
+func decisionCitee(ctx contractapi.TransactionContextInterface, reference string) (*DecisionJudiciaire, error) {
+	juridiction, numero, ok := strings.Cut(reference, ":")
+	if !ok || juridiction == "" || numero == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "référence de décision invalide (juridiction:numero attendu): %s", reference)
+	}
+	decision, err := lireDecision(ctx, juridiction, numero)
+	if err != nil {
+		return nil, err
+	}
+	if decision == nil {
+		return nil, nouvelleErreur(CodeDecisionInconnue, "la décision %s n'est pas enregistrée au registre", reference)
+	}
+	return decision, nil
+}
+
+// Décision citée qui ordonne l'usage sur le titre
+func decisionOrdonnant(ctx contractapi.TransactionContextInterface, reference string, usage string, idTitre string) (*DecisionJudiciaire, error) {
+	decision, err := decisionCitee(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.Vise(usage, idTitre) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la décision %s (portée %s, titres %s) n'ordonne pas %s sur le titre foncier %s", reference, decision.Portee, strings.Join(decision.Titres, ", "), usage, idTitre)
+	}
+	return decision, nil
+}
+
+// Inscrire sur la décision l'opération qu'elle fonde
+func executerDecision(ctx contractapi.TransactionContextInterface, decision *DecisionJudiciaire, usage string, idTitre string, objet string) error {
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	decision.Executions = append(decision.Executions, ExecutionDecision{
+		Usage:   usage,
+		IdTitre: idTitre,
+		Objet:   objet,
+		TxId:    ctx.GetStub().GetTxID(),
+		Date:    maintenant.Format(time.RFC3339),
+	})
+	return sauvegarderDecision(ctx, decision)
+}
+
+func lireDecision(ctx contractapi.TransactionContextInterface, juridiction string, numero string) (*DecisionJudiciaire, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeDecisionJudiciaire, []string{juridiction, numero})
+	if err != nil {
+		return nil, err
+	}
+	var decision DecisionJudiciaire
+	existe, err := lireEtat(ctx, cle, &decision)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+func sauvegarderDecision(ctx contractapi.TransactionContextInterface, decision *DecisionJudiciaire) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeDecisionJudiciaire, []string{decision.Juridiction, decision.Numero})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, decision)
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites du suivi des statuts (nature, objet)
+const (
+	PrefixeSuiviStatut  = "SUIVI_STATUT"
+	PrefixeSuiviEnCours = "SUIVI_EN_COURS" // Index des dossiers dont le statut n'est pas définitif
+)
+
+// Statuts définitifs de chaque procédure suivie : le dossier sort alors des délais
+var statutsDefinitifs = map[string][]string{
+	DossierTransfert:   {TransfertFinalise, TransfertAnnule},
+	DossierLitige:      {LitigeClos},
+	DossierSignalement: {SignalementClasse, SignalementEscalade},
+	DossierApprobation: {ApprobationRejetee, ApprobationUtilisee},
+	DossierAttestation: {AttestationExpiree},
+}
+
+// Procédures dont le job SUIVI_STATUTS ouvre le suivi, dans l'ordre de parcours, et préfixe de leurs
+// enregistrements
+var etapesSuiviStatuts = []string{DossierTransfert, DossierLitige, DossierSignalement, DossierApprobation, DossierAttestation}
+
+var prefixesDossiers = map[string]string{
+	DossierTransfert:   PrefixeTransfert,
+	DossierLitige:      PrefixeLitige,
+	DossierSignalement: PrefixeSignalementOccupation,
+	DossierApprobation: PrefixeApprobation,
+	DossierAttestation: PrefixeAttestation,
+}
+
+func statutDefinitif(nature string, statut string) bool {
+	for _, definitif := range statutsDefinitifs[nature] {
+		if definitif == statut {
+			return true
+		}
+	}
+	return false
+}
+
+// Noter l'entrée d'un dossier dans un statut : le séjour dans le statut précédent est clos
+func suivreStatut(ctx contractapi.TransactionContextInterface, nature string, objet string, idTitre string, statut string) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, []string{nature, objet})
+	if err != nil {
+		return err
+	}
+	var suivi SuiviStatut
+	existe, err := lireEtat(ctx, cle, &suivi)
+	if err != nil {
+		return err
+	}
+	if existe && suivi.Statut == statut {
+		return nil
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		suivi = SuiviStatut{Nature: nature, Objet: objet, IdTitre: idTitre, Passages: []PassageStatut{}}
+	}
+	passerStatut(&suivi, statut, maintenant)
+	return ecrireSuivi(ctx, &suivi)
+}
+
+// Clore le séjour du dossier dans son statut courant (s'il en a un) et l'ouvrir dans le statut indiqué
+func passerStatut(suivi *SuiviStatut, statut string, instant time.Time) {
+	horodatage := instant.Format(time.RFC3339)
+	if suivi.Statut != "" {
+		passage := PassageStatut{Statut: suivi.Statut, Debut: suivi.Depuis, Fin: horodatage}
+		if debut, err := time.Parse(time.RFC3339, suivi.Depuis); err == nil {
+			passage.Duree = int64(instant.Sub(debut) / time.Second)
+		}
+		suivi.Passages = append(suivi.Passages, passage)
+	}
+	suivi.Statut = statut
+	suivi.Depuis = horodatage
+	suivi.Escalade = false
+}
+
+// Écrire le suivi d'un dossier, qui figure dans l'index des dossiers en cours tant que son statut
+// n'est pas définitif
+func ecrireSuivi(ctx contractapi.TransactionContextInterface, suivi *SuiviStatut) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, []string{suivi.Nature, suivi.Objet})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, suivi); err != nil {
+		return err
+	}
+
+	index, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviEnCours, []string{suivi.Nature, suivi.Objet})
+	if err != nil {
+		return err
+	}
+	if statutDefinitif(suivi.Nature, suivi.Statut) {
+		return ctx.GetStub().DelState(index)
+	}
+	return ctx.GetStub().PutState(index, valeurIndex)
+}
+
+// Lire le suivi des statuts d'un dossier : statut courant et durée des séjours précédents
+func (s *SmartContract) LireSuiviStatut(ctx contractapi.TransactionContextInterface, nature string, objet string) (*SuiviStatut, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, []string{nature, objet})
+	if err != nil {
+		return nil, err
+	}
+	var suivi SuiviStatut
+	existe, err := lireEtat(ctx, cle, &suivi)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("aucun suivi de statut pour le dossier %s %s", nature, objet)
+	}
+	return &suivi, nil
+}
+
+// Retard d'un dossier sur le délai configuré pour son statut (nil s'il n'en a pas ou s'il est dans les temps)
+func retardSuivi(config *Configuration, suivi *SuiviStatut, maintenant time.Time) (*DossierEnRetard, error) {
+	delai := config.DelaiTraitement(suivi.Nature, suivi.Statut)
+	if delai == 0 {
+		return nil, nil
+	}
+	depuis, err := time.Parse(time.RFC3339, suivi.Depuis)
+	if err != nil {
+		return nil, fmt.Errorf("suivi illisible %s %s: %v", suivi.Nature, suivi.Objet, err)
+	}
+	ecoule := int(maintenant.Sub(depuis) / time.Hour)
+	if ecoule <= delai {
+		return nil, nil
+	}
+	return &DossierEnRetard{
+		Nature:   suivi.Nature,
+		Objet:    suivi.Objet,
+		IdTitre:  suivi.IdTitre,
+		Statut:   suivi.Statut,
+		Depuis:   suivi.Depuis,
+		Delai:    delai,
+		Retard:   ecoule - delai,
+		Escalade: suivi.Escalade,
+	}, nil
+}
+
+// Dossiers en cours ayant dépassé le délai configuré pour leur statut
+func dossiersEnRetard(ctx contractapi.TransactionContextInterface) ([]DossierEnRetard, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.DelaisTraitement) == 0 {
+		return []DossierEnRetard{}, nil
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSuiviEnCours, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	retards := []DossierEnRetard{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		suivi, err := lireSuiviEnCours(ctx, queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if suivi == nil {
+			continue
+		}
+		retard, err := retardSuivi(config, suivi, maintenant)
+		if err != nil {
+			return nil, err
+		}
+		if retard != nil {
+			retards = append(retards, *retard)
+		}
+	}
+	return retards, nil
+}
+
+// Suivi désigné par une entrée de l'index des dossiers en cours (nil s'il est absent)
+func lireSuiviEnCours(ctx contractapi.TransactionContextInterface, index string) (*SuiviStatut, error) {
+	_, attributs, err := ctx.GetStub().SplitCompositeKey(index)
+	if err != nil {
+		return nil, err
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, attributs)
+	if err != nil {
+		return nil, err
+	}
+	var suivi SuiviStatut
+	existe, err := lireEtat(ctx, cle, &suivi)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &suivi, nil
+}
+
+// Dossiers en retard sur les délais de traitement, du plus ancien au plus récent (réservé aux
+// superviseurs) ; ceux rattachés à un titre sont limités au bureau du superviseur
+func (s *SmartContract) GetDossiersEnRetard(ctx contractapi.TransactionContextInterface) ([]DossierEnRetard, error) {
+	if err := verifierRole(ctx, RoleSuperviseur); err != nil {
+		return nil, err
+	}
+	bureau, err := bureauAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retards, err := dossiersEnRetard(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bureaux := map[string]string{}
+	dossiers := []DossierEnRetard{}
+	for _, retard := range retards {
+		if retard.IdTitre != "" {
+			if _, lu := bureaux[retard.IdTitre]; !lu {
+				var titre TitreFoncier
+				if _, err := lireTitre(ctx, retard.IdTitre, &titre); err != nil {
+					return nil, err
+				}
+				bureaux[retard.IdTitre] = titre.BureauFoncier
+			}
+			if bureaux[retard.IdTitre] != bureau {
+				continue
+			}
+		}
+		dossiers = append(dossiers, retard)
+	}
+	sort.SliceStable(dossiers, func(i, j int) bool { return dossiers[i].Depuis < dossiers[j].Depuis })
+	return dossiers, nil
+}
+
+// Page du job ESCALADE_RETARDS, démarré par un ordonnanceur hors chaîne comme le balayage des
+// échéances : les dossiers en cours de la page passés en retard depuis le dernier passage sont
+// escaladés, une fois par statut, et regroupés dans l'événement de la transaction. Les attestations
+// dont la validité est écoulée sortent des délais. Le signet est la dernière entrée parcourue.
+func pageEscaladeRetards(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return false, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	cles, err := clesParPrefixeApres(ctx, PrefixeSuiviEnCours, job.Signet, taillePage)
+	if err != nil {
+		return false, err
+	}
+
+	escalades := []DossierEnRetard{}
+	for _, cle := range cles {
+		job.Signet = cle
+		job.Compteurs["dossiersParcourus"]++
+		suivi, err := lireSuiviEnCours(ctx, cle)
+		if err != nil {
+			return false, err
+		}
+		if suivi == nil {
+			continue
+		}
+		if suivi.Nature == DossierAttestation {
+			expiree, err := expirerAttestation(ctx, suivi, maintenant)
+			if err != nil {
+				return false, err
+			}
+			if expiree {
+				job.Compteurs["attestationsExpirees"]++
+				continue
+			}
+		}
+		retard, err := retardSuivi(config, suivi, maintenant)
+		if err != nil {
+			return false, err
+		}
+		if retard == nil || suivi.Escalade {
+			continue
+		}
+		suivi.Escalade = true
+		if err := ecrireSuivi(ctx, suivi); err != nil {
+			return false, err
+		}
+		retard.Escalade = true
+		escalades = append(escalades, *retard)
+		job.Compteurs["dossiersEscalades"]++
+	}
+
+	if len(escalades) > 0 {
+		evenement, err := json.Marshal(EscaladeDossiers{TxId: ctx.GetStub().GetTxID(), Dossiers: escalades})
+		if err != nil {
+			return false, err
+		}
+		if err := emettreEvenement(ctx, EvenementDossiersEnRetard, evenement); err != nil {
+			return false, err
+		}
+	}
+	return len(cles) < taillePage, nil
+}
+
+// Sortir des délais, à l'instant de son expiration, une attestation dont la validité est écoulée
+func expirerAttestation(ctx contractapi.TransactionContextInterface, suivi *SuiviStatut, maintenant time.Time) (bool, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAttestation, []string{suivi.Objet})
+	if err != nil {
+		return false, err
+	}
+	var attestation Attestation
+	existe, err := lireEtat(ctx, cle, &attestation)
+	if err != nil || !existe {
+		return false, err
+	}
+	if !echoirAttestation(suivi, &attestation, maintenant) {
+		return false, nil
+	}
+	return true, ecrireSuivi(ctx, suivi)
+}
+
+// Passer au statut EXPIREE le suivi d'une attestation émise dont la validité est écoulée
+func echoirAttestation(suivi *SuiviStatut, attestation *Attestation, maintenant time.Time) bool {
+	expireLe, err := time.Parse(time.RFC3339, attestation.ExpireLe)
+	if err != nil || suivi.Statut != AttestationEmise || !maintenant.After(expireLe) {
+		return false
+	}
+	passerStatut(suivi, AttestationExpiree, expireLe)
+	return true
+}
+
+// Page du job SUIVI_STATUTS : les dossiers enregistrés avant le suivi des délais, qui n'entreraient
+// sinon jamais dans l'index des dossiers en cours, sont suivis à partir de leur historique. Le signet
+// porte la procédure et la dernière clé traitée ; une page incomplète termine la procédure.
+func pageSuiviStatuts(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	nom, derniere, _ := strings.Cut(job.Signet, separateurSignetAudit)
+	etape := 0
+	for i, e := range etapesSuiviStatuts {
+		if e == nom {
+			etape = i
+		}
+	}
+	nature := etapesSuiviStatuts[etape]
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	cles, err := clesParPrefixeApres(ctx, prefixesDossiers[nature], derniere, taillePage)
+	if err != nil {
+		return false, err
+	}
+	for _, cle := range cles {
+		ouvert, err := reconstituerSuivi(ctx, nature, cle, maintenant)
+		if err != nil {
+			return false, err
+		}
+		job.Compteurs["dossiersParcourus"]++
+		if ouvert {
+			job.Compteurs["suivisOuverts"]++
+		}
+		derniere = cle
+	}
+
+	switch {
+	case len(cles) == taillePage:
+		job.Signet = nature + separateurSignetAudit + derniere
+		return false, nil
+	case etape+1 < len(etapesSuiviStatuts):
+		job.Signet = etapesSuiviStatuts[etape+1] + separateurSignetAudit
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// Ouvrir le suivi d'un dossier en rejouant les statuts successifs de son historique ; sans historique,
+// le séjour dans le statut courant part de la transaction. Un dossier déjà suivi est laissé inchangé.
+func reconstituerSuivi(ctx contractapi.TransactionContextInterface, nature string, cle string, maintenant time.Time) (bool, error) {
+	valeur, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return false, err
+	}
+	if valeur == nil {
+		return false, nil
+	}
+	objet, idTitre, statut, err := statutDossier(nature, valeur)
+	if err != nil {
+		return false, err
+	}
+	cleSuivi, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, []string{nature, objet})
+	if err != nil {
+		return false, err
+	}
+	existant, err := ctx.GetStub().GetState(cleSuivi)
+	if err != nil {
+		return false, err
+	}
+	if existant != nil {
+		return false, nil
+	}
+
+	suivi := &SuiviStatut{Nature: nature, Objet: objet, IdTitre: idTitre, Passages: []PassageStatut{}}
+	err = parcourirHistorique(ctx, cle, func(instant time.Time, version []byte) error {
+		_, _, statutVersion, err := statutDossier(nature, version)
+		if err != nil {
+			return err
+		}
+		if statutVersion != suivi.Statut {
+			passerStatut(suivi, statutVersion, instant)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if suivi.Statut != statut {
+		passerStatut(suivi, statut, maintenant)
+	}
+	if nature == DossierAttestation {
+		var attestation Attestation
+		if err := decoderEtat(valeur, &attestation); err != nil {
+			return false, err
+		}
+		echoirAttestation(suivi, &attestation, maintenant)
+	}
+	return true, ecrireSuivi(ctx, suivi)
+}
+
+// Objet, titre et statut d'un dossier suivi, à partir de son enregistrement
+func statutDossier(nature string, valeur []byte) (string, string, string, error) {
+	switch nature {
+	case DossierTransfert:
+		var transfert Transfert
+		err := decoderEtat(valeur, &transfert)
+		return transfert.Id, transfert.IdTitre, transfert.Statut, err
+	case DossierLitige:
+		var litige Litige
+		err := decoderEtat(valeur, &litige)
+		return litige.Id, litige.IdTitre, litige.Statut, err
+	case DossierSignalement:
+		var signalement SignalementOccupation
+		err := decoderEtat(valeur, &signalement)
+		return signalement.Id, signalement.IdTitre, signalement.Statut, err
+	case DossierApprobation:
+		var approbation Approbation
+		err := decoderEtat(valeur, &approbation)
+		return approbation.Workflow + "/" + approbation.Objet, "", approbation.Statut, err
+	default:
+		var attestation Attestation
+		err := decoderEtat(valeur, &attestation)
+		return attestation.Id, attestation.IdTitre, AttestationEmise, err
+	}
+}
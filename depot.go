@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/codec"
+)
+
+// En-tête des enregistrements MessagePack ; un document JSON ne commence jamais par un octet nul
+var enteteMsgpack = []byte{0x00, 'M', 'P'}
+
+// Lire et décoder un enregistrement d'état ; indique s'il existe
+func lireEtat(ctx contractapi.TransactionContextInterface, cle string, v interface{}) (bool, error) {
+	valeur, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture: %v", err)
+	}
+	if valeur == nil {
+		return false, nil
+	}
+	return true, decoderEtat(valeur, v)
+}
+
+// Encoder un enregistrement au format configuré pour le canal et l'écrire
+func ecrireEtat(ctx contractapi.TransactionContextInterface, cle string, v interface{}) error {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	return ecrireEtatAuFormat(ctx, cle, v, config.Serialisation)
+}
+
+// Écrire un enregistrement en JSON quel que soit le format du canal : les titres fonciers doivent
+// rester visibles des requêtes riches CouchDB (RechercherTitres, DetecterTitresDormants)
+func ecrireEtatJSON(ctx contractapi.TransactionContextInterface, cle string, v interface{}) error {
+	return ecrireEtatAuFormat(ctx, cle, v, SerialisationJSON)
+}
+
+func ecrireEtatAuFormat(ctx contractapi.TransactionContextInterface, cle string, v interface{}, format string) error {
+	inconnus, err := champsInconnus(ctx, cle, v)
+	if err != nil {
+		return err
+	}
+	if len(inconnus) > 0 {
+		if v, err = fusionnerChamps(v, inconnus); err != nil {
+			return err
+		}
+	}
+
+	var valeur []byte
+	switch format {
+	case SerialisationMsgpack:
+		contenu, err := codec.MarshalMsgpack(v)
+		if err != nil {
+			return err
+		}
+		valeur = append(append(valeur, enteteMsgpack...), contenu...)
+	default:
+		if valeur, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	return ctx.GetStub().PutState(cle, valeur)
+}
+
+// Champs de l'enregistrement en place que cette version du contrat ne connaît pas ;
+// une version plus récente a pu les écrire pendant une mise à jour progressive
+func champsInconnus(ctx contractapi.TransactionContextInterface, cle string, v interface{}) (map[string]interface{}, error) {
+	valeur, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture: %v", err)
+	}
+	var enPlace map[string]interface{}
+	if valeur == nil || decoderEtat(valeur, &enPlace) != nil {
+		// Un enregistrement qui n'est pas un objet n'a pas de champs à conserver
+		return nil, nil
+	}
+
+	connus := codec.NomsChamps(v)
+	inconnus := make(map[string]interface{})
+	for nom, valeur := range enPlace {
+		if !connus[nom] {
+			inconnus[nom] = valeur
+		}
+	}
+	return inconnus, nil
+}
+
+// Représentation générique d'un enregistrement complétée des champs inconnus
+func fusionnerChamps(v interface{}, inconnus map[string]interface{}) (map[string]interface{}, error) {
+	contenu, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var champs map[string]interface{}
+	if err := decoderJSON(contenu, &champs); err != nil {
+		return nil, err
+	}
+
+	for nom, valeur := range inconnus {
+		champs[nom] = valeur
+	}
+	return champs, nil
+}
+
+// Décoder un enregistrement quel que soit son format, les anciens enregistrements JSON restant lisibles
+func decoderEtat(valeur []byte, v interface{}) error {
+	if bytes.HasPrefix(valeur, enteteMsgpack) {
+		return codec.UnmarshalMsgpack(valeur[len(enteteMsgpack):], v)
+	}
+	return decoderJSON(valeur, v)
+}
+
+// Décoder du JSON en gardant les nombres intacts dans les valeurs génériques
+func decoderJSON(valeur []byte, v interface{}) error {
+	decodeur := json.NewDecoder(bytes.NewReader(valeur))
+	decodeur.UseNumber()
+	return decodeur.Decode(v)
+}
+
+// Lire un enregistrement d'une collection privée ; indique s'il existe
+func lirePrive(ctx contractapi.TransactionContextInterface, collection string, cle string, v interface{}) (bool, error) {
+	valeur, err := ctx.GetStub().GetPrivateData(collection, cle)
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture de la collection %s: %v", collection, err)
+	}
+	if valeur == nil {
+		return false, nil
+	}
+	return true, decoderJSON(valeur, v)
+}
+
+// Écrire un enregistrement dans une collection privée. Les collections restent en JSON,
+// pour être interrogées directement par les organisations membres.
+func ecrirePrive(ctx contractapi.TransactionContextInterface, collection string, cle string, v interface{}) error {
+	valeur, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutPrivateData(collection, cle, valeur)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Rattacher un document émis par une autorité reconnue à un titre foncier ; l'état d'un titre dont les
+// documents sont séparés n'est réécrit que si la pièce change son score de complétude
+func (s *SmartContract) AjouterDocument(ctx contractapi.TransactionContextInterface, idTitre string, chemin string, issuer string) error {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+
+	document, err := s.nouveauDocument(ctx, chemin, issuer)
+	if err != nil {
+		return err
+	}
+	ajoute, err := ajouterDocumentSepare(ctx, titre, document)
+	if err != nil || ajoute {
+		return err
+	}
+	titre.Documents = append(titre.Documents, document)
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Remplacer un document rattaché à un titre ; l'ancien reste au dossier, marqué OBSOLETE,
+// jusqu'à la fin de la durée de rétention configurée
+func (s *SmartContract) RemplacerDocument(ctx contractapi.TransactionContextInterface, idTitre string, cheminAncien string, chemin string, issuer string) error {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+
+	ancien := -1
+	for i, document := range titre.Documents {
+		if document.Chemin == cheminAncien && document.Statut != DocumentObsolete {
+			ancien = i
+			break
+		}
+	}
+	if ancien < 0 {
+		return fmt.Errorf("aucun document en vigueur %s sur le titre foncier %s", cheminAncien, idTitre)
+	}
+
+	document, err := s.nouveauDocument(ctx, chemin, issuer)
+	if err != nil {
+		return err
+	}
+	titre.Documents[ancien].Statut = DocumentObsolete
+	titre.Documents[ancien].RemplacePar = chemin
+	titre.Documents[ancien].ObsoleteLe = document.AjouteLe
+	titre.Documents = append(titre.Documents, document)
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Retirer d'un titre les documents obsolètes dont la durée de rétention est écoulée (réservé à l'État) ;
+// retourne le nombre de documents purgés
+func (s *SmartContract) PurgerDocumentsObsoletes(ctx contractapi.TransactionContextInterface, idTitre string) (int, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return 0, err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return 0, err
+	}
+
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return 0, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return 0, err
+	}
+	limite := maintenant.AddDate(0, 0, -config.RetentionDocumentsObsoletes).Format(FormatDate)
+
+	documents := titre.Documents[:0]
+	for _, document := range titre.Documents {
+		if document.Statut == DocumentObsolete && document.ObsoleteLe <= limite {
+			continue
+		}
+		documents = append(documents, document)
+	}
+	purges := len(titre.Documents) - len(documents)
+	if purges == 0 {
+		return 0, nil
+	}
+	titre.Documents = documents
+
+	return purges, sauvegarderTitre(ctx, titre)
+}
+
+// Document émis par une autorité reconnue, prêt à être rattaché. L'autorité signe le document : sa
+// signature détachée (base64) est déposée à côté du fichier, sous le même chemin suffixé de ".sig", et
+// vérifiée sur le certificat enregistré de l'autorité.
+func (s *SmartContract) nouveauDocument(ctx contractapi.TransactionContextInterface, chemin string, issuer string) (DocumentTitre, error) {
+	autorite, err := s.verifierEmetteur(ctx, issuer)
+	if err != nil {
+		return DocumentTitre{}, err
+	}
+
+	fichier, err := controlerDocument(ctx, chemin)
+	if err != nil {
+		return DocumentTitre{}, err
+	}
+	detachee, err := os.ReadFile(chemin + SuffixeSignatureDocument)
+	if err != nil {
+		return DocumentTitre{}, nouvelleErreur(CodeDocumentRefuse, "signature du document %s introuvable: %v", chemin, err)
+	}
+	signature := strings.TrimSpace(string(detachee))
+	if err := verifierSignatureAutorite(autorite, fichier.contenu, signature); err != nil {
+		return DocumentTitre{}, nouvelleErreur(CodeDocumentRefuse, "document %s: %v", chemin, err)
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return DocumentTitre{}, err
+	}
+
+	return DocumentTitre{
+		Chemin:    chemin,
+		Hash:      fichier.hash,
+		Issuer:    issuer,
+		AjouteLe:  maintenant.Format(FormatDate),
+		Taille:    fichier.taille,
+		Type:      fichier.typeMIME,
+		Signature: signature,
+	}, nil
+}
+
+// Signatures des types de documents que la détection standard ne reconnaît pas
+var signaturesDocuments = []struct {
+	prefixe  string
+	typeMIME string
+}{
+	{"II*\x00", TypeTIFF},
+	{"MM\x00*", TypeTIFF},
+}
+
+// Suffixe du fichier de la signature détachée d'un document par son autorité émettrice
+const SuffixeSignatureDocument = ".sig"
+
+// Document lu sur le partage NFS
+type fichierDocument struct {
+	hash     string // SHA-1 du contenu
+	taille   int
+	typeMIME string
+	contenu  []byte
+}
+
+// Lire un document sur le partage NFS et vérifier que sa taille et son type, détecté sur le contenu,
+// sont admis par la configuration du canal
+func controlerDocument(ctx contractapi.TransactionContextInterface, chemin string) (*fichierDocument, error) {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// La taille est contrôlée avant la lecture, pour ne pas charger un fichier démesuré
+	info, err := os.Stat(chemin)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture du document %s: %v", chemin, err)
+	}
+	if config.TailleMaxDocuments > 0 && info.Size() > int64(config.TailleMaxDocuments) {
+		return nil, nouvelleErreur(CodeDocumentRefuse, "le document %s dépasse la taille maximale admise (%d octets pour %d)", chemin, info.Size(), config.TailleMaxDocuments)
+	}
+	contenu, err := os.ReadFile(chemin)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture du document %s: %v", chemin, err)
+	}
+	typeMIME, _, _ := strings.Cut(http.DetectContentType(contenu), ";")
+	for _, signature := range signaturesDocuments {
+		if strings.HasPrefix(string(contenu), signature.prefixe) {
+			typeMIME = signature.typeMIME
+			break
+		}
+	}
+	if !config.TypeDocumentAdmis(typeMIME) {
+		return nil, nouvelleErreur(CodeDocumentRefuse, "le document %s est de type %s, non admis (%s)", chemin, typeMIME, strings.Join(config.TypesDocuments, ", "))
+	}
+
+	hash := sha1.Sum(contenu)
+	return &fichierDocument{hash: hex.EncodeToString(hash[:]), taille: len(contenu), typeMIME: typeMIME, contenu: contenu}, nil
+}
+
+// Vérifier qu'un document (hash SHA-1 de la copie présentée) appartient à un titre enregistré.
+// Transaction de consultation ouverte à tous : seuls les identifiants des titres sont renvoyés.
+func (s *SmartContract) RechercherParHashDocument(ctx contractapi.TransactionContextInterface, hash string) (*VerificationDocument, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" {
+		return nil, fmt.Errorf("le hash du document est obligatoire")
+	}
+
+	titres, err := titresIndexes(ctx, PrefixeIndexHash, []string{hash}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &VerificationDocument{Hash: hash, Titres: titres}
+	verification.Enregistre = len(verification.Titres) > 0
+
+	return verification, nil
+}
+
+// Préfixe des clés composites des signalements de documents corrompus
+const PrefixeSignalementDocument = "SIGNALEMENT_DOCUMENT"
+
+// Signaler qu'un document ne correspond plus à son hash enregistré (auditeurs uniquement)
+func (s *SmartContract) SignalerDocumentCorrompu(ctx contractapi.TransactionContextInterface, idTitre string, chemin string, hashConstate string) error {
+	if err := verifierRole(ctx, RoleAuditeur); err != nil {
+		return err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+
+	hashRegistre := ""
+	if titre.Document == chemin {
+		hashRegistre = titre.DocHash
+	}
+	for _, document := range titre.Documents {
+		if document.Chemin == chemin {
+			hashRegistre = document.Hash
+		}
+	}
+	if hashRegistre == "" {
+		return fmt.Errorf("le document %s n'est pas rattaché au titre foncier %s", chemin, idTitre)
+	}
+	hashConstate = strings.ToLower(strings.TrimSpace(hashConstate))
+	if hashConstate == hashRegistre {
+		return fmt.Errorf("le hash constaté correspond au hash enregistré du document %s", chemin)
+	}
+
+	auditeur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	signalement := &SignalementDocument{
+		Id:           ctx.GetStub().GetTxID(),
+		IdTitre:      idTitre,
+		Chemin:       chemin,
+		HashRegistre: hashRegistre,
+		HashConstate: hashConstate,
+		SignalePar:   auditeur,
+		Date:         maintenant.Format(FormatDate),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSignalementDocument, []string{idTitre, signalement.Id})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, signalement); err != nil {
+		return err
+	}
+
+	evenement, err := json.Marshal(signalement)
+	if err != nil {
+		return err
+	}
+	return emettreEvenement(ctx, EvenementDocumentCorrompu, evenement)
+}
+
+// Lister les signalements de documents corrompus d'un titre
+func (s *SmartContract) GetSignalementsDocuments(ctx contractapi.TransactionContextInterface, idTitre string) ([]*SignalementDocument, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSignalementDocument, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var signalements []*SignalementDocument
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var signalement SignalementDocument
+		err = decoderEtat(queryResponse.Value, &signalement)
+		if err != nil {
+			return nil, err
+		}
+		signalements = append(signalements, &signalement)
+	}
+
+	return signalements, nil
+}
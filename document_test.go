@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Un document n'est rattaché que signé par la clé du certificat enregistré de son émetteur
+func TestDocumentSigneParEmetteur(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	tribunal := r.autorite("TGI-DK")
+	if err := r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	ajouter := func() error {
+		return r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.AjouterDocument(ctx, "TF100", r.pdf, "TGI-DK")
+		})
+	}
+
+	verifierCode(t, "document sans signature", ajouter(), CodeDocumentRefuse)
+	r.signerDocument(nouvelleAutoriteTest(t, "TGI-DK"))
+	verifierCode(t, "document signé par une autre clé", ajouter(), CodeDocumentRefuse)
+	r.signerDocument(tribunal)
+	verifierCode(t, "document signé par l'émetteur", ajouter(), "")
+	if documents := r.lire("TF100").Documents; len(documents) != 1 || documents[0].Signature == "" {
+		t.Errorf("documents du titre: %+v", documents)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Lire le dossier d'un titre foncier ; un tiers ne le lit qu'avec le consentement du propriétaire
+func (s *SmartContract) LireDossierTitre(ctx contractapi.TransactionContextInterface, id string) (*DossierTitre, error) {
+	titre, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierConsentement(ctx, titre, PorteeDossier); err != nil {
+		return nil, err
+	}
+
+	assurances, err := s.GetAssurancesTitre(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation, err := s.derniereEvaluation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permis, err := s.GetPermisConstruire(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	certificats, err := s.GetCertificatsConformite(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+
+	dossier := &DossierTitre{Titre: titre, Assurances: assurances, Evaluation: evaluation, Permis: permis, Certificats: certificats}
+	for _, assurance := range assurances {
+		if assurance.EstValide(aujourdhui) {
+			dossier.AssuranceValide = true
+		}
+	}
+
+	return dossier, nil
+}
+
+// Immatriculer en une seule transaction un dossier complet (réservé aux conservateurs) : le propriétaire
+// s'il est nouveau, le titre, ses pièces et sa géométrie sont contrôlés ensemble avant la première
+// écriture, si bien qu'un dossier refusé ne laisse rien au registre. Refusé sur un canal qui impose la
+// double saisie des titres d'archive (SaisirTitreArchive).
+func (s *SmartContract) CreerDossierComplet(ctx contractapi.TransactionContextInterface, payload string) (*TitreFoncier, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+
+	var dossier DossierImmatriculation
+	if err := json.Unmarshal([]byte(payload), &dossier); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "dossier invalide: %v", err)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierSaisieDirecte(config); err != nil {
+		return nil, err
+	}
+	return s.immatriculerDossier(ctx, &dossier)
+}
+
+// Contrôler puis immatriculer un dossier complet, sans écriture si l'un des contrôles échoue
+func (s *SmartContract) immatriculerDossier(ctx contractapi.TransactionContextInterface, dossier *DossierImmatriculation) (*TitreFoncier, error) {
+	if err := dossier.Valider(); err != nil {
+		return nil, err
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idProprio := dossier.Proprietaire.Id
+	var qualites *QualitesProprietaire
+	var titre *TitreFoncier
+	operation := nouvelleOperation("dossier complet")
+	operation.ajouter("propriétaire "+idProprio, func() error {
+		fusions, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeFusion, []string{idProprio})
+		if err != nil {
+			return err
+		}
+		fusionne := fusions.HasNext()
+		fusions.Close()
+		if fusionne {
+			return fmt.Errorf("le propriétaire %s a été absorbé par une fusion", idProprio)
+		}
+
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeQualites, []string{idProprio})
+		if err != nil {
+			return err
+		}
+		var enregistre QualitesProprietaire
+		existe, err := lireEtat(ctx, cle, &enregistre)
+		if err != nil {
+			return err
+		}
+		// Les qualités d'un propriétaire déjà connu ne changent que par DefinirQualitesProprietaire
+		if !existe {
+			qualites = &QualitesProprietaire{IdProprio: idProprio, Qualites: dossier.Proprietaire.Qualites}
+			if qualites.Qualites == nil {
+				qualites.Qualites = []string{}
+			}
+		}
+		return nil
+	}, func() error {
+		if qualites == nil {
+			return nil
+		}
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeQualites, []string{idProprio})
+		if err != nil {
+			return err
+		}
+		return ecrireEtat(ctx, cle, qualites)
+	})
+
+	operation.ajouter("titre "+dossier.Id, func() error {
+		existant, err := ctx.GetStub().GetState(dossier.Id)
+		if err != nil {
+			return fmt.Errorf("erreur de récupération de l'état: %v", err)
+		}
+		if existant != nil {
+			return fmt.Errorf("le titre foncier %s existe déjà", dossier.Id)
+		}
+		if err := verifierCommune(config, dossier.Commune); err != nil {
+			return err
+		}
+
+		fichier, err := controlerDocument(ctx, dossier.Document)
+		if err != nil {
+			return err
+		}
+		titre = model.NouveauTitreFoncier(dossier.Id, idProprio, dossier.NumTF, dossier.Superficie, dossier.Commune, dossier.Document, fichier.hash)
+		for _, piece := range dossier.Pieces {
+			document, err := s.nouveauDocument(ctx, piece.Chemin, piece.Issuer)
+			if err != nil {
+				return err
+			}
+			titre.Documents = append(titre.Documents, document)
+		}
+		if len(dossier.Geometrie) > 0 {
+			if titre.Geometrie, titre.SystemeCoordonnees, err = model.NormaliserGeometrie(string(dossier.Geometrie), config.EmpriseTerritoire); err != nil {
+				return nouvelleErreur(CodeRequeteInvalide, "géométrie du titre foncier %s invalide: %v", dossier.Id, err)
+			}
+			if err := verifierChevauchements(ctx, titre.Geometrie); err != nil {
+				return err
+			}
+		}
+		for _, reference := range dossier.AnciennesReferences {
+			reference = reference.Normaliser()
+			if err := verifierAncienneReference(ctx, reference, dossier.Id); err != nil {
+				return err
+			}
+			titre.AnciennesReferences = append(titre.AnciennesReferences, reference)
+		}
+		return titre.Valider()
+	}, func() error {
+		if titre.NumTF == "" {
+			if titre.NumTF, err = s.prochainNumero(ctx, "TF"); err != nil {
+				return err
+			}
+		}
+		return immatriculerTitre(ctx, titre)
+	})
+
+	if err := operation.executer(ctx); err != nil {
+		return nil, err
+	}
+	return titre, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des résolutions de doublons (numTF)
+const PrefixeResolutionDoublon = "DOUBLON_NUMTF"
+
+// Rapport paginé des numéros officiels portés par plusieurs titres. Les titres antérieurs à l'index
+// des numéros n'y figurent qu'après ReconstruireIndex.
+func (s *SmartContract) DetecterDoublonsNumTF(ctx contractapi.TransactionContextInterface, limite int, signet string) (*RapportDoublons, error) {
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+
+	resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeIndexNumTF, []string{}, int32(limite), signet)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// Premier titre de la page pour chaque numéro, dans l'ordre de l'index
+	var numeros []string
+	premiers := make(map[string]string)
+	lus := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		lus++
+
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if _, vu := premiers[attributs[0]]; !vu {
+			numeros = append(numeros, attributs[0])
+			premiers[attributs[0]] = attributs[1]
+		}
+	}
+
+	rapport := &RapportDoublons{Doublons: []DoublonNumTF{}}
+	for _, numTF := range numeros {
+		// Un doublon à cheval sur deux pages n'est rapporté que sur la page où il commence
+		ids, err := titresIndexes(ctx, PrefixeIndexNumTF, []string{numTF}, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) < 2 || ids[0] != premiers[numTF] {
+			continue
+		}
+
+		doublon := DoublonNumTF{NumTF: numTF, Titres: ids}
+		if doublon.Resolution, err = lireResolutionDoublon(ctx, numTF); err != nil {
+			return nil, err
+		}
+		doublon.Resolu = doublon.Resolution != nil && resolutionCouvre(doublon.Resolution, ids)
+		rapport.Doublons = append(rapport.Doublons, doublon)
+	}
+	rapport.Nombre = len(rapport.Doublons)
+	if lus == limite {
+		rapport.Signet = metadonnees.GetBookmark()
+	}
+
+	return rapport, nil
+}
+
+// Enregistrer le titre qui prévaut parmi ceux portant un même numéro officiel (réservé aux conservateurs)
+func (s *SmartContract) MarquerDoublonResolu(ctx contractapi.TransactionContextInterface, numTF string, idRetenu string, motif string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+
+	ids, err := titresIndexes(ctx, PrefixeIndexNumTF, []string{numTF}, 0)
+	if err != nil {
+		return err
+	}
+	if len(ids) < 2 {
+		return fmt.Errorf("le numéro %s n'est porté que par %d titre(s)", numTF, len(ids))
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idRetenu)
+	if err != nil {
+		return err
+	}
+	if titre.NumTF != numTF {
+		return fmt.Errorf("le titre foncier %s ne porte pas le numéro %s", idRetenu, numTF)
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	resolution := &ResolutionDoublon{
+		NumTF:     numTF,
+		IdRetenu:  idRetenu,
+		Ecartes:   []string{},
+		Motif:     motif,
+		ResoluPar: conservateur,
+		ResoluLe:  maintenant.Format(time.RFC3339),
+	}
+	for _, id := range ids {
+		if id != idRetenu {
+			resolution.Ecartes = append(resolution.Ecartes, id)
+		}
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeResolutionDoublon, []string{numTF})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, resolution)
+}
+
+// Résolution enregistrée pour un numéro (nil si aucune)
+func lireResolutionDoublon(ctx contractapi.TransactionContextInterface, numTF string) (*ResolutionDoublon, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeResolutionDoublon, []string{numTF})
+	if err != nil {
+		return nil, err
+	}
+
+	var resolution ResolutionDoublon
+	existe, err := lireEtat(ctx, cle, &resolution)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &resolution, nil
+}
+
+// Une résolution devient caduque si un nouveau titre a reçu le même numéro depuis
+func resolutionCouvre(resolution *ResolutionDoublon, ids []string) bool {
+	couverts := append([]string{resolution.IdRetenu}, resolution.Ecartes...)
+	if len(couverts) != len(ids) {
+		return false
+	}
+	sort.Strings(couverts)
+	for i, id := range ids {
+		if couverts[i] != id {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des échantillons d'audit (annee)
+const PrefixeEchantillonAudit = "ECHANTILLON_AUDIT"
+
+// Nombre minimal d'auditeurs engagés : une graine que son seul auteur connaît d'avance lui laisserait
+// choisir l'échantillon
+const EngagementsAuditMin = 2
+
+// Engager, pour l'auditeur appelant, l'empreinte SHA-256 (hexadécimale) de sa graine pour l'échantillon
+// d'audit de l'année, tiré à pourcentage % (réservé aux auditeurs). Les engagements sont reçus jusqu'à
+// la première révélation ; un échantillon ne peut être tiré qu'une fois par année, de sorte qu'un
+// tirage défavorable ne peut être recommencé.
+func (s *SmartContract) EngagerGraineAudit(ctx contractapi.TransactionContextInterface, pourcentage int, empreinte string) (*EchantillonAudit, error) {
+	if err := verifierRole(ctx, RoleAuditeur); err != nil {
+		return nil, err
+	}
+	if pourcentage < 1 || pourcentage > 100 {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le pourcentage doit être compris entre 1 et 100")
+	}
+	if octets, err := hex.DecodeString(empreinte); err != nil || len(octets) != sha256.Size {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'empreinte de la graine doit être un SHA-256 hexadécimal")
+	}
+	auditeur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	annee := maintenant.Format("2006")
+	echantillon, err := lireEchantillon(ctx, annee)
+	if err != nil {
+		return nil, err
+	}
+	if echantillon == nil {
+		echantillon = &EchantillonAudit{
+			Annee:       annee,
+			Pourcentage: pourcentage,
+			Statut:      EchantillonEngagement,
+			Titres:      []string{},
+			TxId:        ctx.GetStub().GetTxID(),
+		}
+	}
+	if echantillon.Statut != EchantillonEngagement {
+		return nil, fmt.Errorf("les engagements de l'échantillon d'audit de %s sont clos", annee)
+	}
+	if echantillon.Pourcentage != pourcentage {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'échantillon d'audit de %s est tiré à %d %%", annee, echantillon.Pourcentage)
+	}
+	for _, engagement := range echantillon.Engagements {
+		if engagement.Auditeur == auditeur {
+			return nil, fmt.Errorf("l'auditeur a déjà engagé sa graine pour l'échantillon d'audit de %s", annee)
+		}
+	}
+
+	echantillon.Engagements = append(echantillon.Engagements, EngagementGraine{
+		Auditeur:  auditeur,
+		Empreinte: strings.ToLower(empreinte),
+		EngageLe:  maintenant.Format(time.RFC3339),
+	})
+	if err := sauvegarderEchantillon(ctx, echantillon); err != nil {
+		return nil, err
+	}
+	return echantillon, nil
+}
+
+// Révéler, pour l'auditeur appelant, la graine qu'il a engagée ; la première révélation clôt les
+// engagements, la dernière établit la graine de l'échantillon, que tire ensuite JobEchantillonAudit
+func (s *SmartContract) RevelerGraineAudit(ctx contractapi.TransactionContextInterface, annee string, graine string) (*EchantillonAudit, error) {
+	if err := verifierRole(ctx, RoleAuditeur); err != nil {
+		return nil, err
+	}
+	echantillon, err := s.LireEchantillonAudit(ctx, annee)
+	if err != nil {
+		return nil, err
+	}
+	if echantillon.Statut != EchantillonEngagement && echantillon.Statut != EchantillonRevelation {
+		return nil, fmt.Errorf("la graine de l'échantillon d'audit de %s est déjà établie", annee)
+	}
+	if len(echantillon.Engagements) < EngagementsAuditMin {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'échantillon d'audit de %s requiert les engagements d'au moins %d auditeurs", annee, EngagementsAuditMin)
+	}
+	auditeur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	i := slices.IndexFunc(echantillon.Engagements, func(e EngagementGraine) bool { return e.Auditeur == auditeur })
+	if i < 0 {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'auditeur n'a pas engagé de graine pour l'échantillon d'audit de %s", annee)
+	}
+	engagement := &echantillon.Engagements[i]
+	if engagement.Graine != "" {
+		return nil, fmt.Errorf("l'auditeur a déjà révélé sa graine pour l'échantillon d'audit de %s", annee)
+	}
+	empreinte := sha256.Sum256([]byte(graine))
+	if graine == "" || hex.EncodeToString(empreinte[:]) != engagement.Empreinte {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la graine ne correspond pas à l'empreinte engagée")
+	}
+
+	engagement.Graine = graine
+	echantillon.Statut = EchantillonRevelation
+	if echantillon.Revele() {
+		graines := make([]string, len(echantillon.Engagements))
+		for i, e := range echantillon.Engagements {
+			graines[i] = e.Graine
+		}
+		combinee := sha256.Sum256([]byte(strings.Join(graines, "|")))
+		echantillon.Graine = hex.EncodeToString(combinee[:])
+		echantillon.Statut = EchantillonTirage
+	}
+	if err := sauvegarderEchantillon(ctx, echantillon); err != nil {
+		return nil, err
+	}
+	return echantillon, nil
+}
+
+// Lire l'échantillon d'audit d'une année (AAAA)
+func (s *SmartContract) LireEchantillonAudit(ctx contractapi.TransactionContextInterface, annee string) (*EchantillonAudit, error) {
+	echantillon, err := lireEchantillon(ctx, annee)
+	if err != nil {
+		return nil, err
+	}
+	if echantillon == nil {
+		return nil, fmt.Errorf("aucun échantillon d'audit ouvert pour %s", annee)
+	}
+	return echantillon, nil
+}
+
+// Indiquer si un titre est retenu : les huit premiers octets de l'empreinte SHA-256 de la graine et de
+// son identifiant, modulo 100, sont inférieurs au pourcentage
+func retenuEchantillon(graine string, id string, pourcentage int) bool {
+	empreinte := sha256.Sum256([]byte(graine + "|" + id))
+	return binary.BigEndian.Uint64(empreinte[:8])%100 < uint64(pourcentage)
+}
+
+func validerEchantillonAudit(parametres map[string]string) error {
+	if parametres["annee"] == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "l'année de l'échantillon d'audit est requise")
+	}
+	return nil
+}
+
+// Page d'un job de tirage : les titres parcourus sont comptés dans la population et ceux que la graine
+// retient ajoutés à l'échantillon, que la dernière page clôt. Le signet est le dernier titre parcouru.
+func pageEchantillonAudit(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	echantillon, err := s.LireEchantillonAudit(ctx, job.Parametres["annee"])
+	if err != nil {
+		return false, err
+	}
+	if echantillon.Statut != EchantillonTirage {
+		return false, fmt.Errorf("l'échantillon d'audit de %s n'est pas prêt à être tiré (statut: %s)", echantillon.Annee, echantillon.Statut)
+	}
+	// Un seul job mène le tirage, qui ajoute les titres retenus à chaque page
+	if echantillon.Job == "" {
+		echantillon.Job = job.Id
+	}
+	if echantillon.Job != job.Id {
+		return false, fmt.Errorf("le tirage de l'échantillon d'audit de %s est mené par le job %s", echantillon.Annee, echantillon.Job)
+	}
+
+	debut := ""
+	if job.Signet != "" {
+		debut = job.Signet + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return false, err
+	}
+	defer resultsIterator.Close()
+
+	parcourus := 0
+	for parcourus < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, err
+		}
+		parcourus++
+		job.Signet = queryResponse.Key
+
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return false, err
+		}
+		if titre.Id == "" || titre.Id != queryResponse.Key {
+			continue
+		}
+		echantillon.Population++
+		if retenuEchantillon(echantillon.Graine, titre.Id, echantillon.Pourcentage) {
+			echantillon.Titres = append(echantillon.Titres, titre.Id)
+		}
+	}
+	job.Compteurs["titresParcourus"] = echantillon.Population
+	job.Compteurs["titresRetenus"] = len(echantillon.Titres)
+
+	termine := parcourus < taillePage
+	if termine {
+		echantillon.Statut = EchantillonTire
+		echantillon.TirePar = job.DemarrePar
+		maintenant, err := dateTransaction(ctx)
+		if err != nil {
+			return false, err
+		}
+		echantillon.TireLe = maintenant.Format(time.RFC3339)
+	}
+	if err := sauvegarderEchantillon(ctx, echantillon); err != nil {
+		return false, err
+	}
+	return termine, nil
+}
+
+func lireEchantillon(ctx contractapi.TransactionContextInterface, annee string) (*EchantillonAudit, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEchantillonAudit, []string{annee})
+	if err != nil {
+		return nil, err
+	}
+	var echantillon EchantillonAudit
+	existe, err := lireEtat(ctx, cle, &echantillon)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &echantillon, nil
+}
+
+func sauvegarderEchantillon(ctx contractapi.TransactionContextInterface, echantillon *EchantillonAudit) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEchantillonAudit, []string{echantillon.Annee})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, echantillon)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des réserves d'emprise publique (id)
+const PrefixeEmprise = "EMPRISE"
+
+// Préfixe de l'index spatial des réserves d'emprise actives, par tuile geohash de leur corridor (un
+// caractère par attribut, l'attribut vide marquant la fin de la tuile, puis l'id de la réserve)
+const PrefixeIndexEmprise = "INDEX_EMPRISE"
+
+// Réserver une emprise pour un projet public pendant une durée en jours (réservé à l'État). Les parcelles
+// dont le contour chevauche le corridor sont relevées et annoncées par l'événement EmpriseReservee ;
+// leurs transferts sont ensuite signalés, ou bloqués selon la configuration, jusqu'à la levée.
+func (s *SmartContract) ReserverEmprise(ctx contractapi.TransactionContextInterface, geometrie string, projet string, duree int) (*ReserveEmprise, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return nil, err
+	}
+	if projet == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le projet est requis")
+	}
+	if duree <= 0 {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la durée de la réserve doit être positive")
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	geometrie, _, err = model.NormaliserGeometrie(geometrie, config.EmpriseTerritoire)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	corridor, err := model.ContoursGeoJSON(geometrie)
+	if err != nil {
+		return nil, err
+	}
+
+	titres, err := titresChevauchant(ctx, corridor)
+	if err != nil {
+		return nil, err
+	}
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reserve := &ReserveEmprise{
+		Id:          ctx.GetStub().GetTxID(),
+		Projet:      projet,
+		Geometrie:   geometrie,
+		Titres:      titres,
+		Statut:      EmpriseActive,
+		ReserveePar: agent,
+		ReserveeLe:  maintenant.Format(FormatDate),
+		ExpireLe:    maintenant.AddDate(0, 0, duree-1).Format(FormatDate),
+	}
+	if err := sauvegarderEmprise(ctx, reserve); err != nil {
+		return nil, err
+	}
+	evenement, err := json.Marshal(reserve)
+	if err != nil {
+		return nil, err
+	}
+	return reserve, emettreEvenement(ctx, EvenementEmpriseReservee, evenement)
+}
+
+// Lever une réserve d'emprise avant son terme, projet abandonné ou expropriations réalisées (réservé à l'État)
+func (s *SmartContract) LeverEmprise(ctx contractapi.TransactionContextInterface, id string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+	reserve, err := s.LireReserveEmprise(ctx, id)
+	if err != nil {
+		return err
+	}
+	if reserve.Statut != EmpriseActive {
+		return fmt.Errorf("la réserve d'emprise %s est déjà levée", id)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	reserve.Statut = EmpriseLevee
+	reserve.LeveeLe = maintenant.Format(FormatDate)
+	return sauvegarderEmprise(ctx, reserve)
+}
+
+// Lire une réserve d'emprise
+func (s *SmartContract) LireReserveEmprise(ctx contractapi.TransactionContextInterface, id string) (*ReserveEmprise, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEmprise, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	var reserve ReserveEmprise
+	existe, err := lireEtat(ctx, cle, &reserve)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("réserve d'emprise %s non trouvée", id)
+	}
+	return &reserve, nil
+}
+
+// Indexer les réserves d'emprise actives enregistrées avant leur index spatial (réservé à l'État) ;
+// retourne le nombre de réserves indexées
+func (s *SmartContract) IndexerEmprises(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return 0, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeEmprise, []string{})
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	indexees := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		var reserve ReserveEmprise
+		if err := decoderEtat(queryResponse.Value, &reserve); err != nil {
+			return 0, err
+		}
+		if reserve.Statut != EmpriseActive {
+			continue
+		}
+		if err := indexerEmprise(ctx, &reserve); err != nil {
+			return 0, err
+		}
+		indexees++
+	}
+	return indexees, nil
+}
+
+// Réserves d'emprise en vigueur dont le corridor chevauche un titre, y compris immatriculé depuis
+func (s *SmartContract) GetEmprisesTitre(ctx contractapi.TransactionContextInterface, idTitre string) ([]*ReserveEmprise, error) {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	return emprisesTitre(ctx, titre)
+}
+
+func emprisesTitre(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) ([]*ReserveEmprise, error) {
+	reserves := []*ReserveEmprise{}
+	if titre.Geometrie == "" {
+		return reserves, nil
+	}
+	parcelle, err := model.ContoursGeoJSON(titre.Geometrie)
+	if err != nil {
+		return nil, fmt.Errorf("géométrie du titre foncier %s illisible: %v", titre.Id, err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+
+	candidats, err := candidatsGeohash(ctx, PrefixeIndexEmprise, parcelle)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range candidats {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEmprise, []string{id})
+		if err != nil {
+			return nil, err
+		}
+		var reserve ReserveEmprise
+		existe, err := lireEtat(ctx, cle, &reserve)
+		if err != nil {
+			return nil, err
+		}
+		if !existe || !reserve.EnVigueur(aujourdhui) {
+			continue
+		}
+		corridor, err := model.ContoursGeoJSON(reserve.Geometrie)
+		if err != nil {
+			return nil, fmt.Errorf("géométrie de la réserve d'emprise %s illisible: %v", reserve.Id, err)
+		}
+		if model.Chevauchent(parcelle, corridor) {
+			reserves = append(reserves, &reserve)
+		}
+	}
+	return reserves, nil
+}
+
+// Contrôler les réserves d'emprise d'un titre avant un acte : rejet si la configuration bloque ces
+// parcelles, sinon identifiants des réserves à signaler
+func verifierEmprises(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) ([]string, error) {
+	reserves, err := emprisesTitre(ctx, titre)
+	if err != nil || len(reserves) == 0 {
+		return nil, err
+	}
+	ids := make([]string, 0, len(reserves))
+	for _, reserve := range reserves {
+		ids = append(ids, reserve.Id)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.BlocageEmprises {
+		return nil, nouvelleErreur(CodeTitreSousEmprise, "le titre foncier %s est compris dans l'emprise réservée du projet %s (%s)", titre.Id, reserves[0].Projet, reserves[0].Id)
+	}
+	journalTx(ctx).Warn("titre sous réserve d'emprise", slog.String("idTitre", titre.Id), slog.Any("emprises", ids))
+	return ids, nil
+}
+
+// Enregistrer une réserve d'emprise ; seules les réserves actives figurent dans l'index spatial
+func sauvegarderEmprise(ctx contractapi.TransactionContextInterface, reserve *ReserveEmprise) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEmprise, []string{reserve.Id})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, reserve); err != nil {
+		return err
+	}
+	if reserve.Statut == EmpriseActive {
+		return indexerEmprise(ctx, reserve)
+	}
+	cles, err := clesIndexEmprise(ctx, reserve)
+	if err != nil {
+		return err
+	}
+	for _, cle := range cles {
+		if err := ctx.GetStub().DelState(cle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexerEmprise(ctx contractapi.TransactionContextInterface, reserve *ReserveEmprise) error {
+	cles, err := clesIndexEmprise(ctx, reserve)
+	if err != nil {
+		return err
+	}
+	for _, cle := range cles {
+		if err := ctx.GetStub().PutState(cle, valeurIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clés de l'index spatial d'une réserve, dans l'ordre de ses tuiles
+func clesIndexEmprise(ctx contractapi.TransactionContextInterface, reserve *ReserveEmprise) ([]string, error) {
+	corridor, err := model.ContoursGeoJSON(reserve.Geometrie)
+	if err != nil {
+		return nil, fmt.Errorf("géométrie de la réserve d'emprise %s illisible: %v", reserve.Id, err)
+	}
+	var cles []string
+	for _, tuile := range model.TuilesGeohash(corridor) {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexEmprise, append(strings.Split(tuile, ""), "", reserve.Id))
+		if err != nil {
+			return nil, err
+		}
+		cles = append(cles, cle)
+	}
+	return cles, nil
+}
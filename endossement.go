@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Exiger, au niveau de la clé du titre, l'endossement des organisations configurées
+// lorsque la parcelle dépasse un seuil de superficie ou de valeur (valeur 0 : non évaluée).
+// Le renforcement n'est jamais levé automatiquement.
+func renforcerEndossement(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, valeur int) error {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.EndossementRenforce(titre.Superficie, valeur) {
+		return nil
+	}
+
+	politique, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	if err := politique.AddOrgs(statebased.RoleTypeMember, config.OrganisationsEndossement...); err != nil {
+		return fmt.Errorf("politique d'endossement invalide: %v", err)
+	}
+	parametre, err := politique.Policy()
+	if err != nil {
+		return err
+	}
+
+	enPlace, err := ctx.GetStub().GetStateValidationParameter(titre.Id)
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de la politique d'endossement: %v", err)
+	}
+	if bytes.Equal(enPlace, parametre) {
+		return nil
+	}
+
+	journalTx(ctx).Info("endossement renforcé", slog.String("idTitre", titre.Id), slog.Any("organisations", config.OrganisationsEndossement))
+	return ctx.GetStub().SetStateValidationParameter(titre.Id, parametre)
+}
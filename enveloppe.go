@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+
+	"titrefoncier/pkg/model"
+)
+
+// Fonctions système du contrat (métadonnées), jamais enveloppées
+const prefixeFonctionsSysteme = "org.hyperledger.fabric:"
+
+// Chaincode dont les réponses sont caviardées et enveloppées lorsque le canal l'a configuré
+type chaincodeEnveloppe struct {
+	cc shim.Chaincode
+}
+
+func envelopper(cc shim.Chaincode) shim.Chaincode {
+	return &chaincodeEnveloppe{cc: cc}
+}
+
+func (c *chaincodeEnveloppe) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return c.cc.Init(stub)
+}
+
+func (c *chaincodeEnveloppe) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	// La configuration, lue par la transaction puis pour son enveloppe, ne l'est qu'une fois
+	configurationsTransaction.Store(stub, &memoConfiguration{})
+	defer configurationsTransaction.Delete(stub)
+
+	reponse := c.cc.Invoke(stub)
+
+	fonction, _ := stub.GetFunctionAndParameters()
+	if strings.HasPrefix(fonction, prefixeFonctionsSysteme) {
+		return reponse
+	}
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		// Sans ses règles de caviardage, une consultation réussie n'est pas rendue
+		if reponse.Status < shim.ERRORTHRESHOLD && consultation(operationCourante(ctx)) {
+			return shim.Error(fmt.Sprintf("caviardage de la réponse impossible: %v", err))
+		}
+		return reponse
+	}
+	if reponse = caviarderReponse(stub, config, operationCourante(ctx), reponse); !config.EnveloppeReponses {
+		return reponse
+	}
+
+	enveloppe := Enveloppe{Code: CodeSucces, Data: json.RawMessage("null"), TxId: stub.GetTxID()}
+	if ts, err := stub.GetTxTimestamp(); err == nil {
+		enveloppe.Horodatage = ts.AsTime().UTC().Format(time.RFC3339)
+	}
+
+	if reponse.Status >= shim.ERRORTHRESHOLD {
+		enveloppe.Code, enveloppe.Message = CodeErreurInterne, reponse.Message
+		if correspondance := motifCodeErreur.FindStringSubmatch(reponse.Message); correspondance != nil {
+			enveloppe.Code, enveloppe.Message = correspondance[1], strings.TrimPrefix(reponse.Message, correspondance[0])
+		}
+		enveloppe.Classe = model.ClasseErreur(enveloppe.Code, enveloppe.Message)
+		contenu, err := json.Marshal(enveloppe)
+		if err != nil {
+			return reponse
+		}
+		// Le statut d'échec est conservé : la transaction ne doit pas être validée
+		return shim.Error(string(contenu))
+	}
+
+	// Les résultats textuels (identifiants, "pong") ne sont pas du JSON : ils sont encodés en chaîne
+	if len(reponse.Payload) > 0 {
+		enveloppe.Data = reponse.Payload
+		if !json.Valid(reponse.Payload) {
+			if enveloppe.Data, err = json.Marshal(string(reponse.Payload)); err != nil {
+				return reponse
+			}
+		}
+	}
+	contenu, err := json.Marshal(enveloppe)
+	if err != nil {
+		return reponse
+	}
+	return shim.Success(contenu)
+}
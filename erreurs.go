@@ -0,0 +1,8 @@
+package main
+
+import "fmt"
+
+// Construire une erreur métier avec son code
+func nouvelleErreur(code string, format string, args ...interface{}) error {
+	return &ErreurMetier{Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des évaluations de titres
+const PrefixeEvaluation = "EVALUATION"
+
+// Enregistrer une évaluation (évaluateurs agréés uniquement)
+func (s *SmartContract) EnregistrerEvaluation(ctx contractapi.TransactionContextInterface, idTitre string, valeur int, source string, date string) error {
+	if err := verifierRole(ctx, RoleEvaluateur); err != nil {
+		return err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	evaluateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	evaluation := Evaluation{
+		IdTitre:    idTitre,
+		Valeur:     valeur,
+		Source:     source,
+		Date:       date,
+		Evaluateur: evaluateur,
+		TxId:       txID,
+	}
+	if err := evaluation.Valider(); err != nil {
+		return err
+	}
+
+	// La date en tête de clé garde l'historique trié chronologiquement
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEvaluation, []string{idTitre, date, txID})
+	if err != nil {
+		return err
+	}
+
+	if err := ecrireEtat(ctx, cle, evaluation); err != nil {
+		return err
+	}
+	return renforcerEndossement(ctx, titre, valeur)
+}
+
+// Historique des évaluations d'un titre, de la plus ancienne à la plus récente
+func (s *SmartContract) GetHistoriqueEvaluations(ctx contractapi.TransactionContextInterface, idTitre string) ([]*Evaluation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeEvaluation, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var evaluations []*Evaluation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var evaluation Evaluation
+		err = decoderEtat(queryResponse.Value, &evaluation)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = append(evaluations, &evaluation)
+	}
+
+	return evaluations, nil
+}
+
+// Dernière évaluation enregistrée pour un titre (nil si aucune)
+func (s *SmartContract) derniereEvaluation(ctx contractapi.TransactionContextInterface, idTitre string) (*Evaluation, error) {
+	evaluations, err := s.GetHistoriqueEvaluations(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if len(evaluations) == 0 {
+		return nil, nil
+	}
+	return evaluations[len(evaluations)-1], nil
+}
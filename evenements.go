@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Contexte de transaction du contrat. Fabric ne conserve qu'un événement par transaction :
+// les événements sont retenus ici puis émis une seule fois, après la fonction appelée.
+type ContexteTransaction struct {
+	contractapi.TransactionContext
+	evenement      *evenementRetenu
+	declenchements []Declenchement
+	alertesBanques []AlerteBanque
+	cles           []string // Clés écrites, dans l'ordre des modifications relevées
+	modifications  []ModificationCle
+	allocations    map[string]*AllocationTitre // Allocations de titres écrites par la transaction (nil : retirée)
+	journal        map[string][]string         // Titres journalisés par la transaction, par commune
+}
+
+// Relever les écritures de la transaction pour son résumé
+func (c *ContexteTransaction) SetStub(stub shim.ChaincodeStubInterface) {
+	c.TransactionContext.SetStub(&stubTrace{ChaincodeStubInterface: stub, contexte: c})
+}
+
+type evenementRetenu struct {
+	nom     string
+	contenu []byte
+}
+
+// Contrat configuré avec son contexte de transaction
+func nouveauContrat() *SmartContract {
+	contrat := &SmartContract{}
+	contrat.Info = infoContrat()
+	contrat.TransactionContextHandler = new(ContexteTransaction)
+	contrat.BeforeTransaction = avantTransaction
+	contrat.AfterTransaction = emettreEvenementsRetenus
+	return contrat
+}
+
+// Contrôles communs à toutes les transactions, avant la fonction appelée
+func avantTransaction(ctx *ContexteTransaction) error {
+	if err := verifierIdentiteAnonyme(ctx); err != nil {
+		return err
+	}
+	if err := comptabiliserAppel(ctx); err != nil {
+		return err
+	}
+	if err := verifierConsultationTierce(ctx); err != nil {
+		return err
+	}
+	return verifierNonce(ctx)
+}
+
+// Émettre l'événement métier de la transaction ; il prime sur les alertes d'abonnement
+func emettreEvenement(ctx contractapi.TransactionContextInterface, nom string, contenu []byte) error {
+	if contexte, ok := ctx.(*ContexteTransaction); ok {
+		contexte.evenement = &evenementRetenu{nom: nom, contenu: contenu}
+		return nil
+	}
+	return ctx.GetStub().SetEvent(nom, contenu)
+}
+
+// Retenir les abonnements déclenchés par la transaction
+func signalerDeclenchements(ctx contractapi.TransactionContextInterface, declenchements []Declenchement) error {
+	if len(declenchements) == 0 {
+		return nil
+	}
+	if contexte, ok := ctx.(*ContexteTransaction); ok {
+		contexte.declenchements = fusionnerDeclenchements(contexte.declenchements, declenchements)
+		return nil
+	}
+	return emettreAlerte(ctx, declenchements)
+}
+
+// Un abonnement n'est déclenché qu'une fois par titre et par transaction, avec le statut de transfert éventuel
+func fusionnerDeclenchements(existants []Declenchement, nouveaux []Declenchement) []Declenchement {
+	for _, nouveau := range nouveaux {
+		doublon := false
+		for i := range existants {
+			if existants[i].IdAbonnement == nouveau.IdAbonnement && existants[i].IdTitre == nouveau.IdTitre {
+				if nouveau.Statut != "" {
+					existants[i].Statut = nouveau.Statut
+				}
+				doublon = true
+				break
+			}
+		}
+		if !doublon {
+			existants = append(existants, nouveau)
+		}
+	}
+	return existants
+}
+
+// Émettre l'événement retenu, une fois la transaction exécutée avec succès. Sans événement métier
+// ni alerte, la transaction émet son résumé ; sinon le résumé est joint au contenu de l'événement émis,
+// comme les alertes qu'il supplante (ChampEvenementsJoints).
+func emettreEvenementsRetenus(ctx *ContexteTransaction) error {
+	if err := conserverAlertesBanques(ctx, ctx.alertesBanques); err != nil {
+		return err
+	}
+
+	var resume *ResumeTransaction
+	if len(ctx.modifications) > 0 {
+		resume = &ResumeTransaction{TxId: ctx.GetStub().GetTxID(), Operation: operationCourante(ctx), Modifications: ctx.modifications}
+	}
+
+	// Par priorité : l'événement métier, les alertes de surveillance conservées au registre, puis
+	// celles des abonnements
+	var evenements []EvenementJoint
+	if ctx.evenement != nil {
+		evenements = append(evenements, EvenementJoint{Nom: ctx.evenement.nom, Contenu: ctx.evenement.contenu})
+	}
+	if len(ctx.alertesBanques) > 0 {
+		nom, contenu, err := evenementAlertesBanques(ctx, ctx.alertesBanques)
+		if err != nil {
+			return err
+		}
+		evenements = append(evenements, EvenementJoint{Nom: nom, Contenu: contenu})
+	}
+	if len(ctx.declenchements) > 0 {
+		nom, contenu, err := evenementAlerte(ctx, ctx.declenchements)
+		if err != nil {
+			return err
+		}
+		evenements = append(evenements, EvenementJoint{Nom: nom, Contenu: contenu})
+	}
+
+	if len(evenements) == 0 {
+		if resume == nil {
+			return nil
+		}
+		contenu, err := json.Marshal(resume)
+		if err != nil {
+			return err
+		}
+		return ctx.GetStub().SetEvent(EvenementResumeTransaction, contenu)
+	}
+
+	nom, contenu := evenements[0].Nom, []byte(evenements[0].Contenu)
+	var err error
+	if len(evenements) > 1 {
+		if contenu, err = joindreChamp(contenu, ChampEvenementsJoints, evenements[1:]); err != nil {
+			return err
+		}
+	}
+	if resume != nil {
+		if contenu, err = joindreChamp(contenu, ChampResumeTransaction, resume); err != nil {
+			return err
+		}
+	}
+	return ctx.GetStub().SetEvent(nom, contenu)
+}
+
+// Joindre une valeur (résumé de la transaction, événements supplantés) sous un champ du contenu JSON
+// d'un événement
+func joindreChamp(contenu []byte, champ string, valeur interface{}) ([]byte, error) {
+	var champs map[string]json.RawMessage
+	if err := json.Unmarshal(contenu, &champs); err != nil || champs == nil {
+		return nil, fmt.Errorf("contenu d'événement invalide, objet JSON attendu: %v", err)
+	}
+	encodee, err := json.Marshal(valeur)
+	if err != nil {
+		return nil, err
+	}
+	champs[champ] = encodee
+	return json.Marshal(champs)
+}
+
+// Émettre une alerte nommée d'après les abonnements déclenchés, pour un routage sans décodage tant
+// qu'ils sont au plus AbonnementsNommesMax
+func emettreAlerte(ctx contractapi.TransactionContextInterface, declenchements []Declenchement) error {
+	if len(declenchements) == 0 {
+		return nil
+	}
+	nom, contenu, err := evenementAlerte(ctx, declenchements)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(nom, contenu)
+}
+
+func evenementAlerte(ctx contractapi.TransactionContextInterface, declenchements []Declenchement) (string, []byte, error) {
+	uniques := make(map[string]bool)
+	for _, d := range declenchements {
+		uniques[d.IdAbonnement] = true
+	}
+	ids := clesTriees(uniques)
+	nom := EvenementAlerteAbonnement
+	if len(ids) <= AbonnementsNommesMax {
+		nom += ":" + strings.Join(ids, ",")
+	}
+
+	contenu, err := json.Marshal(AlerteAbonnement{TxId: ctx.GetStub().GetTxID(), Declenchements: declenchements})
+	return nom, contenu, err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// L'unique événement d'une transaction porte les alertes qu'il supplante
+func TestEvenementsJoints(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		contexte := &ContexteTransaction{}
+		contexte.SetStub(ctx.GetStub())
+		contexte.SetClientIdentity(etat)
+		if err := emettreEvenement(contexte, EvenementAttestationEmise, []byte(`{"idTitre":"TF002"}`)); err != nil {
+			return err
+		}
+		contexte.alertesBanques = []AlerteBanque{{Banque: "BanqueAtlantiqueMSP", IdTitre: "TF002"}}
+		if err := signalerDeclenchements(contexte, []Declenchement{{IdAbonnement: "tx1", IdTitre: "TF002"}}); err != nil {
+			return err
+		}
+		return emettreEvenementsRetenus(contexte)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var emis []string
+	for len(r.stub.ChaincodeEventsChannel) > 0 {
+		evenement := <-r.stub.ChaincodeEventsChannel
+		for _, joint := range model.SeparerEvenements(evenement.EventName, evenement.Payload) {
+			emis = append(emis, joint.Nom)
+			var contenu map[string]json.RawMessage
+			if err := json.Unmarshal(joint.Contenu, &contenu); err != nil || contenu[ChampEvenementsJoints] != nil {
+				t.Errorf("contenu de %s: %s (%v)", joint.Nom, joint.Contenu, err)
+			}
+		}
+	}
+	attendus := []string{EvenementAttestationEmise, EvenementAlerteBanque + ":BanqueAtlantiqueMSP", EvenementAlerteAbonnement + ":tx1"}
+	if len(emis) != len(attendus) {
+		t.Fatalf("événements %v, attendus %v", emis, attendus)
+	}
+	for i := range attendus {
+		if emis[i] != attendus[i] {
+			t.Errorf("événement %d: %s, attendu %s", i, emis[i], attendus[i])
+		}
+	}
+}
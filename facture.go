@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des factures (id), des quittances du Trésor déjà rapprochées (refTresor)
+// et de l'index des factures par jour d'écriture (date, id)
+const (
+	PrefixeFacture         = "FACTURE"
+	PrefixeQuittanceTresor = "QUITTANCE_TRESOR"
+	PrefixeFactureParDate  = "FACTURE_PAR_DATE"
+)
+
+// Identifiant de la facture d'une procédure : une seule par objet
+func idFacture(procedure string, objet string) string {
+	return procedure + "-" + objet
+}
+
+// Émettre la facture des droits et frais d'une procédure si la configuration en prévoit
+func emettreFacture(ctx contractapi.TransactionContextInterface, procedure string, objet string, idTitre string, assiette int) error {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	lignes := config.LignesFacture(procedure, assiette)
+	if len(lignes) == 0 {
+		return nil
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	facture := &Facture{
+		Id:        idFacture(procedure, objet),
+		Procedure: procedure,
+		Objet:     objet,
+		IdTitre:   idTitre,
+		Assiette:  assiette,
+		Lignes:    lignes,
+		Statut:    FactureEmise,
+		EmiseLe:   maintenant.Format(time.RFC3339),
+	}
+	for _, ligne := range lignes {
+		facture.Montant += ligne.Montant
+	}
+	if err := sauvegarderFacture(ctx, facture); err != nil {
+		return err
+	}
+	if err := indexerFactureLe(ctx, facture.Id, facture.EmiseLe); err != nil {
+		return err
+	}
+	return inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteFraisFactures, Objet: facture.Id, Montant: facture.Montant})
+}
+
+// Enregistrer le paiement d'une facture sur présentation de la quittance du Trésor (réservé au
+// Trésor) ; une quittance ne règle qu'une facture, dont elle doit porter le montant exact
+func (s *SmartContract) EnregistrerPaiement(ctx contractapi.TransactionContextInterface, factureId string, refTresor string, montant int) (*Facture, error) {
+	if err := verifierMSP(ctx, MSPTresor); err != nil {
+		return nil, err
+	}
+	if refTresor == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la référence de la quittance du Trésor est obligatoire")
+	}
+	facture, err := s.LireFacture(ctx, factureId)
+	if err != nil {
+		return nil, err
+	}
+	if facture.Statut != FactureEmise {
+		return nil, fmt.Errorf("la facture %s est déjà réglée (quittance %s)", factureId, facture.RefTresor)
+	}
+	if montant != facture.Montant {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la quittance %s porte %d FCFA, la facture %s en appelle %d", refTresor, montant, factureId, facture.Montant)
+	}
+	cleQuittance, err := ctx.GetStub().CreateCompositeKey(PrefixeQuittanceTresor, []string{refTresor})
+	if err != nil {
+		return nil, err
+	}
+	var rapprochee string
+	existe, err := lireEtat(ctx, cleQuittance, &rapprochee)
+	if err != nil {
+		return nil, err
+	}
+	if existe {
+		return nil, fmt.Errorf("la quittance %s a déjà réglé la facture %s", refTresor, rapprochee)
+	}
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facture.Statut = FacturePayee
+	facture.RefTresor = refTresor
+	facture.PayeeLe = maintenant.Format(time.RFC3339)
+	facture.EncaisseePar = agent
+	if err := sauvegarderFacture(ctx, facture); err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cleQuittance, facture.Id); err != nil {
+		return nil, err
+	}
+	if err := indexerFactureLe(ctx, facture.Id, facture.PayeeLe); err != nil {
+		return nil, err
+	}
+	if err := inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteFraisEncaisses, Objet: facture.Id, Montant: facture.Montant}); err != nil {
+		return nil, err
+	}
+	return facture, nil
+}
+
+// Lire une facture
+func (s *SmartContract) LireFacture(ctx contractapi.TransactionContextInterface, factureId string) (*Facture, error) {
+	facture, err := lireFacture(ctx, factureId)
+	if err != nil {
+		return nil, err
+	}
+	if facture == nil {
+		return nil, fmt.Errorf("facture %s non trouvée", factureId)
+	}
+	return facture, nil
+}
+
+// Vérifier que la facture d'une procédure, s'il en a été émis une, est réglée
+func verifierFactureReglee(ctx contractapi.TransactionContextInterface, procedure string, objet string) error {
+	facture, err := lireFacture(ctx, idFacture(procedure, objet))
+	if err != nil || facture == nil {
+		return err
+	}
+	if facture.Statut != FacturePayee {
+		return nouvelleErreur(CodeFactureImpayee, "la facture %s de %d FCFA n'est pas réglée", facture.Id, facture.Montant)
+	}
+	return nil
+}
+
+// Facture enregistrée (nil si aucune)
+func lireFacture(ctx contractapi.TransactionContextInterface, factureId string) (*Facture, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFacture, []string{factureId})
+	if err != nil {
+		return nil, err
+	}
+
+	var facture Facture
+	existe, err := lireEtat(ctx, cle, &facture)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &facture, nil
+}
+
+func sauvegarderFacture(ctx contractapi.TransactionContextInterface, facture *Facture) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFacture, []string{facture.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, facture)
+}
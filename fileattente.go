@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des files d'attente des agents (rôle, genre, affectation, nature, objet…).
+// L'affectation désigne, selon son genre, l'agent (notaire instrumentaire, agent affecté au dossier) ou
+// le bureau chargé de l'élément ; le genre ROLE, d'affectation vide, le confie à tout agent du rôle.
+// Une entrée n'existe que tant que l'élément attend son traitement.
+const PrefixeFileAttente = "FILE_ATTENTE"
+
+// Genres d'affectation d'une entrée de file : un identifiant d'agent ne peut désigner la file d'un
+// bureau qui porterait le même nom
+const (
+	AffectationAgent  = "AGENT"
+	AffectationBureau = "BUREAU"
+	AffectationRole   = "ROLE"
+)
+
+// Entrées de file d'attente d'un transfert : le conservateur responsable (agent affecté ou, à défaut,
+// bureau du titre) finalise la vente comptant, le notaire instrumentaire suit la vente sous toutes ses
+// formes
+func entreesFileTransfert(ctx contractapi.TransactionContextInterface, transfert *Transfert, genre string, responsable string) ([]string, error) {
+	var attributs [][]string
+	if transfert.Mode == ModeComptant {
+		attributs = append(attributs, []string{RoleConservateur, genre, responsable, AttenteTransfert, transfert.Id})
+	}
+	if transfert.Notaire != "" {
+		attributs = append(attributs, []string{RoleNotaire, AffectationAgent, transfert.Notaire, AttenteTransfert, transfert.Id})
+	}
+	return clesFileAttente(ctx, attributs)
+}
+
+// Entrées de file d'attente d'une demande d'approbation : une par rôle habilité à se prononcer
+func entreesFileApprobation(ctx contractapi.TransactionContextInterface, approbation *Approbation) ([]string, error) {
+	attributs := make([][]string, 0, len(approbation.Roles))
+	for _, role := range approbation.Roles {
+		attributs = append(attributs, []string{role, AffectationRole, "", AttenteApprobation, approbation.Workflow, approbation.Objet})
+	}
+	return clesFileAttente(ctx, attributs)
+}
+
+func clesFileAttente(ctx contractapi.TransactionContextInterface, attributs [][]string) ([]string, error) {
+	cles := make([]string, 0, len(attributs))
+	for _, a := range attributs {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFileAttente, a)
+		if err != nil {
+			return nil, err
+		}
+		cles = append(cles, cle)
+	}
+	return cles, nil
+}
+
+// Inscrire les entrées d'un élément en attente, ou les retirer une fois l'élément traité
+func mettreAJourFileAttente(ctx contractapi.TransactionContextInterface, cles []string, enAttente bool) error {
+	for _, cle := range cles {
+		var err error
+		if enAttente {
+			err = ctx.GetStub().PutState(cle, valeurIndex)
+		} else {
+			err = ctx.GetStub().DelState(cle)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Éléments d'une nature inscrits dans la file d'un rôle et d'une affectation : attributs suivant la nature
+func fileAttente(ctx contractapi.TransactionContextInterface, role string, genre string, affectation string, nature string) ([][]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeFileAttente, []string{role, genre, affectation, nature})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var elements [][]string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, attributs[4:])
+	}
+	return elements, nil
+}
+
+// Transferts en attente d'une file
+func transfertsEnAttente(s *SmartContract, ctx contractapi.TransactionContextInterface, role string, genre string, affectation string) ([]*Transfert, error) {
+	elements, err := fileAttente(ctx, role, genre, affectation, AttenteTransfert)
+	if err != nil {
+		return nil, err
+	}
+	transferts := []*Transfert{}
+	for _, element := range elements {
+		transfert, err := s.LireTransfert(ctx, element[0])
+		if err != nil {
+			return nil, err
+		}
+		transferts = append(transferts, transfert)
+	}
+	return transferts, nil
+}
+
+// Demandes d'approbation en cours sur lesquelles l'appelant, agent du rôle, peut encore se prononcer
+func approbationsEnAttente(ctx contractapi.TransactionContextInterface, role string) ([]*Approbation, error) {
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	elements, err := fileAttente(ctx, role, AffectationRole, "", AttenteApprobation)
+	if err != nil {
+		return nil, err
+	}
+	approbations := []*Approbation{}
+	for _, element := range elements {
+		approbation, err := lireApprobation(ctx, element[0], element[1])
+		if err != nil {
+			return nil, err
+		}
+		if approbation != nil && attendAgent(*approbation, agent, role) {
+			approbations = append(approbations, approbation)
+		}
+	}
+	return approbations, nil
+}
+
+// Transferts en attente dont l'appelant est le notaire instrumentaire (réservé aux notaires)
+func (s *SmartContract) GetTransfertsEnAttenteNotaire(ctx contractapi.TransactionContextInterface) ([]*Transfert, error) {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return nil, err
+	}
+	notaire, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	return transfertsEnAttente(s, ctx, RoleNotaire, AffectationAgent, notaire)
+}
+
+// Demandes d'approbation ouvertes aux géomètres sur lesquelles l'appelant n'a pas encore porté son
+// attestation (réservé aux géomètres)
+func (s *SmartContract) GetAttestationsEnAttenteGeometre(ctx contractapi.TransactionContextInterface) ([]*Approbation, error) {
+	if err := verifierRole(ctx, RoleGeometre); err != nil {
+		return nil, err
+	}
+	return approbationsEnAttente(ctx, RoleGeometre)
+}
+
+// Dossiers en attente du conservateur appelant : transferts comptant non affectés de son bureau ou
+// qui lui sont affectés, et demandes d'approbation ouvertes aux conservateurs (réservé aux conservateurs)
+func (s *SmartContract) GetDossiersEnAttenteConservateur(ctx contractapi.TransactionContextInterface) (*DossiersEnAttente, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	bureau, err := bureauAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bureau == "" {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'appelant n'est rattaché à aucun bureau foncier")
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	dossiers := &DossiersEnAttente{Bureau: bureau}
+	if dossiers.Transferts, err = transfertsEnAttente(s, ctx, RoleConservateur, AffectationBureau, bureau); err != nil {
+		return nil, err
+	}
+	affectes, err := transfertsEnAttente(s, ctx, RoleConservateur, AffectationAgent, agent)
+	if err != nil {
+		return nil, err
+	}
+	dossiers.Transferts = append(dossiers.Transferts, affectes...)
+	if dossiers.Approbations, err = approbationsEnAttente(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	return dossiers, nil
+}
+
+// Étapes du job FILES_ATTENTE, dans l'ordre de parcours : entrées de l'ancienne disposition, sans genre
+// d'affectation, puis transferts et demandes d'approbation en attente
+var etapesFilesAttente = []string{PrefixeFileAttente, PrefixeTransfert, PrefixeApprobation}
+
+// Page du job FILES_ATTENTE : les entrées inscrites avant le genre d'affectation sont retirées, et les
+// transferts et demandes d'approbation en attente, dont ceux antérieurs aux files, y sont inscrits. Le
+// signet porte l'étape et la dernière clé traitée ; une page incomplète termine l'étape. Réinscrire une
+// entrée existante la laisse inchangée.
+func pageFilesAttente(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	nom, derniere, _ := strings.Cut(job.Signet, separateurSignetAudit)
+	etape := 0
+	for i, e := range etapesFilesAttente {
+		if e == nom {
+			etape = i
+		}
+	}
+
+	cles, err := clesParPrefixeApres(ctx, etapesFilesAttente[etape], derniere, taillePage)
+	if err != nil {
+		return false, err
+	}
+	for _, cle := range cles {
+		job.Compteurs["clesParcourues"]++
+		derniere = cle
+		switch etapesFilesAttente[etape] {
+		case PrefixeFileAttente:
+			_, attributs, err := ctx.GetStub().SplitCompositeKey(cle)
+			if err != nil {
+				return false, err
+			}
+			if len(attributs) > 1 && (attributs[1] == AffectationAgent || attributs[1] == AffectationBureau || attributs[1] == AffectationRole) {
+				continue
+			}
+			if err := ctx.GetStub().DelState(cle); err != nil {
+				return false, err
+			}
+			job.Compteurs["entreesRetirees"]++
+		case PrefixeTransfert:
+			var transfert Transfert
+			if _, err := lireEtat(ctx, cle, &transfert); err != nil {
+				return false, err
+			}
+			if transfert.Statut != TransfertEnAttente {
+				continue
+			}
+			var titre TitreFoncier
+			if _, err := lireTitre(ctx, transfert.IdTitre, &titre); err != nil {
+				return false, err
+			}
+			genre, responsable, err := responsableTransfert(ctx, &transfert, titre.BureauFoncier)
+			if err != nil {
+				return false, err
+			}
+			entrees, err := entreesFileTransfert(ctx, &transfert, genre, responsable)
+			if err != nil {
+				return false, err
+			}
+			if err := mettreAJourFileAttente(ctx, entrees, true); err != nil {
+				return false, err
+			}
+			job.Compteurs["dossiersInscrits"]++
+		default:
+			var approbation Approbation
+			if _, err := lireEtat(ctx, cle, &approbation); err != nil {
+				return false, err
+			}
+			if approbation.Statut != ApprobationEnCours {
+				continue
+			}
+			entrees, err := entreesFileApprobation(ctx, &approbation)
+			if err != nil {
+				return false, err
+			}
+			if err := mettreAJourFileAttente(ctx, entrees, true); err != nil {
+				return false, err
+			}
+			job.Compteurs["dossiersInscrits"]++
+		}
+	}
+
+	switch {
+	case len(cles) == taillePage:
+		job.Signet = etapesFilesAttente[etape] + separateurSignetAudit + derniere
+		return false, nil
+	case etape+1 < len(etapesFilesAttente):
+		job.Signet = etapesFilesAttente[etape+1] + separateurSignetAudit
+		return false, nil
+	default:
+		return true, nil
+	}
+}
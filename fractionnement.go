@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"titrefoncier/pkg/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des documents d'un titre, chacun enregistré hors de l'état du titre (idTitre~seq)
+const PrefixeDocumentTitre = "DOC_TITRE"
+
+// Préfixe du compteur des séquences de documents d'un titre (idTitre)
+const PrefixeSequenceDocument = "SEQ_DOC_TITRE"
+
+// Nombre maximal de titres mesurés par page
+const LimiteMesuresHistorique = 200
+
+// Migrer sans attendre sa prochaine modification un titre dont les documents figurent encore dans
+// son état (réservé à l'État) ; tout titre écrit par sauvegarderTitre l'est déjà
+func (s *SmartContract) SeparerDocuments(ctx contractapi.TransactionContextInterface, idTitre string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if titre.DocumentsSepares {
+		return fmt.Errorf("les documents du titre foncier %s sont déjà séparés", idTitre)
+	}
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Mesurer, par pages, l'historique des titres du registre (nombre et taille des versions)
+func (s *SmartContract) MesurerHistoriques(ctx contractapi.TransactionContextInterface, limite int, signet string) (*PageMesuresHistorique, error) {
+	if limite <= 0 || limite > LimiteMesuresHistorique {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteMesuresHistorique)
+	}
+	resultsIterator, metadonnees, err := ctx.GetStub().GetStateByRangeWithPagination("", "", int32(limite), signet)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &PageMesuresHistorique{Mesures: []*MesureHistorique{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return nil, err
+		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return nil, err
+		}
+
+		mesure := &MesureHistorique{
+			Cle:              queryResponse.Key,
+			TailleCourante:   len(queryResponse.Value),
+			Documents:        len(titre.Documents),
+			DocumentsSepares: titre.DocumentsSepares,
+		}
+		historique, err := ctx.GetStub().GetHistoryForKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		for historique.HasNext() {
+			modification, err := historique.Next()
+			if err != nil {
+				historique.Close()
+				return nil, err
+			}
+			mesure.Versions++
+			mesure.TailleCumulee += len(modification.Value)
+			if len(modification.Value) > mesure.TailleMax {
+				mesure.TailleMax = len(modification.Value)
+			}
+		}
+		historique.Close()
+		page.Mesures = append(page.Mesures, mesure)
+	}
+	if len(page.Mesures) == limite {
+		page.Signet = metadonnees.GetBookmark()
+	}
+	return page, nil
+}
+
+// Lire un titre et ses documents, qu'ils figurent dans son état ou sous leurs propres clés
+func lireTitre(ctx contractapi.TransactionContextInterface, id string, titre *TitreFoncier) (bool, error) {
+	existe, err := lireEtat(ctx, id, titre)
+	if err != nil || !existe {
+		return existe, err
+	}
+	return true, chargerDocuments(ctx, titre)
+}
+
+// Rattacher à un titre décodé ses documents séparés, dans l'ordre de leur séquence
+func chargerDocuments(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	if !titre.DocumentsSepares {
+		return nil
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeDocumentTitre, []string{titre.Id})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	titre.Documents = nil
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		var document DocumentTitre
+		if err := decoderEtat(queryResponse.Value, &document); err != nil {
+			return err
+		}
+		titre.Documents = append(titre.Documents, document)
+	}
+	return nil
+}
+
+// Écrire sous sa propre clé chaque document nouveau ou modifié d'un titre, et retirer ceux qui ne
+// figurent plus dans sa liste : un document garde sa séquence jusqu'à son retrait, si bien qu'ajouter,
+// remplacer ou purger un acte ne réécrit pas les autres. Un document qui ne figurait pas sous ce titre
+// reçoit une séquence du compteur du titre.
+func ecrireDocumentsSepares(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, ancien *TitreFoncier) error {
+	enPlace := make(map[int]DocumentTitre)
+	if ancien != nil && ancien.DocumentsSepares {
+		for _, document := range ancien.Documents {
+			enPlace[document.Seq] = document
+		}
+		if ancien.DernierSeqDocument > titre.DernierSeqDocument {
+			titre.DernierSeqDocument = ancien.DernierSeqDocument
+		}
+	}
+
+	// Le compteur n'est lu qu'à la première séquence attribuée
+	dernier := -1
+	conserves := make(map[int]bool)
+	for i := range titre.Documents {
+		document := &titre.Documents[i]
+		if _, ok := enPlace[document.Seq]; !ok || conserves[document.Seq] {
+			if dernier < 0 {
+				seq, err := lireSeqDocument(ctx, titre)
+				if err != nil {
+					return err
+				}
+				dernier = seq
+			}
+			dernier++
+			document.Seq = dernier
+		}
+		conserves[document.Seq] = true
+		if precedent, ok := enPlace[document.Seq]; ok && precedent == *document {
+			continue
+		}
+		cle, err := cleDocumentTitre(ctx, titre.Id, document.Seq)
+		if err != nil {
+			return err
+		}
+		if err := ecrireEtat(ctx, cle, *document); err != nil {
+			return err
+		}
+	}
+	if dernier >= 0 {
+		titre.DernierSeqDocument = dernier
+		if err := ecrireSeqDocument(ctx, titre.Id, dernier); err != nil {
+			return err
+		}
+	}
+	if ancien == nil || !ancien.DocumentsSepares {
+		return nil
+	}
+	// Parcours de la liste et non de la table : l'ordre des écritures doit être le même sur tous les pairs
+	for _, document := range ancien.Documents {
+		if conserves[document.Seq] {
+			continue
+		}
+		cle, err := cleDocumentTitre(ctx, titre.Id, document.Seq)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(cle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retirer du registre les documents séparés d'un titre et leur compteur de séquence, pour qu'un titre
+// immatriculé ensuite sous le même identifiant n'en hérite pas
+func supprimerDocumentsSepares(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	if titre.DocumentsSepares {
+		for _, document := range titre.Documents {
+			cle, err := cleDocumentTitre(ctx, titre.Id, document.Seq)
+			if err != nil {
+				return err
+			}
+			if err := ctx.GetStub().DelState(cle); err != nil {
+				return err
+			}
+		}
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequenceDocument, []string{titre.Id})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(cle)
+}
+
+// Ajouter un document à un titre dont les documents sont séparés sans réécrire l'état du titre : seuls
+// la clé du document, le compteur de séquence et l'entrée d'index de son empreinte sont écrits, puis la
+// modification est journalisée et notifiée comme par sauvegarderTitre. Retourne
+// faux, sans rien écrire, si le document modifie le score de complétude, qui figure dans l'état du titre.
+func ajouterDocumentSepare(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, document DocumentTitre) (bool, error) {
+	if !titre.DocumentsSepares {
+		return false, nil
+	}
+	complete := *titre
+	complete.Documents = append(slices.Clone(titre.Documents), document)
+	if model.EvaluerQualite(&complete) != model.EvaluerQualite(titre) {
+		return false, nil
+	}
+
+	seq, err := lireSeqDocument(ctx, titre)
+	if err != nil {
+		return false, err
+	}
+	document.Seq = seq + 1
+	cle, err := cleDocumentTitre(ctx, titre.Id, document.Seq)
+	if err != nil {
+		return false, err
+	}
+	if err := ecrireEtat(ctx, cle, document); err != nil {
+		return false, err
+	}
+	if err := ecrireSeqDocument(ctx, titre.Id, document.Seq); err != nil {
+		return false, err
+	}
+	if document.Hash != "" {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexHash, []string{document.Hash, titre.Id})
+		if err != nil {
+			return false, err
+		}
+		if err := ctx.GetStub().PutState(cle, valeurIndex); err != nil {
+			return false, err
+		}
+	}
+	if err := journaliserTitre(ctx, titre, titre); err != nil {
+		return false, err
+	}
+	if err := notifierAbonnes(ctx, titre, ""); err != nil {
+		return false, err
+	}
+	return true, alerterBanques(ctx, titre.Id, AlerteModification)
+}
+
+// Dernière séquence attribuée aux documents d'un titre. Les titres séparés avant la tenue du compteur
+// en portent la valeur dans leur état (DernierSeqDocument).
+func lireSeqDocument(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) (int, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequenceDocument, []string{titre.Id})
+	if err != nil {
+		return 0, err
+	}
+	valeur, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return 0, fmt.Errorf("erreur de lecture de la séquence: %v", err)
+	}
+	seq := 0
+	if valeur != nil {
+		if seq, err = strconv.Atoi(string(valeur)); err != nil {
+			return 0, fmt.Errorf("séquence des documents du titre foncier %s corrompue: %v", titre.Id, err)
+		}
+	}
+	return max(seq, titre.DernierSeqDocument), nil
+}
+
+func ecrireSeqDocument(ctx contractapi.TransactionContextInterface, idTitre string, seq int) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequenceDocument, []string{idTitre})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(cle, []byte(strconv.Itoa(seq)))
+}
+
+// Rattacher à chaque version de l'historique d'un titre ses documents séparés tels qu'ils figuraient
+// au registre à l'instant de cette version, par rejeu de l'historique de leurs clés
+func reconstituerDocuments(ctx contractapi.TransactionContextInterface, idTitre string, versions []*VersionTitre) error {
+	var separees []*VersionTitre
+	var instants []time.Time
+	vues := make(map[*VersionTitre]bool)
+	dernier := 0
+	for _, version := range versions {
+		if version.Titre == nil || !version.Titre.DocumentsSepares || vues[version] {
+			continue
+		}
+		instant, err := time.Parse(time.RFC3339Nano, version.Horodatage)
+		if err != nil {
+			return err
+		}
+		vues[version] = true
+		separees = append(separees, version)
+		instants = append(instants, instant)
+		if version.Titre.DernierSeqDocument > dernier {
+			dernier = version.Titre.DernierSeqDocument
+		}
+	}
+
+	// Les documents ajoutés sans réécrire le titre ne relèvent sa séquence que dans le compteur
+	if len(separees) > 0 {
+		courant, err := lireSeqDocument(ctx, &TitreFoncier{Id: idTitre})
+		if err != nil {
+			return err
+		}
+		dernier = max(dernier, courant)
+	}
+
+	for seq := 1; seq <= dernier; seq++ {
+		cle, err := cleDocumentTitre(ctx, idTitre, seq)
+		if err != nil {
+			return err
+		}
+		etats, err := historiqueDocument(ctx, cle)
+		if err != nil {
+			return err
+		}
+		for i, version := range separees {
+			var courant *DocumentTitre
+			for _, etat := range etats {
+				if etat.instant.After(instants[i]) {
+					break
+				}
+				courant = etat.document
+			}
+			if courant != nil {
+				version.Titre.Documents = append(version.Titre.Documents, *courant)
+			}
+		}
+	}
+	return nil
+}
+
+// État successif d'une clé de document ; document nul une fois la clé supprimée
+type etatDocument struct {
+	instant  time.Time
+	document *DocumentTitre
+}
+
+func historiqueDocument(ctx contractapi.TransactionContextInterface, cle string) ([]etatDocument, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(cle)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var etats []etatDocument
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		etat := etatDocument{instant: modification.Timestamp.AsTime().UTC()}
+		if !modification.IsDelete {
+			var document DocumentTitre
+			if err := decoderEtat(modification.Value, &document); err != nil {
+				return nil, err
+			}
+			etat.document = &document
+		}
+		etats = append(etats, etat)
+	}
+	sort.SliceStable(etats, func(i, j int) bool {
+		return etats[i].instant.Before(etats[j].instant)
+	})
+	return etats, nil
+}
+
+// La séquence est complétée de zéros pour que l'ordre des clés suive celui des ajouts
+func cleDocumentTitre(ctx contractapi.TransactionContextInterface, idTitre string, seq int) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(PrefixeDocumentTitre, []string{idTitre, fmt.Sprintf("%06d", seq)})
+}
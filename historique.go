@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// État d'un titre tel qu'il figurait au registre à une date donnée (RFC 3339), par rejeu de son historique
+// et de celui de ses documents
+func (s *SmartContract) LireTitreALaDate(ctx contractapi.TransactionContextInterface, id string, date string) (*TitreFoncier, error) {
+	instant, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "date %q invalide, format RFC 3339 attendu", date)
+	}
+
+	versions, err := versionsTitre(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var courante *VersionTitre
+	for _, version := range versions {
+		horodatage, err := time.Parse(time.RFC3339Nano, version.Horodatage)
+		if err != nil {
+			return nil, err
+		}
+		// Une version postérieure à la date clôt le rejeu : les suivantes ont été validées après elle,
+		// quel que soit l'horodatage qu'elles déclarent
+		if horodatage.After(instant) {
+			break
+		}
+		courante = version
+	}
+
+	if courante == nil {
+		return nil, fmt.Errorf("le titre foncier %s n'existait pas au %s", id, date)
+	}
+	if courante.Supprime {
+		return nil, fmt.Errorf("le titre foncier %s était archivé au %s (transaction %s)", id, date, courante.TxId)
+	}
+	if err := reconstituerDocuments(ctx, id, []*VersionTitre{courante}); err != nil {
+		return nil, err
+	}
+	return courante.Titre, nil
+}
+
+// Contour d'une parcelle en vigueur à une date donnée (RFC 3339), avec sa période de vigueur, par rejeu
+// de l'historique du titre : les contours remplacés restent consultables après l'archivage du titre.
+// Chaque contour prend effet à sa date d'effet légale (date du bornage) ou, à défaut, à la date de la
+// transaction qui l'a enregistré ; une rectification rétroactive prévaut sur les contours antérieurs
+// pour la période qu'elle couvre.
+func (s *SmartContract) LireGeometrieALaDate(ctx contractapi.TransactionContextInterface, id string, date string) (*VersionGeometrie, error) {
+	instant, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "date %q invalide, format RFC 3339 attendu", date)
+	}
+
+	geometries, err := versionsGeometrie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(geometries) == 0 {
+		return nil, fmt.Errorf("aucun contour du titre foncier %s n'a été enregistré", id)
+	}
+
+	// Le contour enregistré le plus récemment dont la période couvre la date l'emporte
+	for i := len(geometries) - 1; i >= 0; i-- {
+		if enVigueur(instant, geometries[i].EnVigueurDepuis, geometries[i].EnVigueurJusquau) {
+			return geometries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("aucun contour du titre foncier %s n'était en vigueur au %s", id, date)
+}
+
+// Contours successifs d'un titre, dans l'ordre de leur enregistrement, reconstitués depuis son
+// historique ; chacun reste en vigueur jusqu'à l'entrée en vigueur du suivant, ou jusqu'à l'archivage
+func versionsGeometrie(ctx contractapi.TransactionContextInterface, id string) ([]*VersionGeometrie, error) {
+	versions, err := versionsTitre(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var geometries []*VersionGeometrie
+	var courante *VersionGeometrie
+	for _, version := range versions {
+		geometrie := ""
+		if !version.Supprime {
+			geometrie = version.Titre.Geometrie
+		}
+		if courante != nil && geometrie == courante.Geometrie {
+			continue
+		}
+
+		// Date de la transaction, à défaut d'une date d'effet portée par le titre : contours antérieurs
+		// à sa tenue, ou modifiés sans qu'elle change
+		depuis := version.Horodatage
+		if !version.Supprime && version.Titre.GeometrieDepuis != "" && (courante == nil || version.Titre.GeometrieDepuis != courante.EnVigueurDepuis) {
+			depuis = version.Titre.GeometrieDepuis
+		}
+		if courante != nil {
+			courante.EnVigueurJusquau = depuis
+			courante.TxRemplacement = version.TxId
+		}
+		courante = nil
+		if geometrie == "" {
+			continue
+		}
+		courante = &VersionGeometrie{
+			IdTitre:            id,
+			Geometrie:          geometrie,
+			SystemeCoordonnees: version.Titre.SystemeCoordonnees,
+			EnVigueurDepuis:    depuis,
+			TxId:               version.TxId,
+		}
+		geometries = append(geometries, courante)
+	}
+	return geometries, nil
+}
+
+// Indiquer si un instant tombe dans une période de vigueur [depuis, jusqu'au[, une borne vide
+// laissant la période ouverte
+func enVigueur(instant time.Time, depuis string, jusquau string) bool {
+	if debut, err := time.Parse(time.RFC3339, depuis); err == nil && instant.Before(debut) {
+		return false
+	}
+	if fin, err := time.Parse(time.RFC3339, jusquau); err == nil && !instant.Before(fin) {
+		return false
+	}
+	return true
+}
+
+// Dater l'entrée en vigueur du contour d'un titre : un contour inchangé garde sa date, un contour
+// nouveau prend la date d'effet fixée par l'opération (bornage) ou, à défaut, celle de la transaction
+func daterGeometrie(ancien *TitreFoncier, titre *TitreFoncier, maintenant time.Time) {
+	if titre.Geometrie == "" {
+		titre.GeometrieDepuis = ""
+		return
+	}
+	if ancien != nil && titre.Geometrie == ancien.Geometrie {
+		titre.GeometrieDepuis = ancien.GeometrieDepuis
+		return
+	}
+	if ancien == nil || titre.GeometrieDepuis == ancien.GeometrieDepuis {
+		titre.GeometrieDepuis = maintenant.Format(time.RFC3339)
+	}
+}
+
+// Versions successives d'un titre, de la plus ancienne à la plus récente
+func versionsTitre(ctx contractapi.TransactionContextInterface, id string) ([]*VersionTitre, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var versions []*VersionTitre
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		version := &VersionTitre{
+			TxId:       modification.TxId,
+			Horodatage: modification.Timestamp.AsTime().UTC().Format(time.RFC3339Nano),
+			Supprime:   modification.IsDelete,
+		}
+		if !modification.IsDelete {
+			var titre TitreFoncier
+			err = decoderEtat(modification.Value, &titre)
+			if err != nil {
+				return nil, err
+			}
+			version.Titre = &titre
+		}
+		versions = append(versions, version)
+	}
+
+	// L'historique est restitué dans l'ordre des blocs, du plus récent au plus ancien ; l'horodatage,
+	// fourni par le client, ne peut servir à ordonner des versions produites comme preuve
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// Différences champ par champ entre deux versions d'un titre identifiées par leur transaction
+func (s *SmartContract) ComparerVersions(ctx contractapi.TransactionContextInterface, id string, txId1 string, txId2 string) (*ComparaisonVersions, error) {
+	versions, err := versionsTitre(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	version1, err := versionParTransaction(versions, id, txId1)
+	if err != nil {
+		return nil, err
+	}
+	version2, err := versionParTransaction(versions, id, txId2)
+	if err != nil {
+		return nil, err
+	}
+	if err := reconstituerDocuments(ctx, id, []*VersionTitre{version1, version2}); err != nil {
+		return nil, err
+	}
+
+	comparaison := &ComparaisonVersions{
+		IdTitre:          id,
+		TxId1:            txId1,
+		TxId2:            txId2,
+		Changements:      []ChangementChamp{},
+		DocumentsAjoutes: differenceDocuments(version2.Titre.Documents, version1.Titre.Documents),
+		DocumentsRetires: differenceDocuments(version1.Titre.Documents, version2.Titre.Documents),
+		ChargesInscrites: differenceCharges(version2.Titre.Charges, version1.Titre.Charges),
+		ChargesRadiees:   differenceCharges(version1.Titre.Charges, version2.Titre.Charges),
+	}
+
+	champs1, err := champsTitre(version1.Titre)
+	if err != nil {
+		return nil, err
+	}
+	champs2, err := champsTitre(version2.Titre)
+	if err != nil {
+		return nil, err
+	}
+	noms := make(map[string]bool)
+	for nom := range champs1 {
+		noms[nom] = true
+	}
+	for nom := range champs2 {
+		noms[nom] = true
+	}
+	for _, nom := range clesTriees(noms) {
+		if nom == "documents" || nom == "charges" || bytes.Equal(champs1[nom], champs2[nom]) {
+			continue
+		}
+		// Tenue interne des documents enregistrés sous leurs propres clés
+		if nom == "documentsSepares" || nom == "dernierSeqDocument" {
+			continue
+		}
+		comparaison.Changements = append(comparaison.Changements, ChangementChamp{
+			Champ: nom,
+			Avant: valeurChamp(champs1[nom]),
+			Apres: valeurChamp(champs2[nom]),
+		})
+	}
+
+	return comparaison, nil
+}
+
+func versionParTransaction(versions []*VersionTitre, id string, txId string) (*VersionTitre, error) {
+	for _, version := range versions {
+		if version.TxId != txId {
+			continue
+		}
+		if version.Supprime {
+			return nil, fmt.Errorf("la transaction %s a archivé le titre foncier %s", txId, id)
+		}
+		return version, nil
+	}
+	return nil, fmt.Errorf("la transaction %s n'a pas modifié le titre foncier %s", txId, id)
+}
+
+// Champs d'un titre dans le format d'échange
+func champsTitre(titre *TitreFoncier) (map[string]json.RawMessage, error) {
+	blob, err := json.Marshal(titre)
+	if err != nil {
+		return nil, err
+	}
+	var champs map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &champs); err != nil {
+		return nil, err
+	}
+	return champs, nil
+}
+
+// Valeur lisible d'un champ : texte brut pour les chaînes, JSON pour le reste
+func valeurChamp(valeur json.RawMessage) string {
+	var texte string
+	if err := json.Unmarshal(valeur, &texte); err == nil {
+		return texte
+	}
+	return string(valeur)
+}
+
+// Documents de a absents de b
+func differenceDocuments(a []DocumentTitre, b []DocumentTitre) []DocumentTitre {
+	difference := []DocumentTitre{}
+	for _, document := range a {
+		present := false
+		for _, autre := range b {
+			if autre.Chemin == document.Chemin && autre.Hash == document.Hash {
+				present = true
+				break
+			}
+		}
+		if !present {
+			difference = append(difference, document)
+		}
+	}
+	return difference
+}
+
+// Charges de a absentes de b
+func differenceCharges(a []Charge, b []Charge) []Charge {
+	difference := []Charge{}
+	for _, charge := range a {
+		present := false
+		for _, autre := range b {
+			if autre == charge {
+				present = true
+				break
+			}
+		}
+		if !present {
+			difference = append(difference, charge)
+		}
+	}
+	return difference
+}
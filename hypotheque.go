@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des conventions de subordination (idTitre~txID)
+const PrefixeSubordination = "SUBORDINATION"
+
+// Préfixe des clés composites des consentements des banques créancières (idTitre~hypotheque~operation~beneficiaire)
+const PrefixeConsentementCreancier = "CONSENTEMENT_CREANCIER"
+
+// Inscrire une hypothèque au rang suivant celles déjà inscrites sur le titre (réservé aux notaires).
+// Le créancier est désigné par le MSP de la banque, seul habilité à consentir aux transferts du titre.
+func (s *SmartContract) InscrireHypotheque(ctx contractapi.TransactionContextInterface, idTitre string, creancier string, montant int) (string, error) {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return "", err
+	}
+	if creancier == "" || montant <= 0 {
+		return "", fmt.Errorf("le créancier et le montant garanti de l'hypothèque sont obligatoires")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return "", err
+	}
+	if err := verifierAlienable(titre); err != nil {
+		return "", err
+	}
+
+	reference := ctx.GetStub().GetTxID()
+	ajouterCharge(titre, Charge{
+		Type:         ChargeHypotheque,
+		Beneficiaire: creancier,
+		Reference:    reference,
+		Montant:      montant,
+		Rang:         len(hypothequesOrdonnees(titre)) + 1,
+	})
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return "", err
+	}
+
+	return reference, nil
+}
+
+// Radier une hypothèque après la mainlevée consentie par sa banque créancière (ConsentirRadiation) ; les
+// hypothèques de rang inférieur avancent d'un rang (réservé aux notaires)
+func (s *SmartContract) RadierHypotheque(ctx contractapi.TransactionContextInterface, idTitre string, reference string) error {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if titre.Realisation == reference {
+		return nouvelleErreur(CodeTitreEnRealisation, "l'hypothèque %s est en cours de réalisation sur le titre foncier %s", reference, idTitre)
+	}
+	if !retirerCharge(titre, ChargeHypotheque, reference) {
+		return fmt.Errorf("aucune hypothèque %s n'est inscrite sur le titre foncier %s", reference, idTitre)
+	}
+	if err := consommerConsentementCreancier(ctx, idTitre, reference, ConsentementRadiation, ""); err != nil {
+		return err
+	}
+	renumeroterHypotheques(hypothequesOrdonnees(titre))
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Enregistrer une convention par laquelle une hypothèque cède son rang à une hypothèque de rang
+// inférieur, placée immédiatement avant elle, avec le consentement de la banque créancière de la
+// cédante (ConsentirSubordination) (réservé aux notaires)
+func (s *SmartContract) SubordonnerHypotheque(ctx contractapi.TransactionContextInterface, idTitre string, cedante string, beneficiaire string, acte string) (*SubordinationHypotheque, error) {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return nil, err
+	}
+	if acte == "" {
+		return nil, fmt.Errorf("la référence de la convention de subordination est obligatoire")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+
+	hypotheques := hypothequesOrdonnees(titre)
+	iCedante, iBeneficiaire := -1, -1
+	for i, hypotheque := range hypotheques {
+		switch hypotheque.Reference {
+		case cedante:
+			iCedante = i
+		case beneficiaire:
+			iBeneficiaire = i
+		}
+	}
+	if iCedante < 0 || iBeneficiaire < 0 {
+		return nil, fmt.Errorf("les hypothèques %s et %s doivent être inscrites sur le titre foncier %s", cedante, beneficiaire, idTitre)
+	}
+	if iBeneficiaire < iCedante {
+		return nil, fmt.Errorf("l'hypothèque %s prime déjà l'hypothèque %s", beneficiaire, cedante)
+	}
+	if err := consommerConsentementCreancier(ctx, idTitre, cedante, ConsentementSubordination, beneficiaire); err != nil {
+		return nil, err
+	}
+
+	notaire, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	promue := hypotheques[iBeneficiaire]
+	copy(hypotheques[iCedante+1:iBeneficiaire+1], hypotheques[iCedante:iBeneficiaire])
+	hypotheques[iCedante] = promue
+	renumeroterHypotheques(hypotheques)
+
+	subordination := &SubordinationHypotheque{
+		Id:           ctx.GetStub().GetTxID(),
+		IdTitre:      idTitre,
+		Cedante:      cedante,
+		Beneficiaire: beneficiaire,
+		Acte:         acte,
+		Notaire:      notaire,
+		Date:         maintenant.Format(FormatDate),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSubordination, []string{idTitre, subordination.Id})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, subordination); err != nil {
+		return nil, err
+	}
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return nil, err
+	}
+
+	return subordination, nil
+}
+
+// Consentir, au nom de la banque appelante, à la radiation de son hypothèque sur un titre
+func (s *SmartContract) ConsentirRadiation(ctx contractapi.TransactionContextInterface, idTitre string, reference string) (*ConsentementCreancier, error) {
+	return s.consentirCreancier(ctx, idTitre, reference, ConsentementRadiation, "")
+}
+
+// Consentir, au nom de la banque appelante, à ce que son hypothèque cède son rang à une autre
+// hypothèque du titre
+func (s *SmartContract) ConsentirSubordination(ctx contractapi.TransactionContextInterface, idTitre string, cedante string, beneficiaire string) (*ConsentementCreancier, error) {
+	if beneficiaire == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'hypothèque à qui le rang est cédé est obligatoire")
+	}
+	return s.consentirCreancier(ctx, idTitre, cedante, ConsentementSubordination, beneficiaire)
+}
+
+// Enregistrer le consentement de la banque appelante, créancière de l'hypothèque, à une opération sur elle
+func (s *SmartContract) consentirCreancier(ctx contractapi.TransactionContextInterface, idTitre string, reference string, operation string, beneficiaire string) (*ConsentementCreancier, error) {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	var hypotheque *Charge
+	for _, inscrite := range hypothequesOrdonnees(titre) {
+		if inscrite.Reference == reference {
+			hypotheque = inscrite
+		}
+	}
+	if hypotheque == nil {
+		return nil, fmt.Errorf("aucune hypothèque %s n'est inscrite sur le titre foncier %s", reference, idTitre)
+	}
+	if err := verifierMSP(ctx, hypotheque.Beneficiaire); err != nil {
+		return nil, err
+	}
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consentement := &ConsentementCreancier{
+		IdTitre:      idTitre,
+		Hypotheque:   reference,
+		Operation:    operation,
+		Beneficiaire: beneficiaire,
+		Creancier:    hypotheque.Beneficiaire,
+		DonnePar:     identite,
+		DonneLe:      maintenant.Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentementCreancier, []string{idTitre, reference, operation, beneficiaire})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, consentement); err != nil {
+		return nil, err
+	}
+	return consentement, nil
+}
+
+// Retirer le consentement de la banque créancière exigé par une opération sur son hypothèque
+func consommerConsentementCreancier(ctx contractapi.TransactionContextInterface, idTitre string, reference string, operation string, beneficiaire string) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentementCreancier, []string{idTitre, reference, operation, beneficiaire})
+	if err != nil {
+		return err
+	}
+	var consentement ConsentementCreancier
+	existe, err := lireEtat(ctx, cle, &consentement)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		return nouvelleErreur(CodeConsentementRequis, "la banque créancière de l'hypothèque %s n'a pas consenti à l'opération %s", reference, operation)
+	}
+	return ctx.GetStub().DelState(cle)
+}
+
+// Hypothèques d'un titre dans l'ordre de paiement sur le prix de réalisation
+func (s *SmartContract) GetRangHypotheques(ctx contractapi.TransactionContextInterface, idTitre string) ([]Charge, error) {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+
+	rangs := []Charge{}
+	for _, hypotheque := range hypothequesOrdonnees(titre) {
+		rangs = append(rangs, *hypotheque)
+	}
+	return rangs, nil
+}
+
+// Consentir, au nom de la banque appelante, au transfert d'un titre sur lequel elle détient une hypothèque
+func (s *SmartContract) ConsentirTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) error {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	msp, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture du MSP: %v", err)
+	}
+
+	detenues := 0
+	for _, hypotheque := range hypothequesOrdonnees(titre) {
+		if hypotheque.Beneficiaire != msp {
+			continue
+		}
+		detenues++
+		if !consentie(transfert, hypotheque.Reference) {
+			transfert.Consentements = append(transfert.Consentements, hypotheque.Reference)
+		}
+	}
+	if detenues == 0 {
+		return nouvelleErreur(CodeAccesRefuse, "%s ne détient aucune hypothèque sur le titre foncier %s", msp, titre.Id)
+	}
+
+	return sauvegarderTransfert(ctx, transfert)
+}
+
+// Signaler aux banques créancières la proposition du transfert d'un titre hypothéqué
+func demanderConsentements(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, transfert *Transfert) error {
+	hypotheques := hypothequesOrdonnees(titre)
+	if len(hypotheques) == 0 {
+		return nil
+	}
+
+	demande := &DemandeConsentement{
+		IdTransfert: transfert.Id,
+		IdTitre:     titre.Id,
+		Acheteur:    transfert.Acheteur,
+		Prix:        transfert.Prix,
+		Creanciers:  []string{},
+	}
+	creanciers := make(map[string]bool)
+	for _, hypotheque := range hypotheques {
+		demande.Hypotheques = append(demande.Hypotheques, hypotheque.Reference)
+		if !creanciers[hypotheque.Beneficiaire] {
+			creanciers[hypotheque.Beneficiaire] = true
+			demande.Creanciers = append(demande.Creanciers, hypotheque.Beneficiaire)
+		}
+	}
+
+	evenement, err := json.Marshal(demande)
+	if err != nil {
+		return err
+	}
+	return emettreEvenement(ctx, EvenementConsentementRequis, evenement)
+}
+
+// Vérifier que chaque hypothèque encore inscrite a reçu le consentement de son créancier, et l'usufruitier
+// celui d'un titre démembré ; une hypothèque radiée après remboursement n'en requiert plus
+func verifierConsentements(titre *TitreFoncier, transfert *Transfert) error {
+	for _, hypotheque := range hypothequesOrdonnees(titre) {
+		if !consentie(transfert, hypotheque.Reference) {
+			return nouvelleErreur(CodeTransfertRestreint, "le transfert %s requiert le consentement de %s, créancier de l'hypothèque %s", transfert.Id, hypotheque.Beneficiaire, hypotheque.Reference)
+		}
+	}
+	if titre.Usufruit != nil && !consentie(transfert, ConsentementUsufruitier) {
+		return nouvelleErreur(CodeTransfertRestreint, "le transfert %s requiert le consentement de l'usufruitier %s", transfert.Id, titre.Usufruit.Usufruitier)
+	}
+	return nil
+}
+
+func consentie(transfert *Transfert, reference string) bool {
+	for _, consentement := range transfert.Consentements {
+		if consentement == reference {
+			return true
+		}
+	}
+	return false
+}
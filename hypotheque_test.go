@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Banque créancière des hypothèques de test
+var banque = &identiteTest{msp: "BanqueAtlantiqueMSP"}
+
+// Titre TF100 du bureau de Dakar grevé d'une hypothèque au profit de la banque de test
+func (r *registreTest) titreHypotheque(t *testing.T) (*identiteTest, string) {
+	t.Helper()
+	if err := r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	notaire := notaireLicencie(t, r, "Me Sow", "N-001")
+	var hypotheque string
+	err := r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) (err error) {
+		hypotheque, err = r.s.InscrireHypotheque(ctx, "TF100", banque.msp, 5000000)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notaire, hypotheque
+}
+
+func TestConsentementDuCreancier(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	notaire, premiere := r.titreHypotheque(t)
+	var seconde string
+	err := r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) (err error) {
+		seconde, err = r.s.InscrireHypotheque(ctx, "TF100", "BanqueHabitatMSP", 2000000)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subordonner := func() error {
+		return r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := r.s.SubordonnerHypotheque(ctx, "TF100", premiere, seconde, "CONV-1")
+			return err
+		})
+	}
+	radier := func(reference string) error {
+		return r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.RadierHypotheque(ctx, "TF100", reference)
+		})
+	}
+
+	verifierCode(t, "subordination sans consentement", subordonner(), CodeConsentementRequis)
+	verifierCode(t, "radiation sans consentement", radier(premiere), CodeConsentementRequis)
+	// Seule la banque de l'hypothèque consent pour elle
+	autreBanque := &identiteTest{msp: "BanqueHabitatMSP"}
+	verifierCode(t, "consentement d'une autre banque", r.appeler(autreBanque, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := r.s.ConsentirSubordination(ctx, "TF100", premiere, seconde)
+		return err
+	}), CodeAccesRefuse)
+	verifierCode(t, "consentement à la subordination", r.appeler(banque, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := r.s.ConsentirSubordination(ctx, "TF100", premiere, seconde)
+		return err
+	}), "")
+	verifierCode(t, "radiation sur consentement à la subordination", radier(premiere), CodeConsentementRequis)
+	verifierCode(t, "subordination consentie", subordonner(), "")
+	if rang := r.lire("TF100").Charges; len(rang) != 2 {
+		t.Fatalf("charges du titre: %+v", rang)
+	}
+
+	verifierCode(t, "consentement à la radiation", r.appeler(banque, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := r.s.ConsentirRadiation(ctx, "TF100", premiere)
+		return err
+	}), "")
+	verifierCode(t, "radiation consentie", radier(premiere), "")
+	var rangs []Charge
+	err = r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		rangs, err = r.s.GetRangHypotheques(ctx, "TF100")
+		return err
+	})
+	if err != nil || len(rangs) != 1 || rangs[0].Reference != seconde || rangs[0].Rang != 1 {
+		t.Errorf("hypothèques après radiation: %+v (%v)", rangs, err)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixes des clés composites des index de titres
+const (
+	PrefixeIndexHash       = "INDEX_HASH"       // Documents par hash
+	PrefixeIndexProprio    = "INDEX_PROPRIO"    // Titres par propriétaire
+	PrefixeIndexNom        = "INDEX_NOM"        // Titres par nom de propriétaire normalisé, un caractère par attribut
+	PrefixeIndexNumTF      = "INDEX_NUMTF"      // Titres par numéro officiel
+	PrefixeIndexHypotheque = "INDEX_HYPOTHEQUE" // Titres grevés par hypothèque
+	PrefixeIndexGeohash    = "INDEX_GEOHASH"    // Parcelles en vigueur par tuile geohash de leur contour, un caractère par attribut
+	PrefixeIndexQualite    = "INDEX_QUALITE"    // Titres en vigueur par tranche de score de complétude
+	PrefixeIndexFolio      = "INDEX_FOLIO"      // Titres migrés par livre et folio des anciens livres fonciers
+)
+
+// Préfixe des clés composites désignant le dernier job de reconstruction de chaque famille d'index (famille)
+const PrefixeReconstructionIndex = "RECONSTRUCTION_INDEX"
+
+// Familles d'index reconstructibles par ReconstruireIndex
+var famillesIndex = []string{PrefixeIndexHash, PrefixeIndexNumTF, PrefixeIndexProprio, PrefixeIndexNom, PrefixeIndexHypotheque, PrefixeIndexGeohash, PrefixeIndexQualite, PrefixeIndexFolio}
+
+// Valeur des entrées d'index : seule la clé composite porte l'information
+var valeurIndex = []byte{0x00}
+
+// Clés d'index pointant vers un titre foncier
+func entreesIndex(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) (map[string]bool, error) {
+	cles := make(map[string]bool)
+	if titre == nil {
+		return cles, nil
+	}
+
+	hashes := []string{titre.DocHash}
+	for _, document := range titre.Documents {
+		hashes = append(hashes, document.Hash)
+	}
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexHash, []string{hash, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	if titre.NumTF != "" {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexNumTF, []string{titre.NumTF, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	for _, charge := range titre.Charges {
+		if charge.Type != ChargeHypotheque {
+			continue
+		}
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexHypotheque, []string{charge.Reference, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	if titre.Proprio != "" {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexProprio, []string{titre.Proprio, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+
+		// L'attribut vide marque la fin du nom : il distingue la recherche exacte de la recherche par préfixe
+		attributs := append(caracteresNom(titre.Proprio), "", titre.Id)
+		if cle, err = ctx.GetStub().CreateCompositeKey(PrefixeIndexNom, attributs); err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	for _, tuile := range tuilesTitre(titre) {
+		// Comme pour les noms, l'attribut vide marque la fin de la tuile
+		attributs := append(strings.Split(tuile, ""), "", titre.Id)
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexGeohash, attributs)
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	// Les titres mutés ou morcelés restent indexés : leur enregistrement désigne le canal ou les lots qui
+	// leur ont succédé
+	for _, reference := range titre.AnciennesReferences {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexFolio, []string{reference.Livre, reference.Folio, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	// Les titres mutés ou morcelés sont fiabilisés sur leur canal ou dans leurs lots
+	if titre.MuteVers == "" && len(titre.MorceleEn) == 0 {
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeIndexQualite, []string{model.EvaluerQualite(titre).Tranche, titre.Id})
+		if err != nil {
+			return nil, err
+		}
+		cles[cle] = true
+	}
+
+	return cles, nil
+}
+
+// Reporter dans les index la modification d'un titre (ancien nil à la création, titre nil à la suppression)
+func mettreAJourIndex(ctx contractapi.TransactionContextInterface, ancien *TitreFoncier, titre *TitreFoncier) error {
+	anciennes, err := entreesIndex(ctx, ancien)
+	if err != nil {
+		return err
+	}
+	nouvelles, err := entreesIndex(ctx, titre)
+	if err != nil {
+		return err
+	}
+
+	for _, cle := range clesTriees(anciennes) {
+		if !nouvelles[cle] {
+			if err := ctx.GetStub().DelState(cle); err != nil {
+				return err
+			}
+		}
+	}
+	for _, cle := range clesTriees(nouvelles) {
+		if !anciennes[cle] {
+			if err := ctx.GetStub().PutState(cle, valeurIndex); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Identifiants des titres indexés sous une clé partielle (dernier attribut de la clé composite), au plus limite si positive
+func titresIndexes(ctx contractapi.TransactionContextInterface, prefixe string, attributs []string, limite int) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(prefixe, attributs)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	ids := []string{}
+	for resultsIterator.HasNext() && (limite <= 0 || len(ids) < limite) {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, cle, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, cle[len(cle)-1])
+	}
+
+	return ids, nil
+}
+
+// Reconstruire une famille d'index page par page (réservé à l'État) : la phase TITRES rétablit les
+// entrées manquantes des titres, la phase ENTREES retire celles qui ne correspondent plus à aucun titre.
+// Un signet vide démarre un job de reconstruction ; chaque appel traite une page et repasse le signet suivant.
+// Chaque page se fonde sur l'état validé du registre : les écritures d'une page ne sont visibles que de
+// la suivante.
+func (s *SmartContract) ReconstruireIndex(ctx contractapi.TransactionContextInterface, typeIndex string, taillePage int, signet string) (*Job, error) {
+	parametres := map[string]string{"index": typeIndex}
+	if err := validerReconstructionIndex(parametres); err != nil {
+		return nil, err
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeReconstructionIndex, []string{typeIndex})
+	if err != nil {
+		return nil, err
+	}
+
+	var job *Job
+	if signet == "" {
+		if job, err = demarrerJob(ctx, JobReconstructionIndex, parametres); err != nil {
+			return nil, err
+		}
+		if err := ecrireEtat(ctx, cle, job.Id); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := verifierMSP(ctx, MSPEtat); err != nil {
+			return nil, err
+		}
+		var id string
+		existe, err := lireEtat(ctx, cle, &id)
+		if err != nil {
+			return nil, err
+		}
+		if existe {
+			job, err = lireJob(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// Seul le dernier signet remis fait avancer la reconstruction : un appel rejoué ne traite pas deux fois une page
+		if job == nil || job.Statut != JobEnCours || job.Signet != signet {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "signet de reconstruction invalide pour l'index %s: %s", typeIndex, signet)
+		}
+	}
+
+	if err := continuerJob(s, ctx, job, taillePage); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Dernier job de reconstruction d'une famille d'index
+func (s *SmartContract) LireReconstructionIndex(ctx contractapi.TransactionContextInterface, typeIndex string) (*Job, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeReconstructionIndex, []string{typeIndex})
+	if err != nil {
+		return nil, err
+	}
+	var id string
+	existe, err := lireEtat(ctx, cle, &id)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("aucune reconstruction de l'index %s", typeIndex)
+	}
+	return lireJob(ctx, id)
+}
+
+func validerReconstructionIndex(parametres map[string]string) error {
+	if !familleIndexConnue(parametres["index"]) {
+		return nouvelleErreur(CodeRequeteInvalide, "famille d'index inconnue: %s (attendu: %s)", parametres["index"], strings.Join(famillesIndex, ", "))
+	}
+	return nil
+}
+
+// Page d'un job de reconstruction d'index, le signet portant la phase et la dernière clé traitée dans
+// celle-ci ; une page incomplète termine la phase. Les requêtes paginées étant réservées aux transactions
+// en lecture seule, les pages sont bornées par un compteur sur des requêtes non paginées
+func pageReconstructionIndex(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	phase, derniere, _ := strings.Cut(job.Signet, separateurSignetAudit)
+	if phase == "" {
+		phase = PhaseReconstructionTitres
+	}
+	var traites int
+	var err error
+	if phase == PhaseReconstructionTitres {
+		traites, derniere, err = retablirEntrees(ctx, job, taillePage, derniere)
+	} else {
+		traites, derniere, err = retirerEntrees(ctx, job, taillePage, derniere)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case traites == taillePage:
+		job.Signet = phase + separateurSignetAudit + derniere
+		return false, nil
+	case phase == PhaseReconstructionTitres:
+		job.Signet = PhaseReconstructionEntrees + separateurSignetAudit
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+func familleIndexConnue(typeIndex string) bool {
+	for _, famille := range famillesIndex {
+		if famille == typeIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// Entrées d'une seule famille d'index pointant vers un titre
+func entreesFamille(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, typeIndex string) (map[string]bool, error) {
+	entrees, err := entreesIndex(ctx, titre)
+	if err != nil {
+		return nil, err
+	}
+	for cle := range entrees {
+		prefixe, _, err := ctx.GetStub().SplitCompositeKey(cle)
+		if err != nil {
+			return nil, err
+		}
+		if prefixe != typeIndex {
+			delete(entrees, cle)
+		}
+	}
+	return entrees, nil
+}
+
+// Phase TITRES : écrire les entrées attendues absentes pour une page de titres
+func retablirEntrees(ctx contractapi.TransactionContextInterface, job *Job, taillePage int, derniere string) (int, string, error) {
+	debut := ""
+	if derniere != "" {
+		debut = derniere + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	traites := 0
+	for traites < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		traites++
+		derniere = queryResponse.Key
+
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return 0, "", err
+		}
+		if titre.Id == "" {
+			continue
+		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return 0, "", err
+		}
+		job.Compteurs["titresParcourus"]++
+
+		entrees, err := entreesFamille(ctx, &titre, job.Parametres["index"])
+		if err != nil {
+			return 0, "", err
+		}
+		for _, cle := range clesTriees(entrees) {
+			valeur, err := ctx.GetStub().GetState(cle)
+			if err != nil {
+				return 0, "", err
+			}
+			if valeur != nil {
+				continue
+			}
+			if err := ctx.GetStub().PutState(cle, valeurIndex); err != nil {
+				return 0, "", err
+			}
+			job.Compteurs["entreesAjoutees"]++
+		}
+	}
+	return traites, derniere, nil
+}
+
+// Phase ENTREES : retirer d'une page d'entrées celles dont le titre est absent ou ne les produit plus
+func retirerEntrees(ctx contractapi.TransactionContextInterface, job *Job, taillePage int, derniere string) (int, string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(job.Parametres["index"], []string{})
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	traites := 0
+	titres := make(map[string]map[string]bool)
+	for traites < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		if queryResponse.Key <= derniere {
+			continue
+		}
+		traites++
+		derniere = queryResponse.Key
+		job.Compteurs["entreesControlees"]++
+
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, "", err
+		}
+		id := attributs[len(attributs)-1]
+
+		entrees, lu := titres[id]
+		if !lu {
+			var titre TitreFoncier
+			existe, err := lireTitre(ctx, id, &titre)
+			if err != nil {
+				return 0, "", err
+			}
+			entrees = map[string]bool{}
+			if existe {
+				if entrees, err = entreesFamille(ctx, &titre, job.Parametres["index"]); err != nil {
+					return 0, "", err
+				}
+			}
+			titres[id] = entrees
+		}
+		if entrees[queryResponse.Key] {
+			continue
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return 0, "", err
+		}
+		job.Compteurs["entreesRetirees"]++
+	}
+	return traites, derniere, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des jobs d'administration (id)
+const PrefixeJob = "JOB"
+
+// Traitement d'un type de job : contrôle de l'appelant et des paramètres au démarrage, puis une page
+// par transaction. traiterPage fait avancer le signet du job et indique s'il est terminé.
+type traitementJob struct {
+	autoriser   func(ctx contractapi.TransactionContextInterface) error
+	valider     func(parametres map[string]string) error
+	traiterPage func(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error)
+}
+
+// Traitements par type de job
+var traitementsJob = map[string]traitementJob{
+	JobReconstructionIndex: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     validerReconstructionIndex,
+		traiterPage: pageReconstructionIndex,
+	},
+	JobAuditCoherence: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierRole(ctx, RoleAuditeur)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageAuditCoherence,
+	},
+	JobRattachementBureaux: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     validerRattachementBureaux,
+		traiterPage: pageRattachementBureaux,
+	},
+	JobMarquageTitres: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageMarquageTitres,
+	},
+	JobEchantillonAudit: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierRole(ctx, RoleAuditeur)
+		},
+		valider:     validerEchantillonAudit,
+		traiterPage: pageEchantillonAudit,
+	},
+	JobAllocationsZones: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageAllocationsZones,
+	},
+	JobIndexActivite: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     validerIndexActivite,
+		traiterPage: pageIndexActivite,
+	},
+	JobEscaladeRetards: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageEscaladeRetards,
+	},
+	JobSuiviStatuts: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageSuiviStatuts,
+	},
+	JobFilesAttente: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageFilesAttente,
+	},
+	JobAbonnements: {
+		autoriser: func(ctx contractapi.TransactionContextInterface) error {
+			return verifierMSP(ctx, MSPEtat)
+		},
+		valider:     func(map[string]string) error { return nil },
+		traiterPage: pageAbonnements,
+	},
+}
+
+// Démarrer un job d'administration, identifié par l'ID de la transaction ; les paramètres sont un
+// objet JSON de chaînes propre au type de job (vide si aucun)
+func (s *SmartContract) DemarrerJob(ctx contractapi.TransactionContextInterface, typeJob string, parametres string) (*Job, error) {
+	valeurs := map[string]string{}
+	if parametres != "" {
+		if err := json.Unmarshal([]byte(parametres), &valeurs); err != nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "paramètres invalides, objet JSON attendu: %v", err)
+		}
+	}
+	job, err := demarrerJob(ctx, typeJob, valeurs)
+	if err != nil {
+		return nil, err
+	}
+	if err := sauvegarderJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Traiter la page suivante d'un job en cours. Deux appels concurrents lisent et écrivent le même job :
+// un seul est validé, l'autre échoue en conflit MVCC et peut être relancé.
+func (s *SmartContract) ContinuerJob(ctx contractapi.TransactionContextInterface, id string, taillePage int) (*Job, error) {
+	job, err := lireJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	traitement := traitementsJob[job.Type]
+	if err := traitement.autoriser(ctx); err != nil {
+		return nil, err
+	}
+	if err := continuerJob(s, ctx, job, taillePage); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Annuler un job en cours ; les pages déjà traitées restent acquises
+func (s *SmartContract) AnnulerJob(ctx contractapi.TransactionContextInterface, id string) error {
+	job, err := lireJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := traitementsJob[job.Type].autoriser(ctx); err != nil {
+		return err
+	}
+	if job.Statut != JobEnCours {
+		return fmt.Errorf("le job %s n'est pas en cours (statut: %s)", id, job.Statut)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	job.Statut = JobAnnule
+	job.MisAJourLe = maintenant.Format(time.RFC3339)
+	job.TermineLe = job.MisAJourLe
+	return sauvegarderJob(ctx, job)
+}
+
+// Lire l'état d'avancement d'un job
+func (s *SmartContract) LireJob(ctx contractapi.TransactionContextInterface, id string) (*Job, error) {
+	return lireJob(ctx, id)
+}
+
+func demarrerJob(ctx contractapi.TransactionContextInterface, typeJob string, parametres map[string]string) (*Job, error) {
+	traitement, connu := traitementsJob[typeJob]
+	if !connu {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "type de job inconnu: %s (attendu: %s)", typeJob, strings.Join(slices.Sorted(maps.Keys(traitementsJob)), ", "))
+	}
+	if err := traitement.autoriser(ctx); err != nil {
+		return nil, err
+	}
+	if err := traitement.valider(parametres); err != nil {
+		return nil, err
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{
+		Id:         ctx.GetStub().GetTxID(),
+		Type:       typeJob,
+		Statut:     JobEnCours,
+		Parametres: parametres,
+		Compteurs:  map[string]int{},
+		DemarrePar: agent,
+		DemarreLe:  maintenant.Format(time.RFC3339),
+		MisAJourLe: maintenant.Format(time.RFC3339),
+	}, nil
+}
+
+// Traiter une page d'un job puis l'enregistrer ; le job peut avoir été démarré dans la même transaction
+func continuerJob(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) error {
+	if job.Statut != JobEnCours {
+		return fmt.Errorf("le job %s n'est pas en cours (statut: %s)", job.Id, job.Statut)
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	if job.Compteurs == nil {
+		job.Compteurs = map[string]int{}
+	}
+
+	termine, err := traitementsJob[job.Type].traiterPage(s, ctx, job, taillePage)
+	if err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	job.Pages++
+	job.MisAJourLe = maintenant.Format(time.RFC3339)
+	if termine {
+		job.Statut = JobTermine
+		job.Signet = ""
+		job.TermineLe = job.MisAJourLe
+	}
+	return sauvegarderJob(ctx, job)
+}
+
+func lireJob(ctx contractapi.TransactionContextInterface, id string) (*Job, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeJob, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	existe, err := lireEtat(ctx, cle, &job)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("le job %s n'existe pas", id)
+	}
+	if _, connu := traitementsJob[job.Type]; !connu {
+		return nil, fmt.Errorf("le job %s est d'un type inconnu: %s", id, job.Type)
+	}
+	return &job, nil
+}
+
+func sauvegarderJob(ctx contractapi.TransactionContextInterface, job *Job) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeJob, []string{job.Id})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, job)
+}
+
+// Page de l'audit de cohérence : les anomalies sont comptées par type, le détail reste accessible
+// par AuditerCoherence
+func pageAuditCoherence(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	rapport, err := s.AuditerCoherence(ctx, taillePage, job.Signet)
+	if err != nil {
+		return false, err
+	}
+	job.Compteurs["controles"] += rapport.Controles
+	for _, anomalie := range rapport.Anomalies {
+		job.Compteurs[anomalie.Type]++
+	}
+	job.Signet = rapport.Signet
+	return rapport.Signet == "", nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Journal structuré (JSON) du chaincode, niveau réglé par CHAINCODE_LOG_LEVEL
+var journal = nouveauJournal(os.Getenv("CHAINCODE_LOG_LEVEL"))
+
+func nouveauJournal(niveau string) *slog.Logger {
+	var seuil slog.Level
+	switch strings.ToLower(niveau) {
+	case "debug":
+		seuil = slog.LevelDebug
+	case "warn", "warning":
+		seuil = slog.LevelWarn
+	case "error":
+		seuil = slog.LevelError
+	default:
+		seuil = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: seuil}))
+}
+
+// Journal enrichi des attributs de corrélation de la transaction
+func journalStub(stub shim.ChaincodeStubInterface) *slog.Logger {
+	fonction, _ := stub.GetFunctionAndParameters()
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		mspID = "inconnu"
+	}
+	return journal.With(
+		slog.String("txId", stub.GetTxID()),
+		slog.String("canal", stub.GetChannelID()),
+		slog.String("fonction", fonction),
+		slog.String("mspAppelant", mspID),
+	)
+}
+
+// Journal de la transaction courante, à utiliser depuis les fonctions du contrat
+func journalTx(ctx contractapi.TransactionContextInterface) *slog.Logger {
+	return journalStub(ctx.GetStub())
+}
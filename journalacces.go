@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Collection privée des consultations motivées, réservée au conservateur (voir collections_config.json)
+const CollectionJournalAcces = "journalAcces"
+
+// Préfixe des clés composites du journal des consultations (idTitre~txID)
+const PrefixeAccesTitre = "ACCES_TITRE"
+
+// Préfixe des clés composites des consultations déclarées en vigueur (idTitre~lecteur)
+const PrefixeConsultationDeclaree = "CONSULTATION_DECLAREE"
+
+// Durée pendant laquelle une consultation déclarée ouvre les lectures simples du titre, en minutes
+const DureeConsultationDeclaree = 60
+
+// Déclarer la finalité d'une consultation d'un titre pour le compte d'un tiers (banque, employeur), qui
+// doit avoir reçu le consentement du propriétaire (voir AccorderConsentement). L'accès est tracé dans le
+// journal du conservateur ; une fois la transaction validée, le lecteur peut consulter le titre
+// (LireTitreFoncier...) pendant DureeConsultationDeclaree minutes. Une simple évaluation ne trace rien,
+// et n'ouvre donc aucune lecture.
+func (s *SmartContract) DeclarerConsultation(ctx contractapi.TransactionContextInterface, id string, motif string) (*AccesTitre, error) {
+	if motif == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif de la consultation est obligatoire")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := controlerConsentement(ctx, titre, operationCourante(ctx)); err != nil {
+		return nil, err
+	}
+
+	lecteur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	acces := AccesTitre{
+		Id:           ctx.GetStub().GetTxID(),
+		IdTitre:      id,
+		Lecteur:      lecteur,
+		Organisation: mspID,
+		Motif:        motif,
+		Date:         maintenant.Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAccesTitre, []string{id, acces.Id})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrirePrive(ctx, CollectionJournalAcces, cle, &acces); err != nil {
+		return nil, err
+	}
+
+	declaration := ConsultationDeclaree{
+		IdTitre:      id,
+		Lecteur:      lecteur,
+		Organisation: mspID,
+		Acces:        acces.Id,
+		ExpireLe:     maintenant.Add(DureeConsultationDeclaree * time.Minute).Format(time.RFC3339),
+	}
+	cle, err = ctx.GetStub().CreateCompositeKey(PrefixeConsultationDeclaree, []string{id, lecteur})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, &declaration); err != nil {
+		return nil, err
+	}
+
+	return &acces, nil
+}
+
+// Vérifier que le lecteur appelant a déclaré, dans une transaction validée, une consultation du titre
+// encore en vigueur
+func verifierConsultationDeclaree(ctx contractapi.TransactionContextInterface, idTitre string) error {
+	lecteur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsultationDeclaree, []string{idTitre, lecteur})
+	if err != nil {
+		return err
+	}
+	var declaration ConsultationDeclaree
+	existe, err := lireEtat(ctx, cle, &declaration)
+	if err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if existe {
+		expiration, err := time.Parse(time.RFC3339, declaration.ExpireLe)
+		if err != nil {
+			return err
+		}
+		if maintenant.Before(expiration) {
+			return nil
+		}
+	}
+	return nouvelleErreur(CodeConsentementRequis, "la consultation du titre foncier %s doit d'abord être déclarée (DeclarerConsultation)", idTitre)
+}
+
+// Journal des consultations motivées d'un titre (réservé à l'État)
+func (s *SmartContract) GetJournalAcces(ctx contractapi.TransactionContextInterface, idTitre string) ([]*AccesTitre, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(CollectionJournalAcces, PrefixeAccesTitre, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var journal []*AccesTitre
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var acces AccesTitre
+		err = decoderJSON(queryResponse.Value, &acces)
+		if err != nil {
+			return nil, err
+		}
+		journal = append(journal, &acces)
+	}
+
+	return journal, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des licences professionnelles (numero)
+const PrefixeLicence = "LICENCE"
+
+// Ordre professionnel tenant les licences de chaque profession réglementée
+var ordresProfessionnels = map[string]string{
+	RoleEvaluateur: MSPOrdreEvaluateurs,
+	RoleGeometre:   MSPOrdreGeometres,
+	RoleNotaire:    MSPChambreNotaires,
+}
+
+// Délivrer ou renouveler la licence d'un professionnel (réservé à l'ordre de la profession) ;
+// une licence suspendue le reste jusqu'à sa réactivation, une licence radiée ne peut être renouvelée
+func (s *SmartContract) EnregistrerLicence(ctx contractapi.TransactionContextInterface, numero string, profession string, titulaire string, expiration string) error {
+	ordre, reglementee := ordresProfessionnels[profession]
+	if !reglementee {
+		return fmt.Errorf("la profession %q n'est pas soumise à licence", profession)
+	}
+	if err := verifierMSP(ctx, ordre); err != nil {
+		return err
+	}
+
+	licence, err := lireLicence(ctx, numero)
+	if err != nil {
+		return err
+	}
+	if licence == nil {
+		licence = &Licence{Numero: numero, Profession: profession, Statut: LicenceActive}
+	}
+	if licence.Profession != profession {
+		return fmt.Errorf("la licence %s a été délivrée pour la profession %s", numero, licence.Profession)
+	}
+	if licence.Statut == LicenceRadiee {
+		return fmt.Errorf("la licence %s est radiée", numero)
+	}
+	licence.Titulaire = titulaire
+	licence.Expiration = expiration
+	if err := licence.Valider(); err != nil {
+		return err
+	}
+
+	return sauvegarderLicence(ctx, licence)
+}
+
+// Lier une licence à l'identité de son titulaire, telle que restituée par GetID() pour son certificat
+// (réservé à l'ordre de la profession) : aucune autre identité ne peut plus s'en prévaloir, même en
+// portant son numéro dans l'attribut "licence"
+func (s *SmartContract) LierLicence(ctx contractapi.TransactionContextInterface, numero string, identite string) error {
+	licence, err := s.LireLicence(ctx, numero)
+	if err != nil {
+		return err
+	}
+	if err := verifierMSP(ctx, ordresProfessionnels[licence.Profession]); err != nil {
+		return err
+	}
+	if licence.Statut == LicenceRadiee {
+		return fmt.Errorf("la licence %s est radiée", numero)
+	}
+	if identite == "" {
+		return fmt.Errorf("l'identité du titulaire est obligatoire")
+	}
+
+	licence.Identite = identite
+	return sauvegarderLicence(ctx, licence)
+}
+
+// Suspendre, réactiver ou radier une licence (réservé à l'ordre de la profession)
+func (s *SmartContract) DefinirStatutLicence(ctx contractapi.TransactionContextInterface, numero string, statut string, motif string) error {
+	licence, err := s.LireLicence(ctx, numero)
+	if err != nil {
+		return err
+	}
+	if err := verifierMSP(ctx, ordresProfessionnels[licence.Profession]); err != nil {
+		return err
+	}
+	if licence.Statut == LicenceRadiee {
+		return fmt.Errorf("la licence %s est radiée", numero)
+	}
+	if statut != LicenceActive && motif == "" {
+		return fmt.Errorf("le motif de la suspension ou de la radiation est obligatoire")
+	}
+
+	licence.Statut = statut
+	licence.Motif = motif
+	if err := licence.Valider(); err != nil {
+		return err
+	}
+	return sauvegarderLicence(ctx, licence)
+}
+
+// Lire une licence professionnelle
+func (s *SmartContract) LireLicence(ctx contractapi.TransactionContextInterface, numero string) (*Licence, error) {
+	licence, err := lireLicence(ctx, numero)
+	if err != nil {
+		return nil, err
+	}
+	if licence == nil {
+		return nil, fmt.Errorf("licence %s non trouvée", numero)
+	}
+	return licence, nil
+}
+
+// Vérifier que l'appelant exerçant une profession réglementée détient une licence active et non
+// expirée pour celle-ci, désignée par l'attribut "licence" de son certificat et liée à son identité.
+// Une licence non encore liée n'est acceptée que sans BlocageLicencesNonLiees, et son usage journalisé.
+func verifierLicence(ctx contractapi.TransactionContextInterface, role string) error {
+	if _, reglementee := ordresProfessionnels[role]; !reglementee {
+		return nil
+	}
+	numero, _, err := ctx.GetClientIdentity().GetAttributeValue("licence")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	if numero == "" {
+		return nouvelleErreur(CodeLicenceInvalide, "le rôle %s requiert une licence, absente du certificat", role)
+	}
+	licence, err := lireLicence(ctx, numero)
+	if err != nil {
+		return err
+	}
+	if licence == nil || licence.Profession != role {
+		return nouvelleErreur(CodeLicenceInvalide, "la licence %s n'est pas enregistrée pour la profession %s", numero, role)
+	}
+	if licence.Statut != LicenceActive {
+		return nouvelleErreur(CodeLicenceInvalide, "la licence %s est %s", numero, licence.Statut)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if licence.Expiration < maintenant.Format(FormatDate) {
+		return nouvelleErreur(CodeLicenceInvalide, "la licence %s a expiré le %s", numero, licence.Expiration)
+	}
+
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if licence.Identite != "" {
+		if licence.Identite != identite {
+			return nouvelleErreur(CodeLicenceInvalide, "la licence %s est liée à une autre identité", numero)
+		}
+		return nil
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if config.BlocageLicencesNonLiees {
+		return nouvelleErreur(CodeLicenceInvalide, "la licence %s n'est liée à aucune identité", numero)
+	}
+	journalTx(ctx).Warn("licence non liée à l'identité de son titulaire", slog.String("licence", numero), slog.String("identite", identite))
+	return nil
+}
+
+func lireLicence(ctx contractapi.TransactionContextInterface, numero string) (*Licence, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLicence, []string{numero})
+	if err != nil {
+		return nil, err
+	}
+	var licence Licence
+	existe, err := lireEtat(ctx, cle, &licence)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &licence, nil
+}
+
+func sauvegarderLicence(ctx contractapi.TransactionContextInterface, licence *Licence) error {
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	licence.MisAJourPar = agent
+	licence.MisAJourLe = maintenant.Format(time.RFC3339)
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLicence, []string{licence.Numero})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, licence)
+}
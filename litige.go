@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des signalements d'occupation et des litiges (idTitre~id)
+const (
+	PrefixeSignalementOccupation = "SIGNALEMENT_OCCUPATION"
+	PrefixeLitige                = "LITIGE"
+)
+
+// Décisions possibles du conservateur selon le statut d'un signalement
+var transitionsSignalement = map[string][]string{
+	SignalementRecu:          {SignalementEnInstruction, SignalementClasse},
+	SignalementEnInstruction: {SignalementClasse, SignalementEscalade},
+}
+
+// Signaler l'occupation illégale d'une parcelle (réservé aux citoyens enrôlés par la passerelle) ;
+// le signalement rejoint la file d'instruction des conservateurs
+func (s *SmartContract) SignalerOccupationIllegale(ctx contractapi.TransactionContextInterface, idTitre string, description string, preuveHash string) (string, error) {
+	if err := verifierMSP(ctx, MSPPasserelle); err != nil {
+		return "", err
+	}
+	if description == "" {
+		return "", fmt.Errorf("la description de l'occupation est obligatoire")
+	}
+	preuveHash = strings.ToLower(strings.TrimSpace(preuveHash))
+	if !hashValide(preuveHash) {
+		return "", nouvelleErreur(CodeRequeteInvalide, "hash de la preuve invalide (SHA-1 ou SHA-256 attendu): %s", preuveHash)
+	}
+
+	if _, err := s.LireTitreFoncier(ctx, idTitre); err != nil {
+		return "", err
+	}
+	signalant, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	signalement := &SignalementOccupation{
+		Id:          ctx.GetStub().GetTxID(),
+		IdTitre:     idTitre,
+		Description: description,
+		PreuveHash:  preuveHash,
+		Signalant:   signalant,
+		Statut:      SignalementRecu,
+		DeposeLe:    maintenant.Format(time.RFC3339),
+	}
+	if err := sauvegarderSignalementOccupation(ctx, signalement); err != nil {
+		return "", err
+	}
+
+	return signalement.Id, nil
+}
+
+// Faire avancer l'instruction d'un signalement (réservé aux conservateurs du bureau du titre) :
+// RECU → EN_INSTRUCTION ou CLASSE, puis EN_INSTRUCTION → CLASSE ou ESCALADE ; l'escalade ouvre un litige
+func (s *SmartContract) InstruireSignalementOccupation(ctx contractapi.TransactionContextInterface, idTitre string, idSignalement string, statut string, motif string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	signalement, err := s.LireSignalementOccupation(ctx, idTitre, idSignalement)
+	if err != nil {
+		return err
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAffectation(ctx, DossierSignalement, idSignalement, titre); err != nil {
+		return err
+	}
+	if !transitionSignalement(signalement.Statut, statut) {
+		return fmt.Errorf("le signalement %s ne peut passer de %s à %s", idSignalement, signalement.Statut, statut)
+	}
+	if statut != SignalementEnInstruction && motif == "" {
+		return fmt.Errorf("le motif de la décision est obligatoire")
+	}
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	if statut == SignalementEscalade {
+		litige, err := ouvrirLitige(ctx, idTitre, "SIGNALEMENT:"+idSignalement, signalement.Description)
+		if err != nil {
+			return err
+		}
+		signalement.Litige = litige.Id
+	}
+	signalement.Statut = statut
+	signalement.Motif = motif
+	signalement.InstruitPar = conservateur
+	return sauvegarderSignalementOccupation(ctx, signalement)
+}
+
+// Lire un signalement d'occupation illégale
+func (s *SmartContract) LireSignalementOccupation(ctx contractapi.TransactionContextInterface, idTitre string, idSignalement string) (*SignalementOccupation, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSignalementOccupation, []string{idTitre, idSignalement})
+	if err != nil {
+		return nil, err
+	}
+	var signalement SignalementOccupation
+	existe, err := lireEtat(ctx, cle, &signalement)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("le signalement %s n'existe pas sur le titre foncier %s", idSignalement, idTitre)
+	}
+	return &signalement, nil
+}
+
+// File d'instruction des signalements d'occupation ayant le statut demandé (tous si vide)
+func (s *SmartContract) GetSignalementsOccupation(ctx contractapi.TransactionContextInterface, statut string) ([]*SignalementOccupation, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSignalementOccupation, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var signalements []*SignalementOccupation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var signalement SignalementOccupation
+		err = decoderEtat(queryResponse.Value, &signalement)
+		if err != nil {
+			return nil, err
+		}
+		if statut != "" && signalement.Statut != statut {
+			continue
+		}
+		signalements = append(signalements, &signalement)
+	}
+
+	return signalements, nil
+}
+
+// Lire un litige foncier
+func (s *SmartContract) LireLitige(ctx contractapi.TransactionContextInterface, idTitre string, idLitige string) (*Litige, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLitige, []string{idTitre, idLitige})
+	if err != nil {
+		return nil, err
+	}
+	var litige Litige
+	existe, err := lireEtat(ctx, cle, &litige)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("le litige %s n'existe pas sur le titre foncier %s", idLitige, idTitre)
+	}
+	return &litige, nil
+}
+
+// Litiges d'un titre, ouverts ou clos
+func (s *SmartContract) GetLitigesTitre(ctx contractapi.TransactionContextInterface, idTitre string) ([]*Litige, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeLitige, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	litiges := []*Litige{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var litige Litige
+		if err := decoderEtat(queryResponse.Value, &litige); err != nil {
+			return nil, err
+		}
+		litiges = append(litiges, &litige)
+	}
+
+	return litiges, nil
+}
+
+// Ouvrir un litige sur un titre, identifié par la transaction courante
+func ouvrirLitige(ctx contractapi.TransactionContextInterface, idTitre string, origine string, objet string) (*Litige, error) {
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	litige := &Litige{
+		Id:       ctx.GetStub().GetTxID(),
+		IdTitre:  idTitre,
+		Origine:  origine,
+		Objet:    objet,
+		Statut:   LitigeOuvert,
+		OuvertLe: maintenant.Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLitige, []string{idTitre, litige.Id})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, litige); err != nil {
+		return nil, err
+	}
+	if err := suivreStatut(ctx, DossierLitige, litige.Id, idTitre, litige.Statut); err != nil {
+		return nil, err
+	}
+	if err := inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteLitigeOuvert, Objet: litige.Id}); err != nil {
+		return nil, err
+	}
+	if err := alerterBanques(ctx, idTitre, AlerteLitige); err != nil {
+		return nil, err
+	}
+	return litige, nil
+}
+
+func transitionSignalement(actuel string, statut string) bool {
+	for _, suivant := range transitionsSignalement[actuel] {
+		if suivant == statut {
+			return true
+		}
+	}
+	return false
+}
+
+func sauvegarderSignalementOccupation(ctx contractapi.TransactionContextInterface, signalement *SignalementOccupation) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSignalementOccupation, []string{signalement.IdTitre, signalement.Id})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, signalement); err != nil {
+		return err
+	}
+
+	return suivreStatut(ctx, DossierSignalement, signalement.Id, signalement.IdTitre, signalement.Statut)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+)
+
+// Version du schéma des enregistrements stockés sur le registre
+const VersionSchema = 2
+
+// Commit Git du build, renseigné via -ldflags "-X main.CommitGit=<sha>"
+var CommitGit = "inconnu"
+
+// Fonctionnalités activées dans cette version du contrat
+var fonctionnalites = []string{
+	"domaine-public",
+	"assurances-titre",
+	"evaluations",
+	"transferts",
+	"ventes-a-temperament",
+	"promesses-de-vente",
+	"autorites-emettrices",
+	"configuration-par-canal",
+	"mutations-inter-canal",
+	"annulation-mutations",
+	"serialisation-msgpack",
+	"conservation-champs-inconnus",
+	"recherche-triee",
+	"verification-documents",
+	"fusion-proprietaires",
+	"recherche-nom-proprietaire",
+	"bureaux-fonciers",
+	"endossement-renforce",
+	"archivage-deux-conservateurs",
+	"abonnements",
+	"signalement-documents",
+	"saisies-conservatoires",
+	"lecture-a-la-date",
+	"comparaison-versions",
+	"quotas-operations",
+	"consultation-motivee",
+	"doublons-numtf",
+	"contacts-proprietaires",
+	"attestations-propriete",
+	"beneficiaires-effectifs",
+	"remplacement-documents",
+	"regles-transfert",
+	"conversion-actes-occupation",
+	"resume-transactions",
+	"enveloppe-reponses",
+	"audit-coherence",
+	"morcellement",
+	"rang-hypotheques",
+	"consentement-creanciers",
+	"realisation-hypotheques",
+	"sequestre-transferts",
+	"detachement-parcelles",
+	"copropriete",
+	"demembrement",
+	"constats-terrain",
+	"signalements-occupation",
+	"approbations",
+	"balayage-echeances",
+	"specification-openapi",
+	"nonces-passerelle",
+	"licences-professionnelles",
+	"rapports-periodiques",
+	"titres-dormants",
+	"surveillance-garanties",
+	"decisions-judiciaires",
+	"controle-documents",
+	"documents-separes",
+	"documents-par-cle",
+	"reconstruction-index",
+	"jobs-administration",
+	"dossier-complet",
+	"plafonds-zones",
+	"reserves-emprise",
+	"permis-construire",
+	"certificats-conformite",
+	"cosignature-transferts",
+	"journal-communes",
+	"echantillonnage-audit",
+	"statistiques-agents",
+	"files-attente",
+	"affectation-dossiers",
+	"delais-traitement",
+	"facturation",
+	"remboursements",
+	"ecritures-comptables",
+	"index-spatial",
+	"systemes-coordonnees",
+	"topologie-geometries",
+	"rectification-bornage",
+	"historique-geometries",
+	"lots-numerisation",
+	"double-saisie-archives",
+	"qualite-titres",
+	"anciennes-references",
+	"caviardage-listes",
+	"consentements-partage",
+	"consultations-anonymes",
+	"mutations-signees",
+	"attestations-signees",
+}
+
+// Transactions de consultation, marquées "evaluate" dans les métadonnées du contrat ; toutes les autres,
+// y compris la consultation motivée qui trace l'accès, doivent être soumises
+var transactionsConsultation = []string{
+	"AuditerCoherence",
+	"CertificatImporte",
+	"ComparerVersions",
+	"DetecterChevauchements",
+	"DetecterDoublonsNumTF",
+	"DetecterTitresDormants",
+	"ExtraireEcrituresComptables",
+	"ExtraireRapportPeriode",
+	"GetAlertesBanque",
+	"GetAllTitresFonciers",
+	"GetAssurancesTitre",
+	"GetAttestationsEnAttenteGeometre",
+	"GetAutorites",
+	"GetCertificatsConformite",
+	"GetConsentements",
+	"GetConstatsTerrain",
+	"GetDeclarationsBeneficiaires",
+	"GetDossiersEnAttenteConservateur",
+	"GetDossiersEnRetard",
+	"GetDoublesSaisiesDivergentes",
+	"GetEcheancesTransfert",
+	"GetEmprisesTitre",
+	"GetFusionsProprietaire",
+	"GetHistoriqueEvaluations",
+	"GetInfoChaine",
+	"GetJournalAcces",
+	"GetLitigesTitre",
+	"GetLotsCopropriete",
+	"GetMetadata",
+	"GetModificationsCommune",
+	"GetPermisConstruire",
+	"GetQuotasCommune",
+	"GetRangHypotheques",
+	"GetRemboursementsFacture",
+	"GetSaisiesConservatoires",
+	"GetSignalementsDocuments",
+	"GetSignalementsOccupation",
+	"GetSignalementsQuotas",
+	"GetStatistiquesAgents",
+	"GetTitresFonciersPagines",
+	"GetTitresParNomProprio",
+	"GetTitresParPrefixeNomProprio",
+	"GetTransfertsEnAttenteNotaire",
+	"ImportRenonce",
+	"LireAbonnement",
+	"LireActeOccupation",
+	"LireAffectationDossier",
+	"LireApprobation",
+	"LireAutorite",
+	"LireBeneficiairesEffectifs",
+	"LireConfiguration",
+	"LireContact",
+	"LireCopropriete",
+	"LireDecisionJudiciaire",
+	"LireDemandeArchivage",
+	"LireDossierTitre",
+	"LireDoubleSaisie",
+	"LireEchantillonAudit",
+	"LireFacture",
+	"LireGeometrieALaDate",
+	"LireJob",
+	"LireLicence",
+	"LireLitige",
+	"LireLotCopropriete",
+	"LireLotNumerisation",
+	"LirePromesse",
+	"LireRealisation",
+	"LireReconstructionIndex",
+	"LireRectificationBornage",
+	"LireRemboursement",
+	"LireReserveEmprise",
+	"LireResumeTransaction",
+	"LireSaisieConservatoire",
+	"LireSequestre",
+	"LireSignalementOccupation",
+	"LireSuiviStatut",
+	"LireTitreALaDate",
+	"LireTitreFoncier",
+	"LireTransfert",
+	"LireVersionTitre",
+	"MesurerHistoriques",
+	"Ping",
+	"RechercherParAncienneReference",
+	"RechercherParHashDocument",
+	"RechercherTitres",
+	"TitresParQualite",
+	"ValiderSansEcrire",
+	"VerifierAttestation",
+	"VerifierCertificatMutation",
+	"VerifierFichierNumerise",
+}
+
+// Présentation du contrat dans ses métadonnées (org.hyperledger.fabric:GetMetadata)
+func infoContrat() metadata.InfoMetadata {
+	return metadata.InfoMetadata{
+		Title:       "titrefoncier",
+		Description: "Registre des titres fonciers : immatriculation, mutations, charges et procédures",
+		Version:     VersionContrat,
+	}
+}
+
+// Transactions à évaluer plutôt qu'à soumettre (contractapi.EvaluationContractInterface)
+func (s *SmartContract) GetEvaluateTransactions() []string {
+	return transactionsConsultation
+}
+
+// Métadonnées de déploiement exposées aux opérateurs et passerelles
+type Metadonnees struct {
+	VersionContrat  string   `json:"versionContrat"`
+	CommitGit       string   `json:"commitGit"`
+	VersionSchema   int      `json:"versionSchema"`
+	AlgorithmesHash []string `json:"algorithmesHash"`
+	Fonctionnalites []string `json:"fonctionnalites"`
+}
+
+// Vérifier que le chaincode répond
+func (s *SmartContract) Ping(ctx contractapi.TransactionContextInterface) (string, error) {
+	return "pong", nil
+}
+
+// Lire les métadonnées de déploiement du chaincode
+func (s *SmartContract) GetMetadata(ctx contractapi.TransactionContextInterface) (*Metadonnees, error) {
+	return &Metadonnees{
+		VersionContrat:  VersionContrat,
+		CommitGit:       CommitGit,
+		VersionSchema:   VersionSchema,
+		AlgorithmesHash: []string{"SHA-1", "SHA-256"},
+		Fonctionnalites: fonctionnalites,
+	}, nil
+}
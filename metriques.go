@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Bornes des histogrammes exposés au format Prometheus
+var (
+	bornesLatence    = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	bornesIterateurs = []float64{1, 5, 10, 50, 100, 500, 1000, 5000}
+)
+
+// Code d'erreur en tête des messages d'erreur métier ("CODE: message")
+var motifCodeErreur = regexp.MustCompile(`^([A-Z][A-Z_]+): `)
+
+// Étiquette des invocations d'une fonction que le contrat n'expose pas : le nom fourni par le client
+// ne crée pas de série
+const FonctionInconnue = "inconnue"
+
+// Transactions du contrat : méthodes exportées de SmartContract, hors celles de contractapi.Contract
+var transactionsContrat = func() map[string]bool {
+	herites := make(map[string]bool)
+	typeContrat := reflect.TypeOf(&contractapi.Contract{})
+	for i := 0; i < typeContrat.NumMethod(); i++ {
+		herites[typeContrat.Method(i).Name] = true
+	}
+	transactions := make(map[string]bool)
+	typeSmartContract := reflect.TypeOf(&SmartContract{})
+	for i := 0; i < typeSmartContract.NumMethod(); i++ {
+		if nom := typeSmartContract.Method(i).Name; !herites[nom] {
+			transactions[nom] = true
+		}
+	}
+	return transactions
+}()
+
+// Étiquette d'une fonction invoquée, sans le nom du contrat qui la préfixe éventuellement
+func etiquetteFonction(fonction string) string {
+	nom := fonction[strings.LastIndex(fonction, ":")+1:]
+	if !transactionsContrat[nom] {
+		return FonctionInconnue
+	}
+	return nom
+}
+
+// Histogramme cumulatif au sens Prometheus
+type histogramme struct {
+	bornes   []float64
+	comptes  []uint64
+	somme    float64
+	effectif uint64
+}
+
+func nouvelHistogramme(bornes []float64) *histogramme {
+	return &histogramme{bornes: bornes, comptes: make([]uint64, len(bornes))}
+}
+
+func (h *histogramme) observer(valeur float64) {
+	for i, borne := range h.bornes {
+		if valeur <= borne {
+			h.comptes[i]++
+		}
+	}
+	h.somme += valeur
+	h.effectif++
+}
+
+func (h *histogramme) ecrire(b *strings.Builder, nom string, etiquettes string) {
+	separateur := ""
+	if etiquettes != "" {
+		separateur = ","
+	}
+	for i, borne := range h.bornes {
+		fmt.Fprintf(b, "%s_bucket{%s%sle=\"%g\"} %d\n", nom, etiquettes, separateur, borne, h.comptes[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%s%sle=\"+Inf\"} %d\n", nom, etiquettes, separateur, h.effectif)
+	fmt.Fprintf(b, "%s_sum{%s} %g\n", nom, etiquettes, h.somme)
+	fmt.Fprintf(b, "%s_count{%s} %d\n", nom, etiquettes, h.effectif)
+}
+
+// Compteurs d'activité du chaincode en mode service externe
+type metriques struct {
+	mu          sync.Mutex
+	invocations map[string]uint64
+	erreurs     map[[2]string]uint64 // (fonction, code d'erreur)
+	latences    map[string]*histogramme
+	iterateurs  *histogramme
+}
+
+var metriquesChaincode = &metriques{
+	invocations: map[string]uint64{},
+	erreurs:     map[[2]string]uint64{},
+	latences:    map[string]*histogramme{},
+	iterateurs:  nouvelHistogramme(bornesIterateurs),
+}
+
+func (m *metriques) observerInvocation(fonction string, duree time.Duration, reponse pb.Response) {
+	fonction = etiquetteFonction(fonction)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.invocations[fonction]++
+	latence, ok := m.latences[fonction]
+	if !ok {
+		latence = nouvelHistogramme(bornesLatence)
+		m.latences[fonction] = latence
+	}
+	latence.observer(duree.Seconds())
+
+	if reponse.Status >= shim.ERRORTHRESHOLD {
+		code := "AUTRE"
+		if correspondance := motifCodeErreur.FindStringSubmatch(reponse.Message); correspondance != nil {
+			code = correspondance[1]
+		}
+		m.erreurs[[2]string{fonction, code}]++
+	}
+}
+
+func (m *metriques) observerIterateur(taille int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iterateurs.observer(float64(taille))
+}
+
+// Exposer les métriques au format texte Prometheus
+func (m *metriques) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP titrefoncier_invocations_total Nombre d'invocations par fonction.\n")
+	b.WriteString("# TYPE titrefoncier_invocations_total counter\n")
+	for _, fonction := range clesTriees(m.invocations) {
+		fmt.Fprintf(&b, "titrefoncier_invocations_total{fonction=%q} %d\n", fonction, m.invocations[fonction])
+	}
+
+	b.WriteString("# HELP titrefoncier_erreurs_total Nombre d'échecs par fonction et code d'erreur.\n")
+	b.WriteString("# TYPE titrefoncier_erreurs_total counter\n")
+	var erreurs [][2]string
+	for cle := range m.erreurs {
+		erreurs = append(erreurs, cle)
+	}
+	sort.Slice(erreurs, func(i, j int) bool {
+		if erreurs[i][0] != erreurs[j][0] {
+			return erreurs[i][0] < erreurs[j][0]
+		}
+		return erreurs[i][1] < erreurs[j][1]
+	})
+	for _, cle := range erreurs {
+		fmt.Fprintf(&b, "titrefoncier_erreurs_total{fonction=%q,code=%q} %d\n", cle[0], cle[1], m.erreurs[cle])
+	}
+
+	b.WriteString("# HELP titrefoncier_latence_secondes Durée d'exécution des transactions.\n")
+	b.WriteString("# TYPE titrefoncier_latence_secondes histogram\n")
+	for _, fonction := range clesTriees(m.latences) {
+		m.latences[fonction].ecrire(&b, "titrefoncier_latence_secondes", fmt.Sprintf("fonction=%q", fonction))
+	}
+
+	b.WriteString("# HELP titrefoncier_taille_iterateurs Nombre d'éléments lus par itérateur du registre.\n")
+	b.WriteString("# TYPE titrefoncier_taille_iterateurs histogram\n")
+	m.iterateurs.ecrire(&b, "titrefoncier_taille_iterateurs", "")
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+func clesTriees[V any](m map[string]V) []string {
+	cles := make([]string, 0, len(m))
+	for cle := range m {
+		cles = append(cles, cle)
+	}
+	sort.Strings(cles)
+	return cles
+}
+
+// Chaincode enveloppé pour mesurer et journaliser chaque invocation
+type chaincodeInstrumente struct {
+	cc shim.Chaincode
+}
+
+func instrumenter(cc shim.Chaincode) shim.Chaincode {
+	return &chaincodeInstrumente{cc: cc}
+}
+
+func (c *chaincodeInstrumente) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return c.cc.Init(stub)
+}
+
+func (c *chaincodeInstrumente) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	fonction, _ := stub.GetFunctionAndParameters()
+	debut := time.Now()
+	reponse := c.cc.Invoke(&stubInstrumente{ChaincodeStubInterface: stub})
+	duree := time.Since(debut)
+
+	metriquesChaincode.observerInvocation(fonction, duree, reponse)
+
+	journalTransaction := journalStub(stub).With(slog.Duration("duree", duree), slog.Int("statut", int(reponse.Status)))
+	if reponse.Status >= shim.ERRORTHRESHOLD {
+		journalTransaction.Warn("transaction rejetée", slog.String("erreur", reponse.Message))
+	} else {
+		journalTransaction.Info("transaction exécutée")
+	}
+
+	return reponse
+}
+
+// Stub comptant les éléments parcourus par les itérateurs
+type stubInstrumente struct {
+	shim.ChaincodeStubInterface
+}
+
+func (s *stubInstrumente) GetStateByRange(debut, fin string) (shim.StateQueryIteratorInterface, error) {
+	it, err := s.ChaincodeStubInterface.GetStateByRange(debut, fin)
+	return compterEtats(it), err
+}
+
+func (s *stubInstrumente) GetStateByRangeWithPagination(debut, fin string, taillePage int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	it, meta, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(debut, fin, taillePage, bookmark)
+	return compterEtats(it), meta, err
+}
+
+func (s *stubInstrumente) GetStateByPartialCompositeKey(typeObjet string, cles []string) (shim.StateQueryIteratorInterface, error) {
+	it, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKey(typeObjet, cles)
+	return compterEtats(it), err
+}
+
+func (s *stubInstrumente) GetStateByPartialCompositeKeyWithPagination(typeObjet string, cles []string, taillePage int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	it, meta, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(typeObjet, cles, taillePage, bookmark)
+	return compterEtats(it), meta, err
+}
+
+func (s *stubInstrumente) GetQueryResult(requete string) (shim.StateQueryIteratorInterface, error) {
+	it, err := s.ChaincodeStubInterface.GetQueryResult(requete)
+	return compterEtats(it), err
+}
+
+func (s *stubInstrumente) GetQueryResultWithPagination(requete string, taillePage int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	it, meta, err := s.ChaincodeStubInterface.GetQueryResultWithPagination(requete, taillePage, bookmark)
+	return compterEtats(it), meta, err
+}
+
+func (s *stubInstrumente) GetHistoryForKey(cle string) (shim.HistoryQueryIteratorInterface, error) {
+	it, err := s.ChaincodeStubInterface.GetHistoryForKey(cle)
+	if it == nil {
+		return nil, err
+	}
+	return &iterateurHistoriqueCompte{HistoryQueryIteratorInterface: it}, err
+}
+
+func compterEtats(it shim.StateQueryIteratorInterface) shim.StateQueryIteratorInterface {
+	if it == nil {
+		return nil
+	}
+	return &iterateurEtatsCompte{StateQueryIteratorInterface: it}
+}
+
+type iterateurEtatsCompte struct {
+	shim.StateQueryIteratorInterface
+	lus int
+}
+
+func (it *iterateurEtatsCompte) Next() (*queryresult.KV, error) {
+	it.lus++
+	return it.StateQueryIteratorInterface.Next()
+}
+
+func (it *iterateurEtatsCompte) Close() error {
+	metriquesChaincode.observerIterateur(it.lus)
+	return it.StateQueryIteratorInterface.Close()
+}
+
+type iterateurHistoriqueCompte struct {
+	shim.HistoryQueryIteratorInterface
+	lus int
+}
+
+func (it *iterateurHistoriqueCompte) Next() (*queryresult.KeyModification, error) {
+	it.lus++
+	return it.HistoryQueryIteratorInterface.Next()
+}
+
+func (it *iterateurHistoriqueCompte) Close() error {
+	metriquesChaincode.observerIterateur(it.lus)
+	return it.HistoryQueryIteratorInterface.Close()
+}
+
+// Servir les métriques sur l'adresse indiquée (ex: ":9443")
+func servirMetriques(adresse string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metriquesChaincode)
+	return http.ListenAndServe(adresse, mux)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Les noms de fonction hors contrat partagent une seule série
+func TestMetriquesFonctionsInconnues(t *testing.T) {
+	m := &metriques{invocations: map[string]uint64{}, erreurs: map[[2]string]uint64{}, latences: map[string]*histogramme{}}
+	for i := 0; i < 50; i++ {
+		m.observerInvocation(fmt.Sprintf("Fonction%d", i), time.Millisecond, shim.Error("Function not found"))
+	}
+	m.observerInvocation("SmartContract:LireTitreFoncier", time.Millisecond, shim.Success(nil))
+	m.observerInvocation("GetName", time.Millisecond, shim.Success(nil))
+
+	if len(m.invocations) != 2 || m.invocations[FonctionInconnue] != 51 || m.invocations["LireTitreFoncier"] != 1 {
+		t.Errorf("séries d'invocations: %v", m.invocations)
+	}
+	if len(m.latences) != 2 || len(m.erreurs) != 1 {
+		t.Errorf("%d séries de latence et %d d'erreurs, 2 et 1 attendues", len(m.latences), len(m.erreurs))
+	}
+}
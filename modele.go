@@ -0,0 +1,394 @@
+package main
+
+import "titrefoncier/pkg/model"
+
+// Types du modèle partagé avec les applications clientes (pkg/model)
+type (
+	TitreFoncier             = model.TitreFoncier
+	Charge                   = model.Charge
+	DocumentTitre            = model.DocumentTitre
+	Transfert                = model.Transfert
+	Echeance                 = model.Echeance
+	Promesse                 = model.Promesse
+	AssuranceTitre           = model.AssuranceTitre
+	Evaluation               = model.Evaluation
+	AutoriteEmettrice        = model.AutoriteEmettrice
+	Configuration            = model.Configuration
+	CertificatMutation       = model.CertificatMutation
+	DossierTitre             = model.DossierTitre
+	ResultatRecherche        = model.ResultatRecherche
+	VerificationDocument     = model.VerificationDocument
+	VerificationAttestation  = model.VerificationAttestation
+	FusionProprietaires      = model.FusionProprietaires
+	ContactProprietaire      = model.ContactProprietaire
+	BeneficiaireEffectif     = model.BeneficiaireEffectif
+	DeclarationBeneficiaires = model.DeclarationBeneficiaires
+	DemandeArchivage         = model.DemandeArchivage
+	Abonnement               = model.Abonnement
+	Declenchement            = model.Declenchement
+	AlerteAbonnement         = model.AlerteAbonnement
+	SignalementDocument      = model.SignalementDocument
+	SaisieConservatoire      = model.SaisieConservatoire
+	VersionTitre             = model.VersionTitre
+	VersionGeometrie         = model.VersionGeometrie
+	ChangementChamp          = model.ChangementChamp
+	ComparaisonVersions      = model.ComparaisonVersions
+	CompteurAppels           = model.CompteurAppels
+	SignalementQuota         = model.SignalementQuota
+	AccesTitre               = model.AccesTitre
+	ConsultationDeclaree     = model.ConsultationDeclaree
+	DoublonNumTF             = model.DoublonNumTF
+	RapportDoublons          = model.RapportDoublons
+	ResolutionDoublon        = model.ResolutionDoublon
+	Attestation              = model.Attestation
+	RegleTransfert           = model.RegleTransfert
+	QualitesProprietaire     = model.QualitesProprietaire
+	ActeOccupation           = model.ActeOccupation
+	ModificationCle          = model.ModificationCle
+	ResumeTransaction        = model.ResumeTransaction
+	EvenementJoint           = model.EvenementJoint
+	Enveloppe                = model.Enveloppe
+	AnomalieCoherence        = model.AnomalieCoherence
+	RapportCoherence         = model.RapportCoherence
+	LotMorcellement          = model.LotMorcellement
+	ConsentementDetachement  = model.ConsentementDetachement
+	ConsentementCreancier    = model.ConsentementCreancier
+	RegleMorcellement        = model.RegleMorcellement
+	SubordinationHypotheque  = model.SubordinationHypotheque
+	DemandeConsentement      = model.DemandeConsentement
+	RealisationHypotheque    = model.RealisationHypotheque
+	EtapeRealisation         = model.EtapeRealisation
+	RepartitionPrix          = model.RepartitionPrix
+	Sequestre                = model.Sequestre
+	DepotSequestre           = model.DepotSequestre
+	Copropriete              = model.Copropriete
+	PartieCommune            = model.PartieCommune
+	LotCopropriete           = model.LotCopropriete
+	DescriptifCopropriete    = model.DescriptifCopropriete
+	Usufruit                 = model.Usufruit
+	ConstatTerrain           = model.ConstatTerrain
+	SignalementOccupation    = model.SignalementOccupation
+	Litige                   = model.Litige
+	QuorumApprobation        = model.QuorumApprobation
+	Approbation              = model.Approbation
+	DecisionApprobation      = model.DecisionApprobation
+	RappelEcheance           = model.RappelEcheance
+	ResultatBalayage         = model.ResultatBalayage
+	NonceTitulaire           = model.NonceTitulaire
+	Licence                  = model.Licence
+	RapportPeriode           = model.RapportPeriode
+	ActiviteDatee            = model.ActiviteDatee
+	SurveillanceGarantie     = model.SurveillanceGarantie
+	AlerteBanque             = model.AlerteBanque
+	PageAlertesBanque        = model.PageAlertesBanque
+	AlertesBanques           = model.AlertesBanques
+	DecisionJudiciaire       = model.DecisionJudiciaire
+	ExecutionDecision        = model.ExecutionDecision
+	MesureHistorique         = model.MesureHistorique
+	PageMesuresHistorique    = model.PageMesuresHistorique
+	Job                      = model.Job
+	DossierImmatriculation   = model.DossierImmatriculation
+	ProprietaireDossier      = model.ProprietaireDossier
+	PieceDossier             = model.PieceDossier
+	PlafondZone              = model.PlafondZone
+	AllocationTitre          = model.AllocationTitre
+	QuotaZone                = model.QuotaZone
+	QuotasCommune            = model.QuotasCommune
+	ReserveEmprise           = model.ReserveEmprise
+	PermisConstruire         = model.PermisConstruire
+	CertificatConformite     = model.CertificatConformite
+	ModificationCommune      = model.ModificationCommune
+	FluxModifications        = model.FluxModifications
+	OperationAppliquee       = model.OperationAppliquee
+	EchantillonAudit         = model.EchantillonAudit
+	EngagementGraine         = model.EngagementGraine
+	TraitementAgent          = model.TraitementAgent
+	StatistiquesAgent        = model.StatistiquesAgent
+	StatistiquesAgents       = model.StatistiquesAgents
+	DossiersEnAttente        = model.DossiersEnAttente
+	AffectationDossier       = model.AffectationDossier
+	EtapeAffectation         = model.EtapeAffectation
+	DelaiTraitement          = model.DelaiTraitement
+	PassageStatut            = model.PassageStatut
+	SuiviStatut              = model.SuiviStatut
+	DossierEnRetard          = model.DossierEnRetard
+	EscaladeDossiers         = model.EscaladeDossiers
+	TarifFrais               = model.TarifFrais
+	LigneFacture             = model.LigneFacture
+	Facture                  = model.Facture
+	Remboursement            = model.Remboursement
+	ComptesComptables        = model.ComptesComptables
+	EcritureComptable        = model.EcritureComptable
+	PageEcrituresComptables  = model.PageEcrituresComptables
+	DemandeBornage           = model.DemandeBornage
+	ParcelleBornage          = model.ParcelleBornage
+	RectificationBornage     = model.RectificationBornage
+	ParcelleRectifiee        = model.ParcelleRectifiee
+	SignatureBornage         = model.SignatureBornage
+	LotNumerisation          = model.LotNumerisation
+	EtapeMerkle              = model.EtapeMerkle
+	VerificationNumerisation = model.VerificationNumerisation
+	SaisieArchive            = model.SaisieArchive
+	DoubleSaisie             = model.DoubleSaisie
+	SaisieOperateur          = model.SaisieOperateur
+	ResolutionDoubleSaisie   = model.ResolutionDoubleSaisie
+	QualiteTitre             = model.QualiteTitre
+	AncienneReference        = model.AncienneReference
+	ReferenceRetiree         = model.ReferenceRetiree
+	RegleCaviardage          = model.RegleCaviardage
+	ConsentementPartage      = model.ConsentementPartage
+	ErreurMetier             = model.ErreurMetier
+)
+
+// Valeurs du format d'échange reprises du modèle partagé
+const (
+	FormatDate = model.FormatDate
+
+	ChargePromesseVente = model.ChargePromesseVente
+	ChargeHypotheque    = model.ChargeHypotheque
+	DocumentObsolete    = model.DocumentObsolete
+	DocTypeTitre        = model.DocTypeTitre
+
+	ModeComptant                = model.ModeComptant
+	ModeTemperament             = model.ModeTemperament
+	TransfertEnCosignature      = model.TransfertEnCosignature
+	TransfertEnAttente          = model.TransfertEnAttente
+	TransfertFinalise           = model.TransfertFinalise
+	TransfertAnnule             = model.TransfertAnnule
+	SignalementEcartEvaluation  = model.SignalementEcartEvaluation
+	EvenementTransfertSignale   = model.EvenementTransfertSignale
+	EvenementAlerteAbonnement   = model.EvenementAlerteAbonnement
+	AbonnementsNommesMax        = model.AbonnementsNommesMax
+	EvenementDocumentCorrompu   = model.EvenementDocumentCorrompu
+	EvenementAttestationEmise   = model.EvenementAttestationEmise
+	AttestationEmise            = model.AttestationEmise
+	AttestationExpiree          = model.AttestationExpiree
+	EvenementResumeTransaction  = model.EvenementResumeTransaction
+	ChampResumeTransaction      = model.ChampResumeTransaction
+	ChampEvenementsJoints       = model.ChampEvenementsJoints
+	TypeModificationTitre       = model.TypeModificationTitre
+	EvenementConsentementRequis = model.EvenementConsentementRequis
+	EvenementRappelsEcheances   = model.EvenementRappelsEcheances
+	PromesseActive              = model.PromesseActive
+	PromesseConvertie           = model.PromesseConvertie
+
+	ArchivageEnAttente = model.ArchivageEnAttente
+	ArchivageConfirme  = model.ArchivageConfirme
+
+	SaisieEnVigueur = model.SaisieEnVigueur
+	SaisieLevee     = model.SaisieLevee
+
+	AnomalieProprietaireManquant = model.AnomalieProprietaireManquant
+	AnomalieProprietaireFusionne = model.AnomalieProprietaireFusionne
+	AnomalieTantiemes            = model.AnomalieTantiemes
+	AnomalieBeneficiaires        = model.AnomalieBeneficiaires
+	AnomalieIndexManquant        = model.AnomalieIndexManquant
+	AnomalieIndexOrphelin        = model.AnomalieIndexOrphelin
+	AnomalieIndexPerime          = model.AnomalieIndexPerime
+
+	ActeDeliberation  = model.ActeDeliberation
+	ActePermisOccuper = model.ActePermisOccuper
+	ActeEnVigueur     = model.ActeEnVigueur
+	ActeConverti      = model.ActeConverti
+
+	RealisationDefaillance  = model.RealisationDefaillance
+	RealisationAutorisee    = model.RealisationAutorisee
+	RealisationEngagee      = model.RealisationEngagee
+	RealisationCloturee     = model.RealisationCloturee
+	RealisationAdjudication = model.RealisationAdjudication
+	RealisationAttribution  = model.RealisationAttribution
+
+	SequestreOuvert    = model.SequestreOuvert
+	SequestreLibere    = model.SequestreLibere
+	SequestreRembourse = model.SequestreRembourse
+	DepotPaiement      = model.DepotPaiement
+	DepotJeton         = model.DepotJeton
+
+	ConsentementUsufruitier = model.ConsentementUsufruitier
+
+	ConsentementRadiation     = model.ConsentementRadiation
+	ConsentementSubordination = model.ConsentementSubordination
+
+	PorteeTitre   = model.PorteeTitre
+	PorteeDossier = model.PorteeDossier
+
+	SignalementRecu          = model.SignalementRecu
+	SignalementEnInstruction = model.SignalementEnInstruction
+	SignalementClasse        = model.SignalementClasse
+	SignalementEscalade      = model.SignalementEscalade
+	LitigeOuvert             = model.LitigeOuvert
+	LitigeClos               = model.LitigeClos
+
+	WorkflowTransfert    = model.WorkflowTransfert
+	WorkflowMorcellement = model.WorkflowMorcellement
+	WorkflowZonage       = model.WorkflowZonage
+	ApprobationEnCours   = model.ApprobationEnCours
+	ApprobationAccordee  = model.ApprobationAccordee
+	ApprobationRejetee   = model.ApprobationRejetee
+	ApprobationUtilisee  = model.ApprobationUtilisee
+	DecisionApprouve     = model.DecisionApprouve
+	DecisionRejete       = model.DecisionRejete
+
+	TraitementImmatriculation = model.TraitementImmatriculation
+	TraitementDemande         = model.TraitementDemande
+	TraitementApprobation     = model.TraitementApprobation
+	TraitementRejet           = model.TraitementRejet
+	TraitementFinalisation    = model.TraitementFinalisation
+
+	AttenteTransfert   = model.AttenteTransfert
+	AttenteApprobation = model.AttenteApprobation
+
+	DossierTransfert          = model.DossierTransfert
+	DossierLitige             = model.DossierLitige
+	DossierSignalement        = model.DossierSignalement
+	DossierApprobation        = model.DossierApprobation
+	DossierAttestation        = model.DossierAttestation
+	EvenementDossiersEnRetard = model.EvenementDossiersEnRetard
+
+	FacturationImmatriculation = model.FacturationImmatriculation
+	FacturationTransfert       = model.FacturationTransfert
+	FactureEmise               = model.FactureEmise
+	FacturePayee               = model.FacturePayee
+
+	RemboursementPropose  = model.RemboursementPropose
+	RemboursementApprouve = model.RemboursementApprouve
+	RemboursementRejete   = model.RemboursementRejete
+
+	JournalFacturation   = model.JournalFacturation
+	JournalEncaissement  = model.JournalEncaissement
+	JournalRemboursement = model.JournalRemboursement
+
+	CrsWGS84  = model.CrsWGS84
+	CrsUTM28N = model.CrsUTM28N
+
+	BornagePropose         = model.BornagePropose
+	BornageApplique        = model.BornageApplique
+	BornageRefuse          = model.BornageRefuse
+	SignataireGeometre     = model.SignataireGeometre
+	SignataireProprietaire = model.SignataireProprietaire
+
+	FrereGauche = model.FrereGauche
+	FrereDroite = model.FrereDroite
+
+	DoubleSaisieAttente       = model.DoubleSaisieAttente
+	DoubleSaisieDivergente    = model.DoubleSaisieDivergente
+	DoubleSaisieActivee       = model.DoubleSaisieActivee
+	EvenementSaisieDivergente = model.EvenementSaisieDivergente
+
+	QualiteFaible  = model.QualiteFaible
+	QualiteMoyenne = model.QualiteMoyenne
+	QualiteBonne   = model.QualiteBonne
+
+	BalayagePromesses  = model.BalayagePromesses
+	BalayageUsufruits  = model.BalayageUsufruits
+	BalayageArchivages = model.BalayageArchivages
+
+	LicenceActive    = model.LicenceActive
+	LicenceSuspendue = model.LicenceSuspendue
+	LicenceRadiee    = model.LicenceRadiee
+
+	RapportTitres     = model.RapportTitres
+	RapportTransferts = model.RapportTransferts
+	RapportLitiges    = model.RapportLitiges
+	RapportFrais      = model.RapportFrais
+
+	ActiviteTitreCree         = model.ActiviteTitreCree
+	ActiviteTransfertPropose  = model.ActiviteTransfertPropose
+	ActiviteTransfertFinalise = model.ActiviteTransfertFinalise
+	ActiviteTransfertAnnule   = model.ActiviteTransfertAnnule
+	ActiviteLitigeOuvert      = model.ActiviteLitigeOuvert
+	ActiviteLitigeClos        = model.ActiviteLitigeClos
+	ActiviteFraisFactures     = model.ActiviteFraisFactures
+	ActiviteFraisEncaisses    = model.ActiviteFraisEncaisses
+	ActiviteFraisRembourses   = model.ActiviteFraisRembourses
+
+	EvenementAlerteBanque  = model.EvenementAlerteBanque
+	AlerteModification     = model.AlerteModification
+	AlerteTransfertPropose = model.AlerteTransfertPropose
+	AlerteLitige           = model.AlerteLitige
+
+	ExecutionGel         = model.ExecutionGel
+	ExecutionMainlevee   = model.ExecutionMainlevee
+	ExecutionTransfert   = model.ExecutionTransfert
+	ExecutionCloture     = model.ExecutionCloture
+	ExecutionRealisation = model.ExecutionRealisation
+
+	TypePDF  = model.TypePDF
+	TypeTIFF = model.TypeTIFF
+
+	PhaseReconstructionTitres  = model.PhaseReconstructionTitres
+	PhaseReconstructionEntrees = model.PhaseReconstructionEntrees
+
+	JobReconstructionIndex = model.JobReconstructionIndex
+	JobAuditCoherence      = model.JobAuditCoherence
+	JobRattachementBureaux = model.JobRattachementBureaux
+	JobMarquageTitres      = model.JobMarquageTitres
+	JobAllocationsZones    = model.JobAllocationsZones
+	JobIndexActivite       = model.JobIndexActivite
+	JobEscaladeRetards     = model.JobEscaladeRetards
+	JobSuiviStatuts        = model.JobSuiviStatuts
+	JobFilesAttente        = model.JobFilesAttente
+	JobAbonnements         = model.JobAbonnements
+	JobEchantillonAudit    = model.JobEchantillonAudit
+	JobEnCours             = model.JobEnCours
+	JobTermine             = model.JobTermine
+	JobAnnule              = model.JobAnnule
+
+	EchantillonEngagement = model.EchantillonEngagement
+	EchantillonRevelation = model.EchantillonRevelation
+	EchantillonTirage     = model.EchantillonTirage
+	EchantillonTire       = model.EchantillonTire
+
+	EmpriseActive            = model.EmpriseActive
+	EmpriseLevee             = model.EmpriseLevee
+	EvenementEmpriseReservee = model.EvenementEmpriseReservee
+
+	PermisDelivre    = model.PermisDelivre
+	PermisEnTravaux  = model.PermisEnTravaux
+	PermisConformite = model.PermisConformite
+
+	AssuranceActive   = model.AssuranceActive
+	AssuranceResiliee = model.AssuranceResiliee
+
+	AutoriteConservation = model.AutoriteConservation
+	AutoriteTribunal     = model.AutoriteTribunal
+	AutoriteMairie       = model.AutoriteMairie
+
+	SerialisationJSON    = model.SerialisationJSON
+	SerialisationMsgpack = model.SerialisationMsgpack
+
+	CodeAccesRefuse        = model.CodeAccesRefuse
+	CodeApprobationRequise = model.CodeApprobationRequise
+	CodeCertificatInvalide = model.CodeCertificatInvalide
+	CodeCommuneHorsCanal   = model.CodeCommuneHorsCanal
+	CodeConformiteRequise  = model.CodeConformiteRequise
+	CodeConsentementRequis = model.CodeConsentementRequis
+	CodeDecisionInconnue   = model.CodeDecisionInconnue
+	CodeDocumentRefuse     = model.CodeDocumentRefuse
+	CodeEmetteurNonReconnu = model.CodeEmetteurNonReconnu
+	CodeFactureImpayee     = model.CodeFactureImpayee
+	CodeLicenceInvalide    = model.CodeLicenceInvalide
+	CodeMorcellementRefuse = model.CodeMorcellementRefuse
+	CodeNonceInvalide      = model.CodeNonceInvalide
+	CodeParcelleChevauchee = model.CodeParcelleChevauchee
+	CodePlafondZoneAtteint = model.CodePlafondZoneAtteint
+	CodeQuotaDepasse       = model.CodeQuotaDepasse
+	CodeRequeteInvalide    = model.CodeRequeteInvalide
+	CodeTitreDemembre      = model.CodeTitreDemembre
+	CodeTitreEnCopropriete = model.CodeTitreEnCopropriete
+	CodeTitreEnRealisation = model.CodeTitreEnRealisation
+	CodeTitreInalienable   = model.CodeTitreInalienable
+	CodeTitreMorcele       = model.CodeTitreMorcele
+	CodeTitreMute          = model.CodeTitreMute
+	CodeTitreSaisi         = model.CodeTitreSaisi
+	CodeTitreSousEmprise   = model.CodeTitreSousEmprise
+	CodeTitreSousPromesse  = model.CodeTitreSousPromesse
+	CodeTransfertRestreint = model.CodeTransfertRestreint
+	CodeVersionPerimee     = model.CodeVersionPerimee
+	CodeSucces             = model.CodeSucces
+	CodeErreurInterne      = model.CodeErreurInterne
+
+	ClasseValidation = model.ClasseValidation
+	ClasseConflit    = model.ClasseConflit
+	ClasseMetier     = model.ClasseMetier
+)
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Origines portées par les titres issus d'un morcellement ou d'un détachement ("MORCELLEMENT:<titre mère>")
+const (
+	OrigineMorcellement = "MORCELLEMENT"
+	OrigineDetachement  = "DETACHEMENT"
+)
+
+// Préfixe des clés composites des consentements des propriétaires aux détachements (idTitre~acheteur)
+const PrefixeConsentementDetachement = "CONSENTEMENT_DETACHEMENT"
+
+// Morceler un titre foncier en lots immatriculés au nom du même propriétaire, avec les charges et
+// zonages du titre mère, qui est clos (réservé aux conservateurs). Les lots relèvent du bureau foncier
+// du titre mère et reçoivent les premiers numéros "<numéro du titre mère>-<n>" encore libres. Les
+// règles de morcellement du canal applicables aux zonages de la parcelle bornent le nombre et la
+// superficie des lots. Le contour d'un titre mère délimité est partagé entre les lots, sans
+// chevauchement entre eux ni débord.
+func (s *SmartContract) MorcelerTitre(ctx contractapi.TransactionContextInterface, idTitre string, lotsJSON string) ([]string, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+
+	var lots []LotMorcellement
+	if err := json.Unmarshal([]byte(lotsJSON), &lots); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "lots invalides: %v", err)
+	}
+	mere, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(lots))
+	for i, lot := range lots {
+		ids[i] = lot.Id
+	}
+	titres := make([]*TitreFoncier, len(lots))
+
+	var approbation *Approbation
+	operation := nouvelleOperation("morcellement")
+	operation.ajouter("titre mère "+idTitre, func() error {
+		if err := verifierBureau(ctx, mere); err != nil {
+			return err
+		}
+		if err := verifierAlienable(mere); err != nil {
+			return err
+		}
+		if err := verifierLots(mere, lots, config); err != nil {
+			return err
+		}
+		if err := verifierContoursLots(mere, titres); err != nil {
+			return err
+		}
+		approbation, err = verifierApprobation(ctx, WorkflowMorcellement, idTitre, lotsJSON)
+		return err
+	}, func() error {
+		mere.MorceleEn = ids
+		if err := consommerApprobation(ctx, approbation); err != nil {
+			return err
+		}
+		return sauvegarderTitre(ctx, mere)
+	})
+
+	suffixe := 0
+	for i, lot := range lots {
+		document, err := controlerDocument(ctx, lot.Document)
+		if err != nil {
+			return nil, err
+		}
+		numTF := ""
+		if mere.NumTF != "" {
+			if numTF, suffixe, err = numeroLotLibre(ctx, mere.NumTF, suffixe+1); err != nil {
+				return nil, err
+			}
+		}
+
+		titre := model.NouveauTitreFoncier(lot.Id, mere.Proprio, numTF, lot.Superficie, mere.Commune, lot.Document, document.hash)
+		titre.Charges = append([]Charge(nil), mere.Charges...)
+		titre.Zones = mere.Zones
+		titre.Origine = OrigineMorcellement + ":" + mere.Id
+		titre.BureauFoncier = mere.BureauFoncier
+		if len(lot.Geometrie) > 0 {
+			if titre.Geometrie, titre.SystemeCoordonnees, err = model.NormaliserGeometrie(string(lot.Geometrie), config.EmpriseTerritoire); err != nil {
+				return nil, nouvelleErreur(CodeRequeteInvalide, "contour du lot %s invalide: %v", lot.Id, err)
+			}
+		}
+		titres[i] = titre
+		operation.ajouter("lot "+lot.Id, func() error {
+			existant, err := ctx.GetStub().GetState(titre.Id)
+			if err != nil {
+				return fmt.Errorf("erreur de récupération de l'état: %v", err)
+			}
+			if existant != nil {
+				return fmt.Errorf("le titre foncier %s existe déjà", titre.Id)
+			}
+			// Les lots se partagent le contour du titre mère, qui sort de l'index spatial à sa clôture
+			if titre.Geometrie != "" {
+				if err := verifierChevauchements(ctx, titre.Geometrie, mere.Id); err != nil {
+					return err
+				}
+			}
+			return titre.Valider()
+		}, func() error {
+			return immatriculerTitre(ctx, titre)
+		})
+	}
+
+	if err := operation.executer(ctx); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Premier numéro officiel "<numéro du titre mère>-<n>" qu'aucun titre ne porte, à partir du suffixe
+// indiqué, et ce suffixe : un précédent morcellement ou une immatriculation peuvent avoir pris les premiers
+func numeroLotLibre(ctx contractapi.TransactionContextInterface, numMere string, suffixe int) (string, int, error) {
+	for ; ; suffixe++ {
+		numTF := fmt.Sprintf("%s-%d", numMere, suffixe)
+		ids, err := titresIndexes(ctx, PrefixeIndexNumTF, []string{numTF}, 1)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(ids) == 0 {
+			return numTF, suffixe, nil
+		}
+	}
+}
+
+// Consentir, pour le propriétaire appelant, au détachement d'une partie de son titre et à sa cession à
+// l'acheteur indiqué ; le consentement remplace celui déjà donné au même acheteur
+func (s *SmartContract) ConsentirDetachement(ctx contractapi.TransactionContextInterface, idTitre string, superficie int, acheteur string, prix int) (*ConsentementDetachement, error) {
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proprio == "" || proprio != titre.Proprio {
+		return nil, nouvelleErreur(CodeAccesRefuse, "seul le propriétaire du titre foncier %s peut consentir à son détachement", idTitre)
+	}
+	if acheteur == "" || superficie <= 0 || prix <= 0 {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'acheteur, la superficie détachée et le prix sont obligatoires")
+	}
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consentement := &ConsentementDetachement{
+		IdTitre:    idTitre,
+		Proprio:    proprio,
+		Acheteur:   acheteur,
+		Superficie: superficie,
+		Prix:       prix,
+		DonnePar:   identite,
+		DonneLe:    maintenant.Format(time.RFC3339),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentementDetachement, []string{idTitre, acheteur})
+	if err != nil {
+		return nil, err
+	}
+	if err := ecrireEtat(ctx, cle, consentement); err != nil {
+		return nil, err
+	}
+	return consentement, nil
+}
+
+// Détacher une partie d'un titre foncier et en proposer aussitôt la cession, payée comptant, aux
+// conditions consenties par le propriétaire (ConsentirDetachement). Le lot détaché est immatriculé au
+// bureau du titre mère sous le prochain numéro du canal, avec ses zonages et sa part des hypothèques,
+// réparties au prorata des superficies. Le titre mère subsiste pour la superficie et le contour restants
+// (obligatoire s'il était délimité) ; les deux parties respectent les règles de morcellement, et le
+// prix atteint celui de réserve des zonages du titre.
+func (s *SmartContract) DetacherEtTransferer(ctx contractapi.TransactionContextInterface, id string, superficieDetachee int, geometrie string, geometrieRestante string, acheteur string, prix int) (*Transfert, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	mere, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	geometrie, crs, err := model.NormaliserGeometrie(geometrie, config.EmpriseTerritoire)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "géométrie du lot détaché invalide: %v", err)
+	}
+	reste := &TitreFoncier{Id: mere.Id, Superficie: mere.Superficie - superficieDetachee}
+	if geometrieRestante != "" {
+		if reste.Geometrie, reste.SystemeCoordonnees, err = model.NormaliserGeometrie(geometrieRestante, config.EmpriseTerritoire); err != nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "géométrie restante du titre foncier %s invalide: %v", id, err)
+		}
+	} else if mere.Geometrie != "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le titre foncier %s est délimité, son contour restant est obligatoire", id)
+	}
+	consentement, cleConsentement, err := lireConsentementDetachement(ctx, id, acheteur)
+	if err != nil {
+		return nil, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Le numéro du lot n'est attribué qu'une fois par transaction : la séquence ne relit pas son écriture
+	idLot, err := s.prochainNumero(ctx, "TF")
+	if err != nil {
+		return nil, err
+	}
+	lot := model.NouveauTitreFoncier(idLot, mere.Proprio, idLot, superficieDetachee, mere.Commune, "", "")
+	lot.Geometrie = geometrie
+	lot.SystemeCoordonnees = crs
+	lot.Zones = mere.Zones
+	lot.Origine = OrigineDetachement + ":" + mere.Id
+	lot.BureauFoncier = mere.BureauFoncier
+
+	// Chaque hypothèque est scindée : le lot en reçoit la part de sa superficie, sous une référence
+	// propre qui se radie ou se réalise indépendamment de celle qui reste au titre mère
+	charges := make([]Charge, 0, len(mere.Charges))
+	for _, charge := range mere.Charges {
+		if charge.Type == ChargeHypotheque {
+			part := charge
+			part.Reference = charge.Reference + "/" + idLot
+			part.Montant = charge.Montant * superficieDetachee / mere.Superficie
+			lot.Charges = append(lot.Charges, part)
+			charge.Montant -= part.Montant
+		}
+		charges = append(charges, charge)
+	}
+	transfert := model.NouveauTransfert(ctx.GetStub().GetTxID(), idLot, mere.Proprio, acheteur, prix, ModeComptant)
+
+	operation := nouvelleOperation("detachement")
+	operation.ajouter("titre mère "+id, func() error {
+		if err := verifierBureau(ctx, mere); err != nil {
+			return err
+		}
+		if err := verifierAlienable(mere); err != nil {
+			return err
+		}
+		if err := verifierLibreDePromesse(mere, "", maintenant.Format(FormatDate)); err != nil {
+			return err
+		}
+		if consentement == nil || consentement.Proprio != mere.Proprio || consentement.Superficie != superficieDetachee || consentement.Prix != prix {
+			return nouvelleErreur(CodeConsentementRequis, "le propriétaire du titre foncier %s n'a pas consenti au détachement de %d m² au profit de %s pour %d FCFA", id, superficieDetachee, acheteur, prix)
+		}
+		if err := verifierDetachement(mere, superficieDetachee, prix, config); err != nil {
+			return err
+		}
+		return verifierContoursLots(mere, []*TitreFoncier{lot, reste})
+	}, func() error {
+		if err := ctx.GetStub().DelState(cleConsentement); err != nil {
+			return err
+		}
+		mere.Superficie = reste.Superficie
+		mere.Charges = charges
+		if reste.Geometrie != "" {
+			mere.Geometrie, mere.SystemeCoordonnees = reste.Geometrie, reste.SystemeCoordonnees
+		}
+		return sauvegarderTitre(ctx, mere)
+	})
+	operation.ajouter("lot "+idLot, func() error {
+		existant, err := ctx.GetStub().GetState(idLot)
+		if err != nil {
+			return fmt.Errorf("erreur de récupération de l'état: %v", err)
+		}
+		if existant != nil {
+			return fmt.Errorf("le titre foncier %s existe déjà", idLot)
+		}
+		// Le lot est pris sur le contour du titre mère, qui en est retranché
+		if err := verifierChevauchements(ctx, lot.Geometrie, mere.Id); err != nil {
+			return err
+		}
+		return lot.Valider()
+	}, func() error {
+		return immatriculerTitre(ctx, lot)
+	})
+	operation.ajouter("transfert "+transfert.Id, func() error {
+		if err := transfert.Valider(); err != nil {
+			return err
+		}
+		return verifierReglesTransfert(ctx, lot, transfert, false)
+	}, func() error {
+		if err := sauvegarderTransfert(ctx, transfert); err != nil {
+			return err
+		}
+		if _, err := ouvrirApprobation(ctx, WorkflowTransfert, transfert.Id, ""); err != nil {
+			return err
+		}
+		return demanderConsentements(ctx, lot, transfert)
+	})
+
+	if err := operation.executer(ctx); err != nil {
+		return nil, err
+	}
+
+	return transfert, nil
+}
+
+// Consentement du propriétaire d'un titre à son détachement au profit d'un acheteur (nil si absent), et sa clé
+func lireConsentementDetachement(ctx contractapi.TransactionContextInterface, idTitre string, acheteur string) (*ConsentementDetachement, string, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeConsentementDetachement, []string{idTitre, acheteur})
+	if err != nil {
+		return nil, "", err
+	}
+	var consentement ConsentementDetachement
+	existe, err := lireEtat(ctx, cle, &consentement)
+	if err != nil || !existe {
+		return nil, cle, err
+	}
+	return &consentement, cle, nil
+}
+
+// Vérifier que les lots couvrent exactement le titre mère et respectent les règles de morcellement
+// de ses zonages, qui écartent les reliquats inconstructibles
+func verifierLots(mere *TitreFoncier, lots []LotMorcellement, config *Configuration) error {
+	if len(lots) < 2 {
+		return nouvelleErreur(CodeRequeteInvalide, "un morcellement doit produire au moins deux lots")
+	}
+
+	superficieMin, lotsMax := config.ContraintesMorcellement(mere.Zones)
+	if lotsMax > 0 && len(lots) > lotsMax {
+		return nouvelleErreur(CodeMorcellementRefuse, "le titre foncier %s ne peut être morcelé en plus de %d lots", mere.Id, lotsMax)
+	}
+
+	vus := map[string]bool{mere.Id: true}
+	total := 0
+	for _, lot := range lots {
+		if vus[lot.Id] {
+			return nouvelleErreur(CodeRequeteInvalide, "l'identifiant de lot %s est déjà utilisé par ce morcellement", lot.Id)
+		}
+		vus[lot.Id] = true
+		if lot.Superficie < superficieMin {
+			return nouvelleErreur(CodeMorcellementRefuse, "le lot %s (%d m²) n'atteint pas la superficie minimale de %d m² applicable au titre foncier %s", lot.Id, lot.Superficie, superficieMin, mere.Id)
+		}
+		total += lot.Superficie
+	}
+	if total != mere.Superficie {
+		return nouvelleErreur(CodeRequeteInvalide, "la superficie des lots (%d m²) diffère de celle du titre foncier %s (%d m²)", total, mere.Id, mere.Superficie)
+	}
+
+	return nil
+}
+
+// Vérifier que les contours des lots partagent celui du titre mère : chacun y est compris et concorde
+// avec sa superficie, aucun n'empiète sur un autre et ensemble ils le couvrent. Les lots d'un titre mère
+// sans contour peuvent en porter un, contrôlé alors seulement contre les parcelles voisines.
+func verifierContoursLots(mere *TitreFoncier, lots []*TitreFoncier) error {
+	var contoursMere [][]model.Point
+	if mere.Geometrie != "" {
+		var err error
+		if contoursMere, err = model.ContoursGeoJSON(mere.Geometrie); err != nil {
+			return nouvelleErreur(CodeRequeteInvalide, "contour du titre foncier %s: %v", mere.Id, err)
+		}
+	}
+
+	contours := make([][][]model.Point, len(lots))
+	aireLots := 0.0
+	for i, lot := range lots {
+		if lot.Geometrie == "" {
+			if contoursMere != nil {
+				return nouvelleErreur(CodeRequeteInvalide, "le titre foncier %s est délimité, le contour du lot %s est obligatoire", mere.Id, lot.Id)
+			}
+			continue
+		}
+		var err error
+		if contours[i], err = model.ContoursGeoJSON(lot.Geometrie); err != nil {
+			return nouvelleErreur(CodeRequeteInvalide, "contour du lot %s: %v", lot.Id, err)
+		}
+		aire := model.Superficie(contours[i])
+		if !model.SuperficieConcorde(lot.Superficie, aire) {
+			return nouvelleErreur(CodeRequeteInvalide, "la superficie du lot %s (%d m²) ne concorde pas avec l'aire de son contour (%.0f m²)", lot.Id, lot.Superficie, aire)
+		}
+		if contoursMere != nil && !model.Inclus(contours[i], contoursMere) {
+			return nouvelleErreur(CodeRequeteInvalide, "le contour du lot %s déborde de celui du titre foncier %s", lot.Id, mere.Id)
+		}
+		for j := 0; j < i; j++ {
+			if contours[j] != nil && model.Chevauchent(contours[i], contours[j]) {
+				return nouvelleErreur(CodeParcelleChevauchee, "les contours des lots %s et %s se chevauchent", lots[j].Id, lot.Id)
+			}
+		}
+		aireLots += aire
+	}
+
+	// Compris dans le titre mère et disjoints, les lots le couvrent si leurs aires font la sienne
+	if contoursMere != nil {
+		if aire := model.Superficie(contoursMere); !model.SuperficieConcorde(int(aireLots), aire) {
+			return nouvelleErreur(CodeRequeteInvalide, "les contours des lots (%.0f m²) ne couvrent pas celui du titre foncier %s (%.0f m²)", aireLots, mere.Id, aire)
+		}
+	}
+	return nil
+}
+
+// Vérifier qu'un détachement laisse au titre mère et au lot détaché la superficie minimale de leurs zonages,
+// et que le prix de cession du lot atteint le prix de réserve de ces zonages
+func verifierDetachement(mere *TitreFoncier, superficieDetachee int, prix int, config *Configuration) error {
+	if superficieDetachee <= 0 || superficieDetachee >= mere.Superficie {
+		return nouvelleErreur(CodeRequeteInvalide, "la superficie détachée doit être comprise entre 1 et %d m²", mere.Superficie-1)
+	}
+
+	superficieMin, lotsMax := config.ContraintesMorcellement(mere.Zones)
+	if lotsMax == 1 {
+		return nouvelleErreur(CodeMorcellementRefuse, "le titre foncier %s ne peut être divisé", mere.Id)
+	}
+	if superficieDetachee < superficieMin || mere.Superficie-superficieDetachee < superficieMin {
+		return nouvelleErreur(CodeMorcellementRefuse, "le détachement de %d m² ne laisse pas à chaque partie du titre foncier %s la superficie minimale de %d m²", superficieDetachee, mere.Id, superficieMin)
+	}
+	if prixM2 := config.PrixReserveMorcellement(mere.Zones); prix < superficieDetachee*prixM2 {
+		return nouvelleErreur(CodeMorcellementRefuse, "le prix de cession du lot détaché (%d FCFA) est inférieur au prix de réserve de %d FCFA (%d FCFA/m²) applicable au titre foncier %s", prix, superficieDetachee*prixM2, prixM2, mere.Id)
+	}
+	return nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Préfixes des clés composites des certificats de mutation inter-canal
+const (
+	PrefixeMutation         = "MUTATION"
+	PrefixeMutationImport   = "MUTATION_IMPORT"
+	PrefixeMutationAnnulee  = "MUTATION_ANNULEE"
+	PrefixeMutationRenoncee = "MUTATION_RENONCEE"
+)
+
+// Émettre le certificat de mutation d'un titre vers un autre canal (réservé à l'État). Le certificat,
+// muni de sa seule empreinte, ne s'importe qu'une fois signé par la conservation foncière du bureau du
+// titre (SignerCertificatMutation).
+func (s *SmartContract) EmettreCertificatMutationInterCanal(ctx contractapi.TransactionContextInterface, id string, canalDestination string) (string, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return "", err
+	}
+
+	canal := ctx.GetStub().GetChannelID()
+	if canalDestination == "" || canalDestination == canal {
+		return "", fmt.Errorf("canal de destination invalide: %q", canalDestination)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if titre.MuteVers != "" {
+		return "", nouvelleErreur(CodeTitreMute, "le titre foncier %s a déjà été muté vers %s", id, titre.MuteVers)
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return "", err
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	certificat := CertificatMutation{
+		Id:               ctx.GetStub().GetTxID(),
+		IdTitre:          id,
+		CanalOrigine:     canal,
+		CanalDestination: canalDestination,
+		EmisLe:           maintenant.Format(FormatDate),
+		Titre:            titre,
+	}
+	if certificat.Empreinte, err = empreinteCertificat(&certificat); err != nil {
+		return "", err
+	}
+
+	blob, err := json.Marshal(certificat)
+	if err != nil {
+		return "", err
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutation, []string{certificat.Id})
+	if err != nil {
+		return "", err
+	}
+	if err := ecrireEtat(ctx, cle, certificat); err != nil {
+		return "", err
+	}
+
+	// Le titre reste consultable ici mais n'est plus administré par ce canal
+	titre.MuteVers = canalDestination
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return "", err
+	}
+
+	return string(blob), nil
+}
+
+// Signer un certificat de mutation au nom de la conservation foncière du bureau du titre (réservé à ses
+// conservateurs) : la signature de l'empreinte, produite hors du registre avec la clé de l'autorité, est
+// vérifiée sur son certificat. Retourne le certificat signé, à importer sur le canal de destination.
+func (s *SmartContract) SignerCertificatMutation(ctx contractapi.TransactionContextInterface, idCertificat string, autorite string, signature string) (string, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return "", err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutation, []string{idCertificat})
+	if err != nil {
+		return "", err
+	}
+	var certificat CertificatMutation
+	existe, err := lireEtat(ctx, cle, &certificat)
+	if err != nil {
+		return "", err
+	}
+	if !existe {
+		return "", fmt.Errorf("certificat de mutation %s non trouvé", idCertificat)
+	}
+	if certificat.Signature != "" {
+		return "", fmt.Errorf("le certificat %s a déjà été signé par %s", idCertificat, certificat.Signataire)
+	}
+	annule, err := mutationAnnulee(ctx, idCertificat)
+	if err != nil {
+		return "", err
+	}
+	if annule {
+		return "", nouvelleErreur(CodeCertificatInvalide, "le certificat %s a été annulé", idCertificat)
+	}
+	if err := verifierBureau(ctx, certificat.Titre); err != nil {
+		return "", err
+	}
+	signataire, err := s.conservationSignataire(ctx, autorite)
+	if err != nil {
+		return "", err
+	}
+	if err := verifierSignatureAutorite(signataire, []byte(certificat.Empreinte), signature); err != nil {
+		return "", nouvelleErreur(CodeCertificatInvalide, "certificat %s: %v", idCertificat, err)
+	}
+
+	certificat.Signataire = autorite
+	certificat.Signature = signature
+	if err := ecrireEtat(ctx, cle, certificat); err != nil {
+		return "", err
+	}
+	blob, err := json.Marshal(certificat)
+	if err != nil {
+		return "", err
+	}
+	return string(blob), nil
+}
+
+// Autorité de conservation foncière active, au certificat en cours de validité
+func (s *SmartContract) conservationSignataire(ctx contractapi.TransactionContextInterface, id string) (*AutoriteEmettrice, error) {
+	autorite, err := s.verifierEmetteur(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if autorite.Type != AutoriteConservation {
+		return nil, nouvelleErreur(CodeEmetteurNonReconnu, "l'autorité %s n'est pas une conservation foncière", id)
+	}
+	return autorite, nil
+}
+
+// Vérifier qu'un certificat de mutation a bien été émis sur ce canal avec cette empreinte
+func (s *SmartContract) VerifierCertificatMutation(ctx contractapi.TransactionContextInterface, idCertificat string, empreinte string) (bool, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutation, []string{idCertificat})
+	if err != nil {
+		return false, err
+	}
+	var certificat CertificatMutation
+	existe, err := lireEtat(ctx, cle, &certificat)
+	if err != nil || !existe {
+		return false, err
+	}
+
+	// Un certificat annulé par compensation ne peut plus être importé
+	annule, err := mutationAnnulee(ctx, idCertificat)
+	if err != nil || annule {
+		return false, err
+	}
+
+	return certificat.Empreinte == empreinte, nil
+}
+
+// Indiquer si un certificat émis sur un canal d'origine a été importé sur ce canal
+func (s *SmartContract) CertificatImporte(ctx contractapi.TransactionContextInterface, canalOrigine string, idCertificat string) (bool, error) {
+	cleImport, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationImport, []string{canalOrigine, idCertificat})
+	if err != nil {
+		return false, err
+	}
+	importe, err := ctx.GetStub().GetState(cleImport)
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture: %v", err)
+	}
+	return importe != nil, nil
+}
+
+// Renoncer sur ce canal à l'import d'un certificat de mutation qui n'y a pas été importé (réservé à
+// l'État) : il ne pourra plus l'être, et le canal d'origine peut alors annuler la mutation
+func (s *SmartContract) RenoncerImportMutation(ctx contractapi.TransactionContextInterface, canalOrigine string, idCertificat string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+	if canalOrigine == "" || idCertificat == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "le canal d'origine et le certificat sont obligatoires")
+	}
+	importe, err := s.CertificatImporte(ctx, canalOrigine, idCertificat)
+	if err != nil {
+		return err
+	}
+	if importe {
+		return nouvelleErreur(CodeCertificatInvalide, "le certificat %s a déjà été importé", idCertificat)
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationRenoncee, []string{canalOrigine, idCertificat})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(cle, []byte(ctx.GetStub().GetTxID()))
+}
+
+// Indiquer si ce canal a renoncé à l'import d'un certificat émis sur un canal d'origine
+func (s *SmartContract) ImportRenonce(ctx contractapi.TransactionContextInterface, canalOrigine string, idCertificat string) (bool, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationRenoncee, []string{canalOrigine, idCertificat})
+	if err != nil {
+		return false, err
+	}
+	renonciation, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture: %v", err)
+	}
+	return renonciation != nil, nil
+}
+
+// Compenser une mutation à l'import de laquelle le canal de destination a renoncé (RenoncerImportMutation) :
+// le titre redevient administré par ce canal et le certificat est invalidé (réservé à l'État). La lecture
+// inter-canal n'est pas revalidée à l'engagement, mais la renonciation est définitive et interdit l'import :
+// une lecture périmée ne peut que refuser l'annulation, jamais la laisser coexister avec un import.
+func (s *SmartContract) AnnulerMutationInterCanal(ctx contractapi.TransactionContextInterface, idCertificat string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutation, []string{idCertificat})
+	if err != nil {
+		return err
+	}
+	var certificat CertificatMutation
+	existe, err := lireEtat(ctx, cle, &certificat)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		return fmt.Errorf("certificat de mutation %s non trouvé", idCertificat)
+	}
+	titre, err := s.LireTitreFoncier(ctx, certificat.IdTitre)
+	if err != nil {
+		return err
+	}
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	cleAnnulation, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationAnnulee, []string{idCertificat})
+	if err != nil {
+		return err
+	}
+
+	operation := nouvelleOperation("annulation-mutation")
+	operation.ajouter("certificat en vigueur", func() error {
+		annule, err := mutationAnnulee(ctx, idCertificat)
+		if err != nil {
+			return err
+		}
+		if annule || titre.MuteVers != certificat.CanalDestination {
+			return nouvelleErreur(CodeCertificatInvalide, "le certificat %s n'est plus en vigueur", idCertificat)
+		}
+		return nil
+	}, func() error {
+		return ctx.GetStub().PutState(cleAnnulation, []byte(ctx.GetStub().GetTxID()))
+	})
+	operation.ajouter("renonciation à l'import", func() error {
+		reponse := ctx.GetStub().InvokeChaincode(config.NomChaincode, [][]byte{
+			[]byte("ImportRenonce"), []byte(certificat.CanalOrigine), []byte(idCertificat),
+		}, certificat.CanalDestination)
+		renonce, err := resultatInvocation(certificat.CanalDestination, reponse)
+		if err != nil {
+			return err
+		}
+		if string(renonce) != "true" {
+			return nouvelleErreur(CodeCertificatInvalide, "le canal %s n'a pas renoncé à l'import du certificat %s", certificat.CanalDestination, idCertificat)
+		}
+		return nil
+	}, func() error {
+		titre.MuteVers = ""
+		return sauvegarderTitre(ctx, titre)
+	})
+
+	return operation.executer(ctx)
+}
+
+// Résultat d'un appel au chaincode d'un autre canal, dont la réponse est enveloppée si ce canal l'a
+// configuré (EnveloppeReponses) : le résultat et le message d'erreur sont alors extraits de l'enveloppe
+func resultatInvocation(canal string, reponse pb.Response) ([]byte, error) {
+	var enveloppe Enveloppe
+	if reponse.Status != shim.OK {
+		message := reponse.Message
+		if json.Unmarshal([]byte(message), &enveloppe) == nil && enveloppe.Code != "" {
+			message = enveloppe.Code + ": " + enveloppe.Message
+		}
+		return nil, fmt.Errorf("erreur de vérification sur le canal %s: %s", canal, message)
+	}
+	if json.Unmarshal(reponse.Payload, &enveloppe) == nil && enveloppe.Code != "" {
+		return enveloppe.Data, nil
+	}
+	return reponse.Payload, nil
+}
+
+func mutationAnnulee(ctx contractapi.TransactionContextInterface, idCertificat string) (bool, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationAnnulee, []string{idCertificat})
+	if err != nil {
+		return false, err
+	}
+	annulation, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return false, fmt.Errorf("erreur de lecture: %v", err)
+	}
+	return annulation != nil, nil
+}
+
+// Recréer sur ce canal un titre muté depuis un autre canal (réservé à l'État)
+func (s *SmartContract) ImporterDepuisAutreCanal(ctx contractapi.TransactionContextInterface, blob string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	var certificat CertificatMutation
+	if err := json.Unmarshal([]byte(blob), &certificat); err != nil {
+		return fmt.Errorf("certificat de mutation invalide: %v", err)
+	}
+	if certificat.Titre == nil || certificat.Titre.Id != certificat.IdTitre {
+		return fmt.Errorf("certificat de mutation incomplet")
+	}
+
+	// Contrôler l'intégrité du certificat et sa destination ; l'empreinte, que quiconque peut recalculer
+	// sur un contenu falsifié, n'en établit pas l'origine
+	empreinte, err := empreinteCertificat(&certificat)
+	if err != nil {
+		return err
+	}
+	if empreinte != certificat.Empreinte {
+		return nouvelleErreur(CodeCertificatInvalide, "l'empreinte du certificat %s ne correspond pas à son contenu", certificat.Id)
+	}
+	if certificat.CanalDestination != ctx.GetStub().GetChannelID() {
+		return nouvelleErreur(CodeCertificatInvalide, "le certificat %s est destiné au canal %s", certificat.Id, certificat.CanalDestination)
+	}
+
+	// La signature de la conservation d'origine, vérifiée sur le certificat que ce canal détient pour elle,
+	// établit l'origine du certificat sans dépendre d'une lecture inter-canal
+	if certificat.Signature == "" {
+		return nouvelleErreur(CodeCertificatInvalide, "le certificat %s n'est pas signé", certificat.Id)
+	}
+	signataire, err := s.conservationSignataire(ctx, certificat.Signataire)
+	if err != nil {
+		return err
+	}
+	if err := verifierSignatureAutorite(signataire, []byte(certificat.Empreinte), certificat.Signature); err != nil {
+		return nouvelleErreur(CodeCertificatInvalide, "certificat %s: %v", certificat.Id, err)
+	}
+
+	// Le canal d'origine confirme en outre que le certificat n'y a pas été annulé
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	reponse := ctx.GetStub().InvokeChaincode(config.NomChaincode, [][]byte{
+		[]byte("VerifierCertificatMutation"), []byte(certificat.Id), []byte(certificat.Empreinte),
+	}, certificat.CanalOrigine)
+	valide, err := resultatInvocation(certificat.CanalOrigine, reponse)
+	if err != nil {
+		return err
+	}
+	if string(valide) != "true" {
+		return nouvelleErreur(CodeCertificatInvalide, "le certificat %s est inconnu du canal %s", certificat.Id, certificat.CanalOrigine)
+	}
+
+	// Un certificat ne s'importe qu'une fois et un titre n'existe qu'une fois par canal
+	cleImport, err := ctx.GetStub().CreateCompositeKey(PrefixeMutationImport, []string{certificat.CanalOrigine, certificat.Id})
+	if err != nil {
+		return err
+	}
+	dejaImporte, err := ctx.GetStub().GetState(cleImport)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if dejaImporte != nil {
+		return fmt.Errorf("le certificat %s a déjà été importé", certificat.Id)
+	}
+	renonce, err := s.ImportRenonce(ctx, certificat.CanalOrigine, certificat.Id)
+	if err != nil {
+		return err
+	}
+	if renonce {
+		return nouvelleErreur(CodeCertificatInvalide, "ce canal a renoncé à l'import du certificat %s", certificat.Id)
+	}
+	existant, err := ctx.GetStub().GetState(certificat.IdTitre)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return fmt.Errorf("le titre foncier %s existe déjà", certificat.IdTitre)
+	}
+
+	titre := certificat.Titre
+	if err := verifierCommune(config, titre.Commune); err != nil {
+		return err
+	}
+	titre.MuteVers = ""
+
+	if err := ctx.GetStub().PutState(cleImport, []byte(certificat.IdTitre)); err != nil {
+		return err
+	}
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Empreinte SHA-256 d'un certificat, calculée avec les champs Empreinte et de signature vides ; sans clé,
+// elle ne vaut pas signature
+func empreinteCertificat(certificat *CertificatMutation) (string, error) {
+	copie := *certificat
+	copie.Empreinte = ""
+	copie.Signataire = ""
+	copie.Signature = ""
+
+	contenu, err := json.Marshal(copie)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(contenu)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Registres des canaux de Dakar et de Thiès, reliés, qui reconnaissent tous deux la conservation foncière
+// de Dakar pour signataire
+func canauxMutation(t *testing.T) (*registreTest, *registreTest, *autoriteTest) {
+	dakar, thies := nouveauRegistreTest(t), nouveauRegistreTest(t)
+	thies.stub.ChannelID = "thies"
+	dakar.relier(thies)
+	conservation := nouvelleAutoriteTest(t, "CF-DK")
+	dakar.enregistrerAutorite(conservation, AutoriteConservation)
+	thies.enregistrerAutorite(conservation, AutoriteConservation)
+	return dakar, thies, conservation
+}
+
+func emettreMutation(t *testing.T, r *registreTest, idTitre string) *CertificatMutation {
+	t.Helper()
+	if err := r.ajouter(conservateurDK, idTitre, "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	var blob string
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		blob, err = r.s.EmettreCertificatMutationInterCanal(ctx, idTitre, "thies")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var certificat CertificatMutation
+	if err := json.Unmarshal([]byte(blob), &certificat); err != nil {
+		t.Fatal(err)
+	}
+	return &certificat
+}
+
+func signerMutation(r *registreTest, appelant *identiteTest, certificat *CertificatMutation, signature string) (*CertificatMutation, error) {
+	var blob string
+	err := r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) (err error) {
+		blob, err = r.s.SignerCertificatMutation(ctx, certificat.Id, "CF-DK", signature)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var signe CertificatMutation
+	return &signe, json.Unmarshal([]byte(blob), &signe)
+}
+
+func importerMutation(r *registreTest, certificat *CertificatMutation) error {
+	blob, _ := json.Marshal(certificat)
+	return r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.ImporterDepuisAutreCanal(ctx, string(blob))
+	})
+}
+
+func TestMutationSignee(t *testing.T) {
+	dakar, thies, conservation := canauxMutation(t)
+	certificat := emettreMutation(t, dakar, "TF100")
+	signature := conservation.signer(t, []byte(certificat.Empreinte))
+
+	verifierCode(t, "import du certificat non signé", importerMutation(thies, certificat), CodeCertificatInvalide)
+	_, err := signerMutation(dakar, conservateurTH, certificat, signature)
+	verifierCode(t, "signature par un autre bureau", err, CodeAccesRefuse)
+	faussaire := nouvelleAutoriteTest(t, "CF-DK")
+	_, err = signerMutation(dakar, conservateurDK, certificat, faussaire.signer(t, []byte(certificat.Empreinte)))
+	verifierCode(t, "signature d'une autre clé", err, CodeCertificatInvalide)
+	signe, err := signerMutation(dakar, conservateurDK, certificat, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// L'empreinte d'un contenu falsifié se recalcule, pas la signature
+	falsifie := *signe
+	titre := *signe.Titre
+	titre.Proprio = "Moussa Fall"
+	falsifie.Titre = &titre
+	if falsifie.Empreinte, err = empreinteCertificat(&falsifie); err != nil {
+		t.Fatal(err)
+	}
+	verifierCode(t, "import du certificat falsifié", importerMutation(thies, &falsifie), CodeCertificatInvalide)
+
+	verifierCode(t, "import du certificat signé", importerMutation(thies, signe), "")
+	if proprio := thies.lire("TF100").Proprio; proprio != "Awa Ndiaye" {
+		t.Errorf("titre importé: propriétaire %s", proprio)
+	}
+	err = thies.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return thies.s.RenoncerImportMutation(ctx, "dakar", certificat.Id)
+	})
+	verifierCode(t, "renonciation après l'import", err, CodeCertificatInvalide)
+	err = dakar.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return dakar.s.AnnulerMutationInterCanal(ctx, certificat.Id)
+	})
+	verifierCode(t, "annulation d'une mutation importée", err, CodeCertificatInvalide)
+}
+
+func TestAnnulationSurRenonciation(t *testing.T) {
+	dakar, thies, conservation := canauxMutation(t)
+	certificat := emettreMutation(t, dakar, "TF100")
+	signe, err := signerMutation(dakar, conservateurDK, certificat, conservation.signer(t, []byte(certificat.Empreinte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	annuler := func() error {
+		return dakar.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+			return dakar.s.AnnulerMutationInterCanal(ctx, certificat.Id)
+		})
+	}
+
+	// Sans renonciation du canal de destination, l'absence d'import ne suffit pas
+	verifierCode(t, "annulation sans renonciation", annuler(), CodeCertificatInvalide)
+	err = thies.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return thies.s.RenoncerImportMutation(ctx, "dakar", certificat.Id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifierCode(t, "import après renonciation", importerMutation(thies, signe), CodeCertificatInvalide)
+	verifierCode(t, "annulation sur renonciation", annuler(), "")
+	if titre := dakar.lire("TF100"); titre.MuteVers != "" {
+		t.Errorf("titre toujours muté vers %s après l'annulation", titre.MuteVers)
+	}
+}
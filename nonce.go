@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des nonces des titulaires (titulaire)
+const PrefixeNonce = "NONCE"
+
+// Champ transitoire portant le nonce de la requête signée par le titulaire, recopié par la passerelle
+const champNonce = "nonce"
+
+// Exiger des transactions soumises via la passerelle citoyenne un nonce strictement croissant par
+// titulaire : une requête signée interceptée ne peut être rejouée, par exemple pour proposer de
+// nouveau un transfert annulé. Les consultations, qui n'écrivent rien, n'en requièrent pas.
+func verifierNonce(ctx contractapi.TransactionContextInterface) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	operation := operationCourante(ctx)
+	if mspID != MSPPasserelle || consultation(operation) {
+		return nil
+	}
+
+	transitoire, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des données transitoires: %v", err)
+	}
+	nonce, err := strconv.ParseUint(string(transitoire[champNonce]), 10, 64)
+	if err != nil || nonce == 0 {
+		return nouvelleErreur(CodeNonceInvalide, "les requêtes de la passerelle doivent porter un nonce positif (champ transitoire %q)", champNonce)
+	}
+
+	titulaire, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if titulaire == "" {
+		if titulaire, err = ctx.GetClientIdentity().GetID(); err != nil {
+			return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+		}
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeNonce, []string{titulaire})
+	if err != nil {
+		return err
+	}
+	var precedent NonceTitulaire
+	if _, err := lireEtat(ctx, cle, &precedent); err != nil {
+		return err
+	}
+	if nonce <= precedent.Dernier {
+		return nouvelleErreur(CodeNonceInvalide, "nonce %d périmé pour %s (dernier accepté: %d) : requête rejouée ou hors séquence", nonce, titulaire, precedent.Dernier)
+	}
+
+	return ecrireEtat(ctx, cle, &NonceTitulaire{
+		Titulaire: titulaire,
+		Dernier:   nonce,
+		Operation: operation,
+		TxId:      ctx.GetStub().GetTxID(),
+	})
+}
+
+func consultation(operation string) bool {
+	for _, nom := range transactionsConsultation {
+		if nom == operation {
+			return true
+		}
+	}
+	return false
+}
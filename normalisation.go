@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Forme de recherche d'un nom : décomposition NFKD sans diacritiques, en minuscules, espaces réduits
+// ("Ndèye Fatou DABO" devient "ndeye fatou dabo")
+func normaliserNom(nom string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(nom) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Caractères du nom normalisé, attributs de l'index des noms
+func caracteresNom(nom string) []string {
+	var caracteres []string
+	for _, r := range normaliserNom(nom) {
+		caracteres = append(caracteres, string(r))
+	}
+	return caracteres
+}
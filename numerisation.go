@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixes des clés composites des lots de numérisation des archives (lotId), des doubles saisies de
+// leurs titres (idTitre) et de l'index des doubles saisies divergentes (idTitre)
+const (
+	PrefixeLotNumerisation  = "LOT_NUMERISATION"
+	PrefixeDoubleSaisie     = "DOUBLE_SAISIE"
+	PrefixeSaisieDivergente = "SAISIE_DIVERGENTE"
+)
+
+// Ancrer un lot d'archives papier numérisées (réservé aux conservateurs), avant la saisie des titres
+// qu'il contient : merkleRoot est la racine SHA-256 de l'arbre des hashes de ses count fichiers (voir
+// model.RacineMerkle). Un lot ancré ne peut plus être modifié.
+func (s *SmartContract) EnregistrerLotNumerisation(ctx contractapi.TransactionContextInterface, lotId string, merkleRoot string, count int, operateur string) (*LotNumerisation, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	merkleRoot = strings.ToLower(strings.TrimSpace(merkleRoot))
+	switch {
+	case lotId == "":
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'identifiant du lot est obligatoire")
+	case len(merkleRoot) != 64 || !hashValide(merkleRoot):
+		return nil, nouvelleErreur(CodeRequeteInvalide, "racine de Merkle invalide (SHA-256 attendu): %s", merkleRoot)
+	case count <= 0:
+		return nil, nouvelleErreur(CodeRequeteInvalide, "un lot compte au moins un fichier")
+	case operateur == "":
+		return nil, nouvelleErreur(CodeRequeteInvalide, "l'opérateur de numérisation est obligatoire")
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLotNumerisation, []string{lotId})
+	if err != nil {
+		return nil, err
+	}
+	existant, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return nil, err
+	}
+	if existant != nil {
+		return nil, fmt.Errorf("le lot de numérisation %s est déjà ancré", lotId)
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	bureau, err := bureauAppelant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lot := &LotNumerisation{
+		Id:             lotId,
+		RacineMerkle:   merkleRoot,
+		NombreFichiers: count,
+		Operateur:      operateur,
+		BureauFoncier:  bureau,
+		EnregistrePar:  agent,
+		EnregistreLe:   maintenant.Format(time.RFC3339),
+		TxId:           ctx.GetStub().GetTxID(),
+	}
+	if err := ecrireEtat(ctx, cle, lot); err != nil {
+		return nil, err
+	}
+	return lot, nil
+}
+
+// Lire un lot de numérisation
+func (s *SmartContract) LireLotNumerisation(ctx contractapi.TransactionContextInterface, lotId string) (*LotNumerisation, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeLotNumerisation, []string{lotId})
+	if err != nil {
+		return nil, err
+	}
+
+	var lot LotNumerisation
+	existe, err := lireEtat(ctx, cle, &lot)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("lot de numérisation %s non trouvé", lotId)
+	}
+	return &lot, nil
+}
+
+// Vérifier qu'un fichier numérisé (hash SHA-1 ou SHA-256) appartient à un lot ancré, au vu de sa
+// preuve d'inclusion (JSON, liste d'EtapeMerkle de la feuille vers la racine, voir model.PreuveMerkle)
+func (s *SmartContract) VerifierFichierNumerise(ctx contractapi.TransactionContextInterface, lotId string, hashFichier string, preuve string) (*VerificationNumerisation, error) {
+	lot, err := s.LireLotNumerisation(ctx, lotId)
+	if err != nil {
+		return nil, err
+	}
+	var etapes []EtapeMerkle
+	if err := json.Unmarshal([]byte(preuve), &etapes); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "preuve d'inclusion invalide: %v", err)
+	}
+	inclus, err := verifierInclusionLot(lot, hashFichier, etapes)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationNumerisation{
+		IdLot:       lotId,
+		HashFichier: strings.ToLower(strings.TrimSpace(hashFichier)),
+		Inclus:      inclus,
+		Lot:         lot,
+	}, nil
+}
+
+func verifierInclusionLot(lot *LotNumerisation, hashFichier string, etapes []EtapeMerkle) (bool, error) {
+	inclus, err := model.VerifierPreuveMerkle(hashFichier, etapes, lot.RacineMerkle, lot.NombreFichiers)
+	if err != nil {
+		return false, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	return inclus, nil
+}
+
+// Refuser la saisie directe d'un titre lorsque le canal impose la double saisie des titres d'archive
+func verifierSaisieDirecte(config *Configuration) error {
+	if config.DoubleSaisieObligatoire {
+		return nouvelleErreur(CodeAccesRefuse, "la double saisie est obligatoire sur ce canal : le titre doit être saisi par SaisirTitreArchive")
+	}
+	return nil
+}
+
+// Saisir un titre d'archive d'après un fichier numérisé d'un lot ancré (réservé aux conservateurs) : la
+// saisie (SaisieArchive, JSON) porte le dossier d'immatriculation et la preuve d'inclusion du fichier.
+// Le titre n'est immatriculé qu'à la saisie concordante d'un second opérateur ; deux saisies dont les
+// empreintes diffèrent sont signalées (événement SaisieDivergente) et soumises à un superviseur. Le
+// contenu des saisies n'est pas communiqué aux opérateurs, pour garder les saisies indépendantes.
+func (s *SmartContract) SaisirTitreArchive(ctx contractapi.TransactionContextInterface, saisie string) (*DoubleSaisie, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	var requete SaisieArchive
+	if err := json.Unmarshal([]byte(saisie), &requete); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "saisie invalide: %v", err)
+	}
+	if err := requete.Dossier.Valider(); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	lot, err := s.LireLotNumerisation(ctx, requete.IdLot)
+	if err != nil {
+		return nil, err
+	}
+	inclus, err := verifierInclusionLot(lot, requete.HashScan, requete.Preuve)
+	if err != nil {
+		return nil, err
+	}
+	if !inclus {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le fichier %s n'appartient pas au lot de numérisation %s", requete.HashScan, lot.Id)
+	}
+	empreinte, err := requete.Empreinte()
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+
+	idTitre := requete.Dossier.Id
+	existant, err := ctx.GetStub().GetState(idTitre)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return nil, fmt.Errorf("le titre foncier %s existe déjà", idTitre)
+	}
+	double, err := lireDoubleSaisie(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if double == nil {
+		double = &DoubleSaisie{IdTitre: idTitre, Statut: DoubleSaisieAttente, Saisies: []SaisieOperateur{}}
+	}
+	if double.Statut != DoubleSaisieAttente {
+		return nil, fmt.Errorf("la double saisie du titre foncier %s est %s", idTitre, double.Statut)
+	}
+
+	operateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	for _, precedente := range double.Saisies {
+		if precedente.Operateur == operateur {
+			return nil, nouvelleErreur(CodeAccesRefuse, "la seconde saisie du titre foncier %s doit être faite par un autre opérateur", idTitre)
+		}
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	double.Saisies = append(double.Saisies, SaisieOperateur{
+		Operateur:  operateur,
+		Empreinte:  empreinte,
+		IdLot:      lot.Id,
+		HashScan:   strings.ToLower(strings.TrimSpace(requete.HashScan)),
+		Dossier:    &requete.Dossier,
+		Horodatage: maintenant.Format(time.RFC3339),
+		TxId:       ctx.GetStub().GetTxID(),
+	})
+
+	divergente := false
+	if len(double.Saisies) == 2 {
+		if double.Saisies[0].Empreinte == empreinte {
+			if err := s.activerDoubleSaisie(ctx, double, &requete.Dossier); err != nil {
+				return nil, err
+			}
+		} else {
+			double.Statut = DoubleSaisieDivergente
+			if err := indexerSaisieDivergente(ctx, idTitre, true); err != nil {
+				return nil, err
+			}
+			divergente = true
+		}
+	}
+	if err := sauvegarderDoubleSaisie(ctx, double); err != nil {
+		return nil, err
+	}
+	if divergente {
+		evenement, err := json.Marshal(double.SansDossiers())
+		if err != nil {
+			return nil, err
+		}
+		if err := emettreEvenement(ctx, EvenementSaisieDivergente, evenement); err != nil {
+			return nil, err
+		}
+	}
+	return double.SansDossiers(), nil
+}
+
+// Trancher des saisies divergentes (réservé aux superviseurs, étrangers aux saisies) : la saisie
+// d'empreinte donnée est retenue et le titre immatriculé ; sans empreinte, les saisies sont écartées
+// et le titre doit être saisi à nouveau par deux opérateurs
+func (s *SmartContract) ResoudreDoubleSaisie(ctx contractapi.TransactionContextInterface, idTitre string, empreinte string, motif string) (*DoubleSaisie, error) {
+	if err := verifierRole(ctx, RoleSuperviseur); err != nil {
+		return nil, err
+	}
+	if motif == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif de la décision est obligatoire")
+	}
+	double, err := s.LireDoubleSaisie(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if double.Statut != DoubleSaisieDivergente {
+		return nil, fmt.Errorf("la double saisie du titre foncier %s est %s", idTitre, double.Statut)
+	}
+	superviseur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	for _, saisie := range double.Saisies {
+		if saisie.Operateur == superviseur {
+			return nil, nouvelleErreur(CodeAccesRefuse, "l'auteur d'une saisie du titre foncier %s ne peut la trancher", idTitre)
+		}
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if empreinte == "" {
+		double.Ecartees = append(double.Ecartees, double.Saisies...)
+		double.Saisies = []SaisieOperateur{}
+		double.Statut = DoubleSaisieAttente
+	} else {
+		var retenue *SaisieOperateur
+		for i := range double.Saisies {
+			if double.Saisies[i].Empreinte == empreinte {
+				retenue = &double.Saisies[i]
+			}
+		}
+		if retenue == nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "aucune saisie du titre foncier %s n'a l'empreinte %s", idTitre, empreinte)
+		}
+		if err := s.activerDoubleSaisie(ctx, double, retenue.Dossier); err != nil {
+			return nil, err
+		}
+	}
+	double.Resolutions = append(double.Resolutions, ResolutionDoubleSaisie{
+		Superviseur: superviseur,
+		Empreinte:   empreinte,
+		Motif:       motif,
+		Horodatage:  maintenant.Format(time.RFC3339),
+		TxId:        ctx.GetStub().GetTxID(),
+	})
+	if err := indexerSaisieDivergente(ctx, idTitre, false); err != nil {
+		return nil, err
+	}
+	if err := sauvegarderDoubleSaisie(ctx, double); err != nil {
+		return nil, err
+	}
+	return double, nil
+}
+
+// Lire la double saisie d'un titre d'archive, saisies comprises (réservé aux superviseurs)
+func (s *SmartContract) LireDoubleSaisie(ctx contractapi.TransactionContextInterface, idTitre string) (*DoubleSaisie, error) {
+	if err := verifierRole(ctx, RoleSuperviseur); err != nil {
+		return nil, err
+	}
+	double, err := lireDoubleSaisie(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if double == nil {
+		return nil, fmt.Errorf("aucune double saisie du titre foncier %s", idTitre)
+	}
+	return double, nil
+}
+
+// Lister les doubles saisies divergentes en attente de la décision d'un superviseur (réservé aux superviseurs)
+func (s *SmartContract) GetDoublesSaisiesDivergentes(ctx contractapi.TransactionContextInterface) ([]*DoubleSaisie, error) {
+	if err := verifierRole(ctx, RoleSuperviseur); err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSaisieDivergente, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	doubles := []*DoubleSaisie{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		double, err := lireDoubleSaisie(ctx, attributs[0])
+		if err != nil {
+			return nil, err
+		}
+		if double != nil {
+			doubles = append(doubles, double)
+		}
+	}
+	return doubles, nil
+}
+
+// Immatriculer le dossier retenu d'une double saisie
+func (s *SmartContract) activerDoubleSaisie(ctx contractapi.TransactionContextInterface, double *DoubleSaisie, dossier *DossierImmatriculation) error {
+	if _, err := s.immatriculerDossier(ctx, dossier); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	double.Statut = DoubleSaisieActivee
+	double.ActiveeLe = maintenant.Format(time.RFC3339)
+	double.TxActivation = ctx.GetStub().GetTxID()
+	return nil
+}
+
+func lireDoubleSaisie(ctx contractapi.TransactionContextInterface, idTitre string) (*DoubleSaisie, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeDoubleSaisie, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	var double DoubleSaisie
+	existe, err := lireEtat(ctx, cle, &double)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &double, nil
+}
+
+func sauvegarderDoubleSaisie(ctx contractapi.TransactionContextInterface, double *DoubleSaisie) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeDoubleSaisie, []string{double.IdTitre})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, double)
+}
+
+// Inscrire une double saisie à l'index des saisies divergentes, ou l'en retirer
+func indexerSaisieDivergente(ctx contractapi.TransactionContextInterface, idTitre string, divergente bool) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSaisieDivergente, []string{idTitre})
+	if err != nil {
+		return err
+	}
+	if !divergente {
+		return ctx.GetStub().DelState(cle)
+	}
+	return ctx.GetStub().PutState(cle, valeurIndex)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des actes d'occupation (type~id)
+const PrefixeActeOccupation = "ACTE_OCCUPATION"
+
+// Enregistrer une délibération du conseil municipal (réservé aux agents de la commune)
+func (s *SmartContract) EnregistrerDeliberation(ctx contractapi.TransactionContextInterface, id string, commune string, beneficiaire string, superficie int, date string, document string) error {
+	if err := verifierAgentCommunal(ctx, commune); err != nil {
+		return err
+	}
+	return s.enregistrerActe(ctx, ActeDeliberation, id, commune, beneficiaire, superficie, date, document)
+}
+
+// Enregistrer un permis d'occuper délivré par l'administration des domaines (réservé à l'État)
+func (s *SmartContract) EnregistrerPermisOccuper(ctx contractapi.TransactionContextInterface, id string, commune string, beneficiaire string, superficie int, date string, document string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+	return s.enregistrerActe(ctx, ActePermisOccuper, id, commune, beneficiaire, superficie, date, document)
+}
+
+// Viser la conversion d'un acte d'occupation en titre foncier (réservé aux agents de la commune de situation)
+func (s *SmartContract) ViserConversion(ctx contractapi.TransactionContextInterface, typeActe string, idActe string) error {
+	acte, err := s.LireActeOccupation(ctx, typeActe, idActe)
+	if err != nil {
+		return err
+	}
+	if err := verifierAgentCommunal(ctx, acte.Commune); err != nil {
+		return err
+	}
+	if acte.Statut != ActeEnVigueur {
+		return fmt.Errorf("l'acte %s a déjà été converti en titre foncier %s", idActe, acte.IdTitre)
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	acte.VisePar = agent
+	acte.ViseLe = maintenant.Format(FormatDate)
+
+	return sauvegarderActe(ctx, acte)
+}
+
+// Immatriculer un titre foncier à partir d'un acte d'occupation visé par la commune (réservé aux conservateurs) ;
+// l'acte est consommé et le titre en garde la filiation
+func (s *SmartContract) ConvertirEnTitreFoncier(ctx contractapi.TransactionContextInterface, id string, typeActe string, idActe string, numTF string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+
+	acte, err := s.LireActeOccupation(ctx, typeActe, idActe)
+	if err != nil {
+		return err
+	}
+	if acte.Statut != ActeEnVigueur {
+		return fmt.Errorf("l'acte %s a déjà été converti en titre foncier %s", idActe, acte.IdTitre)
+	}
+	if acte.VisePar == "" {
+		return nouvelleErreur(CodeAccesRefuse, "la conversion de l'acte %s n'a pas été visée par la commune %s", idActe, acte.Commune)
+	}
+
+	existant, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return fmt.Errorf("le titre foncier %s existe déjà", id)
+	}
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifierCommune(config, acte.Commune); err != nil {
+		return err
+	}
+	if numTF == "" {
+		if numTF, err = s.prochainNumero(ctx, "TF"); err != nil {
+			return err
+		}
+	}
+
+	titre := model.NouveauTitreFoncier(id, acte.Beneficiaire, numTF, acte.Superficie, acte.Commune, acte.Document, acte.DocHash)
+	titre.Origine = acte.Type + ":" + acte.Id
+	if err := immatriculerTitre(ctx, titre); err != nil {
+		return err
+	}
+
+	acte.Statut = ActeConverti
+	acte.IdTitre = id
+	return sauvegarderActe(ctx, acte)
+}
+
+// Lire un acte d'occupation
+func (s *SmartContract) LireActeOccupation(ctx contractapi.TransactionContextInterface, typeActe string, idActe string) (*ActeOccupation, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeActeOccupation, []string{typeActe, idActe})
+	if err != nil {
+		return nil, err
+	}
+
+	var acte ActeOccupation
+	existe, err := lireEtat(ctx, cle, &acte)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("acte d'occupation %s %s non trouvé", typeActe, idActe)
+	}
+
+	return &acte, nil
+}
+
+func (s *SmartContract) enregistrerActe(ctx contractapi.TransactionContextInterface, typeActe string, id string, commune string, beneficiaire string, superficie int, date string, document string) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeActeOccupation, []string{typeActe, id})
+	if err != nil {
+		return err
+	}
+	existant, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return fmt.Errorf("l'acte d'occupation %s %s existe déjà", typeActe, id)
+	}
+
+	fichier, err := controlerDocument(ctx, document)
+	if err != nil {
+		return err
+	}
+
+	acte := &ActeOccupation{
+		Id:           id,
+		Type:         typeActe,
+		Commune:      commune,
+		Beneficiaire: beneficiaire,
+		Superficie:   superficie,
+		Date:         date,
+		Document:     document,
+		DocHash:      fichier.hash,
+		Statut:       ActeEnVigueur,
+	}
+	if err := acte.Valider(); err != nil {
+		return err
+	}
+
+	return sauvegarderActe(ctx, acte)
+}
+
+func sauvegarderActe(ctx contractapi.TransactionContextInterface, acte *ActeOccupation) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeActeOccupation, []string{acte.Type, acte.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, acte)
+}
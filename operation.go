@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Opération portant sur plusieurs actifs (morcellement, fusion, mutation...).
+// Toutes les étapes sont validées avant la première écriture : une transaction Fabric ne relit
+// pas ses propres écritures, si bien qu'une validation intercalée entre deux écritures jugerait
+// un état périmé. Un échec de validation rejette la transaction avant toute écriture.
+type operation struct {
+	nom    string
+	etapes []etape
+}
+
+// Étape d'une opération ; valider ne doit pas écrire sur le registre
+type etape struct {
+	description string
+	valider     func() error
+	appliquer   func() error
+}
+
+func nouvelleOperation(nom string) *operation {
+	return &operation{nom: nom}
+}
+
+// Ajouter une étape (valider peut être nil si l'étape n'a rien à contrôler)
+func (o *operation) ajouter(description string, valider func() error, appliquer func() error) {
+	o.etapes = append(o.etapes, etape{description: description, valider: valider, appliquer: appliquer})
+}
+
+// Valider toutes les étapes puis les appliquer dans l'ordre
+func (o *operation) executer(ctx contractapi.TransactionContextInterface) error {
+	for _, e := range o.etapes {
+		if e.valider == nil {
+			continue
+		}
+		if err := e.valider(); err != nil {
+			journalTx(ctx).Info("opération rejetée", slog.String("operation", o.nom), slog.String("etape", e.description), slog.Any("erreur", err))
+			return err
+		}
+	}
+
+	for _, e := range o.etapes {
+		if err := e.appliquer(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites du journal des agents (jour, txId, type, objet)
+const PrefixeTraitementAgent = "TRAITEMENT_AGENT"
+
+// Inscrire au journal des agents le traitement d'un acte par l'appelant
+func enregistrerTraitement(ctx contractapi.TransactionContextInterface, typeTraitement string, objet string, delai int64) error {
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	role, _, err := ctx.GetClientIdentity().GetAttributeValue("role")
+	if err != nil {
+		return fmt.Errorf("erreur de lecture des attributs: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	txId := ctx.GetStub().GetTxID()
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeTraitementAgent, []string{maintenant.Format(FormatDate), txId, typeTraitement, objet})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, &TraitementAgent{
+		Agent:      agent,
+		Role:       role,
+		Type:       typeTraitement,
+		Objet:      objet,
+		TxId:       txId,
+		Horodatage: maintenant.Format(time.RFC3339),
+		Delai:      delai,
+	})
+}
+
+// Bornes d'une période de statistiques : une année (AAAA), un mois (AAAA-MM) ou un jour (AAAA-MM-JJ)
+func bornesPeriode(periode string) (time.Time, time.Time, error) {
+	for _, format := range []struct {
+		disposition      string
+		ans, mois, jours int
+	}{{"2006", 1, 0, 0}, {"2006-01", 0, 1, 0}, {FormatDate, 0, 0, 1}} {
+		if len(periode) != len(format.disposition) {
+			continue
+		}
+		debut, err := time.Parse(format.disposition, periode)
+		if err != nil {
+			break
+		}
+		return debut, debut.AddDate(format.ans, format.mois, format.jours), nil
+	}
+	return time.Time{}, time.Time{}, nouvelleErreur(CodeRequeteInvalide, "période invalide (AAAA, AAAA-MM ou AAAA-MM-JJ attendu): %s", periode)
+}
+
+// Statistiques des agents du registre sur une période (réservé à l'administration), par pages d'au
+// plus taillePage traitements du journal : actes traités par chaque agent, délai moyen de ses décisions
+// et de ses finalisations, demandes d'approbation qui l'attendent encore
+func (s *SmartContract) GetStatistiquesAgents(ctx contractapi.TransactionContextInterface, periode string, taillePage int, signet string) (*StatistiquesAgents, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return nil, err
+	}
+	debut, fin, err := bornesPeriode(periode)
+	if err != nil {
+		return nil, err
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+
+	// Le signet porte le jour en cours de lecture et le signet de pagination de ce jour
+	jour, signetJour := debut, ""
+	if signet != "" {
+		date, suite, _ := strings.Cut(signet, separateurSignetAudit)
+		jour, err = time.Parse(FormatDate, date)
+		if err != nil || jour.Before(debut) || !jour.Before(fin) {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "signet de statistiques invalide: %s", signet)
+		}
+		signetJour = suite
+	}
+
+	agents := map[string]*StatistiquesAgent{}
+	agent := func(id string) *StatistiquesAgent {
+		if agents[id] == nil {
+			agents[id] = &StatistiquesAgent{Agent: id}
+		}
+		return agents[id]
+	}
+	resultat := &StatistiquesAgents{
+		Periode: periode,
+		Debut:   debut.Format(FormatDate),
+		Fin:     fin.AddDate(0, 0, -1).Format(FormatDate),
+	}
+
+	// Le journal est classé par jour : chaque jour de la période est parcouru dans l'ordre
+	restant := taillePage
+	for ; jour.Before(fin); jour, signetJour = jour.AddDate(0, 0, 1), "" {
+		resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeTraitementAgent, []string{jour.Format(FormatDate)}, int32(restant), signetJour)
+		if err != nil {
+			return nil, err
+		}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			var traitement TraitementAgent
+			if err := decoderEtat(queryResponse.Value, &traitement); err != nil {
+				resultsIterator.Close()
+				return nil, fmt.Errorf("traitement illisible %q: %v", queryResponse.Key, err)
+			}
+			restant--
+			statistiques := agent(traitement.Agent)
+			if traitement.Role != "" {
+				statistiques.Role = traitement.Role
+			}
+			switch traitement.Type {
+			case TraitementImmatriculation:
+				statistiques.Immatriculations++
+			case TraitementDemande:
+				statistiques.Demandes++
+			case TraitementApprobation:
+				statistiques.Approbations++
+				statistiques.DelaiCumuleDecision += traitement.Delai
+			case TraitementRejet:
+				statistiques.Rejets++
+				statistiques.DelaiCumuleDecision += traitement.Delai
+			case TraitementFinalisation:
+				statistiques.Finalisations++
+				statistiques.DelaiCumuleFinalisation += traitement.Delai
+			}
+		}
+		resultsIterator.Close()
+		if restant == 0 {
+			resultat.Signet = jour.Format(FormatDate) + separateurSignetAudit + metadonnees.GetBookmark()
+			break
+		}
+	}
+	for _, statistiques := range agents {
+		if decisions := statistiques.Approbations + statistiques.Rejets; decisions > 0 {
+			statistiques.DelaiMoyenDecision = statistiques.DelaiCumuleDecision / int64(decisions)
+		}
+		if statistiques.Finalisations > 0 {
+			statistiques.DelaiMoyenFinalisation = statistiques.DelaiCumuleFinalisation / int64(statistiques.Finalisations)
+		}
+	}
+
+	// Files d'attente des agents de la page : demandes en cours qu'ils ont ouvertes, ou sur lesquelles
+	// leur rôle leur permet encore de se prononcer, lues dans l'index des dossiers en cours
+	if len(agents) > 0 {
+		enCours, err := approbationsEnCours(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, approbation := range enCours {
+			if statistiques := agents[approbation.DemandeePar]; statistiques != nil {
+				statistiques.DemandesEnCours++
+			}
+			for _, statistiques := range agents {
+				if statistiques.Role != "" && attendAgent(*approbation, statistiques.Agent, statistiques.Role) {
+					statistiques.EnAttente++
+				}
+			}
+		}
+	}
+
+	resultat.Agents = make([]StatistiquesAgent, 0, len(agents))
+	for _, statistiques := range agents {
+		resultat.Agents = append(resultat.Agents, *statistiques)
+	}
+	sort.Slice(resultat.Agents, func(i, j int) bool { return resultat.Agents[i].Agent < resultat.Agents[j].Agent })
+	return resultat, nil
+}
+
+// Demandes d'approbation en cours, d'après l'index des dossiers dont le statut n'est pas définitif
+func approbationsEnCours(ctx contractapi.TransactionContextInterface) ([]*Approbation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSuiviEnCours, []string{DossierApprobation})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var approbations []*Approbation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		workflow, objet, _ := strings.Cut(attributs[1], "/")
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeApprobation, []string{workflow, objet})
+		if err != nil {
+			return nil, err
+		}
+		var approbation Approbation
+		existe, err := lireEtat(ctx, cle, &approbation)
+		if err != nil {
+			return nil, err
+		}
+		if existe && approbation.Statut == ApprobationEnCours {
+			approbations = append(approbations, &approbation)
+		}
+	}
+	return approbations, nil
+}
+
+// Délai, en secondes, écoulé depuis l'entrée d'un dossier dans son premier statut suivi (0 si inconnu)
+func delaiDepuisOuverture(ctx contractapi.TransactionContextInterface, nature string, objet string) (int64, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSuiviStatut, []string{nature, objet})
+	if err != nil {
+		return 0, err
+	}
+	var suivi SuiviStatut
+	existe, err := lireEtat(ctx, cle, &suivi)
+	if err != nil || !existe {
+		return 0, err
+	}
+	ouverture := suivi.Depuis
+	if len(suivi.Passages) > 0 {
+		ouverture = suivi.Passages[0].Debut
+	}
+	debut, err := time.Parse(time.RFC3339, ouverture)
+	if err != nil {
+		return 0, nil
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(maintenant.Sub(debut) / time.Second), nil
+}
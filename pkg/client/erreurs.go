@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/status"
+
+	"titrefoncier/pkg/model"
+)
+
+// Échec d'une transaction, avec le code d'erreur métier retourné par le contrat lorsqu'il y en a un
+type Erreur struct {
+	Code    string // Code d'erreur métier (model.Code...), vide si le contrat n'en a pas retourné
+	Message string
+	Classe  string // model.ClasseValidation, model.ClasseConflit ou model.ClasseMetier
+	cause   error
+}
+
+func (e *Erreur) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Erreur) Unwrap() error {
+	return e.cause
+}
+
+// Indiquer si la transaction peut être soumise de nouveau sans modification : seul un conflit MVCC
+// est passager, un refus métier ou une requête invalide échoueraient de la même façon
+func (e *Erreur) Reessayable() bool {
+	return e.Classe == model.ClasseConflit
+}
+
+// Code d'erreur métier d'un échec, vide s'il n'en porte pas
+func CodeErreur(err error) string {
+	var erreur *Erreur
+	if errors.As(err, &erreur) {
+		return erreur.Code
+	}
+	return ""
+}
+
+// Code d'erreur en tête du message du contrat
+var motifCodeErreur = regexp.MustCompile(`^([A-Z][A-Z_]+): `)
+
+// Préfixe ajouté par le pair au message d'échec du chaincode
+const prefixeReponseChaincode = "chaincode response "
+
+// Statuts de validation signalant une lecture périmée
+var conflitsMVCC = []string{
+	peer.TxValidationCode_MVCC_READ_CONFLICT.String(),
+	peer.TxValidationCode_PHANTOM_READ_CONFLICT.String(),
+}
+
+// Décoder l'échec d'une transaction : le message du chaincode figure dans les détails de l'erreur gRPC
+// de la passerelle (endossement), le statut de validation dans le message (validation)
+func decoderErreur(err error) *Erreur {
+	erreur := &Erreur{Message: err.Error(), cause: err}
+	for _, conflit := range conflitsMVCC {
+		if strings.Contains(erreur.Message, conflit) {
+			erreur.Classe = model.ClasseConflit
+			return erreur
+		}
+	}
+
+	if etat, ok := status.FromError(err); ok {
+		for _, detail := range etat.Details() {
+			if detail, ok := detail.(interface{ GetMessage() string }); ok && detail.GetMessage() != "" {
+				erreur.Message = detail.GetMessage()
+				break
+			}
+		}
+	}
+	if i := strings.Index(erreur.Message, prefixeReponseChaincode); i >= 0 {
+		if _, suite, ok := strings.Cut(erreur.Message[i+len(prefixeReponseChaincode):], ", "); ok {
+			erreur.Message = suite
+		}
+	}
+
+	// Réponse enveloppée par le canal, ou message précédé de son code
+	var enveloppe model.Enveloppe
+	if json.Unmarshal([]byte(erreur.Message), &enveloppe) == nil && enveloppe.Code != "" {
+		erreur.Code, erreur.Message, erreur.Classe = enveloppe.Code, enveloppe.Message, enveloppe.Classe
+	} else if correspondance := motifCodeErreur.FindStringSubmatch(erreur.Message); correspondance != nil {
+		erreur.Code, erreur.Message = correspondance[1], strings.TrimPrefix(erreur.Message, correspondance[0])
+	}
+	if erreur.Classe == "" {
+		erreur.Classe = model.ClasseErreur(erreur.Code, erreur.Message)
+	}
+	return erreur
+}
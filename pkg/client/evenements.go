@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Événement de chaincode reçu du service Gateway d'un pair
+type EvenementChaincode struct {
+	Nom     string
+	TxId    string
+	Bloc    uint64
+	Contenu []byte
+}
+
+// Écoute des événements du chaincode par le service Gateway d'un pair, signée par l'identité de
+// l'application (Passerelle.Ecouteur)
+type EcouteurEvenements struct {
+	passerelle *Passerelle
+	identite   Identite
+
+	// Sans position de reprise, l'écoute commence au prochain bloc validé, ou à l'origine du canal
+	// pour les applications qui reconstituent un état depuis le premier bloc
+	DepuisOrigine bool
+}
+
+// Position de reprise d'une écoute, au sens de fabric-gateway
+type position struct {
+	bloc    uint64
+	apresTx string
+}
+
+func (p position) BlockNumber() uint64   { return p.bloc }
+func (p position) TransactionID() string { return p.apresTx }
+
+// Recevoir les événements à partir d'un bloc, après la transaction apresTx de ce bloc si elle est
+// renseignée, jusqu'à l'annulation du contexte ou la rupture du flux
+func (e *EcouteurEvenements) Ecouter(ctx context.Context, bloc uint64, apresTx string, recevoir func(EvenementChaincode) error) error {
+	passerelle, err := e.passerelle.ouvrir(e.identite)
+	if err != nil {
+		return err
+	}
+	defer passerelle.Close()
+
+	ctx, annuler := context.WithCancel(ctx)
+	defer annuler()
+	var options []fabric.ChaincodeEventsOption
+	if e.DepuisOrigine {
+		options = append(options, fabric.WithStartBlock(0))
+	}
+	options = append(options, fabric.WithCheckpoint(position{bloc: bloc, apresTx: apresTx}))
+	evenements, err := passerelle.GetNetwork(e.passerelle.canal).ChaincodeEvents(ctx, e.passerelle.chaincode, options...)
+	if err != nil {
+		return err
+	}
+	for evenement := range evenements {
+		if err := recevoir(EvenementChaincode{Nom: evenement.EventName, TxId: evenement.TransactionID, Bloc: evenement.BlockNumber, Contenu: evenement.Payload}); err != nil {
+			// Le flux n'est libéré qu'une fois ses événements en attente consommés
+			annuler()
+			for range evenements {
+			}
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("flux des événements du canal %s interrompu", e.passerelle.canal)
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"flag"
+	"os"
+)
+
+// Options de ligne de commande des applications du registre : pair, canal et chaincode, puis identité
+type Options struct {
+	Pair      Pair
+	Canal     string
+	Chaincode string
+	Identite  Identite
+}
+
+// Déclarer les options -pair, -tls-ca, -nom-tls, -canal et -chaincode ; les valeurs par défaut du pair
+// sont celles des variables CORE_PEER_* de la CLI peer
+func DeclarerOptions(f *flag.FlagSet) *Options {
+	o := &Options{}
+	f.StringVar(&o.Pair.Adresse, "pair", variable("CORE_PEER_ADDRESS", "localhost:7051"), "adresse gRPC du pair dont le service Gateway est appelé")
+	f.StringVar(&o.Pair.CA, "tls-ca", variable("CORE_PEER_TLS_ROOTCERT_FILE", ""), "certificat de l'autorité TLS du pair (vide : connexion sans TLS)")
+	f.StringVar(&o.Pair.NomTLS, "nom-tls", variable("CORE_PEER_TLS_SERVERHOSTOVERRIDE", ""), "nom attendu du certificat TLS du pair, s'il diffère de son adresse")
+	f.StringVar(&o.Canal, "canal", "mychannel", "canal du registre")
+	f.StringVar(&o.Chaincode, "chaincode", "titrefoncier", "nom du chaincode")
+	return o
+}
+
+// Déclarer en outre les options -msp et -msp-config de l'identité de l'application, par défaut
+// CORE_PEER_LOCALMSPID et CORE_PEER_MSPCONFIGPATH
+func (o *Options) DeclarerIdentite(f *flag.FlagSet) {
+	f.StringVar(&o.Identite.MSP, "msp", variable("CORE_PEER_LOCALMSPID", ""), "MSP de l'identité Fabric de l'application")
+	f.StringVar(&o.Identite.Configuration, "msp-config", variable("CORE_PEER_MSPCONFIGPATH", ""), "répertoire MSP (certificat et clé) de l'identité de l'application")
+}
+
+// Se connecter au pair désigné par les options
+func (o *Options) Connecter() (*Passerelle, error) {
+	return Connecter(o.Pair, o.Canal, o.Chaincode)
+}
+
+// Registre du canal sous l'identité désignée par les options
+func (o *Options) Registre() (*Passerelle, *Registre, error) {
+	passerelle, err := o.Connecter()
+	if err != nil {
+		return nil, nil, err
+	}
+	registre, err := passerelle.Registre(o.Identite)
+	if err != nil {
+		passerelle.Fermer()
+		return nil, nil, err
+	}
+	return passerelle, registre, nil
+}
+
+func variable(nom string, defaut string) string {
+	if valeur := os.Getenv(nom); valeur != "" {
+		return valeur
+	}
+	return defaut
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Identité Fabric d'une application, dans l'arborescence MSP habituelle
+type Identite struct {
+	MSP           string `json:"msp"`           // CORE_PEER_LOCALMSPID
+	Configuration string `json:"configuration"` // CORE_PEER_MSPCONFIGPATH : certificat (signcerts) et clé (keystore) de l'identité
+}
+
+// Pair dont le service Gateway (Fabric 2.4+) reçoit les transactions de l'application
+type Pair struct {
+	Adresse string // Adresse gRPC du pair (ex: peer0.dakar:7051)
+	CA      string // Certificat de l'autorité TLS du pair (vide : sans TLS)
+	NomTLS  string // Nom attendu du certificat TLS, s'il diffère de l'adresse
+}
+
+// Délais des appels au service Gateway
+const (
+	delaiEvaluation  = 30 * time.Second
+	delaiEndossement = 30 * time.Second
+	delaiSoumission  = 15 * time.Second
+	delaiValidation  = time.Minute
+)
+
+// Connexion au service Gateway d'un pair pour le contrat du registre d'un canal. La connexion gRPC
+// est partagée par toutes les identités sous lesquelles l'application appelle le registre : le
+// pair endosse, fait ordonner et suit la validation des transactions qu'elles signent.
+type Passerelle struct {
+	connexion *grpc.ClientConn
+	canal     string
+	chaincode string
+}
+
+// Se connecter au pair ; la connexion est établie au premier appel
+func Connecter(pair Pair, canal string, chaincode string) (*Passerelle, error) {
+	transport := grpc.WithTransportCredentials(insecure.NewCredentials())
+	if pair.CA != "" {
+		certificats, err := credentials.NewClientTLSFromFile(pair.CA, pair.NomTLS)
+		if err != nil {
+			return nil, fmt.Errorf("autorité TLS du pair %s illisible: %v", pair.Adresse, err)
+		}
+		transport = grpc.WithTransportCredentials(certificats)
+	}
+	connexion, err := grpc.Dial(pair.Adresse, transport)
+	if err != nil {
+		return nil, fmt.Errorf("connexion au pair %s impossible: %v", pair.Adresse, err)
+	}
+	return &Passerelle{connexion: connexion, canal: canal, chaincode: chaincode}, nil
+}
+
+// Fermer la connexion au pair
+func (p *Passerelle) Fermer() error {
+	return p.connexion.Close()
+}
+
+// Registre du canal, dont les transactions sont signées par une identité
+func (p *Passerelle) Registre(identite Identite) (*Registre, error) {
+	passerelle, err := p.ouvrir(identite)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistre(passerelle.GetNetwork(p.canal).GetContract(p.chaincode)), nil
+}
+
+// Écouteur des événements du chaincode, reçus sous une identité
+func (p *Passerelle) Ecouteur(identite Identite) *EcouteurEvenements {
+	return &EcouteurEvenements{passerelle: p, identite: identite}
+}
+
+// Passerelle fabric-gateway d'une identité sur la connexion partagée
+func (p *Passerelle) ouvrir(identite Identite) (*fabric.Gateway, error) {
+	id, signature, err := chargerIdentite(identite)
+	if err != nil {
+		return nil, err
+	}
+	return fabric.Connect(id,
+		fabric.WithSign(signature),
+		fabric.WithClientConnection(p.connexion),
+		fabric.WithEvaluateTimeout(delaiEvaluation),
+		fabric.WithEndorseTimeout(delaiEndossement),
+		fabric.WithSubmitTimeout(delaiSoumission),
+		fabric.WithCommitStatusTimeout(delaiValidation),
+	)
+}
+
+// Certificat et clé d'une identité, dans l'arborescence MSP habituelle (signcerts, keystore)
+func chargerIdentite(identite Identite) (*identity.X509Identity, identity.Sign, error) {
+	if identite.MSP == "" || identite.Configuration == "" {
+		return nil, nil, fmt.Errorf("identité Fabric incomplète : MSP et répertoire MSP requis")
+	}
+	contenu, err := premierFichier(filepath.Join(identite.Configuration, "signcerts"))
+	if err != nil {
+		return nil, nil, err
+	}
+	certificat, err := identity.CertificateFromPEM(contenu)
+	if err != nil {
+		return nil, nil, fmt.Errorf("certificat illisible dans %s: %v", identite.Configuration, err)
+	}
+	id, err := identity.NewX509Identity(identite.MSP, certificat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contenu, err = premierFichier(filepath.Join(identite.Configuration, "keystore"))
+	if err != nil {
+		return nil, nil, err
+	}
+	cle, err := identity.PrivateKeyFromPEM(contenu)
+	if err != nil {
+		return nil, nil, fmt.Errorf("clé privée illisible dans %s: %v", identite.Configuration, err)
+	}
+	signature, err := identity.NewPrivateKeySign(cle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("clé privée de %s: %v", identite.Configuration, err)
+	}
+	return id, signature, nil
+}
+
+func premierFichier(repertoire string) ([]byte, error) {
+	entrees, err := os.ReadDir(repertoire)
+	if err != nil {
+		return nil, err
+	}
+	for _, entree := range entrees {
+		if !entree.IsDir() {
+			return os.ReadFile(filepath.Join(repertoire, entree.Name()))
+		}
+	}
+	return nil, fmt.Errorf("aucun fichier dans %s", repertoire)
+}
@@ -0,0 +1,273 @@
+// Package client expose aux applications Go les transactions du registre foncier, typées avec
+// le format d'échange de pkg/model. Il se connecte au service Gateway d'un pair (Connecter), sous
+// l'identité de chaque application, rejoue les soumissions invalidées par un conflit MVCC et décode les
+// codes d'erreur métier.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"titrefoncier/pkg/model"
+)
+
+// Contrat déployé sur un canal, tel que le fournit fabric-gateway (*client.Contract)
+type Contrat interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+}
+
+// Reprises par défaut d'une soumission invalidée par un conflit MVCC
+const (
+	TentativesParDefaut = 3
+	AttenteParDefaut    = 200 * time.Millisecond
+)
+
+// Accès typé au registre foncier d'un canal
+type Registre struct {
+	contrat    Contrat
+	Tentatives int           // Nombre total de soumissions d'une transaction en conflit MVCC
+	Attente    time.Duration // Attente avant la première reprise, doublée à chaque reprise
+}
+
+// Accéder au registre par le contrat d'un canal (gateway.GetNetwork(canal).GetContract(chaincode))
+func NewRegistre(contrat Contrat) *Registre {
+	return &Registre{contrat: contrat, Tentatives: TentativesParDefaut, Attente: AttenteParDefaut}
+}
+
+// Immatriculer un titre foncier
+func (r *Registre) AjouterTitreFoncier(ctx context.Context, id string, proprio string, numTF string, superficie int, document string) error {
+	_, err := r.soumettre(ctx, "AjouterTitreFoncier", id, proprio, numTF, strconv.Itoa(superficie), document)
+	return err
+}
+
+// Immatriculer un titre foncier situé dans une commune
+func (r *Registre) AjouterTitreFoncierCommune(ctx context.Context, id string, proprio string, numTF string, superficie int, document string, commune string) error {
+	_, err := r.soumettre(ctx, "AjouterTitreFoncierCommune", id, proprio, numTF, strconv.Itoa(superficie), document, commune)
+	return err
+}
+
+// Immatriculer un dossier complet : propriétaire, titre, pièces et géométrie
+func (r *Registre) CreerDossierComplet(ctx context.Context, dossier *model.DossierImmatriculation) (*model.TitreFoncier, error) {
+	payload, err := json.Marshal(dossier)
+	if err != nil {
+		return nil, err
+	}
+	var titre model.TitreFoncier
+	if err := r.soumettreDecoder(ctx, &titre, "CreerDossierComplet", string(payload)); err != nil {
+		return nil, err
+	}
+	return &titre, nil
+}
+
+// Lire un titre foncier
+func (r *Registre) LireTitreFoncier(id string) (*model.TitreFoncier, error) {
+	var titre model.TitreFoncier
+	if err := r.evaluerDecoder(&titre, "LireTitreFoncier", id); err != nil {
+		return nil, err
+	}
+	return &titre, nil
+}
+
+// Tous les titres du registre, par pages d'au plus limite titres ; repasser le signet de la page pour
+// obtenir la suivante, jusqu'à un signet vide
+func (r *Registre) TitresPagines(limite int, signet string) (*model.ResultatRecherche, error) {
+	var resultat model.ResultatRecherche
+	if err := r.evaluerDecoder(&resultat, "GetTitresFonciersPagines", strconv.Itoa(limite), signet); err != nil {
+		return nil, err
+	}
+	return &resultat, nil
+}
+
+// Titres d'un propriétaire, par pages de limite titres ; repasser le signet de la page pour obtenir la suivante
+func (r *Registre) TitresParProprietaire(proprio string, limite int, signet string) (*model.ResultatRecherche, error) {
+	selecteur, err := json.Marshal(map[string]string{"proprio": proprio})
+	if err != nil {
+		return nil, err
+	}
+	var resultat model.ResultatRecherche
+	if err := r.evaluerDecoder(&resultat, "RechercherTitres", string(selecteur), "", strconv.Itoa(limite), signet); err != nil {
+		return nil, err
+	}
+	return &resultat, nil
+}
+
+// Titres d'une commune, immatriculés entre du et au inclus (AAAA-MM-JJ, vides : sans borne), par pages
+// de limite titres ; repasser le signet de la page pour obtenir la suivante
+func (r *Registre) TitresParCommune(commune string, du string, au string, limite int, signet string) (*model.ResultatRecherche, error) {
+	critere := map[string]interface{}{"commune": commune}
+	periode := map[string]string{}
+	if du != "" {
+		periode["$gte"] = du
+	}
+	if au != "" {
+		periode["$lte"] = au
+	}
+	if len(periode) > 0 {
+		critere["creeLe"] = periode
+	}
+	selecteur, err := json.Marshal(critere)
+	if err != nil {
+		return nil, err
+	}
+	var resultat model.ResultatRecherche
+	if err := r.evaluerDecoder(&resultat, "RechercherTitres", string(selecteur), "", strconv.Itoa(limite), signet); err != nil {
+		return nil, err
+	}
+	return &resultat, nil
+}
+
+// Titres en vigueur d'une tranche de complétude (model.QualiteFaible, QualiteMoyenne ou QualiteBonne), par
+// pages de limite titres ; repasser le signet de la page pour obtenir la suivante
+func (r *Registre) TitresParQualite(tranche string, limite int, signet string) (*model.ResultatRecherche, error) {
+	var resultat model.ResultatRecherche
+	if err := r.evaluerDecoder(&resultat, "TitresParQualite", tranche, strconv.Itoa(limite), signet); err != nil {
+		return nil, err
+	}
+	return &resultat, nil
+}
+
+// Titres migrés enregistrés sous un livre et un folio des anciens livres fonciers
+func (r *Registre) TitresParAncienneReference(livre string, folio string) ([]*model.TitreFoncier, error) {
+	var titres []*model.TitreFoncier
+	if err := r.evaluerDecoder(&titres, "RechercherParAncienneReference", livre, folio); err != nil {
+		return nil, err
+	}
+	return titres, nil
+}
+
+// Écritures de titres d'une commune après la transaction depuis (vide : depuis l'origine), par pages
+// d'au moins limite entrées ; repasser le signet du flux pour obtenir la suite
+func (r *Registre) ModificationsCommune(commune string, depuis string, limite int) (*model.FluxModifications, error) {
+	var flux model.FluxModifications
+	if err := r.evaluerDecoder(&flux, "GetModificationsCommune", commune, depuis, strconv.Itoa(limite)); err != nil {
+		return nil, err
+	}
+	return &flux, nil
+}
+
+// Dernière écriture journalisée d'un titre
+func (r *Registre) VersionTitre(idTitre string) (*model.ModificationCommune, error) {
+	var version model.ModificationCommune
+	if err := r.evaluerDecoder(&version, "LireVersionTitre", idTitre); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Appliquer une opération saisie hors ligne si le titre en est toujours à la version connue du
+// terminal ; une version périmée est refusée avec le code model.CodeVersionPerimee
+func (r *Registre) OperationHorsLigne(ctx context.Context, idTitre string, version string, fonction string, arguments []string) (*model.OperationAppliquee, error) {
+	liste, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, err
+	}
+	var resultat model.OperationAppliquee
+	if err := r.soumettreDecoder(ctx, &resultat, "AppliquerOperationHorsLigne", idTitre, version, fonction, string(liste)); err != nil {
+		return nil, err
+	}
+	return &resultat, nil
+}
+
+// Proposer la cession d'un titre à un acheteur
+func (r *Registre) ProposerTransfert(ctx context.Context, idTransfert string, idTitre string, acheteur string, prix int) error {
+	_, err := r.soumettre(ctx, "ProposerTransfert", idTransfert, idTitre, acheteur, strconv.Itoa(prix))
+	return err
+}
+
+// Finaliser un transfert une fois les consentements et approbations réunis
+func (r *Registre) FinaliserTransfert(ctx context.Context, idTransfert string) error {
+	_, err := r.soumettre(ctx, "FinaliserTransfert", idTransfert)
+	return err
+}
+
+// Annuler un transfert en attente
+func (r *Registre) AnnulerTransfert(ctx context.Context, idTransfert string) error {
+	_, err := r.soumettre(ctx, "AnnulerTransfert", idTransfert)
+	return err
+}
+
+// Lire un transfert
+func (r *Registre) LireTransfert(idTransfert string) (*model.Transfert, error) {
+	var transfert model.Transfert
+	if err := r.evaluerDecoder(&transfert, "LireTransfert", idTransfert); err != nil {
+		return nil, err
+	}
+	return &transfert, nil
+}
+
+// Évaluer une transaction de consultation
+func (r *Registre) Evaluer(fonction string, arguments ...string) ([]byte, error) {
+	reponse, err := r.contrat.EvaluateTransaction(fonction, arguments...)
+	if err != nil {
+		return nil, decoderErreur(err)
+	}
+	return donnees(reponse), nil
+}
+
+// Soumettre une transaction sans typer sa réponse
+func (r *Registre) Soumettre(ctx context.Context, fonction string, arguments ...string) ([]byte, error) {
+	return r.soumettre(ctx, fonction, arguments...)
+}
+
+// Soumettre une transaction, de nouveau tant qu'elle est invalidée par un conflit MVCC et que les
+// tentatives ne sont pas épuisées
+func (r *Registre) soumettre(ctx context.Context, fonction string, arguments ...string) ([]byte, error) {
+	attente := r.Attente
+	for tentative := 1; ; tentative++ {
+		reponse, err := r.contrat.SubmitTransaction(fonction, arguments...)
+		if err == nil {
+			return donnees(reponse), nil
+		}
+		erreur := decoderErreur(err)
+		if !erreur.Reessayable() || tentative >= r.Tentatives {
+			return nil, erreur
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s abandonnée après %d tentatives: %w", fonction, tentative, ctx.Err())
+		case <-time.After(attente):
+		}
+		attente *= 2
+	}
+}
+
+func (r *Registre) soumettreDecoder(ctx context.Context, v interface{}, fonction string, arguments ...string) error {
+	reponse, err := r.soumettre(ctx, fonction, arguments...)
+	if err != nil {
+		return err
+	}
+	return decoderReponse(reponse, v, fonction)
+}
+
+func (r *Registre) evaluerDecoder(v interface{}, fonction string, arguments ...string) error {
+	reponse, err := r.Evaluer(fonction, arguments...)
+	if err != nil {
+		return err
+	}
+	return decoderReponse(reponse, v, fonction)
+}
+
+func decoderReponse(reponse []byte, v interface{}, fonction string) error {
+	if err := json.Unmarshal(reponse, v); err != nil {
+		return fmt.Errorf("réponse %s invalide: %v", fonction, err)
+	}
+	return nil
+}
+
+// Résultat d'une réponse, que le canal enveloppe ou non les réponses du contrat
+func donnees(reponse []byte) []byte {
+	var enveloppe struct {
+		Code *string         `json:"code"`
+		Data json.RawMessage `json:"data"`
+		TxId *string         `json:"txId"`
+	}
+	if json.Unmarshal(reponse, &enveloppe) != nil || enveloppe.Code == nil || enveloppe.TxId == nil {
+		return reponse
+	}
+	return enveloppe.Data
+}
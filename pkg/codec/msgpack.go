@@ -0,0 +1,635 @@
+// Package codec fournit l'encodage MessagePack des enregistrements d'état du
+// registre. Les champs sont nommés d'après leurs étiquettes json, de sorte
+// qu'un même type Go s'échange indifféremment en JSON ou en MessagePack.
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encoder une valeur en MessagePack ; les clés des maps sont triées pour un résultat déterministe
+func MarshalMsgpack(v interface{}) ([]byte, error) {
+	e := &encodeur{}
+	if err := e.encoder(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.tampon, nil
+}
+
+// Décoder un contenu MessagePack dans la valeur pointée par v
+func UnmarshalMsgpack(donnees []byte, v interface{}) error {
+	cible := reflect.ValueOf(v)
+	if cible.Kind() != reflect.Pointer || cible.IsNil() {
+		return fmt.Errorf("msgpack: cible non pointeur %T", v)
+	}
+	d := &decodeur{donnees: donnees}
+	if err := d.decoder(cible.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.donnees) {
+		return fmt.Errorf("msgpack: %d octets inattendus en fin de contenu", len(d.donnees)-d.pos)
+	}
+	return nil
+}
+
+// Champ de structure sérialisé
+type champ struct {
+	nom       string
+	index     []int
+	omitempty bool
+	etiquete  bool // Nommé par une étiquette json
+}
+
+var champsParType sync.Map // reflect.Type -> []champ
+
+// Champs sérialisés d'un type structure, selon les règles de encoding/json : les champs des structures
+// incorporées sans nom sont promus, le moins profond l'emporte, puis celui qui est étiqueté, et des
+// homonymes indiscernables sont tous omis
+func champsDe(t reflect.Type) []champ {
+	if champs, ok := champsParType.Load(t); ok {
+		return champs.([]champ)
+	}
+	candidats := make(map[string][]champ)
+	var noms []string
+	for _, c := range champsPromus(t, nil, map[reflect.Type]bool{}) {
+		if candidats[c.nom] == nil {
+			noms = append(noms, c.nom)
+		}
+		candidats[c.nom] = append(candidats[c.nom], c)
+	}
+
+	var champs []champ
+	for _, nom := range noms {
+		if retenu, ok := dominant(candidats[nom]); ok {
+			champs = append(champs, retenu)
+		}
+	}
+	sort.Slice(champs, func(i, j int) bool { return avant(champs[i].index, champs[j].index) })
+	champsParType.Store(t, champs)
+	return champs
+}
+
+func champsPromus(t reflect.Type, prefixe []int, visites map[reflect.Type]bool) []champ {
+	if visites[t] {
+		return nil
+	}
+	visites[t] = true
+	defer delete(visites, t)
+
+	var champs []champ
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		etiquette := f.Tag.Get("json")
+		if etiquette == "-" {
+			continue
+		}
+		nom, options, _ := strings.Cut(etiquette, ",")
+		index := append(append([]int(nil), prefixe...), i)
+
+		if f.Anonymous {
+			incorpore := f.Type
+			if incorpore.Kind() == reflect.Pointer {
+				incorpore = incorpore.Elem()
+			}
+			if nom == "" && incorpore.Kind() == reflect.Struct {
+				// Une structure non exportée incorporée par pointeur ne peut être allouée au décodage
+				if f.Type.Kind() == reflect.Pointer && !f.IsExported() {
+					continue
+				}
+				champs = append(champs, champsPromus(incorpore, index, visites)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		champs = append(champs, champ{nom: nomOuDefaut(nom, f.Name), index: index, omitempty: strings.Contains(options, "omitempty"), etiquete: nom != ""})
+	}
+	return champs
+}
+
+func nomOuDefaut(nom string, defaut string) string {
+	if nom == "" {
+		return defaut
+	}
+	return nom
+}
+
+// Champ retenu parmi les homonymes : le moins profond, puis le seul étiqueté à cette profondeur
+func dominant(homonymes []champ) (champ, bool) {
+	profondeur := len(homonymes[0].index)
+	for _, c := range homonymes {
+		profondeur = min(profondeur, len(c.index))
+	}
+	var retenus, etiquetes []champ
+	for _, c := range homonymes {
+		if len(c.index) == profondeur {
+			retenus = append(retenus, c)
+			if c.etiquete {
+				etiquetes = append(etiquetes, c)
+			}
+		}
+	}
+	if len(retenus) == 1 {
+		return retenus[0], true
+	}
+	if len(etiquetes) == 1 {
+		return etiquetes[0], true
+	}
+	return champ{}, false
+}
+
+func avant(a []int, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// Valeur d'un champ, éventuellement promu ; faute d'allocation, un pointeur incorporé nil n'a pas de
+// champ (faux)
+func valeurChamp(v reflect.Value, index []int, allouer bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !allouer {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Noms sérialisés des champs d'une structure (ou d'un pointeur vers une structure)
+func NomsChamps(v interface{}) map[string]bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	noms := make(map[string]bool)
+	if t == nil || t.Kind() != reflect.Struct {
+		return noms
+	}
+	for _, c := range champsDe(t) {
+		noms[c.nom] = true
+	}
+	return noms
+}
+
+func estVide(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+type encodeur struct {
+	tampon []byte
+}
+
+func (e *encodeur) octets(b ...byte) {
+	e.tampon = append(e.tampon, b...)
+}
+
+func (e *encodeur) entete(court byte, limiteCourt int, code8, code16, code32 byte, n int) {
+	switch {
+	case limiteCourt > 0 && n < limiteCourt:
+		e.octets(court | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		e.octets(code8, byte(n))
+	case n <= math.MaxUint16:
+		e.octets(code16)
+		e.tampon = binary.BigEndian.AppendUint16(e.tampon, uint16(n))
+	default:
+		e.octets(code32)
+		e.tampon = binary.BigEndian.AppendUint32(e.tampon, uint32(n))
+	}
+}
+
+func (e *encodeur) entier(n int64) {
+	switch {
+	case n >= 0 && n <= 127:
+		e.octets(byte(n))
+	case n < 0 && n >= -32:
+		e.octets(byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		e.octets(0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		e.octets(0xd1)
+		e.tampon = binary.BigEndian.AppendUint16(e.tampon, uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		e.octets(0xd2)
+		e.tampon = binary.BigEndian.AppendUint32(e.tampon, uint32(n))
+	default:
+		e.octets(0xd3)
+		e.tampon = binary.BigEndian.AppendUint64(e.tampon, uint64(n))
+	}
+}
+
+func (e *encodeur) chaine(s string) {
+	e.entete(0xa0, 32, 0xd9, 0xda, 0xdb, len(s))
+	e.tampon = append(e.tampon, s...)
+}
+
+// Nombre issu d'un décodage JSON, encodé en entier dès que possible
+func (e *encodeur) nombre(n json.Number) error {
+	if i, err := n.Int64(); err == nil {
+		e.entier(i)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("msgpack: nombre invalide %q", n)
+	}
+	e.octets(0xcb)
+	e.tampon = binary.BigEndian.AppendUint64(e.tampon, math.Float64bits(f))
+	return nil
+}
+
+func (e *encodeur) encoder(v reflect.Value) error {
+	if !v.IsValid() {
+		e.octets(0xc0)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.octets(0xc0)
+			return nil
+		}
+		return e.encoder(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.octets(0xc3)
+		} else {
+			e.octets(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.entier(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); u > math.MaxInt64 {
+			e.octets(0xcf)
+			e.tampon = binary.BigEndian.AppendUint64(e.tampon, u)
+		} else {
+			e.entier(int64(u))
+		}
+	case reflect.Float32, reflect.Float64:
+		e.octets(0xcb)
+		e.tampon = binary.BigEndian.AppendUint64(e.tampon, math.Float64bits(v.Float()))
+	case reflect.String:
+		if nombre, ok := v.Interface().(json.Number); ok {
+			return e.nombre(nombre)
+		}
+		e.chaine(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.octets(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.entete(0, 0, 0xc4, 0xc5, 0xc6, v.Len())
+			e.tampon = append(e.tampon, v.Bytes()...)
+			return nil
+		}
+		e.entete(0x90, 16, 0, 0xdc, 0xdd, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encoder(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.octets(0xc0)
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("msgpack: clé de map non supportée %s", v.Type().Key())
+		}
+		cles := v.MapKeys()
+		sort.Slice(cles, func(i, j int) bool { return cles[i].String() < cles[j].String() })
+		e.entete(0x80, 16, 0, 0xde, 0xdf, len(cles))
+		for _, cle := range cles {
+			e.chaine(cle.String())
+			if err := e.encoder(v.MapIndex(cle)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var presents []champ
+		var valeurs []reflect.Value
+		for _, c := range champsDe(v.Type()) {
+			valeur, ok := valeurChamp(v, c.index, false)
+			if !ok || (c.omitempty && estVide(valeur)) {
+				continue
+			}
+			presents = append(presents, c)
+			valeurs = append(valeurs, valeur)
+		}
+		e.entete(0x80, 16, 0, 0xde, 0xdf, len(presents))
+		for i, c := range presents {
+			e.chaine(c.nom)
+			if err := e.encoder(valeurs[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: type non supporté %s", v.Type())
+	}
+	return nil
+}
+
+type decodeur struct {
+	donnees []byte
+	pos     int
+}
+
+func (d *decodeur) lire(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.donnees) {
+		return nil, fmt.Errorf("msgpack: contenu tronqué")
+	}
+	b := d.donnees[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decodeur) longueur(taille int) (int, error) {
+	b, err := d.lire(taille)
+	if err != nil {
+		return 0, err
+	}
+	switch taille {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+// Valeur brute : nil, bool, int64, uint64, float64, string, []byte, []interface{} ou map[string]interface{}
+func (d *decodeur) valeur() (interface{}, error) {
+	b, err := d.lire(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return d.map_(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return d.tableau(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		s, err := d.lire(int(code & 0x1f))
+		return string(s), err
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.longueur(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		octets, err := d.lire(n)
+		return append([]byte(nil), octets...), err
+	case 0xca:
+		o, err := d.lire(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(o))), nil
+	case 0xcb:
+		o, err := d.lire(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(o)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		o, err := d.lire(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, x := range o {
+			u = u<<8 | uint64(x)
+		}
+		return u, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		taille := 1 << (code - 0xd0)
+		o, err := d.lire(taille)
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, x := range o {
+			u = u<<8 | uint64(x)
+		}
+		decalage := uint(64 - 8*taille)
+		return int64(u<<decalage) >> decalage, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.longueur(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.lire(n)
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := d.longueur(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.tableau(n)
+	case 0xde, 0xdf:
+		n, err := d.longueur(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.map_(n)
+	}
+	return nil, fmt.Errorf("msgpack: code 0x%02x non supporté", code)
+}
+
+func (d *decodeur) tableau(n int) ([]interface{}, error) {
+	elements := make([]interface{}, n)
+	for i := range elements {
+		var err error
+		if elements[i], err = d.valeur(); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+func (d *decodeur) map_(n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		cle, err := d.valeur()
+		if err != nil {
+			return nil, err
+		}
+		nom, ok := cle.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: clé de map non textuelle")
+		}
+		if m[nom], err = d.valeur(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (d *decodeur) decoder(cible reflect.Value) error {
+	brute, err := d.valeur()
+	if err != nil {
+		return err
+	}
+	return affecter(cible, brute)
+}
+
+// Affecter une valeur brute décodée à la cible typée
+func affecter(cible reflect.Value, brute interface{}) error {
+	if brute == nil {
+		cible.Set(reflect.Zero(cible.Type()))
+		return nil
+	}
+
+	switch cible.Kind() {
+	case reflect.Pointer:
+		if cible.IsNil() {
+			cible.Set(reflect.New(cible.Type().Elem()))
+		}
+		return affecter(cible.Elem(), brute)
+	case reflect.Interface:
+		cible.Set(reflect.ValueOf(brute))
+		return nil
+	case reflect.Bool:
+		b, ok := brute.(bool)
+		if !ok {
+			return erreurType(cible, brute)
+		}
+		cible.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := brute.(type) {
+		case int64:
+			cible.SetInt(n)
+		case uint64:
+			cible.SetInt(int64(n))
+		default:
+			return erreurType(cible, brute)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := brute.(type) {
+		case int64:
+			cible.SetUint(uint64(n))
+		case uint64:
+			cible.SetUint(n)
+		default:
+			return erreurType(cible, brute)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := brute.(type) {
+		case float64:
+			cible.SetFloat(n)
+		case int64:
+			cible.SetFloat(float64(n))
+		case uint64:
+			cible.SetFloat(float64(n))
+		default:
+			return erreurType(cible, brute)
+		}
+	case reflect.String:
+		s, ok := brute.(string)
+		if !ok {
+			return erreurType(cible, brute)
+		}
+		cible.SetString(s)
+	case reflect.Slice:
+		if octets, ok := brute.([]byte); ok && cible.Type().Elem().Kind() == reflect.Uint8 {
+			cible.SetBytes(octets)
+			return nil
+		}
+		elements, ok := brute.([]interface{})
+		if !ok {
+			return erreurType(cible, brute)
+		}
+		tranche := reflect.MakeSlice(cible.Type(), len(elements), len(elements))
+		for i, element := range elements {
+			if err := affecter(tranche.Index(i), element); err != nil {
+				return err
+			}
+		}
+		cible.Set(tranche)
+	case reflect.Map:
+		m, ok := brute.(map[string]interface{})
+		if !ok || cible.Type().Key().Kind() != reflect.String {
+			return erreurType(cible, brute)
+		}
+		resultat := reflect.MakeMapWithSize(cible.Type(), len(m))
+		for cle, element := range m {
+			valeur := reflect.New(cible.Type().Elem()).Elem()
+			if err := affecter(valeur, element); err != nil {
+				return err
+			}
+			resultat.SetMapIndex(reflect.ValueOf(cle).Convert(cible.Type().Key()), valeur)
+		}
+		cible.Set(resultat)
+	case reflect.Struct:
+		m, ok := brute.(map[string]interface{})
+		if !ok {
+			return erreurType(cible, brute)
+		}
+		for _, c := range champsDe(cible.Type()) {
+			element, present := m[c.nom]
+			if !present {
+				continue
+			}
+			valeur, _ := valeurChamp(cible, c.index, true)
+			if err := affecter(valeur, element); err != nil {
+				return fmt.Errorf("champ %s: %v", c.nom, err)
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: type non supporté %s", cible.Type())
+	}
+	return nil
+}
+
+func erreurType(cible reflect.Value, brute interface{}) error {
+	return fmt.Errorf("msgpack: impossible d'affecter %T à %s", brute, cible.Type())
+}
@@ -0,0 +1,170 @@
+package codec
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type adresse struct {
+	Commune string `json:"commune"`
+	Lot     string `json:"lot,omitempty"`
+}
+
+type Horodatage struct {
+	CreeLe string `json:"creeLe"`
+	Id     string `json:"id"` // Masqué par le champ de même nom, moins profond
+}
+
+type enregistrement struct {
+	adresse
+	*Horodatage
+	Id         string            `json:"id"`
+	Superficie int               `json:"superficie"`
+	Valeur     float64           `json:"valeur,omitempty"`
+	Negatif    int64             `json:"negatif"`
+	Grand      uint64            `json:"grand"`
+	Actif      bool              `json:"actif"`
+	Empreinte  []byte            `json:"empreinte,omitempty"`
+	Charges    []string          `json:"charges"`
+	Parts      map[string]int    `json:"parts,omitempty"`
+	Suivant    *enregistrement   `json:"suivant,omitempty"`
+	Attributs  map[string]string `json:"-"`
+	interne    string
+}
+
+func TestAllerRetour(t *testing.T) {
+	cas := []enregistrement{
+		{Id: "TF1"},
+		{
+			adresse:    adresse{Commune: "Dakar", Lot: "12"},
+			Horodatage: &Horodatage{CreeLe: "2024-01-01"},
+			Id:         "TF2",
+			Superficie: 70000,
+			Valeur:     1.5,
+			Negatif:    -40000,
+			Grand:      math.MaxUint64,
+			Actif:      true,
+			Empreinte:  []byte{0, 1, 2},
+			Charges:    []string{"", "hypothèque"},
+			Parts:      map[string]int{"b": 2, "a": -1},
+			Suivant:    &enregistrement{Id: "TF3", Charges: []string{}},
+		},
+	}
+	for _, original := range cas {
+		contenu, err := MarshalMsgpack(original)
+		if err != nil {
+			t.Fatalf("%s: encodage: %v", original.Id, err)
+		}
+		var decode enregistrement
+		if err := UnmarshalMsgpack(contenu, &decode); err != nil {
+			t.Fatalf("%s: décodage: %v", original.Id, err)
+		}
+		if !reflect.DeepEqual(decode, original) {
+			t.Errorf("%s: aller-retour\n obtenu  %+v\n attendu %+v", original.Id, decode, original)
+		}
+	}
+}
+
+// Les champs sérialisés portent les noms, et suivent les règles de promotion, de encoding/json
+func TestChampsCommeJSON(t *testing.T) {
+	original := enregistrement{adresse: adresse{Commune: "Thiès"}, Horodatage: &Horodatage{CreeLe: "2024-01-01", Id: "masqué"}, Id: "TF1"}
+
+	contenuJSON, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attendu map[string]interface{}
+	if err := json.Unmarshal(contenuJSON, &attendu); err != nil {
+		t.Fatal(err)
+	}
+
+	contenu, err := MarshalMsgpack(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obtenu map[string]interface{}
+	if err := UnmarshalMsgpack(contenu, &obtenu); err != nil {
+		t.Fatal(err)
+	}
+	if len(obtenu) != len(attendu) {
+		t.Fatalf("champs obtenus %v, attendus %v", obtenu, attendu)
+	}
+	for nom := range attendu {
+		if _, ok := obtenu[nom]; !ok {
+			t.Errorf("champ %s absent", nom)
+		}
+	}
+	if obtenu["id"] != "TF1" || obtenu["commune"] != "Thiès" {
+		t.Errorf("id = %v, commune = %v, attendu les champs les moins profonds", obtenu["id"], obtenu["commune"])
+	}
+	noms := NomsChamps(&original)
+	for nom := range attendu {
+		if !noms[nom] {
+			t.Errorf("champ %s absent de NomsChamps", nom)
+		}
+	}
+}
+
+// Un pointeur incorporé nil n'apporte aucun champ, et il est alloué au décodage de ses champs
+func TestIncorporeNil(t *testing.T) {
+	contenu, err := MarshalMsgpack(enregistrement{Id: "TF1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var champs map[string]interface{}
+	if err := UnmarshalMsgpack(contenu, &champs); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := champs["creeLe"]; ok {
+		t.Errorf("creeLe encodé pour un pointeur incorporé nil")
+	}
+
+	contenu, err = MarshalMsgpack(map[string]interface{}{"id": "TF1", "creeLe": "2024-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decode enregistrement
+	if err := UnmarshalMsgpack(contenu, &decode); err != nil {
+		t.Fatal(err)
+	}
+	if decode.Horodatage == nil || decode.CreeLe != "2024-01-01" {
+		t.Errorf("horodatage incorporé non décodé: %+v", decode.Horodatage)
+	}
+}
+
+// Les nombres d'un décodage JSON générique sont encodés en entiers lorsqu'ils le sont
+func TestNombresJSON(t *testing.T) {
+	contenu, err := MarshalMsgpack(map[string]interface{}{"entier": json.Number("42"), "reel": json.Number("0.5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decode map[string]interface{}
+	if err := UnmarshalMsgpack(contenu, &decode); err != nil {
+		t.Fatal(err)
+	}
+	if decode["entier"] != int64(42) || decode["reel"] != 0.5 {
+		t.Errorf("nombres décodés %v", decode)
+	}
+}
+
+func TestContenuInvalide(t *testing.T) {
+	contenu, err := MarshalMsgpack(enregistrement{Id: "TF1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decode enregistrement
+	if err := UnmarshalMsgpack(contenu[:len(contenu)-1], &decode); err == nil {
+		t.Error("contenu tronqué accepté")
+	}
+	if err := UnmarshalMsgpack(append(contenu, 0xc0), &decode); err == nil {
+		t.Error("octets finaux acceptés")
+	}
+	if err := UnmarshalMsgpack(contenu, decode); err == nil {
+		t.Error("cible non pointeur acceptée")
+	}
+	if _, err := MarshalMsgpack(map[int]string{1: "a"}); err == nil {
+		t.Error("clé de map non textuelle acceptée")
+	}
+}
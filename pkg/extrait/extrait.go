@@ -0,0 +1,187 @@
+// Package extrait produit les extraits tabulaires (CSV, XLSX) des titres d'une commune que les
+// bureaux fonciers transmettent à leur hiérarchie. Les titres sont lus page par page et chaque
+// page est écrite dès sa lecture : un extrait n'est jamais tenu en mémoire en entier.
+package extrait
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"titrefoncier/pkg/client"
+	"titrefoncier/pkg/model"
+)
+
+// Formats d'extrait
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Titres lus par page du registre
+const TaillePage = 100
+
+// Colonne d'un extrait : son en-tête et sa valeur pour un titre
+type Colonne struct {
+	Nom       string
+	Numerique bool // Écrite comme un nombre dans un classeur
+	valeur    func(t *model.TitreFoncier) string
+}
+
+var colonnes = []Colonne{
+	{Nom: "id", valeur: func(t *model.TitreFoncier) string { return t.Id }},
+	{Nom: "numTF", valeur: func(t *model.TitreFoncier) string { return t.NumTF }},
+	{Nom: "proprio", valeur: func(t *model.TitreFoncier) string { return t.Proprio }},
+	{Nom: "commune", valeur: func(t *model.TitreFoncier) string { return t.Commune }},
+	{Nom: "superficie", Numerique: true, valeur: func(t *model.TitreFoncier) string { return strconv.Itoa(t.Superficie) }},
+	{Nom: "bureau", valeur: func(t *model.TitreFoncier) string { return t.BureauFoncier }},
+	{Nom: "zones", valeur: func(t *model.TitreFoncier) string { return strings.Join(t.Zones, "|") }},
+	{Nom: "creeLe", valeur: func(t *model.TitreFoncier) string { return t.CreeLe }},
+	{Nom: "dernierActiviteLe", valeur: func(t *model.TitreFoncier) string { return t.DernierActiviteLe }},
+	{Nom: "inalienable", valeur: func(t *model.TitreFoncier) string { return strconv.FormatBool(t.Inalienable) }},
+	{Nom: "charges", Numerique: true, valeur: func(t *model.TitreFoncier) string { return strconv.Itoa(len(t.Charges)) }},
+	{Nom: "saisies", Numerique: true, valeur: func(t *model.TitreFoncier) string { return strconv.Itoa(len(t.Saisies)) }},
+	{Nom: "hash", valeur: func(t *model.TitreFoncier) string { return t.DocHash }},
+}
+
+// Colonnes retenues à défaut de sélection
+var ColonnesParDefaut = []string{"id", "numTF", "proprio", "superficie", "creeLe"}
+
+// Colonnes d'après leurs noms, dans l'ordre demandé
+func Colonnes(noms []string) ([]Colonne, error) {
+	if len(noms) == 0 {
+		noms = ColonnesParDefaut
+	}
+	selection := make([]Colonne, 0, len(noms))
+	for _, nom := range noms {
+		trouvee := false
+		for _, c := range colonnes {
+			if c.Nom == nom {
+				selection = append(selection, c)
+				trouvee = true
+				break
+			}
+		}
+		if !trouvee {
+			return nil, fmt.Errorf("colonne inconnue: %q (disponibles: %s)", nom, strings.Join(NomsColonnes(), ", "))
+		}
+	}
+	return selection, nil
+}
+
+// Noms de toutes les colonnes disponibles
+func NomsColonnes() []string {
+	noms := make([]string, len(colonnes))
+	for i, c := range colonnes {
+		noms[i] = c.Nom
+	}
+	return noms
+}
+
+// Titres extraits : une commune et, facultativement, une période d'immatriculation
+type Filtre struct {
+	Commune string
+	Du      string // Immatriculés à partir de cette date (AAAA-MM-JJ)
+	Au      string // Immatriculés jusqu'à cette date incluse (AAAA-MM-JJ)
+}
+
+// Une période, facultative, est bornée par des dates AAAA-MM-JJ dans l'ordre
+func (f Filtre) Valider() error {
+	if f.Commune == "" {
+		return fmt.Errorf("la commune de l'extrait est requise")
+	}
+	for _, date := range []string{f.Du, f.Au} {
+		if date == "" {
+			continue
+		}
+		if _, err := model.AnalyserDate(date); err != nil {
+			return err
+		}
+	}
+	if f.Du != "" && f.Au != "" && f.Au < f.Du {
+		return fmt.Errorf("période vide: du %s au %s", f.Du, f.Au)
+	}
+	return nil
+}
+
+// Écriture d'un extrait ligne à ligne ; Vider transmet les lignes déjà écrites
+type Ecrivain interface {
+	Ligne(valeurs []string) error
+	Vider() error
+	Fermer() error
+}
+
+// Vérifier qu'un format d'extrait est pris en charge
+func ValiderFormat(format string) error {
+	if format != FormatCSV && format != FormatXLSX {
+		return fmt.Errorf("format d'extrait inconnu: %q (attendu: %s, %s)", format, FormatCSV, FormatXLSX)
+	}
+	return nil
+}
+
+// Écrivain d'un format d'extrait
+func NouvelEcrivain(format string, w io.Writer, colonnes []Colonne) (Ecrivain, error) {
+	if err := ValiderFormat(format); err != nil {
+		return nil, err
+	}
+	if format == FormatXLSX {
+		return nouveauXLSX(w, colonnes)
+	}
+	return nouveauCSV(w), nil
+}
+
+// Type MIME d'un format d'extrait
+func TypeContenu(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Écrire l'extrait des titres du filtre et renvoyer le nombre de titres écrits. La première page est
+// lue avant toute écriture : un échec d'accès au registre laisse la sortie intacte.
+func Exporter(r *client.Registre, filtre Filtre, colonnes []Colonne, nouvelEcrivain func() (Ecrivain, error)) (int, error) {
+	if err := filtre.Valider(); err != nil {
+		return 0, err
+	}
+	page, err := r.TitresParCommune(filtre.Commune, filtre.Du, filtre.Au, TaillePage, "")
+	if err != nil {
+		return 0, err
+	}
+	ecrivain, err := nouvelEcrivain()
+	if err != nil {
+		return 0, err
+	}
+	entetes := make([]string, len(colonnes))
+	for i, c := range colonnes {
+		entetes[i] = c.Nom
+	}
+	if err := ecrivain.Ligne(entetes); err != nil {
+		return 0, err
+	}
+
+	nombre := 0
+	valeurs := make([]string, len(colonnes))
+	for {
+		for _, titre := range page.Titres {
+			for i, c := range colonnes {
+				valeurs[i] = c.valeur(titre)
+			}
+			if err := ecrivain.Ligne(valeurs); err != nil {
+				return nombre, err
+			}
+			nombre++
+		}
+		if err := ecrivain.Vider(); err != nil {
+			return nombre, err
+		}
+		if page.Signet == "" {
+			break
+		}
+		if page, err = r.TitresParCommune(filtre.Commune, filtre.Du, filtre.Au, TaillePage, page.Signet); err != nil {
+			return nombre, err
+		}
+	}
+	return nombre, ecrivain.Fermer()
+}
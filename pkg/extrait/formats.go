@@ -0,0 +1,133 @@
+package extrait
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type ecrivainCSV struct {
+	csv *csv.Writer
+}
+
+func nouveauCSV(w io.Writer) *ecrivainCSV {
+	return &ecrivainCSV{csv: csv.NewWriter(w)}
+}
+
+// Une valeur qu'un tableur prendrait pour une formule (=, +, -, @) est précédée d'une apostrophe
+func (e *ecrivainCSV) Ligne(valeurs []string) error {
+	ligne := make([]string, len(valeurs))
+	for i, v := range valeurs {
+		if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				v = "'" + v
+			}
+		}
+		ligne[i] = v
+	}
+	return e.csv.Write(ligne)
+}
+
+func (e *ecrivainCSV) Vider() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func (e *ecrivainCSV) Fermer() error {
+	return e.Vider()
+}
+
+// Parties fixes d'un classeur d'une seule feuille
+var partiesXLSX = []struct{ nom, contenu string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Titres" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// Classeur XLSX écrit au fil de l'eau : la feuille, dernière partie de l'archive, reçoit les lignes
+// en chaînes en ligne (sans table de chaînes partagées, qu'il faudrait connaître d'avance)
+type ecrivainXLSX struct {
+	archive    *zip.Writer
+	feuille    *bufio.Writer
+	colonnes   []Colonne
+	ligne      int
+	references []string
+}
+
+func nouveauXLSX(w io.Writer, colonnes []Colonne) (*ecrivainXLSX, error) {
+	archive := zip.NewWriter(w)
+	for _, partie := range partiesXLSX {
+		f, err := archive.Create(partie.nom)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, partie.contenu); err != nil {
+			return nil, err
+		}
+	}
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &ecrivainXLSX{archive: archive, feuille: bufio.NewWriter(f), colonnes: colonnes}
+	for i := range colonnes {
+		e.references = append(e.references, referenceColonne(i))
+	}
+	_, err = e.feuille.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return e, err
+}
+
+// Lettres de la colonne i (0 : A, 26 : AA)
+func referenceColonne(i int) string {
+	reference := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		reference = string(rune('A'+(i-1)%26)) + reference
+	}
+	return reference
+}
+
+// Les en-têtes, première ligne, sont toujours des chaînes
+func (e *ecrivainXLSX) Ligne(valeurs []string) error {
+	e.ligne++
+	numero := strconv.Itoa(e.ligne)
+	e.feuille.WriteString(`<row r="` + numero + `">`)
+	for i, v := range valeurs {
+		reference := e.references[i] + numero
+		if e.ligne > 1 && e.colonnes[i].Numerique {
+			e.feuille.WriteString(`<c r="` + reference + `"><v>` + v + `</v></c>`)
+			continue
+		}
+		e.feuille.WriteString(`<c r="` + reference + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(e.feuille, []byte(v))
+		e.feuille.WriteString(`</t></is></c>`)
+	}
+	_, err := e.feuille.WriteString(`</row>`)
+	return err
+}
+
+func (e *ecrivainXLSX) Vider() error {
+	if err := e.feuille.Flush(); err != nil {
+		return err
+	}
+	return e.archive.Flush()
+}
+
+func (e *ecrivainXLSX) Fermer() error {
+	if _, err := e.feuille.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := e.feuille.Flush(); err != nil {
+		return err
+	}
+	return e.archive.Close()
+}
@@ -0,0 +1,33 @@
+package model
+
+// Préfixe des événements d'alerte, suivi des identifiants des abonnements concernés
+// (ex: "AlerteAbonnement:tx1,tx7")
+const EvenementAlerteAbonnement = "AlerteAbonnement"
+
+// Nombre maximal d'abonnements nommés par l'événement d'alerte ; au-delà, l'événement ne porte que son
+// préfixe et les abonnements se lisent dans son contenu
+const AbonnementsNommesMax = 10
+
+// Abonnement à une recherche permanente ; les critères renseignés doivent tous correspondre
+type Abonnement struct {
+	Id      string `json:"id"`                                     // Transaction de création
+	Abonne  string `json:"abonne"`                                 // Identité du client abonné
+	IdTitre string `json:"idTitre,omitempty" metadata:",optional"` // Titre suivi
+	Commune string `json:"commune,omitempty" metadata:",optional"` // Commune de situation du titre
+	Proprio string `json:"proprio,omitempty" metadata:",optional"` // Nom du propriétaire (comparé sans accents ni casse)
+	Statut  string `json:"statut,omitempty" metadata:",optional"`  // Statut de transfert (EN_ATTENTE, FINALISE, ANNULE)
+	CreeLe  string `json:"creeLe"`                                 // Date de création (AAAA-MM-JJ)
+}
+
+// Déclenchement d'un abonnement par une modification du registre
+type Declenchement struct {
+	IdAbonnement string `json:"idAbonnement"`
+	IdTitre      string `json:"idTitre"`
+	Statut       string `json:"statut,omitempty" metadata:",optional"` // Statut du transfert à l'origine de l'alerte
+}
+
+// Contenu de l'événement d'alerte d'une transaction
+type AlerteAbonnement struct {
+	TxId           string          `json:"txId"`
+	Declenchements []Declenchement `json:"declenchements"`
+}
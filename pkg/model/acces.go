@@ -0,0 +1,21 @@
+package model
+
+// Consultation motivée d'un titre, tracée dans la collection privée du conservateur
+type AccesTitre struct {
+	Id           string `json:"id"` // Transaction de consultation
+	IdTitre      string `json:"idTitre"`
+	Lecteur      string `json:"lecteur"`      // Identité du client (banque, notaire, ...)
+	Organisation string `json:"organisation"` // MSP du client
+	Motif        string `json:"motif"`        // Finalité déclarée de la consultation
+	Date         string `json:"date"`         // Horodatage de la consultation (RFC 3339)
+}
+
+// Consultation motivée en vigueur, qui ouvre au lecteur d'une organisation tierce les lectures
+// simples du titre jusqu'à son expiration ; le motif reste dans le journal privé
+type ConsultationDeclaree struct {
+	IdTitre      string `json:"idTitre"`
+	Lecteur      string `json:"lecteur"`
+	Organisation string `json:"organisation"`
+	Acces        string `json:"acces"`    // Transaction de la consultation motivée
+	ExpireLe     string `json:"expireLe"` // RFC 3339
+}
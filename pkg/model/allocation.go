@@ -0,0 +1,45 @@
+package model
+
+import "fmt"
+
+// Plafond de superficie attribuable dans un zonage d'une commune, configuré par canal
+type PlafondZone struct {
+	Commune    string `json:"commune"`    // Commune concernée
+	Zone       string `json:"zone"`       // Zonage plafonné (ex: "littorale")
+	Superficie int    `json:"superficie"` // Superficie totale attribuable (m²)
+}
+
+// Vérifier la cohérence d'un plafond de zone
+func (p *PlafondZone) Valider() error {
+	if p.Commune == "" || p.Zone == "" {
+		return fmt.Errorf("un plafond de zone exige une commune et un zonage")
+	}
+	if p.Superficie <= 0 {
+		return fmt.Errorf("le plafond de la zone %q de %s doit être positif", p.Zone, p.Commune)
+	}
+	return nil
+}
+
+// Superficie qu'un titre occupe dans un zonage d'une commune, tenue à chaque écriture du titre sous
+// sa propre clé : les titres d'un même zonage s'écrivent sans se disputer un compteur commun
+type AllocationTitre struct {
+	Commune    string `json:"commune"`
+	Zone       string `json:"zone"`
+	IdTitre    string `json:"idTitre"`
+	Superficie int    `json:"superficie"` // Superficie du titre (m²)
+}
+
+// Situation d'un zonage d'une commune au regard de son plafond
+type QuotaZone struct {
+	Zone       string `json:"zone"`
+	Attribuee  int    `json:"attribuee"`                                 // Superficie attribuée (m²)
+	Titres     int    `json:"titres"`                                    // Titres actifs du zonage
+	Plafond    int    `json:"plafond,omitempty" metadata:",optional"`    // Plafond configuré (0 : aucun)
+	Disponible int    `json:"disponible,omitempty" metadata:",optional"` // Superficie encore attribuable sous le plafond
+}
+
+// Quotas de superficie d'une commune, par zonage
+type QuotasCommune struct {
+	Commune string      `json:"commune"`
+	Zones   []QuotaZone `json:"zones"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import "fmt"
+
+// Procédures soumises, lorsque la configuration le prévoit, à l'approbation collégiale d'agents
+const (
+	WorkflowTransfert    = "TRANSFERT"    // Objet : identifiant du transfert
+	WorkflowMorcellement = "MORCELLEMENT" // Objet : titre morcelé, détails : lots demandés (JSON)
+	WorkflowZonage       = "ZONAGE"       // Objet : titre rezoné, détails : zonages demandés (liste JSON)
+)
+
+// Statuts d'une demande d'approbation
+const (
+	ApprobationEnCours  = "EN_COURS"
+	ApprobationAccordee = "ACCORDEE"
+	ApprobationRejetee  = "REJETEE"
+	ApprobationUtilisee = "UTILISEE" // Consommée par l'acte approuvé
+)
+
+// Décisions d'un approbateur
+const (
+	DecisionApprouve = "APPROUVE"
+	DecisionRejete   = "REJETE"
+)
+
+// Quorum d'approbation d'une procédure, configuré par canal : la demande est accordée dès que Quorum
+// agents distincts, portant l'un des rôles désignés, l'ont approuvée ; un seul rejet la clôt
+type QuorumApprobation struct {
+	Workflow string   `json:"workflow"`
+	Roles    []string `json:"roles"`  // Rôles des agents habilités à se prononcer
+	Quorum   int      `json:"quorum"` // Nombre d'approbations requises
+}
+
+// Demande d'approbation d'un acte, identifiée par sa procédure et son objet
+type Approbation struct {
+	Workflow    string                `json:"workflow"`
+	Objet       string                `json:"objet"`
+	Details     string                `json:"details"` // Contenu exact de l'acte soumis (vide si l'objet suffit)
+	Roles       []string              `json:"roles"`
+	Quorum      int                   `json:"quorum"`
+	Statut      string                `json:"statut"`
+	Decisions   []DecisionApprobation `json:"decisions"`
+	DemandeePar string                `json:"demandeePar"`
+	DemandeeLe  string                `json:"demandeeLe"` // Horodatage de la demande (RFC 3339)
+}
+
+// Décision d'un agent sur une demande d'approbation
+type DecisionApprobation struct {
+	Approbateur string `json:"approbateur"` // Identité de l'agent
+	Role        string `json:"role"`
+	Decision    string `json:"decision"` // APPROUVE ou REJETE
+	Motif       string `json:"motif,omitempty" metadata:",optional"`
+	Horodatage  string `json:"horodatage"` // RFC 3339
+}
+
+// Vérifier la cohérence d'un quorum d'approbation
+func (q *QuorumApprobation) Valider() error {
+	switch q.Workflow {
+	case WorkflowTransfert, WorkflowMorcellement, WorkflowZonage:
+	default:
+		return fmt.Errorf("procédure d'approbation inconnue: %s", q.Workflow)
+	}
+	if len(q.Roles) == 0 {
+		return fmt.Errorf("le quorum de la procédure %s doit désigner au moins un rôle", q.Workflow)
+	}
+	if q.Quorum <= 0 {
+		return fmt.Errorf("le quorum de la procédure %s doit être positif", q.Workflow)
+	}
+	return nil
+}
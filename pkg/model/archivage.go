@@ -0,0 +1,20 @@
+package model
+
+// Statuts d'une demande d'archivage
+const (
+	ArchivageEnAttente = "EN_ATTENTE"
+	ArchivageConfirme  = "CONFIRME"
+)
+
+// Demande d'archivage d'un titre, soumise à la règle des deux conservateurs
+type DemandeArchivage struct {
+	Id          string        `json:"id"`                                         // Transaction de proposition
+	IdTitre     string        `json:"idTitre"`                                    // Titre foncier à archiver
+	Motif       string        `json:"motif"`                                      // Justification de l'archivage
+	ProposePar  string        `json:"proposePar"`                                 // Identité du conservateur proposant
+	ProposeLe   string        `json:"proposeLe"`                                  // Horodatage de la proposition (RFC 3339)
+	ConfirmePar string        `json:"confirmePar,omitempty" metadata:",optional"` // Identité du second conservateur
+	ConfirmeLe  string        `json:"confirmeLe,omitempty" metadata:",optional"`  // Horodatage de la confirmation (RFC 3339)
+	Statut      string        `json:"statut"`                                     // EN_ATTENTE ou CONFIRME
+	Titre       *TitreFoncier `json:"titre,omitempty" metadata:",optional"`       // Dernier état du titre, conservé à l'archivage
+}
@@ -0,0 +1,68 @@
+package model
+
+import "fmt"
+
+// Statuts d'une police d'assurance de titre
+const (
+	AssuranceActive   = "ACTIVE"
+	AssuranceResiliee = "RESILIEE"
+)
+
+// Définition d'une assurance de titre rattachée à une parcelle
+type AssuranceTitre struct {
+	IdTitre      string `json:"idTitre"`      // Titre foncier assuré
+	Assureur     string `json:"assureur"`     // MSP de la compagnie d'assurance
+	NumeroPolice string `json:"numeroPolice"` // Numéro de police
+	Couverture   int    `json:"couverture"`   // Montant couvert en FCFA
+	DateDebut    string `json:"dateDebut"`    // Début de validité (AAAA-MM-JJ)
+	DateFin      string `json:"dateFin"`      // Fin de validité (AAAA-MM-JJ)
+	Statut       string `json:"statut"`       // ACTIVE ou RESILIEE
+}
+
+// Définition d'une évaluation de la valeur vénale d'un titre
+type Evaluation struct {
+	IdTitre    string `json:"idTitre"`    // Titre foncier évalué
+	Valeur     int    `json:"valeur"`     // Valeur estimée en FCFA
+	Source     string `json:"source"`     // Méthode ou référence de l'évaluation
+	Date       string `json:"date"`       // Date d'effet de l'évaluation (AAAA-MM-JJ)
+	Evaluateur string `json:"evaluateur"` // Identité de l'évaluateur agréé
+	TxId       string `json:"txId"`       // Transaction d'enregistrement
+}
+
+// Créer une assurance de titre active
+func NouvelleAssurance(idTitre string, assureur string, numeroPolice string, couverture int, dateDebut string, dateFin string) *AssuranceTitre {
+	return &AssuranceTitre{
+		IdTitre:      idTitre,
+		Assureur:     assureur,
+		NumeroPolice: numeroPolice,
+		Couverture:   couverture,
+		DateDebut:    dateDebut,
+		DateFin:      dateFin,
+		Statut:       AssuranceActive,
+	}
+}
+
+// Vérifier la cohérence d'une assurance de titre
+func (a *AssuranceTitre) Valider() error {
+	if a.NumeroPolice == "" {
+		return fmt.Errorf("le numéro de police est obligatoire")
+	}
+	if a.Couverture <= 0 {
+		return fmt.Errorf("la couverture doit être positive")
+	}
+	return verifierPeriode(a.DateDebut, a.DateFin)
+}
+
+// Indiquer si une assurance couvre le titre à la date donnée
+func (a *AssuranceTitre) EstValide(date string) bool {
+	return a.Statut == AssuranceActive && a.DateDebut <= date && date <= a.DateFin
+}
+
+// Vérifier la cohérence d'une évaluation
+func (e *Evaluation) Valider() error {
+	if e.Valeur <= 0 {
+		return fmt.Errorf("la valeur doit être positive")
+	}
+	_, err := AnalyserDate(e.Date)
+	return err
+}
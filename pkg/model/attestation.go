@@ -0,0 +1,31 @@
+package model
+
+// Événement émis à la délivrance d'une attestation de propriété
+const EvenementAttestationEmise = "AttestationEmise"
+
+// Statuts d'une attestation de propriété, suivis dans les délais de traitement
+const (
+	AttestationEmise   = "EMISE"
+	AttestationExpiree = "EXPIREE" // Validité écoulée
+)
+
+// Attestation de propriété à durée limitée, remise à un tiers (bailleur, consulat) à la place du dossier
+type Attestation struct {
+	Id           string `json:"id"` // Transaction de délivrance, à communiquer au destinataire
+	IdTitre      string `json:"idTitre"`
+	NumTF        string `json:"numTF"`
+	Proprio      string `json:"proprio"`      // Propriétaire attesté
+	Destinataire string `json:"destinataire"` // Tiers auquel l'attestation est destinée
+	EmiseLe      string `json:"emiseLe"`      // Horodatage de délivrance (RFC 3339)
+	ExpireLe     string `json:"expireLe"`     // Fin de validité (RFC 3339)
+	Empreinte    string `json:"empreinte"`    // SHA-256 de l'attestation, empreinte et signature vides
+	Signataire   string `json:"signataire"`   // Autorité de conservation foncière signataire
+	Signature    string `json:"signature"`    // Signature de l'empreinte par l'autorité (base64)
+}
+
+// Résultat de la vérification d'une attestation par un tiers
+type VerificationAttestation struct {
+	Valide      bool         `json:"valide"`
+	Motif       string       `json:"motif,omitempty" metadata:",optional"` // Raison de l'invalidité
+	Attestation *Attestation `json:"attestation,omitempty" metadata:",optional"`
+}
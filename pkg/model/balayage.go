@@ -0,0 +1,28 @@
+package model
+
+// Échéances couvertes par le balayage périodique
+const (
+	BalayagePromesses  = "PROMESSE"  // Fin de l'exclusivité des promesses de vente actives
+	BalayageUsufruits  = "USUFRUIT"  // Terme des usufruits temporaires
+	BalayageArchivages = "ARCHIVAGE" // Délai de confirmation des demandes d'archivage en attente
+)
+
+// Événement regroupant les rappels d'échéance d'un balayage
+const EvenementRappelsEcheances = "RappelsEcheances"
+
+// Rappel d'une échéance proche
+type RappelEcheance struct {
+	Type     string `json:"type"`
+	Objet    string `json:"objet"` // Promesse, titre ou demande d'archivage concerné
+	IdTitre  string `json:"idTitre"`
+	Echeance string `json:"echeance"` // AAAA-MM-JJ
+	Jours    int    `json:"jours"`    // Jours restants
+}
+
+// Résultat d'un passage du balayage des échéances
+type ResultatBalayage struct {
+	Type     string           `json:"type"`
+	Examines int              `json:"examines"` // Enregistrements parcourus lors de ce passage
+	Rappels  []RappelEcheance `json:"rappels"`
+	Termine  bool             `json:"termine"` // Le parcours est complet, le prochain passage reprend au début
+}
@@ -0,0 +1,48 @@
+package model
+
+import "fmt"
+
+// Bénéficiaire effectif d'un titre détenu par une personne morale ou un prête-nom
+type BeneficiaireEffectif struct {
+	Nom            string `json:"nom"`
+	DateNaissance  string `json:"dateNaissance,omitempty" metadata:",optional"` // AAAA-MM-JJ
+	Nationalite    string `json:"nationalite,omitempty" metadata:",optional"`
+	Pourcentage    int    `json:"pourcentage"`                                   // Part détenue, directement ou non (en %)
+	NatureControle string `json:"natureControle,omitempty" metadata:",optional"` // Détention du capital, droits de vote, autre contrôle
+}
+
+// Déclaration des bénéficiaires effectifs d'un titre ; la plus récente remplace les précédentes
+type DeclarationBeneficiaires struct {
+	Id            string                 `json:"id"` // Transaction de déclaration
+	IdTitre       string                 `json:"idTitre"`
+	Titulaire     string                 `json:"titulaire"` // Propriétaire inscrit au titre lors de la déclaration
+	Beneficiaires []BeneficiaireEffectif `json:"beneficiaires"`
+	DeclarePar    string                 `json:"declarePar"` // Identité du déclarant
+	DeclareLe     string                 `json:"declareLe"`  // Horodatage de la déclaration (RFC 3339)
+}
+
+// Vérifier la cohérence d'une déclaration de bénéficiaires effectifs
+func (d *DeclarationBeneficiaires) Valider() error {
+	if len(d.Beneficiaires) == 0 {
+		return fmt.Errorf("au moins un bénéficiaire effectif doit être déclaré")
+	}
+	total := 0
+	for _, b := range d.Beneficiaires {
+		if b.Nom == "" {
+			return fmt.Errorf("le nom de chaque bénéficiaire effectif est obligatoire")
+		}
+		if b.Pourcentage < 0 || b.Pourcentage > 100 {
+			return fmt.Errorf("la part de %s doit être comprise entre 0 et 100", b.Nom)
+		}
+		if b.DateNaissance != "" {
+			if _, err := AnalyserDate(b.DateNaissance); err != nil {
+				return err
+			}
+		}
+		total += b.Pourcentage
+	}
+	if total > 100 {
+		return fmt.Errorf("les parts déclarées dépassent 100%% (%d%%)", total)
+	}
+	return nil
+}
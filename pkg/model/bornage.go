@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Statuts d'une rectification de bornage
+const (
+	BornagePropose  = "PROPOSEE"  // En attente des signatures des propriétaires
+	BornageApplique = "APPLIQUEE" // Contours et superficies des deux titres rectifiés
+	BornageRefuse   = "REFUSEE"   // Refusée par l'une des parties
+)
+
+// Qualités des signataires d'une rectification de bornage
+const (
+	SignataireGeometre     = "GEOMETRE"
+	SignataireProprietaire = "PROPRIETAIRE"
+)
+
+// Demande de rectification de la limite commune de deux parcelles contiguës, établie par un géomètre
+type DemandeBornage struct {
+	Parcelles    []ParcelleBornage `json:"parcelles"`    // Les deux parcelles, avec leur contour rectifié
+	ProcesVerbal PieceDossier      `json:"procesVerbal"` // Procès-verbal de bornage, émis par une autorité reconnue
+	DateEffet    string            `json:"dateEffet"`    // Date du bornage (AAAA-MM-JJ), d'entrée en vigueur des contours rectifiés
+}
+
+// Parcelle d'une demande de rectification de bornage
+type ParcelleBornage struct {
+	IdTitre    string          `json:"idTitre"`
+	Geometrie  json.RawMessage `json:"geometrie"`  // Contour rectifié (GeoJSON, WGS84 ou UTM 28N)
+	Superficie int             `json:"superficie"` // Superficie rectifiée en m²
+}
+
+// Rectification de bornage entre deux titres, appliquée aux deux à la fois une fois signée du géomètre
+// et des deux propriétaires
+type RectificationBornage struct {
+	Id            string              `json:"id"`
+	Parcelles     []ParcelleRectifiee `json:"parcelles"`
+	ProcesVerbal  DocumentTitre       `json:"procesVerbal"` // Rattaché aux deux titres à l'application
+	DateEffet     string              `json:"dateEffet"`    // Entrée en vigueur des contours rectifiés (AAAA-MM-JJ)
+	Geometre      string              `json:"geometre"`     // Identité du géomètre auteur de la proposition
+	Statut        string              `json:"statut"`
+	ProposeeLe    string              `json:"proposeeLe"` // RFC 3339
+	TxId          string              `json:"txId"`
+	Signatures    []SignatureBornage  `json:"signatures"`
+	AppliqueeLe   string              `json:"appliqueeLe,omitempty" metadata:",optional"` // RFC 3339
+	TxApplication string              `json:"txApplication,omitempty" metadata:",optional"`
+	Motif         string              `json:"motif,omitempty" metadata:",optional"` // Motif du refus
+}
+
+// Parcelle d'une rectification de bornage : contour et superficie avant et après rectification
+type ParcelleRectifiee struct {
+	IdTitre            string `json:"idTitre"`
+	Proprio            string `json:"proprio"` // Propriétaire appelé à signer
+	Geometrie          string `json:"geometrie"`
+	SystemeCoordonnees string `json:"systemeCoordonnees"` // Système de référence du contour déposé
+	Superficie         int    `json:"superficie"`
+	AncienneGeometrie  string `json:"ancienneGeometrie,omitempty" metadata:",optional"`
+	AncienneSuperficie int    `json:"ancienneSuperficie"`
+}
+
+// Signature d'une rectification de bornage
+type SignatureBornage struct {
+	Qualite    string `json:"qualite"`                                // GEOMETRE ou PROPRIETAIRE
+	IdTitre    string `json:"idTitre,omitempty" metadata:",optional"` // Titre du propriétaire signataire
+	Signataire string `json:"signataire"`                             // Propriétaire, ou identité du géomètre
+	Identite   string `json:"identite"`                               // Identité du certificat de l'appelant
+	Horodatage string `json:"horodatage"`                             // RFC 3339
+	TxId       string `json:"txId"`
+}
+
+// Vérifier la cohérence interne d'une demande de rectification de bornage
+func (d *DemandeBornage) Valider() error {
+	if len(d.Parcelles) != 2 {
+		return fmt.Errorf("une rectification de bornage porte sur deux parcelles, %d indiquées", len(d.Parcelles))
+	}
+	for _, parcelle := range d.Parcelles {
+		if parcelle.IdTitre == "" {
+			return fmt.Errorf("le titre foncier de chaque parcelle est obligatoire")
+		}
+		if len(parcelle.Geometrie) == 0 {
+			return fmt.Errorf("le contour rectifié du titre foncier %s est obligatoire", parcelle.IdTitre)
+		}
+		if parcelle.Superficie <= 0 {
+			return fmt.Errorf("la superficie rectifiée du titre foncier %s doit être positive", parcelle.IdTitre)
+		}
+	}
+	if d.Parcelles[0].IdTitre == d.Parcelles[1].IdTitre {
+		return fmt.Errorf("les deux parcelles doivent relever de titres distincts")
+	}
+	if d.ProcesVerbal.Chemin == "" || d.ProcesVerbal.Issuer == "" {
+		return fmt.Errorf("le procès-verbal de bornage et son émetteur sont obligatoires")
+	}
+	if _, err := AnalyserDate(d.DateEffet); err != nil {
+		return fmt.Errorf("date du bornage: %v", err)
+	}
+	return nil
+}
+
+// Indiquer si une parcelle a reçu la signature de son propriétaire
+func (r *RectificationBornage) SigneePar(idTitre string) bool {
+	for _, signature := range r.Signatures {
+		if signature.Qualite == SignataireProprietaire && signature.IdTitre == idTitre {
+			return true
+		}
+	}
+	return false
+}
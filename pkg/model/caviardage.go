@@ -0,0 +1,142 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Traitements d'un champ caviardé, du plus léger au plus strict
+const (
+	CaviardageInitiales = "INITIALES" // Chaque mot réduit à son initiale (ex: "Awa Diop" : "A. D.")
+	CaviardageMasque    = "MASQUE"    // Valeur remplacée par "***" (null pour une valeur non textuelle)
+	CaviardageRetrait   = "RETRAIT"   // Champ retiré de la réponse
+)
+
+// Valeur substituée aux champs masqués
+const ValeurMasquee = "***"
+
+// Règle de caviardage des réponses de consultation, configurée par canal : les champs désignés de
+// l'enregistrement lu ou des éléments de liste (titres d'une recherche, versions d'un historique, ...)
+// sont caviardés pour les appelants des rôles concernés
+type RegleCaviardage struct {
+	Roles      []string `json:"roles"`                                     // Rôles des appelants (ex: "citoyen", "notaire")
+	Champs     []string `json:"champs"`                                    // Noms JSON des champs, à toute profondeur des éléments (ex: "proprio")
+	Traitement string   `json:"traitement"`                                // INITIALES, MASQUE ou RETRAIT
+	Operations []string `json:"operations,omitempty" metadata:",optional"` // Transactions concernées (vide : toutes les consultations)
+}
+
+// Vérifier la cohérence d'une règle de caviardage
+func (r *RegleCaviardage) Valider() error {
+	if len(r.Roles) == 0 || len(r.Champs) == 0 {
+		return fmt.Errorf("une règle de caviardage désigne au moins un rôle et un champ")
+	}
+	for _, valeur := range append(append([]string{}, r.Roles...), r.Champs...) {
+		if strings.TrimSpace(valeur) == "" {
+			return fmt.Errorf("les rôles et champs d'une règle de caviardage ne peuvent être vides")
+		}
+	}
+	if rangTraitement(r.Traitement) <= 0 {
+		return fmt.Errorf("traitement de caviardage inconnu: %s (attendu: %s, %s ou %s)", r.Traitement, CaviardageInitiales, CaviardageMasque, CaviardageRetrait)
+	}
+	return nil
+}
+
+// Indiquer si la règle s'applique à un appelant du rôle donné consultant par operation ; un appelant
+// sans rôle relève de toutes les règles
+func (r *RegleCaviardage) Concerne(role string, operation string) bool {
+	if len(r.Operations) > 0 && !intersecte(r.Operations, []string{operation}) {
+		return false
+	}
+	return role == "" || intersecte(r.Roles, []string{role})
+}
+
+// Règles de caviardage applicables à un appelant du rôle donné consultant par operation
+func (c *Configuration) ReglesCaviardage(role string, operation string) []RegleCaviardage {
+	var regles []RegleCaviardage
+	for _, regle := range c.Caviardage {
+		if regle.Concerne(role, operation) {
+			regles = append(regles, regle)
+		}
+	}
+	return regles
+}
+
+// Caviarder une réponse JSON, à toute profondeur ; un champ visé par plusieurs règles reçoit le
+// traitement le plus strict. Le contenu est rendu tel quel si aucune règle ne s'applique.
+func CaviarderReponse(contenu []byte, regles []RegleCaviardage) ([]byte, error) {
+	traitements := map[string]string{}
+	for _, regle := range regles {
+		for _, champ := range regle.Champs {
+			if rangTraitement(regle.Traitement) > rangTraitement(traitements[champ]) {
+				traitements[champ] = regle.Traitement
+			}
+		}
+	}
+	if len(traitements) == 0 {
+		return contenu, nil
+	}
+
+	decodeur := json.NewDecoder(bytes.NewReader(contenu))
+	decodeur.UseNumber()
+	var valeur interface{}
+	if err := decodeur.Decode(&valeur); err != nil {
+		return nil, err
+	}
+	return json.Marshal(caviarderValeur(valeur, traitements))
+}
+
+func caviarderValeur(valeur interface{}, traitements map[string]string) interface{} {
+	switch v := valeur.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = caviarderValeur(v[i], traitements)
+		}
+	case map[string]interface{}:
+		for champ, contenu := range v {
+			traitement, vise := traitements[champ]
+			if !vise {
+				v[champ] = caviarderValeur(contenu, traitements)
+				continue
+			}
+			texte, textuel := contenu.(string)
+			switch {
+			case traitement == CaviardageRetrait:
+				delete(v, champ)
+			case traitement == CaviardageInitiales && textuel:
+				v[champ] = Initiales(texte)
+			case textuel && texte != "":
+				v[champ] = ValeurMasquee
+			case !textuel:
+				v[champ] = nil
+			}
+		}
+	}
+	return valeur
+}
+
+// Initiales d'un nom, chaque mot réduit à sa première lettre (ex: "Awa Diop" : "A. D.")
+func Initiales(nom string) string {
+	mots := strings.Fields(nom)
+	for i, mot := range mots {
+		mots[i] = string(unicode.ToUpper([]rune(mot)[0])) + "."
+	}
+	return strings.Join(mots, " ")
+}
+
+func rangTraitement(traitement string) int {
+	switch traitement {
+	case "":
+		return 0
+	case CaviardageInitiales:
+		return 1
+	case CaviardageMasque:
+		return 2
+	case CaviardageRetrait:
+		return 3
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValiderRegleCaviardage(t *testing.T) {
+	valide := RegleCaviardage{Roles: []string{"citoyen"}, Champs: []string{"proprio"}, Traitement: CaviardageInitiales}
+	if err := valide.Valider(); err != nil {
+		t.Errorf("règle valide: %v", err)
+	}
+	invalides := map[string]RegleCaviardage{
+		"sans rôle":          {Champs: []string{"proprio"}, Traitement: CaviardageMasque},
+		"sans champ":         {Roles: []string{"citoyen"}, Traitement: CaviardageMasque},
+		"rôle vide":          {Roles: []string{" "}, Champs: []string{"proprio"}, Traitement: CaviardageMasque},
+		"champ vide":         {Roles: []string{"citoyen"}, Champs: []string{""}, Traitement: CaviardageMasque},
+		"sans traitement":    {Roles: []string{"citoyen"}, Champs: []string{"proprio"}},
+		"traitement inconnu": {Roles: []string{"citoyen"}, Champs: []string{"proprio"}, Traitement: "FLOU"},
+	}
+	for nom, regle := range invalides {
+		if err := regle.Valider(); err == nil {
+			t.Errorf("%s: règle acceptée", nom)
+		}
+	}
+}
+
+func TestReglesCaviardage(t *testing.T) {
+	config := &Configuration{Caviardage: []RegleCaviardage{
+		{Roles: []string{"citoyen"}, Champs: []string{"proprio"}, Traitement: CaviardageInitiales},
+		{Roles: []string{"citoyen", "notaire"}, Champs: []string{"nin"}, Traitement: CaviardageRetrait, Operations: []string{"RechercherTitres"}},
+	}}
+	cas := []struct {
+		role      string
+		operation string
+		regles    int
+	}{
+		{"citoyen", "RechercherTitres", 2},
+		{"citoyen", "LireTitreFoncier", 1},
+		{"notaire", "RechercherTitres", 1},
+		{"notaire", "LireTitreFoncier", 0},
+		{"conservateur", "RechercherTitres", 0},
+		// Un appelant sans rôle relève de toutes les règles des opérations qu'il consulte
+		{"", "RechercherTitres", 2},
+		{"", "LireTitreFoncier", 1},
+	}
+	for _, c := range cas {
+		if regles := config.ReglesCaviardage(c.role, c.operation); len(regles) != c.regles {
+			t.Errorf("rôle %q, %s: %d règles, %d attendues", c.role, c.operation, len(regles), c.regles)
+		}
+	}
+}
+
+func TestCaviarderReponse(t *testing.T) {
+	contenu := []byte(`[{"id":"TF1","proprio":"awa  Ndiaye Diop","nin":"1234567890123","superficie":70000,` +
+		`"vendeur":{"nom":"Élodie Sarr","telephone":771234567},"notes":""},` +
+		`{"id":"TF2","proprio":"","nin":null,"historique":[{"proprio":"Moussa Fall"}]}]`)
+	regles := []RegleCaviardage{
+		{Roles: []string{"citoyen"}, Champs: []string{"proprio", "nom", "telephone"}, Traitement: CaviardageInitiales},
+		{Roles: []string{"citoyen"}, Champs: []string{"nin", "notes"}, Traitement: CaviardageMasque},
+		// La règle la plus stricte l'emporte sur un même champ
+		{Roles: []string{"citoyen"}, Champs: []string{"notes", "superficie"}, Traitement: CaviardageRetrait},
+		{Roles: []string{"citoyen"}, Champs: []string{"telephone"}, Traitement: CaviardageMasque},
+	}
+	caviarde, err := CaviarderReponse(contenu, regles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attendu := `[{"id":"TF1","nin":"***","proprio":"A. N. D.","vendeur":{"nom":"É. S.","telephone":null}},` +
+		`{"historique":[{"proprio":"M. F."}],"id":"TF2","nin":null,"proprio":""}]`
+	var obtenu, reference interface{}
+	json.Unmarshal(caviarde, &obtenu)
+	json.Unmarshal([]byte(attendu), &reference)
+	if !reflect.DeepEqual(obtenu, reference) {
+		t.Errorf("réponse caviardée\n obtenue  %s\n attendue %s", caviarde, attendu)
+	}
+
+	// Sans règle, le contenu est rendu octet pour octet ; les nombres ne passent pas par float64
+	if rendu, err := CaviarderReponse(contenu, nil); err != nil || string(rendu) != string(contenu) {
+		t.Errorf("contenu sans règle modifié: %s (%v)", rendu, err)
+	}
+	grand := []byte(`{"proprio":"Awa","montant":123456789012345678}`)
+	if rendu, _ := CaviarderReponse(grand, regles[:1]); string(rendu) != `{"montant":123456789012345678,"proprio":"A."}` {
+		t.Errorf("nombre altéré: %s", rendu)
+	}
+	if _, err := CaviarderReponse([]byte(`{"proprio":`), regles); err == nil {
+		t.Error("réponse JSON invalide caviardée")
+	}
+}
+
+func TestInitiales(t *testing.T) {
+	for nom, attendues := range map[string]string{
+		"Awa Diop":          "A. D.",
+		"  mame   diarra  ": "M. D.",
+		"Ndèye":             "N.",
+		"élodie":            "É.",
+		"":                  "",
+	} {
+		if obtenues := Initiales(nom); obtenues != attendues {
+			t.Errorf("Initiales(%q) = %q, attendu %q", nom, obtenues, attendues)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+// Invariants contrôlés par l'audit de cohérence du registre
+const (
+	AnomalieProprietaireManquant = "PROPRIETAIRE_MANQUANT" // Titre sans propriétaire
+	AnomalieProprietaireFusionne = "PROPRIETAIRE_FUSIONNE" // Titre au nom d'un propriétaire absorbé par une fusion
+	AnomalieTantiemes            = "TANTIEMES_INCOHERENTS" // Tantièmes des lots d'une copropriété différents du total
+	AnomalieBeneficiaires        = "PARTS_BENEFICIAIRES"   // Parts des bénéficiaires effectifs déclarés ne totalisant pas 100 %
+	AnomalieIndexManquant        = "INDEX_MANQUANT"        // Entrée d'index attendue pour un titre mais absente
+	AnomalieIndexOrphelin        = "INDEX_ORPHELIN"        // Entrée d'index vers un titre absent du registre (archivé ou supprimé)
+	AnomalieIndexPerime          = "INDEX_PERIME"          // Entrée d'index ne correspondant plus au titre
+)
+
+// Violation d'un invariant relevée par l'audit de cohérence
+type AnomalieCoherence struct {
+	Type      string   `json:"type"`
+	IdTitre   string   `json:"idTitre"`
+	Index     string   `json:"index,omitempty" metadata:",optional"`     // Préfixe de l'entrée d'index concernée
+	Attributs []string `json:"attributs,omitempty" metadata:",optional"` // Attributs de l'entrée d'index concernée
+	Detail    string   `json:"detail"`
+}
+
+// Page du rapport d'audit de cohérence : chaque page contrôle une étape (les titres, puis chaque index)
+type RapportCoherence struct {
+	Etape     string              `json:"etape"`     // Enregistrements contrôlés sur la page
+	Controles int                 `json:"controles"` // Nombre d'enregistrements contrôlés sur la page
+	Anomalies []AnomalieCoherence `json:"anomalies"`
+	Nombre    int                 `json:"nombre"` // Nombre d'anomalies de la page
+	Signet    string              `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin d'audit)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Portées d'un consentement au partage des données d'un propriétaire
+const (
+	PorteeTitre   = "TITRE"   // Enregistrement des titres (DeclarerConsultation)
+	PorteeDossier = "DOSSIER" // Dossier complet des titres : enregistrement, assurances, évaluation, permis (LireDossierTitre)
+)
+
+// Consentement d'un propriétaire à la consultation de ses titres par un tiers (banque, employeur),
+// exigé des lectures déléguées ; un nouveau consentement au même destinataire remplace le précédent
+type ConsentementPartage struct {
+	Id           string `json:"id"` // Transaction d'octroi
+	Proprio      string `json:"proprio"`
+	Destinataire string `json:"destinataire"`                             // MSP de l'organisation autorisée à consulter
+	Portee       string `json:"portee"`                                   // TITRE ou DOSSIER
+	AccordeLe    string `json:"accordeLe"`                                // RFC 3339
+	ExpireLe     string `json:"expireLe"`                                 // RFC 3339
+	RevoqueLe    string `json:"revoqueLe,omitempty" metadata:",optional"` // RFC 3339
+	TxRevocation string `json:"txRevocation,omitempty" metadata:",optional"`
+}
+
+// Indiquer si le consentement autorise, à la date donnée, une consultation de la portée demandée ;
+// la portée DOSSIER comprend la portée TITRE
+func (c *ConsentementPartage) Autorise(portee string, date time.Time) bool {
+	if c.RevoqueLe != "" || (c.Portee != portee && c.Portee != PorteeDossier) {
+		return false
+	}
+	expireLe, err := time.Parse(time.RFC3339, c.ExpireLe)
+	return err == nil && date.Before(expireLe)
+}
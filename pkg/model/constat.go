@@ -0,0 +1,12 @@
+package model
+
+// Constat dressé sur le terrain par un inspecteur, rattaché au titre foncier visité
+type ConstatTerrain struct {
+	Id           string   `json:"id"` // Transaction d'enregistrement
+	IdTitre      string   `json:"idTitre"`
+	Coordonnees  string   `json:"coordonnees"`  // Position relevée ou emprise occupée constatée (GeoJSON)
+	Photos       []string `json:"photos"`       // Hashes des photos prises sur place
+	Observations string   `json:"observations"` // Occupation constatée (construction, clôture, cultures, ...)
+	Inspecteur   string   `json:"inspecteur"`   // Identité de l'inspecteur
+	Horodatage   string   `json:"horodatage"`   // Date et heure du constat (RFC3339)
+}
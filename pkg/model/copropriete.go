@@ -0,0 +1,75 @@
+package model
+
+import "fmt"
+
+// Copropriété constituée sur un titre foncier : le titre demeure, ses lots sont cédés séparément
+type Copropriete struct {
+	IdTitre         string          `json:"idTitre"`         // Titre foncier support de la copropriété
+	Syndic          string          `json:"syndic"`          // Identité du syndic en exercice
+	TantiemesTotal  int             `json:"tantiemesTotal"`  // Total des quotes-parts (ex: 1000 ou 10000)
+	Lots            []string        `json:"lots"`            // Numéros des lots privatifs
+	PartiesCommunes []PartieCommune `json:"partiesCommunes"` // Parties communes à tous les copropriétaires
+	CreeLe          string          `json:"creeLe"`          // Date de constitution (AAAA-MM-JJ)
+	// Délibération de l'assemblée générale des copropriétaires ayant désigné le syndic en exercice
+	Deliberation string `json:"deliberation,omitempty" metadata:",optional"`
+}
+
+// Partie commune d'une copropriété (hall, toiture, parking, ...)
+type PartieCommune struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Lot privatif d'une copropriété, avec sa quote-part des parties communes
+type LotCopropriete struct {
+	IdTitre     string `json:"idTitre"`
+	Numero      string `json:"numero"`
+	Description string `json:"description"`
+	Superficie  int    `json:"superficie"` // Superficie privative en m²
+	Tantiemes   int    `json:"tantiemes"`  // Quote-part des parties communes
+	Proprio     string `json:"proprio"`
+	Acte        string `json:"acte,omitempty" metadata:",optional"` // Dernier acte de cession du lot
+	// Acheteur auquel le propriétaire du lot a consenti la cession, jusqu'à son enregistrement
+	CessionConsentie string   `json:"cessionConsentie,omitempty" metadata:",optional"`
+	Saisies          []string `json:"saisies,omitempty" metadata:",optional"` // Procédures de saisie conservatoire en vigueur sur le lot
+}
+
+// État descriptif de division soumis à la constitution d'une copropriété
+type DescriptifCopropriete struct {
+	TantiemesTotal  int              `json:"tantiemesTotal"`
+	Lots            []LotCopropriete `json:"lots"`
+	PartiesCommunes []PartieCommune  `json:"partiesCommunes"`
+}
+
+// Vérifier la cohérence d'un état descriptif de division : les tantièmes des lots font le total déclaré
+func (d *DescriptifCopropriete) Valider() error {
+	if d.TantiemesTotal <= 0 {
+		return fmt.Errorf("le total des tantièmes doit être positif")
+	}
+	if len(d.Lots) < 2 {
+		return fmt.Errorf("une copropriété comporte au moins deux lots")
+	}
+	numeros := make(map[string]bool)
+	somme := 0
+	for _, lot := range d.Lots {
+		if lot.Numero == "" || numeros[lot.Numero] {
+			return fmt.Errorf("numéro de lot absent ou dupliqué: %q", lot.Numero)
+		}
+		numeros[lot.Numero] = true
+		if lot.Tantiemes <= 0 {
+			return fmt.Errorf("les tantièmes du lot %s doivent être positifs", lot.Numero)
+		}
+		somme += lot.Tantiemes
+	}
+	if somme != d.TantiemesTotal {
+		return fmt.Errorf("les tantièmes des lots (%d) diffèrent du total déclaré (%d)", somme, d.TantiemesTotal)
+	}
+	codes := make(map[string]bool)
+	for _, partie := range d.PartiesCommunes {
+		if partie.Code == "" || codes[partie.Code] {
+			return fmt.Errorf("code de partie commune absent ou dupliqué: %q", partie.Code)
+		}
+		codes[partie.Code] = true
+	}
+	return nil
+}
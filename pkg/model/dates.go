@@ -0,0 +1,34 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Format des dates métier (échéances, validités)
+const FormatDate = "2006-01-02"
+
+// Analyser une date métier au format AAAA-MM-JJ
+func AnalyserDate(valeur string) (time.Time, error) {
+	date, err := time.Parse(FormatDate, valeur)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date invalide %q (format attendu AAAA-MM-JJ)", valeur)
+	}
+	return date, nil
+}
+
+// Vérifier qu'une période de validité est bien formée
+func verifierPeriode(dateDebut string, dateFin string) error {
+	debut, err := AnalyserDate(dateDebut)
+	if err != nil {
+		return err
+	}
+	fin, err := AnalyserDate(dateFin)
+	if err != nil {
+		return err
+	}
+	if !fin.After(debut) {
+		return fmt.Errorf("la date de fin %s doit être postérieure au %s", dateFin, dateDebut)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package model
+
+import "fmt"
+
+// Usages d'une décision de justice comme fondement d'une opération du registre
+const (
+	ExecutionGel         = "GEL"
+	ExecutionMainlevee   = "MAINLEVEE"
+	ExecutionTransfert   = "TRANSFERT_FORCE"
+	ExecutionCloture     = "CLOTURE_LITIGE"
+	ExecutionRealisation = "REALISATION_HYPOTHEQUE"
+)
+
+// Décision de justice enregistrée par le greffe de la juridiction qui l'a rendue. Sa portée, ses
+// titres et son bénéficiaire bornent les opérations qu'elle peut fonder sur le registre.
+type DecisionJudiciaire struct {
+	Juridiction    string              `json:"juridiction"`                                  // Identifiant du tribunal au registre des autorités
+	Numero         string              `json:"numero"`                                       // Numéro de la décision au répertoire de la juridiction
+	Dispositif     string              `json:"dispositif"`                                   // Ce que la juridiction ordonne
+	Portee         string              `json:"portee"`                                       // Usage ordonné : GEL, MAINLEVEE, TRANSFERT_FORCE, CLOTURE_LITIGE ou REALISATION_HYPOTHEQUE
+	Titres         []string            `json:"titres"`                                       // Titres fonciers visés par le dispositif
+	Beneficiaire   string              `json:"beneficiaire,omitempty" metadata:",optional"`  // Propriétaire désigné (TRANSFERT_FORCE)
+	DecisionLevee  string              `json:"decisionLevee,omitempty" metadata:",optional"` // Décision de gel levée, "juridiction:numero" (MAINLEVEE)
+	HashJugement   string              `json:"hashJugement"`
+	RendueLe       string              `json:"rendueLe"`       // AAAA-MM-JJ
+	EnregistreePar string              `json:"enregistreePar"` // Identité du greffier
+	EnregistreeLe  string              `json:"enregistreeLe"`  // RFC 3339
+	Executions     []ExecutionDecision `json:"executions,omitempty" metadata:",optional"`
+}
+
+// Opération du registre fondée sur une décision de justice
+type ExecutionDecision struct {
+	Usage   string `json:"usage"` // GEL, MAINLEVEE, TRANSFERT_FORCE, CLOTURE_LITIGE ou REALISATION_HYPOTHEQUE
+	IdTitre string `json:"idTitre"`
+	Objet   string `json:"objet,omitempty" metadata:",optional"` // Litige clos, bénéficiaire du transfert, ...
+	TxId    string `json:"txId"`
+	Date    string `json:"date"` // RFC 3339
+}
+
+// Référence d'une décision citée par une autre opération ("juridiction:numero")
+func (d *DecisionJudiciaire) Reference() string {
+	return d.Juridiction + ":" + d.Numero
+}
+
+// Valider la portée d'une décision : bénéficiaire d'un transfert forcé, décision levée par une mainlevée
+func (d *DecisionJudiciaire) Valider() error {
+	switch d.Portee {
+	case ExecutionGel, ExecutionMainlevee, ExecutionTransfert, ExecutionCloture, ExecutionRealisation:
+	default:
+		return fmt.Errorf("portée de décision inconnue: %q (attendu: %s, %s, %s, %s ou %s)", d.Portee, ExecutionGel, ExecutionMainlevee, ExecutionTransfert, ExecutionCloture, ExecutionRealisation)
+	}
+	if len(d.Titres) == 0 {
+		return fmt.Errorf("la décision %s doit désigner au moins un titre foncier", d.Reference())
+	}
+	vus := make(map[string]bool)
+	for _, id := range d.Titres {
+		if id == "" || vus[id] {
+			return fmt.Errorf("titre foncier vide ou en double dans la décision %s: %q", d.Reference(), id)
+		}
+		vus[id] = true
+	}
+	if (d.Portee == ExecutionTransfert) != (d.Beneficiaire != "") {
+		return fmt.Errorf("seule une décision de transfert forcé désigne un bénéficiaire, et elle le doit")
+	}
+	if (d.Portee == ExecutionMainlevee) != (d.DecisionLevee != "") {
+		return fmt.Errorf("seule une décision de mainlevée désigne la décision qu'elle lève, et elle le doit")
+	}
+	if d.DecisionLevee == d.Reference() {
+		return fmt.Errorf("la décision %s ne peut se lever elle-même", d.Reference())
+	}
+	return nil
+}
+
+// Indiquer si la décision ordonne l'usage sur un titre
+func (d *DecisionJudiciaire) Vise(usage string, idTitre string) bool {
+	if d.Portee != usage {
+		return false
+	}
+	for _, id := range d.Titres {
+		if id == idTitre {
+			return true
+		}
+	}
+	return false
+}
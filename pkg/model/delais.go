@@ -0,0 +1,76 @@
+package model
+
+import "fmt"
+
+// Procédures dont le séjour dans chaque statut est suivi
+const (
+	DossierTransfert   = "TRANSFERT"   // Objet : identifiant du transfert
+	DossierLitige      = "LITIGE"      // Objet : identifiant du litige
+	DossierSignalement = "SIGNALEMENT" // Objet : identifiant du signalement d'occupation
+	DossierApprobation = "APPROBATION" // Objet : procédure/objet de la demande d'approbation
+	DossierAttestation = "ATTESTATION" // Objet : identifiant de l'attestation de propriété
+)
+
+// Événement émis à l'escalade des dossiers restés au-delà du délai de leur statut
+const EvenementDossiersEnRetard = "DossiersEnRetard"
+
+// Contenu de l'événement d'escalade d'une page de dossiers en retard
+type EscaladeDossiers struct {
+	TxId     string            `json:"txId"`
+	Dossiers []DossierEnRetard `json:"dossiers"`
+}
+
+// Délai de traitement d'un statut d'une procédure, configuré par canal : un dossier resté plus
+// longtemps dans ce statut est en retard et escaladé
+type DelaiTraitement struct {
+	Nature string `json:"nature"` // TRANSFERT, LITIGE, SIGNALEMENT, APPROBATION ou ATTESTATION
+	Statut string `json:"statut"`
+	Heures int    `json:"heures"`
+}
+
+// Séjour achevé d'un dossier dans un statut
+type PassageStatut struct {
+	Statut string `json:"statut"`
+	Debut  string `json:"debut"` // RFC 3339
+	Fin    string `json:"fin"`   // RFC 3339
+	Duree  int64  `json:"duree"` // Secondes
+}
+
+// Suivi des statuts successifs d'un dossier
+type SuiviStatut struct {
+	Nature   string          `json:"nature"`
+	Objet    string          `json:"objet"`
+	IdTitre  string          `json:"idTitre,omitempty" metadata:",optional"`
+	Statut   string          `json:"statut"`   // Statut courant
+	Depuis   string          `json:"depuis"`   // Entrée dans le statut courant (RFC 3339)
+	Escalade bool            `json:"escalade"` // Retard du statut courant déjà escaladé
+	Passages []PassageStatut `json:"passages"` // Statuts antérieurs, dans l'ordre
+}
+
+// Dossier resté au-delà du délai de son statut
+type DossierEnRetard struct {
+	Nature   string `json:"nature"`
+	Objet    string `json:"objet"`
+	IdTitre  string `json:"idTitre,omitempty" metadata:",optional"`
+	Statut   string `json:"statut"`
+	Depuis   string `json:"depuis"`
+	Delai    int    `json:"delai"`  // Délai configuré (heures)
+	Retard   int    `json:"retard"` // Heures écoulées au-delà du délai
+	Escalade bool   `json:"escalade"`
+}
+
+// Vérifier la cohérence d'un délai de traitement
+func (d *DelaiTraitement) Valider() error {
+	switch d.Nature {
+	case DossierTransfert, DossierLitige, DossierSignalement, DossierApprobation, DossierAttestation:
+	default:
+		return fmt.Errorf("procédure de délai inconnue: %s", d.Nature)
+	}
+	if d.Statut == "" {
+		return fmt.Errorf("le délai de la procédure %s doit désigner un statut", d.Nature)
+	}
+	if d.Heures <= 0 {
+		return fmt.Errorf("le délai du statut %s de la procédure %s doit être positif", d.Statut, d.Nature)
+	}
+	return nil
+}
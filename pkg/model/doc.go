@@ -0,0 +1,5 @@
+// Package model définit le format d'échange des enregistrements du registre
+// foncier (titres, transferts, assurances, ...). Il est partagé par le
+// chaincode et par les applications clientes afin que le format JSON ne
+// puisse pas diverger entre les composants.
+package model
@@ -0,0 +1,26 @@
+package model
+
+// Titres partageant un même numéro officiel, hérités des registres antérieurs
+type DoublonNumTF struct {
+	NumTF      string             `json:"numTF"`
+	Titres     []string           `json:"titres"`                                    // Identifiants des titres portant ce numéro
+	Resolu     bool               `json:"resolu"`                                    // La résolution enregistrée couvre tous ces titres
+	Resolution *ResolutionDoublon `json:"resolution,omitempty" metadata:",optional"` // Dernière résolution enregistrée
+}
+
+// Page du rapport des doublons de numéro officiel
+type RapportDoublons struct {
+	Doublons []DoublonNumTF `json:"doublons"`
+	Nombre   int            `json:"nombre"` // Nombre de doublons de la page
+	Signet   string         `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin de rapport)
+}
+
+// Décision du conservateur sur le titre qui prévaut parmi des doublons
+type ResolutionDoublon struct {
+	NumTF     string   `json:"numTF"`
+	IdRetenu  string   `json:"idRetenu"` // Titre qui prévaut
+	Ecartes   []string `json:"ecartes"`  // Autres titres portant le même numéro
+	Motif     string   `json:"motif"`
+	ResoluPar string   `json:"resoluPar"` // Identité du conservateur
+	ResoluLe  string   `json:"resoluLe"`  // Horodatage de la décision (RFC 3339)
+}
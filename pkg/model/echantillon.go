@@ -0,0 +1,44 @@
+package model
+
+// Statuts d'un échantillon d'audit
+const (
+	EchantillonEngagement = "ENGAGEMENT" // Les auditeurs engagent l'empreinte de leur graine
+	EchantillonRevelation = "REVELATION" // Engagements clos, les auditeurs révèlent leur graine
+	EchantillonTirage     = "TIRAGE"     // Graine établie, le job de tirage parcourt le registre
+	EchantillonTire       = "TIRE"
+)
+
+// Échantillon des titres retenus pour l'audit annuel. La graine combine celles que plusieurs auditeurs
+// ont engagées avant d'en connaître aucune, et le tirage d'un titre ne dépend que d'elle et de son
+// identifiant : chacun peut refaire le tirage à partir de la population enregistrée.
+type EchantillonAudit struct {
+	Annee       string             `json:"annee"`       // Une seule sélection par année
+	Pourcentage int                `json:"pourcentage"` // Part de la population retenue (1 à 100)
+	Statut      string             `json:"statut"`
+	Engagements []EngagementGraine `json:"engagements"`
+	Graine      string             `json:"graine,omitempty" metadata:",optional"`  // Empreinte des graines révélées, dans l'ordre des engagements
+	Job         string             `json:"job,omitempty" metadata:",optional"`     // Job de tirage
+	Population  int                `json:"population"`                             // Titres du registre parcourus par le tirage
+	Titres      []string           `json:"titres"`                                 // Identifiants retenus, dans l'ordre du registre
+	TxId        string             `json:"txId"`                                   // Transaction du premier engagement
+	TirePar     string             `json:"tirePar,omitempty" metadata:",optional"` // Identité de l'auditeur ayant mené le tirage
+	TireLe      string             `json:"tireLe,omitempty" metadata:",optional"`  // Fin du tirage (RFC 3339)
+}
+
+// Engagement d'un auditeur sur la graine qu'il révèlera une fois les engagements clos
+type EngagementGraine struct {
+	Auditeur  string `json:"auditeur"`                              // Identité de l'auditeur
+	Empreinte string `json:"empreinte"`                             // SHA-256 de la graine (hexadécimal)
+	Graine    string `json:"graine,omitempty" metadata:",optional"` // Graine révélée
+	EngageLe  string `json:"engageLe"`                              // Horodatage (RFC 3339)
+}
+
+// Indiquer si toutes les graines engagées ont été révélées
+func (e *EchantillonAudit) Revele() bool {
+	for _, engagement := range e.Engagements {
+		if engagement.Graine == "" {
+			return false
+		}
+	}
+	return len(e.Engagements) > 0
+}
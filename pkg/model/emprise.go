@@ -0,0 +1,29 @@
+package model
+
+// Statuts d'une réserve d'emprise
+const (
+	EmpriseActive = "ACTIVE"
+	EmpriseLevee  = "LEVEE"
+)
+
+// Événement émis à la réservation d'une emprise, listant les parcelles concernées
+const EvenementEmpriseReservee = "EmpriseReservee"
+
+// Emprise réservée par l'État pour un projet public (route, voie ferrée, ...) : les parcelles qu'elle
+// chevauche sont promises à l'expropriation, et leurs transferts signalés ou bloqués jusqu'à sa levée
+type ReserveEmprise struct {
+	Id          string   `json:"id"`                                     // Transaction de réservation
+	Projet      string   `json:"projet"`                                 // Projet d'utilité publique
+	Geometrie   string   `json:"geometrie"`                              // Contour du corridor (GeoJSON, WGS84)
+	Titres      []string `json:"titres"`                                 // Parcelles chevauchées lors de la réservation
+	Statut      string   `json:"statut"`                                 // ACTIVE ou LEVEE
+	ReserveePar string   `json:"reserveePar"`                            // Identité de l'agent de l'État
+	ReserveeLe  string   `json:"reserveeLe"`                             // Date de réservation (AAAA-MM-JJ)
+	ExpireLe    string   `json:"expireLe"`                               // Dernier jour de la réserve (AAAA-MM-JJ)
+	LeveeLe     string   `json:"leveeLe,omitempty" metadata:",optional"` // Date de la levée anticipée (AAAA-MM-JJ)
+}
+
+// Indiquer si la réserve produit encore ses effets à une date (AAAA-MM-JJ)
+func (r *ReserveEmprise) EnVigueur(date string) bool {
+	return r.Statut == EmpriseActive && date <= r.ExpireLe
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Code des réponses réussies
+const CodeSucces = "OK"
+
+// Code des échecs sans code d'erreur métier
+const CodeErreurInterne = "ERREUR_INTERNE"
+
+// Classes d'échec : seules les transactions invalidées par un conflit de lecture peuvent être
+// soumises de nouveau telles quelles
+const (
+	ClasseValidation = "VALIDATION"   // Requête rejetée à l'endossement (fonction, arguments, schéma, rejeu)
+	ClasseConflit    = "CONFLIT_MVCC" // Lecture périmée constatée à la validation du bloc : à soumettre de nouveau
+	ClasseMetier     = "METIER"       // Refus opposé par les règles du registre
+)
+
+// Enveloppe uniforme des réponses du contrat, pour les passerelles REST et les outils en ligne de commande
+type Enveloppe struct {
+	Code       string          `json:"code"`                                  // OK, ou code d'erreur métier
+	Message    string          `json:"message"`                               // Message d'erreur (vide en cas de succès)
+	Classe     string          `json:"classe,omitempty" metadata:",optional"` // Classe de l'échec (vide en cas de succès)
+	Data       json.RawMessage `json:"data"`                                  // Résultat de la transaction (null si aucun)
+	TxId       string          `json:"txId"`                                  // Transaction
+	Horodatage string          `json:"timestamp"`                             // Horodatage de la transaction (RFC 3339)
+}
+
+// Indiquer si la transaction peut être soumise de nouveau sans modification
+func (e *Enveloppe) Reessayable() bool {
+	return e.Classe == ClasseConflit
+}
+
+// Début des messages de rejet propres au contrat (fonction inconnue, arguments non conformes au schéma)
+var rejetsContrat = []string{
+	"Contract not found with name",
+	"Blank function name passed",
+	"Incorrect number of params",
+	"Error managing parameter",
+}
+
+// Classe d'un échec d'après son code et son message. Les échecs sans code d'erreur métier sont des
+// refus du registre (titre inconnu, statut incompatible...), hormis les rejets propres au contrat.
+func ClasseErreur(code string, message string) string {
+	switch code {
+	case CodeRequeteInvalide, CodeNonceInvalide:
+		return ClasseValidation
+	case CodeErreurInterne, "":
+		if strings.HasPrefix(message, "Function ") && strings.Contains(message, " not found in contract ") {
+			return ClasseValidation
+		}
+		for _, rejet := range rejetsContrat {
+			if strings.HasPrefix(message, rejet) {
+				return ClasseValidation
+			}
+		}
+	}
+	return ClasseMetier
+}
@@ -0,0 +1,45 @@
+package model
+
+import "fmt"
+
+// Codes d'erreur métier retournés par le Smart Contract
+const (
+	CodeAccesRefuse        = "ACCES_REFUSE"
+	CodeApprobationRequise = "APPROBATION_REQUISE"
+	CodeCertificatInvalide = "CERTIFICAT_INVALIDE"
+	CodeCommuneHorsCanal   = "COMMUNE_HORS_CANAL"
+	CodeConformiteRequise  = "CONFORMITE_REQUISE"
+	CodeConsentementRequis = "CONSENTEMENT_REQUIS"
+	CodeDecisionInconnue   = "DECISION_INCONNUE"
+	CodeDocumentRefuse     = "DOCUMENT_REFUSE"
+	CodeEmetteurNonReconnu = "EMETTEUR_NON_RECONNU"
+	CodeFactureImpayee     = "FACTURE_IMPAYEE"
+	CodeLicenceInvalide    = "LICENCE_INVALIDE"
+	CodeMorcellementRefuse = "MORCELLEMENT_REFUSE"
+	CodeNonceInvalide      = "NONCE_INVALIDE"
+	CodeParcelleChevauchee = "PARCELLE_CHEVAUCHEE"
+	CodePlafondZoneAtteint = "PLAFOND_ZONE_ATTEINT"
+	CodeQuotaDepasse       = "QUOTA_DEPASSE"
+	CodeRequeteInvalide    = "REQUETE_INVALIDE"
+	CodeTitreDemembre      = "TITRE_DEMEMBRE"
+	CodeTitreEnCopropriete = "TITRE_EN_COPROPRIETE"
+	CodeTitreEnRealisation = "TITRE_EN_REALISATION"
+	CodeTitreInalienable   = "TITRE_INALIENABLE"
+	CodeTitreMorcele       = "TITRE_MORCELE"
+	CodeTitreMute          = "TITRE_MUTE"
+	CodeTitreSaisi         = "TITRE_SAISI"
+	CodeTitreSousEmprise   = "TITRE_SOUS_EMPRISE"
+	CodeTitreSousPromesse  = "TITRE_SOUS_PROMESSE"
+	CodeTransfertRestreint = "TRANSFERT_RESTREINT"
+	CodeVersionPerimee     = "VERSION_PERIMEE"
+)
+
+// Erreur métier portant un code exploitable par les applications clientes
+type ErreurMetier struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ErreurMetier) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
@@ -0,0 +1,33 @@
+package model
+
+import "encoding/json"
+
+// Champ du contenu de l'événement émis portant les événements supplantés de sa transaction : Fabric ne
+// conserve qu'un événement par transaction, les alertes de surveillance et d'abonnement qu'il supplante
+// y sont jointes
+const ChampEvenementsJoints = "evenementsJoints"
+
+// Événement joint au contenu de l'événement émis par sa transaction
+type EvenementJoint struct {
+	Nom     string          `json:"nom"`
+	Contenu json.RawMessage `json:"contenu"`
+}
+
+// Séparer l'événement émis par une transaction de ses événements joints : le premier de la liste est
+// l'événement émis, sans le champ des joints
+func SeparerEvenements(nom string, contenu []byte) []EvenementJoint {
+	var champs map[string]json.RawMessage
+	if err := json.Unmarshal(contenu, &champs); err != nil || champs[ChampEvenementsJoints] == nil {
+		return []EvenementJoint{{Nom: nom, Contenu: contenu}}
+	}
+	var joints []EvenementJoint
+	if err := json.Unmarshal(champs[ChampEvenementsJoints], &joints); err != nil {
+		return []EvenementJoint{{Nom: nom, Contenu: contenu}}
+	}
+	delete(champs, ChampEvenementsJoints)
+	emis, err := json.Marshal(champs)
+	if err != nil {
+		return []EvenementJoint{{Nom: nom, Contenu: contenu}}
+	}
+	return append([]EvenementJoint{{Nom: nom, Contenu: emis}}, joints...)
+}
@@ -0,0 +1,157 @@
+package model
+
+import "fmt"
+
+// Procédures donnant lieu à facturation des droits et frais
+const (
+	FacturationImmatriculation = "IMMATRICULATION" // Objet : titre immatriculé ; assiette nulle
+	FacturationTransfert       = "TRANSFERT"       // Objet : transfert ; assiette : prix déclaré
+)
+
+// Statuts d'une facture
+const (
+	FactureEmise = "EMISE"
+	FacturePayee = "PAYEE"
+)
+
+// Statuts d'une proposition de remboursement
+const (
+	RemboursementPropose  = "PROPOSE"
+	RemboursementApprouve = "APPROUVE"
+	RemboursementRejete   = "REJETE"
+)
+
+// Journaux des écritures comptables exportées au Trésor
+const (
+	JournalFacturation   = "FACTURATION"   // Émission : débit des redevables, crédit des produits, par ligne
+	JournalEncaissement  = "ENCAISSEMENT"  // Paiement : débit de la trésorerie, crédit des redevables
+	JournalRemboursement = "REMBOURSEMENT" // Remboursement approuvé : débit des remboursements, crédit de la trésorerie
+)
+
+// Droit ou frais perçu sur une procédure, configuré par canal
+type TarifFrais struct {
+	Procedure string `json:"procedure"` // IMMATRICULATION ou TRANSFERT
+	Motif     string `json:"motif"`     // Libellé de la ligne de facture (ex: "Droit d'enregistrement")
+	Montant   int    `json:"montant"`   // Part fixe (FCFA)
+	Taux      int    `json:"taux"`      // Part proportionnelle, pour mille de l'assiette
+}
+
+// Ligne d'une facture
+type LigneFacture struct {
+	Motif   string `json:"motif"`
+	Montant int    `json:"montant"` // FCFA
+}
+
+// Facture des droits et frais d'une procédure ; la procédure ne progresse qu'une fois la facture
+// réglée auprès du Trésor
+type Facture struct {
+	Id        string         `json:"id"` // Procédure et objet (ex: "TRANSFERT-TR-0001")
+	Procedure string         `json:"procedure"`
+	Objet     string         `json:"objet"`
+	IdTitre   string         `json:"idTitre"`
+	Assiette  int            `json:"assiette"` // Base des parts proportionnelles (FCFA)
+	Lignes    []LigneFacture `json:"lignes"`
+	Montant   int            `json:"montant"` // Total dû (FCFA)
+	Statut    string         `json:"statut"`  // EMISE ou PAYEE
+	EmiseLe   string         `json:"emiseLe"` // Horodatage de la transaction (RFC 3339)
+
+	RefTresor    string `json:"refTresor,omitempty" metadata:",optional"`    // Quittance du Trésor
+	PayeeLe      string `json:"payeeLe,omitempty" metadata:",optional"`      // RFC 3339
+	EncaisseePar string `json:"encaisseePar,omitempty" metadata:",optional"` // Identité de l'agent du Trésor
+
+	Rembourse      int      `json:"rembourse,omitempty" metadata:",optional"`      // Cumul des remboursements approuvés (FCFA)
+	Remboursements []string `json:"remboursements,omitempty" metadata:",optional"` // Propositions de remboursement, dans l'ordre
+}
+
+// Remboursement d'un trop-perçu sur une facture réglée, proposé par un agent du registre et
+// approuvé ou rejeté par le Trésor
+type Remboursement struct {
+	Id         string `json:"id"` // Facture et rang de la proposition (ex: "TRANSFERT-TR-0001-R1")
+	IdFacture  string `json:"idFacture"`
+	Montant    int    `json:"montant"` // FCFA
+	Motif      string `json:"motif"`
+	Statut     string `json:"statut"`
+	ProposePar string `json:"proposePar"` // Identité de l'agent
+	ProposeLe  string `json:"proposeLe"`  // RFC 3339
+	TxId       string `json:"txId"`       // Transaction de la proposition
+
+	DecidePar     string `json:"decidePar,omitempty" metadata:",optional"` // Identité de l'agent du Trésor
+	DecideLe      string `json:"decideLe,omitempty" metadata:",optional"`  // RFC 3339
+	TxDecision    string `json:"txDecision,omitempty" metadata:",optional"`
+	RefTresor     string `json:"refTresor,omitempty" metadata:",optional"`     // Ordre de remboursement du Trésor
+	MotifDecision string `json:"motifDecision,omitempty" metadata:",optional"` // Motif du rejet
+}
+
+// Vérifier la cohérence d'un tarif
+func (t *TarifFrais) Valider() error {
+	switch t.Procedure {
+	case FacturationImmatriculation, FacturationTransfert:
+	default:
+		return fmt.Errorf("procédure de facturation inconnue: %s", t.Procedure)
+	}
+	if t.Motif == "" {
+		return fmt.Errorf("le motif des frais de la procédure %s est obligatoire", t.Procedure)
+	}
+	if t.Montant < 0 || t.Taux < 0 || t.Montant+t.Taux == 0 {
+		return fmt.Errorf("les frais %q de la procédure %s doivent avoir un montant ou un taux positif", t.Motif, t.Procedure)
+	}
+	return nil
+}
+
+// Comptes du plan comptable du Trésor imputés par les écritures du registre, configurés par canal
+type ComptesComptables struct {
+	Tresorerie     string            `json:"tresorerie"`     // Compte du Trésor encaissant les droits (ex: "571")
+	Redevables     string            `json:"redevables"`     // Créances sur les redevables (ex: "411")
+	Produits       string            `json:"produits"`       // Produits des droits et frais (ex: "706")
+	Procedures     map[string]string `json:"procedures"`     // Compte de produits propre à une procédure (ex: {"TRANSFERT": "7061"})
+	Remboursements string            `json:"remboursements"` // Compte débité des remboursements (vide : compte de produits)
+}
+
+// Écriture comptable équilibrée, débitant et créditant un même montant
+type EcritureComptable struct {
+	Journal      string `json:"journal"`
+	Horodatage   string `json:"horodatage"` // Transaction d'origine (RFC 3339)
+	Piece        string `json:"piece"`      // Facture, ou quittance et ordre de remboursement du Trésor
+	IdFacture    string `json:"idFacture"`
+	Procedure    string `json:"procedure"`
+	Libelle      string `json:"libelle"`
+	CompteDebit  string `json:"compteDebit"`
+	CompteCredit string `json:"compteCredit"`
+	Montant      int    `json:"montant"` // FCFA
+}
+
+// Page d'écritures comptables d'une période (AAAA, AAAA-MM ou AAAA-MM-JJ)
+type PageEcrituresComptables struct {
+	Periode   string              `json:"periode"`
+	Ecritures []EcritureComptable `json:"ecritures"`
+	Signet    string              `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin de parcours)
+}
+
+// Vérifier la cohérence des comptes
+func (c *ComptesComptables) Valider() error {
+	if c.Tresorerie == "" || c.Redevables == "" || c.Produits == "" {
+		return fmt.Errorf("les comptes de trésorerie, de redevables et de produits sont obligatoires")
+	}
+	for procedure, compte := range c.Procedures {
+		if compte == "" {
+			return fmt.Errorf("le compte de produits de la procédure %s est vide", procedure)
+		}
+	}
+	return nil
+}
+
+// Compte de produits d'une procédure
+func (c *ComptesComptables) Produit(procedure string) string {
+	if compte := c.Procedures[procedure]; compte != "" {
+		return compte
+	}
+	return c.Produits
+}
+
+// Compte débité des remboursements d'une procédure
+func (c *ComptesComptables) Remboursement(procedure string) string {
+	if c.Remboursements != "" {
+		return c.Remboursements
+	}
+	return c.Produit(procedure)
+}
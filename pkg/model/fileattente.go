@@ -0,0 +1,31 @@
+package model
+
+// Nature des éléments d'une file d'attente d'agents
+const (
+	AttenteTransfert   = "TRANSFERT"   // Transfert en attente de finalisation
+	AttenteApprobation = "APPROBATION" // Demande d'approbation en cours
+)
+
+// Dossiers en attente d'un conservateur : transferts de son bureau à finaliser et demandes
+// d'approbation sur lesquelles il peut encore se prononcer
+type DossiersEnAttente struct {
+	Bureau       string         `json:"bureau"`
+	Transferts   []*Transfert   `json:"transferts"`
+	Approbations []*Approbation `json:"approbations"`
+}
+
+// Affectation d'un dossier (transfert, signalement) à un agent du bureau foncier, par un superviseur
+type AffectationDossier struct {
+	Nature     string             `json:"nature"` // Procédure du dossier (TRANSFERT, SIGNALEMENT)
+	IdDossier  string             `json:"idDossier"`
+	Agent      string             `json:"agent"`      // Identité de l'agent en charge
+	Historique []EtapeAffectation `json:"historique"` // Affectations successives, la dernière en vigueur
+}
+
+// Affectation ou réaffectation d'un dossier
+type EtapeAffectation struct {
+	Agent       string `json:"agent"`
+	AffecteePar string `json:"affecteePar"` // Identité du superviseur
+	AffecteeLe  string `json:"affecteeLe"`  // Horodatage de la transaction (RFC 3339)
+	TxId        string `json:"txId"`
+}
@@ -0,0 +1,347 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Point (longitude, latitude) d'un contour GeoJSON
+type Point [2]float64
+
+// Contours extérieurs d'une géométrie GeoJSON de type Polygon ou MultiPolygon, éventuellement portée
+// par une Feature ; les trous des polygones sont ignorés
+func ContoursGeoJSON(geojson string) ([][]Point, error) {
+	var geometrie struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+	}
+	if err := json.Unmarshal([]byte(geojson), &geometrie); err != nil {
+		return nil, fmt.Errorf("géométrie GeoJSON invalide: %v", err)
+	}
+
+	var contours [][]Point
+	switch geometrie.Type {
+	case "Feature":
+		return ContoursGeoJSON(string(geometrie.Geometry))
+	case "Polygon":
+		var anneaux [][]Point
+		if err := json.Unmarshal(geometrie.Coordinates, &anneaux); err != nil || len(anneaux) == 0 {
+			return nil, fmt.Errorf("coordonnées de polygone invalides")
+		}
+		contours = append(contours, anneaux[0])
+	case "MultiPolygon":
+		var polygones [][][]Point
+		if err := json.Unmarshal(geometrie.Coordinates, &polygones); err != nil || len(polygones) == 0 {
+			return nil, fmt.Errorf("coordonnées de multipolygone invalides")
+		}
+		for _, anneaux := range polygones {
+			if len(anneaux) == 0 {
+				return nil, fmt.Errorf("coordonnées de multipolygone invalides")
+			}
+			contours = append(contours, anneaux[0])
+		}
+	default:
+		return nil, fmt.Errorf("type de géométrie non pris en charge: %q (attendu: Polygon ou MultiPolygon)", geometrie.Type)
+	}
+	for _, contour := range contours {
+		if len(contour) < 4 || contour[0] != contour[len(contour)-1] {
+			return nil, fmt.Errorf("un contour doit être fermé et compter au moins trois sommets")
+		}
+	}
+	return contours, nil
+}
+
+// Indiquer si deux géométries ont une surface commune ; des parcelles qui ne font que partager
+// une limite ne se chevauchent pas
+func Chevauchent(a [][]Point, b [][]Point) bool {
+	for _, contourA := range a {
+		for _, contourB := range b {
+			if contoursChevauchent(contourA, contourB) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Indiquer si deux géométries sans surface commune partagent une portion de limite, à toleranceLimite
+// près ; un simple sommet commun ne suffit pas
+func Contigues(a [][]Point, b [][]Point) bool {
+	if Chevauchent(a, b) {
+		return false
+	}
+	for _, contourA := range a {
+		for _, contourB := range b {
+			for i := 0; i+1 < len(contourA); i++ {
+				for j := 0; j+1 < len(contourB); j++ {
+					if limiteCommune(contourA[i], contourA[i+1], contourB[j], contourB[j+1]) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Indiquer si une géométrie est entièrement comprise dans une autre : chacun de ses contours tient
+// dans l'un des contours de l'autre, dont il peut partager des portions de limite sans les franchir
+func Inclus(interieur [][]Point, exterieur [][]Point) bool {
+	for _, contour := range interieur {
+		inclus := false
+		for _, autre := range exterieur {
+			inclus = inclus || contourInclus(contour, autre)
+		}
+		if !inclus {
+			return false
+		}
+	}
+	return true
+}
+
+func contourInclus(contour []Point, autre []Point) bool {
+	for i := 0; i+1 < len(contour); i++ {
+		milieu := Point{(contour[i][0] + contour[i+1][0]) / 2, (contour[i][1] + contour[i+1][1]) / 2}
+		for _, p := range []Point{contour[i], milieu} {
+			if !contient(autre, p) && !surLimite(autre, p) {
+				return false
+			}
+		}
+		for j := 0; j+1 < len(autre); j++ {
+			if segmentsSeCoupent(contour[i], contour[i+1], autre[j], autre[j+1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Écart relatif toléré entre la superficie déclarée d'une parcelle et l'aire de son contour, qui
+// absorbe l'arrondi des sommets et celui de la superficie au mètre carré
+const EcartSuperficieMax = 0.01
+
+// Aire en m² d'une géométrie (contours extérieurs en WGS84), par projection locale de chaque contour
+// sur l'ellipsoïde à sa latitude moyenne : l'approximation est négligeable à l'échelle d'une parcelle
+func Superficie(contours [][]Point) float64 {
+	aire := 0.0
+	for _, contour := range contours {
+		latitude := point(contour)[1] * math.Pi / 180
+		e2 := aplatissement * (2 - aplatissement)
+		w := math.Sqrt(1 - e2*math.Sin(latitude)*math.Sin(latitude))
+		// Rayons de courbure du premier vertical et du méridien, en mètres par radian
+		normale := demiGrandAxe / w
+		meridien := demiGrandAxe * (1 - e2) / (w * w * w)
+		echelle := (math.Pi / 180) * (math.Pi / 180) * normale * math.Cos(latitude) * meridien
+		aire += math.Abs(aireSignee(contour)) / 2 * echelle
+	}
+	return aire
+}
+
+// Indiquer si une superficie déclarée (m²) concorde, à EcartSuperficieMax près, avec une aire calculée
+func SuperficieConcorde(declaree int, aire float64) bool {
+	return math.Abs(float64(declaree)-aire) <= max(EcartSuperficieMax*aire, 1)
+}
+
+// Recouvrement de deux segments alignés sur une longueur non nulle ; un sommet est tenu pour aligné
+// à moins de toleranceLimite de la droite de l'autre segment
+func limiteCommune(p1, p2, q1, q2 Point) bool {
+	dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+	longueur := math.Hypot(dx, dy)
+	if longueur == 0 || math.Abs(orientation(p1, p2, q1)) > toleranceLimite*longueur || math.Abs(orientation(p1, p2, q2)) > toleranceLimite*longueur {
+		return false
+	}
+	// Positions de q1 et q2 le long de [p1, p2], rapportées à sa longueur
+	t1 := ((q1[0]-p1[0])*dx + (q1[1]-p1[1])*dy) / (longueur * longueur)
+	t2 := ((q2[0]-p1[0])*dx + (q2[1]-p1[1])*dy) / (longueur * longueur)
+	return (min(max(t1, t2), 1)-max(min(t1, t2), 0))*longueur > toleranceLimite
+}
+
+func contoursChevauchent(a []Point, b []Point) bool {
+	minA, maxA := englobant(a)
+	minB, maxB := englobant(b)
+	if maxA[0] <= minB[0] || maxB[0] <= minA[0] || maxA[1] <= minB[1] || maxB[1] <= minA[1] {
+		return false
+	}
+	for i := 0; i+1 < len(a); i++ {
+		for j := 0; j+1 < len(b); j++ {
+			if segmentsSeCoupent(a[i], a[i+1], b[j], b[j+1]) {
+				return true
+			}
+		}
+	}
+	// Sans croisement de limites, les contours se recouvrent le long de limites alignées, l'un contient
+	// l'autre ou ils coïncident
+	return sommetInterieur(a, b) || sommetInterieur(b, a) || contient(b, point(a)) || contient(a, point(b))
+}
+
+// Écart, en degrés, sous lequel un point est tenu pour situé sur une limite (environ 1 cm)
+const toleranceLimite = 1e-7
+
+// Indiquer si un sommet ou un milieu d'arête d'un contour est strictement intérieur à un autre
+func sommetInterieur(contour []Point, autre []Point) bool {
+	for i := 0; i+1 < len(contour); i++ {
+		milieu := Point{(contour[i][0] + contour[i+1][0]) / 2, (contour[i][1] + contour[i+1][1]) / 2}
+		for _, p := range []Point{contour[i], milieu} {
+			if contient(autre, p) && !surLimite(autre, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Indiquer si un point est situé sur la limite d'un contour, à toleranceLimite près
+func surLimite(contour []Point, p Point) bool {
+	for i := 0; i+1 < len(contour); i++ {
+		a, b := contour[i], contour[i+1]
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		t := 0.0
+		if longueur := dx*dx + dy*dy; longueur > 0 {
+			t = max(0, min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/longueur))
+		}
+		if math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy) <= toleranceLimite {
+			return true
+		}
+	}
+	return false
+}
+
+// Précision maximale des tuiles geohash de l'index spatial (7 caractères : environ 150 m de côté), et
+// nombre de tuiles au-delà duquel une géométrie est couverte par des tuiles plus grossières
+const (
+	PrecisionGeohash = 7
+	TuilesGeohashMax = 16
+)
+
+// Alphabet des geohash
+const base32Geohash = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Tuiles geohash couvrant les rectangles englobants d'une géométrie, à la plus fine précision qui en
+// compte au plus TuilesGeohashMax. Deux géométries ne peuvent se chevaucher que si l'une de leurs
+// tuiles est préfixe d'une tuile de l'autre.
+func TuilesGeohash(contours [][]Point) []string {
+	precision := PrecisionGeohash
+	for ; precision > 1; precision-- {
+		nombre := 0
+		for _, contour := range contours {
+			colonnes, lignes := grilleTuiles(contour, precision)
+			nombre += (colonnes[1] - colonnes[0] + 1) * (lignes[1] - lignes[0] + 1)
+		}
+		if nombre <= TuilesGeohashMax {
+			break
+		}
+	}
+
+	largeur, hauteur := dimensionsTuile(precision)
+	vues := map[string]bool{}
+	for _, contour := range contours {
+		colonnes, lignes := grilleTuiles(contour, precision)
+		for i := colonnes[0]; i <= colonnes[1]; i++ {
+			for j := lignes[0]; j <= lignes[1]; j++ {
+				centre := Point{-180 + (float64(i)+0.5)*largeur, -90 + (float64(j)+0.5)*hauteur}
+				vues[Geohash(centre, precision)] = true
+			}
+		}
+	}
+	tuiles := make([]string, 0, len(vues))
+	for tuile := range vues {
+		tuiles = append(tuiles, tuile)
+	}
+	sort.Strings(tuiles)
+	return tuiles
+}
+
+// Geohash d'un point à une précision donnée (nombre de caractères)
+func Geohash(p Point, precision int) string {
+	longitude, latitude := [2]float64{-180, 180}, [2]float64{-90, 90}
+	hash := make([]byte, 0, precision)
+	bits, valeur, pair := 0, 0, true
+	for len(hash) < precision {
+		intervalle, coordonnee := &latitude, p[1]
+		if pair {
+			intervalle, coordonnee = &longitude, p[0]
+		}
+		milieu := (intervalle[0] + intervalle[1]) / 2
+		valeur <<= 1
+		if coordonnee >= milieu {
+			valeur |= 1
+			intervalle[0] = milieu
+		} else {
+			intervalle[1] = milieu
+		}
+		pair = !pair
+		if bits++; bits == 5 {
+			hash = append(hash, base32Geohash[valeur])
+			bits, valeur = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// Largeur et hauteur, en degrés, d'une tuile geohash : les bits sont répartis en alternance, en
+// commençant par la longitude
+func dimensionsTuile(precision int) (float64, float64) {
+	bits := 5 * precision
+	return 360 / math.Pow(2, float64((bits+1)/2)), 180 / math.Pow(2, float64(bits/2))
+}
+
+// Plages de colonnes et de lignes de la grille des tuiles recouvrant le rectangle englobant d'un contour
+func grilleTuiles(contour []Point, precision int) ([2]int, [2]int) {
+	largeur, hauteur := dimensionsTuile(precision)
+	colonnesMax, lignesMax := int(math.Round(360/largeur))-1, int(math.Round(180/hauteur))-1
+	indice := func(valeur float64, origine float64, pas float64, indiceMax int) int {
+		return max(0, min(indiceMax, int(math.Floor((valeur-origine)/pas))))
+	}
+	bas, haut := englobant(contour)
+	colonnes := [2]int{indice(bas[0], -180, largeur, colonnesMax), indice(haut[0], -180, largeur, colonnesMax)}
+	lignes := [2]int{indice(bas[1], -90, hauteur, lignesMax), indice(haut[1], -90, hauteur, lignesMax)}
+	return colonnes, lignes
+}
+
+func englobant(contour []Point) (Point, Point) {
+	bas, haut := contour[0], contour[0]
+	for _, p := range contour[1:] {
+		bas = Point{min(bas[0], p[0]), min(bas[1], p[1])}
+		haut = Point{max(haut[0], p[0]), max(haut[1], p[1])}
+	}
+	return bas, haut
+}
+
+// Point intérieur représentatif d'un contour : barycentre de ses sommets
+func point(contour []Point) Point {
+	var p Point
+	sommets := contour[:len(contour)-1]
+	for _, sommet := range sommets {
+		p[0] += sommet[0]
+		p[1] += sommet[1]
+	}
+	return Point{p[0] / float64(len(sommets)), p[1] / float64(len(sommets))}
+}
+
+// Croisement strict de deux segments, hors extrémités et segments colinéaires
+func segmentsSeCoupent(p1, p2, q1, q2 Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	return d1*d2 < 0 && d3*d4 < 0
+}
+
+func orientation(a, b, c Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// Appartenance d'un point à l'intérieur d'un contour (lancer de rayon)
+func contient(contour []Point, p Point) bool {
+	dedans := false
+	for i, j := 0, len(contour)-1; i < len(contour); j, i = i, i+1 {
+		a, b := contour[i], contour[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			dedans = !dedans
+		}
+	}
+	return dedans
+}
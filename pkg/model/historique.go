@@ -0,0 +1,41 @@
+package model
+
+// Version d'un titre foncier reconstituée depuis l'historique du registre
+type VersionTitre struct {
+	TxId       string        `json:"txId"`                                 // Transaction ayant produit la version
+	Horodatage string        `json:"horodatage"`                           // Horodatage de la transaction (RFC 3339)
+	Supprime   bool          `json:"supprime"`                             // Le titre a quitté le registre courant (archivage)
+	Titre      *TitreFoncier `json:"titre,omitempty" metadata:",optional"` // État du titre après la transaction
+}
+
+// Contour d'une parcelle et sa période de vigueur, reconstitués depuis l'historique du titre : les
+// contours remplacés restent consultables, fût-ce après l'archivage du titre, pour les besoins des
+// litiges de limites
+type VersionGeometrie struct {
+	IdTitre            string `json:"idTitre"`
+	Geometrie          string `json:"geometrie"`                                         // GeoJSON, WGS84
+	SystemeCoordonnees string `json:"systemeCoordonnees,omitempty" metadata:",optional"` // Système de référence du contour déposé
+	EnVigueurDepuis    string `json:"enVigueurDepuis"`                                   // Date d'effet légale ou, à défaut, de la transaction (RFC 3339)
+	EnVigueurJusquau   string `json:"enVigueurJusquau,omitempty" metadata:",optional"`   // RFC 3339, exclu ; vide : contour en vigueur
+	TxId               string `json:"txId"`                                              // Transaction ayant enregistré le contour
+	TxRemplacement     string `json:"txRemplacement,omitempty" metadata:",optional"`     // Transaction ayant remplacé le contour
+}
+
+// Modification d'un champ entre deux versions d'un titre
+type ChangementChamp struct {
+	Champ string `json:"champ"` // Nom du champ dans le format d'échange (proprio, superficie, ...)
+	Avant string `json:"avant"` // Valeur dans la première version (vide si absent)
+	Apres string `json:"apres"` // Valeur dans la seconde version (vide si absent)
+}
+
+// Différences entre deux versions d'un titre, champ par champ
+type ComparaisonVersions struct {
+	IdTitre          string            `json:"idTitre"`
+	TxId1            string            `json:"txId1"`
+	TxId2            string            `json:"txId2"`
+	Changements      []ChangementChamp `json:"changements"`      // Champs simples modifiés
+	DocumentsAjoutes []DocumentTitre   `json:"documentsAjoutes"` // Documents présents dans la seule seconde version
+	DocumentsRetires []DocumentTitre   `json:"documentsRetires"` // Documents présents dans la seule première version
+	ChargesInscrites []Charge          `json:"chargesInscrites"`
+	ChargesRadiees   []Charge          `json:"chargesRadiees"`
+}
@@ -0,0 +1,43 @@
+package model
+
+// Trace d'une convention de subordination entre deux hypothèques d'un même titre
+type SubordinationHypotheque struct {
+	Id           string `json:"id"` // Transaction d'enregistrement
+	IdTitre      string `json:"idTitre"`
+	Cedante      string `json:"cedante"`      // Hypothèque qui cède son rang
+	Beneficiaire string `json:"beneficiaire"` // Hypothèque placée immédiatement avant la cédante
+	Acte         string `json:"acte"`         // Référence de la convention de subordination
+	Notaire      string `json:"notaire"`      // Identité du notaire ayant enregistré la convention
+	Date         string `json:"date"`         // Date d'enregistrement (AAAA-MM-JJ)
+}
+
+// Opérations sur une hypothèque subordonnées au consentement de sa banque créancière
+const (
+	ConsentementRadiation     = "RADIATION"
+	ConsentementSubordination = "SUBORDINATION"
+)
+
+// Consentement de la banque créancière à la radiation de son hypothèque ou à la cession de son rang,
+// que l'opération du notaire consomme
+type ConsentementCreancier struct {
+	IdTitre      string `json:"idTitre"`
+	Hypotheque   string `json:"hypotheque"`                                  // Hypothèque de la banque
+	Operation    string `json:"operation"`                                   // RADIATION ou SUBORDINATION
+	Beneficiaire string `json:"beneficiaire,omitempty" metadata:",optional"` // Hypothèque à qui le rang est cédé (SUBORDINATION)
+	Creancier    string `json:"creancier"`                                   // MSP de la banque
+	DonnePar     string `json:"donnePar"`                                    // Identité de l'appelant
+	DonneLe      string `json:"donneLe"`                                     // Horodatage (RFC 3339)
+}
+
+// Événement émis à la proposition du transfert d'un titre hypothéqué, à l'attention des banques créancières
+const EvenementConsentementRequis = "ConsentementTransfertRequis"
+
+// Consentements attendus des créanciers hypothécaires avant la finalisation d'un transfert
+type DemandeConsentement struct {
+	IdTransfert string   `json:"idTransfert"`
+	IdTitre     string   `json:"idTitre"`
+	Acheteur    string   `json:"acheteur"`
+	Prix        int      `json:"prix"`
+	Creanciers  []string `json:"creanciers"`  // MSP des banques dont le consentement est requis
+	Hypotheques []string `json:"hypotheques"` // Hypothèques concernées, dans l'ordre de leur rang
+}
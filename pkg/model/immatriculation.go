@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Dossier d'immatriculation soumis d'un seul tenant : propriétaire, titre, pièces et géométrie
+type DossierImmatriculation struct {
+	Id                  string              `json:"id"`
+	NumTF               string              `json:"numTF,omitempty" metadata:",optional"` // Attribué par la séquence du canal si vide
+	Superficie          int                 `json:"superficie"`
+	Commune             string              `json:"commune"`
+	Document            string              `json:"document"`                                 // Chemin NFS du document d'origine
+	Geometrie           json.RawMessage     `json:"geometrie,omitempty" metadata:",optional"` // Contour de la parcelle (GeoJSON)
+	Proprietaire        ProprietaireDossier `json:"proprietaire"`
+	Pieces              []PieceDossier      `json:"pieces,omitempty" metadata:",optional"`              // Documents rattachés dès l'immatriculation
+	AnciennesReferences []AncienneReference `json:"anciennesReferences,omitempty" metadata:",optional"` // Titre migré des anciens livres fonciers
+}
+
+// Propriétaire désigné par un dossier d'immatriculation, enregistré avec ses qualités s'il est nouveau
+type ProprietaireDossier struct {
+	Id       string   `json:"id"`
+	Qualites []string `json:"qualites,omitempty" metadata:",optional"`
+}
+
+// Pièce d'un dossier d'immatriculation, émise par une autorité reconnue
+type PieceDossier struct {
+	Chemin string `json:"chemin"`
+	Issuer string `json:"issuer"`
+}
+
+// Vérifier la cohérence interne d'un dossier d'immatriculation
+func (d *DossierImmatriculation) Valider() error {
+	if d.Id == "" {
+		return fmt.Errorf("l'identifiant du titre foncier est obligatoire")
+	}
+	if d.Proprietaire.Id == "" {
+		return fmt.Errorf("le propriétaire du titre foncier %s est obligatoire", d.Id)
+	}
+	if d.Superficie <= 0 {
+		return fmt.Errorf("la superficie du titre foncier %s doit être positive", d.Id)
+	}
+	if d.Document == "" {
+		return fmt.Errorf("le document d'origine du titre foncier %s est obligatoire", d.Id)
+	}
+	if len(d.Geometrie) > 0 && !json.Valid(d.Geometrie) {
+		return fmt.Errorf("la géométrie du titre foncier %s doit être un document GeoJSON", d.Id)
+	}
+	chemins := map[string]bool{d.Document: true}
+	for _, piece := range d.Pieces {
+		if piece.Chemin == "" || piece.Issuer == "" {
+			return fmt.Errorf("chaque pièce du dossier %s doit indiquer son chemin et son émetteur", d.Id)
+		}
+		if chemins[piece.Chemin] {
+			return fmt.Errorf("le document %s figure plusieurs fois au dossier %s", piece.Chemin, d.Id)
+		}
+		chemins[piece.Chemin] = true
+	}
+	references := map[AncienneReference]bool{}
+	for _, reference := range d.AnciennesReferences {
+		if err := reference.Valider(); err != nil {
+			return err
+		}
+		if references[reference.Normaliser()] {
+			return fmt.Errorf("l'ancienne référence livre %s folio %s figure plusieurs fois au dossier %s", reference.Livre, reference.Folio, d.Id)
+		}
+		references[reference.Normaliser()] = true
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+// Types de jobs d'administration
+const (
+	JobReconstructionIndex = "RECONSTRUCTION_INDEX" // Paramètre "index" : famille d'index à reconstruire
+	JobAuditCoherence      = "AUDIT_COHERENCE"      // Compte les anomalies de l'audit de cohérence par type
+	JobRattachementBureaux = "RATTACHEMENT_BUREAUX" // Paramètres "commune" et "bureau" : rattache au bureau les titres de la commune qui n'en ont pas
+	JobMarquageTitres      = "MARQUAGE_TITRES"      // Pose le type d'enregistrement des titres écrits avant son introduction et réécrit en JSON les titres MessagePack
+	JobEchantillonAudit    = "ECHANTILLON_AUDIT"    // Paramètre "annee" : tire l'échantillon d'audit de l'année, une fois sa graine établie
+	JobAllocationsZones    = "ALLOCATIONS_ZONES"    // Inscrit dans leurs zonages les titres écrits avant le suivi par titre
+	JobIndexActivite       = "INDEX_ACTIVITE"       // Inscrit dans les index par date l'activité et les factures antérieures à leur introduction
+	JobEscaladeRetards     = "ESCALADE_RETARDS"     // Escalade les dossiers en cours passés au-delà du délai de leur statut
+	JobSuiviStatuts        = "SUIVI_STATUTS"        // Ouvre, à partir de leur historique, le suivi des dossiers antérieurs aux délais
+	JobFilesAttente        = "FILES_ATTENTE"        // Inscrit dans les files d'attente les dossiers en attente, selon la disposition par genre d'affectation
+	JobAbonnements         = "ABONNEMENTS"          // Inscrit les abonnements antérieurs dans les index par statut et par abonné
+)
+
+// Statuts d'un job
+const (
+	JobEnCours = "EN_COURS"
+	JobTermine = "TERMINE"
+	JobAnnule  = "ANNULE"
+)
+
+// Opération d'administration menée sur plusieurs transactions, une page à chaque ContinuerJob
+type Job struct {
+	Id         string            `json:"id"` // Transaction de démarrage
+	Type       string            `json:"type"`
+	Statut     string            `json:"statut"`
+	Parametres map[string]string `json:"parametres,omitempty" metadata:",optional"`
+	Signet     string            `json:"signet,omitempty" metadata:",optional"`    // Reprise propre au type de job (vide : au début)
+	Pages      int               `json:"pages"`                                    // Pages traitées
+	Compteurs  map[string]int    `json:"compteurs,omitempty" metadata:",optional"` // Progression, propre au type de job
+	DemarrePar string            `json:"demarrePar"`
+	DemarreLe  string            `json:"demarreLe"`                                // Horodatage RFC 3339
+	MisAJourLe string            `json:"misAJourLe"`                               // Horodatage RFC 3339 de la dernière page
+	TermineLe  string            `json:"termineLe,omitempty" metadata:",optional"` // Horodatage RFC 3339 de fin ou d'annulation
+}
@@ -0,0 +1,41 @@
+package model
+
+import "fmt"
+
+// Statuts d'une licence professionnelle
+const (
+	LicenceActive    = "ACTIVE"
+	LicenceSuspendue = "SUSPENDUE"
+	LicenceRadiee    = "RADIEE" // Définitive
+)
+
+// Licence d'exercice d'un professionnel (notaire, géomètre, évaluateur), tenue par son ordre
+type Licence struct {
+	Numero     string `json:"numero"`     // Porté par l'attribut "licence" du certificat du professionnel
+	Profession string `json:"profession"` // Rôle ouvert par la licence
+	Titulaire  string `json:"titulaire"`
+	// Identité (GetID) du certificat du titulaire, seule admise à se prévaloir de la licence ; vide pour
+	// les licences délivrées avant la liaison
+	Identite    string `json:"identite,omitempty" metadata:",optional"`
+	Expiration  string `json:"expiration"` // Dernier jour de validité (AAAA-MM-JJ)
+	Statut      string `json:"statut"`
+	Motif       string `json:"motif,omitempty" metadata:",optional"` // Motif de la suspension ou de la radiation
+	MisAJourPar string `json:"misAJourPar"`                          // Identité de l'agent de l'ordre
+	MisAJourLe  string `json:"misAJourLe"`                           // Horodatage (RFC 3339)
+}
+
+// Vérifier la cohérence d'une licence
+func (l *Licence) Valider() error {
+	if l.Numero == "" || l.Titulaire == "" {
+		return fmt.Errorf("le numéro et le titulaire de la licence sont obligatoires")
+	}
+	if _, err := AnalyserDate(l.Expiration); err != nil {
+		return err
+	}
+	switch l.Statut {
+	case LicenceActive, LicenceSuspendue, LicenceRadiee:
+		return nil
+	default:
+		return fmt.Errorf("statut de licence inconnu: %s", l.Statut)
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+// Statuts d'un signalement d'occupation illégale
+const (
+	SignalementRecu          = "RECU"
+	SignalementEnInstruction = "EN_INSTRUCTION"
+	SignalementClasse        = "CLASSE"   // Sans suite
+	SignalementEscalade      = "ESCALADE" // Porté en litige
+)
+
+// Signalement, par un citoyen, de l'occupation illégale d'une parcelle
+type SignalementOccupation struct {
+	Id          string `json:"id"` // Transaction de dépôt
+	IdTitre     string `json:"idTitre"`
+	Description string `json:"description"`
+	PreuveHash  string `json:"preuveHash"` // Hash de la pièce jointe (photo, constat d'huissier, ...)
+	Signalant   string `json:"signalant"`  // Identité du citoyen enrôlée par la passerelle
+	Statut      string `json:"statut"`
+	DeposeLe    string `json:"deposeLe"`                                   // Horodatage du dépôt (RFC 3339)
+	Motif       string `json:"motif,omitempty" metadata:",optional"`       // Motif de la dernière décision du conservateur
+	InstruitPar string `json:"instruitPar,omitempty" metadata:",optional"` // Identité du conservateur en charge
+	Litige      string `json:"litige,omitempty" metadata:",optional"`      // Litige ouvert à l'escalade
+}
+
+// Statuts d'un litige foncier
+const (
+	LitigeOuvert = "OUVERT"
+	LitigeClos   = "CLOS" // Tranché par une décision de justice
+)
+
+// Litige foncier ouvert sur un titre, à partir d'un signalement ou d'une autre procédure
+type Litige struct {
+	Id       string `json:"id"` // Transaction d'ouverture
+	IdTitre  string `json:"idTitre"`
+	Origine  string `json:"origine"` // Procédure à l'origine du litige (ex: "SIGNALEMENT:<id>")
+	Objet    string `json:"objet"`
+	Statut   string `json:"statut"`
+	OuvertLe string `json:"ouvertLe"`                                // Horodatage de l'ouverture (RFC 3339)
+	Decision string `json:"decision,omitempty" metadata:",optional"` // Décision de justice ayant tranché le litige
+	ClosLe   string `json:"closLe,omitempty" metadata:",optional"`   // Horodatage de la clôture (RFC 3339)
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Lot à immatriculer lors du morcellement d'un titre foncier
+type LotMorcellement struct {
+	Id         string          `json:"id"`                                       // Identifiant du titre à créer pour le lot
+	Superficie int             `json:"superficie"`                               // Superficie du lot en m²
+	Document   string          `json:"document"`                                 // Chemin NFS du plan du lot
+	Geometrie  json.RawMessage `json:"geometrie,omitempty" metadata:",optional"` // Contour du lot (GeoJSON, WGS84 ou UTM 28N), requis si le titre mère en porte un
+}
+
+// Consentement du propriétaire au détachement d'une partie de son titre au profit d'un acheteur, dont
+// le détachement reprend les conditions et qu'il consomme
+type ConsentementDetachement struct {
+	IdTitre    string `json:"idTitre"`
+	Proprio    string `json:"proprio"`
+	Acheteur   string `json:"acheteur"`
+	Superficie int    `json:"superficie"` // Superficie détachée (m²)
+	Prix       int    `json:"prix"`       // Prix de cession du lot détaché (FCFA)
+	DonnePar   string `json:"donnePar"`   // Identité de l'appelant de la passerelle citoyenne
+	DonneLe    string `json:"donneLe"`    // Horodatage (RFC 3339)
+}
+
+// Contraintes de morcellement des parcelles d'un zonage, configurées par canal
+type RegleMorcellement struct {
+	Zone          string `json:"zone"`                                         // Zonage concerné (vide : toutes les parcelles)
+	SuperficieMin int    `json:"superficieMin,omitempty" metadata:",optional"` // Superficie minimale d'un lot (m²)
+	LotsMax       int    `json:"lotsMax,omitempty" metadata:",optional"`       // Nombre maximal de lots issus d'un morcellement
+	PrixReserveM2 int    `json:"prixReserveM2,omitempty" metadata:",optional"` // Prix minimal au m² de la cession d'un lot détaché (FCFA)
+}
+
+// Vérifier la cohérence d'une règle de morcellement
+func (r *RegleMorcellement) Valider() error {
+	if r.SuperficieMin < 0 || r.LotsMax < 0 || r.PrixReserveM2 < 0 {
+		return fmt.Errorf("les contraintes de morcellement de la zone %q ne peuvent être négatives", r.Zone)
+	}
+	if r.SuperficieMin == 0 && r.LotsMax == 0 && r.PrixReserveM2 == 0 {
+		return fmt.Errorf("la règle de morcellement de la zone %q ne fixe aucune contrainte", r.Zone)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package model
+
+// Dernier nonce accepté d'un titulaire agissant via la passerelle citoyenne
+type NonceTitulaire struct {
+	Titulaire string `json:"titulaire"` // Propriétaire représenté, ou identité du citoyen
+	Dernier   uint64 `json:"dernier"`   // Tout nouvel appel doit porter un nonce strictement supérieur
+	Operation string `json:"operation"` // Transaction l'ayant consommé
+	TxId      string `json:"txId"`
+}
@@ -0,0 +1,243 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Côté du hash frère d'une étape de preuve d'inclusion
+const (
+	FrereGauche = "GAUCHE"
+	FrereDroite = "DROITE"
+)
+
+// Statuts de la double saisie d'un titre d'archive
+const (
+	DoubleSaisieAttente    = "EN_ATTENTE" // Saisie du second opérateur attendue
+	DoubleSaisieDivergente = "DIVERGENTE" // Saisies discordantes, soumises à la décision d'un superviseur
+	DoubleSaisieActivee    = "ACTIVEE"    // Titre immatriculé
+)
+
+// Événement annonçant deux saisies discordantes d'un même titre d'archive
+const EvenementSaisieDivergente = "SaisieDivergente"
+
+// Lot d'archives papier numérisées, ancré sur le registre avant la saisie des titres qu'il contient :
+// la racine de l'arbre de Merkle des fichiers numérisés permet de vérifier, fichier par fichier, qu'une
+// saisie repose sur un scan d'origine
+type LotNumerisation struct {
+	Id             string `json:"id"`
+	RacineMerkle   string `json:"racineMerkle"`   // SHA-256 (hexadécimal) de l'arbre des fichiers, voir RacineMerkle
+	NombreFichiers int    `json:"nombreFichiers"` // Nombre de fichiers (feuilles) du lot
+	Operateur      string `json:"operateur"`      // Opérateur de numérisation (agent ou prestataire)
+	BureauFoncier  string `json:"bureauFoncier,omitempty"`
+	EnregistrePar  string `json:"enregistrePar"` // Identité de l'agent ayant ancré le lot
+	EnregistreLe   string `json:"enregistreLe"`  // RFC3339
+	TxId           string `json:"txId"`
+}
+
+// Étape d'une preuve d'inclusion, de la feuille vers la racine
+type EtapeMerkle struct {
+	Hash string `json:"hash"` // Hash du nœud frère (hexadécimal)
+	Cote string `json:"cote"` // GAUCHE ou DROITE : position du frère
+}
+
+// Résultat de la vérification d'un fichier numérisé contre son lot
+type VerificationNumerisation struct {
+	IdLot       string           `json:"idLot"`
+	HashFichier string           `json:"hashFichier"`
+	Inclus      bool             `json:"inclus"`
+	Lot         *LotNumerisation `json:"lot"`
+}
+
+// Saisie d'un titre d'archive par un opérateur, d'après un fichier numérisé d'un lot ancré
+type SaisieArchive struct {
+	Dossier  DossierImmatriculation `json:"dossier"`
+	IdLot    string                 `json:"idLot"`
+	HashScan string                 `json:"hashScan"` // Hash du fichier numérisé dont la saisie est faite
+	Preuve   []EtapeMerkle          `json:"preuve"`   // Inclusion du fichier dans le lot, voir PreuveMerkle
+}
+
+// Double saisie d'un titre d'archive : le titre n'est immatriculé que si deux opérateurs distincts en
+// font des saisies identiques, ou sur décision d'un superviseur
+type DoubleSaisie struct {
+	IdTitre      string                   `json:"idTitre"`
+	Statut       string                   `json:"statut"`
+	Saisies      []SaisieOperateur        `json:"saisies"`
+	Ecartees     []SaisieOperateur        `json:"ecartees,omitempty"` // Saisies écartées par un superviseur, avant reprise
+	Resolutions  []ResolutionDoubleSaisie `json:"resolutions,omitempty"`
+	ActiveeLe    string                   `json:"activeeLe,omitempty"`
+	TxActivation string                   `json:"txActivation,omitempty"`
+}
+
+// Saisie enregistrée d'un opérateur ; le dossier saisi n'est communiqué qu'aux superviseurs
+type SaisieOperateur struct {
+	Operateur  string                  `json:"operateur"` // Identité de l'opérateur de saisie
+	Empreinte  string                  `json:"empreinte"` // Voir SaisieArchive.Empreinte
+	IdLot      string                  `json:"idLot"`
+	HashScan   string                  `json:"hashScan"`
+	Dossier    *DossierImmatriculation `json:"dossier,omitempty"`
+	Horodatage string                  `json:"horodatage"` // RFC3339
+	TxId       string                  `json:"txId"`
+}
+
+// Décision d'un superviseur sur des saisies discordantes
+type ResolutionDoubleSaisie struct {
+	Superviseur string `json:"superviseur"`
+	Empreinte   string `json:"empreinte"` // Saisie retenue et immatriculée (vide : saisies écartées, à reprendre)
+	Motif       string `json:"motif"`
+	Horodatage  string `json:"horodatage"` // RFC3339
+	TxId        string `json:"txId"`
+}
+
+// Empreinte d'une saisie : SHA-256 (hexadécimal) du JSON du dossier, du lot et du fichier numérisé,
+// la géométrie compactée ; deux saisies concordent si leurs empreintes sont égales
+func (s *SaisieArchive) Empreinte() (string, error) {
+	dossier := s.Dossier
+	if len(dossier.Geometrie) > 0 {
+		var compacte bytes.Buffer
+		if err := json.Compact(&compacte, dossier.Geometrie); err != nil {
+			return "", fmt.Errorf("la géométrie du titre foncier %s doit être un document GeoJSON", dossier.Id)
+		}
+		dossier.Geometrie = compacte.Bytes()
+	}
+	contenu, err := json.Marshal(struct {
+		Dossier  DossierImmatriculation `json:"dossier"`
+		IdLot    string                 `json:"idLot"`
+		HashScan string                 `json:"hashScan"`
+	}{dossier, s.IdLot, strings.ToLower(strings.TrimSpace(s.HashScan))})
+	if err != nil {
+		return "", err
+	}
+	somme := sha256.Sum256(contenu)
+	return hex.EncodeToString(somme[:]), nil
+}
+
+// Copie d'une double saisie sans le contenu des saisies, tel que communiqué aux opérateurs
+func (d *DoubleSaisie) SansDossiers() *DoubleSaisie {
+	copie := *d
+	copie.Saisies = sansDossiers(d.Saisies)
+	copie.Ecartees = sansDossiers(d.Ecartees)
+	return &copie
+}
+
+func sansDossiers(saisies []SaisieOperateur) []SaisieOperateur {
+	if saisies == nil {
+		return nil
+	}
+	resultat := make([]SaisieOperateur, len(saisies))
+	for i, saisie := range saisies {
+		saisie.Dossier = nil
+		resultat[i] = saisie
+	}
+	return resultat
+}
+
+// Racine de l'arbre de Merkle des hashes (SHA-1 ou SHA-256, hexadécimal) des fichiers d'un lot, dans
+// l'ordre du lot, selon la construction de la RFC 6962 : feuille SHA-256(0x00 || hash du fichier), nœud
+// SHA-256(0x01 || gauche || droite), un nombre impair de nœuds ne dupliquant aucun fichier
+func RacineMerkle(hashesFichiers []string) (string, error) {
+	feuilles, err := feuillesMerkle(hashesFichiers)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(racineArbre(feuilles)), nil
+}
+
+// Preuve d'inclusion du fichier de rang indice (à partir de 0) dans l'arbre d'un lot
+func PreuveMerkle(hashesFichiers []string, indice int) ([]EtapeMerkle, error) {
+	feuilles, err := feuillesMerkle(hashesFichiers)
+	if err != nil {
+		return nil, err
+	}
+	if indice < 0 || indice >= len(feuilles) {
+		return nil, fmt.Errorf("rang de fichier hors du lot: %d (%d fichiers)", indice, len(feuilles))
+	}
+	var preuve []EtapeMerkle
+	for len(feuilles) > 1 {
+		k := separationArbre(len(feuilles))
+		if indice < k {
+			preuve = append(preuve, EtapeMerkle{Hash: hex.EncodeToString(racineArbre(feuilles[k:])), Cote: FrereDroite})
+			feuilles = feuilles[:k]
+		} else {
+			preuve = append(preuve, EtapeMerkle{Hash: hex.EncodeToString(racineArbre(feuilles[:k])), Cote: FrereGauche})
+			feuilles, indice = feuilles[k:], indice-k
+		}
+	}
+	// Recueillie de la racine vers la feuille, la preuve se lit dans l'autre sens
+	for i, j := 0, len(preuve)-1; i < j; i, j = i+1, j-1 {
+		preuve[i], preuve[j] = preuve[j], preuve[i]
+	}
+	return preuve, nil
+}
+
+// Vérifier qu'un fichier appartient à l'arbre de racine donnée au vu de sa preuve d'inclusion ; la
+// preuve ne peut compter plus d'étapes que la hauteur d'un arbre de nombreFichiers feuilles
+func VerifierPreuveMerkle(hashFichier string, preuve []EtapeMerkle, racine string, nombreFichiers int) (bool, error) {
+	feuilles, err := feuillesMerkle([]string{hashFichier})
+	if err != nil {
+		return false, err
+	}
+	attendue, err := hex.DecodeString(racine)
+	if err != nil || len(attendue) != sha256.Size {
+		return false, fmt.Errorf("racine de Merkle invalide (SHA-256 attendu): %s", racine)
+	}
+	if nombreFichiers > 0 && len(preuve) > bits.Len(uint(nombreFichiers-1)) {
+		return false, nil
+	}
+
+	noeud := feuilles[0]
+	for _, etape := range preuve {
+		frere, err := hex.DecodeString(strings.ToLower(etape.Hash))
+		if err != nil || len(frere) != sha256.Size {
+			return false, fmt.Errorf("hash d'étape de preuve invalide: %s", etape.Hash)
+		}
+		switch etape.Cote {
+		case FrereGauche:
+			noeud = noeudMerkle(frere, noeud)
+		case FrereDroite:
+			noeud = noeudMerkle(noeud, frere)
+		default:
+			return false, fmt.Errorf("côté d'étape de preuve invalide: %q (attendu: %s ou %s)", etape.Cote, FrereGauche, FrereDroite)
+		}
+	}
+	return bytes.Equal(noeud, attendue), nil
+}
+
+func feuillesMerkle(hashesFichiers []string) ([][]byte, error) {
+	if len(hashesFichiers) == 0 {
+		return nil, fmt.Errorf("un lot compte au moins un fichier")
+	}
+	feuilles := make([][]byte, len(hashesFichiers))
+	for i, hash := range hashesFichiers {
+		octets, err := hex.DecodeString(strings.ToLower(strings.TrimSpace(hash)))
+		if err != nil || (len(octets) != sha256.Size && len(octets) != 20) {
+			return nil, fmt.Errorf("hash de fichier invalide (SHA-1 ou SHA-256 attendu): %s", hash)
+		}
+		somme := sha256.Sum256(append([]byte{0x00}, octets...))
+		feuilles[i] = somme[:]
+	}
+	return feuilles, nil
+}
+
+func racineArbre(feuilles [][]byte) []byte {
+	if len(feuilles) == 1 {
+		return feuilles[0]
+	}
+	k := separationArbre(len(feuilles))
+	return noeudMerkle(racineArbre(feuilles[:k]), racineArbre(feuilles[k:]))
+}
+
+// Plus grande puissance de deux strictement inférieure à n (n > 1)
+func separationArbre(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+func noeudMerkle(gauche []byte, droite []byte) []byte {
+	somme := sha256.Sum256(append(append([]byte{0x01}, gauche...), droite...))
+	return somme[:]
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Hashes SHA-256 de fichiers fictifs, le premier en SHA-1
+func hashesFichiers(n int) []string {
+	hashes := make([]string, n)
+	for i := range hashes {
+		contenu := []byte(fmt.Sprintf("dossier-%d.pdf", i))
+		if i == 0 {
+			somme := sha1.Sum(contenu)
+			hashes[i] = hex.EncodeToString(somme[:])
+			continue
+		}
+		somme := sha256.Sum256(contenu)
+		hashes[i] = hex.EncodeToString(somme[:])
+	}
+	return hashes
+}
+
+func feuille(t *testing.T, hash string) []byte {
+	t.Helper()
+	octets, err := hex.DecodeString(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	somme := sha256.Sum256(append([]byte{0x00}, octets...))
+	return somme[:]
+}
+
+func noeud(gauche []byte, droite []byte) []byte {
+	somme := sha256.Sum256(append(append([]byte{0x01}, gauche...), droite...))
+	return somme[:]
+}
+
+// La racine suit la construction de la RFC 6962, sans duplication du dernier fichier d'un niveau impair
+func TestRacineMerkle(t *testing.T) {
+	hashes := hashesFichiers(5)
+	f := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		f[i] = feuille(t, hash)
+	}
+	cas := []struct {
+		fichiers int
+		attendue []byte
+	}{
+		{1, f[0]},
+		{2, noeud(f[0], f[1])},
+		{3, noeud(noeud(f[0], f[1]), f[2])},
+		{5, noeud(noeud(noeud(f[0], f[1]), noeud(f[2], f[3])), f[4])},
+	}
+	for _, c := range cas {
+		racine, err := RacineMerkle(hashes[:c.fichiers])
+		if err != nil {
+			t.Fatalf("%d fichiers: %v", c.fichiers, err)
+		}
+		if racine != hex.EncodeToString(c.attendue) {
+			t.Errorf("%d fichiers: racine %s, attendue %x", c.fichiers, racine, c.attendue)
+		}
+	}
+
+	// Casse et espaces des hashes déposés sont indifférents
+	racine, _ := RacineMerkle(hashes[:3])
+	variante, err := RacineMerkle([]string{strings.ToUpper(hashes[0]), " " + hashes[1], hashes[2]})
+	if err != nil || variante != racine {
+		t.Errorf("racine des hashes en majuscules: %s (%v), attendue %s", variante, err, racine)
+	}
+
+	for _, invalides := range [][]string{nil, {"zz"}, {hashes[1][:40] + "00"}} {
+		if _, err := RacineMerkle(invalides); err == nil {
+			t.Errorf("lot %q accepté", invalides)
+		}
+	}
+}
+
+func TestPreuveMerkle(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		hashes := hashesFichiers(n)
+		racine, err := RacineMerkle(hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, hash := range hashes {
+			preuve, err := PreuveMerkle(hashes, i)
+			if err != nil {
+				t.Fatalf("%d fichiers, rang %d: %v", n, i, err)
+			}
+			if ok, err := VerifierPreuveMerkle(hash, preuve, racine, n); !ok || err != nil {
+				t.Errorf("%d fichiers, rang %d: preuve refusée (%v)", n, i, err)
+			}
+			// La preuve d'un fichier ne vaut pas pour un autre
+			if ok, _ := VerifierPreuveMerkle(hashes[(i+1)%n], preuve, racine, n); ok && n > 1 {
+				t.Errorf("%d fichiers, rang %d: preuve acceptée pour le fichier %d", n, i, (i+1)%n)
+			}
+		}
+	}
+
+	hashes := hashesFichiers(4)
+	if _, err := PreuveMerkle(hashes, 4); err == nil {
+		t.Error("preuve d'un rang hors du lot")
+	}
+	if _, err := PreuveMerkle(hashes, -1); err == nil {
+		t.Error("preuve d'un rang négatif")
+	}
+}
+
+func TestVerifierPreuveMerkle(t *testing.T) {
+	hashes := hashesFichiers(4)
+	racine, _ := RacineMerkle(hashes)
+	preuve, _ := PreuveMerkle(hashes, 2)
+
+	// Un nœud interne présenté comme un fichier, avec une preuve tronquée
+	interne := hex.EncodeToString(noeud(feuille(t, hashes[2]), feuille(t, hashes[3])))
+	if ok, _ := VerifierPreuveMerkle(interne, preuve[1:], racine, 4); ok {
+		t.Error("nœud interne accepté comme fichier")
+	}
+
+	// Étape altérée, côté inversé ou preuve plus longue que la hauteur de l'arbre
+	alteree := append([]EtapeMerkle{}, preuve...)
+	alteree[0].Hash = strings.Repeat("0", 64)
+	inversee := append([]EtapeMerkle{}, preuve...)
+	inversee[0].Cote = FrereGauche
+	allongee := append(append([]EtapeMerkle{}, preuve...), EtapeMerkle{Hash: racine, Cote: FrereDroite})
+	for nom, p := range map[string][]EtapeMerkle{"altérée": alteree, "inversée": inversee, "allongée": allongee} {
+		if ok, err := VerifierPreuveMerkle(hashes[2], p, racine, 4); ok || err != nil {
+			t.Errorf("preuve %s: %v, %v (refus sans erreur attendu)", nom, ok, err)
+		}
+	}
+
+	if ok, err := VerifierPreuveMerkle(hashes[2], preuve, strings.ToUpper(racine), 4); !ok || err != nil {
+		t.Errorf("racine en majuscules: %v, %v", ok, err)
+	}
+	erronees := []struct {
+		nom    string
+		preuve []EtapeMerkle
+		racine string
+	}{
+		{"racine SHA-1", preuve, hashes[0]},
+		{"racine non hexadécimale", preuve, "racine"},
+		{"étape non hexadécimale", []EtapeMerkle{{Hash: "xyz", Cote: FrereDroite}}, racine},
+		{"côté inconnu", []EtapeMerkle{{Hash: racine, Cote: "HAUT"}}, racine},
+	}
+	for _, c := range erronees {
+		if _, err := VerifierPreuveMerkle(hashes[2], c.preuve, c.racine, 4); err == nil {
+			t.Errorf("%s: erreur attendue", c.nom)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import "fmt"
+
+// Actes d'occupation antérieurs à l'immatriculation
+const (
+	ActeDeliberation  = "DELIBERATION"   // Délibération du conseil municipal affectant une parcelle
+	ActePermisOccuper = "PERMIS_OCCUPER" // Permis d'occuper délivré par l'administration des domaines
+)
+
+// Statuts d'un acte d'occupation
+const (
+	ActeEnVigueur = "EN_VIGUEUR"
+	ActeConverti  = "CONVERTI" // Consommé par l'immatriculation d'un titre foncier
+)
+
+// Acte d'occupation (délibération ou permis d'occuper), converti en titre foncier après visa de la commune
+type ActeOccupation struct {
+	Id           string `json:"id"`
+	Type         string `json:"type"` // DELIBERATION ou PERMIS_OCCUPER
+	Commune      string `json:"commune"`
+	Beneficiaire string `json:"beneficiaire"`                           // Occupant, futur propriétaire
+	Superficie   int    `json:"superficie"`                             // Superficie en m²
+	Date         string `json:"date"`                                   // Date de la délibération ou de délivrance du permis (AAAA-MM-JJ)
+	Document     string `json:"document"`                               // Chemin du fichier NFS
+	DocHash      string `json:"doc_hash"`                               // Hash SHA-1 du document
+	Statut       string `json:"statut"`                                 // EN_VIGUEUR ou CONVERTI
+	VisePar      string `json:"visePar,omitempty" metadata:",optional"` // Identité de l'agent communal ayant visé la conversion
+	ViseLe       string `json:"viseLe,omitempty" metadata:",optional"`  // Date du visa (AAAA-MM-JJ)
+	IdTitre      string `json:"idTitre,omitempty" metadata:",optional"` // Titre foncier issu de la conversion
+}
+
+// Vérifier la cohérence d'un acte d'occupation
+func (a *ActeOccupation) Valider() error {
+	if a.Id == "" {
+		return fmt.Errorf("l'identifiant de l'acte d'occupation est obligatoire")
+	}
+	switch a.Type {
+	case ActeDeliberation, ActePermisOccuper:
+	default:
+		return fmt.Errorf("type d'acte d'occupation inconnu: %s", a.Type)
+	}
+	if a.Commune == "" || a.Beneficiaire == "" {
+		return fmt.Errorf("la commune et le bénéficiaire de l'acte %s sont obligatoires", a.Id)
+	}
+	if a.Superficie <= 0 {
+		return fmt.Errorf("la superficie de l'acte %s doit être positive", a.Id)
+	}
+	_, err := AnalyserDate(a.Date)
+	return err
+}
@@ -0,0 +1,54 @@
+package model
+
+// Nature d'un traitement inscrit au journal des agents
+const (
+	TraitementImmatriculation = "IMMATRICULATION"     // Objet : titre immatriculé
+	TraitementDemande         = "DEMANDE_APPROBATION" // Objet : procédure/objet de la demande
+	TraitementApprobation     = "APPROBATION"         // Objet : procédure/objet de la demande
+	TraitementRejet           = "REJET"               // Objet : procédure/objet de la demande
+	TraitementFinalisation    = "FINALISATION"        // Objet : transfert finalisé
+)
+
+// Traitement d'un acte par un agent du registre, inscrit à la date de la transaction
+type TraitementAgent struct {
+	Agent      string `json:"agent"` // Identité de l'agent
+	Role       string `json:"role,omitempty" metadata:",optional"`
+	Type       string `json:"type"`
+	Objet      string `json:"objet"`
+	TxId       string `json:"txId"`
+	Horodatage string `json:"horodatage"` // RFC 3339
+	// Délai, en secondes, entre la demande d'approbation et la décision de l'agent, ou entre la
+	// proposition du transfert et sa finalisation
+	Delai int64 `json:"delai,omitempty" metadata:",optional"`
+}
+
+// Activité d'un agent sur une période et demandes d'approbation restant à traiter
+type StatistiquesAgent struct {
+	Agent            string `json:"agent"`
+	Role             string `json:"role,omitempty" metadata:",optional"` // Dernier rôle sous lequel il a agi
+	Immatriculations int    `json:"immatriculations"`
+	Demandes         int    `json:"demandes"` // Demandes d'approbation ouvertes
+	Approbations     int    `json:"approbations"`
+	Rejets           int    `json:"rejets"`
+	Finalisations    int    `json:"finalisations"` // Transferts finalisés
+	// Délai moyen, en secondes, entre une demande et la décision de l'agent
+	DelaiMoyenDecision int64 `json:"delaiMoyenDecision"`
+	// Délai moyen, en secondes, entre la proposition d'un transfert et sa finalisation par l'agent
+	DelaiMoyenFinalisation int64 `json:"delaiMoyenFinalisation"`
+	// Délais cumulés, pour recalculer les moyennes de la période à partir de celles des pages
+	DelaiCumuleDecision     int64 `json:"delaiCumuleDecision"`
+	DelaiCumuleFinalisation int64 `json:"delaiCumuleFinalisation"`
+	// État courant des files, répété à chaque page où figure l'agent
+	EnAttente       int `json:"enAttente"`       // Demandes en cours sur lesquelles il peut se prononcer
+	DemandesEnCours int `json:"demandesEnCours"` // Demandes qu'il a ouvertes, toujours en cours
+}
+
+// Statistiques des agents sur une période (AAAA, AAAA-MM ou AAAA-MM-JJ), par pages du journal : les
+// comptes d'un agent sur la période sont la somme de ses comptes sur les pages
+type StatistiquesAgents struct {
+	Periode string              `json:"periode"`
+	Debut   string              `json:"debut"`
+	Fin     string              `json:"fin"` // Dernier jour inclus
+	Agents  []StatistiquesAgent `json:"agents"`
+	Signet  string              `json:"signet,omitempty" metadata:",optional"` // Reprise de la page suivante (vide : période épuisée)
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Systèmes de référence des géométries déposées ; le registre les enregistre en WGS84
+const (
+	CrsWGS84  = "EPSG:4326"  // Longitude, latitude en degrés (défaut de GeoJSON)
+	CrsUTM28N = "EPSG:32628" // UTM fuseau 28 Nord, en mètres, usuel pour les levés au Sénégal
+)
+
+// Paramètres de l'ellipsoïde WGS84 et de la projection UTM du fuseau 28 Nord
+const (
+	demiGrandAxe    = 6378137.0
+	aplatissement   = 1 / 298.257223563
+	facteurEchelle  = 0.9996
+	fausseAbscisse  = 500000.0
+	meridienCentral = -15.0
+)
+
+// Coordonnées enregistrées au dix-millionième de degré (environ 1 cm)
+const precisionDegres = 1e7
+
+// Normaliser une géométrie GeoJSON de type Polygon ou MultiPolygon, éventuellement portée par une
+// Feature, déposée en WGS84 ou en UTM 28N selon son membre "crs" (WGS84 à défaut) : elle est convertie
+// en WGS84, ses coordonnées arrondies pour que tous les pairs enregistrent la même valeur, et chaque
+// sommet doit tomber dans l'emprise du territoire [ouest, sud, est, nord] (vide : tout le globe) et
+// la topologie des polygones être valide (ValiderTopologie). Les anneaux sont réorientés selon RFC 7946
+// (OrienterAnneaux), les exports de SIG et de levés étant souvent dans le sens horaire. Retourne la
+// géométrie canonique et le système dans lequel elle a été déposée.
+func NormaliserGeometrie(geojson string, emprise []float64) (string, string, error) {
+	return normaliserGeometrie(geojson, CrsWGS84, emprise)
+}
+
+// Le système déclaré par une Feature vaut pour sa géométrie, qui peut aussi déclarer le sien
+func normaliserGeometrie(geojson string, crs string, emprise []float64) (string, string, error) {
+	var geometrie struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+		Crs         *struct {
+			Properties struct {
+				Name string `json:"name"`
+			} `json:"properties"`
+		} `json:"crs"`
+	}
+	if err := json.Unmarshal([]byte(geojson), &geometrie); err != nil {
+		return "", "", fmt.Errorf("géométrie GeoJSON invalide: %v", err)
+	}
+	if geometrie.Crs != nil {
+		var err error
+		if crs, err = systemeReference(geometrie.Crs.Properties.Name); err != nil {
+			return "", "", err
+		}
+	}
+	if geometrie.Type == "Feature" {
+		return normaliserGeometrie(string(geometrie.Geometry), crs, emprise)
+	}
+
+	var polygones [][][]Point
+	switch geometrie.Type {
+	case "Polygon":
+		var anneaux [][]Point
+		if err := json.Unmarshal(geometrie.Coordinates, &anneaux); err != nil {
+			return "", "", fmt.Errorf("coordonnées de polygone invalides")
+		}
+		polygones = [][][]Point{anneaux}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometrie.Coordinates, &polygones); err != nil {
+			return "", "", fmt.Errorf("coordonnées de multipolygone invalides")
+		}
+	default:
+		return "", "", fmt.Errorf("type de géométrie non pris en charge: %q (attendu: Polygon ou MultiPolygon)", geometrie.Type)
+	}
+
+	for _, anneaux := range polygones {
+		for _, anneau := range anneaux {
+			for i, p := range anneau {
+				normalise, err := normaliserPoint(p, crs, emprise)
+				if err != nil {
+					return "", "", err
+				}
+				anneau[i] = normalise
+			}
+		}
+	}
+	OrienterAnneaux(polygones)
+	if err := ValiderTopologie(polygones); err != nil {
+		return "", "", err
+	}
+
+	sortie := struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{Type: geometrie.Type, Coordinates: polygones}
+	if geometrie.Type == "Polygon" {
+		sortie.Coordinates = polygones[0]
+	}
+	canonique, err := json.Marshal(sortie)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := ContoursGeoJSON(string(canonique)); err != nil {
+		return "", "", err
+	}
+	return string(canonique), crs, nil
+}
+
+// Système de référence désigné par le nom d'un membre "crs" GeoJSON (ex: "EPSG:32628",
+// "urn:ogc:def:crs:EPSG::32628", "urn:ogc:def:crs:OGC:1.3:CRS84")
+func systemeReference(nom string) (string, error) {
+	normalise := strings.ToUpper(nom)
+	switch {
+	case normalise == CrsWGS84, strings.HasSuffix(normalise, ":EPSG::4326"), strings.HasSuffix(normalise, ":CRS84"):
+		return CrsWGS84, nil
+	case normalise == CrsUTM28N, strings.HasSuffix(normalise, ":EPSG::32628"):
+		return CrsUTM28N, nil
+	}
+	return "", fmt.Errorf("système de référence non pris en charge: %q (attendu: %s ou %s)", nom, CrsWGS84, CrsUTM28N)
+}
+
+// Convertir un sommet en WGS84 et en contrôler la vraisemblance
+func normaliserPoint(p Point, crs string, emprise []float64) (Point, error) {
+	if crs == CrsUTM28N {
+		if p[0] <= 0 || p[0] >= 1000000 || p[1] < 0 || p[1] >= 9400000 {
+			return Point{}, fmt.Errorf("coordonnées UTM 28N invraisemblables: %v (abscisse et ordonnée en mètres attendues)", p)
+		}
+		p = UTM28NVersWGS84(p)
+	} else if math.Abs(p[0]) > 180 || math.Abs(p[1]) > 90 {
+		return Point{}, fmt.Errorf("coordonnées hors du domaine WGS84: %v (géométrie en UTM 28N sans membre crs ?)", p)
+	}
+	p = Point{math.Round(p[0]*precisionDegres) / precisionDegres, math.Round(p[1]*precisionDegres) / precisionDegres}
+	if len(emprise) == 4 && (p[0] < emprise[0] || p[1] < emprise[1] || p[0] > emprise[2] || p[1] > emprise[3]) {
+		return Point{}, fmt.Errorf("le point (%v, %v) est hors de l'emprise du territoire %v (longitude et latitude inversées ?)", p[0], p[1], emprise)
+	}
+	return p, nil
+}
+
+// Convertir un point UTM 28N (abscisse, ordonnée en mètres) en WGS84 (longitude, latitude en degrés),
+// par les séries de Krüger : l'écart reste inférieur au millimètre dans le fuseau
+func UTM28NVersWGS84(p Point) Point {
+	n := aplatissement / (2 - aplatissement)
+	rayon := demiGrandAxe / (1 + n) * (1 + n*n/4 + n*n*n*n/64)
+	beta := [3]float64{n/2 - 2*n*n/3 + 37*n*n*n/96, n*n/48 + n*n*n/15, 17 * n * n * n / 480}
+	delta := [3]float64{2*n - 2*n*n/3 - 2*n*n*n, 7*n*n/3 - 8*n*n*n/5, 56 * n * n * n / 15}
+
+	xi := p[1] / (facteurEchelle * rayon)
+	eta := (p[0] - fausseAbscisse) / (facteurEchelle * rayon)
+	xiPrime, etaPrime := xi, eta
+	for j := 1; j <= 3; j++ {
+		k := 2 * float64(j)
+		xiPrime -= beta[j-1] * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= beta[j-1] * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+	latitude := chi
+	for j := 1; j <= 3; j++ {
+		latitude += delta[j-1] * math.Sin(2*float64(j)*chi)
+	}
+	longitude := meridienCentral*math.Pi/180 + math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime))
+	return Point{longitude * 180 / math.Pi, latitude * 180 / math.Pi}
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Emprise du Sénégal [ouest, sud, est, nord]
+var empriseTest = []float64{-17.6, 12.2, -11.3, 16.7}
+
+// Projection directe WGS84 vers UTM 28N par les séries de Krüger (coefficients alpha), indépendante de
+// la projection inverse contrôlée
+func wgs84VersUTM28N(p Point) Point {
+	n := aplatissement / (2 - aplatissement)
+	rayon := demiGrandAxe / (1 + n) * (1 + n*n/4 + n*n*n*n/64)
+	alpha := [3]float64{n/2 - 2*n*n/3 + 5*n*n*n/16, 13*n*n/48 - 3*n*n*n/5, 61 * n * n * n / 240}
+
+	latitude := p[1] * math.Pi / 180
+	ecart := (p[0] - meridienCentral) * math.Pi / 180
+	e := 2 * math.Sqrt(n) / (1 + n)
+	tau := math.Sinh(math.Atanh(math.Sin(latitude)) - e*math.Atanh(e*math.Sin(latitude)))
+	xi := math.Atan2(tau, math.Cos(ecart))
+	eta := math.Atanh(math.Sin(ecart) / math.Sqrt(1+tau*tau))
+	x, y := eta, xi
+	for j := 1; j <= 3; j++ {
+		k := 2 * float64(j)
+		x += alpha[j-1] * math.Cos(k*xi) * math.Sinh(k*eta)
+		y += alpha[j-1] * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	return Point{fausseAbscisse + facteurEchelle*rayon*x, facteurEchelle * rayon * y}
+}
+
+func TestUTM28NVersWGS84(t *testing.T) {
+	// Origine du fuseau : équateur sur le méridien central
+	if p := UTM28NVersWGS84(Point{500000, 0}); math.Abs(p[0]+15) > 1e-12 || math.Abs(p[1]) > 1e-12 {
+		t.Errorf("origine du fuseau: %v", p)
+	}
+
+	// Aller-retour au millimètre sur le territoire, de Dakar à Kédougou
+	for _, attendu := range []Point{{-17.4467, 14.6928}, {-16.2625, 12.5833}, {-15, 14}, {-12.18, 12.55}, {-16.49, 16.02}} {
+		utm := wgs84VersUTM28N(attendu)
+		p := UTM28NVersWGS84(utm)
+		dx := (p[0] - attendu[0]) * math.Pi / 180 * demiGrandAxe * math.Cos(attendu[1]*math.Pi/180)
+		dy := (p[1] - attendu[1]) * math.Pi / 180 * demiGrandAxe
+		if math.Hypot(dx, dy) > 1e-3 {
+			t.Errorf("%v: UTM %v reconverti en %v, écart de %.4f m", attendu, utm, p, math.Hypot(dx, dy))
+		}
+	}
+
+	// Symétrie de part et d'autre du méridien central
+	ouest, est := UTM28NVersWGS84(Point{400000, 1600000}), UTM28NVersWGS84(Point{600000, 1600000})
+	if math.Abs(ouest[0]+est[0]+30) > 1e-9 || math.Abs(ouest[1]-est[1]) > 1e-9 {
+		t.Errorf("points symétriques: %v et %v", ouest, est)
+	}
+}
+
+func TestNormaliserGeometrie(t *testing.T) {
+	// Carré d'environ 100 m levé en UTM 28N dans le sens horaire, près de Dakar
+	sommets := []Point{{236000, 1626000}, {236000, 1626100}, {236100, 1626100}, {236100, 1626000}, {236000, 1626000}}
+	coordonnees, _ := json.Marshal([][]Point{sommets})
+	utm := `{"type":"Feature","crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:EPSG::32628"}},` +
+		`"geometry":{"type":"Polygon","coordinates":` + string(coordonnees) + `}}`
+
+	canonique, crs, err := NormaliserGeometrie(utm, empriseTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crs != CrsUTM28N {
+		t.Errorf("système déposé %s, attendu %s", crs, CrsUTM28N)
+	}
+	var geometrie struct {
+		Type        string    `json:"type"`
+		Coordinates [][]Point `json:"coordinates"`
+	}
+	if err := json.Unmarshal([]byte(canonique), &geometrie); err != nil || geometrie.Type != "Polygon" {
+		t.Fatalf("géométrie canonique %s (%v)", canonique, err)
+	}
+	anneau := geometrie.Coordinates[0]
+	if aireSignee(anneau) <= 0 || anneau[0] != anneau[len(anneau)-1] {
+		t.Errorf("anneau extérieur non réorienté dans le sens antihoraire: %v", anneau)
+	}
+	for _, p := range anneau {
+		for _, c := range p {
+			if math.Abs(c*precisionDegres-math.Round(c*precisionDegres)) > 1e-6 {
+				t.Errorf("coordonnée %v non arrondie au dix-millionième de degré", c)
+			}
+		}
+	}
+	contours, _ := ContoursGeoJSON(canonique)
+	if aire := Superficie(contours); math.Abs(aire-10000) > 100 {
+		t.Errorf("superficie %.1f m², environ 10 000 m² attendus", aire)
+	}
+
+	// La géométrie canonique se normalise à l'identique
+	if encore, crs, err := NormaliserGeometrie(canonique, empriseTest); err != nil || encore != canonique || crs != CrsWGS84 {
+		t.Errorf("renormalisation: %s, %s (%v)\n attendu %s", encore, crs, err, canonique)
+	}
+}
+
+func TestNormaliserGeometrieRefus(t *testing.T) {
+	carre := func(ouest, sud, cote float64) string {
+		coordonnees, _ := json.Marshal([][]Point{{{ouest, sud}, {ouest + cote, sud}, {ouest + cote, sud + cote}, {ouest, sud + cote}, {ouest, sud}}})
+		return string(coordonnees)
+	}
+	cas := []struct {
+		nom     string
+		geojson string
+		erreur  string
+	}{
+		{"JSON invalide", `{"type":`, "GeoJSON invalide"},
+		{"type non pris en charge", `{"type":"Point","coordinates":[-17.4,14.7]}`, "non pris en charge"},
+		{"système inconnu", `{"type":"Polygon","crs":{"properties":{"name":"EPSG:2154"}},"coordinates":` + carre(-17.4, 14.7, 0.001) + `}`, "EPSG:2154"},
+		{"UTM sans membre crs", `{"type":"Polygon","coordinates":` + carre(236000, 1626000, 100) + `}`, "hors du domaine WGS84"},
+		{"UTM invraisemblable", `{"type":"Polygon","crs":{"properties":{"name":"EPSG:32628"}},"coordinates":` + carre(-17.4, 14.7, 0.001) + `}`, "UTM 28N invraisemblables"},
+		{"latitude et longitude inversées", `{"type":"Polygon","coordinates":` + carre(14.7, -17.4, 0.001) + `}`, "hors de l'emprise"},
+		{"topologie invalide", `{"type":"Polygon","coordinates":[[[-17.4,14.7],[-17.3,14.8],[-17.3,14.7],[-17.4,14.8],[-17.4,14.7]]]}`, "se recoupe"},
+	}
+	for _, c := range cas {
+		_, _, err := NormaliserGeometrie(c.geojson, empriseTest)
+		if err == nil || !strings.Contains(err.Error(), c.erreur) {
+			t.Errorf("%s: erreur %v, attendue contenant %q", c.nom, err, c.erreur)
+		}
+	}
+
+	// Sans emprise, seul le domaine WGS84 est contrôlé
+	if _, _, err := NormaliserGeometrie(`{"type":"Polygon","coordinates":`+carre(2.35, 48.85, 0.001)+`}`, nil); err != nil {
+		t.Errorf("géométrie hors du territoire sans emprise: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Trace d'audit de la fusion d'un propriétaire enregistré en double
+type FusionProprietaires struct {
+	Id           string   `json:"id"`           // Transaction de fusion
+	IdCanonique  string   `json:"idCanonique"`  // Propriétaire conservé
+	IdDoublon    string   `json:"idDoublon"`    // Propriétaire absorbé
+	Titres       []string `json:"titres"`       // Titres réattribués au propriétaire canonique
+	Conservateur string   `json:"conservateur"` // Identité du conservateur ayant prononcé la fusion
+	Date         string   `json:"date"`         // Date de la fusion (AAAA-MM-JJ)
+}
+
+// Coordonnées d'un propriétaire, conservées dans la collection des données personnelles
+type ContactProprietaire struct {
+	IdProprio  string `json:"idProprio"`
+	Telephone  string `json:"telephone,omitempty" metadata:",optional"`
+	Email      string `json:"email,omitempty" metadata:",optional"`
+	Adresse    string `json:"adresse,omitempty" metadata:",optional"`
+	MisAJourLe string `json:"misAJourLe"` // Horodatage de la dernière mise à jour (RFC 3339)
+}
+
+// Vérifier la cohérence des coordonnées saisies par le propriétaire
+func (c *ContactProprietaire) Valider() error {
+	if c.Telephone == "" && c.Email == "" && c.Adresse == "" {
+		return fmt.Errorf("au moins une coordonnée est requise")
+	}
+	for _, r := range c.Telephone {
+		if !unicode.IsDigit(r) && r != '+' && r != ' ' {
+			return fmt.Errorf("numéro de téléphone invalide: %s", c.Telephone)
+		}
+	}
+	if c.Email != "" && !strings.Contains(c.Email, "@") {
+		return fmt.Errorf("adresse email invalide: %s", c.Email)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package model
+
+// Tranches du score de complétude d'un titre foncier
+const (
+	QualiteFaible  = "FAIBLE"  // Score inférieur à 50
+	QualiteMoyenne = "MOYENNE" // Score de 50 à 74
+	QualiteBonne   = "BONNE"   // Score de 75 et plus
+)
+
+// Complétude d'un titre foncier, recalculée à chaque écriture ; chaque critère rempli compte pour un
+// quart du score
+type QualiteTitre struct {
+	Score               int    `json:"score"` // De 0 à 100
+	Tranche             string `json:"tranche"`
+	Geometrie           bool   `json:"geometrie"`           // Contour de la parcelle déposé
+	ProprietaireNIN     bool   `json:"proprietaireNIN"`     // Propriétaire désigné par son numéro d'identification national
+	DocumentsVerifies   bool   `json:"documentsVerifies"`   // Document d'origine contrôlé et pièce en vigueur d'une autorité reconnue
+	AttestationGeometre bool   `json:"attestationGeometre"` // Contour courant attesté par un géomètre agréé
+}
+
+// Évaluer la complétude d'un titre d'après son seul enregistrement
+func EvaluerQualite(titre *TitreFoncier) QualiteTitre {
+	qualite := QualiteTitre{
+		Geometrie:           titre.Geometrie != "",
+		ProprietaireNIN:     EstNIN(titre.Proprio),
+		AttestationGeometre: titre.Geometrie != "" && titre.GeometreContour != "",
+	}
+	if titre.DocHash != "" {
+		for _, document := range titre.Documents {
+			// Les pièces ne sont rattachées que sur présentation par une autorité enregistrée
+			if document.Statut != DocumentObsolete && document.Issuer != "" && document.Hash != "" {
+				qualite.DocumentsVerifies = true
+				break
+			}
+		}
+	}
+	for _, rempli := range []bool{qualite.Geometrie, qualite.ProprietaireNIN, qualite.DocumentsVerifies, qualite.AttestationGeometre} {
+		if rempli {
+			qualite.Score += 25
+		}
+	}
+	qualite.Tranche = TrancheQualite(qualite.Score)
+	return qualite
+}
+
+// Tranche d'un score de complétude
+func TrancheQualite(score int) string {
+	switch {
+	case score >= 75:
+		return QualiteBonne
+	case score >= 50:
+		return QualiteMoyenne
+	default:
+		return QualiteFaible
+	}
+}
+
+// Indiquer si un identifiant est un numéro d'identification national (NIN) : 13 chiffres, le premier
+// (1 ou 2) donnant le sexe du titulaire
+func EstNIN(identifiant string) bool {
+	if len(identifiant) != 13 || (identifiant[0] != '1' && identifiant[0] != '2') {
+		return false
+	}
+	for _, c := range identifiant {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+package model
+
+// Appels d'une identité à une transaction soumise à quota pendant une tranche horaire
+type CompteurAppels struct {
+	Identite  string `json:"identite"`
+	Operation string `json:"operation"` // Nom de la transaction (ex: ProposerTransfert)
+	Tranche   string `json:"tranche"`   // Heure de la tranche (AAAA-MM-JJTHH, UTC)
+	Appels    int    `json:"appels"`
+}
+
+// Dépassement de quota toléré (mode signalement), conservé pour les enquêteurs
+type SignalementQuota struct {
+	Id        string `json:"id"` // Transaction ayant dépassé le quota
+	Identite  string `json:"identite"`
+	Operation string `json:"operation"`
+	Nombre    int    `json:"nombre"` // Appels sur la fenêtre, celui-ci compris
+	Quota     int    `json:"quota"`
+	Date      string `json:"date"` // Horodatage de l'appel (RFC 3339)
+}
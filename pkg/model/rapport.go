@@ -0,0 +1,95 @@
+package model
+
+// Volets du rapport d'activité par période (vide : tous)
+const (
+	RapportTitres     = "TITRES"
+	RapportTransferts = "TRANSFERTS"
+	RapportLitiges    = "LITIGES"
+	RapportFrais      = "FRAIS"
+)
+
+// Activités inscrites dans l'index par date au fil des transactions qui les produisent
+const (
+	ActiviteTitreCree         = "TITRE_CREE"
+	ActiviteTransfertPropose  = "TRANSFERT_PROPOSE"
+	ActiviteTransfertFinalise = "TRANSFERT_FINALISE" // Montant : prix du transfert
+	ActiviteTransfertAnnule   = "TRANSFERT_ANNULE"
+	ActiviteLitigeOuvert      = "LITIGE_OUVERT"
+	ActiviteLitigeClos        = "LITIGE_CLOS"
+	ActiviteFraisFactures     = "FRAIS_FACTURES"   // Montant : facture émise
+	ActiviteFraisEncaisses    = "FRAIS_ENCAISSES"  // Montant : facture réglée
+	ActiviteFraisRembourses   = "FRAIS_REMBOURSES" // Montant : remboursement approuvé
+)
+
+// Activité datée du registre, enregistrée sous la date de sa transaction
+type ActiviteDatee struct {
+	Activite string `json:"activite"`
+	Objet    string `json:"objet"` // Titre, transfert, litige, facture ou remboursement concerné
+	Commune  string `json:"commune,omitempty" metadata:",optional"`
+	Montant  int    `json:"montant,omitempty" metadata:",optional"` // FCFA
+}
+
+// Volet du rapport d'activité dont relève une activité
+func VoletActivite(activite string) string {
+	switch activite {
+	case ActiviteTitreCree:
+		return RapportTitres
+	case ActiviteTransfertPropose, ActiviteTransfertFinalise, ActiviteTransfertAnnule:
+		return RapportTransferts
+	case ActiviteLitigeOuvert, ActiviteLitigeClos:
+		return RapportLitiges
+	default:
+		return RapportFrais
+	}
+}
+
+// Activité du registre sur une période, datée par l'horodatage des transactions. Un rapport paginé ne
+// compte que les activités de sa page : les comptes de la période sont la somme de ceux des pages.
+type RapportPeriode struct {
+	Debut string `json:"debut"` // AAAA-MM-JJ, inclus
+	Fin   string `json:"fin"`   // AAAA-MM-JJ, inclus
+	Type  string `json:"type,omitempty" metadata:",optional"`
+
+	TitresCrees         int            `json:"titresCrees"`
+	TitresParCommune    map[string]int `json:"titresParCommune,omitempty" metadata:",optional"`
+	TransfertsProposes  int            `json:"transfertsProposes"`
+	TransfertsFinalises int            `json:"transfertsFinalises"`
+	TransfertsAnnules   int            `json:"transfertsAnnules"`
+	VolumeTransferts    int            `json:"volumeTransferts"` // Cumul des prix des transferts finalisés (FCFA)
+	LitigesOuverts      int            `json:"litigesOuverts"`
+	LitigesClos         int            `json:"litigesClos"`
+	FraisFactures       int            `json:"fraisFactures"`   // Droits et frais facturés sur la période (FCFA)
+	FraisEncaisses      int            `json:"fraisEncaisses"`  // Factures réglées au Trésor sur la période (FCFA)
+	FraisRembourses     int            `json:"fraisRembourses"` // Remboursements approuvés sur la période (FCFA)
+
+	Signet string `json:"signet,omitempty" metadata:",optional"` // Reprise de la page suivante (vide : période épuisée)
+}
+
+// Reporter une activité sur les comptes du rapport
+func (r *RapportPeriode) Ajouter(activite ActiviteDatee) {
+	switch activite.Activite {
+	case ActiviteTitreCree:
+		r.TitresCrees++
+		if r.TitresParCommune == nil {
+			r.TitresParCommune = map[string]int{}
+		}
+		r.TitresParCommune[activite.Commune]++
+	case ActiviteTransfertPropose:
+		r.TransfertsProposes++
+	case ActiviteTransfertFinalise:
+		r.TransfertsFinalises++
+		r.VolumeTransferts += activite.Montant
+	case ActiviteTransfertAnnule:
+		r.TransfertsAnnules++
+	case ActiviteLitigeOuvert:
+		r.LitigesOuverts++
+	case ActiviteLitigeClos:
+		r.LitigesClos++
+	case ActiviteFraisFactures:
+		r.FraisFactures += activite.Montant
+	case ActiviteFraisEncaisses:
+		r.FraisEncaisses += activite.Montant
+	case ActiviteFraisRembourses:
+		r.FraisRembourses += activite.Montant
+	}
+}
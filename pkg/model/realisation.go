@@ -0,0 +1,46 @@
+package model
+
+// Statuts d'une procédure de réalisation d'hypothèque
+const (
+	RealisationDefaillance = "DEFAILLANCE" // Défaillance du débiteur constatée par le créancier
+	RealisationAutorisee   = "AUTORISEE"   // Décision judiciaire autorisant la réalisation enregistrée
+	RealisationEngagee     = "ENGAGEE"     // Réalisation engagée par le créancier, le titre est indisponible
+	RealisationCloturee    = "CLOTUREE"    // Titre adjugé ou attribué, hypothèques purgées
+)
+
+// Issues d'une réalisation d'hypothèque
+const (
+	RealisationAdjudication = "ADJUDICATION" // Vente forcée aux enchères
+	RealisationAttribution  = "ATTRIBUTION"  // Attribution judiciaire du titre au créancier
+)
+
+// Procédure de réalisation d'une hypothèque, de la défaillance du débiteur au transfert forcé du titre
+type RealisationHypotheque struct {
+	IdHypotheque string             `json:"idHypotheque"`
+	IdTitre      string             `json:"idTitre"`
+	Creancier    string             `json:"creancier"` // MSP de la banque poursuivante
+	Statut       string             `json:"statut"`
+	Etapes       []EtapeRealisation `json:"etapes"`                                      // Étapes franchies, dans l'ordre
+	Mode         string             `json:"mode,omitempty" metadata:",optional"`         // ADJUDICATION ou ATTRIBUTION
+	Attributaire string             `json:"attributaire,omitempty" metadata:",optional"` // Nouveau propriétaire du titre
+	Prix         int                `json:"prix,omitempty" metadata:",optional"`         // Prix d'adjudication ou valeur d'attribution (FCFA)
+	Repartition  []RepartitionPrix  `json:"repartition,omitempty" metadata:",optional"`  // Distribution du prix entre créanciers, par rang
+	Solde        int                `json:"solde,omitempty" metadata:",optional"`        // Reliquat revenant au propriétaire saisi
+}
+
+// Étape d'une réalisation d'hypothèque et acte qui la fonde
+type EtapeRealisation struct {
+	Statut    string `json:"statut"`                                  // Statut atteint par l'étape
+	Reference string `json:"reference"`                               // Commandement, décision ou jugement
+	Autorite  string `json:"autorite,omitempty" metadata:",optional"` // Juridiction ayant rendu la décision
+	Par       string `json:"par"`                                     // Identité de l'auteur de l'étape
+	Le        string `json:"le"`                                      // Horodatage de l'étape (RFC 3339)
+}
+
+// Part du prix de réalisation revenant à un créancier hypothécaire
+type RepartitionPrix struct {
+	IdHypotheque string `json:"idHypotheque"`
+	Creancier    string `json:"creancier"`
+	Rang         int    `json:"rang"`
+	Montant      int    `json:"montant"` // Somme attribuée, au plus la créance garantie
+}
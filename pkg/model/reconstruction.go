@@ -0,0 +1,7 @@
+package model
+
+// Phases de la reconstruction d'un index, dans l'ordre de parcours
+const (
+	PhaseReconstructionTitres  = "TITRES"  // Rétablir les entrées manquantes à partir des titres
+	PhaseReconstructionEntrees = "ENTREES" // Retirer les entrées orphelines ou périmées
+)
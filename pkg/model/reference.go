@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Référence d'un titre dans les anciens livres fonciers papier, citée par les actes antérieurs à sa
+// migration sur le registre
+type AncienneReference struct {
+	Livre string `json:"livre"` // Volume du livre foncier (ex: "12", "B-3")
+	Folio string `json:"folio"`
+}
+
+// Forme normalisée d'une référence, sous laquelle elle est enregistrée et recherchée : casse et
+// espacement ignorés, zéros non significatifs d'un folio numérique retirés
+func (r AncienneReference) Normaliser() AncienneReference {
+	folio := strings.ToUpper(strings.Join(strings.Fields(r.Folio), ""))
+	if numerique := strings.TrimLeft(folio, "0"); numerique != "" && strings.Trim(numerique, "0123456789") == "" {
+		folio = numerique
+	} else if numerique == "" && folio != "" {
+		folio = "0"
+	}
+	return AncienneReference{Livre: strings.ToUpper(strings.Join(strings.Fields(r.Livre), "")), Folio: folio}
+}
+
+// Vérifier qu'une référence désigne un livre et un folio
+func (r AncienneReference) Valider() error {
+	if strings.TrimSpace(r.Livre) == "" || strings.TrimSpace(r.Folio) == "" {
+		return fmt.Errorf("une ancienne référence indique le livre foncier et le folio")
+	}
+	return nil
+}
+
+// Ancienne référence retirée d'un titre, saisie par erreur : la correction reste inscrite sur le titre
+type ReferenceRetiree struct {
+	Id           string            `json:"id"`           // Transaction de la correction
+	Reference    AncienneReference `json:"reference"`    // Livre et folio retirés, libérés pour le titre qu'ils désignent
+	Motif        string            `json:"motif"`        // Justification de la correction
+	Conservateur string            `json:"conservateur"` // Identité du conservateur ayant retiré la référence
+	Date         string            `json:"date"`         // Horodatage de la correction (RFC 3339)
+}
@@ -0,0 +1,385 @@
+package model
+
+import "fmt"
+
+// Catégories d'autorités habilitées à émettre des actes
+const (
+	AutoriteConservation = "CONSERVATION_FONCIERE"
+	AutoriteTribunal     = "TRIBUNAL"
+	AutoriteMairie       = "MAIRIE"
+)
+
+// Types MIME des documents numérisés admis par défaut
+const (
+	TypePDF  = "application/pdf"
+	TypeTIFF = "image/tiff"
+)
+
+// Formats de sérialisation des enregistrements d'état
+const (
+	SerialisationJSON    = "json"
+	SerialisationMsgpack = "msgpack" // Plus compact, mais invisible des requêtes riches CouchDB ; les titres restent en JSON
+)
+
+// Définition d'une autorité reconnue pour l'émission de documents
+type AutoriteEmettrice struct {
+	Id         string `json:"id"`         // Identifiant de l'autorité (référencé par les documents)
+	Nom        string `json:"nom"`        // Dénomination officielle
+	Type       string `json:"type"`       // CONSERVATION_FONCIERE, TRIBUNAL ou MAIRIE
+	Certificat string `json:"certificat"` // Certificat de signature (PEM)
+	Empreinte  string `json:"empreinte"`  // Empreinte SHA-256 du certificat
+	Active     bool   `json:"active"`     // Faux après révocation
+}
+
+// Paramètres métier du registre pour un canal, modifiables par l'État
+type Configuration struct {
+	SeuilEcartPrix      int      `json:"seuilEcartPrix"`      // Écart toléré (en %) entre prix déclaré et dernière évaluation
+	PrefixeNumerotation string   `json:"prefixeNumerotation"` // Préfixe des numéros attribués sur ce canal (ex: "DK")
+	Communes            []string `json:"communes"`            // Communes administrées par ce canal (vide: aucune restriction)
+	NomChaincode        string   `json:"nomChaincode"`        // Nom de ce chaincode sur les autres canaux régionaux
+	Serialisation       string   `json:"serialisation"`       // Format des enregistrements écrits sur ce canal (json ou msgpack)
+
+	// Endossement renforcé des parcelles de grande valeur (seuils à 0 : désactivé)
+	SeuilSuperficieEndossement int      `json:"seuilSuperficieEndossement"` // Superficie (m²) à partir de laquelle le renforcement s'applique
+	SeuilValeurEndossement     int      `json:"seuilValeurEndossement"`     // Valeur évaluée (FCFA) à partir de laquelle le renforcement s'applique
+	OrganisationsEndossement   []string `json:"organisationsEndossement"`   // MSP dont l'endossement est alors exigé (ex: ministère et bureau régional)
+
+	DelaiConfirmationArchivage  int `json:"delaiConfirmationArchivage"`  // Heures laissées au second conservateur pour confirmer un archivage
+	RetentionDocumentsObsoletes int `json:"retentionDocumentsObsoletes"` // Jours de conservation d'un document remplacé avant sa purge
+	DelaiRappelEcheances        int `json:"delaiRappelEcheances"`        // Jours avant une échéance à partir desquels le balayage la rappelle
+
+	// Documents acceptés à l'enregistrement, d'après leur contenu
+	TailleMaxDocuments int      `json:"tailleMaxDocuments"` // Taille maximale d'un fichier (octets, 0 : sans limite)
+	TypesDocuments     []string `json:"typesDocuments"`     // Types MIME admis (ex: "application/pdf")
+
+	// Quotas anti-fraude : appels admis par identité sur 24 heures glissantes, par transaction
+	QuotasOperations map[string]int `json:"quotasOperations"` // Ex: {"ProposerTransfert": 10} (vide : aucun quota)
+	BlocageQuotas    bool           `json:"blocageQuotas"`    // Rejeter les appels au-delà du quota (sinon : les signaler)
+
+	ReglesTransfert    []RegleTransfert    `json:"reglesTransfert"`    // Restrictions de cession par zone, commune et qualité de l'acquéreur
+	ReglesMorcellement []RegleMorcellement `json:"reglesMorcellement"` // Superficie minimale des lots, nombre maximal de lots et prix de réserve des détachements, par zone
+	QuorumsApprobation []QuorumApprobation `json:"quorumsApprobation"` // Approbations collégiales exigées par procédure (vide : aucune)
+	PlafondsZones      []PlafondZone       `json:"plafondsZones"`      // Superficie attribuable par commune et zonage (vide : sans plafond)
+	DelaisTraitement   []DelaiTraitement   `json:"delaisTraitement"`   // Séjour maximal par procédure et statut avant escalade (vide : aucun)
+	TarifsFrais        []TarifFrais        `json:"tarifsFrais"`        // Droits et frais facturés par procédure (vide : aucune facturation)
+
+	ComptesComptables ComptesComptables `json:"comptesComptables"` // Comptes des écritures exportées au Trésor
+
+	// Rectangle [ouest, sud, est, nord], en degrés WGS84, hors duquel un sommet de géométrie déposée est
+	// tenu pour invraisemblable (vide : tout le globe)
+	EmpriseTerritoire []float64 `json:"empriseTerritoire"`
+
+	BlocageEmprises bool `json:"blocageEmprises"` // Rejeter transferts et permis de construire des parcelles sous réserve d'emprise (sinon : les signaler)
+
+	// Refuser la cession d'une parcelle dont les travaux autorisés ont commencé sans certificat de conformité
+	// (première vente d'un bien nouvellement construit)
+	ConformiteAvantCession bool `json:"conformiteAvantCession"`
+
+	EnveloppeReponses bool `json:"enveloppeReponses"` // Envelopper les réponses {code, message, data, txId, timestamp}
+
+	// Rejeter les consultations d'un titre par une organisation tierce sans le consentement de son
+	// propriétaire (sinon : les journaliser, le temps que les intégrateurs recueillent les consentements)
+	BlocageConsentements bool `json:"blocageConsentements"`
+
+	// Rejeter les licences qui ne sont pas encore liées à l'identité de leur titulaire (sinon : accepter
+	// leur usage en le journalisant, le temps que les ordres lient les licences déjà délivrées)
+	BlocageLicencesNonLiees bool `json:"blocageLicencesNonLiees"`
+
+	// N'immatriculer les titres d'archive que par double saisie concordante (SaisirTitreArchive) :
+	// la saisie directe par CreerDossierComplet ou AjouterTitreFoncier(Commune) est alors refusée
+	DoubleSaisieObligatoire bool `json:"doubleSaisieObligatoire"`
+
+	// Champs caviardés dans les listes des consultations, selon le rôle de l'appelant (vide : aucun)
+	Caviardage []RegleCaviardage `json:"caviardage"`
+
+	// MSP des compagnies d'assurance agréées, seules dont les identités sont reconnues au rôle assureur
+	CompagniesAssurance []string `json:"compagniesAssurance"`
+}
+
+// Certificat d'export d'un titre vers un autre canal régional. Son empreinte n'en garantit que l'intégrité :
+// son authenticité tient à la signature de la conservation foncière du bureau du titre, vérifiée par le
+// canal de destination sur le certificat que son registre des autorités détient pour elle.
+type CertificatMutation struct {
+	Id               string        `json:"id"`               // Transaction d'émission sur le canal d'origine
+	IdTitre          string        `json:"idTitre"`          // Titre foncier muté
+	CanalOrigine     string        `json:"canalOrigine"`     // Canal émetteur
+	CanalDestination string        `json:"canalDestination"` // Seul canal autorisé à importer le titre
+	EmisLe           string        `json:"emisLe"`           // Date d'émission (AAAA-MM-JJ)
+	Titre            *TitreFoncier `json:"titre"`            // Enregistrement au moment de la mutation
+	Empreinte        string        `json:"empreinte"`        // SHA-256 du certificat, empreinte et signature vides ; calculable par quiconque, ce n'est pas une signature
+	Signataire       string        `json:"signataire"`       // Autorité de conservation foncière signataire
+	Signature        string        `json:"signature"`        // Signature de l'empreinte par l'autorité (base64)
+}
+
+// Dossier complet d'un titre foncier, tel que consulté par les prêteurs
+type DossierTitre struct {
+	Titre           *TitreFoncier           `json:"titre"`
+	Assurances      []*AssuranceTitre       `json:"assurances"`
+	AssuranceValide bool                    `json:"assuranceValide"`                           // Au moins une police couvre le titre à ce jour
+	Evaluation      *Evaluation             `json:"evaluation,omitempty" metadata:",optional"` // Dernière évaluation enregistrée
+	Permis          []*PermisConstruire     `json:"permis"`                                    // Permis de construire délivrés sur la parcelle
+	Certificats     []*CertificatConformite `json:"certificats"`                               // Certificats de conformité délivrés par la commune
+}
+
+// Configuration appliquée tant qu'aucune n'a été enregistrée sur le canal
+func ConfigurationParDefaut() *Configuration {
+	return &Configuration{
+		SeuilEcartPrix: 30,
+		Communes:       []string{},
+		NomChaincode:   "titrefoncier",
+		Serialisation:  SerialisationJSON,
+
+		OrganisationsEndossement: []string{},
+
+		DelaiConfirmationArchivage:  72,
+		RetentionDocumentsObsoletes: 3650,
+		DelaiRappelEcheances:        30,
+
+		TailleMaxDocuments: 50 << 20,
+		TypesDocuments:     []string{TypePDF, TypeTIFF},
+
+		QuotasOperations: map[string]int{},
+		ReglesTransfert:  []RegleTransfert{},
+
+		ReglesMorcellement: []RegleMorcellement{},
+		QuorumsApprobation: []QuorumApprobation{},
+		PlafondsZones:      []PlafondZone{},
+		DelaisTraitement:   []DelaiTraitement{},
+		TarifsFrais:        []TarifFrais{},
+
+		ComptesComptables: ComptesComptables{
+			Tresorerie: "571",
+			Redevables: "411",
+			Produits:   "706",
+			Procedures: map[string]string{},
+		},
+		EmpriseTerritoire: []float64{-17.6, 12.2, -11.3, 16.8}, // Sénégal
+		Caviardage:        []RegleCaviardage{},
+
+		CompagniesAssurance: []string{},
+	}
+}
+
+// Vérifier la cohérence d'une configuration
+func (c *Configuration) Valider() error {
+	if c.SeuilEcartPrix < 0 {
+		return fmt.Errorf("le seuil d'écart de prix ne peut être négatif")
+	}
+	if c.SeuilSuperficieEndossement < 0 || c.SeuilValeurEndossement < 0 {
+		return fmt.Errorf("les seuils d'endossement renforcé ne peuvent être négatifs")
+	}
+	if (c.SeuilSuperficieEndossement > 0 || c.SeuilValeurEndossement > 0) && len(c.OrganisationsEndossement) == 0 {
+		return fmt.Errorf("l'endossement renforcé exige au moins une organisation")
+	}
+	if c.DelaiConfirmationArchivage <= 0 {
+		return fmt.Errorf("le délai de confirmation des archivages doit être positif")
+	}
+	if c.RetentionDocumentsObsoletes < 0 {
+		return fmt.Errorf("la durée de rétention des documents obsolètes ne peut être négative")
+	}
+	if c.DelaiRappelEcheances < 0 {
+		return fmt.Errorf("le délai de rappel des échéances ne peut être négatif")
+	}
+	if c.TailleMaxDocuments < 0 {
+		return fmt.Errorf("la taille maximale des documents ne peut être négative")
+	}
+	if len(c.TypesDocuments) == 0 {
+		return fmt.Errorf("au moins un type de document doit être admis")
+	}
+	for operation, quota := range c.QuotasOperations {
+		if quota <= 0 {
+			return fmt.Errorf("le quota de l'opération %s doit être positif", operation)
+		}
+	}
+	regles := make(map[string]bool)
+	for i := range c.ReglesTransfert {
+		if err := c.ReglesTransfert[i].Valider(); err != nil {
+			return err
+		}
+		if regles[c.ReglesTransfert[i].Id] {
+			return fmt.Errorf("la règle de transfert %s est définie plusieurs fois", c.ReglesTransfert[i].Id)
+		}
+		regles[c.ReglesTransfert[i].Id] = true
+	}
+	zones := make(map[string]bool)
+	for i := range c.ReglesMorcellement {
+		if err := c.ReglesMorcellement[i].Valider(); err != nil {
+			return err
+		}
+		if zones[c.ReglesMorcellement[i].Zone] {
+			return fmt.Errorf("la règle de morcellement de la zone %q est définie plusieurs fois", c.ReglesMorcellement[i].Zone)
+		}
+		zones[c.ReglesMorcellement[i].Zone] = true
+	}
+	workflows := make(map[string]bool)
+	for i := range c.QuorumsApprobation {
+		if err := c.QuorumsApprobation[i].Valider(); err != nil {
+			return err
+		}
+		if workflows[c.QuorumsApprobation[i].Workflow] {
+			return fmt.Errorf("le quorum de la procédure %s est défini plusieurs fois", c.QuorumsApprobation[i].Workflow)
+		}
+		workflows[c.QuorumsApprobation[i].Workflow] = true
+	}
+	plafonds := make(map[string]bool)
+	for i := range c.PlafondsZones {
+		if err := c.PlafondsZones[i].Valider(); err != nil {
+			return err
+		}
+		cle := c.PlafondsZones[i].Commune + "\x00" + c.PlafondsZones[i].Zone
+		if plafonds[cle] {
+			return fmt.Errorf("le plafond de la zone %q de %s est défini plusieurs fois", c.PlafondsZones[i].Zone, c.PlafondsZones[i].Commune)
+		}
+		plafonds[cle] = true
+	}
+	delais := make(map[string]bool)
+	for i := range c.DelaisTraitement {
+		if err := c.DelaisTraitement[i].Valider(); err != nil {
+			return err
+		}
+		cle := c.DelaisTraitement[i].Nature + "\x00" + c.DelaisTraitement[i].Statut
+		if delais[cle] {
+			return fmt.Errorf("le délai du statut %s de la procédure %s est défini plusieurs fois", c.DelaisTraitement[i].Statut, c.DelaisTraitement[i].Nature)
+		}
+		delais[cle] = true
+	}
+	for i := range c.TarifsFrais {
+		if err := c.TarifsFrais[i].Valider(); err != nil {
+			return err
+		}
+	}
+	for i := range c.Caviardage {
+		if err := c.Caviardage[i].Valider(); err != nil {
+			return err
+		}
+	}
+	if err := c.ComptesComptables.Valider(); err != nil {
+		return err
+	}
+	if e := c.EmpriseTerritoire; len(e) > 0 && (len(e) != 4 || e[0] >= e[2] || e[1] >= e[3]) {
+		return fmt.Errorf("l'emprise du territoire doit être un rectangle [ouest, sud, est, nord]")
+	}
+	switch c.Serialisation {
+	case SerialisationJSON, SerialisationMsgpack:
+		return nil
+	default:
+		return fmt.Errorf("format de sérialisation inconnu: %s", c.Serialisation)
+	}
+}
+
+// Indiquer si une commune relève du canal
+func (c *Configuration) CommuneAutorisee(commune string) bool {
+	if len(c.Communes) == 0 {
+		return true
+	}
+	for _, autorisee := range c.Communes {
+		if autorisee == commune {
+			return true
+		}
+	}
+	return false
+}
+
+// Indiquer si une parcelle relève de l'endossement renforcé (valeur 0 : non évaluée)
+func (c *Configuration) EndossementRenforce(superficie int, valeur int) bool {
+	if c.SeuilSuperficieEndossement > 0 && superficie >= c.SeuilSuperficieEndossement {
+		return true
+	}
+	return c.SeuilValeurEndossement > 0 && valeur >= c.SeuilValeurEndossement
+}
+
+// Contraintes de morcellement d'une parcelle : les plus strictes des règles de ses zonages
+// et de la règle générale (0 : sans contrainte)
+func (c *Configuration) ContraintesMorcellement(zones []string) (superficieMin int, lotsMax int) {
+	for _, regle := range c.ReglesMorcellement {
+		if regle.Zone != "" && !intersecte([]string{regle.Zone}, zones) {
+			continue
+		}
+		if regle.SuperficieMin > superficieMin {
+			superficieMin = regle.SuperficieMin
+		}
+		if regle.LotsMax > 0 && (lotsMax == 0 || regle.LotsMax < lotsMax) {
+			lotsMax = regle.LotsMax
+		}
+	}
+	return superficieMin, lotsMax
+}
+
+// Prix de réserve au m² des lots détachés d'une parcelle portant les zonages indiqués : le plus élevé
+// des règles applicables (0 : aucun)
+func (c *Configuration) PrixReserveMorcellement(zones []string) int {
+	prixM2 := 0
+	for _, regle := range c.ReglesMorcellement {
+		if regle.Zone != "" && !intersecte([]string{regle.Zone}, zones) {
+			continue
+		}
+		if regle.PrixReserveM2 > prixM2 {
+			prixM2 = regle.PrixReserveM2
+		}
+	}
+	return prixM2
+}
+
+// Plafond de superficie d'un zonage d'une commune (0 : aucun)
+func (c *Configuration) PlafondSuperficie(commune string, zone string) int {
+	for _, plafond := range c.PlafondsZones {
+		if plafond.Commune == commune && plafond.Zone == zone {
+			return plafond.Superficie
+		}
+	}
+	return 0
+}
+
+// Indiquer si un type de document est admis à l'enregistrement
+func (c *Configuration) TypeDocumentAdmis(typeMIME string) bool {
+	for _, admis := range c.TypesDocuments {
+		if admis == typeMIME {
+			return true
+		}
+	}
+	return false
+}
+
+// Quorum d'approbation applicable à une procédure (nil : aucune approbation requise)
+func (c *Configuration) QuorumApprobation(workflow string) *QuorumApprobation {
+	for i := range c.QuorumsApprobation {
+		if c.QuorumsApprobation[i].Workflow == workflow {
+			return &c.QuorumsApprobation[i]
+		}
+	}
+	return nil
+}
+
+// Lignes de facture d'une procédure pour une assiette (vide : procédure non facturée)
+func (c *Configuration) LignesFacture(procedure string, assiette int) []LigneFacture {
+	lignes := []LigneFacture{}
+	for _, tarif := range c.TarifsFrais {
+		if tarif.Procedure == procedure {
+			lignes = append(lignes, LigneFacture{Motif: tarif.Motif, Montant: tarif.Montant + assiette*tarif.Taux/1000})
+		}
+	}
+	return lignes
+}
+
+// Délai de traitement, en heures, d'un statut d'une procédure (0 : aucun)
+func (c *Configuration) DelaiTraitement(nature string, statut string) int {
+	for _, delai := range c.DelaisTraitement {
+		if delai.Nature == nature && delai.Statut == statut {
+			return delai.Heures
+		}
+	}
+	return 0
+}
+
+// Vérifier la cohérence d'une autorité émettrice
+func (a *AutoriteEmettrice) Valider() error {
+	if a.Id == "" {
+		return fmt.Errorf("l'identifiant de l'autorité est obligatoire")
+	}
+	switch a.Type {
+	case AutoriteConservation, AutoriteTribunal, AutoriteMairie:
+		return nil
+	default:
+		return fmt.Errorf("type d'autorité inconnu: %s", a.Type)
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import "fmt"
+
+// Règle de restriction des transferts, configurée par canal. Tous les critères renseignés doivent
+// correspondre ; un transfert concerné exige l'approbation de l'organisation désignée, ou est
+// interdit si aucune ne l'est.
+type RegleTransfert struct {
+	Id                string   `json:"id"`
+	Description       string   `json:"description"`
+	Zones             []string `json:"zones,omitempty" metadata:",optional"`             // Zonages du titre (ex: "littorale")
+	Communes          []string `json:"communes,omitempty" metadata:",optional"`          // Communes de situation du titre
+	QualitesAcquereur []string `json:"qualitesAcquereur,omitempty" metadata:",optional"` // Qualités de l'acquéreur (ex: "non-resident")
+	Approbation       string   `json:"approbation,omitempty" metadata:",optional"`       // MSP habilité à approuver (vide : transfert interdit)
+}
+
+// Qualités reconnues à un propriétaire ou acquéreur (résidence, nationalité, ...), et pour une personne
+// morale ses représentants habilités à céder
+type QualitesProprietaire struct {
+	IdProprio        string   `json:"idProprio"`
+	Qualites         []string `json:"qualites"`
+	Representants    []string `json:"representants,omitempty" metadata:",optional"`    // Identités des représentants habilités
+	SeuilCosignature int      `json:"seuilCosignature,omitempty" metadata:",optional"` // Signatures de représentants exigées pour une vente (0 : aucune)
+}
+
+// Vérifier la cohérence de la représentation d'une personne morale : K signatures parmi N représentants distincts
+func (q *QualitesProprietaire) ValiderRepresentation() error {
+	if q.SeuilCosignature < 0 || q.SeuilCosignature > len(q.Representants) {
+		return fmt.Errorf("le seuil de cosignature doit être compris entre 0 et %d représentants", len(q.Representants))
+	}
+	if len(q.Representants) > 0 && q.SeuilCosignature == 0 {
+		return fmt.Errorf("des représentants sont désignés sans seuil de cosignature")
+	}
+	vus := make(map[string]bool)
+	for _, representant := range q.Representants {
+		if representant == "" || vus[representant] {
+			return fmt.Errorf("les représentants doivent être identifiés et distincts")
+		}
+		vus[representant] = true
+	}
+	return nil
+}
+
+// Vérifier la cohérence d'une règle de transfert
+func (r *RegleTransfert) Valider() error {
+	if r.Id == "" {
+		return fmt.Errorf("l'identifiant de la règle de transfert est obligatoire")
+	}
+	if len(r.Zones) == 0 && len(r.Communes) == 0 && len(r.QualitesAcquereur) == 0 {
+		return fmt.Errorf("la règle de transfert %s doit comporter au moins un critère", r.Id)
+	}
+	return nil
+}
+
+// Indiquer si la règle s'applique à la cession d'un titre à un acquéreur de qualités données
+func (r *RegleTransfert) Concerne(titre *TitreFoncier, qualites []string) bool {
+	if len(r.Zones) > 0 && !intersecte(r.Zones, titre.Zones) {
+		return false
+	}
+	if len(r.Communes) > 0 && !intersecte(r.Communes, []string{titre.Commune}) {
+		return false
+	}
+	return len(r.QualitesAcquereur) == 0 || intersecte(r.QualitesAcquereur, qualites)
+}
+
+func intersecte(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package model
+
+// Événement résumant les écritures d'une transaction, pour les consommateurs légers (passerelle SMS)
+const EvenementResumeTransaction = "ResumeTransaction"
+
+// Champ du contenu d'un événement métier ou d'une alerte portant le résumé de sa transaction
+const ChampResumeTransaction = "resumeTransaction"
+
+// Type des modifications portant sur un titre foncier, dont la clé est l'identifiant du titre
+const TypeModificationTitre = "TITRE"
+
+// Enregistrement écrit ou supprimé par une transaction
+type ModificationCle struct {
+	Type        string   `json:"type"`                                       // Préfixe de la clé composite, TypeModificationTitre pour un titre foncier
+	Attributs   []string `json:"attributs"`                                  // Attributs de la clé (identifiant du titre, du transfert, ...)
+	Suppression bool     `json:"suppression,omitempty" metadata:",optional"` // L'enregistrement a été supprimé
+}
+
+// Résumé compact d'une transaction : opération invoquée et enregistrements modifiés, hors index
+type ResumeTransaction struct {
+	TxId          string            `json:"txId"`
+	Operation     string            `json:"operation"`
+	Modifications []ModificationCle `json:"modifications"`
+	Evenement     string            `json:"evenement,omitempty" metadata:",optional"` // Événement émis à la place du résumé (résumés conservés au registre avant ChampResumeTransaction)
+}
@@ -0,0 +1,22 @@
+package model
+
+// Statuts d'une saisie conservatoire
+const (
+	SaisieEnVigueur = "EN_VIGUEUR"
+	SaisieLevee     = "LEVEE"
+)
+
+// Saisie conservatoire posée par le parquet dans le cadre d'une enquête pour corruption, ou gel ordonné
+// par une juridiction : elle bloque les actes de disposition jusqu'à sa mainlevée, indépendamment des autres mesures
+type SaisieConservatoire struct {
+	IdTitre      string `json:"idTitre"`
+	Lot          string `json:"lot,omitempty" metadata:",optional"`       // Lot de copropriété saisi, seul indisponible
+	RefProcedure string `json:"refProcedure"`                             // Référence de la procédure pénale
+	PoseePar     string `json:"poseePar"`                                 // Identité du magistrat ayant posé la saisie
+	PoseeLe      string `json:"poseeLe"`                                  // Horodatage de la saisie (RFC 3339)
+	Statut       string `json:"statut"`                                   // EN_VIGUEUR ou LEVEE
+	Mainlevee    string `json:"mainlevee,omitempty" metadata:",optional"` // Référence de la décision de mainlevée
+	LeveePar     string `json:"leveePar,omitempty" metadata:",optional"`  // Identité du magistrat ayant prononcé la mainlevée
+	LeveeLe      string `json:"leveeLe,omitempty" metadata:",optional"`   // Horodatage de la mainlevée (RFC 3339)
+	Decision     string `json:"decision,omitempty" metadata:",optional"`  // Gel ordonné par décision de justice, levé sur une autre décision
+}
@@ -0,0 +1,35 @@
+package model
+
+// Statuts d'un séquestre de transfert
+const (
+	SequestreOuvert    = "OUVERT"
+	SequestreLibere    = "LIBERE"    // Fonds remis au vendeur à la finalisation du transfert
+	SequestreRembourse = "REMBOURSE" // Fonds restitués à l'acheteur à l'annulation du transfert
+)
+
+// Natures des dépôts sous séquestre
+const (
+	DepotPaiement = "PAIEMENT" // Paiement reçu sur le compte séquestre du notaire
+	DepotJeton    = "JETON"    // Blocage de jetons sur le chaincode de paiement
+)
+
+// Séquestre des sommes versées sur un transfert en attente (acompte, dépôt de garantie)
+type Sequestre struct {
+	IdTransfert  string           `json:"idTransfert"`
+	IdTitre      string           `json:"idTitre"`
+	Notaire      string           `json:"notaire"`                                     // Identité du notaire séquestre
+	Conditions   string           `json:"conditions"`                                  // Conditions de libération convenues entre les parties
+	Depots       []DepotSequestre `json:"depots"`                                      // Dépôts enregistrés, dans l'ordre
+	Total        int              `json:"total"`                                       // Cumul des dépôts (FCFA)
+	Statut       string           `json:"statut"`                                      // OUVERT, LIBERE ou REMBOURSE
+	Beneficiaire string           `json:"beneficiaire,omitempty" metadata:",optional"` // Vendeur ou acheteur ayant reçu les fonds
+	ClotureLe    string           `json:"clotureLe,omitempty" metadata:",optional"`    // Horodatage de la libération ou du remboursement (RFC 3339)
+}
+
+// Dépôt enregistré sous séquestre
+type DepotSequestre struct {
+	Type      string `json:"type"`      // PAIEMENT ou JETON
+	Reference string `json:"reference"` // Référence du paiement ou du blocage de jetons
+	Montant   int    `json:"montant"`   // Montant déposé (FCFA)
+	Le        string `json:"le"`        // Horodatage de l'enregistrement (RFC 3339)
+}
@@ -0,0 +1,43 @@
+package model
+
+// Préfixe des événements d'alerte de surveillance, suivi des MSP des banques concernées
+// (ex: "AlerteBanque:BanqueAMSP,BanqueBMSP")
+const EvenementAlerteBanque = "AlerteBanque"
+
+// Motifs d'une alerte de surveillance
+const (
+	AlerteModification     = "MODIFICATION"      // Inscription, radiation ou mutation sur le titre
+	AlerteTransfertPropose = "TRANSFERT_PROPOSE" // Transfert de propriété proposé
+	AlerteLitige           = "LITIGE"            // Litige ouvert sur le titre
+)
+
+// Surveillance d'un titre donné en garantie, inscrite par la banque prêteuse
+type SurveillanceGarantie struct {
+	IdTitre   string `json:"idTitre"`
+	Banque    string `json:"banque"`    // MSP de la banque
+	Reference string `json:"reference"` // Référence du concours garanti
+	CreeLe    string `json:"creeLe"`    // AAAA-MM-JJ
+}
+
+// Alerte adressée à une banque pour une transaction touchant un titre qu'elle surveille
+type AlerteBanque struct {
+	Banque     string   `json:"banque"`
+	IdTitre    string   `json:"idTitre"`
+	Reference  string   `json:"reference"` // Référence du concours garanti
+	Motifs     []string `json:"motifs"`
+	Operation  string   `json:"operation"` // Transaction invoquée
+	TxId       string   `json:"txId"`
+	Horodatage string   `json:"horodatage"` // RFC 3339
+}
+
+// Page des alertes d'une banque
+type PageAlertesBanque struct {
+	Alertes []*AlerteBanque `json:"alertes"`
+	Signet  string          `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin de parcours)
+}
+
+// Contenu de l'événement d'alerte de surveillance d'une transaction
+type AlertesBanques struct {
+	TxId    string         `json:"txId"`
+	Alertes []AlerteBanque `json:"alertes"`
+}
@@ -0,0 +1,27 @@
+package model
+
+// Écriture d'un titre inscrite au journal de sa commune. Le journal suit l'ordre des blocs : un
+// terminal qui a reçu les écritures d'une transaction n'a rien manqué des précédentes.
+type ModificationCommune struct {
+	Commune      string `json:"commune"`
+	IdTitre      string `json:"idTitre"`
+	TxId         string `json:"txId"`                                       // Transaction de l'écriture (vide : titre jamais journalisé)
+	Operation    string `json:"operation"`                                  // Transaction à l'origine de l'écriture
+	Organisation string `json:"organisation"`                               // MSP de son auteur
+	Date         string `json:"date"`                                       // Horodatage de la transaction (RFC 3339)
+	Suppression  bool   `json:"suppression,omitempty" metadata:",optional"` // Titre archivé ou sorti de la commune
+}
+
+// Page du journal d'une commune, à partir d'un signet
+type FluxModifications struct {
+	Commune       string                `json:"commune"`
+	Modifications []ModificationCommune `json:"modifications"`
+	Signet        string                `json:"signet"` // Transaction de la dernière modification reçue, à repasser pour la suite
+	Suite         bool                  `json:"suite"`  // D'autres modifications suivent déjà cette page
+}
+
+// Issue d'une opération saisie hors ligne appliquée au registre
+type OperationAppliquee struct {
+	Version string `json:"version"`                                // Version du titre après l'opération, à attendre pour la suivante
+	Reponse string `json:"reponse,omitempty" metadata:",optional"` // Identifiant rendu par l'opération (constat, signalement)
+}
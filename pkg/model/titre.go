@@ -0,0 +1,152 @@
+package model
+
+import "fmt"
+
+// Types de charges inscrites sur un titre foncier
+const (
+	ChargePromesseVente = "PROMESSE_VENTE"
+	ChargeHypotheque    = "HYPOTHEQUE" // Classée par rang pour la distribution du prix de réalisation
+)
+
+// Type des enregistrements de titres fonciers, seuls retenus par les requêtes riches
+const DocTypeTitre = "titre"
+
+// Statut d'un document remplacé, conservé jusqu'à la fin de sa durée de rétention
+const DocumentObsolete = "OBSOLETE"
+
+// Définition de la structure des Titres Fonciers
+type TitreFoncier struct {
+	Id                  string              `json:"id"`                                                 // Identifiant unique du titre foncier
+	DocType             string              `json:"docType,omitempty" metadata:",optional"`             // Type d'enregistrement (DocTypeTitre), posé à chaque écriture
+	Proprio             string              `json:"proprio"`                                            // Nom du propriétaire
+	NumTF               string              `json:"numTF"`                                              // Numéro officiel du titre foncier
+	Superficie          int                 `json:"superficie"`                                         // Superficie du terrain en m²
+	Commune             string              `json:"commune"`                                            // Commune de situation de la parcelle
+	Document            string              `json:"document"`                                           // Chemin du fichier NFS
+	DocHash             string              `json:"doc_hash"`                                           // Hash SHA-1 du document
+	Inalienable         bool                `json:"inalienable"`                                        // Parcelle du domaine public (routes, littoral, réserves)
+	Charges             []Charge            `json:"charges,omitempty" metadata:",optional"`             // Charges inscrites (promesses de vente, ...)
+	Documents           []DocumentTitre     `json:"documents,omitempty" metadata:",optional"`           // Documents rattachés après la création
+	MuteVers            string              `json:"muteVers,omitempty" metadata:",optional"`            // Canal régional désormais chargé du titre
+	CreeLe              string              `json:"creeLe,omitempty" metadata:",optional"`              // Date d'immatriculation sur le registre (AAAA-MM-JJ)
+	BureauFoncier       string              `json:"bureau,omitempty" metadata:",optional"`              // Bureau foncier gestionnaire du titre
+	Saisies             []string            `json:"saisies,omitempty" metadata:",optional"`             // Procédures de saisie conservatoire en vigueur
+	Zones               []string            `json:"zones,omitempty" metadata:",optional"`               // Zonages applicables à la parcelle (ex: "littorale")
+	Origine             string              `json:"origine,omitempty" metadata:",optional"`             // Acte d'occupation converti ou titre morcelé (ex: "DELIBERATION:D-2024-12")
+	MorceleEn           []string            `json:"morceleEn,omitempty" metadata:",optional"`           // Titres des lots issus du morcellement, qui clôt le titre
+	Realisation         string              `json:"realisation,omitempty" metadata:",optional"`         // Hypothèque en cours de réalisation forcée
+	Geometrie           string              `json:"geometrie,omitempty" metadata:",optional"`           // Contour de la parcelle (GeoJSON, WGS84)
+	SystemeCoordonnees  string              `json:"systemeCoordonnees,omitempty" metadata:",optional"`  // Système de référence du contour déposé (ex: "EPSG:32628")
+	GeometrieDepuis     string              `json:"geometrieDepuis,omitempty" metadata:",optional"`     // Entrée en vigueur légale du contour courant (RFC 3339 ; vide : date de son enregistrement)
+	GeometreContour     string              `json:"geometreContour,omitempty" metadata:",optional"`     // Identité du géomètre ayant attesté le contour courant
+	Copropriete         bool                `json:"copropriete,omitempty" metadata:",optional"`         // Titre placé sous le régime de la copropriété : seuls ses lots sont cessibles
+	Usufruit            *Usufruit           `json:"usufruit,omitempty" metadata:",optional"`            // Usufruit démembré de la propriété : Proprio n'en est que nu-propriétaire
+	DernierActiviteLe   string              `json:"dernierActiviteLe,omitempty" metadata:",optional"`   // Date de la dernière transaction ayant modifié le titre (AAAA-MM-JJ)
+	DocumentsSepares    bool                `json:"documentsSepares,omitempty" metadata:",optional"`    // Documents enregistrés chacun sous sa propre clé, hors de l'état du titre
+	DernierSeqDocument  int                 `json:"dernierSeqDocument,omitempty" metadata:",optional"`  // Dernière séquence de document connue à l'écriture du titre (le compteur SEQ_DOC_TITRE fait foi)
+	Qualite             *QualiteTitre       `json:"qualite,omitempty" metadata:",optional"`             // Complétude de l'enregistrement, recalculée à chaque écriture
+	AnciennesReferences []AncienneReference `json:"anciennesReferences,omitempty" metadata:",optional"` // Livre et folio du titre dans les anciens livres fonciers
+	ReferencesRetirees  []ReferenceRetiree  `json:"referencesRetirees,omitempty" metadata:",optional"`  // Anciennes références retirées après une erreur de saisie
+}
+
+// Résultat public de la vérification d'un document, sans donnée personnelle
+type VerificationDocument struct {
+	Hash       string   `json:"hash"`
+	Enregistre bool     `json:"enregistre"` // Le document est rattaché à au moins un titre
+	Titres     []string `json:"titres"`     // Identifiants des titres concernés
+}
+
+// Page de résultats d'une recherche de titres fonciers
+type ResultatRecherche struct {
+	Titres []*TitreFoncier `json:"titres"`
+	Nombre int             `json:"nombre"` // Nombre de titres de la page
+	Signet string          `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin de résultats)
+}
+
+// Charge (droit d'un tiers) inscrite sur un titre foncier
+type Charge struct {
+	Type         string `json:"type"`                                      // Nature de la charge
+	Beneficiaire string `json:"beneficiaire"`                              // Titulaire du droit
+	Reference    string `json:"reference"`                                 // Acte à l'origine de la charge
+	DateLimite   string `json:"dateLimite,omitempty" metadata:",optional"` // Échéance éventuelle (AAAA-MM-JJ)
+	Montant      int    `json:"montant,omitempty" metadata:",optional"`    // Créance garantie (FCFA)
+	Rang         int    `json:"rang,omitempty" metadata:",optional"`       // Rang de l'hypothèque, 1 étant payé en premier
+}
+
+// Document (acte, jugement, plan) rattaché à un titre foncier
+type DocumentTitre struct {
+	Chemin      string `json:"chemin"`                                     // Chemin du fichier NFS
+	Hash        string `json:"hash"`                                       // Hash SHA-1 du document
+	Issuer      string `json:"issuer"`                                     // Autorité émettrice du document
+	AjouteLe    string `json:"ajouteLe"`                                   // Date d'ajout (AAAA-MM-JJ)
+	Statut      string `json:"statut,omitempty" metadata:",optional"`      // OBSOLETE une fois remplacé (vide : en vigueur)
+	RemplacePar string `json:"remplacePar,omitempty" metadata:",optional"` // Chemin du document de remplacement
+	ObsoleteLe  string `json:"obsoleteLe,omitempty" metadata:",optional"`  // Date du remplacement (AAAA-MM-JJ)
+	Taille      int    `json:"taille,omitempty" metadata:",optional"`      // Taille du fichier (octets)
+	Type        string `json:"type,omitempty" metadata:",optional"`        // Type MIME détecté sur le contenu
+	Seq         int    `json:"seq,omitempty" metadata:",optional"`         // Numéro de séquence de la clé du document séparé (idTitre~seq)
+	Signature   string `json:"signature,omitempty" metadata:",optional"`   // Signature du document par l'autorité émettrice (base64)
+}
+
+// Créer un titre foncier à partir de son document d'origine
+func NouveauTitreFoncier(id string, proprio string, numTF string, superficie int, commune string, document string, docHash string) *TitreFoncier {
+	return &TitreFoncier{
+		Id:         id,
+		Proprio:    proprio,
+		NumTF:      numTF,
+		Superficie: superficie,
+		Commune:    commune,
+		Document:   document,
+		DocHash:    docHash,
+	}
+}
+
+// Vérifier la cohérence d'un titre foncier
+func (t *TitreFoncier) Valider() error {
+	if t.Id == "" {
+		return fmt.Errorf("l'identifiant du titre foncier est obligatoire")
+	}
+	if t.Proprio == "" {
+		return fmt.Errorf("le propriétaire du titre foncier %s est obligatoire", t.Id)
+	}
+	if t.Superficie <= 0 {
+		return fmt.Errorf("la superficie du titre foncier %s doit être positive", t.Id)
+	}
+	return nil
+}
+
+// Indiquer si une charge est échue à la date donnée (AAAA-MM-JJ)
+func (c *Charge) EstEchue(date string) bool {
+	return c.DateLimite != "" && c.DateLimite < date
+}
+
+// Événement émis lorsqu'un document rattaché à un titre est signalé corrompu
+const EvenementDocumentCorrompu = "DocumentCorrompu"
+
+// Signalement d'un document dont le contenu ne correspond plus au hash enregistré
+type SignalementDocument struct {
+	Id           string `json:"id"`           // Transaction de signalement
+	IdTitre      string `json:"idTitre"`      // Titre auquel le document est rattaché
+	Chemin       string `json:"chemin"`       // Chemin du fichier NFS
+	HashRegistre string `json:"hashRegistre"` // Hash SHA-1 enregistré sur le registre
+	HashConstate string `json:"hashConstate"` // Hash SHA-1 recalculé sur le fichier
+	SignalePar   string `json:"signalePar"`   // Identité de l'auditeur
+	Date         string `json:"date"`         // Date du signalement (AAAA-MM-JJ)
+}
+
+// Empreinte de l'historique d'une clé du registre
+type MesureHistorique struct {
+	Cle              string `json:"cle"`
+	Versions         int    `json:"versions"`         // Écritures et suppressions conservées
+	TailleCourante   int    `json:"tailleCourante"`   // Octets de la valeur en vigueur
+	TailleMax        int    `json:"tailleMax"`        // Octets de la plus grande version
+	TailleCumulee    int    `json:"tailleCumulee"`    // Octets de toutes les versions
+	Documents        int    `json:"documents"`        // Documents rattachés au titre
+	DocumentsSepares bool   `json:"documentsSepares"` // Les documents sont déjà hors de l'état du titre
+}
+
+// Page de mesures d'historique des titres
+type PageMesuresHistorique struct {
+	Mesures []*MesureHistorique `json:"mesures"`
+	Signet  string              `json:"signet"` // À repasser pour obtenir la page suivante (vide en fin de parcours)
+}
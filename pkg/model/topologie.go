@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Nombre maximal de sommets d'un anneau de polygone
+const SommetsMaxAnneau = 2000
+
+// Vérifier la topologie des polygones d'une géométrie (anneau extérieur puis trous) : anneaux fermés,
+// d'au moins trois sommets distincts et d'au plus SommetsMaxAnneau, sans arête nulle, aller-retour ni
+// recoupement, d'aire non nulle ; trous compris dans leur extérieur, polygones d'un multipolygone sans
+// surface commune. L'orientation des anneaux n'est pas contrôlée (OrienterAnneaux la rétablit). Les
+// erreurs désignent polygone, anneau et sommet par leur rang, à partir de 1.
+func ValiderTopologie(polygones [][][]Point) error {
+	if len(polygones) == 0 {
+		return fmt.Errorf("la géométrie ne compte aucun polygone")
+	}
+	for p, anneaux := range polygones {
+		if len(anneaux) == 0 {
+			return fmt.Errorf("polygone %d: aucun anneau", p+1)
+		}
+		for a, anneau := range anneaux {
+			if err := validerAnneau(anneau); err != nil {
+				return fmt.Errorf("polygone %d, anneau %d: %v", p+1, a+1, err)
+			}
+		}
+		for a := 0; a < len(anneaux); a++ {
+			for b := a + 1; b < len(anneaux); b++ {
+				if i, j, touche := anneauxSeTouchent(anneaux[a], anneaux[b]); touche {
+					return fmt.Errorf("polygone %d: l'arête %d de l'anneau %d touche l'arête %d de l'anneau %d", p+1, i+1, a+1, j+1, b+1)
+				}
+			}
+			if a > 0 && !contient(anneaux[0], anneaux[a][0]) {
+				return fmt.Errorf("polygone %d: le trou %d sort de l'anneau extérieur", p+1, a)
+			}
+		}
+	}
+	for p := 0; p < len(polygones); p++ {
+		for q := p + 1; q < len(polygones); q++ {
+			if contoursChevauchent(polygones[p][0], polygones[q][0]) {
+				return fmt.Errorf("les polygones %d et %d du multipolygone se chevauchent", p+1, q+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Réorienter en place les anneaux des polygones selon RFC 7946 : l'anneau extérieur dans le sens
+// antihoraire, les trous dans le sens horaire. Le premier sommet, qui ferme l'anneau, est conservé.
+func OrienterAnneaux(polygones [][][]Point) {
+	for _, anneaux := range polygones {
+		for a, anneau := range anneaux {
+			if aire := aireSignee(anneau); (a == 0 && aire < 0) || (a > 0 && aire > 0) {
+				slices.Reverse(anneau)
+			}
+		}
+	}
+}
+
+// Vérifier un anneau seul
+func validerAnneau(anneau []Point) error {
+	if len(anneau) < 4 {
+		return fmt.Errorf("%d positions, au moins 4 attendues (trois sommets et la fermeture)", len(anneau))
+	}
+	if len(anneau)-1 > SommetsMaxAnneau {
+		return fmt.Errorf("%d sommets, au plus %d admis", len(anneau)-1, SommetsMaxAnneau)
+	}
+	if anneau[0] != anneau[len(anneau)-1] {
+		return fmt.Errorf("anneau non fermé: le dernier sommet %v diffère du premier %v", anneau[len(anneau)-1], anneau[0])
+	}
+
+	aretes := len(anneau) - 1
+	for i := 0; i < aretes; i++ {
+		a, b, c := anneau[i], anneau[i+1], anneau[(i+2)%aretes]
+		if a == b {
+			return fmt.Errorf("les sommets %d et %d sont confondus en %v", i+1, i+2, a)
+		}
+		// Arête revenant sur la précédente : l'anneau fait un aller-retour au sommet b
+		if orientation(a, b, c) == 0 && (b[0]-a[0])*(c[0]-b[0])+(b[1]-a[1])*(c[1]-b[1]) < 0 {
+			return fmt.Errorf("aller-retour au sommet %d %v", i+2, b)
+		}
+	}
+	for i := 0; i < aretes; i++ {
+		for j := i + 2; j < aretes; j++ {
+			if i == 0 && j == aretes-1 {
+				continue // Arêtes adjacentes par la fermeture
+			}
+			if segmentsSeTouchent(anneau[i], anneau[i+1], anneau[j], anneau[j+1]) {
+				return fmt.Errorf("l'anneau se recoupe: l'arête %d (sommets %d-%d) touche l'arête %d (sommets %d-%d)", i+1, i+1, i+2, j+1, j+1, j+2)
+			}
+		}
+	}
+
+	if aireSignee(anneau) == 0 {
+		return fmt.Errorf("anneau d'aire nulle")
+	}
+	return nil
+}
+
+// Première paire d'arêtes de deux anneaux qui se touchent
+func anneauxSeTouchent(a []Point, b []Point) (int, int, bool) {
+	for i := 0; i+1 < len(a); i++ {
+		for j := 0; j+1 < len(b); j++ {
+			if segmentsSeTouchent(a[i], a[i+1], b[j], b[j+1]) {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Contact de deux segments, extrémités et recouvrements colinéaires compris
+func segmentsSeTouchent(p1, p2, q1, q2 Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && surSegment(q1, q2, p1)) || (d2 == 0 && surSegment(q1, q2, p2)) ||
+		(d3 == 0 && surSegment(p1, p2, q1)) || (d4 == 0 && surSegment(p1, p2, q2))
+}
+
+// Appartenance d'un point colinéaire au segment [a, b]
+func surSegment(a, b, p Point) bool {
+	return min(a[0], b[0]) <= p[0] && p[0] <= max(a[0], b[0]) && min(a[1], b[1]) <= p[1] && p[1] <= max(a[1], b[1])
+}
+
+// Double de l'aire signée d'un anneau fermé : positive dans le sens antihoraire
+func aireSignee(anneau []Point) float64 {
+	aire := 0.0
+	for i := 0; i+1 < len(anneau); i++ {
+		aire += anneau[i][0]*anneau[i+1][1] - anneau[i+1][0]*anneau[i][1]
+	}
+	return aire
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func carreTopologie(ouest, sud, cote float64) []Point {
+	return []Point{{ouest, sud}, {ouest + cote, sud}, {ouest + cote, sud + cote}, {ouest, sud + cote}, {ouest, sud}}
+}
+
+func TestValiderTopologie(t *testing.T) {
+	exterieur := carreTopologie(0, 0, 10)
+	valides := map[string][][][]Point{
+		"carré":                  {{exterieur}},
+		"triangle":               {{{{0, 0}, {4, 0}, {0, 3}, {0, 0}}}},
+		"sommet aligné":          {{{{0, 0}, {5, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+		"trou":                   {{exterieur, carreTopologie(2, 2, 3)}},
+		"polygones contigus":     {{exterieur}, {carreTopologie(10, 0, 10)}},
+		"anneau sens horaire":    {{{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}}},
+		"polygones disjoints":    {{exterieur}, {carreTopologie(20, 20, 1)}},
+		"deux trous disjoints":   {{exterieur, carreTopologie(1, 1, 2), carreTopologie(5, 5, 2)}},
+		"anneau de 2000 sommets": {{anneauRegulier(SommetsMaxAnneau)}},
+	}
+	for nom, polygones := range valides {
+		if err := ValiderTopologie(polygones); err != nil {
+			t.Errorf("%s: %v", nom, err)
+		}
+	}
+
+	invalides := []struct {
+		nom       string
+		polygones [][][]Point
+		erreur    string
+	}{
+		{"aucun polygone", nil, "aucun polygone"},
+		{"aucun anneau", [][][]Point{{}}, "polygone 1: aucun anneau"},
+		{"trois positions", [][][]Point{{{{0, 0}, {1, 0}, {0, 0}}}}, "3 positions"},
+		{"trop de sommets", [][][]Point{{anneauRegulier(SommetsMaxAnneau + 1)}}, "2001 sommets"},
+		{"anneau ouvert", [][][]Point{{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}}, "non fermé"},
+		{"sommets confondus", [][][]Point{{{{0, 0}, {1, 0}, {1, 0}, {1, 1}, {0, 0}}}}, "sommets 2 et 3 sont confondus"},
+		{"aller-retour", [][][]Point{{{{0, 0}, {10, 0}, {5, 0}, {5, 5}, {0, 0}}}}, "aller-retour au sommet 2"},
+		{"papillon", [][][]Point{{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}, "polygone 1, anneau 1: l'anneau se recoupe: l'arête 1"},
+		{"sommet sur une arête", [][][]Point{{{{0, 0}, {10, 0}, {10, 10}, {5, 0}, {0, 10}, {0, 0}}}}, "l'anneau se recoupe"},
+		{"trou touchant l'extérieur", [][][]Point{{exterieur, carreTopologie(0, 2, 3)}}, "touche l'arête"},
+		{"trou hors de l'extérieur", [][][]Point{{exterieur, carreTopologie(20, 20, 3)}}, "le trou 1 sort de l'anneau extérieur"},
+		{"trous qui se recoupent", [][][]Point{{exterieur, carreTopologie(1, 1, 3), carreTopologie(2, 2, 3)}}, "de l'anneau 2 touche l'arête"},
+		{"deuxième anneau invalide", [][][]Point{{exterieur, {{1, 1}, {2, 1}, {1, 1}}}}, "polygone 1, anneau 2"},
+		{"polygones superposés", [][][]Point{{exterieur}, {carreTopologie(5, 5, 10)}}, "les polygones 1 et 2 du multipolygone se chevauchent"},
+		{"polygone inclus", [][][]Point{{exterieur}, {carreTopologie(2, 2, 2)}}, "se chevauchent"},
+	}
+	for _, c := range invalides {
+		err := ValiderTopologie(c.polygones)
+		if err == nil || !strings.Contains(err.Error(), c.erreur) {
+			t.Errorf("%s: erreur %v, attendue contenant %q", c.nom, err, c.erreur)
+		}
+	}
+}
+
+func TestOrienterAnneaux(t *testing.T) {
+	horaire := []Point{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	trouAntihoraire := carreTopologie(2, 2, 3)
+	polygones := [][][]Point{{slices.Clone(horaire), slices.Clone(trouAntihoraire)}, {carreTopologie(20, 0, 1)}}
+
+	OrienterAnneaux(polygones)
+	if aireSignee(polygones[0][0]) <= 0 || aireSignee(polygones[1][0]) <= 0 {
+		t.Errorf("anneaux extérieurs non antihoraires: %v, %v", polygones[0][0], polygones[1][0])
+	}
+	if aireSignee(polygones[0][1]) >= 0 {
+		t.Errorf("trou non horaire: %v", polygones[0][1])
+	}
+	if polygones[0][0][0] != horaire[0] || polygones[0][1][0] != trouAntihoraire[0] {
+		t.Error("le sommet de fermeture d'un anneau réorienté a changé")
+	}
+	if !slices.Equal(polygones[1][0], carreTopologie(20, 0, 1)) {
+		t.Errorf("anneau déjà orienté modifié: %v", polygones[1][0])
+	}
+	if err := ValiderTopologie(polygones); err != nil {
+		t.Errorf("polygones réorientés: %v", err)
+	}
+}
+
+// Anneau fermé de n sommets régulièrement répartis sur un cercle
+func anneauRegulier(n int) []Point {
+	anneau := make([]Point, n+1)
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		anneau[i] = Point{100 * math.Cos(angle), 100 * math.Sin(angle)}
+	}
+	anneau[n] = anneau[0]
+	return anneau
+}
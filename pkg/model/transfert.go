@@ -0,0 +1,125 @@
+package model
+
+import "fmt"
+
+// Modes de paiement d'un transfert de propriété
+const (
+	ModeComptant    = "COMPTANT"
+	ModeTemperament = "TEMPERAMENT" // Vente à tempérament avec réserve de propriété
+)
+
+// Statuts d'un transfert de propriété
+const (
+	TransfertEnCosignature = "EN_COSIGNATURE" // Vente d'une personne morale en attente des signatures de ses représentants
+	TransfertEnAttente     = "EN_ATTENTE"
+	TransfertFinalise      = "FINALISE"
+	TransfertAnnule        = "ANNULE"
+)
+
+// Signalement d'un prix déclaré trop éloigné de la dernière évaluation
+const SignalementEcartEvaluation = "ECART_EVALUATION"
+
+// Événement émis lorsqu'un transfert est signalé à l'administration fiscale
+const EvenementTransfertSignale = "TransfertSignale"
+
+// Statuts d'une promesse de vente
+const (
+	PromesseActive    = "ACTIVE"
+	PromesseConvertie = "CONVERTIE"
+)
+
+// Définition d'un transfert de propriété
+type Transfert struct {
+	Id          string `json:"id"`                                         // Identifiant unique du transfert
+	IdTitre     string `json:"idTitre"`                                    // Titre foncier cédé
+	Vendeur     string `json:"vendeur"`                                    // Propriétaire au moment de la proposition
+	Acheteur    string `json:"acheteur"`                                   // Futur propriétaire
+	Prix        int    `json:"prix"`                                       // Prix déclaré en FCFA
+	Mode        string `json:"mode"`                                       // COMPTANT ou TEMPERAMENT
+	MontantPaye int    `json:"montantPaye"`                                // Cumul des échéances payées (vente à tempérament)
+	Statut      string `json:"statut"`                                     // EN_COSIGNATURE, EN_ATTENTE, FINALISE ou ANNULE
+	Signalement string `json:"signalement,omitempty" metadata:",optional"` // Motif de contrôle fiscal éventuel
+	EcartPrix   int    `json:"ecartPrix,omitempty" metadata:",optional"`   // Écart (en %) avec la dernière évaluation
+
+	Approbations  []string `json:"approbations,omitempty" metadata:",optional"`  // Règles de transfert levées par l'organisation habilitée
+	Consentements []string `json:"consentements,omitempty" metadata:",optional"` // Hypothèques dont le créancier a consenti au transfert
+	Emprises      []string `json:"emprises,omitempty" metadata:",optional"`      // Réserves d'emprise publique grevant la parcelle, signalées à l'acheteur
+
+	SeuilCosignature int      `json:"seuilCosignature,omitempty" metadata:",optional"` // Signatures exigées des représentants du vendeur à la proposition
+	Cosignatures     []string `json:"cosignatures,omitempty" metadata:",optional"`     // Représentants du vendeur ayant signé
+
+	Notaire string `json:"notaire,omitempty" metadata:",optional"` // Notaire instrumentaire : auteur de la promesse convertie ou séquestre
+}
+
+// Échéance payée dans le cadre d'une vente à tempérament
+type Echeance struct {
+	IdTransfert string `json:"idTransfert"` // Vente concernée
+	Montant     int    `json:"montant"`     // Montant payé en FCFA
+	Reference   string `json:"reference"`   // Référence du paiement
+	Date        string `json:"date"`        // Date d'enregistrement (AAAA-MM-JJ)
+	TxId        string `json:"txId"`        // Transaction d'enregistrement
+}
+
+// Définition d'une promesse de vente (avant-contrat notarié)
+type Promesse struct {
+	Id           string `json:"id"`                                         // Identifiant (transaction d'enregistrement)
+	IdTitre      string `json:"idTitre"`                                    // Titre foncier promis
+	Beneficiaire string `json:"beneficiaire"`                               // Acquéreur pressenti, seul à pouvoir acheter
+	DateLimite   string `json:"dateLimite"`                                 // Fin de l'exclusivité (AAAA-MM-JJ)
+	Notaire      string `json:"notaire"`                                    // Identité du notaire instrumentaire
+	Statut       string `json:"statut"`                                     // ACTIVE ou CONVERTIE
+	IdTransfert  string `json:"idTransfert,omitempty" metadata:",optional"` // Transfert issu de la conversion
+}
+
+// Créer un transfert en attente
+func NouveauTransfert(id string, idTitre string, vendeur string, acheteur string, prix int, mode string) *Transfert {
+	return &Transfert{
+		Id:       id,
+		IdTitre:  idTitre,
+		Vendeur:  vendeur,
+		Acheteur: acheteur,
+		Prix:     prix,
+		Mode:     mode,
+		Statut:   TransfertEnAttente,
+	}
+}
+
+// Vérifier la cohérence d'un transfert
+func (t *Transfert) Valider() error {
+	if t.Id == "" || t.IdTitre == "" {
+		return fmt.Errorf("le transfert et le titre cédé doivent être identifiés")
+	}
+	if t.Acheteur == "" {
+		return fmt.Errorf("l'acheteur du transfert %s est obligatoire", t.Id)
+	}
+	if t.Prix <= 0 {
+		return fmt.Errorf("le prix doit être positif")
+	}
+	switch t.Mode {
+	case ModeComptant, ModeTemperament:
+	default:
+		return fmt.Errorf("mode de paiement inconnu: %s", t.Mode)
+	}
+	return nil
+}
+
+// Créer une promesse de vente active
+func NouvellePromesse(id string, idTitre string, beneficiaire string, dateLimite string, notaire string) *Promesse {
+	return &Promesse{
+		Id:           id,
+		IdTitre:      idTitre,
+		Beneficiaire: beneficiaire,
+		DateLimite:   dateLimite,
+		Notaire:      notaire,
+		Statut:       PromesseActive,
+	}
+}
+
+// Vérifier la cohérence d'une promesse de vente
+func (p *Promesse) Valider() error {
+	if p.Beneficiaire == "" {
+		return fmt.Errorf("le bénéficiaire de la promesse est obligatoire")
+	}
+	_, err := AnalyserDate(p.DateLimite)
+	return err
+}
@@ -0,0 +1,46 @@
+package model
+
+// Statuts d'un permis de construire, dans l'ordre de leur succession
+const (
+	PermisDelivre    = "DELIVRE"
+	PermisEnTravaux  = "EN_TRAVAUX"
+	PermisConformite = "CONFORMITE" // Achèvement des travaux constaté conforme au permis
+)
+
+// Permis de construire délivré par la direction de l'urbanisme sur un titre foncier ; il renseigne
+// acquéreurs et banques sur la légalité des constructions de la parcelle
+type PermisConstruire struct {
+	Id           string   `json:"id"`                                        // Numéro du permis
+	IdTitre      string   `json:"idTitre"`                                   // Titre foncier de la parcelle
+	Beneficiaire string   `json:"beneficiaire"`                              // Propriétaire lors de la délivrance
+	Objet        string   `json:"objet"`                                     // Construction autorisée (ex: "R+2 à usage d'habitation")
+	Statut       string   `json:"statut"`                                    // DELIVRE, EN_TRAVAUX ou CONFORMITE
+	DelivrePar   string   `json:"delivrePar"`                                // Identité de l'agent de l'urbanisme
+	DelivreLe    string   `json:"delivreLe"`                                 // Date de délivrance (AAAA-MM-JJ)
+	TravauxLe    string   `json:"travauxLe,omitempty" metadata:",optional"`  // Date d'ouverture du chantier (AAAA-MM-JJ)
+	ConformeLe   string   `json:"conformeLe,omitempty" metadata:",optional"` // Date du constat de conformité (AAAA-MM-JJ)
+	Emprises     []string `json:"emprises,omitempty" metadata:",optional"`   // Réserves d'emprise publique grevant la parcelle à la délivrance
+}
+
+// Indiquer si le permis peut passer au statut indiqué : chaque statut succède au précédent
+func (p *PermisConstruire) PeutPasserA(statut string) bool {
+	switch statut {
+	case PermisEnTravaux:
+		return p.Statut == PermisDelivre
+	case PermisConformite:
+		return p.Statut == PermisEnTravaux
+	default:
+		return false
+	}
+}
+
+// Certificat de conformité délivré par la commune après inspection des constructions d'une parcelle
+type CertificatConformite struct {
+	Id         string `json:"id"`                                      // Numéro du certificat
+	IdTitre    string `json:"idTitre"`                                 // Titre foncier de la parcelle
+	IdPermis   string `json:"idPermis,omitempty" metadata:",optional"` // Permis de construire dont les travaux sont certifiés
+	Commune    string `json:"commune"`                                 // Commune de délivrance
+	Inspection string `json:"inspection"`                              // Référence du procès-verbal d'inspection
+	DelivrePar string `json:"delivrePar"`                              // Identité de l'agent communal
+	DelivreLe  string `json:"delivreLe"`                               // Date de délivrance (AAAA-MM-JJ)
+}
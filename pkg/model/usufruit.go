@@ -0,0 +1,12 @@
+package model
+
+// Repère du consentement de l'usufruitier dans les consentements d'un transfert
+const ConsentementUsufruitier = "USUFRUIT"
+
+// Démembrement de la propriété d'un titre : le propriétaire inscrit ne conserve que la nue-propriété
+type Usufruit struct {
+	Usufruitier string `json:"usufruitier"`                             // Titulaire de l'usufruit
+	Acte        string `json:"acte"`                                    // Acte constitutif (donation, succession, vente en viager, ...)
+	ConstitueLe string `json:"constitueLe"`                             // Date d'inscription (AAAA-MM-JJ)
+	Echeance    string `json:"echeance,omitempty" metadata:",optional"` // Terme de l'usufruit (AAAA-MM-JJ), vide s'il est viager
+}
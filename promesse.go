@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des promesses de vente
+const PrefixePromesse = "PROMESSE"
+
+// Enregistrer une promesse de vente bloquant les transferts concurrents jusqu'à la date limite
+func (s *SmartContract) EnregistrerPromesse(ctx contractapi.TransactionContextInterface, idTitre string, beneficiaire string, dateLimite string) (string, error) {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return "", err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return "", err
+	}
+	if err := verifierAlienable(titre); err != nil {
+		return "", err
+	}
+
+	notaire, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	idPromesse := ctx.GetStub().GetTxID()
+	promesse := model.NouvellePromesse(idPromesse, idTitre, beneficiaire, dateLimite, notaire)
+	if err := promesse.Valider(); err != nil {
+		return "", err
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return "", err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+	if dateLimite < aujourdhui {
+		return "", fmt.Errorf("la date limite %s est déjà passée", dateLimite)
+	}
+	if err := verifierLibreDePromesse(titre, idPromesse, aujourdhui); err != nil {
+		return "", err
+	}
+
+	if err := sauvegarderPromesse(ctx, promesse); err != nil {
+		return "", err
+	}
+
+	ajouterCharge(titre, Charge{Type: ChargePromesseVente, Beneficiaire: beneficiaire, Reference: idPromesse, DateLimite: dateLimite})
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return "", err
+	}
+
+	return idPromesse, nil
+}
+
+// Lire une promesse de vente
+func (s *SmartContract) LirePromesse(ctx contractapi.TransactionContextInterface, idPromesse string) (*Promesse, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixePromesse, []string{idPromesse})
+	if err != nil {
+		return nil, err
+	}
+
+	var promesse Promesse
+	existe, err := lireEtat(ctx, cle, &promesse)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("promesse %s non trouvée", idPromesse)
+	}
+
+	return &promesse, nil
+}
+
+// Convertir une promesse en transfert effectif ; nil si la référence ne désignait pas une promesse
+func (s *SmartContract) convertirPromesse(ctx contractapi.TransactionContextInterface, idPromesse string, idTransfert string) (*Promesse, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixePromesse, []string{idPromesse})
+	if err != nil {
+		return nil, err
+	}
+	var promesse Promesse
+	existe, err := lireEtat(ctx, cle, &promesse)
+	if err != nil || !existe {
+		// Sans promesse, la réservation provient d'un transfert en cours et non d'un acte notarié
+		return nil, err
+	}
+
+	promesse.Statut = PromesseConvertie
+	promesse.IdTransfert = idTransfert
+
+	return &promesse, sauvegarderPromesse(ctx, &promesse)
+}
+
+func sauvegarderPromesse(ctx contractapi.TransactionContextInterface, promesse *Promesse) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixePromesse, []string{promesse.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, promesse)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des fusions de propriétaires
+const PrefixeFusion = "FUSION"
+
+// Longueur minimale d'un préfixe de recherche, pour borner le nombre d'entrées parcourues
+const LongueurPrefixeMin = 3
+
+// Fusionner un propriétaire enregistré en double dans le propriétaire canonique (conservateurs uniquement)
+func (s *SmartContract) FusionnerProprietaires(ctx contractapi.TransactionContextInterface, idCanonique string, idDoublon string) (*FusionProprietaires, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	if idCanonique == "" || idDoublon == "" || idCanonique == idDoublon {
+		return nil, fmt.Errorf("les propriétaires à fusionner doivent être distincts et renseignés")
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := titresIndexes(ctx, PrefixeIndexProprio, []string{idDoublon}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("aucun titre n'est enregistré au nom de %s", idDoublon)
+	}
+
+	// La réattribution met à jour l'index des propriétaires avec chaque titre ;
+	// les titres mutés vers un autre canal y sont corrigés par ce canal
+	operation := nouvelleOperation("fusion-proprietaires")
+	var reattribues []string
+	for _, id := range ids {
+		titre, err := s.LireTitreFoncier(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if titre.MuteVers != "" {
+			continue
+		}
+		operation.ajouter("réattribution "+id, func() error {
+			return verifierBureau(ctx, titre)
+		}, func() error {
+			titre.Proprio = idCanonique
+			for i := range titre.Charges {
+				if titre.Charges[i].Beneficiaire == idDoublon {
+					titre.Charges[i].Beneficiaire = idCanonique
+				}
+			}
+			return sauvegarderTitre(ctx, titre)
+		})
+		reattribues = append(reattribues, id)
+	}
+	if len(reattribues) == 0 {
+		return nil, nouvelleErreur(CodeTitreMute, "les titres de %s sont administrés sur d'autres canaux", idDoublon)
+	}
+
+	fusion := &FusionProprietaires{
+		Id:           ctx.GetStub().GetTxID(),
+		IdCanonique:  idCanonique,
+		IdDoublon:    idDoublon,
+		Titres:       reattribues,
+		Conservateur: conservateur,
+		Date:         maintenant.Format(FormatDate),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFusion, []string{idDoublon, fusion.Id})
+	if err != nil {
+		return nil, err
+	}
+	operation.ajouter("trace de fusion", nil, func() error {
+		return ecrireEtat(ctx, cle, fusion)
+	})
+
+	if err := operation.executer(ctx); err != nil {
+		return nil, err
+	}
+
+	return fusion, nil
+}
+
+// Lister les fusions ayant absorbé un propriétaire
+func (s *SmartContract) GetFusionsProprietaire(ctx contractapi.TransactionContextInterface, idDoublon string) ([]*FusionProprietaires, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeFusion, []string{idDoublon})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var fusions []*FusionProprietaires
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var fusion FusionProprietaires
+		err = decoderEtat(queryResponse.Value, &fusion)
+		if err != nil {
+			return nil, err
+		}
+		fusions = append(fusions, &fusion)
+	}
+
+	return fusions, nil
+}
+
+// Titres d'un propriétaire, sans distinction d'accents, de casse ni d'espacement
+func (s *SmartContract) GetTitresParNomProprio(ctx contractapi.TransactionContextInterface, nom string) ([]*TitreFoncier, error) {
+	caracteres := caracteresNom(nom)
+	if len(caracteres) == 0 {
+		return nil, fmt.Errorf("le nom du propriétaire est obligatoire")
+	}
+
+	ids, err := titresIndexes(ctx, PrefixeIndexNom, append(caracteres, ""), 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.lireTitres(ctx, ids)
+}
+
+// Titres dont le nom du propriétaire commence par un préfixe, pour l'autocomplétion
+func (s *SmartContract) GetTitresParPrefixeNomProprio(ctx contractapi.TransactionContextInterface, prefixe string, limite int) ([]*TitreFoncier, error) {
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	caracteres := caracteresNom(prefixe)
+	if len(caracteres) < LongueurPrefixeMin {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le préfixe doit compter au moins %d caractères", LongueurPrefixeMin)
+	}
+
+	ids, err := titresIndexes(ctx, PrefixeIndexNom, caracteres, limite)
+	if err != nil {
+		return nil, err
+	}
+	return s.lireTitres(ctx, ids)
+}
+
+func (s *SmartContract) lireTitres(ctx contractapi.TransactionContextInterface, ids []string) ([]*TitreFoncier, error) {
+	titres := []*TitreFoncier{}
+	for _, id := range ids {
+		titre, err := s.LireTitreFoncier(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		titres = append(titres, titre)
+	}
+	return titres, nil
+}
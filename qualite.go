@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Recalculer la complétude d'un titre avant son écriture ; l'attestation d'un géomètre ne vaut que pour
+// le contour attesté, et tombe lorsqu'un autre contour est déposé sans nouvelle attestation
+func evaluerQualite(ancien *TitreFoncier, titre *TitreFoncier) {
+	if ancien != nil && ancien.Geometrie != titre.Geometrie && ancien.GeometreContour == titre.GeometreContour {
+		titre.GeometreContour = ""
+	}
+	qualite := model.EvaluerQualite(titre)
+	titre.Qualite = &qualite
+}
+
+// Lister, par pages, les titres en vigueur d'une tranche de complétude (FAIBLE, MOYENNE ou BONNE),
+// pour cibler les campagnes de fiabilisation. Les titres écrits avant le calcul du score n'y figurent
+// qu'après ReconstruireIndex.
+func (s *SmartContract) TitresParQualite(ctx contractapi.TransactionContextInterface, tranche string, limite int, signet string) (*ResultatRecherche, error) {
+	if tranche != QualiteFaible && tranche != QualiteMoyenne && tranche != QualiteBonne {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "tranche de qualité inconnue: %s (attendu: %s, %s ou %s)", tranche, QualiteFaible, QualiteMoyenne, QualiteBonne)
+	}
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+
+	resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeIndexQualite, []string{tranche}, int32(limite), signet)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	ids := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, attributs[1])
+	}
+	titres, err := s.lireTitres(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	resultat := &ResultatRecherche{Titres: titres, Nombre: len(titres)}
+	if resultat.Nombre == limite {
+		resultat.Signet = metadonnees.GetBookmark()
+	}
+	return resultat, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des compteurs d'appels (identite~operation~tranche horaire)
+// et des dépassements signalés (operation~txID)
+const (
+	PrefixeCompteurAppels   = "COMPTEUR_APPELS"
+	PrefixeSignalementQuota = "SIGNALEMENT_QUOTA"
+)
+
+// Durée de la fenêtre glissante des quotas
+const FenetreQuotas = 24 * time.Hour
+
+// Écart admis entre l'horodatage d'une transaction soumise à quota et l'horloge du pair endosseur
+const ToleranceHorodatage = 5 * time.Minute
+
+// Format des tranches horaires des compteurs d'appels
+const formatTrancheAppels = "2006-01-02T15"
+
+// Horloge du pair endosseur
+var horlogePair = time.Now
+
+// Compter l'appel de la transaction courante lorsqu'elle est soumise à quota, puis le rejeter
+// ou le signaler au-delà du quota selon la configuration du canal. Les appels sont comptés par
+// tranche horaire ; la fenêtre couvre la tranche en cours et les 24 précédentes.
+func comptabiliserAppel(ctx *ContexteTransaction) error {
+	operation := operationCourante(ctx)
+
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	quota, soumise := config.QuotasOperations[operation]
+	if !soumise {
+		return nil
+	}
+	// Les lettres de créance anonymes ne peuvent être reliées entre elles : leurs appels ne sont pas comptés
+	anonyme, err := identiteAnonyme(ctx)
+	if err != nil || anonyme {
+		return err
+	}
+
+	identite, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	// L'horodatage est fourni par le client : avancé, il sortirait de la fenêtre les appels déjà
+	// comptés. Chaque endosseur le confronte à sa propre horloge ; celle-ci n'entre pas dans les
+	// écritures, qui restent identiques d'un pair à l'autre.
+	if ecart := horlogePair().Sub(maintenant); ecart > ToleranceHorodatage || ecart < -ToleranceHorodatage {
+		return nouvelleErreur(CodeRequeteInvalide, "l'horodatage %s de la transaction s'écarte de plus de %s de l'horloge du pair", maintenant.Format(time.RFC3339), ToleranceHorodatage)
+	}
+
+	// Les tranches sorties de la fenêtre, et les compteurs antérieurs aux tranches, sont supprimés
+	premiere := maintenant.Add(-FenetreQuotas).Truncate(time.Hour).Format(formatTrancheAppels)
+	tranche := maintenant.Truncate(time.Hour).Format(formatTrancheAppels)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeCompteurAppels, []string{identite, operation})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	compteur := CompteurAppels{Identite: identite, Operation: operation, Tranche: tranche}
+	total := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return err
+		}
+		if len(attributs) != 3 || attributs[2] < premiere {
+			if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+				return err
+			}
+			continue
+		}
+		var existant CompteurAppels
+		if err := decoderEtat(queryResponse.Value, &existant); err != nil {
+			return err
+		}
+		total += existant.Appels
+		if attributs[2] == tranche {
+			compteur.Appels = existant.Appels
+		}
+	}
+	compteur.Appels++
+	total++
+
+	if total > quota {
+		if config.BlocageQuotas {
+			return nouvelleErreur(CodeQuotaDepasse, "quota de %d appels à %s sur 24 heures atteint", quota, operation)
+		}
+		if err := signalerDepassement(ctx, &compteur, total, quota, maintenant); err != nil {
+			return err
+		}
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeCompteurAppels, []string{identite, operation, tranche})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, &compteur)
+}
+
+func signalerDepassement(ctx contractapi.TransactionContextInterface, compteur *CompteurAppels, nombre int, quota int, maintenant time.Time) error {
+	signalement := SignalementQuota{
+		Id:        ctx.GetStub().GetTxID(),
+		Identite:  compteur.Identite,
+		Operation: compteur.Operation,
+		Nombre:    nombre,
+		Quota:     quota,
+		Date:      maintenant.Format(time.RFC3339),
+	}
+	journalTx(ctx).Warn("quota d'appels dépassé", slog.String("operation", signalement.Operation), slog.Int("nombre", signalement.Nombre), slog.Int("quota", quota))
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSignalementQuota, []string{signalement.Operation, signalement.Id})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, &signalement)
+}
+
+// Dépassements de quota signalés pour une transaction (réservé aux auditeurs)
+func (s *SmartContract) GetSignalementsQuotas(ctx contractapi.TransactionContextInterface, operation string) ([]*SignalementQuota, error) {
+	if err := verifierRole(ctx, RoleAuditeur); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSignalementQuota, []string{operation})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var signalements []*SignalementQuota
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var signalement SignalementQuota
+		err = decoderEtat(queryResponse.Value, &signalement)
+		if err != nil {
+			return nil, err
+		}
+		signalements = append(signalements, &signalement)
+	}
+
+	return signalements, nil
+}
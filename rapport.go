@@ -0,0 +1,383 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"titrefoncier/pkg/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe de l'index des activités par date (AAAA-MM-JJ~volet~activité~objet), tenu par les
+// transactions qui les produisent
+const PrefixeActivite = "ACTIVITE_PAR_DATE"
+
+// Étapes du job INDEX_ACTIVITE, dans l'ordre de parcours : les titres puis les dossiers de chaque volet
+var etapesIndexActivite = []string{EtapeAuditTitres, PrefixeTransfert, PrefixeLitige, PrefixeFacture, PrefixeRemboursement}
+
+// Synthèse, par pages, de l'activité du registre entre deux dates incluses (AAAA-MM-JJ), réservée à
+// l'État : les activités sont lues jour après jour dans l'index par date, au plus taillePage par appel.
+// Chaque page compte les activités qu'elle couvre et porte le signet de la suivante. L'activité
+// antérieure à l'index n'y figure qu'après le job INDEX_ACTIVITE.
+func (s *SmartContract) ExtraireRapportPeriode(ctx contractapi.TransactionContextInterface, debut string, fin string, typeRapport string, taillePage int, signet string) (*RapportPeriode, error) {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return nil, err
+	}
+	dateDebut, err := time.Parse(FormatDate, debut)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "date de début invalide (AAAA-MM-JJ attendu): %s", debut)
+	}
+	dateFin, err := time.Parse(FormatDate, fin)
+	if err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "date de fin invalide (AAAA-MM-JJ attendu): %s", fin)
+	}
+	if dateFin.Before(dateDebut) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la date de fin %s précède le %s", fin, debut)
+	}
+	switch typeRapport {
+	case "", RapportTitres, RapportTransferts, RapportLitiges, RapportFrais:
+	default:
+		return nil, nouvelleErreur(CodeRequeteInvalide, "type de rapport %q inconnu", typeRapport)
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+
+	// Le signet porte le jour en cours de lecture et le signet de pagination de ce jour
+	jour, signetJour := dateDebut, ""
+	if signet != "" {
+		date, suite, _ := strings.Cut(signet, separateurSignetAudit)
+		jour, err = time.Parse(FormatDate, date)
+		if err != nil || jour.Before(dateDebut) || jour.After(dateFin) {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "signet de rapport invalide: %s", signet)
+		}
+		signetJour = suite
+	}
+
+	rapport := &RapportPeriode{Debut: debut, Fin: fin, Type: typeRapport}
+	restant := taillePage
+	for ; !jour.After(dateFin); jour, signetJour = jour.AddDate(0, 0, 1), "" {
+		attributs := []string{jour.Format(FormatDate)}
+		if typeRapport != "" {
+			attributs = append(attributs, typeRapport)
+		}
+		resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeActivite, attributs, int32(restant), signetJour)
+		if err != nil {
+			return nil, err
+		}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			var activite ActiviteDatee
+			if err := decoderEtat(queryResponse.Value, &activite); err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+			rapport.Ajouter(activite)
+			restant--
+		}
+		resultsIterator.Close()
+		if restant == 0 {
+			rapport.Signet = jour.Format(FormatDate) + separateurSignetAudit + metadonnees.GetBookmark()
+			break
+		}
+	}
+	return rapport, nil
+}
+
+// Inscrire une activité dans l'index par date, sous la date de la transaction
+func inscrireActivite(ctx contractapi.TransactionContextInterface, activite ActiviteDatee) error {
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	return inscrireActiviteLe(ctx, maintenant, activite)
+}
+
+// Une activité n'est inscrite qu'une fois par jour : réécrire la même clé est sans effet sur les comptes
+func inscrireActiviteLe(ctx contractapi.TransactionContextInterface, instant time.Time, activite ActiviteDatee) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeActivite, []string{instant.UTC().Format(FormatDate), model.VoletActivite(activite.Activite), activite.Activite, activite.Objet})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, activite)
+}
+
+// Inscrire le changement de statut d'un transfert ; statut précédent vide pour un transfert proposé
+func inscrireActiviteTransfert(ctx contractapi.TransactionContextInterface, statutPrecedent string, transfert *Transfert) error {
+	activites, _ := activitesTransfert(statutPrecedent, transfert)
+	for _, activite := range activites {
+		if err := inscrireActivite(ctx, activite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Activités d'une version d'un transfert au regard de son statut précédent ; indique le statut retenu
+func activitesTransfert(statutPrecedent string, transfert *Transfert) ([]ActiviteDatee, string) {
+	var activites []ActiviteDatee
+	if statutPrecedent == "" {
+		activites = append(activites, ActiviteDatee{Activite: ActiviteTransfertPropose, Objet: transfert.Id})
+	}
+	if transfert.Statut != statutPrecedent {
+		switch transfert.Statut {
+		case TransfertFinalise:
+			activites = append(activites, ActiviteDatee{Activite: ActiviteTransfertFinalise, Objet: transfert.Id, Montant: transfert.Prix})
+		case TransfertAnnule:
+			activites = append(activites, ActiviteDatee{Activite: ActiviteTransfertAnnule, Objet: transfert.Id})
+		}
+	}
+	return activites, transfert.Statut
+}
+
+func validerIndexActivite(map[string]string) error { return nil }
+
+// Page du job INDEX_ACTIVITE : l'activité antérieure à l'index est reconstituée de l'historique des
+// titres et des transferts et litiges, et des dates portées par les factures et remboursements, qui
+// inscrivent aussi les factures dans leur index par date. Le signet porte l'étape et la dernière clé traitée ; une page incomplète termine l'étape. Réinscrire
+// une activité déjà indexée la laisse inchangée.
+func pageIndexActivite(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	nom, derniere, _ := strings.Cut(job.Signet, separateurSignetAudit)
+	etape := 0
+	for i, e := range etapesIndexActivite {
+		if e == nom {
+			etape = i
+		}
+	}
+
+	var cles []string
+	var err error
+	if etapesIndexActivite[etape] == EtapeAuditTitres {
+		cles, err = clesTitresApres(ctx, derniere, taillePage)
+	} else {
+		cles, err = clesParPrefixeApres(ctx, etapesIndexActivite[etape], derniere, taillePage)
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, cle := range cles {
+		inscrites, err := reconstituerActivites(ctx, etapesIndexActivite[etape], cle)
+		if err != nil {
+			return false, err
+		}
+		job.Compteurs["clesParcourues"]++
+		job.Compteurs["activitesInscrites"] += inscrites
+		derniere = cle
+	}
+
+	switch {
+	case len(cles) == taillePage:
+		job.Signet = etapesIndexActivite[etape] + separateurSignetAudit + derniere
+		return false, nil
+	case etape+1 < len(etapesIndexActivite):
+		job.Signet = etapesIndexActivite[etape+1] + separateurSignetAudit
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// Inscrire les activités datées d'un enregistrement de l'étape ; retourne leur nombre
+func reconstituerActivites(ctx contractapi.TransactionContextInterface, etape string, cle string) (int, error) {
+	type datee struct {
+		instant  time.Time
+		activite ActiviteDatee
+	}
+	var activites []datee
+	switch etape {
+	case EtapeAuditTitres:
+		var titre TitreFoncier
+		if _, err := lireEtat(ctx, cle, &titre); err != nil {
+			return 0, err
+		}
+		premiere := true
+		err := parcourirHistorique(ctx, cle, func(instant time.Time, _ []byte) error {
+			if premiere {
+				activites = append(activites, datee{instant, ActiviteDatee{Activite: ActiviteTitreCree, Objet: titre.Id, Commune: titre.Commune}})
+			}
+			premiere = false
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	case PrefixeTransfert:
+		statutPrecedent := ""
+		err := parcourirHistorique(ctx, cle, func(instant time.Time, valeur []byte) error {
+			var transfert Transfert
+			if err := decoderEtat(valeur, &transfert); err != nil {
+				return err
+			}
+			var nouvelles []ActiviteDatee
+			nouvelles, statutPrecedent = activitesTransfert(statutPrecedent, &transfert)
+			for _, activite := range nouvelles {
+				activites = append(activites, datee{instant, activite})
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	case PrefixeLitige:
+		// Un litige est clos dès qu'il quitte le statut OUVERT
+		premiere, clos := true, false
+		err := parcourirHistorique(ctx, cle, func(instant time.Time, valeur []byte) error {
+			var litige Litige
+			if err := decoderEtat(valeur, &litige); err != nil {
+				return err
+			}
+			if premiere {
+				activites = append(activites, datee{instant, ActiviteDatee{Activite: ActiviteLitigeOuvert, Objet: litige.Id}})
+			}
+			premiere = false
+			if !clos && litige.Statut != LitigeOuvert {
+				clos = true
+				activites = append(activites, datee{instant, ActiviteDatee{Activite: ActiviteLitigeClos, Objet: litige.Id}})
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	case PrefixeFacture:
+		var facture Facture
+		if _, err := lireEtat(ctx, cle, &facture); err != nil {
+			return 0, err
+		}
+		if emise, err := time.Parse(time.RFC3339, facture.EmiseLe); err == nil {
+			activites = append(activites, datee{emise, ActiviteDatee{Activite: ActiviteFraisFactures, Objet: facture.Id, Montant: facture.Montant}})
+		}
+		if payee, err := time.Parse(time.RFC3339, facture.PayeeLe); err == nil {
+			activites = append(activites, datee{payee, ActiviteDatee{Activite: ActiviteFraisEncaisses, Objet: facture.Id, Montant: facture.Montant}})
+		}
+		for _, horodatage := range []string{facture.EmiseLe, facture.PayeeLe} {
+			if horodatage == "" {
+				continue
+			}
+			if err := indexerFactureLe(ctx, facture.Id, horodatage); err != nil {
+				return 0, err
+			}
+		}
+	case PrefixeRemboursement:
+		var remboursement Remboursement
+		if _, err := lireEtat(ctx, cle, &remboursement); err != nil {
+			return 0, err
+		}
+		if decision, err := time.Parse(time.RFC3339, remboursement.DecideLe); err == nil && remboursement.Statut == RemboursementApprouve {
+			activites = append(activites, datee{decision, ActiviteDatee{Activite: ActiviteFraisRembourses, Objet: remboursement.Id, Montant: remboursement.Montant}})
+			if err := indexerFactureLe(ctx, remboursement.IdFacture, remboursement.DecideLe); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for _, a := range activites {
+		if err := inscrireActiviteLe(ctx, a.instant, a.activite); err != nil {
+			return 0, err
+		}
+	}
+	return len(activites), nil
+}
+
+// Clés des titres suivant la dernière traitée, au plus limite
+func clesTitresApres(ctx contractapi.TransactionContextInterface, derniere string, limite int) ([]string, error) {
+	debut := ""
+	if derniere != "" {
+		debut = derniere + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var cles []string
+	for len(cles) < limite && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return nil, err
+		}
+		if titre.Id == "" || titre.Id != queryResponse.Key {
+			continue
+		}
+		cles = append(cles, queryResponse.Key)
+	}
+	return cles, nil
+}
+
+// Clés d'un préfixe de clé composite suivant la dernière traitée, au plus limite ; les transactions qui
+// écrivent ne peuvent recourir aux requêtes paginées
+func clesParPrefixeApres(ctx contractapi.TransactionContextInterface, prefixe string, derniere string, limite int) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(prefixe, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var cles []string
+	for len(cles) < limite && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if queryResponse.Key <= derniere {
+			continue
+		}
+		cles = append(cles, queryResponse.Key)
+	}
+	return cles, nil
+}
+
+// Période couverte par un rapport, fin exclue
+type periodeRapport struct {
+	debut time.Time
+	fin   time.Time
+}
+
+func (p periodeRapport) contient(instant time.Time) bool {
+	return !instant.Before(p.debut) && instant.Before(p.fin)
+}
+
+// Parcourir les versions non supprimées d'une clé, de la plus ancienne à la plus récente dans l'ordre des blocs
+func parcourirHistorique(ctx contractapi.TransactionContextInterface, cle string, visiter func(instant time.Time, valeur []byte) error) error {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(cle)
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	type version struct {
+		instant time.Time
+		valeur  []byte
+	}
+	var versions []version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		if modification.IsDelete {
+			continue
+		}
+		versions = append(versions, version{modification.Timestamp.AsTime().UTC(), modification.Value})
+	}
+
+	// L'historique est restitué dans l'ordre des blocs, du plus récent au plus ancien
+	slices.Reverse(versions)
+	for _, v := range versions {
+		if err := visiter(v.instant, v.valeur); err != nil {
+			return err
+		}
+	}
+	return nil
+}
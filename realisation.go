@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des réalisations d'hypothèques (idHypotheque)
+const PrefixeRealisation = "REALISATION"
+
+// Constater la défaillance du débiteur sur commandement de payer resté infructueux (réservé à la banque créancière)
+func (s *SmartContract) ConstaterDefaillance(ctx contractapi.TransactionContextInterface, idHypotheque string, commandement string) error {
+	if commandement == "" {
+		return fmt.Errorf("la référence du commandement de payer est requise")
+	}
+	titre, hypotheque, err := titreHypotheque(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	if err := verifierMSP(ctx, hypotheque.Beneficiaire); err != nil {
+		return err
+	}
+
+	existante, err := lireRealisation(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	if existante != nil && existante.Statut != RealisationCloturee {
+		return fmt.Errorf("la réalisation de l'hypothèque %s est déjà en cours (%s)", idHypotheque, existante.Statut)
+	}
+
+	etape, err := etapeRealisation(ctx, RealisationDefaillance, commandement, "")
+	if err != nil {
+		return err
+	}
+	return sauvegarderRealisation(ctx, &RealisationHypotheque{
+		IdHypotheque: idHypotheque,
+		IdTitre:      titre.Id,
+		Creancier:    hypotheque.Beneficiaire,
+		Statut:       RealisationDefaillance,
+		Etapes:       []EtapeRealisation{etape},
+	})
+}
+
+// Autoriser la réalisation en exécution de la décision enregistrée qui l'ordonne sur le titre grevé,
+// citée sous la référence "juridiction:numero" (réservé aux conservateurs du bureau du titre)
+func (s *SmartContract) AutoriserRealisation(ctx contractapi.TransactionContextInterface, idHypotheque string, decision string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+
+	realisation, err := s.LireRealisation(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	if realisation.Statut != RealisationDefaillance {
+		return fmt.Errorf("la réalisation de l'hypothèque %s n'attend pas d'autorisation (%s)", idHypotheque, realisation.Statut)
+	}
+	titre, err := s.LireTitreFoncier(ctx, realisation.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	fondement, err := decisionOrdonnant(ctx, decision, ExecutionRealisation, titre.Id)
+	if err != nil {
+		return err
+	}
+
+	etape, err := etapeRealisation(ctx, RealisationAutorisee, fondement.Reference(), fondement.Juridiction)
+	if err != nil {
+		return err
+	}
+	realisation.Statut = RealisationAutorisee
+	realisation.Etapes = append(realisation.Etapes, etape)
+	if err := sauvegarderRealisation(ctx, realisation); err != nil {
+		return err
+	}
+
+	return executerDecision(ctx, fondement, ExecutionRealisation, titre.Id, idHypotheque)
+}
+
+// Engager la réalisation autorisée d'une hypothèque (réservé à la banque créancière) : le titre devient
+// indisponible jusqu'à son adjudication ou son attribution
+func (s *SmartContract) InitierRealisation(ctx contractapi.TransactionContextInterface, idHypotheque string) error {
+	realisation, err := s.LireRealisation(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	if err := verifierMSP(ctx, realisation.Creancier); err != nil {
+		return err
+	}
+	if realisation.Statut != RealisationAutorisee {
+		return fmt.Errorf("la réalisation de l'hypothèque %s requiert une défaillance constatée et une autorisation judiciaire (%s)", idHypotheque, realisation.Statut)
+	}
+
+	titre, _, err := titreHypotheque(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	// L'hypothèque inscrite avant le démembrement s'impose à l'usufruitier
+	if err := verifierAlienableDemembre(titre); err != nil {
+		return err
+	}
+
+	etape, err := etapeRealisation(ctx, RealisationEngagee, ctx.GetStub().GetTxID(), "")
+	if err != nil {
+		return err
+	}
+	realisation.Statut = RealisationEngagee
+	realisation.Etapes = append(realisation.Etapes, etape)
+	if err := sauvegarderRealisation(ctx, realisation); err != nil {
+		return err
+	}
+
+	titre.Realisation = idHypotheque
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Clôturer une réalisation par le jugement d'adjudication ou d'attribution (réservé aux conservateurs) :
+// le titre passe à l'attributaire, le prix est distribué aux créanciers par rang et les hypothèques sont purgées
+func (s *SmartContract) CloturerRealisation(ctx contractapi.TransactionContextInterface, idHypotheque string, mode string, attributaire string, prix int, jugement string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+	if jugement == "" || prix <= 0 {
+		return fmt.Errorf("le jugement et le prix de la réalisation sont obligatoires")
+	}
+
+	realisation, err := s.LireRealisation(ctx, idHypotheque)
+	if err != nil {
+		return err
+	}
+	if realisation.Statut != RealisationEngagee {
+		return fmt.Errorf("la réalisation de l'hypothèque %s n'est pas engagée (%s)", idHypotheque, realisation.Statut)
+	}
+	switch mode {
+	case RealisationAdjudication:
+		if attributaire == "" {
+			return fmt.Errorf("l'adjudicataire est obligatoire")
+		}
+	case RealisationAttribution:
+		if attributaire == "" {
+			attributaire = realisation.Creancier
+		}
+		if attributaire != realisation.Creancier {
+			return fmt.Errorf("l'attribution judiciaire ne peut bénéficier qu'au créancier poursuivant %s", realisation.Creancier)
+		}
+	default:
+		return fmt.Errorf("issue de réalisation inconnue: %s", mode)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, realisation.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierNonSaisi(titre); err != nil {
+		return err
+	}
+
+	restant := prix
+	hypotheques := hypothequesOrdonnees(titre)
+	for _, hypotheque := range hypotheques {
+		montant := min(hypotheque.Montant, restant)
+		restant -= montant
+		realisation.Repartition = append(realisation.Repartition, RepartitionPrix{
+			IdHypotheque: hypotheque.Reference,
+			Creancier:    hypotheque.Beneficiaire,
+			Rang:         hypotheque.Rang,
+			Montant:      montant,
+		})
+	}
+	for _, repartition := range realisation.Repartition {
+		retirerCharge(titre, ChargeHypotheque, repartition.IdHypotheque)
+	}
+
+	etape, err := etapeRealisation(ctx, RealisationCloturee, jugement, "")
+	if err != nil {
+		return err
+	}
+	realisation.Statut = RealisationCloturee
+	realisation.Etapes = append(realisation.Etapes, etape)
+	realisation.Mode = mode
+	realisation.Attributaire = attributaire
+	realisation.Prix = prix
+	realisation.Solde = restant
+	if err := sauvegarderRealisation(ctx, realisation); err != nil {
+		return err
+	}
+
+	titre.Proprio = attributaire
+	titre.Realisation = ""
+	titre.Usufruit = nil
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Lire la procédure de réalisation d'une hypothèque
+func (s *SmartContract) LireRealisation(ctx contractapi.TransactionContextInterface, idHypotheque string) (*RealisationHypotheque, error) {
+	realisation, err := lireRealisation(ctx, idHypotheque)
+	if err != nil {
+		return nil, err
+	}
+	if realisation == nil {
+		return nil, fmt.Errorf("aucune réalisation de l'hypothèque %s n'est enregistrée", idHypotheque)
+	}
+	return realisation, nil
+}
+
+// Titre grevé par une hypothèque et charge correspondante ; les titres clos par un morcellement sont ignorés
+func titreHypotheque(ctx contractapi.TransactionContextInterface, idHypotheque string) (*TitreFoncier, *Charge, error) {
+	ids, err := titresIndexes(ctx, PrefixeIndexHypotheque, []string{idHypotheque}, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var titres []*TitreFoncier
+	for _, id := range ids {
+		var titre TitreFoncier
+		existe, err := lireTitre(ctx, id, &titre)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existe && len(titre.MorceleEn) == 0 {
+			titres = append(titres, &titre)
+		}
+	}
+	if len(titres) == 0 {
+		return nil, nil, fmt.Errorf("hypothèque %s non trouvée", idHypotheque)
+	}
+	if len(titres) > 1 {
+		return nil, nil, fmt.Errorf("l'hypothèque %s grève plusieurs titres (%s)", idHypotheque, strings.Join(ids, ", "))
+	}
+
+	for _, hypotheque := range hypothequesOrdonnees(titres[0]) {
+		if hypotheque.Reference == idHypotheque {
+			return titres[0], hypotheque, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("hypothèque %s non trouvée sur le titre foncier %s", idHypotheque, titres[0].Id)
+}
+
+// Étape de réalisation franchie par l'appelant
+func etapeRealisation(ctx contractapi.TransactionContextInterface, statut string, reference string, autorite string) (EtapeRealisation, error) {
+	auteur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return EtapeRealisation{}, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return EtapeRealisation{}, err
+	}
+	return EtapeRealisation{Statut: statut, Reference: reference, Autorite: autorite, Par: auteur, Le: maintenant.Format(time.RFC3339)}, nil
+}
+
+// Réalisation enregistrée pour une hypothèque (nil si aucune)
+func lireRealisation(ctx contractapi.TransactionContextInterface, idHypotheque string) (*RealisationHypotheque, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRealisation, []string{idHypotheque})
+	if err != nil {
+		return nil, err
+	}
+
+	var realisation RealisationHypotheque
+	existe, err := lireEtat(ctx, cle, &realisation)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &realisation, nil
+}
+
+func sauvegarderRealisation(ctx contractapi.TransactionContextInterface, realisation *RealisationHypotheque) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRealisation, []string{realisation.IdHypotheque})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, realisation)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Enregistrer une décision rendue par le tribunal TGI-DK
+func (r *registreTest) decision(numero string, portee string, titre string) error {
+	greffe := &identiteTest{msp: MSPJuridictions}
+	return r.appeler(greffe, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.EnregistrerDecisionJudiciaire(ctx, "TGI-DK", numero, "ordonne", "a8472b5ec66cfcb5ba20ae4e6b23c8c7277457df", "2024-01-15", portee, `["`+titre+`"]`, "", "")
+	})
+}
+
+func TestAutorisationDeRealisation(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	r.autorite("TGI-DK")
+	_, hypotheque := r.titreHypotheque(t)
+	err := r.appeler(banque, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.ConstaterDefaillance(ctx, hypotheque, "CMD-1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []struct{ numero, portee, titre string }{
+		{"2024/1", ExecutionGel, "TF100"},
+		{"2024/2", ExecutionRealisation, "TF002"},
+		{"2024/3", ExecutionRealisation, "TF100"},
+	} {
+		if err := r.decision(d.numero, d.portee, d.titre); err != nil {
+			t.Fatal(err)
+		}
+	}
+	autoriser := func(appelant *identiteTest, decision string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.AutoriserRealisation(ctx, hypotheque, decision)
+		})
+	}
+
+	verifierCode(t, "décision non enregistrée", autoriser(conservateurDK, "TGI-DK:2024/9"), CodeDecisionInconnue)
+	verifierCode(t, "décision d'une autre portée", autoriser(conservateurDK, "TGI-DK:2024/1"), CodeRequeteInvalide)
+	verifierCode(t, "décision visant un autre titre", autoriser(conservateurDK, "TGI-DK:2024/2"), CodeRequeteInvalide)
+	verifierCode(t, "conservateur d'un autre bureau", autoriser(conservateurTH, "TGI-DK:2024/3"), CodeAccesRefuse)
+	verifierCode(t, "décision ordonnant la réalisation", autoriser(conservateurDK, "TGI-DK:2024/3"), "")
+
+	var realisation *RealisationHypotheque
+	var decision *DecisionJudiciaire
+	err = r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		if realisation, err = r.s.LireRealisation(ctx, hypotheque); err != nil {
+			return err
+		}
+		decision, err = r.s.LireDecisionJudiciaire(ctx, "TGI-DK", "2024/3")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etape := realisation.Etapes[len(realisation.Etapes)-1]; realisation.Statut != RealisationAutorisee || etape.Reference != "TGI-DK:2024/3" || etape.Autorite != "TGI-DK" {
+		t.Errorf("réalisation autorisée: %+v", realisation)
+	}
+	if len(decision.Executions) != 1 || decision.Executions[0].Objet != hypotheque {
+		t.Errorf("exécutions de la décision: %+v", decision.Executions)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Nombre maximal de titres renvoyés par page de recherche
+const LimiteRechercheMax = 100
+
+// Index CouchDB déployés avec le chaincode (META-INF/statedb/couchdb/indexes)
+type indexCouchDB struct {
+	ddoc string
+	nom  string
+}
+
+// Index des champs de titre, après docType : toute recherche doit porter sur l'un d'eux, et seuls
+// ceux-ci peuvent servir de critère de tri
+var indexChamps = map[string]indexCouchDB{
+	"commune":    {ddoc: "indexCommuneDoc", nom: "indexCommune"},
+	"creeLe":     {ddoc: "indexCreeLeDoc", nom: "indexCreeLe"},
+	"proprio":    {ddoc: "indexProprioDoc", nom: "indexProprio"},
+	"superficie": {ddoc: "indexSuperficieDoc", nom: "indexSuperficie"},
+}
+
+// Rechercher des titres fonciers par sélecteur CouchDB portant sur un champ indexé, triés sur un champ
+// indexé (ex: "superficie:desc") ; sans critère, seul le tri parcourt son index.
+// Les titres sont toujours écrits en JSON ; ceux écrits en MessagePack ou avant l'introduction du type
+// d'enregistrement ne sont visibles des requêtes riches qu'une fois repris par JobMarquageTitres.
+func (s *SmartContract) RechercherTitres(ctx contractapi.TransactionContextInterface, selecteurJSON string, tri string, limite int, signet string) (*ResultatRecherche, error) {
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	requete, err := construireRequete(selecteurJSON, tri)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadonnees, err := ctx.GetStub().GetQueryResultWithPagination(requete, int32(limite), signet)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de recherche: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	resultat := &ResultatRecherche{Titres: []*TitreFoncier{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var titre TitreFoncier
+		err = decoderEtat(queryResponse.Value, &titre)
+		if err != nil {
+			return nil, err
+		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return nil, err
+		}
+		resultat.Titres = append(resultat.Titres, &titre)
+	}
+	resultat.Nombre = len(resultat.Titres)
+	if resultat.Nombre == limite {
+		resultat.Signet = metadonnees.GetBookmark()
+	}
+
+	return resultat, nil
+}
+
+// Lister, par pages, les titres sans activité depuis au moins seuilAnnees ans, pour les campagnes de
+// relance et de fiabilisation. À défaut de date d'activité, antérieure à son suivi, un titre est jugé
+// sur sa date d'immatriculation.
+func (s *SmartContract) DetecterTitresDormants(ctx contractapi.TransactionContextInterface, seuilAnnees int, pageSize int, bookmark string) (*ResultatRecherche, error) {
+	if seuilAnnees <= 0 {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le seuil de dormance doit être d'au moins un an")
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seuil := maintenant.AddDate(-seuilAnnees, 0, 0).Format(FormatDate)
+
+	// Un titre n'a pas d'activité antérieure à son immatriculation : le critère sur creeLe, indexé,
+	// borne la recherche
+	selecteur, err := json.Marshal(map[string]interface{}{
+		"creeLe": map[string]string{"$lt": seuil},
+		"$or": []map[string]interface{}{
+			{"dernierActiviteLe": map[string]string{"$lt": seuil}},
+			{"dernierActiviteLe": map[string]bool{"$exists": false}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.RechercherTitres(ctx, string(selecteur), "", pageSize, bookmark)
+}
+
+// Traduire un sélecteur et un critère de tri en requête CouchDB servie par un index déclaré : celui du
+// champ trié, sinon celui du premier champ indexé du sélecteur. Un sélecteur sans champ indexé, qui
+// parcourrait tous les enregistrements du canal, est refusé.
+func construireRequete(selecteurJSON string, tri string) (string, error) {
+	selecteur := map[string]interface{}{}
+	if selecteurJSON != "" {
+		if err := json.Unmarshal([]byte(selecteurJSON), &selecteur); err != nil {
+			return "", nouvelleErreur(CodeRequeteInvalide, "sélecteur invalide: %v", err)
+		}
+	}
+	requete := map[string]interface{}{"selector": selecteur}
+
+	// Seuls les titres fonciers sont retenus parmi les enregistrements du canal, quel que soit le sélecteur
+	delete(selecteur, "docType")
+	criteres := len(selecteur)
+	champsIndexes := slices.Sorted(maps.Keys(indexChamps))
+	champIndexe := ""
+	for _, champ := range champsIndexes {
+		if _, present := selecteur[champ]; present {
+			champIndexe = champ
+			break
+		}
+	}
+	selecteur["docType"] = DocTypeTitre
+
+	if tri != "" {
+		champ, sens, _ := strings.Cut(tri, ":")
+		if sens == "" {
+			sens = "asc"
+		}
+		if sens != "asc" && sens != "desc" {
+			return "", nouvelleErreur(CodeRequeteInvalide, "sens de tri inconnu: %s", sens)
+		}
+		if _, ok := indexChamps[champ]; !ok {
+			return "", nouvelleErreur(CodeRequeteInvalide, "le tri sur %q n'est pas indexé", champ)
+		}
+		requete["sort"] = []map[string]string{{"docType": sens}, {champ: sens}}
+
+		// CouchDB n'utilise l'index que si le champ trié figure dans le sélecteur ; sans autre critère,
+		// la recherche parcourt l'index dans l'ordre du tri
+		if _, present := selecteur[champ]; !present {
+			selecteur[champ] = map[string]interface{}{"$gt": nil}
+		}
+		if champIndexe != "" || criteres == 0 {
+			champIndexe = champ
+		}
+	}
+	if champIndexe == "" {
+		return "", nouvelleErreur(CodeRequeteInvalide, "la recherche doit porter sur un champ indexé (%s)", strings.Join(champsIndexes, ", "))
+	}
+	index := indexChamps[champIndexe]
+	requete["use_index"] = []string{"_design/" + index.ddoc, index.nom}
+
+	contenu, err := json.Marshal(requete)
+	if err != nil {
+		return "", err
+	}
+	return string(contenu), nil
+}
+
+// Page d'un job de marquage : les titres écrits avant l'introduction du type d'enregistrement le
+// reçoivent, et ceux écrits en MessagePack sont réécrits en JSON, sans passer par sauvegarderTitre qui en
+// daterait l'activité et en notifierait les abonnés. Le signet est le dernier enregistrement parcouru.
+func pageMarquageTitres(s *SmartContract, ctx contractapi.TransactionContextInterface, job *Job, taillePage int) (bool, error) {
+	debut := ""
+	if job.Signet != "" {
+		debut = job.Signet + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(debut, "")
+	if err != nil {
+		return false, err
+	}
+	defer resultsIterator.Close()
+
+	parcourus := 0
+	for parcourus < taillePage && resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, err
+		}
+		parcourus++
+		job.Signet = queryResponse.Key
+
+		var titre TitreFoncier
+		if err := decoderEtat(queryResponse.Value, &titre); err != nil {
+			return false, err
+		}
+		if titre.Id == "" || titre.Id != queryResponse.Key {
+			continue
+		}
+		job.Compteurs["titresParcourus"]++
+		msgpack := bytes.HasPrefix(queryResponse.Value, enteteMsgpack)
+		if titre.DocType == DocTypeTitre && !msgpack {
+			continue
+		}
+		titre.DocType = DocTypeTitre
+		if err := ecrireEtatJSON(ctx, titre.Id, titre); err != nil {
+			return false, err
+		}
+		if msgpack {
+			job.Compteurs["titresReencodes"]++
+		} else {
+			job.Compteurs["titresMarques"]++
+		}
+	}
+	return parcourus < taillePage, nil
+}
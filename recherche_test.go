@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Une recherche riche n'est construite que servie par un index déclaré
+func TestConstruireRequete(t *testing.T) {
+	servies := []struct {
+		selecteur string
+		tri       string
+		index     string
+	}{
+		{`{"proprio":"Awa Ndiaye"}`, "", "indexProprio"},
+		{`{"commune":"Dakar","creeLe":{"$gte":"2024-01-01"}}`, "", "indexCommune"},
+		{`{"commune":"Dakar","nin":"123"}`, "superficie:desc", "indexSuperficie"},
+		{"", "creeLe", "indexCreeLe"},
+		{`{"docType":"autre"}`, "superficie", "indexSuperficie"},
+	}
+	for _, c := range servies {
+		contenu, err := construireRequete(c.selecteur, c.tri)
+		if err != nil {
+			t.Errorf("%s trié sur %q: %v", c.selecteur, c.tri, err)
+			continue
+		}
+		var requete struct {
+			Selector map[string]interface{} `json:"selector"`
+			UseIndex []string               `json:"use_index"`
+		}
+		if err := json.Unmarshal([]byte(contenu), &requete); err != nil {
+			t.Fatal(err)
+		}
+		if len(requete.UseIndex) != 2 || requete.UseIndex[1] != c.index || requete.Selector["docType"] != DocTypeTitre {
+			t.Errorf("%s trié sur %q: requête %s, index %s attendu", c.selecteur, c.tri, contenu, c.index)
+		}
+	}
+
+	refusees := []struct {
+		selecteur string
+		tri       string
+	}{
+		{"", ""},
+		{`{"nin":"123"}`, ""},
+		{`{"nin":"123"}`, "superficie"},
+		{`{"$or":[{"commune":"Dakar"},{"commune":"Thiès"}]}`, ""},
+		{`{"proprio":"Awa Ndiaye"}`, "nin"},
+	}
+	for _, c := range refusees {
+		_, err := construireRequete(c.selecteur, c.tri)
+		verifierCode(t, c.selecteur+" trié sur "+c.tri, err, CodeRequeteInvalide)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Rattacher à un titre migré sa référence dans les anciens livres fonciers (réservé aux conservateurs) ;
+// un même livre et folio ne désigne qu'un titre
+func (s *SmartContract) AjouterAncienneReference(ctx contractapi.TransactionContextInterface, idTitre string, livre string, folio string) (*TitreFoncier, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	reference := AncienneReference{Livre: livre, Folio: folio}
+	if err := reference.Valider(); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	reference = reference.Normaliser()
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return nil, err
+	}
+	for _, existante := range titre.AnciennesReferences {
+		if existante == reference {
+			return nil, fmt.Errorf("le titre foncier %s porte déjà l'ancienne référence livre %s folio %s", idTitre, reference.Livre, reference.Folio)
+		}
+	}
+	if err := verifierAncienneReference(ctx, reference, idTitre); err != nil {
+		return nil, err
+	}
+
+	titre.AnciennesReferences = append(titre.AnciennesReferences, reference)
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return nil, err
+	}
+	return titre, nil
+}
+
+// Retirer d'un titre une ancienne référence saisie par erreur (réservé aux conservateurs) : le livre et
+// le folio redeviennent disponibles pour le titre qu'ils désignent, et la correction, motivée, reste
+// inscrite sur le titre
+func (s *SmartContract) RetirerAncienneReference(ctx contractapi.TransactionContextInterface, idTitre string, livre string, folio string, motif string) (*TitreFoncier, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	reference := AncienneReference{Livre: livre, Folio: folio}
+	if err := reference.Valider(); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	if strings.TrimSpace(motif) == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif de la correction est obligatoire")
+	}
+	reference = reference.Normaliser()
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return nil, err
+	}
+	i := slices.Index(titre.AnciennesReferences, reference)
+	if i < 0 {
+		return nil, fmt.Errorf("le titre foncier %s ne porte pas l'ancienne référence livre %s folio %s", idTitre, reference.Livre, reference.Folio)
+	}
+
+	conservateur, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titre.AnciennesReferences = slices.Delete(titre.AnciennesReferences, i, i+1)
+	titre.ReferencesRetirees = append(titre.ReferencesRetirees, ReferenceRetiree{
+		Id:           ctx.GetStub().GetTxID(),
+		Reference:    reference,
+		Motif:        motif,
+		Conservateur: conservateur,
+		Date:         maintenant.Format(time.RFC3339),
+	})
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return nil, err
+	}
+	return titre, nil
+}
+
+// Retrouver le titre enregistré sous un livre et un folio des anciens livres fonciers, tels que cités
+// par les actes antérieurs à la migration ; casse, espaces et zéros non significatifs du folio sont ignorés
+func (s *SmartContract) RechercherParAncienneReference(ctx contractapi.TransactionContextInterface, livre string, folio string) ([]*TitreFoncier, error) {
+	reference := AncienneReference{Livre: livre, Folio: folio}
+	if err := reference.Valider(); err != nil {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+	reference = reference.Normaliser()
+
+	ids, err := titresIndexes(ctx, PrefixeIndexFolio, []string{reference.Livre, reference.Folio}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.lireTitres(ctx, ids)
+}
+
+// Vérifier qu'une ancienne référence (normalisée) n'est portée par aucun autre titre que idTitre
+func verifierAncienneReference(ctx contractapi.TransactionContextInterface, reference AncienneReference, idTitre string) error {
+	ids, err := titresIndexes(ctx, PrefixeIndexFolio, []string{reference.Livre, reference.Folio}, 0)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id != idTitre {
+			return fmt.Errorf("l'ancienne référence livre %s folio %s est déjà portée par le titre foncier %s", reference.Livre, reference.Folio, id)
+		}
+	}
+	return nil
+}
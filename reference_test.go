@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestAnciennesReferences(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	verifierCode(t, "immatriculation", r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"), "")
+	verifierCode(t, "immatriculation", r.ajouter(conservateurDK, "TF101", "Moussa Fall"), "")
+	referencer := func(appelant *identiteTest, id string, folio string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := r.s.AjouterAncienneReference(ctx, id, "12", folio)
+			return err
+		})
+	}
+	retirer := func(appelant *identiteTest, folio string, motif string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := r.s.RetirerAncienneReference(ctx, "TF100", "12", folio, motif)
+			return err
+		})
+	}
+	rechercher := func() []*TitreFoncier {
+		var titres []*TitreFoncier
+		err := r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) (err error) {
+			titres, err = r.s.RechercherParAncienneReference(ctx, "12", "34")
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return titres
+	}
+
+	verifierCode(t, "référence par un notaire", referencer(notaire, "TF100", "34"), CodeAccesRefuse)
+	verifierCode(t, "référence par un autre bureau", referencer(conservateurTH, "TF100", "34"), CodeAccesRefuse)
+	verifierCode(t, "référence", referencer(conservateurDK, "TF100", "034"), "")
+	if titres := rechercher(); len(titres) != 1 || titres[0].Id != "TF100" {
+		t.Fatalf("recherche par référence: %v", titres)
+	}
+	if err := referencer(conservateurDK, "TF101", "34"); err == nil {
+		t.Error("une référence est attribuée à deux titres")
+	}
+
+	verifierCode(t, "retrait par un notaire", retirer(notaire, "34", "folio mal recopié"), CodeAccesRefuse)
+	verifierCode(t, "retrait sans motif", retirer(conservateurDK, "34", " "), CodeRequeteInvalide)
+	verifierCode(t, "retrait", retirer(conservateurDK, "34", "folio mal recopié"), "")
+	titre := r.lire("TF100")
+	if len(titre.AnciennesReferences) != 0 || len(titre.ReferencesRetirees) != 1 || titre.ReferencesRetirees[0].Motif != "folio mal recopié" {
+		t.Errorf("titre après retrait: références %v, retraits %v", titre.AnciennesReferences, titre.ReferencesRetirees)
+	}
+	if titres := rechercher(); len(titres) != 0 {
+		t.Errorf("référence retirée encore indexée: %v", titres)
+	}
+	verifierCode(t, "référence libérée", referencer(conservateurDK, "TF101", "34"), "")
+	if titres := rechercher(); len(titres) != 1 || titres[0].Id != "TF101" {
+		t.Errorf("recherche après réattribution: %v", titres)
+	}
+}
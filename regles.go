@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des qualités de propriétaires (idProprio)
+const PrefixeQualites = "QUALITES_PROPRIO"
+
+// Définir les zonages d'une parcelle, critères des règles de transfert (réservé à l'État)
+func (s *SmartContract) DefinirZones(ctx contractapi.TransactionContextInterface, id string, zones []string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
+	if err != nil {
+		return err
+	}
+	details, err := json.Marshal(zones)
+	if err != nil {
+		return err
+	}
+	approbation, err := verifierApprobation(ctx, WorkflowZonage, id, string(details))
+	if err != nil {
+		return err
+	}
+
+	titre.Zones = zones
+	if err := consommerApprobation(ctx, approbation); err != nil {
+		return err
+	}
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Définir les qualités reconnues à un propriétaire ou acquéreur (réservé à l'État)
+func (s *SmartContract) DefinirQualitesProprietaire(ctx contractapi.TransactionContextInterface, idProprio string, qualites []string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeQualites, []string{idProprio})
+	if err != nil {
+		return err
+	}
+	enregistre := QualitesProprietaire{IdProprio: idProprio}
+	if _, err := lireEtat(ctx, cle, &enregistre); err != nil {
+		return err
+	}
+	enregistre.Qualites = qualites
+
+	return ecrireEtat(ctx, cle, &enregistre)
+}
+
+// Désigner les représentants d'un propriétaire personne morale et le nombre de leurs signatures
+// exigées pour qu'une vente soit proposée (réservé à l'État ; seuil 0 et aucun représentant : levée)
+func (s *SmartContract) DefinirRepresentants(ctx contractapi.TransactionContextInterface, idProprio string, representants []string, seuil int) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeQualites, []string{idProprio})
+	if err != nil {
+		return err
+	}
+	enregistre := QualitesProprietaire{IdProprio: idProprio, Qualites: []string{}}
+	if _, err := lireEtat(ctx, cle, &enregistre); err != nil {
+		return err
+	}
+	enregistre.Representants = representants
+	enregistre.SeuilCosignature = seuil
+	if err := enregistre.ValiderRepresentation(); err != nil {
+		return nouvelleErreur(CodeRequeteInvalide, "%v", err)
+	}
+
+	return ecrireEtat(ctx, cle, &enregistre)
+}
+
+// Lever une règle de transfert pour un transfert en attente (réservé à l'organisation désignée par la règle)
+func (s *SmartContract) ApprouverTransfert(ctx contractapi.TransactionContextInterface, idTransfert string, idRegle string) error {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	var regle *RegleTransfert
+	for i := range config.ReglesTransfert {
+		if config.ReglesTransfert[i].Id == idRegle {
+			regle = &config.ReglesTransfert[i]
+		}
+	}
+	if regle == nil {
+		return fmt.Errorf("règle de transfert %s non trouvée", idRegle)
+	}
+	if regle.Approbation == "" {
+		return nouvelleErreur(CodeTransfertRestreint, "la règle %s interdit le transfert sans possibilité d'approbation", idRegle)
+	}
+	if err := verifierMSP(ctx, regle.Approbation); err != nil {
+		return err
+	}
+
+	if approuvee(transfert, idRegle) {
+		return nil
+	}
+	transfert.Approbations = append(transfert.Approbations, idRegle)
+
+	return sauvegarderTransfert(ctx, transfert)
+}
+
+// Appliquer les règles de transfert du canal. À la proposition, seules les interdictions sont opposées ;
+// à l'exécution, chaque règle concernée doit en outre avoir été approuvée.
+func verifierReglesTransfert(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, transfert *Transfert, execution bool) error {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if len(config.ReglesTransfert) == 0 {
+		return nil
+	}
+
+	qualites, err := lireQualites(ctx, transfert.Acheteur)
+	if err != nil {
+		return err
+	}
+
+	for i := range config.ReglesTransfert {
+		regle := &config.ReglesTransfert[i]
+		if !regle.Concerne(titre, qualites.Qualites) {
+			continue
+		}
+		if regle.Approbation == "" {
+			return nouvelleErreur(CodeTransfertRestreint, "transfert du titre foncier %s interdit par la règle %s: %s", titre.Id, regle.Id, regle.Description)
+		}
+		if execution && !approuvee(transfert, regle.Id) {
+			return nouvelleErreur(CodeTransfertRestreint, "le transfert %s requiert l'approbation de %s (règle %s: %s)", transfert.Id, regle.Approbation, regle.Id, regle.Description)
+		}
+	}
+	return nil
+}
+
+func approuvee(transfert *Transfert, idRegle string) bool {
+	for _, approbation := range transfert.Approbations {
+		if approbation == idRegle {
+			return true
+		}
+	}
+	return false
+}
+
+// Qualités et représentation enregistrées d'un propriétaire (vides s'il n'en a aucune)
+func lireQualites(ctx contractapi.TransactionContextInterface, idProprio string) (*QualitesProprietaire, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeQualites, []string{idProprio})
+	if err != nil {
+		return nil, err
+	}
+	qualites := &QualitesProprietaire{IdProprio: idProprio}
+	if _, err := lireEtat(ctx, cle, qualites); err != nil {
+		return nil, err
+	}
+	return qualites, nil
+}
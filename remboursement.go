@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des remboursements de trop-perçus (id)
+const PrefixeRemboursement = "REMBOURSEMENT"
+
+// Proposer le remboursement d'un trop-perçu sur une facture réglée (réservé aux conservateurs du
+// bureau du titre). Une seule proposition est instruite à la fois, et le cumul des remboursements ne
+// peut dépasser le montant encaissé ; chaque proposition reste inscrite à la suite de la facture.
+func (s *SmartContract) ProposerRemboursement(ctx contractapi.TransactionContextInterface, factureId string, montant int, motif string) (*Remboursement, error) {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return nil, err
+	}
+	if montant <= 0 {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le montant du remboursement doit être positif")
+	}
+	if motif == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif du remboursement est obligatoire")
+	}
+	facture, err := s.LireFacture(ctx, factureId)
+	if err != nil {
+		return nil, err
+	}
+	if facture.Statut != FacturePayee {
+		return nil, fmt.Errorf("la facture %s n'est pas réglée", factureId)
+	}
+	titre, err := s.LireTitreFoncier(ctx, facture.IdTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return nil, err
+	}
+	if n := len(facture.Remboursements); n > 0 {
+		precedent, err := s.LireRemboursement(ctx, facture.Remboursements[n-1])
+		if err != nil {
+			return nil, err
+		}
+		if precedent.Statut == RemboursementPropose {
+			return nil, fmt.Errorf("le remboursement %s de la facture %s est en cours d'instruction", precedent.Id, factureId)
+		}
+	}
+	if restant := facture.Montant - facture.Rembourse; montant > restant {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le remboursement de %d FCFA excède les %d FCFA encaissés non remboursés sur la facture %s", montant, restant, factureId)
+	}
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remboursement := &Remboursement{
+		Id:         fmt.Sprintf("%s-R%d", factureId, len(facture.Remboursements)+1),
+		IdFacture:  factureId,
+		Montant:    montant,
+		Motif:      motif,
+		Statut:     RemboursementPropose,
+		ProposePar: agent,
+		ProposeLe:  maintenant.Format(time.RFC3339),
+		TxId:       ctx.GetStub().GetTxID(),
+	}
+	if err := sauvegarderRemboursement(ctx, remboursement); err != nil {
+		return nil, err
+	}
+	facture.Remboursements = append(facture.Remboursements, remboursement.Id)
+	if err := sauvegarderFacture(ctx, facture); err != nil {
+		return nil, err
+	}
+	return remboursement, nil
+}
+
+// Approuver un remboursement proposé, sur l'ordre de remboursement émis par le Trésor (réservé au
+// Trésor) ; le montant est porté au cumul remboursé de la facture
+func (s *SmartContract) ApprouverRemboursement(ctx contractapi.TransactionContextInterface, id string, refTresor string) (*Remboursement, error) {
+	if refTresor == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la référence de l'ordre de remboursement du Trésor est obligatoire")
+	}
+	remboursement, err := deciderRemboursement(s, ctx, id, RemboursementApprouve)
+	if err != nil {
+		return nil, err
+	}
+	facture, err := s.LireFacture(ctx, remboursement.IdFacture)
+	if err != nil {
+		return nil, err
+	}
+
+	remboursement.RefTresor = refTresor
+	if err := sauvegarderRemboursement(ctx, remboursement); err != nil {
+		return nil, err
+	}
+	facture.Rembourse += remboursement.Montant
+	if err := sauvegarderFacture(ctx, facture); err != nil {
+		return nil, err
+	}
+	if err := indexerFactureLe(ctx, facture.Id, remboursement.DecideLe); err != nil {
+		return nil, err
+	}
+	if err := inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteFraisRembourses, Objet: remboursement.Id, Montant: remboursement.Montant}); err != nil {
+		return nil, err
+	}
+	return remboursement, nil
+}
+
+// Rejeter un remboursement proposé (réservé au Trésor)
+func (s *SmartContract) RejeterRemboursement(ctx contractapi.TransactionContextInterface, id string, motif string) (*Remboursement, error) {
+	if motif == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le motif du rejet est obligatoire")
+	}
+	remboursement, err := deciderRemboursement(s, ctx, id, RemboursementRejete)
+	if err != nil {
+		return nil, err
+	}
+
+	remboursement.MotifDecision = motif
+	if err := sauvegarderRemboursement(ctx, remboursement); err != nil {
+		return nil, err
+	}
+	return remboursement, nil
+}
+
+// Statuer, au nom du Trésor, sur un remboursement en cours d'instruction ; l'appelant le sauvegarde
+func deciderRemboursement(s *SmartContract, ctx contractapi.TransactionContextInterface, id string, statut string) (*Remboursement, error) {
+	if err := verifierMSP(ctx, MSPTresor); err != nil {
+		return nil, err
+	}
+	remboursement, err := s.LireRemboursement(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if remboursement.Statut != RemboursementPropose {
+		return nil, fmt.Errorf("le remboursement %s est déjà %s", id, remboursement.Statut)
+	}
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remboursement.Statut = statut
+	remboursement.DecidePar = agent
+	remboursement.DecideLe = maintenant.Format(time.RFC3339)
+	remboursement.TxDecision = ctx.GetStub().GetTxID()
+	return remboursement, nil
+}
+
+// Lire un remboursement
+func (s *SmartContract) LireRemboursement(ctx contractapi.TransactionContextInterface, id string) (*Remboursement, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRemboursement, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	var remboursement Remboursement
+	existe, err := lireEtat(ctx, cle, &remboursement)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("remboursement %s non trouvé", id)
+	}
+	return &remboursement, nil
+}
+
+// Remboursements proposés sur une facture, du premier au dernier
+func (s *SmartContract) GetRemboursementsFacture(ctx contractapi.TransactionContextInterface, factureId string) ([]*Remboursement, error) {
+	facture, err := s.LireFacture(ctx, factureId)
+	if err != nil {
+		return nil, err
+	}
+
+	remboursements := make([]*Remboursement, 0, len(facture.Remboursements))
+	for _, id := range facture.Remboursements {
+		remboursement, err := s.LireRemboursement(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		remboursements = append(remboursements, remboursement)
+	}
+	return remboursements, nil
+}
+
+func sauvegarderRemboursement(ctx contractapi.TransactionContextInterface, remboursement *Remboursement) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeRemboursement, []string{remboursement.Id})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, remboursement)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des résumés conservés, avant leur intégration aux événements, lorsqu'un
+// autre événement occupait la transaction (txID)
+const PrefixeResumeTransaction = "RESUME_TX"
+
+// Les entrées d'index n'intéressent pas les consommateurs du résumé
+const prefixeIndexResume = "INDEX_"
+
+// Stub relevant les enregistrements écrits par la transaction
+type stubTrace struct {
+	shim.ChaincodeStubInterface
+	contexte *ContexteTransaction
+}
+
+func (s *stubTrace) PutState(cle string, valeur []byte) error {
+	if err := s.ChaincodeStubInterface.PutState(cle, valeur); err != nil {
+		return err
+	}
+	s.contexte.releverModification(cle, false)
+	return nil
+}
+
+func (s *stubTrace) DelState(cle string) error {
+	if err := s.ChaincodeStubInterface.DelState(cle); err != nil {
+		return err
+	}
+	s.contexte.releverModification(cle, true)
+	return nil
+}
+
+// Retenir une écriture ; la dernière opération sur une clé l'emporte
+func (c *ContexteTransaction) releverModification(cle string, suppression bool) {
+	modification := ModificationCle{Type: TypeModificationTitre, Attributs: []string{cle}, Suppression: suppression}
+	if strings.HasPrefix(cle, "\x00") {
+		typeObjet, attributs, err := c.TransactionContext.GetStub().SplitCompositeKey(cle)
+		if err != nil || strings.HasPrefix(typeObjet, prefixeIndexResume) {
+			return
+		}
+		modification.Type, modification.Attributs = typeObjet, attributs
+	}
+
+	for i := range c.modifications {
+		if c.cles[i] == cle {
+			c.modifications[i].Suppression = suppression
+			return
+		}
+	}
+	c.cles = append(c.cles, cle)
+	c.modifications = append(c.modifications, modification)
+}
+
+// Nom de la transaction invoquée, sans le préfixe éventuel du contrat ("SmartContract:Operation")
+func operationCourante(ctx contractapi.TransactionContextInterface) string {
+	fonction, _ := ctx.GetStub().GetFunctionAndParameters()
+	return fonction[strings.LastIndex(fonction, ":")+1:]
+}
+
+// Lire le résumé conservé d'une transaction antérieure à ChampResumeTransaction dont l'événement a été
+// occupé par un événement métier ou une alerte ; le résumé des suivantes est joint à leur événement
+func (s *SmartContract) LireResumeTransaction(ctx contractapi.TransactionContextInterface, txId string) (*ResumeTransaction, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeResumeTransaction, []string{txId})
+	if err != nil {
+		return nil, err
+	}
+
+	var resume ResumeTransaction
+	existe, err := lireEtat(ctx, cle, &resume)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("aucun résumé conservé pour la transaction %s", txId)
+	}
+
+	return &resume, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des saisies conservatoires (idTitre~refProcedure)
+const PrefixeSaisie = "SAISIE"
+
+// Poser une saisie conservatoire sur un titre (réservé au parquet) ; le titre reste consultable
+// et peut recevoir des documents, mais aucun acte de disposition n'est possible jusqu'à la mainlevée
+func (s *SmartContract) PoserSaisieConservatoire(ctx contractapi.TransactionContextInterface, idTitre string, refProcedure string) error {
+	if err := verifierMSP(ctx, MSPParquet); err != nil {
+		return err
+	}
+	if refProcedure == "" {
+		return fmt.Errorf("la référence de la procédure est requise")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	existante, err := lireSaisie(ctx, idTitre, refProcedure)
+	if err != nil {
+		return err
+	}
+	if existante != nil && existante.Statut == SaisieEnVigueur {
+		return fmt.Errorf("la saisie %s est déjà en vigueur sur le titre foncier %s", refProcedure, idTitre)
+	}
+
+	magistrat, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	saisie := &SaisieConservatoire{
+		IdTitre:      idTitre,
+		RefProcedure: refProcedure,
+		PoseePar:     magistrat,
+		PoseeLe:      maintenant.Format(time.RFC3339),
+		Statut:       SaisieEnVigueur,
+	}
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+
+	titre.Saisies = append(titre.Saisies, refProcedure)
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Lever une saisie conservatoire sur décision de mainlevée (réservé au parquet) ; le titre redevient
+// disponible lorsque plus aucune saisie n'est en vigueur
+func (s *SmartContract) LeverSaisieConservatoire(ctx contractapi.TransactionContextInterface, idTitre string, refProcedure string, mainlevee string) error {
+	if err := verifierMSP(ctx, MSPParquet); err != nil {
+		return err
+	}
+	if mainlevee == "" {
+		return fmt.Errorf("la référence de la décision de mainlevée est requise")
+	}
+
+	saisie, err := s.LireSaisieConservatoire(ctx, idTitre, refProcedure)
+	if err != nil {
+		return err
+	}
+	if saisie.Statut != SaisieEnVigueur {
+		return fmt.Errorf("la saisie %s du titre foncier %s a déjà été levée", refProcedure, idTitre)
+	}
+	if saisie.Decision != "" {
+		return fmt.Errorf("le gel %s du titre foncier %s, ordonné par une juridiction, ne peut être levé que sur décision de justice", refProcedure, idTitre)
+	}
+	if saisie.Lot != "" {
+		return nouvelleErreur(CodeRequeteInvalide, "la saisie %s porte sur le lot %s, à lever par LeverSaisieLot", refProcedure, saisie.Lot)
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+
+	magistrat, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	saisie.Statut = SaisieLevee
+	saisie.Mainlevee = mainlevee
+	saisie.LeveePar = magistrat
+	saisie.LeveeLe = maintenant.Format(time.RFC3339)
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+
+	saisies := titre.Saisies[:0]
+	for _, ref := range titre.Saisies {
+		if ref != refProcedure {
+			saisies = append(saisies, ref)
+		}
+	}
+	titre.Saisies = saisies
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Poser une saisie conservatoire sur un lot de copropriété (réservé au parquet) : seul le lot devient
+// indisponible, les autres lots du titre support restant cessibles
+func (s *SmartContract) PoserSaisieLot(ctx contractapi.TransactionContextInterface, idTitre string, numero string, refProcedure string) error {
+	if err := verifierMSP(ctx, MSPParquet); err != nil {
+		return err
+	}
+	if refProcedure == "" {
+		return fmt.Errorf("la référence de la procédure est requise")
+	}
+
+	lot, err := s.LireLotCopropriete(ctx, idTitre, numero)
+	if err != nil {
+		return err
+	}
+	existante, err := lireSaisie(ctx, idTitre, refProcedure)
+	if err != nil {
+		return err
+	}
+	if existante != nil && existante.Statut == SaisieEnVigueur {
+		return fmt.Errorf("la saisie %s est déjà en vigueur sur le titre foncier %s", refProcedure, idTitre)
+	}
+
+	magistrat, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	saisie := &SaisieConservatoire{
+		IdTitre:      idTitre,
+		Lot:          numero,
+		RefProcedure: refProcedure,
+		PoseePar:     magistrat,
+		PoseeLe:      maintenant.Format(time.RFC3339),
+		Statut:       SaisieEnVigueur,
+	}
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+
+	lot.Saisies = append(lot.Saisies, refProcedure)
+	return sauvegarderLotCopropriete(ctx, lot)
+}
+
+// Lever la saisie conservatoire d'un lot de copropriété sur décision de mainlevée (réservé au parquet)
+func (s *SmartContract) LeverSaisieLot(ctx contractapi.TransactionContextInterface, idTitre string, refProcedure string, mainlevee string) error {
+	if err := verifierMSP(ctx, MSPParquet); err != nil {
+		return err
+	}
+	if mainlevee == "" {
+		return fmt.Errorf("la référence de la décision de mainlevée est requise")
+	}
+
+	saisie, err := s.LireSaisieConservatoire(ctx, idTitre, refProcedure)
+	if err != nil {
+		return err
+	}
+	if saisie.Lot == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "la saisie %s porte sur le titre foncier %s, à lever par LeverSaisieConservatoire", refProcedure, idTitre)
+	}
+	if saisie.Statut != SaisieEnVigueur {
+		return fmt.Errorf("la saisie %s du lot %s a déjà été levée", refProcedure, saisie.Lot)
+	}
+	lot, err := s.LireLotCopropriete(ctx, idTitre, saisie.Lot)
+	if err != nil {
+		return err
+	}
+
+	magistrat, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	saisie.Statut = SaisieLevee
+	saisie.Mainlevee = mainlevee
+	saisie.LeveePar = magistrat
+	saisie.LeveeLe = maintenant.Format(time.RFC3339)
+	if err := sauvegarderSaisie(ctx, saisie); err != nil {
+		return err
+	}
+
+	saisies := lot.Saisies[:0]
+	for _, ref := range lot.Saisies {
+		if ref != refProcedure {
+			saisies = append(saisies, ref)
+		}
+	}
+	lot.Saisies = saisies
+	return sauvegarderLotCopropriete(ctx, lot)
+}
+
+// Lire une saisie conservatoire
+func (s *SmartContract) LireSaisieConservatoire(ctx contractapi.TransactionContextInterface, idTitre string, refProcedure string) (*SaisieConservatoire, error) {
+	saisie, err := lireSaisie(ctx, idTitre, refProcedure)
+	if err != nil {
+		return nil, err
+	}
+	if saisie == nil {
+		return nil, fmt.Errorf("saisie %s du titre foncier %s non trouvée", refProcedure, idTitre)
+	}
+	return saisie, nil
+}
+
+// Historique des saisies conservatoires d'un titre, en vigueur ou levées
+func (s *SmartContract) GetSaisiesConservatoires(ctx contractapi.TransactionContextInterface, idTitre string) ([]*SaisieConservatoire, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSaisie, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var saisies []*SaisieConservatoire
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var saisie SaisieConservatoire
+		err = decoderEtat(queryResponse.Value, &saisie)
+		if err != nil {
+			return nil, err
+		}
+		saisies = append(saisies, &saisie)
+	}
+
+	return saisies, nil
+}
+
+// Vérifier qu'aucune saisie conservatoire n'est en vigueur sur un titre
+func verifierNonSaisi(titre *TitreFoncier) error {
+	if len(titre.Saisies) > 0 {
+		return nouvelleErreur(CodeTitreSaisi, "le titre foncier %s fait l'objet d'une saisie conservatoire (procédure %s)", titre.Id, titre.Saisies[0])
+	}
+	return nil
+}
+
+// Saisie conservatoire enregistrée (nil si aucune)
+func lireSaisie(ctx contractapi.TransactionContextInterface, idTitre string, refProcedure string) (*SaisieConservatoire, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSaisie, []string{idTitre, refProcedure})
+	if err != nil {
+		return nil, err
+	}
+
+	var saisie SaisieConservatoire
+	existe, err := lireEtat(ctx, cle, &saisie)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &saisie, nil
+}
+
+func sauvegarderSaisie(ctx contractapi.TransactionContextInterface, saisie *SaisieConservatoire) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSaisie, []string{saisie.IdTitre, saisie.RefProcedure})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, saisie)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des séquestres (idTransfert)
+const PrefixeSequestre = "SEQUESTRE"
+
+// Ouvrir le séquestre d'un transfert en attente (réservé aux notaires) ; les fonds sont remis au vendeur
+// à la finalisation du transfert et restitués à l'acheteur s'il est annulé
+func (s *SmartContract) OuvrirSequestre(ctx contractapi.TransactionContextInterface, idTransfert string, conditions string) error {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return err
+	}
+
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+	existant, err := lireSequestre(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if existant != nil {
+		return fmt.Errorf("un séquestre est déjà ouvert pour le transfert %s", idTransfert)
+	}
+
+	notaire, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+
+	// Sans promesse convertie, le notaire séquestre instrumente la vente
+	if transfert.Notaire == "" {
+		transfert.Notaire = notaire
+		if err := sauvegarderTransfert(ctx, transfert); err != nil {
+			return err
+		}
+	}
+
+	return sauvegarderSequestre(ctx, &Sequestre{
+		IdTransfert: idTransfert,
+		IdTitre:     transfert.IdTitre,
+		Notaire:     notaire,
+		Conditions:  conditions,
+		Depots:      []DepotSequestre{},
+		Statut:      SequestreOuvert,
+	})
+}
+
+// Enregistrer un dépôt sous séquestre : paiement reçu ou blocage de jetons (réservé au notaire séquestre)
+func (s *SmartContract) DeposerSequestre(ctx contractapi.TransactionContextInterface, idTransfert string, typeDepot string, reference string, montant int) error {
+	sequestre, err := s.LireSequestre(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	notaire, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if notaire != sequestre.Notaire {
+		return nouvelleErreur(CodeAccesRefuse, "seul le notaire séquestre peut enregistrer des dépôts sur le transfert %s", idTransfert)
+	}
+	if sequestre.Statut != SequestreOuvert {
+		return fmt.Errorf("le séquestre du transfert %s est clos (%s)", idTransfert, sequestre.Statut)
+	}
+	switch typeDepot {
+	case DepotPaiement, DepotJeton:
+	default:
+		return fmt.Errorf("nature de dépôt inconnue: %s", typeDepot)
+	}
+	if reference == "" || montant <= 0 {
+		return fmt.Errorf("la référence et le montant du dépôt sont obligatoires")
+	}
+	for _, depot := range sequestre.Depots {
+		if depot.Type == typeDepot && depot.Reference == reference {
+			return fmt.Errorf("le dépôt %s est déjà enregistré sur le séquestre du transfert %s", reference, idTransfert)
+		}
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	sequestre.Depots = append(sequestre.Depots, DepotSequestre{
+		Type:      typeDepot,
+		Reference: reference,
+		Montant:   montant,
+		Le:        maintenant.Format(time.RFC3339),
+	})
+	sequestre.Total += montant
+
+	return sauvegarderSequestre(ctx, sequestre)
+}
+
+// Lire le séquestre d'un transfert
+func (s *SmartContract) LireSequestre(ctx contractapi.TransactionContextInterface, idTransfert string) (*Sequestre, error) {
+	sequestre, err := lireSequestre(ctx, idTransfert)
+	if err != nil {
+		return nil, err
+	}
+	if sequestre == nil {
+		return nil, fmt.Errorf("aucun séquestre n'est ouvert pour le transfert %s", idTransfert)
+	}
+	return sequestre, nil
+}
+
+// Libérer ou rembourser le séquestre ouvert d'un transfert finalisé ou annulé
+func cloturerSequestre(ctx contractapi.TransactionContextInterface, transfert *Transfert) error {
+	sequestre, err := lireSequestre(ctx, transfert.Id)
+	if err != nil || sequestre == nil || sequestre.Statut != SequestreOuvert {
+		return err
+	}
+
+	switch transfert.Statut {
+	case TransfertFinalise:
+		sequestre.Statut = SequestreLibere
+		sequestre.Beneficiaire = transfert.Vendeur
+	case TransfertAnnule:
+		sequestre.Statut = SequestreRembourse
+		sequestre.Beneficiaire = transfert.Acheteur
+	default:
+		return nil
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	sequestre.ClotureLe = maintenant.Format(time.RFC3339)
+
+	return sauvegarderSequestre(ctx, sequestre)
+}
+
+// Séquestre enregistré pour un transfert (nil si aucun)
+func lireSequestre(ctx contractapi.TransactionContextInterface, idTransfert string) (*Sequestre, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequestre, []string{idTransfert})
+	if err != nil {
+		return nil, err
+	}
+
+	var sequestre Sequestre
+	existe, err := lireEtat(ctx, cle, &sequestre)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &sequestre, nil
+}
+
+func sauvegarderSequestre(ctx contractapi.TransactionContextInterface, sequestre *Sequestre) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSequestre, []string{sequestre.IdTransfert})
+	if err != nil {
+		return err
+	}
+
+	return ecrireEtat(ctx, cle, sequestre)
+}
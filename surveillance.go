@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes des clés composites des surveillances (idTitre~banque) et des alertes (banque~txId~idTitre)
+const (
+	PrefixeSurveillance = "SURVEILLANCE"
+	PrefixeAlerteBanque = "ALERTE_BANQUE"
+)
+
+// Surveiller, pour la banque appelante, un titre donné en garantie d'un concours ; seule une banque
+// créancière d'une hypothèque inscrite sur le titre peut le surveiller
+func (s *SmartContract) SurveillerTitre(ctx contractapi.TransactionContextInterface, idTitre string, reference string) error {
+	if reference == "" {
+		return fmt.Errorf("la référence du concours garanti est obligatoire")
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	banque, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture du MSP: %v", err)
+	}
+	creanciere := false
+	for _, hypotheque := range hypothequesOrdonnees(titre) {
+		creanciere = creanciere || hypotheque.Beneficiaire == banque
+	}
+	if !creanciere {
+		return nouvelleErreur(CodeAccesRefuse, "%s ne détient aucune hypothèque sur le titre foncier %s", banque, idTitre)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	surveillance := &SurveillanceGarantie{
+		IdTitre:   idTitre,
+		Banque:    banque,
+		Reference: reference,
+		CreeLe:    maintenant.Format(FormatDate),
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSurveillance, []string{idTitre, banque})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, surveillance)
+}
+
+// Lever la surveillance d'un titre par la banque appelante
+func (s *SmartContract) LeverSurveillance(ctx contractapi.TransactionContextInterface, idTitre string) error {
+	banque, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture du MSP: %v", err)
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeSurveillance, []string{idTitre, banque})
+	if err != nil {
+		return err
+	}
+	var surveillance SurveillanceGarantie
+	existe, err := lireEtat(ctx, cle, &surveillance)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		return fmt.Errorf("%s ne surveille pas le titre foncier %s", banque, idTitre)
+	}
+	return ctx.GetStub().DelState(cle)
+}
+
+// Alertes reçues par la banque appelante, par pages de taillePage alertes parcourues ; celles
+// antérieures à depuis (AAAA-MM-JJ, vide : toutes) sont écartées de la page
+func (s *SmartContract) GetAlertesBanque(ctx contractapi.TransactionContextInterface, depuis string, taillePage int, signet string) (*PageAlertesBanque, error) {
+	if depuis != "" {
+		if _, err := time.Parse(FormatDate, depuis); err != nil {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "date invalide (AAAA-MM-JJ attendu): %s", depuis)
+		}
+	}
+	if taillePage <= 0 || taillePage > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la taille de page doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	banque, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture du MSP: %v", err)
+	}
+
+	resultsIterator, metadonnees, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(PrefixeAlerteBanque, []string{banque}, int32(taillePage), signet)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &PageAlertesBanque{Alertes: []*AlerteBanque{}}
+	parcourues := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		parcourues++
+		var alerte AlerteBanque
+		if err := decoderEtat(queryResponse.Value, &alerte); err != nil {
+			return nil, err
+		}
+		if depuis == "" || alerte.Horodatage >= depuis {
+			page.Alertes = append(page.Alertes, &alerte)
+		}
+	}
+	if parcourues == taillePage {
+		page.Signet = metadonnees.GetBookmark()
+	}
+	return page, nil
+}
+
+// Alerter les banques surveillant un titre touché par la transaction
+func alerterBanques(ctx contractapi.TransactionContextInterface, idTitre string, motif string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSurveillance, []string{idTitre})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	var alertes []AlerteBanque
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		var surveillance SurveillanceGarantie
+		if err := decoderEtat(queryResponse.Value, &surveillance); err != nil {
+			return err
+		}
+		alertes = append(alertes, AlerteBanque{
+			Banque:    surveillance.Banque,
+			IdTitre:   idTitre,
+			Reference: surveillance.Reference,
+			Motifs:    []string{motif},
+		})
+	}
+	if len(alertes) == 0 {
+		return nil
+	}
+
+	if contexte, ok := ctx.(*ContexteTransaction); ok {
+		contexte.alertesBanques = fusionnerAlertesBanques(contexte.alertesBanques, alertes)
+		return nil
+	}
+	if err := conserverAlertesBanques(ctx, alertes); err != nil {
+		return err
+	}
+	return emettreAlertesBanques(ctx, alertes)
+}
+
+// Une banque n'est alertée qu'une fois par titre et par transaction, avec l'ensemble des motifs
+func fusionnerAlertesBanques(existantes []AlerteBanque, nouvelles []AlerteBanque) []AlerteBanque {
+	for _, nouvelle := range nouvelles {
+		doublon := false
+		for i := range existantes {
+			if existantes[i].Banque != nouvelle.Banque || existantes[i].IdTitre != nouvelle.IdTitre {
+				continue
+			}
+			doublon = true
+			for _, motif := range nouvelle.Motifs {
+				connu := false
+				for _, existant := range existantes[i].Motifs {
+					connu = connu || existant == motif
+				}
+				if !connu {
+					existantes[i].Motifs = append(existantes[i].Motifs, motif)
+				}
+			}
+			break
+		}
+		if !doublon {
+			existantes = append(existantes, nouvelle)
+		}
+	}
+	return existantes
+}
+
+// Enregistrer les alertes de la transaction pour leur consultation par les banques
+func conserverAlertesBanques(ctx contractapi.TransactionContextInterface, alertes []AlerteBanque) error {
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range alertes {
+		alertes[i].Operation = operationCourante(ctx)
+		alertes[i].TxId = ctx.GetStub().GetTxID()
+		alertes[i].Horodatage = maintenant.Format(time.RFC3339)
+
+		cle, err := ctx.GetStub().CreateCompositeKey(PrefixeAlerteBanque, []string{alertes[i].Banque, alertes[i].TxId, alertes[i].IdTitre})
+		if err != nil {
+			return err
+		}
+		if err := ecrireEtat(ctx, cle, &alertes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Émettre une alerte nommée d'après les banques concernées, pour un routage sans décodage
+func emettreAlertesBanques(ctx contractapi.TransactionContextInterface, alertes []AlerteBanque) error {
+	nom, contenu, err := evenementAlertesBanques(ctx, alertes)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(nom, contenu)
+}
+
+func evenementAlertesBanques(ctx contractapi.TransactionContextInterface, alertes []AlerteBanque) (string, []byte, error) {
+	uniques := make(map[string]bool)
+	for _, alerte := range alertes {
+		uniques[alerte.Banque] = true
+	}
+	banques := clesTriees(uniques)
+
+	contenu, err := json.Marshal(AlertesBanques{TxId: ctx.GetStub().GetTxID(), Alertes: alertes})
+	return EvenementAlerteBanque + ":" + strings.Join(banques, ","), contenu, err
+}
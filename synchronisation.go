@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixes du journal des écritures de titres par commune, lu par les terminaux de terrain pour se
+// synchroniser : entrées (commune~txId~id), fil des transactions d'une commune (commune) et dernière
+// entrée d'un titre (id)
+const (
+	PrefixeJournalCommune = "JOURNAL_COMMUNE"
+	PrefixeFilJournal     = "FIL_JOURNAL"
+	PrefixeVersionTitre   = "VERSION_TITRE"
+)
+
+// Inscrire l'écriture d'un titre au journal de sa commune (titre nil : suppression). Aucun compteur
+// n'est partagé entre les transactions d'une commune : chaque entrée est rangée sous sa transaction, et
+// l'ordre du journal est celui de l'historique du fil de la commune, que chaque transaction écrit sans
+// le lire. Les titres sans commune, absents des extraits de terrain, ne sont pas journalisés.
+func journaliserTitre(ctx contractapi.TransactionContextInterface, ancien *TitreFoncier, titre *TitreFoncier) error {
+	if ancien != nil && ancien.Commune != "" && (titre == nil || ancien.Commune != titre.Commune) {
+		if err := inscrireJournal(ctx, ancien.Commune, ancien.Id, true); err != nil {
+			return err
+		}
+	}
+	if titre == nil || titre.Commune == "" {
+		return nil
+	}
+	return inscrireJournal(ctx, titre.Commune, titre.Id, false)
+}
+
+func inscrireJournal(ctx contractapi.TransactionContextInterface, commune string, idTitre string, suppression bool) error {
+	organisation, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture du MSP: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	modification := &ModificationCommune{
+		Commune:      commune,
+		IdTitre:      idTitre,
+		TxId:         ctx.GetStub().GetTxID(),
+		Operation:    operationCourante(ctx),
+		Organisation: organisation,
+		Date:         maintenant.Format(time.RFC3339),
+		Suppression:  suppression,
+	}
+
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeJournalCommune, []string{commune, modification.TxId, idTitre})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, modification); err != nil {
+		return err
+	}
+	if cle, err = ctx.GetStub().CreateCompositeKey(PrefixeVersionTitre, []string{idTitre}); err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, modification); err != nil {
+		return err
+	}
+
+	// Le fil liste les titres écrits par la transaction dans la commune. Il est écrit directement, sans
+	// ecrireEtat qui relit l'enregistrement en place : deux transactions concurrentes n'entrent pas en
+	// conflit, et l'historique du fil garde l'écriture de chacune dans l'ordre des blocs.
+	titres := []string{idTitre}
+	if contexte, ok := ctx.(*ContexteTransaction); ok {
+		if contexte.journal == nil {
+			contexte.journal = make(map[string][]string)
+		}
+		contexte.journal[commune] = append(contexte.journal[commune], idTitre)
+		titres = contexte.journal[commune]
+	}
+	if cle, err = ctx.GetStub().CreateCompositeKey(PrefixeFilJournal, []string{commune}); err != nil {
+		return err
+	}
+	valeur, err := json.Marshal(titres)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(cle, valeur)
+}
+
+// Indiquer si la transaction a journalisé une écriture du titre
+func titreJournalise(ctx contractapi.TransactionContextInterface, idTitre string) bool {
+	contexte, ok := ctx.(*ContexteTransaction)
+	if !ok {
+		return false
+	}
+	for _, titres := range contexte.journal {
+		for _, id := range titres {
+			if id == idTitre {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Écritures de titres d'une commune après la transaction depuis (vide : depuis l'origine), par pages
+// d'au moins limite entrées, les écritures d'une même transaction n'étant jamais séparées ; le signet
+// rendu est à repasser comme depuis à la synchronisation suivante
+func (s *SmartContract) GetModificationsCommune(ctx contractapi.TransactionContextInterface, commune string, depuis string, limite int) (*FluxModifications, error) {
+	if commune == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la commune est requise")
+	}
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeFilJournal, []string{commune})
+	if err != nil {
+		return nil, err
+	}
+
+	// L'historique du fil est restitué de la plus récente à la plus ancienne transaction : il est
+	// parcouru jusqu'au signet, puis les transactions retenues sont reprises dans l'ordre des blocs
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(cle)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	type transaction struct {
+		txId   string
+		titres []string
+	}
+	var nouvelles []transaction
+	atteint := depuis == ""
+	for resultsIterator.HasNext() {
+		version, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if version.TxId == depuis {
+			atteint = true
+			break
+		}
+		var titres []string
+		if err := decoderEtat(version.Value, &titres); err != nil {
+			return nil, fmt.Errorf("fil du journal illisible à la transaction %s: %v", version.TxId, err)
+		}
+		nouvelles = append(nouvelles, transaction{txId: version.TxId, titres: titres})
+	}
+	if !atteint {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "signet de synchronisation inconnu du journal de %s: %q", commune, depuis)
+	}
+
+	flux := &FluxModifications{Commune: commune, Modifications: []ModificationCommune{}, Signet: depuis}
+	for i := len(nouvelles) - 1; i >= 0; i-- {
+		if len(flux.Modifications) >= limite {
+			flux.Suite = true
+			break
+		}
+		for _, idTitre := range nouvelles[i].titres {
+			cle, err := ctx.GetStub().CreateCompositeKey(PrefixeJournalCommune, []string{commune, nouvelles[i].txId, idTitre})
+			if err != nil {
+				return nil, err
+			}
+			var modification ModificationCommune
+			existe, err := lireEtat(ctx, cle, &modification)
+			if err != nil {
+				return nil, fmt.Errorf("entrée de journal illisible %q: %v", cle, err)
+			}
+			if existe {
+				flux.Modifications = append(flux.Modifications, modification)
+			}
+		}
+		flux.Signet = nouvelles[i].txId
+	}
+	return flux, nil
+}
+
+// Dernière écriture journalisée d'un titre, qu'un terminal compare à celle de son extrait avant de
+// soumettre une opération saisie hors ligne (transaction vide : titre jamais journalisé)
+func (s *SmartContract) LireVersionTitre(ctx contractapi.TransactionContextInterface, idTitre string) (*ModificationCommune, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeVersionTitre, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	version := &ModificationCommune{IdTitre: idTitre}
+	if _, err := lireEtat(ctx, cle, version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// Nombre d'arguments, après l'identifiant du titre, des opérations qu'un terminal peut saisir hors ligne
+var operationsHorsLigne = map[string]int{
+	"AjouterDocument":            2,
+	"EnregistrerConstatTerrain":  3,
+	"SignalerOccupationIllegale": 2,
+}
+
+// Appliquer une opération saisie hors ligne par un terminal de terrain, si le titre n'a pas été écrit
+// depuis la version que le terminal en connaissait (transaction de sa dernière écriture journalisée,
+// vide si le titre n'y figurait pas). La version est lue dans la transaction : une écriture concurrente
+// du titre la fait échouer à la validation au lieu de passer inaperçue.
+func (s *SmartContract) AppliquerOperationHorsLigne(ctx contractapi.TransactionContextInterface, idTitre string, version string, fonction string, arguments string) (*OperationAppliquee, error) {
+	arite, autorisee := operationsHorsLigne[fonction]
+	if !autorisee {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%q ne peut être saisie hors ligne", fonction)
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || len(args) != arite {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "%s attend une liste JSON de %d arguments après l'identifiant du titre", fonction, arite)
+	}
+
+	derniere, err := s.LireVersionTitre(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if derniere.TxId != version {
+		return nil, nouvelleErreur(CodeVersionPerimee, "le titre %s a été écrit par la transaction %s depuis la version %q", idTitre, derniere.TxId, version)
+	}
+
+	resultat := &OperationAppliquee{Version: version}
+	switch fonction {
+	case "AjouterDocument":
+		err = s.AjouterDocument(ctx, idTitre, args[0], args[1])
+	case "EnregistrerConstatTerrain":
+		resultat.Reponse, err = s.EnregistrerConstatTerrain(ctx, idTitre, args[0], args[1], args[2])
+	case "SignalerOccupationIllegale":
+		resultat.Reponse, err = s.SignalerOccupationIllegale(ctx, idTitre, args[0], args[1])
+	}
+	if err != nil {
+		return nil, err
+	}
+	if titreJournalise(ctx, idTitre) {
+		resultat.Version = ctx.GetStub().GetTxID()
+	}
+	return resultat, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Date de la transaction courante, identique sur tous les pairs endosseurs
+func dateTransaction(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("erreur de lecture de l'horodatage: %v", err)
+	}
+	return ts.AsTime().UTC(), nil
+}
@@ -1,49 +1,22 @@
 package main
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-)
 
-//Définition de la structure des Titres Fonciers
-type TitreFoncier struct {
-	Id         string `json:"id"`         // Identifiant unique du titre foncier
-	Proprio    string `json:"proprio"`    // Nom du propriétaire
-	NumTF      string `json:"numTF"`      // Numéro officiel du titre foncier
-	Superficie int    `json:"superficie"` // Superficie du terrain en m²
-	Document   string `json:"document"`   // Chemin du fichier NFS
-	DocHash    string `json:"doc_hash"`   // Hash SHA-1 du document
-}
+	"titrefoncier/pkg/model"
+)
 
 //Définition du Smart Contract
 type SmartContract struct {
 	contractapi.Contract
 }
 
-// Fonction pour calculer le hash SHA-1 d'un document
-func GenerateSHA1Hash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("erreur d'ouverture fichier: %v", err)
-	}
-	defer file.Close()
-
-	content, err := ioutil.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("erreur de lecture fichier: %v", err)
-	}
-
-	hash := sha1.Sum(content)
-	return hex.EncodeToString(hash[:]), nil
-}
-
 // Initialisation avec quelques Titres Fonciers
 func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
 	titres := []TitreFoncier{
@@ -52,12 +25,7 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 	}
 
 	for _, titre := range titres {
-		titreJSON, err := json.Marshal(titre)
-		if err != nil {
-			return err
-		}
-
-		err = ctx.GetStub().PutState(titre.Id, titreJSON)
+		err := sauvegarderTitre(ctx, &titre)
 		if err != nil {
 			return fmt.Errorf("erreur d'enregistrement: %v", err)
 		}
@@ -68,6 +36,16 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 
 //Ajouter un nouveau Titre Foncier
 func (s *SmartContract) AjouterTitreFoncier(ctx contractapi.TransactionContextInterface, id string, proprio string, numTF string, superficie int, document string) error {
+	return s.AjouterTitreFoncierCommune(ctx, id, proprio, numTF, superficie, document, "")
+}
+
+// Ajouter un nouveau Titre Foncier situé dans une commune, requise sur les canaux qui restreignent
+// leurs communes
+func (s *SmartContract) AjouterTitreFoncierCommune(ctx contractapi.TransactionContextInterface, id string, proprio string, numTF string, superficie int, document string, commune string) error {
+	if err := verifierRole(ctx, RoleConservateur); err != nil {
+		return err
+	}
+
 	// Vérifier si l'ID existe déjà
 	existant, err := ctx.GetStub().GetState(id)
 	if err != nil {
@@ -77,44 +55,86 @@ func (s *SmartContract) AjouterTitreFoncier(ctx contractapi.TransactionContextIn
 		return fmt.Errorf("le titre foncier %s existe déjà", id)
 	}
 
-	// Générer le hash du document
-	docHash, err := GenerateSHA1Hash(document)
+	// Vérifier que la commune relève de ce canal
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if commune == "" && len(config.Communes) > 0 {
+		return nouvelleErreur(CodeRequeteInvalide, "la commune du titre est requise sur ce canal (AjouterTitreFoncierCommune)")
+	}
+	if err := verifierCommune(config, commune); err != nil {
+		return err
+	}
+	if err := verifierSaisieDirecte(config); err != nil {
+		return err
+	}
+
+	// Attribuer un numéro de la séquence du canal si aucun n'est fourni
+	if numTF == "" {
+		numTF, err = s.prochainNumero(ctx, "TF")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Contrôler le document et générer son hash
+	fichier, err := controlerDocument(ctx, document)
 	if err != nil {
-		return fmt.Errorf("erreur de génération du hash: %v", err)
+		return err
 	}
 
 	// Créer l'objet
-	titre := TitreFoncier{
-		Id:         id,
-		Proprio:    proprio,
-		NumTF:      numTF,
-		Superficie: superficie,
-		Document:   document,
-		DocHash:    docHash,
+	titre := model.NouveauTitreFoncier(id, proprio, numTF, superficie, commune, document, fichier.hash)
+
+	return immatriculerTitre(ctx, titre)
+}
+
+// Immatriculer un nouveau titre au bureau de l'agent appelant
+func immatriculerTitre(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	if err := titre.Valider(); err != nil {
+		return err
 	}
 
-	// Convertir en JSON et enregistrer
-	titreJSON, err := json.Marshal(titre)
+	// Le titre est rattaché au bureau de l'agent qui l'immatricule, sauf un lot qui reste à celui du titre mère
+	var err error
+	if titre.BureauFoncier == "" {
+		if titre.BureauFoncier, err = bureauAppelant(ctx); err != nil {
+			return err
+		}
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
 	if err != nil {
 		return err
 	}
+	titre.CreeLe = maintenant.Format(FormatDate)
 
-	return ctx.GetStub().PutState(id, titreJSON)
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return err
+	}
+	if err := enregistrerTraitement(ctx, TraitementImmatriculation, titre.Id, 0); err != nil {
+		return err
+	}
+	if err := emettreFacture(ctx, FacturationImmatriculation, titre.Id, titre.Id, 0); err != nil {
+		return err
+	}
+	return renforcerEndossement(ctx, titre, 0)
 }
 
 // Lire un Titre Foncier
 func (s *SmartContract) LireTitreFoncier(ctx contractapi.TransactionContextInterface, id string) (*TitreFoncier, error) {
-	titreJSON, err := ctx.GetStub().GetState(id)
+	var titre TitreFoncier
+	existe, err := lireTitre(ctx, id, &titre)
 	if err != nil {
-		return nil, fmt.Errorf("erreur de lecture: %v", err)
+		return nil, err
 	}
-	if titreJSON == nil {
+	if !existe {
 		return nil, fmt.Errorf("titre foncier %s non trouvé", id)
 	}
-
-	var titre TitreFoncier
-	err = json.Unmarshal(titreJSON, &titre)
-	if err != nil {
+	if err := eteindreUsufruitEchu(ctx, &titre); err != nil {
 		return nil, err
 	}
 
@@ -127,28 +147,142 @@ func (s *SmartContract) ModifierProprietaire(ctx contractapi.TransactionContextI
 	if err != nil {
 		return err
 	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+
+	if err := verifierAlienable(titre); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifierLibreDePromesse(titre, "", maintenant.Format(FormatDate)); err != nil {
+		return err
+	}
 
 	titre.Proprio = nouveauProprio
 
-	titreJSON, err := json.Marshal(titre)
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Classer ou déclasser une parcelle du domaine public (réservé à l'État)
+func (s *SmartContract) DefinirInalienable(ctx contractapi.TransactionContextInterface, id string, inalienable bool) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(id, titreJSON)
+	titre.Inalienable = inalienable
+
+	return sauvegarderTitre(ctx, titre)
 }
 
-// Supprimer un Titre Foncier
-func (s *SmartContract) SupprimerTitreFoncier(ctx contractapi.TransactionContextInterface, id string) error {
-	existant, err := ctx.GetStub().GetState(id)
+// Rattacher un titre à un autre bureau foncier (réservé à l'administration centrale)
+func (s *SmartContract) DefinirBureauFoncier(ctx contractapi.TransactionContextInterface, id string, bureau string) error {
+	if err := verifierMSP(ctx, MSPEtat); err != nil {
+		return err
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, id)
 	if err != nil {
-		return fmt.Errorf("erreur lors de la suppression: %v", err)
+		return err
 	}
-	if existant == nil {
-		return fmt.Errorf("titre foncier %s introuvable", id)
+
+	titre.BureauFoncier = bureau
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Vérifier qu'un titre peut faire l'objet d'un acte de disposition (cession, hypothèque, morcellement)
+func verifierAlienable(titre *TitreFoncier) error {
+	if titre.Inalienable {
+		return nouvelleErreur(CodeTitreInalienable, "le titre foncier %s relève du domaine public et ne peut être cédé, hypothéqué ou morcelé", titre.Id)
+	}
+	if titre.MuteVers != "" {
+		return nouvelleErreur(CodeTitreMute, "le titre foncier %s est désormais administré sur le canal %s", titre.Id, titre.MuteVers)
+	}
+	if titre.Copropriete {
+		return nouvelleErreur(CodeTitreEnCopropriete, "le titre foncier %s est placé sous le régime de la copropriété, seuls ses lots sont cessibles", titre.Id)
+	}
+	if titre.Usufruit != nil {
+		return nouvelleErreur(CodeTitreDemembre, "la propriété du titre foncier %s est démembrée, l'acte requiert le concours de l'usufruitier %s", titre.Id, titre.Usufruit.Usufruitier)
+	}
+	if titre.Realisation != "" {
+		return nouvelleErreur(CodeTitreEnRealisation, "le titre foncier %s fait l'objet de la réalisation de l'hypothèque %s", titre.Id, titre.Realisation)
+	}
+	if len(titre.MorceleEn) > 0 {
+		return nouvelleErreur(CodeTitreMorcele, "le titre foncier %s est clos, morcelé en %s", titre.Id, strings.Join(titre.MorceleEn, ", "))
+	}
+	return verifierNonSaisi(titre)
+}
+
+// Enregistrer l'état courant d'un titre foncier, daté de la transaction, et tenir ses index à jour
+func sauvegarderTitre(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	var ancien *TitreFoncier
+	var enPlace TitreFoncier
+	existe, err := lireTitre(ctx, titre.Id, &enPlace)
+	if err != nil {
+		return err
+	}
+	if existe {
+		ancien = &enPlace
 	}
 
-	return ctx.GetStub().DelState(id)
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	titre.DocType = DocTypeTitre
+	titre.DernierActiviteLe = maintenant.Format(FormatDate)
+	if err := eteindreUsufruitEchu(ctx, titre); err != nil {
+		return err
+	}
+	daterGeometrie(ancien, titre, maintenant)
+	evaluerQualite(ancien, titre)
+
+	// Les documents sont enregistrés chacun sous leur propre clé : l'ajout d'un acte ne réécrit
+	// pas toute la liste, et les titres dont elle figure encore dans l'état migrent ici
+	titre.DocumentsSepares = true
+	if err := ecrireDocumentsSepares(ctx, titre, ancien); err != nil {
+		return err
+	}
+	enregistre := *titre
+	enregistre.Documents = nil
+
+	if err := ecrireEtatJSON(ctx, titre.Id, enregistre); err != nil {
+		return err
+	}
+	if err := mettreAJourIndex(ctx, ancien, titre); err != nil {
+		return err
+	}
+	if err := reporterAllocations(ctx, ancien, titre); err != nil {
+		return err
+	}
+	if err := journaliserTitre(ctx, ancien, titre); err != nil {
+		return err
+	}
+	if ancien == nil {
+		if err := inscrireActivite(ctx, ActiviteDatee{Activite: ActiviteTitreCree, Objet: titre.Id, Commune: titre.Commune}); err != nil {
+			return err
+		}
+	}
+	if err := notifierAbonnes(ctx, titre, ""); err != nil {
+		return err
+	}
+	return alerterBanques(ctx, titre.Id, AlerteModification)
+}
+
+// Supprimer un Titre Foncier : ouvre une demande d'archivage, identifiée par l'ID de la transaction,
+// qu'un second conservateur doit confirmer (ConfirmerArchivage)
+func (s *SmartContract) SupprimerTitreFoncier(ctx contractapi.TransactionContextInterface, id string) error {
+	_, err := s.ProposerArchivage(ctx, id, "suppression")
+	return err
 }
 
 // Lister tous les Titres Fonciers
@@ -167,24 +301,127 @@ func (s *SmartContract) GetAllTitresFonciers(ctx contractapi.TransactionContextI
 		}
 
 		var titre TitreFoncier
-		err = json.Unmarshal(queryResponse.Value, &titre)
+		err = decoderEtat(queryResponse.Value, &titre)
 		if err != nil {
 			return nil, err
 		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return nil, err
+		}
 		titres = append(titres, &titre)
 	}
 
 	return titres, nil
 }
 
+// Lister les titres fonciers par pages de limite clés du registre, dans l'ordre des identifiants ;
+// repasser le signet de la page pour obtenir la suivante. Une page peut compter moins de titres que
+// de clés parcourues.
+func (s *SmartContract) GetTitresFonciersPagines(ctx contractapi.TransactionContextInterface, limite int, signet string) (*ResultatRecherche, error) {
+	if limite <= 0 || limite > LimiteRechercheMax {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "la limite doit être comprise entre 1 et %d", LimiteRechercheMax)
+	}
+	resultsIterator, metadonnees, err := ctx.GetStub().GetStateByRangeWithPagination("", "", int32(limite), signet)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	resultat := &ResultatRecherche{Titres: []*TitreFoncier{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var titre TitreFoncier
+		err = decoderEtat(queryResponse.Value, &titre)
+		if err != nil {
+			return nil, err
+		}
+		if titre.Id == "" {
+			continue
+		}
+		if err := chargerDocuments(ctx, &titre); err != nil {
+			return nil, err
+		}
+		resultat.Titres = append(resultat.Titres, &titre)
+	}
+	resultat.Nombre = len(resultat.Titres)
+	if metadonnees.GetFetchedRecordsCount() == int32(limite) {
+		resultat.Signet = metadonnees.GetBookmark()
+	}
+
+	return resultat, nil
+}
+
 func main() {
-	titreChaincode, err := contractapi.NewChaincode(&SmartContract{})
+	titreChaincode, err := contractapi.NewChaincode(nouveauContrat())
 	if err != nil {
-		log.Panicf("Erreur création chaincode: %v", err)
+		journal.Error("Erreur création chaincode", slog.Any("erreur", err))
+		os.Exit(1)
 	}
 
-	if err := titreChaincode.Start(); err != nil {
-		log.Panicf("Erreur démarrage chaincode: %v", err)
+	// Sans adresse de service, le chaincode est lancé par le pair
+	adresse := os.Getenv("CHAINCODE_SERVER_ADDRESS")
+	if adresse == "" {
+		if err := shim.Start(envelopper(instrumenter(titreChaincode))); err != nil {
+			journal.Error("Erreur démarrage chaincode", slog.Any("erreur", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Mode chaincode-as-a-service : exposer aussi les métriques Prometheus
+	journal.Info("Démarrage du service chaincode", slog.String("adresse", adresse))
+	if adresseMetriques := os.Getenv("METRICS_ADDRESS"); adresseMetriques != "" {
+		go func() {
+			if err := servirMetriques(adresseMetriques); err != nil {
+				journal.Error("Erreur serveur de métriques", slog.Any("erreur", err))
+			}
+		}()
+	}
+
+	tls, err := proprietesTLS()
+	if err != nil {
+		journal.Error("Erreur configuration TLS", slog.Any("erreur", err))
+		os.Exit(1)
+	}
+
+	serveur := &shim.ChaincodeServer{
+		CCID:     os.Getenv("CHAINCODE_ID"),
+		Address:  adresse,
+		CC:       envelopper(instrumenter(titreChaincode)),
+		TLSProps: tls,
+	}
+	if err := serveur.Start(); err != nil {
+		journal.Error("Erreur démarrage service chaincode", slog.Any("erreur", err))
+		os.Exit(1)
 	}
 }
 
+// Propriétés TLS du service chaincode, lues depuis les fichiers indiqués dans l'environnement
+func proprietesTLS() (shim.TLSProperties, error) {
+	fichierCle := os.Getenv("CHAINCODE_TLS_KEY_FILE")
+	if fichierCle == "" {
+		return shim.TLSProperties{Disabled: true}, nil
+	}
+
+	cle, err := os.ReadFile(fichierCle)
+	if err != nil {
+		return shim.TLSProperties{}, fmt.Errorf("erreur de lecture de la clé TLS: %v", err)
+	}
+	certificat, err := os.ReadFile(os.Getenv("CHAINCODE_TLS_CERT_FILE"))
+	if err != nil {
+		return shim.TLSProperties{}, fmt.Errorf("erreur de lecture du certificat TLS: %v", err)
+	}
+
+	var autoritesClients []byte
+	if fichierCA := os.Getenv("CHAINCODE_CLIENT_CA_CERT_FILE"); fichierCA != "" {
+		if autoritesClients, err = os.ReadFile(fichierCA); err != nil {
+			return shim.TLSProperties{}, fmt.Errorf("erreur de lecture de l'autorité TLS cliente: %v", err)
+		}
+	}
+
+	return shim.TLSProperties{Key: cle, Cert: certificat, ClientCACerts: autoritesClients}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestImmatriculationReserveeAuConservateur(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	verifierCode(t, "notaire", r.ajouter(notaire, "TF100", "Awa Ndiaye"), CodeAccesRefuse)
+	verifierCode(t, "agent sans rôle", r.ajouter(sansRole, "TF100", "Awa Ndiaye"), CodeAccesRefuse)
+	// Un agent du bureau qui n'en est pas le conservateur n'immatricule pas
+	verifierCode(t, "inspecteur du bureau", r.ajouter(inspecteurDK, "TF100", "Awa Ndiaye"), CodeAccesRefuse)
+	// Le rôle de conservateur ne vaut que délivré par la conservation foncière
+	usurpateur := &identiteTest{msp: MSPChambreNotaires, attributs: map[string]string{"role": RoleConservateur, "bureau": "DK"}}
+	verifierCode(t, "conservateur d'une autre organisation", r.ajouter(usurpateur, "TF100", "Awa Ndiaye"), CodeAccesRefuse)
+	verifierCode(t, "conservateur", r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"), "")
+
+	titre := r.lire("TF100")
+	if titre.BureauFoncier != "DK" || titre.NumTF == "" || titre.DocHash == "" || titre.CreeLe == "" {
+		t.Errorf("titre immatriculé: %+v", titre)
+	}
+	if err := r.ajouter(conservateurDK, "TF100", "Moussa Fall"); err == nil {
+		t.Error("un identifiant existant est réimmatriculé")
+	}
+	if proprio := r.lire("TF100").Proprio; proprio != "Awa Ndiaye" {
+		t.Errorf("titre écrasé: propriétaire %s", proprio)
+	}
+}
+
+func TestImmatriculationCommunesDuCanal(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	err := r.appeler(etat, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.DefinirConfiguration(ctx, `{"communes":["Dakar","Rufisque"]}`)
+	})
+	verifierCode(t, "configuration par l'État", err, "")
+	err = r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.DefinirConfiguration(ctx, `{"communes":[]}`)
+	})
+	verifierCode(t, "configuration par un bureau", err, CodeAccesRefuse)
+
+	immatriculer := func(id string, commune string) error {
+		return r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.AjouterTitreFoncierCommune(ctx, id, "Awa Ndiaye", "", 1000, r.pdf, commune)
+		})
+	}
+	verifierCode(t, "sans commune", r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"), CodeRequeteInvalide)
+	verifierCode(t, "commune d'un autre canal", immatriculer("TF100", "Thiès"), CodeCommuneHorsCanal)
+	verifierCode(t, "commune du canal", immatriculer("TF100", "Rufisque"), "")
+	verifierCode(t, "notaire", r.appeler(notaire, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.AjouterTitreFoncierCommune(ctx, "TF101", "Awa Ndiaye", "", 1000, r.pdf, "Dakar")
+	}), CodeAccesRefuse)
+
+	// Les numéros sont attribués par la séquence du canal
+	verifierCode(t, "second titre", immatriculer("TF101", "Dakar"), "")
+	if a, b := r.lire("TF100").NumTF, r.lire("TF101").NumTF; a == b || a == "" || b == "" {
+		t.Errorf("numéros attribués: %q et %q", a, b)
+	}
+}
+
+func TestModificationParLeBureauDuTitre(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	verifierCode(t, "immatriculation", r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"), "")
+	modifier := func(appelant *identiteTest, proprio string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.ModifierProprietaire(ctx, "TF100", proprio)
+		})
+	}
+
+	verifierCode(t, "autre bureau", modifier(conservateurTH, "Moussa Fall"), CodeAccesRefuse)
+	// L'attribut bureau n'est reconnu qu'aux agents de la conservation foncière
+	verifierCode(t, "notaire muni de l'attribut bureau", modifier(notaire, "Moussa Fall"), CodeAccesRefuse)
+	verifierCode(t, "bureau du titre", modifier(conservateurDK, "Moussa Fall"), "")
+	verifierCode(t, "administration centrale", modifier(etat, "Fatou Sow"), "")
+	if proprio := r.lire("TF100").Proprio; proprio != "Fatou Sow" {
+		t.Errorf("propriétaire %s, Fatou Sow attendu", proprio)
+	}
+
+	// Rattaché au bureau de Thiès, le titre échappe à celui de Dakar
+	rattacher := func(appelant *identiteTest) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.DefinirBureauFoncier(ctx, "TF100", "TH")
+		})
+	}
+	verifierCode(t, "rattachement par un bureau", rattacher(conservateurDK), CodeAccesRefuse)
+	verifierCode(t, "rattachement par l'État", rattacher(etat), "")
+	verifierCode(t, "ancien bureau", modifier(conservateurDK, "Awa Ndiaye"), CodeAccesRefuse)
+	verifierCode(t, "nouveau bureau", modifier(conservateurTH, "Awa Ndiaye"), "")
+}
+
+func TestDomainePublicInalienable(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	verifierCode(t, "immatriculation", r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"), "")
+	modifier := func(appelant *identiteTest, proprio string) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.ModifierProprietaire(ctx, "TF100", proprio)
+		})
+	}
+
+	// Une parcelle du domaine public ne change pas de propriétaire
+	classer := func(appelant *identiteTest) error {
+		return r.appeler(appelant, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.DefinirInalienable(ctx, "TF100", true)
+		})
+	}
+	verifierCode(t, "classement par le bureau du titre", classer(conservateurDK), CodeAccesRefuse)
+	verifierCode(t, "classement par l'État", classer(etat), "")
+	verifierCode(t, "cession du domaine public", modifier(conservateurDK, "Moussa Fall"), CodeTitreInalienable)
+	if proprio := r.lire("TF100").Proprio; proprio != "Awa Ndiaye" {
+		t.Errorf("propriétaire %s après les refus, Awa Ndiaye attendu", proprio)
+	}
+}
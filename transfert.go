@@ -0,0 +1,573 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Préfixe des clés composites des transferts de propriété
+const PrefixeTransfert = "TRANSFERT"
+
+// Préfixe des clés composites des échéances payées
+const PrefixeEcheance = "ECHEANCE"
+
+// Préfixe des clés composites des références de paiement déjà rapprochées d'une échéance (reference)
+const PrefixeReferenceEcheance = "REFERENCE_ECHEANCE"
+
+// Proposer le transfert d'un titre foncier à un acheteur, payé comptant
+func (s *SmartContract) ProposerTransfert(ctx contractapi.TransactionContextInterface, idTransfert string, idTitre string, acheteur string, prix int) error {
+	return s.proposerTransfert(ctx, idTransfert, idTitre, acheteur, prix, ModeComptant)
+}
+
+// Proposer une vente à tempérament : la propriété n'est transférée qu'au paiement complet du prix
+func (s *SmartContract) ProposerVenteATemperament(ctx contractapi.TransactionContextInterface, idTransfert string, idTitre string, acheteur string, prixTotal int) error {
+	return s.proposerTransfert(ctx, idTransfert, idTitre, acheteur, prixTotal, ModeTemperament)
+}
+
+// Enregistrer le paiement d'une échéance, justifié par la quittance du Trésor ou par le notaire
+// instrumentaire qui en a reçu les fonds ; une référence de paiement ne justifie qu'une échéance, et le
+// dernier paiement transfère la propriété
+func (s *SmartContract) EnregistrerEcheance(ctx contractapi.TransactionContextInterface, idTransfert string, montant int, reference string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	if mspID != MSPTresor {
+		if err := verifierRole(ctx, RoleNotaire); err != nil {
+			return err
+		}
+	}
+	if reference == "" {
+		return nouvelleErreur(CodeRequeteInvalide, "la référence du paiement est obligatoire")
+	}
+	cleReference, err := ctx.GetStub().CreateCompositeKey(PrefixeReferenceEcheance, []string{reference})
+	if err != nil {
+		return err
+	}
+	var rapprochee string
+	existe, err := lireEtat(ctx, cleReference, &rapprochee)
+	if err != nil {
+		return err
+	}
+	if existe {
+		return fmt.Errorf("le paiement %s a déjà été enregistré pour le transfert %s", reference, rapprochee)
+	}
+
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Mode != ModeTemperament {
+		return fmt.Errorf("le transfert %s n'est pas une vente à tempérament", idTransfert)
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+	if mspID != MSPTresor {
+		notaire, err := ctx.GetClientIdentity().GetID()
+		if err != nil {
+			return fmt.Errorf("erreur de lecture de l'identité: %v", err)
+		}
+		if transfert.Notaire == "" || notaire != transfert.Notaire {
+			return nouvelleErreur(CodeAccesRefuse, "seul le notaire instrumentaire peut enregistrer les échéances du transfert %s", idTransfert)
+		}
+	}
+	if montant <= 0 {
+		return fmt.Errorf("le montant doit être positif")
+	}
+	if transfert.MontantPaye+montant > transfert.Prix {
+		return fmt.Errorf("le paiement dépasse le solde restant de %d FCFA", transfert.Prix-transfert.MontantPaye)
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	echeance := Echeance{
+		IdTransfert: idTransfert,
+		Montant:     montant,
+		Reference:   reference,
+		Date:        maintenant.Format(FormatDate),
+		TxId:        txID,
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeEcheance, []string{idTransfert, txID})
+	if err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cle, echeance); err != nil {
+		return err
+	}
+	if err := ecrireEtat(ctx, cleReference, idTransfert); err != nil {
+		return err
+	}
+
+	transfert.MontantPaye += montant
+	if transfert.MontantPaye < transfert.Prix {
+		return sauvegarderTransfert(ctx, transfert)
+	}
+
+	return s.executerTransfert(ctx, transfert)
+}
+
+// Lister les échéances payées d'une vente à tempérament
+func (s *SmartContract) GetEcheancesTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) ([]*Echeance, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeEcheance, []string{idTransfert})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var echeances []*Echeance
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var echeance Echeance
+		err = decoderEtat(queryResponse.Value, &echeance)
+		if err != nil {
+			return nil, err
+		}
+		echeances = append(echeances, &echeance)
+	}
+
+	return echeances, nil
+}
+
+// Créer un transfert en attente après les contrôles communs à tous les modes
+func (s *SmartContract) proposerTransfert(ctx contractapi.TransactionContextInterface, idTransfert string, idTitre string, acheteur string, prix int, mode string) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeTransfert, []string{idTransfert})
+	if err != nil {
+		return err
+	}
+	existant, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return fmt.Errorf("le transfert %s existe déjà", idTransfert)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAlienableDemembre(titre); err != nil {
+		return err
+	}
+	// Les droits d'immatriculation doivent être acquittés avant toute mutation
+	if err := verifierFactureReglee(ctx, FacturationImmatriculation, idTitre); err != nil {
+		return err
+	}
+	transfert := model.NouveauTransfert(idTransfert, idTitre, titre.Proprio, acheteur, prix, mode)
+	if err := transfert.Valider(); err != nil {
+		return err
+	}
+	if err := verifierReglesTransfert(ctx, titre, transfert, false); err != nil {
+		return err
+	}
+	if transfert.Emprises, err = verifierEmprises(ctx, titre); err != nil {
+		return err
+	}
+	if err := s.verifierConformite(ctx, titre); err != nil {
+		return err
+	}
+
+	// La vente d'une personne morale attend les signatures de ses représentants
+	seuil, err := seuilCosignature(ctx, titre.Proprio)
+	if err != nil {
+		return err
+	}
+	if seuil > 0 {
+		transfert.Statut = TransfertEnCosignature
+		transfert.SeuilCosignature = seuil
+		return sauvegarderTransfert(ctx, transfert)
+	}
+
+	return s.ouvrirTransfert(ctx, titre, transfert)
+}
+
+// Mettre un transfert en attente : conversion d'une promesse de l'acheteur, demandes de consentement,
+// alertes et réserve de propriété
+func (s *SmartContract) ouvrirTransfert(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, transfert *Transfert) error {
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+
+	// Une promesse de vente au profit de l'acheteur est convertie en ce transfert
+	reserve := transfert.Mode == ModeTemperament
+	if charge := promesseAuProfitDe(titre, transfert.Acheteur, aujourdhui); charge != nil {
+		promesse, err := s.convertirPromesse(ctx, charge.Reference, transfert.Id)
+		if err != nil {
+			return err
+		}
+		if promesse != nil {
+			retirerCharge(titre, ChargePromesseVente, charge.Reference)
+			reserve = true
+			transfert.Notaire = promesse.Notaire
+		}
+	}
+	if err := verifierLibreDePromesse(titre, transfert.Id, aujourdhui); err != nil {
+		return err
+	}
+
+	transfert.Statut = TransfertEnAttente
+	if err := sauvegarderTransfert(ctx, transfert); err != nil {
+		return err
+	}
+	if err := emettreFacture(ctx, FacturationTransfert, transfert.Id, transfert.IdTitre, transfert.Prix); err != nil {
+		return err
+	}
+	if err := demanderConsentements(ctx, titre, transfert); err != nil {
+		return err
+	}
+	if err := alerterBanques(ctx, titre.Id, AlerteTransfertPropose); err != nil {
+		return err
+	}
+	if _, err := ouvrirApprobation(ctx, WorkflowTransfert, transfert.Id, ""); err != nil {
+		return err
+	}
+	if !reserve {
+		return nil
+	}
+
+	// L'acheteur garde l'exclusivité sur le titre jusqu'à la finalisation
+	ajouterCharge(titre, Charge{Type: ChargePromesseVente, Beneficiaire: transfert.Acheteur, Reference: transfert.Id})
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Signer la vente proposée d'une personne morale en tant que l'un de ses représentants habilités ;
+// la signature qui atteint le seuil met le transfert en attente
+func (s *SmartContract) CosignerTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) (*Transfert, error) {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return nil, err
+	}
+	if transfert.Statut != TransfertEnCosignature {
+		return nil, fmt.Errorf("le transfert %s n'attend pas de cosignature (statut: %s)", idTransfert, transfert.Statut)
+	}
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return nil, err
+	}
+	if titre.Proprio != transfert.Vendeur {
+		return nil, fmt.Errorf("le titre foncier %s a changé de propriétaire depuis la proposition", titre.Id)
+	}
+
+	representant, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	qualites, err := lireQualites(ctx, transfert.Vendeur)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(qualites.Representants, representant) {
+		return nil, nouvelleErreur(CodeAccesRefuse, "l'appelant n'est pas un représentant habilité de %s", transfert.Vendeur)
+	}
+	if slices.Contains(transfert.Cosignatures, representant) {
+		return nil, fmt.Errorf("le représentant a déjà signé le transfert %s", idTransfert)
+	}
+
+	transfert.Cosignatures = append(transfert.Cosignatures, representant)
+	if len(transfert.Cosignatures) < transfert.SeuilCosignature {
+		return transfert, sauvegarderTransfert(ctx, transfert)
+	}
+	if err := verifierAlienableDemembre(titre); err != nil {
+		return nil, err
+	}
+	return transfert, s.ouvrirTransfert(ctx, titre, transfert)
+}
+
+// Signatures de représentants exigées pour la vente d'un propriétaire (0 : aucune)
+func seuilCosignature(ctx contractapi.TransactionContextInterface, idProprio string) (int, error) {
+	qualites, err := lireQualites(ctx, idProprio)
+	if err != nil {
+		return 0, err
+	}
+	return qualites.SeuilCosignature, nil
+}
+
+// Finaliser un transfert : le titre change de propriétaire
+func (s *SmartContract) FinaliserTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) error {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+	if transfert.Mode == ModeTemperament {
+		return fmt.Errorf("la vente à tempérament %s est finalisée par le paiement de la dernière échéance", idTransfert)
+	}
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAffectation(ctx, DossierTransfert, idTransfert, titre); err != nil {
+		return err
+	}
+
+	return s.executerTransfert(ctx, transfert)
+}
+
+// Transférer la propriété du titre à l'acheteur et clôturer le transfert ; l'appelant est contrôlé par
+// l'opération qui l'invoque, agent du bureau du titre ou payeur de la dernière échéance
+func (s *SmartContract) executerTransfert(ctx contractapi.TransactionContextInterface, transfert *Transfert) error {
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierAlienableDemembre(titre); err != nil {
+		return err
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifierLibreDePromesse(titre, transfert.Id, maintenant.Format(FormatDate)); err != nil {
+		return err
+	}
+	if titre.Proprio != transfert.Vendeur {
+		return fmt.Errorf("le titre foncier %s a changé de propriétaire depuis la proposition", titre.Id)
+	}
+	if err := verifierReglesTransfert(ctx, titre, transfert, true); err != nil {
+		return err
+	}
+	// Une réserve posée depuis la proposition bloque encore la finalisation
+	if transfert.Emprises, err = verifierEmprises(ctx, titre); err != nil {
+		return err
+	}
+	if err := s.verifierConformite(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierConsentements(titre, transfert); err != nil {
+		return err
+	}
+	if err := verifierFactureReglee(ctx, FacturationTransfert, transfert.Id); err != nil {
+		return err
+	}
+	approbation, err := verifierApprobation(ctx, WorkflowTransfert, transfert.Id, "")
+	if err != nil {
+		return err
+	}
+
+	if err := s.controlerPrixDeclare(ctx, transfert); err != nil {
+		return err
+	}
+
+	// Nu-propriétaire et usufruitier ayant consenti, l'acheteur acquiert la pleine propriété
+	titre.Proprio = transfert.Acheteur
+	titre.Usufruit = nil
+	retirerCharge(titre, ChargePromesseVente, transfert.Id)
+	if err := sauvegarderTitre(ctx, titre); err != nil {
+		return err
+	}
+
+	transfert.Statut = TransfertFinalise
+	if err := sauvegarderTransfert(ctx, transfert); err != nil {
+		return err
+	}
+	if err := consommerApprobation(ctx, approbation); err != nil {
+		return err
+	}
+	if err := cloturerSequestre(ctx, transfert); err != nil {
+		return err
+	}
+
+	if transfert.Signalement == "" {
+		return nil
+	}
+	journalTx(ctx).Warn("transfert signalé", slog.String("idTransfert", transfert.Id), slog.String("signalement", transfert.Signalement), slog.Int("ecartPrix", transfert.EcartPrix))
+	evenement, err := json.Marshal(transfert)
+	if err != nil {
+		return err
+	}
+	return emettreEvenement(ctx, EvenementTransfertSignale, evenement)
+}
+
+// Annuler un transfert en attente
+func (s *SmartContract) AnnulerTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) error {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente && transfert.Statut != TransfertEnCosignature {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAffectation(ctx, DossierTransfert, idTransfert, titre); err != nil {
+		return err
+	}
+
+	modifie, err := abandonnerTransfert(ctx, titre, transfert)
+	if err != nil || !modifie {
+		return err
+	}
+
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Annuler un transfert en attente et clore son séquestre ; indique si la charge de la promesse
+// convertie en ce transfert a été retirée du titre, qui reste à enregistrer
+func abandonnerTransfert(ctx contractapi.TransactionContextInterface, titre *TitreFoncier, transfert *Transfert) (bool, error) {
+	transfert.Statut = TransfertAnnule
+	if err := sauvegarderTransfert(ctx, transfert); err != nil {
+		return false, err
+	}
+	if err := cloturerSequestre(ctx, transfert); err != nil {
+		return false, err
+	}
+	return retirerCharge(titre, ChargePromesseVente, transfert.Id), nil
+}
+
+// Transferts d'un titre en attente ou en cosignature, retrouvés par le suivi des dossiers en cours
+func (s *SmartContract) transfertsEnCoursTitre(ctx contractapi.TransactionContextInterface, idTitre string) ([]*Transfert, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeSuiviEnCours, []string{DossierTransfert})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var transferts []*Transfert
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributs, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		transfert, err := s.LireTransfert(ctx, attributs[1])
+		if err != nil {
+			return nil, err
+		}
+		if transfert.IdTitre == idTitre && (transfert.Statut == TransfertEnAttente || transfert.Statut == TransfertEnCosignature) {
+			transferts = append(transferts, transfert)
+		}
+	}
+	return transferts, nil
+}
+
+// Lire un transfert de propriété
+func (s *SmartContract) LireTransfert(ctx contractapi.TransactionContextInterface, idTransfert string) (*Transfert, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeTransfert, []string{idTransfert})
+	if err != nil {
+		return nil, err
+	}
+
+	var transfert Transfert
+	existe, err := lireEtat(ctx, cle, &transfert)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		return nil, fmt.Errorf("transfert %s non trouvé", idTransfert)
+	}
+
+	return &transfert, nil
+}
+
+// Comparer le prix déclaré à la dernière évaluation et marquer les écarts excessifs
+func (s *SmartContract) controlerPrixDeclare(ctx contractapi.TransactionContextInterface, transfert *Transfert) error {
+	evaluation, err := s.derniereEvaluation(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	if evaluation == nil {
+		return nil
+	}
+
+	config, err := s.LireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+
+	ecart := (transfert.Prix - evaluation.Valeur) * 100 / evaluation.Valeur
+	if ecart < 0 {
+		ecart = -ecart
+	}
+	if ecart <= config.SeuilEcartPrix {
+		return nil
+	}
+
+	transfert.Signalement = SignalementEcartEvaluation
+	transfert.EcartPrix = ecart
+
+	return nil
+}
+
+func sauvegarderTransfert(ctx contractapi.TransactionContextInterface, transfert *Transfert) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeTransfert, []string{transfert.Id})
+	if err != nil {
+		return err
+	}
+	var precedent Transfert
+	if _, err := lireEtat(ctx, cle, &precedent); err != nil {
+		return err
+	}
+
+	if err := ecrireEtat(ctx, cle, transfert); err != nil {
+		return err
+	}
+	if err := inscrireActiviteTransfert(ctx, precedent.Statut, transfert); err != nil {
+		return err
+	}
+	if transfert.Statut == TransfertFinalise && precedent.Statut != TransfertFinalise {
+		delai, err := delaiDepuisOuverture(ctx, DossierTransfert, transfert.Id)
+		if err != nil {
+			return err
+		}
+		if err := enregistrerTraitement(ctx, TraitementFinalisation, transfert.Id, delai); err != nil {
+			return err
+		}
+	}
+	if err := suivreStatut(ctx, DossierTransfert, transfert.Id, transfert.IdTitre, transfert.Statut); err != nil {
+		return err
+	}
+
+	// Le titre lu est celui d'avant la transaction : un abonnement au vendeur suit donc la vente
+	var titre TitreFoncier
+	existe, err := lireEtat(ctx, transfert.IdTitre, &titre)
+	if err != nil || !existe {
+		return err
+	}
+	genre, responsable, err := responsableTransfert(ctx, transfert, titre.BureauFoncier)
+	if err != nil {
+		return err
+	}
+	entrees, err := entreesFileTransfert(ctx, transfert, genre, responsable)
+	if err != nil {
+		return err
+	}
+	if err := mettreAJourFileAttente(ctx, entrees, transfert.Statut == TransfertEnAttente); err != nil {
+		return err
+	}
+	return notifierAbonnes(ctx, &titre, transfert.Statut)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Notaire titulaire d'une licence délivrée par la chambre et liée à son identité
+func notaireLicencie(t *testing.T, r *registreTest, nom string, licence string) *identiteTest {
+	t.Helper()
+	notaire := &identiteTest{msp: MSPChambreNotaires, attributs: map[string]string{"role": RoleNotaire, "licence": licence}, nom: nom}
+	chambre := &identiteTest{msp: MSPChambreNotaires}
+	err := r.appeler(chambre, func(ctx contractapi.TransactionContextInterface) error {
+		identite, _ := notaire.GetID()
+		if err := r.s.EnregistrerLicence(ctx, licence, RoleNotaire, nom, "2099-12-31"); err != nil {
+			return err
+		}
+		return r.s.LierLicence(ctx, licence, identite)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notaire
+}
+
+func TestVenteATemperament(t *testing.T) {
+	r := nouveauRegistreTest(t)
+	if err := r.ajouter(conservateurDK, "TF100", "Awa Ndiaye"); err != nil {
+		t.Fatal(err)
+	}
+	instrumentaire := notaireLicencie(t, r, "Me Sow", "N-001")
+	confrere := notaireLicencie(t, r, "Me Ba", "N-002")
+	tresor := &identiteTest{msp: MSPTresor}
+
+	err := r.appeler(conservateurDK, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.ProposerVenteATemperament(ctx, "VT1", "TF100", "Moussa Fall", 3000000)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payer := func(payeur *identiteTest, montant int, reference string) error {
+		return r.appeler(payeur, func(ctx contractapi.TransactionContextInterface) error {
+			return r.s.EnregistrerEcheance(ctx, "VT1", montant, reference)
+		})
+	}
+	verifierCode(t, "notaire sans séquestre", payer(instrumentaire, 1000000, "Q-1"), CodeAccesRefuse)
+	err = r.appeler(instrumentaire, func(ctx contractapi.TransactionContextInterface) error {
+		return r.s.OuvrirSequestre(ctx, "VT1", "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifierCode(t, "notaire instrumentaire", payer(instrumentaire, 1000000, "Q-1"), "")
+	verifierCode(t, "autre notaire", payer(confrere, 1000000, "Q-2"), CodeAccesRefuse)
+	verifierCode(t, "agent du bureau", payer(conservateurDK, 1000000, "Q-2"), CodeAccesRefuse)
+	verifierCode(t, "quittance du Trésor", payer(tresor, 1000000, "Q-2"), "")
+	if proprio := r.lire("TF100").Proprio; proprio != "Awa Ndiaye" {
+		t.Fatalf("propriété transférée avant le paiement complet: %s", proprio)
+	}
+
+	// La dernière échéance transfère la propriété, sans intervention du bureau
+	verifierCode(t, "dernière échéance", payer(instrumentaire, 1000000, "Q-3"), "")
+	if proprio := r.lire("TF100").Proprio; proprio != "Moussa Fall" {
+		t.Errorf("propriétaire après le paiement complet: %s", proprio)
+	}
+	var transfert *Transfert
+	err = r.appeler(etat, func(ctx contractapi.TransactionContextInterface) (err error) {
+		transfert, err = r.s.LireTransfert(ctx, "VT1")
+		return err
+	})
+	if err != nil || transfert.Statut != TransfertFinalise || transfert.MontantPaye != 3000000 {
+		t.Errorf("transfert après le paiement complet: %+v (%v)", transfert, err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Préfixe des clés composites des permis de construire (idTitre~idPermis)
+const PrefixePermisConstruire = "PERMIS_CONSTRUIRE"
+
+// Enregistrer un permis de construire délivré sur un titre (réservé à la direction de l'urbanisme).
+// Une parcelle sous réserve d'emprise publique est signalée sur le permis, ou refusée selon la configuration.
+func (s *SmartContract) DelivrerPermisConstruire(ctx contractapi.TransactionContextInterface, idPermis string, idTitre string, objet string) (*PermisConstruire, error) {
+	if err := verifierRole(ctx, RoleUrbanisme); err != nil {
+		return nil, err
+	}
+	if idPermis == "" || objet == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le numéro et l'objet du permis sont requis")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if titre.MuteVers != "" {
+		return nil, nouvelleErreur(CodeTitreMute, "le titre foncier %s est désormais administré sur le canal %s", titre.Id, titre.MuteVers)
+	}
+	if len(titre.MorceleEn) > 0 {
+		return nil, nouvelleErreur(CodeTitreMorcele, "le titre foncier %s est clos, le permis doit viser l'un de ses lots", titre.Id)
+	}
+	existant, err := lirePermisConstruire(ctx, idTitre, idPermis)
+	if err != nil {
+		return nil, err
+	}
+	if existant != nil {
+		return nil, fmt.Errorf("le permis %s est déjà enregistré sur le titre foncier %s", idPermis, idTitre)
+	}
+	emprises, err := verifierEmprises(ctx, titre)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permis := &PermisConstruire{
+		Id:           idPermis,
+		IdTitre:      idTitre,
+		Beneficiaire: titre.Proprio,
+		Objet:        objet,
+		Statut:       PermisDelivre,
+		DelivrePar:   agent,
+		DelivreLe:    maintenant.Format(FormatDate),
+		Emprises:     emprises,
+	}
+	return permis, sauvegarderPermisConstruire(ctx, permis)
+}
+
+// Faire avancer un permis de construire (réservé à la direction de l'urbanisme) : ouverture du
+// chantier (EN_TRAVAUX), puis constat de conformité des travaux (CONFORMITE)
+func (s *SmartContract) MettreAJourPermisConstruire(ctx contractapi.TransactionContextInterface, idTitre string, idPermis string, statut string) (*PermisConstruire, error) {
+	if err := verifierRole(ctx, RoleUrbanisme); err != nil {
+		return nil, err
+	}
+	permis, err := lirePermisConstruire(ctx, idTitre, idPermis)
+	if err != nil {
+		return nil, err
+	}
+	if permis == nil {
+		return nil, fmt.Errorf("permis de construire %s non trouvé sur le titre foncier %s", idPermis, idTitre)
+	}
+	if !permis.PeutPasserA(statut) {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le permis %s ne peut passer du statut %s au statut %s", idPermis, permis.Statut, statut)
+	}
+
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permis.Statut = statut
+	switch statut {
+	case PermisEnTravaux:
+		permis.TravauxLe = maintenant.Format(FormatDate)
+	case PermisConformite:
+		permis.ConformeLe = maintenant.Format(FormatDate)
+	}
+	return permis, sauvegarderPermisConstruire(ctx, permis)
+}
+
+// Lister les permis de construire délivrés sur un titre
+func (s *SmartContract) GetPermisConstruire(ctx contractapi.TransactionContextInterface, idTitre string) ([]*PermisConstruire, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixePermisConstruire, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	permis := []*PermisConstruire{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var p PermisConstruire
+		if err := decoderEtat(queryResponse.Value, &p); err != nil {
+			return nil, err
+		}
+		permis = append(permis, &p)
+	}
+	return permis, nil
+}
+
+func lirePermisConstruire(ctx contractapi.TransactionContextInterface, idTitre string, idPermis string) (*PermisConstruire, error) {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixePermisConstruire, []string{idTitre, idPermis})
+	if err != nil {
+		return nil, err
+	}
+	var permis PermisConstruire
+	existe, err := lireEtat(ctx, cle, &permis)
+	if err != nil || !existe {
+		return nil, err
+	}
+	return &permis, nil
+}
+
+func sauvegarderPermisConstruire(ctx contractapi.TransactionContextInterface, permis *PermisConstruire) error {
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixePermisConstruire, []string{permis.IdTitre, permis.Id})
+	if err != nil {
+		return err
+	}
+	return ecrireEtat(ctx, cle, permis)
+}
+
+// Préfixe des clés composites des certificats de conformité (idTitre~idCertificat)
+const PrefixeCertificatConformite = "CERTIFICAT_CONFORMITE"
+
+// Délivrer le certificat de conformité des constructions d'une parcelle après inspection (réservé aux
+// agents de la commune de situation) ; idPermis, facultatif, désigne le permis dont les travaux sont certifiés
+func (s *SmartContract) DelivrerCertificatConformite(ctx contractapi.TransactionContextInterface, idCertificat string, idTitre string, idPermis string, inspection string) (*CertificatConformite, error) {
+	if idCertificat == "" || inspection == "" {
+		return nil, nouvelleErreur(CodeRequeteInvalide, "le numéro du certificat et la référence de l'inspection sont requis")
+	}
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifierAgentCommunal(ctx, titre.Commune); err != nil {
+		return nil, err
+	}
+	cle, err := ctx.GetStub().CreateCompositeKey(PrefixeCertificatConformite, []string{idTitre, idCertificat})
+	if err != nil {
+		return nil, err
+	}
+	existant, err := ctx.GetStub().GetState(cle)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de récupération de l'état: %v", err)
+	}
+	if existant != nil {
+		return nil, fmt.Errorf("le certificat %s est déjà délivré sur le titre foncier %s", idCertificat, idTitre)
+	}
+	if idPermis != "" {
+		permis, err := lirePermisConstruire(ctx, idTitre, idPermis)
+		if err != nil {
+			return nil, err
+		}
+		if permis == nil {
+			return nil, fmt.Errorf("permis de construire %s non trouvé sur le titre foncier %s", idPermis, idTitre)
+		}
+		if permis.Statut == PermisDelivre {
+			return nil, nouvelleErreur(CodeRequeteInvalide, "les travaux du permis %s n'ont pas commencé", idPermis)
+		}
+	}
+
+	agent, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("erreur de lecture de l'identité: %v", err)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	certificat := &CertificatConformite{
+		Id:         idCertificat,
+		IdTitre:    idTitre,
+		IdPermis:   idPermis,
+		Commune:    titre.Commune,
+		Inspection: inspection,
+		DelivrePar: agent,
+		DelivreLe:  maintenant.Format(FormatDate),
+	}
+	return certificat, ecrireEtat(ctx, cle, certificat)
+}
+
+// Lister les certificats de conformité délivrés sur un titre
+func (s *SmartContract) GetCertificatsConformite(ctx contractapi.TransactionContextInterface, idTitre string) ([]*CertificatConformite, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PrefixeCertificatConformite, []string{idTitre})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	certificats := []*CertificatConformite{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var certificat CertificatConformite
+		if err := decoderEtat(queryResponse.Value, &certificat); err != nil {
+			return nil, err
+		}
+		certificats = append(certificats, &certificat)
+	}
+	return certificats, nil
+}
+
+// Refuser, si la configuration l'exige, la cession d'une parcelle dont des travaux autorisés ont
+// commencé sans que la commune en ait certifié la conformité
+func (s *SmartContract) verifierConformite(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	config, err := lireConfiguration(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.ConformiteAvantCession {
+		return nil
+	}
+	permis, err := s.GetPermisConstruire(ctx, titre.Id)
+	if err != nil {
+		return err
+	}
+	certificats, err := s.GetCertificatsConformite(ctx, titre.Id)
+	if err != nil {
+		return err
+	}
+	certifies := make(map[string]bool)
+	for _, certificat := range certificats {
+		certifies[certificat.IdPermis] = true
+	}
+	for _, p := range permis {
+		if p.Statut != PermisDelivre && !certifies[p.Id] {
+			return nouvelleErreur(CodeConformiteRequise, "les travaux du permis %s sur le titre foncier %s n'ont pas reçu de certificat de conformité", p.Id, titre.Id)
+		}
+	}
+	return nil
+}
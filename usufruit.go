@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Démembrer la propriété d'un titre (réservé aux notaires) : le propriétaire inscrit en conserve la
+// nue-propriété, l'usufruit revient à l'usufruitier jusqu'à l'échéance (AAAA-MM-JJ, vide s'il est viager)
+func (s *SmartContract) DemembrerPropriete(ctx contractapi.TransactionContextInterface, idTitre string, usufruitier string, echeance string, acte string) error {
+	if err := verifierRole(ctx, RoleNotaire); err != nil {
+		return err
+	}
+	if usufruitier == "" || acte == "" {
+		return fmt.Errorf("l'usufruitier et l'acte constitutif sont obligatoires")
+	}
+
+	titre, err := s.LireTitreFoncier(ctx, idTitre)
+	if err != nil {
+		return err
+	}
+	if err := verifierBureau(ctx, titre); err != nil {
+		return err
+	}
+	if err := verifierAlienable(titre); err != nil {
+		return err
+	}
+	if usufruitier == titre.Proprio {
+		return fmt.Errorf("l'usufruitier %s est déjà plein propriétaire du titre foncier %s", usufruitier, idTitre)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	aujourdhui := maintenant.Format(FormatDate)
+	if echeance != "" {
+		if _, err := time.Parse(FormatDate, echeance); err != nil {
+			return nouvelleErreur(CodeRequeteInvalide, "échéance de l'usufruit invalide (AAAA-MM-JJ attendu): %s", echeance)
+		}
+		if echeance <= aujourdhui {
+			return fmt.Errorf("l'échéance de l'usufruit %s est déjà atteinte", echeance)
+		}
+	}
+
+	titre.Usufruit = &Usufruit{
+		Usufruitier: usufruitier,
+		Acte:        acte,
+		ConstitueLe: aujourdhui,
+		Echeance:    echeance,
+	}
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Consentir, au nom de l'usufruitier appelant, au transfert d'un titre démembré
+func (s *SmartContract) ConsentirTransfertUsufruitier(ctx contractapi.TransactionContextInterface, idTransfert string) error {
+	transfert, err := s.LireTransfert(ctx, idTransfert)
+	if err != nil {
+		return err
+	}
+	if transfert.Statut != TransfertEnAttente {
+		return fmt.Errorf("le transfert %s n'est pas en attente", idTransfert)
+	}
+	titre, err := s.LireTitreFoncier(ctx, transfert.IdTitre)
+	if err != nil {
+		return err
+	}
+	if titre.Usufruit == nil {
+		return fmt.Errorf("la propriété du titre foncier %s n'est pas démembrée", titre.Id)
+	}
+	proprio, err := proprioAppelant(ctx)
+	if err != nil {
+		return err
+	}
+	if proprio == "" || proprio != titre.Usufruit.Usufruitier {
+		return nouvelleErreur(CodeAccesRefuse, "seul l'usufruitier du titre foncier %s peut consentir au transfert %s", titre.Id, idTransfert)
+	}
+
+	if !consentie(transfert, ConsentementUsufruitier) {
+		transfert.Consentements = append(transfert.Consentements, ConsentementUsufruitier)
+	}
+	return sauvegarderTransfert(ctx, transfert)
+}
+
+// Reconstituer la pleine propriété au profit du nu-propriétaire : à l'échéance de l'usufruit, n'importe
+// quelle transaction peut la déclencher ; avant, un notaire doit produire l'acte qui l'éteint (décès,
+// renonciation)
+func (s *SmartContract) ReunirPropriete(ctx contractapi.TransactionContextInterface, idTitre string, acte string) error {
+	// L'état enregistré, et non celui de LireTitreFoncier qui tient déjà pour éteint l'usufruit échu
+	titre := &TitreFoncier{}
+	existe, err := lireTitre(ctx, idTitre, titre)
+	if err != nil {
+		return err
+	}
+	if !existe {
+		return fmt.Errorf("titre foncier %s non trouvé", idTitre)
+	}
+	if titre.Usufruit == nil {
+		return fmt.Errorf("la propriété du titre foncier %s n'est pas démembrée", idTitre)
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if !usufruitEchu(titre.Usufruit, maintenant.Format(FormatDate)) {
+		if err := verifierRole(ctx, RoleNotaire); err != nil {
+			return err
+		}
+		if acte == "" {
+			return fmt.Errorf("l'usufruit du titre foncier %s n'est pas échu, l'acte qui l'éteint est obligatoire", idTitre)
+		}
+	}
+
+	journalTx(ctx).Info("pleine propriété reconstituée", slog.String("idTitre", idTitre), slog.String("usufruitier", titre.Usufruit.Usufruitier), slog.String("acte", acte))
+	titre.Usufruit = nil
+	return sauvegarderTitre(ctx, titre)
+}
+
+// Contrôles d'aliénabilité d'un acte auquel l'usufruitier concourt ou qu'il ne peut empêcher
+func verifierAlienableDemembre(titre *TitreFoncier) error {
+	support := *titre
+	support.Usufruit = nil
+	return verifierAlienable(&support)
+}
+
+func usufruitEchu(usufruit *Usufruit, aujourdhui string) bool {
+	return usufruit.Echeance != "" && usufruit.Echeance <= aujourdhui
+}
+
+// Éteindre l'usufruit d'un titre parvenu à son échéance : il prend fin de plein droit, sans attendre
+// que ReunirPropriete soit appelée, et ne fait plus obstacle aux actes de disposition
+func eteindreUsufruitEchu(ctx contractapi.TransactionContextInterface, titre *TitreFoncier) error {
+	if titre.Usufruit == nil {
+		return nil
+	}
+	maintenant, err := dateTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	if usufruitEchu(titre.Usufruit, maintenant.Format(FormatDate)) {
+		titre.Usufruit = nil
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"titrefoncier/pkg/model"
+)
+
+// Résultat d'une validation à blanc
+type ResultatValidation struct {
+	Valide  bool   `json:"valide"`
+	Code    string `json:"code,omitempty" metadata:",optional"`    // Code d'erreur métier éventuel
+	Message string `json:"message,omitempty" metadata:",optional"` // Message d'erreur à présenter à l'utilisateur
+	Classe  string `json:"classe,omitempty" metadata:",optional"`  // Classe de l'échec (VALIDATION, METIER)
+}
+
+// Chaincode utilisé pour rejouer les opérations à blanc, construit une seule fois
+var chaincodeValidation = sync.OnceValues(func() (*contractapi.ContractChaincode, error) {
+	return contractapi.NewChaincode(nouveauContrat())
+})
+
+// Exécuter une opération sans rien écrire : contrôles d'accès, de schéma et règles métier.
+// Le payload est le tableau JSON des arguments de l'opération, dans l'ordre de sa signature.
+func (s *SmartContract) ValiderSansEcrire(ctx contractapi.TransactionContextInterface, operation string, payload string) (*ResultatValidation, error) {
+	if operation == "ValiderSansEcrire" {
+		return nil, fmt.Errorf("opération non validable: %s", operation)
+	}
+
+	var arguments []string
+	if err := json.Unmarshal([]byte(payload), &arguments); err != nil {
+		return nil, fmt.Errorf("payload invalide, tableau JSON d'arguments attendu: %v", err)
+	}
+
+	cc, err := chaincodeValidation()
+	if err != nil {
+		return nil, err
+	}
+
+	reponse := cc.Invoke(&stubSansEcriture{
+		ChaincodeStubInterface: ctx.GetStub(),
+		fonction:               operation,
+		arguments:              arguments,
+	})
+	if reponse.Status < shim.ERRORTHRESHOLD {
+		return &ResultatValidation{Valide: true}, nil
+	}
+
+	resultat := &ResultatValidation{Message: reponse.Message}
+	if correspondance := motifCodeErreur.FindStringSubmatch(reponse.Message); correspondance != nil {
+		resultat.Code = correspondance[1]
+	}
+	resultat.Classe = model.ClasseErreur(resultat.Code, strings.TrimPrefix(resultat.Message, resultat.Code+": "))
+	return resultat, nil
+}
+
+// Stub qui présente une autre opération au contrat et ignore toutes les écritures
+type stubSansEcriture struct {
+	shim.ChaincodeStubInterface
+	fonction  string
+	arguments []string
+}
+
+func (s *stubSansEcriture) GetFunctionAndParameters() (string, []string) {
+	return s.fonction, s.arguments
+}
+
+func (s *stubSansEcriture) GetStringArgs() []string {
+	return append([]string{s.fonction}, s.arguments...)
+}
+
+func (s *stubSansEcriture) GetArgs() [][]byte {
+	args := [][]byte{[]byte(s.fonction)}
+	for _, argument := range s.arguments {
+		args = append(args, []byte(argument))
+	}
+	return args
+}
+
+func (s *stubSansEcriture) PutState(cle string, valeur []byte) error { return nil }
+
+func (s *stubSansEcriture) DelState(cle string) error { return nil }
+
+func (s *stubSansEcriture) SetStateValidationParameter(cle string, politique []byte) error {
+	return nil
+}
+
+func (s *stubSansEcriture) PutPrivateData(collection string, cle string, valeur []byte) error {
+	return nil
+}
+
+func (s *stubSansEcriture) DelPrivateData(collection string, cle string) error { return nil }
+
+func (s *stubSansEcriture) PurgePrivateData(collection string, cle string) error { return nil }
+
+func (s *stubSansEcriture) SetPrivateDataValidationParameter(collection string, cle string, politique []byte) error {
+	return nil
+}
+
+func (s *stubSansEcriture) SetEvent(nom string, contenu []byte) error { return nil }